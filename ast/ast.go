@@ -33,6 +33,9 @@ type Grammar struct {
 	p     Pos
 	Init  *CodeBlock
 	Rules []*Rule
+	// Comments lists the comments of the grammar outside of the code
+	// blocks, in source order.
+	Comments []*Comment
 }
 
 var _ Expression = (*Grammar)(nil)
@@ -81,6 +84,14 @@ type Rule struct {
 	DisplayName *StringLit
 	Expr        Expression
 
+	// Doc lists the comments that directly precede the rule, on the lines
+	// before it and starting at the same column.
+	Doc []*Comment
+
+	// Memoize is set if the results of the rule are memoized even if the
+	// memoization is not enabled for the whole parse, see Memoized.
+	Memoize bool
+
 	// Fields below to work with left recursion.
 	Visited       bool
 	Nullable      bool
@@ -105,6 +116,88 @@ func (r *Rule) String() string {
 		r.p, r, r.Name, r.DisplayName, r.Expr)
 }
 
+// DocText returns the text of the doc comments of the rule, without the
+// comment markers. Leading and trailing blank lines are removed, as well as
+// the trailing spaces of each line, and blank lines are collapsed into one.
+func (r *Rule) DocText() string {
+	var lines []string
+	for _, c := range r.Doc {
+		var text []string
+		if strings.HasPrefix(c.Val, "//") {
+			text = []string{strings.TrimPrefix(c.Val[2:], " ")}
+		} else {
+			text = strings.Split(c.Val[2:len(c.Val)-2], "\n")
+			text[0] = strings.TrimPrefix(text[0], " ")
+			trimCommonIndent(text[1:])
+		}
+		for _, l := range text {
+			l = strings.TrimRight(l, " \t\r")
+			if l == "" && (len(lines) == 0 || lines[len(lines)-1] == "") {
+				continue
+			}
+			lines = append(lines, l)
+		}
+	}
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Annotations of the doc comments of the rules, on a line of their own.
+const (
+	// MemoizeAnnotation marks the rules that are memoized.
+	MemoizeAnnotation = "@memoize"
+	// CommitAnnotation marks the rules that are commit points: once they
+	// match, the memoized results before the end of their match are
+	// discarded.
+	CommitAnnotation = "@commit"
+)
+
+// HasAnnotation returns true if a line of the doc comments of the rule is
+// the annotation, e.g. MemoizeAnnotation.
+func (r *Rule) HasAnnotation(annotation string) bool {
+	for _, l := range strings.Split(r.DocText(), "\n") {
+		if strings.TrimSpace(l) == annotation {
+			return true
+		}
+	}
+	return false
+}
+
+// Memoized returns true if the results of the rule are memoized even if
+// the memoization is not enabled for the whole parse: if its Memoize
+// field is set or if it has the @memoize annotation.
+func (r *Rule) Memoized() bool {
+	return r.Memoize || r.HasAnnotation(MemoizeAnnotation)
+}
+
+// trimCommonIndent removes the leading whitespace common to the non-blank
+// lines.
+func trimCommonIndent(lines []string) {
+	var prefix string
+	first := true
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		indent := l[:len(l)-len(strings.TrimLeft(l, " \t"))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	for i, l := range lines {
+		lines[i] = strings.TrimPrefix(l, prefix)
+	}
+}
+
 // NullableVisit recursively determines whether an object is nullable.
 func (r *Rule) NullableVisit(rules map[string]*Rule) bool {
 	if r.Visited {
@@ -442,6 +535,45 @@ func (l *LabeledExpr) InitialNames() map[string]struct{} {
 	return l.Expr.InitialNames()
 }
 
+// BackRefExpr is an expression that matches the exact text matched by a
+// previously labeled expression in scope.
+type BackRefExpr struct {
+	p     Pos
+	Label *Identifier
+}
+
+var _ Expression = (*BackRefExpr)(nil)
+
+// NewBackRefExpr creates a new back-reference expression at the specified
+// position.
+func NewBackRefExpr(p Pos) *BackRefExpr {
+	return &BackRefExpr{p: p}
+}
+
+// Pos returns the starting position of the node.
+func (b *BackRefExpr) Pos() Pos { return b.p }
+
+// String returns the textual representation of a node.
+func (b *BackRefExpr) String() string {
+	return fmt.Sprintf("%s: %T{Label: %v}", b.p, b, b.Label)
+}
+
+// NullableVisit recursively determines whether an object is nullable.
+func (b *BackRefExpr) NullableVisit(rules map[string]*Rule) bool {
+	// The referenced text may be empty.
+	return true
+}
+
+// IsNullable returns the nullable attribute of the node.
+func (b *BackRefExpr) IsNullable() bool {
+	return true
+}
+
+// InitialNames returns names of nodes with which an expression can begin.
+func (b *BackRefExpr) InitialNames() map[string]struct{} {
+	return make(map[string]struct{})
+}
+
 // AndExpr is a zero-length matcher that is considered a match if the
 // expression it contains is a match.
 type AndExpr struct {
@@ -627,6 +759,71 @@ func (o *OneOrMoreExpr) InitialNames() map[string]struct{} {
 	return o.Expr.InitialNames()
 }
 
+// IndentKind is the kind of an indentation expression.
+type IndentKind int
+
+// List of indentation expression kinds.
+const (
+	IndentKindIndent IndentKind = iota
+	IndentKindDedent
+	IndentKindSamedent
+)
+
+var indentKindNames = [...]string{
+	IndentKindIndent:   "INDENT",
+	IndentKindDedent:   "DEDENT",
+	IndentKindSamedent: "SAMEDENT",
+}
+
+// String returns the name of the indentation expression kind.
+func (k IndentKind) String() string {
+	if int(k) < len(indentKindNames) {
+		return indentKindNames[k]
+	}
+	return "IndentKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// IndentExpr is an expression that tests the indentation of the next
+// non-blank line against the indentation stack of the parser. INDENT
+// and DEDENT push and pop a level of the stack without consuming any
+// input, SAMEDENT consumes the blank lines and the indentation of the
+// next line.
+type IndentExpr struct {
+	p    Pos
+	Kind IndentKind
+}
+
+var _ Expression = (*IndentExpr)(nil)
+
+// NewIndentExpr creates a new indentation expression at the specified
+// position and with the specified kind.
+func NewIndentExpr(p Pos, kind IndentKind) *IndentExpr {
+	return &IndentExpr{p: p, Kind: kind}
+}
+
+// Pos returns the starting position of the node.
+func (i *IndentExpr) Pos() Pos { return i.p }
+
+// String returns the textual representation of a node.
+func (i *IndentExpr) String() string {
+	return fmt.Sprintf("%s: %T{Kind: %s}", i.p, i, i.Kind)
+}
+
+// NullableVisit recursively determines whether an object is nullable.
+func (i *IndentExpr) NullableVisit(rules map[string]*Rule) bool {
+	return true
+}
+
+// IsNullable returns the nullable attribute of the node.
+func (i *IndentExpr) IsNullable() bool {
+	return true
+}
+
+// InitialNames returns names of nodes with which an expression can begin.
+func (i *IndentExpr) InitialNames() map[string]struct{} {
+	return make(map[string]struct{})
+}
+
 // RuleRefExpr is an expression that references a rule by name.
 type RuleRefExpr struct {
 	p    Pos
@@ -852,6 +1049,23 @@ func NewCharClassMatcher(p Pos, raw string) *CharClassMatcher {
 	return c
 }
 
+// NewCharClassMatcherRunes creates a character class matcher at the
+// specified position that matches the chars, the pairs of low/high
+// ranges and the Unicode classes, or any other character if inverted is
+// set. Its source is generated from these values.
+func NewCharClassMatcherRunes(p Pos, chars, ranges []rune, unicodeClasses []string, inverted, ignoreCase bool) *CharClassMatcher {
+	c := &CharClassMatcher{
+		posValue:       posValue{p: p},
+		IgnoreCase:     ignoreCase,
+		Inverted:       inverted,
+		Chars:          chars,
+		Ranges:         ranges,
+		UnicodeClasses: unicodeClasses,
+	}
+	c.updateVal()
+	return c
+}
+
 func (c *CharClassMatcher) parse() {
 	raw := c.Val
 	c.IgnoreCase = strings.HasSuffix(raw, "i")
@@ -1053,6 +1267,43 @@ func (c *CodeBlock) InitialNames() map[string]struct{} {
 	panic("InitialNames should not be called on the CodeBlock")
 }
 
+// Comment represents a comment, either a "//" comment up to the end of
+// the line or a "/* */" comment.
+type Comment struct {
+	posValue
+}
+
+var _ Expression = (*Comment)(nil)
+
+// NewComment creates a new comment at the specified position and with
+// the specified value. The value includes the comment markers.
+func NewComment(p Pos, text string) *Comment {
+	return &Comment{posValue{p: p, Val: text}}
+}
+
+// Pos returns the starting position of the node.
+func (c *Comment) Pos() Pos { return c.p }
+
+// String returns the textual representation of a node.
+func (c *Comment) String() string {
+	return fmt.Sprintf("%s: %T{Val: %q}", c.p, c, c.Val)
+}
+
+// NullableVisit recursively determines whether an object is nullable.
+func (c *Comment) NullableVisit(rules map[string]*Rule) bool {
+	panic("NullableVisit should not be called on the Comment")
+}
+
+// IsNullable returns the nullable attribute of the node.
+func (c *Comment) IsNullable() bool {
+	panic("IsNullable should not be called on the Comment")
+}
+
+// InitialNames returns names of nodes with which an expression can begin.
+func (c *Comment) InitialNames() map[string]struct{} {
+	panic("InitialNames should not be called on the Comment")
+}
+
 // Identifier represents an identifier.
 type Identifier struct {
 	posValue
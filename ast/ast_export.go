@@ -0,0 +1,568 @@
+package ast
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ExportW3CEBNF writes g to w in the EBNF notation of the W3C XML
+// specification. The predicates, the code blocks and the other
+// constructs that the notation cannot express are written as comments.
+func ExportW3CEBNF(w io.Writer, g *Grammar) error {
+	var buf bytes.Buffer
+	for i, r := range g.Rules {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		if doc := r.DocText(); doc != "" {
+			buf.WriteString("/* " + strings.ReplaceAll(strings.TrimSuffix(doc, "\n"), "\n", "\n * ") + " */\n")
+		}
+		head := r.Name.Val + " ::= "
+		for i, alt := range alternatives(r.Expr) {
+			if i > 0 {
+				head = strings.Repeat(" ", utf8.RuneCountInString(head)-2) + "| "
+			}
+			buf.WriteString(head + w3c(alt, precSeq) + "\n")
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// alternatives returns the alternatives of the rule expression expr,
+// without the labels, actions and recovery expressions around them.
+func alternatives(expr Expression) []Expression {
+	if ch, ok := unwrap(expr).(*ChoiceExpr); ok {
+		return ch.Alternatives
+	}
+	return []Expression{expr}
+}
+
+// unwrap returns the expression of the labels, actions and recovery
+// expressions around expr.
+func unwrap(expr Expression) Expression {
+	for {
+		switch e := expr.(type) {
+		case *ActionExpr:
+			expr = e.Expr
+		case *LabeledExpr:
+			expr = e.Expr
+		case *RecoveryExpr:
+			expr = e.Expr
+		default:
+			return expr
+		}
+	}
+}
+
+func w3c(expr Expression, prec int) string {
+	var s string
+	var own int
+	switch expr := unwrap(expr).(type) {
+	case *ChoiceExpr:
+		alts := make([]string, len(expr.Alternatives))
+		for i, alt := range expr.Alternatives {
+			alts[i] = w3c(alt, precSeq)
+		}
+		s, own = strings.Join(alts, " | "), precChoice
+	case *SeqExpr:
+		exprs := make([]string, len(expr.Exprs))
+		for i, e := range expr.Exprs {
+			exprs[i] = w3c(e, precSeq)
+		}
+		s, own = strings.Join(exprs, " "), precSeq
+	case *ZeroOrOneExpr:
+		s, own = w3c(expr.Expr, precPrimary)+"?", precSuffixed
+	case *ZeroOrMoreExpr:
+		s, own = w3c(expr.Expr, precPrimary)+"*", precSuffixed
+	case *OneOrMoreExpr:
+		s, own = w3c(expr.Expr, precPrimary)+"+", precSuffixed
+	case *RuleRefExpr:
+		s, own = expr.Name.Val, precPrimary
+	case *LitMatcher:
+		s, own = w3cString(expr.Val, expr.IgnoreCase)
+	case *CharClassMatcher:
+		s, own = W3CClass(expr), precPrimary
+	case *AnyMatcher:
+		s, own = "[#x0-#x10FFFF]", precPrimary
+	default:
+		// predicates, code blocks, back references, indentation and
+		// throw expressions
+		return pegComment(expr)
+	}
+	if own < prec {
+		return "( " + s + " )"
+	}
+	return s
+}
+
+// w3cString returns the W3C EBNF sequence that matches s. Each letter is
+// written as a character class of its two cases if ignoreCase is set.
+func w3cString(s string, ignoreCase bool) (string, int) {
+	var parts []string
+	var run strings.Builder
+	flush := func() {
+		if run.Len() > 0 {
+			parts = append(parts, `"`+run.String()+`"`)
+			run.Reset()
+		}
+	}
+	for _, r := range s {
+		switch {
+		case ignoreCase && unicode.ToUpper(r) != unicode.ToLower(r):
+			flush()
+			parts = append(parts, "["+w3cChar(unicode.ToLower(r))+w3cChar(unicode.ToUpper(r))+"]")
+		case r == '"':
+			flush()
+			parts = append(parts, `'"'`)
+		case unicode.IsPrint(r):
+			run.WriteRune(r)
+		default:
+			flush()
+			parts = append(parts, fmt.Sprintf("#x%X", r))
+		}
+	}
+	flush()
+	switch len(parts) {
+	case 0:
+		return `""`, precPrimary
+	case 1:
+		return parts[0], precPrimary
+	default:
+		return strings.Join(parts, " "), precSeq
+	}
+}
+
+// W3CClass returns the W3C EBNF character class that matches the same
+// characters as c, e.g. "[a-z#x20]". A class with Unicode classes is
+// approximated by a class that matches more characters.
+func W3CClass(c *CharClassMatcher) string {
+	chars, ranges := c.Chars, c.Ranges
+	if c.IgnoreCase {
+		chars, ranges = foldClass(chars, ranges)
+	}
+	if len(c.UnicodeClasses) > 0 && (!c.Inverted || len(chars)+len(ranges) == 0) {
+		return "[#x0-#x10FFFF] " + pegComment(c)
+	}
+
+	var b strings.Builder
+	b.WriteByte('[')
+	if c.Inverted {
+		b.WriteByte('^')
+	}
+	for _, r := range chars {
+		b.WriteString(w3cChar(r))
+	}
+	for i := 0; i+1 < len(ranges); i += 2 {
+		b.WriteString(w3cChar(ranges[i]) + "-" + w3cChar(ranges[i+1]))
+	}
+	b.WriteByte(']')
+	if len(c.UnicodeClasses) > 0 {
+		b.WriteString(" " + pegComment(c))
+	}
+	return b.String()
+}
+
+// w3cChar returns r as a character of a W3C EBNF character class.
+func w3cChar(r rune) string {
+	if r < utf8.RuneSelf && unicode.IsPrint(r) && !strings.ContainsRune(`]^-#\'" `, r) {
+		return string(r)
+	}
+	return fmt.Sprintf("#x%X", r)
+}
+
+// foldClass returns the characters and ranges of a character class that
+// ignores case, with the other case of the letters added.
+func foldClass(chars, ranges []rune) ([]rune, []rune) {
+	out := append([]rune(nil), chars...)
+	for _, r := range chars {
+		for _, f := range []rune{unicode.ToLower(r), unicode.ToUpper(r)} {
+			if f != r {
+				out = append(out, f)
+			}
+		}
+	}
+	outRanges := append([]rune(nil), ranges...)
+	for i := 0; i+1 < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		switch {
+		case lo >= 'a' && hi <= 'z':
+			outRanges = append(outRanges, lo-'a'+'A', hi-'a'+'A')
+		case lo >= 'A' && hi <= 'Z':
+			outRanges = append(outRanges, lo-'A'+'a', hi-'A'+'a')
+		}
+	}
+	return out, outRanges
+}
+
+// pegComment returns expr as PEG source in a comment, for the constructs
+// that an export format cannot express.
+func pegComment(expr Expression) string {
+	var src string
+	switch expr.(type) {
+	case *AndCodeExpr:
+		src = "&{…}"
+	case *NotCodeExpr:
+		src = "!{…}"
+	case *StateCodeExpr:
+		src = "#{…}"
+	default:
+		src = (&printer{}).expr(expr, precRecovery, 0)
+	}
+	return "/* " + strings.ReplaceAll(src, "*/", "* /") + " */"
+}
+
+// ExportPEGjs writes g to w as a PEG.js grammar. The Go code blocks are
+// left out, or replaced by stubs that return the matched text if stubCode
+// is set. The constructs that PEG.js does not support, such as the state
+// blocks, the back references, the indentation and the throw expressions,
+// are written as comments that match the empty string, and the recovery
+// expressions are left out.
+func ExportPEGjs(w io.Writer, g *Grammar, stubCode bool) error {
+	var buf bytes.Buffer
+	if stubCode && g.Init != nil {
+		buf.WriteString("{\n  // TODO: port the initializer of the Go grammar.\n}\n\n")
+	}
+	for i, r := range g.Rules {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		writeLineComments(&buf, r.DocText(), "")
+		buf.WriteString(r.Name.Val)
+		if r.DisplayName != nil {
+			buf.WriteString(" " + jsQuote(unquoteDisplayName(r.DisplayName.Val)))
+		}
+		buf.WriteByte('\n')
+		for i, alt := range pegjsAlternatives(r.Expr, stubCode) {
+			op := "="
+			if i > 0 {
+				op = "/"
+			}
+			s := pegjs(alt, precAction, stubCode)
+			if s == "" {
+				s = `""`
+			}
+			buf.WriteString("  " + op + " " + s + "\n")
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// pegjsAlternatives returns the alternatives of the rule expression expr,
+// written on separate lines.
+func pegjsAlternatives(expr Expression, stubCode bool) []Expression {
+	if r, ok := expr.(*RecoveryExpr); ok {
+		expr = r.Expr
+	}
+	if a, ok := expr.(*ActionExpr); ok && !stubCode {
+		expr = a.Expr
+	}
+	if ch, ok := expr.(*ChoiceExpr); ok {
+		return ch.Alternatives
+	}
+	return []Expression{expr}
+}
+
+// pegjs returns expr in PEG.js syntax, or an empty string if it is left
+// out.
+func pegjs(expr Expression, prec int, stubCode bool) string {
+	var s string
+	var own int
+	switch expr := expr.(type) {
+	case *RecoveryExpr:
+		return pegjs(expr.Expr, prec, stubCode)
+	case *ChoiceExpr:
+		alts := make([]string, len(expr.Alternatives))
+		for i, alt := range expr.Alternatives {
+			if alts[i] = pegjs(alt, precAction, stubCode); alts[i] == "" {
+				alts[i] = `""`
+			}
+		}
+		s, own = strings.Join(alts, " / "), precChoice
+	case *ActionExpr:
+		if !stubCode {
+			return pegjs(expr.Expr, prec, stubCode)
+		}
+		s, own = pegjs(expr.Expr, precSeq, stubCode), precAction
+		if s == "" {
+			s = `""`
+		}
+		s += " { return text(); }"
+	case *SeqExpr:
+		var exprs []string
+		for _, e := range expr.Exprs {
+			if e := pegjs(e, precLabeled, stubCode); e != "" {
+				exprs = append(exprs, e)
+			}
+		}
+		s, own = strings.Join(exprs, " "), precSeq
+		switch len(exprs) {
+		case 0:
+			return ""
+		case 1:
+			own = precLabeled
+		}
+	case *LabeledExpr:
+		s, own = expr.Label.Val+":"+pegjs(expr.Expr, precPrefixed, stubCode), precLabeled
+	case *AndExpr:
+		s, own = "&"+pegjs(expr.Expr, precSuffixed, stubCode), precPrefixed
+	case *NotExpr:
+		s, own = "!"+pegjs(expr.Expr, precSuffixed, stubCode), precPrefixed
+	case *ZeroOrOneExpr:
+		s, own = pegjs(expr.Expr, precPrimary, stubCode)+"?", precSuffixed
+	case *ZeroOrMoreExpr:
+		s, own = pegjs(expr.Expr, precPrimary, stubCode)+"*", precSuffixed
+	case *OneOrMoreExpr:
+		s, own = pegjs(expr.Expr, precPrimary, stubCode)+"+", precSuffixed
+	case *AndCodeExpr:
+		if !stubCode {
+			return ""
+		}
+		s, own = "&{ return true; }", precPrimary
+	case *NotCodeExpr:
+		if !stubCode {
+			return ""
+		}
+		s, own = "!{ return false; }", precPrimary
+	case *StateCodeExpr:
+		return ""
+	case *RuleRefExpr:
+		s, own = expr.Name.Val, precPrimary
+	case *LitMatcher:
+		s, own = jsQuote(expr.Val), precPrimary
+		if expr.IgnoreCase {
+			s += "i"
+		}
+	case *CharClassMatcher:
+		s, own = pegjsClass(expr), precPrimary
+	case *AnyMatcher:
+		s, own = ".", precPrimary
+	default:
+		// back references, indentation and throw expressions
+		s, own = `"" `+pegComment(expr), precPrimary
+	}
+	if own < prec {
+		return "( " + s + " )"
+	}
+	return s
+}
+
+// pegjsClass returns the PEG.js character class that matches the same
+// characters as c. A class with Unicode classes is approximated by a class
+// that matches more characters.
+func pegjsClass(c *CharClassMatcher) string {
+	if len(c.UnicodeClasses) > 0 && (!c.Inverted || len(c.Chars)+len(c.Ranges) == 0) {
+		return ". " + pegComment(c)
+	}
+	s := "[" + jsClass(c) + "]"
+	if c.IgnoreCase {
+		s += "i"
+	}
+	if len(c.UnicodeClasses) > 0 {
+		s += " " + pegComment(c)
+	}
+	return s
+}
+
+// jsClass returns the inside of the brackets of a JavaScript regular
+// expression character class that matches the characters and ranges of c.
+func jsClass(c *CharClassMatcher) string {
+	var b strings.Builder
+	if c.Inverted {
+		b.WriteByte('^')
+	}
+	for _, r := range c.Chars {
+		b.WriteString(jsClassChar(r))
+	}
+	for i := 0; i+1 < len(c.Ranges); i += 2 {
+		b.WriteString(jsClassChar(c.Ranges[i]) + "-" + jsClassChar(c.Ranges[i+1]))
+	}
+	return b.String()
+}
+
+// jsClassChar returns r as a character of a JavaScript character class.
+func jsClassChar(r rune) string {
+	if strings.ContainsRune(`\]^-[/`, r) {
+		return `\` + string(r)
+	}
+	return jsEscape(r)
+}
+
+// jsQuote returns s as a double-quoted JavaScript string.
+func jsQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		if r == '"' || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteString(jsEscape(r))
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// jsEscape returns r escaped for a JavaScript string or regular
+// expression if it is not printable.
+func jsEscape(r rune) string {
+	switch r {
+	case '\n':
+		return `\n`
+	case '\r':
+		return `\r`
+	case '\t':
+		return `\t`
+	case '\f':
+		return `\f`
+	case '\v':
+		return `\v`
+	}
+	switch {
+	case unicode.IsPrint(r):
+		return string(r)
+	case r < 0x100:
+		return fmt.Sprintf(`\x%02x`, r)
+	case r <= 0xffff:
+		return fmt.Sprintf(`\u%04x`, r)
+	default:
+		r1, r2 := utf16.EncodeRune(r)
+		return fmt.Sprintf(`\u%04x\u%04x`, r1, r2)
+	}
+}
+
+// unquoteDisplayName returns the value of the display name lit, written
+// as a Go string or rune literal.
+func unquoteDisplayName(lit string) string {
+	if s, err := strconv.Unquote(lit); err == nil {
+		return s
+	}
+	return lit
+}
+
+// writeLineComments writes the lines of text to buf as // comments,
+// indented by indent.
+func writeLineComments(buf *bytes.Buffer, text, indent string) {
+	for _, line := range strings.SplitAfter(text, "\n") {
+		switch line {
+		case "":
+		case "\n":
+			buf.WriteString(indent + "//\n")
+		default:
+			buf.WriteString(indent + "// " + line)
+		}
+	}
+}
+
+// ExportTreeSitter writes g to w as the grammar.js skeleton of a
+// tree-sitter grammar named name. The code blocks and the predicates are
+// left out, as tree-sitter does not support them, as well as the state
+// blocks, the back references, the indentation and the throw expressions.
+// The labels are written as fields. As the whitespace is matched by the
+// rules of g, the grammar has no extras.
+func ExportTreeSitter(w io.Writer, g *Grammar, name string) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "module.exports = grammar({\n  name: %s,\n\n  extras: $ => [],\n\n  rules: {\n", jsQuote(name))
+	for i, r := range g.Rules {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		writeLineComments(&buf, r.DocText(), "    ")
+		buf.WriteString("    " + r.Name.Val + ": $ => ")
+
+		if ch, ok := unwrap(r.Expr).(*ChoiceExpr); ok {
+			buf.WriteString("choice(\n")
+			for _, alt := range ch.Alternatives {
+				buf.WriteString("      " + treeSitter(alt) + ",\n")
+			}
+			buf.WriteString("    ),\n")
+			continue
+		}
+		buf.WriteString(treeSitter(r.Expr) + ",\n")
+	}
+	buf.WriteString("  },\n});\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// treeSitter returns expr as a tree-sitter rule, blank() if it is left
+// out.
+func treeSitter(expr Expression) string {
+	if s := treeSitterOpt(expr); s != "" {
+		return s
+	}
+	return "blank()"
+}
+
+// treeSitterOpt returns expr as a tree-sitter rule, or an empty string if
+// it is left out.
+func treeSitterOpt(expr Expression) string {
+	switch expr := expr.(type) {
+	case *ActionExpr:
+		return treeSitterOpt(expr.Expr)
+	case *RecoveryExpr:
+		return treeSitterOpt(expr.Expr)
+	case *ChoiceExpr:
+		alts := make([]string, len(expr.Alternatives))
+		for i, alt := range expr.Alternatives {
+			alts[i] = treeSitter(alt)
+		}
+		return "choice(" + strings.Join(alts, ", ") + ")"
+	case *SeqExpr:
+		var exprs []string
+		for _, e := range expr.Exprs {
+			if e := treeSitterOpt(e); e != "" {
+				exprs = append(exprs, e)
+			}
+		}
+		if len(exprs) < 2 {
+			return strings.Join(exprs, "")
+		}
+		return "seq(" + strings.Join(exprs, ", ") + ")"
+	case *LabeledExpr:
+		return "field(" + jsQuote(expr.Label.Val) + ", " + treeSitter(expr.Expr) + ")"
+	case *ZeroOrOneExpr:
+		return "optional(" + treeSitter(expr.Expr) + ")"
+	case *ZeroOrMoreExpr:
+		return "repeat(" + treeSitter(expr.Expr) + ")"
+	case *OneOrMoreExpr:
+		return "repeat1(" + treeSitter(expr.Expr) + ")"
+	case *RuleRefExpr:
+		return "$." + expr.Name.Val
+	case *LitMatcher:
+		if !expr.IgnoreCase {
+			return jsQuote(expr.Val)
+		}
+		var b strings.Builder
+		for _, r := range expr.Val {
+			if strings.ContainsRune(`\^$.|?*+()[]{}/`, r) {
+				b.WriteByte('\\')
+			}
+			b.WriteString(jsEscape(r))
+		}
+		return "/" + b.String() + "/i"
+	case *CharClassMatcher:
+		var cl strings.Builder
+		cl.WriteString(jsClass(expr))
+		for _, name := range expr.UnicodeClasses {
+			cl.WriteString(`\p{` + name + `}`)
+		}
+		s := "/[" + cl.String() + "]/"
+		if expr.IgnoreCase {
+			s += "i"
+		}
+		return s
+	case *AnyMatcher:
+		return `/[\s\S]/`
+	default:
+		// predicates, code blocks, back references, indentation and
+		// throw expressions
+		return ""
+	}
+}
@@ -0,0 +1,365 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// jsonPos is the JSON representation of a Pos.
+type jsonPos struct {
+	Filename string `json:"filename,omitempty"`
+	Line     int    `json:"line"`
+	Col      int    `json:"col"`
+	Off      int    `json:"off"`
+}
+
+// jsonValue is the JSON representation of the nodes that only have a
+// value: identifiers, string literals, code blocks and comments.
+type jsonValue struct {
+	Pos jsonPos `json:"pos"`
+	Val string  `json:"val"`
+}
+
+// jsonNode is the JSON representation of a rule or an expression. Type
+// identifies the kind of node, and only the fields of this kind are set.
+type jsonNode struct {
+	Type string  `json:"type"`
+	Pos  jsonPos `json:"pos"`
+
+	// rule
+	Name        *jsonValue   `json:"name,omitempty"`
+	DisplayName *jsonValue   `json:"displayName,omitempty"`
+	Doc         []*jsonValue `json:"doc,omitempty"`
+
+	// expressions
+	Val        string      `json:"val,omitempty"`
+	Bytes      []byte      `json:"bytes,omitempty"` // literal that is not valid UTF-8
+	IgnoreCase bool        `json:"ignoreCase,omitempty"`
+	Kind       string      `json:"kind,omitempty"`
+	Label      *jsonValue  `json:"label,omitempty"`
+	Labels     []string    `json:"labels,omitempty"`
+	Code       *jsonValue  `json:"code,omitempty"`
+	Expr       *jsonNode   `json:"expr,omitempty"`
+	Recover    *jsonNode   `json:"recover,omitempty"`
+	Exprs      []*jsonNode `json:"exprs,omitempty"`
+}
+
+// jsonGrammar is the JSON representation of a Grammar.
+type jsonGrammar struct {
+	Pos      jsonPos      `json:"pos"`
+	Init     *jsonValue   `json:"init,omitempty"`
+	Rules    []*jsonNode  `json:"rules"`
+	Comments []*jsonValue `json:"comments,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. The grammar is encoded with the
+// positions of its nodes, so that the decoded grammar is the same as g.
+// The attributes computed by the builder and the optimizer are not
+// encoded.
+func (g *Grammar) MarshalJSON() ([]byte, error) {
+	jg := jsonGrammar{Pos: toJSONPos(g.p), Rules: []*jsonNode{}}
+	if g.Init != nil {
+		jg.Init = toJSONValue(&g.Init.posValue)
+	}
+	for _, r := range g.Rules {
+		n := &jsonNode{
+			Type: "rule",
+			Pos:  toJSONPos(r.p),
+			Name: toJSONValue(&r.Name.posValue),
+			Doc:  toJSONValues(r.Doc),
+		}
+		if r.DisplayName != nil {
+			n.DisplayName = toJSONValue(&r.DisplayName.posValue)
+		}
+		var err error
+		if n.Expr, err = toJSONNode(r.Expr); err != nil {
+			return nil, err
+		}
+		jg.Rules = append(jg.Rules, n)
+	}
+	jg.Comments = toJSONValues(g.Comments)
+	return json.Marshal(jg)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, it decodes a grammar encoded
+// by MarshalJSON.
+func (g *Grammar) UnmarshalJSON(b []byte) error {
+	var jg jsonGrammar
+	if err := json.Unmarshal(b, &jg); err != nil {
+		return err
+	}
+
+	*g = Grammar{p: fromJSONPos(jg.Pos)}
+	if jg.Init != nil {
+		g.Init = NewCodeBlock(fromJSONPos(jg.Init.Pos), jg.Init.Val)
+	}
+	for _, n := range jg.Rules {
+		if n.Type != "rule" || n.Name == nil {
+			return fmt.Errorf("%s: invalid rule", fromJSONPos(n.Pos))
+		}
+		r := NewRule(fromJSONPos(n.Pos), NewIdentifier(fromJSONPos(n.Name.Pos), n.Name.Val))
+		if n.DisplayName != nil {
+			r.DisplayName = NewStringLit(fromJSONPos(n.DisplayName.Pos), n.DisplayName.Val)
+		}
+		for _, c := range n.Doc {
+			r.Doc = append(r.Doc, NewComment(fromJSONPos(c.Pos), c.Val))
+		}
+		expr, err := fromJSONNode(n.Expr)
+		if err != nil {
+			return err
+		}
+		r.Expr = expr
+		g.Rules = append(g.Rules, r)
+	}
+	for _, c := range jg.Comments {
+		g.Comments = append(g.Comments, NewComment(fromJSONPos(c.Pos), c.Val))
+	}
+
+	// the doc comments are also comments of the grammar, share the nodes
+	for _, r := range g.Rules {
+		for i, d := range r.Doc {
+			for _, c := range g.Comments {
+				if c.p == d.p {
+					r.Doc[i] = c
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func toJSONPos(p Pos) jsonPos {
+	return jsonPos{Filename: p.Filename, Line: p.Line, Col: p.Col, Off: p.Off}
+}
+
+func fromJSONPos(p jsonPos) Pos {
+	return Pos{Filename: p.Filename, Line: p.Line, Col: p.Col, Off: p.Off}
+}
+
+func toJSONValue(v *posValue) *jsonValue {
+	return &jsonValue{Pos: toJSONPos(v.p), Val: v.Val}
+}
+
+func toJSONValues(cs []*Comment) []*jsonValue {
+	var vs []*jsonValue
+	for _, c := range cs {
+		vs = append(vs, toJSONValue(&c.posValue))
+	}
+	return vs
+}
+
+func toJSONNode(expr Expression) (*jsonNode, error) {
+	n := &jsonNode{Pos: toJSONPos(expr.Pos())}
+	var err error
+	switch expr := expr.(type) {
+	case *ActionExpr:
+		n.Type, n.Code = "action", toJSONValue(&expr.Code.posValue)
+		n.Expr, err = toJSONNode(expr.Expr)
+	case *AndCodeExpr:
+		n.Type, n.Code = "andCode", toJSONValue(&expr.Code.posValue)
+	case *AndExpr:
+		n.Type = "and"
+		n.Expr, err = toJSONNode(expr.Expr)
+	case *AnyMatcher:
+		n.Type, n.Val = "any", expr.Val
+	case *BackRefExpr:
+		n.Type, n.Label = "backRef", toJSONValue(&expr.Label.posValue)
+	case *CharClassMatcher:
+		n.Type, n.Val = "charClass", expr.Val
+	case *ChoiceExpr:
+		n.Type = "choice"
+		n.Exprs, err = toJSONNodes(expr.Alternatives)
+	case *IndentExpr:
+		n.Type, n.Kind = "indent", expr.Kind.String()
+	case *LabeledExpr:
+		n.Type, n.Label = "labeled", toJSONValue(&expr.Label.posValue)
+		n.Expr, err = toJSONNode(expr.Expr)
+	case *LitMatcher:
+		n.Type, n.Val, n.IgnoreCase = "lit", expr.Val, expr.IgnoreCase
+		if !utf8.ValidString(expr.Val) {
+			n.Val, n.Bytes = "", []byte(expr.Val)
+		}
+	case *NotCodeExpr:
+		n.Type, n.Code = "notCode", toJSONValue(&expr.Code.posValue)
+	case *NotExpr:
+		n.Type = "not"
+		n.Expr, err = toJSONNode(expr.Expr)
+	case *OneOrMoreExpr:
+		n.Type = "oneOrMore"
+		n.Expr, err = toJSONNode(expr.Expr)
+	case *RecoveryExpr:
+		n.Type = "recovery"
+		for _, l := range expr.Labels {
+			n.Labels = append(n.Labels, string(l))
+		}
+		if n.Expr, err = toJSONNode(expr.Expr); err == nil {
+			n.Recover, err = toJSONNode(expr.RecoverExpr)
+		}
+	case *RuleRefExpr:
+		n.Type, n.Name = "ruleRef", toJSONValue(&expr.Name.posValue)
+	case *SeqExpr:
+		n.Type = "seq"
+		n.Exprs, err = toJSONNodes(expr.Exprs)
+	case *StateCodeExpr:
+		n.Type, n.Code = "stateCode", toJSONValue(&expr.Code.posValue)
+	case *ThrowExpr:
+		n.Type, n.Val = "throw", expr.Label
+	case *ZeroOrMoreExpr:
+		n.Type = "zeroOrMore"
+		n.Expr, err = toJSONNode(expr.Expr)
+	case *ZeroOrOneExpr:
+		n.Type = "zeroOrOne"
+		n.Expr, err = toJSONNode(expr.Expr)
+	default:
+		return nil, fmt.Errorf("%s: unknown expression type %T", expr.Pos(), expr)
+	}
+	return n, err
+}
+
+func toJSONNodes(exprs []Expression) ([]*jsonNode, error) {
+	ns := make([]*jsonNode, len(exprs))
+	for i, e := range exprs {
+		n, err := toJSONNode(e)
+		if err != nil {
+			return nil, err
+		}
+		ns[i] = n
+	}
+	return ns, nil
+}
+
+var indentKinds = map[string]IndentKind{
+	IndentKindIndent.String():   IndentKindIndent,
+	IndentKindDedent.String():   IndentKindDedent,
+	IndentKindSamedent.String(): IndentKindSamedent,
+}
+
+func fromJSONNode(n *jsonNode) (Expression, error) {
+	if n == nil {
+		return nil, fmt.Errorf("missing expression")
+	}
+	p := fromJSONPos(n.Pos)
+
+	// check the fields required by the type of node
+	switch n.Type {
+	case "action", "andCode", "notCode", "stateCode":
+		if n.Code == nil {
+			return nil, fmt.Errorf("%s: %s: missing code", p, n.Type)
+		}
+	case "backRef", "labeled":
+		if n.Label == nil {
+			return nil, fmt.Errorf("%s: %s: missing label", p, n.Type)
+		}
+	case "ruleRef":
+		if n.Name == nil {
+			return nil, fmt.Errorf("%s: %s: missing name", p, n.Type)
+		}
+	}
+
+	var err error
+	switch n.Type {
+	case "action":
+		e := NewActionExpr(p)
+		e.Code = NewCodeBlock(fromJSONPos(n.Code.Pos), n.Code.Val)
+		e.Expr, err = fromJSONNode(n.Expr)
+		return e, err
+	case "andCode":
+		e := NewAndCodeExpr(p)
+		e.Code = NewCodeBlock(fromJSONPos(n.Code.Pos), n.Code.Val)
+		return e, nil
+	case "and":
+		e := NewAndExpr(p)
+		e.Expr, err = fromJSONNode(n.Expr)
+		return e, err
+	case "any":
+		return NewAnyMatcher(p, n.Val), nil
+	case "backRef":
+		e := NewBackRefExpr(p)
+		e.Label = NewIdentifier(fromJSONPos(n.Label.Pos), n.Label.Val)
+		return e, nil
+	case "charClass":
+		return NewCharClassMatcher(p, n.Val), nil
+	case "choice":
+		e := NewChoiceExpr(p)
+		e.Alternatives, err = fromJSONNodes(n.Exprs)
+		return e, err
+	case "indent":
+		kind, ok := indentKinds[n.Kind]
+		if !ok {
+			return nil, fmt.Errorf("%s: invalid indent kind %q", p, n.Kind)
+		}
+		return NewIndentExpr(p, kind), nil
+	case "labeled":
+		e := NewLabeledExpr(p)
+		e.Label = NewIdentifier(fromJSONPos(n.Label.Pos), n.Label.Val)
+		e.Expr, err = fromJSONNode(n.Expr)
+		return e, err
+	case "lit":
+		e := NewLitMatcher(p, n.Val)
+		if n.Bytes != nil {
+			e.Val = string(n.Bytes)
+		}
+		e.IgnoreCase = n.IgnoreCase
+		return e, nil
+	case "notCode":
+		e := NewNotCodeExpr(p)
+		e.Code = NewCodeBlock(fromJSONPos(n.Code.Pos), n.Code.Val)
+		return e, nil
+	case "not":
+		e := NewNotExpr(p)
+		e.Expr, err = fromJSONNode(n.Expr)
+		return e, err
+	case "oneOrMore":
+		e := NewOneOrMoreExpr(p)
+		e.Expr, err = fromJSONNode(n.Expr)
+		return e, err
+	case "recovery":
+		e := NewRecoveryExpr(p)
+		for _, l := range n.Labels {
+			e.Labels = append(e.Labels, FailureLabel(l))
+		}
+		if e.Expr, err = fromJSONNode(n.Expr); err == nil {
+			e.RecoverExpr, err = fromJSONNode(n.Recover)
+		}
+		return e, err
+	case "ruleRef":
+		e := NewRuleRefExpr(p)
+		e.Name = NewIdentifier(fromJSONPos(n.Name.Pos), n.Name.Val)
+		return e, nil
+	case "seq":
+		e := NewSeqExpr(p)
+		e.Exprs, err = fromJSONNodes(n.Exprs)
+		return e, err
+	case "stateCode":
+		e := NewStateCodeExpr(p)
+		e.Code = NewCodeBlock(fromJSONPos(n.Code.Pos), n.Code.Val)
+		return e, nil
+	case "throw":
+		e := NewThrowExpr(p)
+		e.Label = n.Val
+		return e, nil
+	case "zeroOrMore":
+		e := NewZeroOrMoreExpr(p)
+		e.Expr, err = fromJSONNode(n.Expr)
+		return e, err
+	case "zeroOrOne":
+		e := NewZeroOrOneExpr(p)
+		e.Expr, err = fromJSONNode(n.Expr)
+		return e, err
+	default:
+		return nil, fmt.Errorf("%s: unknown expression type %q", p, n.Type)
+	}
+}
+
+func fromJSONNodes(ns []*jsonNode) ([]Expression, error) {
+	exprs := make([]Expression, len(ns))
+	for i, n := range ns {
+		e, err := fromJSONNode(n)
+		if err != nil {
+			return nil, err
+		}
+		exprs[i] = e
+	}
+	return exprs, nil
+}
@@ -0,0 +1,54 @@
+package ast
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGrammarJSON(t *testing.T) {
+	g := NewGrammar(Pos{Line: 1, Col: 1})
+	doc := NewComment(Pos{Line: 1, Col: 1}, "// doc")
+	g.Comments = []*Comment{doc}
+	r := NewRule(Pos{Line: 2, Col: 1, Off: 7}, NewIdentifier(Pos{Line: 2, Col: 1, Off: 7}, "A"))
+	r.Doc = []*Comment{doc}
+	lit := NewLitMatcher(Pos{Line: 2, Col: 6, Off: 12}, "\x89a")
+	lit.IgnoreCase = true
+	r.Expr = lit
+	g.Rules = []*Rule{r}
+
+	b, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Grammar
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Rules) != 1 || got.Rules[0].Name.Pos() != r.Name.Pos() {
+		t.Fatalf("want rule A at %s, got %v", r.Name.Pos(), got.Rules)
+	}
+	if gl, ok := got.Rules[0].Expr.(*LitMatcher); !ok || gl.Val != lit.Val || !gl.IgnoreCase || gl.Pos() != lit.Pos() {
+		t.Errorf("want %v, got %v", lit, got.Rules[0].Expr)
+	}
+	if got.Rules[0].Doc[0] != got.Comments[0] {
+		t.Errorf("the doc comment is not shared with the grammar comments")
+	}
+}
+
+func TestGrammarJSONErrors(t *testing.T) {
+	cases := map[string]string{
+		`{"rules": [{"type": "choice"}]}`:                                                              "invalid rule",
+		`{"rules": [{"type": "rule", "name": {"val": "A"}}]}`:                                          "missing expression",
+		`{"rules": [{"type": "rule", "name": {"val": "A"}, "expr": {"type": "x"}}]}`:                   `unknown expression type "x"`,
+		`{"rules": [{"type": "rule", "name": {"val": "A"}, "expr": {"type": "labeled"}}]}`:             "missing label",
+		`{"rules": [{"type": "rule", "name": {"val": "A"}, "expr": {"type": "indent", "kind": "X"}}]}`: `invalid indent kind "X"`,
+	}
+	for in, want := range cases {
+		var g Grammar
+		err := json.Unmarshal([]byte(in), &g)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: want error %q, got %v", in, want, err)
+		}
+	}
+}
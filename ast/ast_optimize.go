@@ -2,8 +2,9 @@ package ast
 
 import (
 	"bytes"
+	"slices"
 	"strconv"
-	"strings"
+	"unicode/utf8"
 )
 
 type grammarOptimizer struct {
@@ -14,6 +15,8 @@ type grammarOptimizer struct {
 	ruleUsedByRules map[string]map[string]struct{}
 	visitor         func(expr Expression) Visitor
 	optimized       bool
+	// the parser matches bytes instead of runes
+	byteMode bool
 }
 
 func newGrammarOptimizer(protectedRules []string) *grammarOptimizer {
@@ -100,7 +103,7 @@ func (r *grammarOptimizer) optimize(expr0 Expression) Visitor {
 				switch {
 				// Combine two LitMatcher to CharClassMatcher
 				// "a" / "b" => [ab]
-				case lok0 && lok1 && len([]rune(l0.Val)) == 1 && len([]rune(l1.Val)) == 1 && l0.IgnoreCase == l1.IgnoreCase:
+				case lok0 && lok1 && r.singleChar(l0.Val) && r.singleChar(l1.Val) && l0.IgnoreCase == l1.IgnoreCase:
 					combined = true
 					cm := CharClassMatcher{
 						Chars:      append([]rune(l0.Val), []rune(l1.Val)...),
@@ -111,14 +114,14 @@ func (r *grammarOptimizer) optimize(expr0 Expression) Visitor {
 
 				// Combine LitMatcher with CharClassMatcher
 				// "a" / [bc] => [abc]
-				case lok0 && cok1 && len([]rune(l0.Val)) == 1 && l0.IgnoreCase == c1.IgnoreCase && !c1.Inverted:
+				case lok0 && cok1 && r.singleChar(l0.Val) && l0.IgnoreCase == c1.IgnoreCase && !c1.Inverted:
 					combined = true
 					c1.Chars = append(c1.Chars, []rune(l0.Val)...)
 					expr.Alternatives[i-1] = c1
 
 				// Combine CharClassMatcher with LitMatcher
 				// [ab] / "c" => [abc]
-				case cok0 && lok1 && len([]rune(l1.Val)) == 1 && c0.IgnoreCase == l1.IgnoreCase && !c0.Inverted:
+				case cok0 && lok1 && r.singleChar(l1.Val) && c0.IgnoreCase == l1.IgnoreCase && !c0.Inverted:
 					combined = true
 					c0.Chars = append(c0.Chars, []rune(l1.Val)...)
 
@@ -229,7 +232,11 @@ func (r *grammarOptimizer) optimizeRules(exprs []Expression) []Expression {
 func (r *grammarOptimizer) optimizeRule(expr Expression) Expression {
 	// Optimize RuleRefExpr
 	if ruleRef, ok := expr.(*RuleRefExpr); ok {
-		if _, ok := r.ruleUsesRules[ruleRef.Name.Val]; !ok {
+		// the memoized rules, the commit points and the precedence tables
+		// are kept, their annotations would be lost once copied
+		rule := r.rules[ruleRef.Name.Val]
+		keep := rule != nil && (rule.Memoized() || rule.HasAnnotation(CommitAnnotation) || rule.HasPrecedence())
+		if _, ok := r.ruleUsesRules[ruleRef.Name.Val]; !ok && !keep {
 			r.optimized = true
 			delete(r.ruleUsedByRules[ruleRef.Name.Val], r.rule)
 			if len(r.ruleUsedByRules[ruleRef.Name.Val]) == 0 {
@@ -286,6 +293,11 @@ func cloneExpr(expr Expression) Expression {
 			FuncIx: expr.FuncIx,
 			p:      expr.p,
 		}
+	case *BackRefExpr:
+		return &BackRefExpr{
+			Label: expr.Label,
+			p:     expr.p,
+		}
 	case *CharClassMatcher:
 		return &CharClassMatcher{
 			Chars:          append([]rune{}, expr.Chars...),
@@ -304,6 +316,11 @@ func cloneExpr(expr Expression) Expression {
 			Alternatives: alts,
 			p:            expr.p,
 		}
+	case *IndentExpr:
+		return &IndentExpr{
+			Kind: expr.Kind,
+			p:    expr.p,
+		}
 	case *LabeledExpr:
 		return &LabeledExpr{
 			Expr:  cloneExpr(expr.Expr),
@@ -409,53 +426,119 @@ func (r *grammarOptimizer) cleanupCharClassMatcher(expr0 Expression) Visitor {
 			chr.UnicodeClasses = nil
 		}
 
-		// Regenerate the content for Val
-		var val bytes.Buffer
-		val.WriteString("[")
-		if chr.Inverted {
-			val.WriteString("^")
-		}
-		for _, c := range chr.Chars {
-			val.WriteString(escapeRune(c))
-		}
-		for i := 0; i < len(chr.Ranges); i += 2 {
-			val.WriteString(escapeRune(chr.Ranges[i]))
-			val.WriteString("-")
-			val.WriteString(escapeRune(chr.Ranges[i+1]))
-		}
-		for _, u := range chr.UnicodeClasses {
-			val.WriteString("\\p" + u)
+		chr.updateVal()
+	}
+	return r
+}
+
+// updateVal regenerates Val, the source of the class, from its chars,
+// ranges and Unicode classes.
+func (c *CharClassMatcher) updateVal() {
+	// A '-' is only a char and not a range as the first char of Val
+	if i := slices.Index(c.Chars, '-'); i > 0 {
+		copy(c.Chars[1:i+1], c.Chars[:i])
+		c.Chars[0] = '-'
+	}
+
+	var val bytes.Buffer
+	val.WriteString("[")
+	if c.Inverted {
+		val.WriteString("^")
+	}
+	for i, r := range c.Chars {
+		if r == '^' && i == 0 && !c.Inverted {
+			// would be an inversion
+			val.WriteString(`\x5e`)
+			continue
 		}
-		val.WriteString("]")
-		if chr.IgnoreCase {
-			val.WriteString("i")
+		val.WriteString(escapeRune(r))
+	}
+	for i := 0; i < len(c.Ranges); i += 2 {
+		if c.Ranges[i] == '^' && i == 0 && len(c.Chars) == 0 && !c.Inverted {
+			val.WriteString(`\x5e-`)
+			val.WriteString(escapeRune(c.Ranges[i+1]))
+			continue
+		}
+		val.WriteString(escapeRune(c.Ranges[i]))
+		val.WriteString("-")
+		val.WriteString(escapeRune(c.Ranges[i+1]))
+	}
+	for _, u := range c.UnicodeClasses {
+		if len(u) == 1 {
+			val.WriteString(`\p` + u)
+		} else {
+			val.WriteString(`\p{` + u + `}`)
 		}
-		chr.posValue.Val = val.String()
 	}
-	return r
+	val.WriteString("]")
+	if c.IgnoreCase {
+		val.WriteString("i")
+	}
+	c.Val = val.String()
+}
+
+// singleChar returns true if the literal s can be combined into a
+// character class: s is a single character, and an ASCII one in byte
+// mode, as the other characters are several bytes in UTF-8.
+func (r *grammarOptimizer) singleChar(s string) bool {
+	if r.byteMode {
+		return len(s) == 1 && s[0] < utf8.RuneSelf
+	}
+	return utf8.RuneCountInString(s) == 1
 }
 
+// escapeRune returns r escaped for use in the source of a character class.
 func escapeRune(r rune) string {
-	return strings.Trim(strconv.QuoteRune(r), `'`)
+	switch r {
+	case ']':
+		return `\]`
+	case '\'':
+		return "'"
+	}
+	q := strconv.QuoteRune(r)
+	return q[1 : len(q)-1]
 }
 
 // Optimize walks a given grammar and optimizes the grammar in regards
 // of parsing performance. This is done with several optimizations:
 //   - removal of unreferenced rules
 //   - replace rule references with a copy of the referenced Rule, if the
-//     referenced rule it self has no references.
+//     referenced rule it self has no references, is not memoized, is not a
+//     commit point and does not declare a precedence table.
 //   - resolve nested choice expressions
 //   - resolve choice expressions with only one alternative
 //   - resolve nested sequences expression
 //   - resolve sequence expressions with only one element
 //   - combine character class matcher and literal matcher, where possible
+//
+// The order of the alternatives of the choices is kept, see
+// ReorderChoices to reorder them by the statistics of the parser.
 func Optimize(g *Grammar, alternateEntrypoints ...string) {
+	optimize(g, false, alternateEntrypoints)
+}
+
+// OptimizeBytes optimizes the grammar g as Optimize, for a parser that
+// matches bytes instead of runes: only the literals of a single ASCII
+// character are combined into character classes.
+func OptimizeBytes(g *Grammar, alternateEntrypoints ...string) {
+	optimize(g, true, alternateEntrypoints)
+}
+
+func optimize(g *Grammar, byteMode bool, alternateEntrypoints []string) {
 	entrypoints := alternateEntrypoints
 	if len(g.Rules) > 0 {
 		entrypoints = append(entrypoints, g.Rules[0].Name.Val)
 	}
+	// the rules skipped around the operators of the precedence tables are
+	// only referenced by the annotations
+	for _, rule := range g.Rules {
+		if table, err := rule.Precedence(); err == nil && table != nil && table.Skip != "" {
+			entrypoints = append(entrypoints, table.Skip)
+		}
+	}
 
 	r := newGrammarOptimizer(entrypoints)
+	r.byteMode = byteMode
 	Walk(r, g)
 
 	r.visitor = r.optimize
@@ -2,6 +2,7 @@ package ast
 
 import (
 	"reflect"
+	"slices"
 	"testing"
 	// "github.com/pmezard/go-difflib/difflib"
 	// goon "github.com/shurcooL/go-goon"
@@ -360,3 +361,139 @@ func TestOptimize(t *testing.T) {
 		}
 	}
 }
+
+func TestOptimizeBytes(t *testing.T) {
+	grammar := func() *Grammar {
+		r := NewRule(Pos{}, NewIdentifier(Pos{}, "A"))
+		r.Expr = &ChoiceExpr{Alternatives: []Expression{
+			NewLitMatcher(Pos{}, "é"), NewLitMatcher(Pos{}, "è"), NewLitMatcher(Pos{}, "a"), NewLitMatcher(Pos{}, "b"),
+		}}
+		return &Grammar{Rules: []*Rule{r}}
+	}
+
+	// the runes are combined into a class
+	g := grammar()
+	Optimize(g)
+	if cl, ok := g.Rules[0].Expr.(*CharClassMatcher); !ok || cl.Val != "[éèab]" {
+		t.Errorf("want the class [éèab], got %#v", g.Rules[0].Expr)
+	}
+
+	// only the ASCII bytes are combined in byte mode
+	g = grammar()
+	OptimizeBytes(g)
+	ch, ok := g.Rules[0].Expr.(*ChoiceExpr)
+	if !ok || len(ch.Alternatives) != 3 {
+		t.Fatalf("want a choice of 3 alternatives, got %#v", g.Rules[0].Expr)
+	}
+	if cl, ok := ch.Alternatives[2].(*CharClassMatcher); !ok || cl.Val != "[ab]" {
+		t.Errorf("want the class [ab], got %#v", ch.Alternatives[2])
+	}
+}
+
+func TestCleanupCharClassMatcherVal(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{`[+-]`, `[-+]`},
+		{`[-+]`, `[-+]`},
+		{`[+a-z-]`, `[-+a-z]`},
+		{`[\x5ea]`, `[\x5ea]`},
+		{`[^^]`, `[^^]`},
+		{`[\]']`, `[\]']`},
+		{`[\x00-\x1f]`, `[\x00-\x1f]`},
+		{`[\pL\p{Greek}]i`, `[\pL\p{Greek}]i`},
+	}
+	r := newGrammarOptimizer(nil)
+	for _, tc := range cases {
+		cc := NewCharClassMatcher(Pos{}, tc.in)
+		r.cleanupCharClassMatcher(cc)
+		if cc.Val != tc.want {
+			t.Errorf("%s: want %s, got %s", tc.in, tc.want, cc.Val)
+			continue
+		}
+
+		// the regenerated value must parse to the same matcher
+		got := NewCharClassMatcher(Pos{}, cc.Val)
+		if !slices.Equal(got.Chars, cc.Chars) || !slices.Equal(got.Ranges, cc.Ranges) ||
+			!slices.Equal(got.UnicodeClasses, cc.UnicodeClasses) {
+			t.Errorf("%s: %s does not round-trip", tc.in, cc.Val)
+		}
+	}
+}
+
+func TestOptimizeMemoized(t *testing.T) {
+	ref := func(name string) *RuleRefExpr {
+		return &RuleRefExpr{Name: NewIdentifier(Pos{}, name)}
+	}
+	rule := func(name string, expr Expression) *Rule {
+		r := NewRule(Pos{}, NewIdentifier(Pos{}, name))
+		r.Expr = expr
+		return r
+	}
+	memo := rule("B", &LitMatcher{posValue: posValue{Val: "b"}})
+	memo.Memoize = true
+	g := &Grammar{Rules: []*Rule{
+		rule("A", &SeqExpr{Exprs: []Expression{ref("B"), ref("C")}}),
+		memo,
+		rule("C", &LitMatcher{posValue: posValue{Val: "c"}}),
+	}}
+	Optimize(g)
+
+	if len(g.Rules) != 2 || g.Rules[1] != memo {
+		t.Fatalf("want the rules A and B, got %d rules", len(g.Rules))
+	}
+	seq, ok := g.Rules[0].Expr.(*SeqExpr)
+	if !ok || len(seq.Exprs) != 2 {
+		t.Fatalf("want a sequence of 2 expressions, got %#v", g.Rules[0].Expr)
+	}
+	if r, ok := seq.Exprs[0].(*RuleRefExpr); !ok || r.Name.Val != "B" {
+		t.Errorf("want a reference to the memoized rule B, got %#v", seq.Exprs[0])
+	}
+	if l, ok := seq.Exprs[1].(*LitMatcher); !ok || l.Val != "c" {
+		t.Errorf("want the rule C inlined, got %#v", seq.Exprs[1])
+	}
+}
+
+func TestOptimizeCommit(t *testing.T) {
+	commit := NewRule(Pos{}, NewIdentifier(Pos{}, "B"))
+	commit.Expr = &LitMatcher{posValue: posValue{Val: "b"}}
+	commit.Doc = []*Comment{NewComment(Pos{}, "// @commit")}
+	a := NewRule(Pos{}, NewIdentifier(Pos{}, "A"))
+	a.Expr = &RuleRefExpr{Name: NewIdentifier(Pos{}, "B")}
+	g := &Grammar{Rules: []*Rule{a, commit}}
+	Optimize(g)
+
+	if len(g.Rules) != 2 || g.Rules[1] != commit {
+		t.Fatalf("want the rules A and B, got %d rules", len(g.Rules))
+	}
+	if r, ok := g.Rules[0].Expr.(*RuleRefExpr); !ok || r.Name.Val != "B" {
+		t.Errorf("want a reference to the commit rule B, got %#v", g.Rules[0].Expr)
+	}
+}
+
+func TestOptimizePrecedence(t *testing.T) {
+	ref := func(name string) *RuleRefExpr {
+		return &RuleRefExpr{Name: NewIdentifier(Pos{}, name)}
+	}
+	rule := func(name string, expr Expression) *Rule {
+		r := NewRule(Pos{}, NewIdentifier(Pos{}, name))
+		r.Expr = expr
+		return r
+	}
+	expr := rule("Expr", &ActionExpr{Expr: &LitMatcher{posValue: posValue{Val: "1"}}})
+	expr.Doc = []*Comment{NewComment(Pos{}, `// @left "+"`), NewComment(Pos{}, "// @skip S")}
+	g := &Grammar{Rules: []*Rule{
+		rule("A", ref("Expr")),
+		expr,
+		rule("S", &LitMatcher{posValue: posValue{Val: " "}}),
+	}}
+	Optimize(g)
+
+	if len(g.Rules) != 3 || g.Rules[1] != expr || g.Rules[2].Name.Val != "S" {
+		t.Fatalf("want the rules A, Expr and S, got %d rules", len(g.Rules))
+	}
+	if r, ok := g.Rules[0].Expr.(*RuleRefExpr); !ok || r.Name.Val != "Expr" {
+		t.Errorf("want a reference to the rule Expr, got %#v", g.Rules[0].Expr)
+	}
+}
@@ -0,0 +1,157 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Annotations of the precedence tables, on the lines of the doc comments
+// of a rule: each operator annotation is a level of the table, from the
+// lowest to the highest precedence, followed by its quoted operators.
+const (
+	// LeftAnnotation declares left-associative infix operators.
+	LeftAnnotation = "@left"
+	// RightAnnotation declares right-associative infix operators.
+	RightAnnotation = "@right"
+	// NonAssocAnnotation declares infix operators that do not associate.
+	NonAssocAnnotation = "@nonassoc"
+	// PrefixAnnotation declares prefix operators.
+	PrefixAnnotation = "@prefix"
+	// PostfixAnnotation declares postfix operators.
+	PostfixAnnotation = "@postfix"
+	// SkipAnnotation names the rule matched around the operators, e.g. the
+	// whitespace.
+	SkipAnnotation = "@skip"
+)
+
+// OperatorKind is the kind of the operators of a level of a precedence
+// table.
+type OperatorKind int
+
+// List of operator kinds.
+const (
+	InfixLeft OperatorKind = iota
+	InfixRight
+	InfixNonAssoc
+	Prefix
+	Postfix
+)
+
+var operatorAnnotations = map[string]OperatorKind{
+	LeftAnnotation:     InfixLeft,
+	RightAnnotation:    InfixRight,
+	NonAssocAnnotation: InfixNonAssoc,
+	PrefixAnnotation:   Prefix,
+	PostfixAnnotation:  Postfix,
+}
+
+// Infix returns true if the operators of kind k are infix operators.
+func (k OperatorKind) Infix() bool {
+	return k == InfixLeft || k == InfixRight || k == InfixNonAssoc
+}
+
+// PrecedenceLevel is a level of a precedence table, with its operators.
+type PrecedenceLevel struct {
+	Kind      OperatorKind
+	Operators []string
+}
+
+// PrecedenceTable is the table of the operators declared by the
+// annotations of a rule, whose expression matches the operands.
+type PrecedenceTable struct {
+	// Levels are the levels of the operators, from the lowest to the
+	// highest precedence.
+	Levels []PrecedenceLevel
+	// Skip is the name of the rule matched before and after the
+	// operators, if set.
+	Skip string
+}
+
+// HasPrecedence returns true if the doc comments of the rule declare a
+// precedence table, see Precedence.
+func (r *Rule) HasPrecedence() bool {
+	for _, l := range strings.Split(r.DocText(), "\n") {
+		if f := strings.Fields(l); len(f) > 0 {
+			if _, ok := operatorAnnotations[f[0]]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Precedence returns the precedence table declared by the annotations of
+// the doc comments of the rule, or nil if it declares none. E.g.:
+//
+//	// @left "+" "-"
+//	// @left "*" "/"
+//	// @right "^"
+//	// @prefix "-"
+//	// @skip _
+//	Expr = Primary { ... }
+//
+// The expression of the rule is the operand of the operators, and must
+// have a code block: instead of the operand, the code block combines the
+// operators with their operands, as labeled by op, left and right. The
+// left operand of the prefix operators and the right one of the postfix
+// operators are nil.
+func (r *Rule) Precedence() (*PrecedenceTable, error) {
+	if !r.HasPrecedence() {
+		return nil, nil
+	}
+	if _, ok := r.Expr.(*ActionExpr); !ok {
+		return nil, fmt.Errorf("%s: rule %s: the operand of a precedence table must have a code block", r.Pos(), r.Name.Val)
+	}
+
+	var (
+		table PrecedenceTable
+		seen  = make(map[string]bool)
+	)
+	for _, l := range strings.Split(r.DocText(), "\n") {
+		f := strings.Fields(l)
+		if len(f) == 0 {
+			continue
+		}
+		if f[0] == SkipAnnotation {
+			if len(f) != 2 || table.Skip != "" {
+				return nil, fmt.Errorf("%s: rule %s: want a single %s rule", r.Pos(), r.Name.Val, SkipAnnotation)
+			}
+			table.Skip = f[1]
+			continue
+		}
+		kind, ok := operatorAnnotations[f[0]]
+		if !ok {
+			continue
+		}
+
+		level := PrecedenceLevel{Kind: kind}
+		rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(l), f[0]))
+		for rest != "" {
+			q, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return nil, fmt.Errorf("%s: rule %s: invalid operator: %s", r.Pos(), r.Name.Val, rest)
+			}
+			op, _ := strconv.Unquote(q)
+			if op == "" {
+				return nil, fmt.Errorf("%s: rule %s: empty operator", r.Pos(), r.Name.Val)
+			}
+			// the operators of a position must be distinct
+			key := op
+			if kind.Infix() || kind == Postfix {
+				key = "after " + op
+			}
+			if seen[key] {
+				return nil, fmt.Errorf("%s: rule %s: duplicate operator %q", r.Pos(), r.Name.Val, op)
+			}
+			seen[key] = true
+			level.Operators = append(level.Operators, op)
+			rest = strings.TrimSpace(rest[len(q):])
+		}
+		if len(level.Operators) == 0 {
+			return nil, fmt.Errorf("%s: rule %s: %s without operator", r.Pos(), r.Name.Val, f[0])
+		}
+		table.Levels = append(table.Levels, level)
+	}
+	return &table, nil
+}
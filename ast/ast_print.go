@@ -0,0 +1,423 @@
+package ast
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// PrintConfig controls the output of Fprint.
+type PrintConfig struct {
+	// RuleDefOp is the operator written between the name of a rule and
+	// its expression, one of "=", "<-", "←" or "⟵". The default is "←".
+	RuleDefOp string
+
+	// LineLen is the length over which the alternatives of the choice
+	// expression of a rule are written on separate lines. The default
+	// is 100.
+	LineLen int
+}
+
+// Fprint writes the grammar g to w as canonical PEG source, that parses
+// back to the same grammar. A nil cfg uses the default configuration.
+//
+// The comments of the grammar are written before the rule or the
+// alternative that follows them, or at the end of the line if they were
+// at the end of a line of the source. Code blocks are re-indented
+// relative to the line they start on, unless they contain a raw string
+// literal.
+func Fprint(w io.Writer, g *Grammar, cfg *PrintConfig) error {
+	p := &printer{comments: g.Comments}
+	if cfg != nil {
+		p.cfg = *cfg
+	}
+	switch p.cfg.RuleDefOp {
+	case "=", "<-", "←", "⟵":
+	case "":
+		p.cfg.RuleDefOp = "←"
+	default:
+		return fmt.Errorf("invalid rule definition operator %q", p.cfg.RuleDefOp)
+	}
+	if p.cfg.LineLen <= 0 {
+		p.cfg.LineLen = 100
+	}
+
+	p.grammar(g)
+	_, err := w.Write(p.buf.Bytes())
+	return err
+}
+
+// precedence levels of the expressions, an expression must be enclosed
+// in parentheses when it appears where a higher level is expected.
+const (
+	precRecovery = iota
+	precChoice
+	precAction
+	precSeq
+	precLabeled
+	precPrefixed
+	precSuffixed
+	precPrimary
+)
+
+type printer struct {
+	cfg PrintConfig
+	buf bytes.Buffer
+
+	// comments not yet written, in source order
+	comments []*Comment
+	// last source line of what has been written
+	lastLine int
+	// the current line of the output is not terminated
+	lineOpen bool
+	// a blank line must precede the next line written
+	blank bool
+}
+
+func (p *printer) grammar(g *Grammar) {
+	if g.Init != nil {
+		p.flush(g.Init.Pos(), 0)
+		p.buf.WriteString(formatCode(g.Init.Val, 0, 0))
+		p.lastLine = codeEndLine(g.Init)
+		p.lineOpen = true
+		p.blank = true
+	}
+
+	for _, r := range g.Rules {
+		// the rule starts before the end of the previous one in the source
+		// if the previous one contains inlined expressions, e.g. in an
+		// optimized grammar
+		if p.lineOpen && r.Pos().Line <= p.lastLine {
+			p.blank = true
+		}
+		p.flush(r.Pos(), 0)
+		if p.rule(r) {
+			p.blank = true
+		}
+		p.lastLine = endLine(r.Expr)
+		p.lineOpen = true
+	}
+
+	p.writeComments(-1, 0)
+	if p.lineOpen {
+		p.buf.WriteByte('\n')
+	}
+}
+
+// flush writes the comments that precede pos and starts the line of pos,
+// indented by indent.
+func (p *printer) flush(pos Pos, indent int) {
+	p.writeComments(pos.Off, indent)
+	p.startLine(pos.Line, indent)
+}
+
+// writeComments writes the comments that precede the offset off, indented
+// by indent. An offset of -1 writes all the comments.
+func (p *printer) writeComments(off, indent int) {
+	for len(p.comments) > 0 && (off < 0 || p.comments[0].Pos().Off < off) {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+
+		if p.lineOpen && c.Pos().Line == p.lastLine {
+			p.buf.WriteString(" " + c.Val)
+		} else {
+			p.startLine(c.Pos().Line, indent)
+			p.buf.WriteString(c.Val)
+		}
+		p.lastLine = c.Pos().Line + strings.Count(c.Val, "\n")
+		p.lineOpen = true
+	}
+}
+
+// startLine terminates the current line and starts a new one at the
+// source line, indented by indent.
+func (p *printer) startLine(line, indent int) {
+	if p.lineOpen {
+		p.buf.WriteByte('\n')
+		if p.blank || line > p.lastLine+1 {
+			p.buf.WriteByte('\n')
+		}
+	}
+	p.blank = false
+	p.lineOpen = false
+	p.buf.WriteString(strings.Repeat(" ", indent))
+}
+
+// rule writes the rule r and returns true if it spans multiple lines.
+func (p *printer) rule(r *Rule) bool {
+	head := r.Name.Val
+	if r.DisplayName != nil {
+		head += " " + r.DisplayName.Val
+	}
+	align := utf8.RuneCountInString(head) + 1
+	head += " " + p.cfg.RuleDefOp + " "
+
+	alts := []Expression{r.Expr}
+	prec := precRecovery
+	if ch, ok := r.Expr.(*ChoiceExpr); ok && len(ch.Alternatives) > 1 {
+		alts = ch.Alternatives
+		prec = precAction
+	}
+
+	parts := make([]string, len(alts))
+	oneLine := len(head)
+	for i, alt := range alts {
+		parts[i] = p.expr(alt, prec, 0)
+		oneLine += len(parts[i]) + 3
+		if strings.Contains(parts[i], "\n") {
+			oneLine += p.cfg.LineLen
+		}
+	}
+	inner := len(alts) > 1 && len(p.comments) > 0 &&
+		p.comments[0].Pos().Off < alts[len(alts)-1].Pos().Off
+	if len(alts) == 1 || (oneLine-3 <= p.cfg.LineLen && !inner) {
+		s := head + strings.Join(parts, " / ")
+		p.buf.WriteString(s)
+		return strings.Contains(s, "\n")
+	}
+
+	p.buf.WriteString(head + parts[0])
+	for i, alt := range alts[1:] {
+		p.lastLine = endLine(alts[i])
+		p.lineOpen = true
+		p.flush(alt.Pos(), align)
+		p.buf.WriteString("/ " + p.expr(alt, precAction, align))
+	}
+	return true
+}
+
+// expr returns the source of expr, where an expression of precedence
+// prec is expected. Multi-line code blocks are indented relative to
+// indent, the indentation of the line where expr starts.
+func (p *printer) expr(expr Expression, prec, indent int) string {
+	var s string
+	var own int
+	switch expr := expr.(type) {
+	case *RecoveryExpr:
+		own = precRecovery
+		labels := make([]string, len(expr.Labels))
+		for i, l := range expr.Labels {
+			labels[i] = string(l)
+		}
+		s = p.expr(expr.Expr, precRecovery, indent) + " //{" + strings.Join(labels, ", ") + "} " +
+			p.expr(expr.RecoverExpr, precChoice, indent)
+
+	case *ChoiceExpr:
+		own = precChoice
+		if len(expr.Alternatives) == 1 {
+			return p.expr(expr.Alternatives[0], prec, indent)
+		}
+		parts := make([]string, len(expr.Alternatives))
+		for i, alt := range expr.Alternatives {
+			parts[i] = p.expr(alt, precAction, indent)
+		}
+		s = strings.Join(parts, " / ")
+
+	case *ActionExpr:
+		own = precAction
+		s = p.expr(expr.Expr, precSeq, indent) + " " + formatCode(expr.Code.Val, indent, 4)
+
+	case *SeqExpr:
+		own = precSeq
+		if len(expr.Exprs) == 1 {
+			return p.expr(expr.Exprs[0], prec, indent)
+		}
+		parts := make([]string, len(expr.Exprs))
+		for i, e := range expr.Exprs {
+			parts[i] = p.expr(e, precLabeled, indent)
+		}
+		s = strings.Join(parts, " ")
+
+	case *LabeledExpr:
+		if expr.Label == nil {
+			return p.expr(expr.Expr, prec, indent)
+		}
+		own = precLabeled
+		s = expr.Label.Val + ":" + p.expr(expr.Expr, precPrefixed, indent)
+
+	case *ThrowExpr:
+		own = precLabeled
+		s = "%{" + expr.Label + "}"
+
+	case *AndExpr:
+		own = precPrefixed
+		s = "&" + p.expr(expr.Expr, precSuffixed, indent)
+	case *NotExpr:
+		own = precPrefixed
+		s = "!" + p.expr(expr.Expr, precSuffixed, indent)
+
+	case *ZeroOrOneExpr:
+		own = precSuffixed
+		s = p.expr(expr.Expr, precPrimary, indent) + "?"
+	case *ZeroOrMoreExpr:
+		own = precSuffixed
+		s = p.expr(expr.Expr, precPrimary, indent) + "*"
+	case *OneOrMoreExpr:
+		own = precSuffixed
+		s = p.expr(expr.Expr, precPrimary, indent) + "+"
+
+	default:
+		own = precPrimary
+		s = p.primary(expr, indent)
+	}
+
+	if own < prec {
+		return "( " + s + " )"
+	}
+	return s
+}
+
+func (p *printer) primary(expr Expression, indent int) string {
+	switch expr := expr.(type) {
+	case *LitMatcher:
+		s := strconv.Quote(expr.Val)
+		// an invalid UTF-8 byte would be quoted as U+FFFD
+		if utf8.RuneCountInString(expr.Val) == 1 && utf8.ValidString(expr.Val) {
+			s = strconv.QuoteRune([]rune(expr.Val)[0])
+		}
+		if expr.IgnoreCase {
+			s += "i"
+		}
+		return s
+	case *CharClassMatcher:
+		return expr.Val
+	case *AnyMatcher:
+		return "."
+	case *RuleRefExpr:
+		return expr.Name.Val
+	case *BackRefExpr:
+		return "\\" + expr.Label.Val
+	case *IndentExpr:
+		return "%" + expr.Kind.String()
+	case *AndCodeExpr:
+		return "&" + formatCode(expr.Code.Val, indent, 4)
+	case *NotCodeExpr:
+		return "!" + formatCode(expr.Code.Val, indent, 4)
+	case *StateCodeExpr:
+		return "#" + formatCode(expr.Code.Val, indent, 4)
+	default:
+		panic(fmt.Sprintf("unknown expression type %T", expr))
+	}
+}
+
+// formatCode returns the code block code, with the braces. A single-line
+// block has a space inside each brace. The lines of a multi-line block are
+// indented by indent+body spaces after removing their common indentation,
+// and the closing brace is indented by indent. Code with a raw string literal
+// is returned unchanged, as the indentation may be part of the literal.
+func formatCode(code string, indent, body int) string {
+	inner := code[1 : len(code)-1]
+	if !strings.Contains(inner, "\n") {
+		inner = strings.TrimSpace(inner)
+		if inner == "" {
+			return "{}"
+		}
+		return "{ " + inner + " }"
+	}
+	if strings.Contains(inner, "`") {
+		return code
+	}
+
+	lines := strings.Split(inner, "\n")
+	if strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	common := -1
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		if w := leadingWidth(l); common < 0 || w < common {
+			common = w
+		}
+	}
+
+	var buf strings.Builder
+	buf.WriteString("{\n")
+	for _, l := range lines {
+		if strings.TrimSpace(l) != "" {
+			buf.WriteString(strings.Repeat(" ", indent+body))
+			buf.WriteString(strings.TrimRight(trimIndent(l, common), " \t\r"))
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString(strings.Repeat(" ", indent) + "}")
+	return buf.String()
+}
+
+// leadingWidth returns the width of the leading whitespace of s, with
+// tabs advancing to the next multiple of 4.
+func leadingWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		switch r {
+		case ' ':
+			w++
+		case '\t':
+			w += 4 - w%4
+		default:
+			return w
+		}
+	}
+	return w
+}
+
+// trimIndent removes n columns of leading whitespace from s, a tab that
+// spans past n columns is replaced by the spaces that remain.
+func trimIndent(s string, n int) string {
+	w := 0
+	for i, r := range s {
+		if w >= n {
+			return s[i:]
+		}
+		switch r {
+		case ' ':
+			w++
+		case '\t':
+			w += 4 - w%4
+		default:
+			return s[i:]
+		}
+		if w > n {
+			return strings.Repeat(" ", w-n) + s[i+1:]
+		}
+	}
+	return ""
+}
+
+// endLine returns the last source line of expr.
+func endLine(expr Expression) int {
+	line := expr.Pos().Line
+	Inspect(expr, func(e Expression) bool {
+		var code *CodeBlock
+		switch e := e.(type) {
+		case nil:
+			return false
+		case *ActionExpr:
+			code = e.Code
+		case *AndCodeExpr:
+			code = e.Code
+		case *NotCodeExpr:
+			code = e.Code
+		case *StateCodeExpr:
+			code = e.Code
+		}
+		line = max(line, e.Pos().Line)
+		if code != nil {
+			line = max(line, codeEndLine(code))
+		}
+		return true
+	})
+	return line
+}
+
+func codeEndLine(code *CodeBlock) int {
+	return code.Pos().Line + strings.Count(code.Val, "\n")
+}
@@ -0,0 +1,152 @@
+package ast
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFprintExpr(t *testing.T) {
+	lit := func(v string) Expression { return NewLitMatcher(Pos{}, v) }
+	ref := func(nm string) Expression {
+		r := NewRuleRefExpr(Pos{})
+		r.Name = NewIdentifier(Pos{}, nm)
+		return r
+	}
+	choice := func(alts ...Expression) Expression {
+		ch := NewChoiceExpr(Pos{})
+		ch.Alternatives = alts
+		return ch
+	}
+	seq := func(exprs ...Expression) Expression {
+		s := NewSeqExpr(Pos{})
+		s.Exprs = exprs
+		return s
+	}
+	star := func(e Expression) Expression {
+		z := NewZeroOrMoreExpr(Pos{})
+		z.Expr = e
+		return z
+	}
+	not := func(e Expression) Expression {
+		n := NewNotExpr(Pos{})
+		n.Expr = e
+		return n
+	}
+	label := func(nm string, e Expression) Expression {
+		l := NewLabeledExpr(Pos{})
+		l.Label = NewIdentifier(Pos{}, nm)
+		l.Expr = e
+		return l
+	}
+	action := func(e Expression, code string) Expression {
+		a := NewActionExpr(Pos{})
+		a.Expr = e
+		a.Code = NewCodeBlock(Pos{}, code)
+		return a
+	}
+	ignoreCase := NewLitMatcher(Pos{}, "ab")
+	ignoreCase.IgnoreCase = true
+	recovery := NewRecoveryExpr(Pos{})
+	recovery.Expr = ref("A")
+	recovery.RecoverExpr = choice(ref("B"), ref("C"))
+	recovery.Labels = []FailureLabel{"x", "y"}
+	throw := NewThrowExpr(Pos{})
+	throw.Label = "x"
+
+	cases := []struct {
+		expr Expression
+		want string
+	}{
+		{lit("a"), `R ← 'a'`},
+		{lit("'"), `R ← '\''`},
+		{lit("ab\n"), `R ← "ab\n"`},
+		{lit("é"), `R ← 'é'`},
+		{lit("\xff"), `R ← "\xff"`},
+		{ignoreCase, `R ← "ab"i`},
+		{NewCharClassMatcher(Pos{}, "[a-z]i"), `R ← [a-z]i`},
+		{NewAnyMatcher(Pos{}, "."), `R ← .`},
+		{choice(ref("A"), seq(ref("B"), ref("C"))), `R ← A / B C`},
+		{seq(ref("A"), choice(ref("B"), ref("C"))), `R ← A ( B / C )`},
+		{star(seq(ref("A"), ref("B"))), `R ← ( A B )*`},
+		{not(star(ref("A"))), `R ← !A*`},
+		{star(not(ref("A"))), `R ← ( !A )*`},
+		{label("x", star(ref("A"))), `R ← x:A*`},
+		{label("x", seq(ref("A"), ref("B"))), `R ← x:( A B )`},
+		{seq(label("x", ref("A")), throw), `R ← x:A %{x}`},
+		{action(choice(ref("A"), ref("B")), "{return nil, nil}"), `R ← ( A / B ) { return nil, nil }`},
+		{choice(action(ref("A"), "{}"), ref("B")), `R ← A {} / B`},
+		{recovery, `R ← A //{x, y} B / C`},
+		{seq(recovery, ref("D")), `R ← ( A //{x, y} B / C ) D`},
+		{NewIndentExpr(Pos{}, IndentKindSamedent), `R ← %SAMEDENT`},
+	}
+	for _, tc := range cases {
+		g := NewGrammar(Pos{})
+		r := NewRule(Pos{}, NewIdentifier(Pos{}, "R"))
+		r.Expr = tc.expr
+		g.Rules = []*Rule{r}
+
+		var buf bytes.Buffer
+		if err := Fprint(&buf, g, nil); err != nil {
+			t.Errorf("%q: Fprint failed: %v", tc.want, err)
+			continue
+		}
+		if got := buf.String(); got != tc.want+"\n" {
+			t.Errorf("want %q, got %q", tc.want+"\n", got)
+		}
+	}
+}
+
+func TestFprintConfig(t *testing.T) {
+	g := NewGrammar(Pos{})
+	r := NewRule(Pos{Line: 1}, NewIdentifier(Pos{}, "Rule"))
+	r.DisplayName = NewStringLit(Pos{}, `"rule"`)
+	ch := NewChoiceExpr(Pos{Line: 1})
+	for i, v := range []string{"first", "second", "third"} {
+		ch.Alternatives = append(ch.Alternatives, NewLitMatcher(Pos{Line: 1, Off: 20 + i*10}, v))
+	}
+	r.Expr = ch
+	g.Rules = []*Rule{r}
+
+	cases := []struct {
+		cfg  *PrintConfig
+		want string
+	}{
+		{nil, "Rule \"rule\" ← \"first\" / \"second\" / \"third\"\n"},
+		{&PrintConfig{RuleDefOp: "<-"}, "Rule \"rule\" <- \"first\" / \"second\" / \"third\"\n"},
+		{&PrintConfig{RuleDefOp: "=", LineLen: 20}, "Rule \"rule\" = \"first\"\n            / \"second\"\n            / \"third\"\n"},
+	}
+	for _, tc := range cases {
+		var buf bytes.Buffer
+		if err := Fprint(&buf, g, tc.cfg); err != nil {
+			t.Errorf("%+v: Fprint failed: %v", tc.cfg, err)
+			continue
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("%+v: want %q, got %q", tc.cfg, tc.want, got)
+		}
+	}
+
+	if err := Fprint(&bytes.Buffer{}, g, &PrintConfig{RuleDefOp: ":="}); err == nil {
+		t.Errorf("want error for invalid RuleDefOp")
+	}
+}
+
+func TestFormatCode(t *testing.T) {
+	cases := []struct {
+		code   string
+		indent int
+		want   string
+	}{
+		{"{}", 0, "{}"},
+		{"{  return nil, nil\t}", 0, "{ return nil, nil }"},
+		{"{\n\t\tif x {\n\t\t\treturn\n\t\t}   \n\n}", 0, "{\n    if x {\n    \treturn\n    }\n\n}"},
+		{"{\n  a\n    b\n}", 2, "{\n      a\n        b\n  }"},
+		{"{ a\n\tb }", 0, "{\n    a\n       b\n}"},
+		{"{\n\treturn `a\n  b`\n}", 4, "{\n\treturn `a\n  b`\n}"},
+	}
+	for _, tc := range cases {
+		if got := formatCode(tc.code, tc.indent, 4); got != tc.want {
+			t.Errorf("%q: want %q, got %q", tc.code, tc.want, got)
+		}
+	}
+}
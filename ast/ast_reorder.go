@@ -0,0 +1,493 @@
+package ast
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ChoiceReorder is a change made by ReorderChoices: the alternatives of
+// the ordered choice at Pos, in rule Rule, were reordered to Order, the
+// one-based indexes of the alternatives in their original order.
+type ChoiceReorder struct {
+	Rule  string
+	Pos   Pos
+	Order []int
+}
+
+// String returns the textual representation of the change, e.g.
+// "rule Expr 3:8: alternatives 1, 2, 3 reordered as 3, 1, 2".
+func (c ChoiceReorder) String() string {
+	orig := make([]string, len(c.Order))
+	order := make([]string, len(c.Order))
+	for i, ix := range c.Order {
+		orig[i] = strconv.Itoa(i + 1)
+		order[i] = strconv.Itoa(ix)
+	}
+	return fmt.Sprintf("rule %s %d:%d: alternatives %s reordered as %s",
+		c.Rule, c.Pos.Line, c.Pos.Col, strings.Join(orig, ", "), strings.Join(order, ", "))
+}
+
+// ChoiceCounts returns the numbers of matches of the alternatives of the
+// ordered choices in stats, in the format of the Stats.ChoiceAltCnt of
+// the generated parsers, see ReorderChoices. The counts are indexed by
+// the position of the choice, e.g. "3:8", and the zero-based index of
+// the alternative. The counts of a choice reached from several rules are
+// added, and the keys that are not the index of an alternative are
+// ignored.
+func ChoiceCounts(stats map[string]map[string]int) map[string][]int {
+	counts := make(map[string][]int)
+	for key, alts := range stats {
+		// the key is the rule name followed by the position of the choice
+		_, pos, ok := strings.Cut(key, " ")
+		if !ok {
+			continue
+		}
+		for alt, n := range alts {
+			ix, err := strconv.Atoi(alt)
+			if err != nil || ix < 1 {
+				continue
+			}
+			c := counts[pos]
+			for len(c) < ix {
+				c = append(c, 0)
+			}
+			c[ix-1] += n
+			counts[pos] = c
+		}
+	}
+	return counts
+}
+
+// ReorderChoices reorders the alternatives of the ordered choices of g
+// by decreasing number of matches in stats, so that the parser tries
+// the most frequent ones first. The format of stats is the one of the
+// Stats.ChoiceAltCnt of the generated parsers, gathered over a corpus
+// with the Statistics option and saved as JSON: the key of a choice is
+// the name of a rule and its position, e.g. "Expr 3:8", and its
+// alternatives are counted by one-based index. The counts of a choice
+// reached from several rules are added, and the keys that are not the
+// index of an alternative, such as the counter of the failures, are
+// ignored.
+//
+// As PEG choices are ordered, two alternatives keep their relative order
+// unless they are provably disjoint: neither can match the empty string
+// nor run code before it consumes a character, and the sets of the
+// first characters that they can match (their FIRST sets) do not
+// intersect. At most one of them can then match any input, and the one
+// that does not match fails without side effects. The parse results are
+// thus preserved.
+//
+// The stats must be gathered with the grammar before it is optimized,
+// and ReorderChoices must be called before Optimize, so that the
+// positions of the choices and of the alternatives match. It returns
+// the changes it made.
+func ReorderChoices(g *Grammar, stats map[string]map[string]int) []ChoiceReorder {
+	counts := ChoiceCounts(stats)
+	fa := newFirstAnalyzer(g)
+	var changes []ChoiceReorder
+	for _, r := range g.Rules {
+		Inspect(r.Expr, func(expr Expression) bool {
+			ch, ok := expr.(*ChoiceExpr)
+			if !ok {
+				return true
+			}
+			c := counts[fmt.Sprintf("%d:%d", ch.Pos().Line, ch.Pos().Col)]
+			if len(c) == 0 || len(c) > len(ch.Alternatives) {
+				// not profiled, or profiled with another grammar
+				return true
+			}
+			if order := fa.reorder(ch.Alternatives, c); order != nil {
+				alts := make([]Expression, len(order))
+				for i, ix := range order {
+					alts[i] = ch.Alternatives[ix-1]
+				}
+				ch.Alternatives = alts
+				changes = append(changes, ChoiceReorder{Rule: r.Name.Val, Pos: ch.Pos(), Order: order})
+			}
+			return true
+		})
+	}
+	return changes
+}
+
+// firstAnalyzer computes the FIRST sets of the expressions of a grammar.
+type firstAnalyzer struct {
+	rules map[string]*Rule
+	// the rules being analyzed, a reference to one of them is recursive
+	active map[string]bool
+	cache  map[string]first
+}
+
+// first is the result of the analysis of an expression: the set of the
+// characters that it can consume first, whether it can match without
+// consuming a character, and whether it can run code before it consumes
+// a character or cannot be analyzed. Such an expression is not disjoint
+// from any other.
+type first struct {
+	set      charSet
+	nullable bool
+	unknown  bool
+}
+
+var unknownFirst = first{unknown: true}
+
+func newFirstAnalyzer(g *Grammar) *firstAnalyzer {
+	fa := &firstAnalyzer{
+		rules:  make(map[string]*Rule, len(g.Rules)),
+		active: make(map[string]bool),
+		cache:  make(map[string]first),
+	}
+	for _, r := range g.Rules {
+		fa.rules[r.Name.Val] = r
+	}
+	return fa
+}
+
+// reorder returns the order of the alternatives alts, as one-based
+// indexes, by decreasing counts where allowed, or nil if their order is
+// unchanged. The counts of the alternatives after len(counts) are 0.
+func (fa *firstAnalyzer) reorder(alts []Expression, counts []int) []int {
+	firsts := make([]first, len(alts))
+	for i, alt := range alts {
+		firsts[i] = fa.first(alt)
+	}
+	count := func(i int) int {
+		if i < len(counts) {
+			return counts[i]
+		}
+		return 0
+	}
+
+	// pick the most frequent alternative among those that are disjoint
+	// from all the alternatives before them that are not picked yet
+	var (
+		order   []int
+		picked  = make([]bool, len(alts))
+		changed bool
+	)
+	for len(order) < len(alts) {
+		best := -1
+		for i := range alts {
+			if picked[i] || (best >= 0 && count(i) <= count(best)) {
+				continue
+			}
+			free := true
+			for j := 0; j < i && free; j++ {
+				free = picked[j] || disjoint(firsts[i], firsts[j])
+			}
+			if free {
+				best = i
+			}
+		}
+		picked[best] = true
+		changed = changed || best != len(order)
+		order = append(order, best+1)
+	}
+	if !changed {
+		return nil
+	}
+	return order
+}
+
+// disjoint returns true if no input can be matched by the expressions
+// of both a and b.
+func disjoint(a, b first) bool {
+	return !a.unknown && !b.unknown && !a.nullable && !b.nullable && !a.set.intersects(b.set)
+}
+
+func (fa *firstAnalyzer) first(expr Expression) first {
+	switch expr := expr.(type) {
+	case *ActionExpr:
+		f := fa.first(expr.Expr)
+		if f.nullable {
+			// the code may run before a character is consumed
+			return unknownFirst
+		}
+		return f
+	case *AndExpr:
+		return fa.predicate(expr.Expr)
+	case *AnyMatcher:
+		return first{set: charSet{0, unicode.MaxRune}}
+	case *CharClassMatcher:
+		set, ok := classSet(expr)
+		if !ok {
+			return unknownFirst
+		}
+		return first{set: set}
+	case *ChoiceExpr:
+		var f first
+		for _, alt := range expr.Alternatives {
+			fa := fa.first(alt)
+			f.set = f.set.union(fa.set)
+			f.nullable = f.nullable || fa.nullable
+			f.unknown = f.unknown || fa.unknown
+		}
+		return f
+	case *LabeledExpr:
+		return fa.first(expr.Expr)
+	case *LitMatcher:
+		if expr.Val == "" {
+			return first{nullable: true}
+		}
+		rn, _ := utf8.DecodeRuneInString(expr.Val)
+		return first{set: runeSet(rn, expr.IgnoreCase)}
+	case *NotExpr:
+		return fa.predicate(expr.Expr)
+	case *OneOrMoreExpr:
+		return fa.first(expr.Expr)
+	case *RuleRefExpr:
+		return fa.rule(expr.Name.Val)
+	case *SeqExpr:
+		f := first{nullable: true}
+		for _, e := range expr.Exprs {
+			fe := fa.first(e)
+			f.set = f.set.union(fe.set)
+			f.unknown = f.unknown || fe.unknown
+			if !fe.nullable {
+				f.nullable = false
+				break
+			}
+		}
+		return f
+	case *ZeroOrMoreExpr:
+		f := fa.first(expr.Expr)
+		f.nullable = true
+		return f
+	case *ZeroOrOneExpr:
+		f := fa.first(expr.Expr)
+		f.nullable = true
+		return f
+	default:
+		// code, back-references, indentation, throw and recovery
+		// expressions
+		return unknownFirst
+	}
+}
+
+// rule returns the analysis of the rule name, unknown if it is
+// undefined or left-recursive.
+func (fa *firstAnalyzer) rule(name string) first {
+	if f, ok := fa.cache[name]; ok {
+		return f
+	}
+	r := fa.rules[name]
+	if r == nil || fa.active[name] {
+		return unknownFirst
+	}
+	fa.active[name] = true
+	f := fa.first(r.Expr)
+	delete(fa.active, name)
+	fa.cache[name] = f
+	return f
+}
+
+// predicate returns the analysis of a predicate on expr: it matches
+// without consuming, and it is unknown if expr may run code.
+func (fa *firstAnalyzer) predicate(expr Expression) first {
+	if fa.hasCode(expr, make(map[string]bool)) {
+		return unknownFirst
+	}
+	return first{nullable: true}
+}
+
+// hasCode returns true if expr, or a rule that it refers to, contains
+// code, or expressions that are not analyzed.
+func (fa *firstAnalyzer) hasCode(expr Expression, seen map[string]bool) bool {
+	code := false
+	Inspect(expr, func(expr Expression) bool {
+		switch expr := expr.(type) {
+		case *ActionExpr, *AndCodeExpr, *NotCodeExpr, *StateCodeExpr, *ThrowExpr, *RecoveryExpr, *IndentExpr:
+			code = true
+		case *RuleRefExpr:
+			name := expr.Name.Val
+			if r := fa.rules[name]; r != nil && !seen[name] {
+				seen[name] = true
+				code = code || fa.hasCode(r.Expr, seen)
+			}
+		}
+		return !code
+	})
+	return code
+}
+
+// charSet is a set of characters, as sorted and disjoint pairs of the
+// lowest and highest characters of ranges.
+type charSet []rune
+
+// newCharSet returns the set of the ranges, as pairs of the lowest and
+// highest characters, in any order.
+func newCharSet(ranges []rune) charSet {
+	pairs := make([][2]rune, 0, len(ranges)/2)
+	for i := 0; i+1 < len(ranges); i += 2 {
+		pairs = append(pairs, [2]rune{ranges[i], ranges[i+1]})
+	}
+	slices.SortFunc(pairs, func(a, b [2]rune) int { return cmp.Compare(a[0], b[0]) })
+
+	var s charSet
+	for _, p := range pairs {
+		if n := len(s); n > 0 && p[0] <= s[n-1]+1 {
+			s[n-1] = max(s[n-1], p[1])
+			continue
+		}
+		s = append(s, p[0], p[1])
+	}
+	return s
+}
+
+func (s charSet) union(t charSet) charSet {
+	return newCharSet(append(slices.Clip(s), t...))
+}
+
+// complement returns the characters that are not in s.
+func (s charSet) complement() charSet {
+	var c charSet
+	lo := rune(0)
+	for i := 0; i < len(s); i += 2 {
+		if s[i] > lo {
+			c = append(c, lo, s[i]-1)
+		}
+		lo = s[i+1] + 1
+	}
+	if lo <= unicode.MaxRune {
+		c = append(c, lo, unicode.MaxRune)
+	}
+	return c
+}
+
+func (s charSet) intersects(t charSet) bool {
+	for i, j := 0, 0; i < len(s) && j < len(t); {
+		switch {
+		case s[i+1] < t[j]:
+			i += 2
+		case t[j+1] < s[i]:
+			j += 2
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// runeSet returns the set of rn, with its other cases if ignoreCase is
+// set. It also contains the first byte of the UTF-8 encoding of rn, to
+// which the parsers in byte mode compare the bytes.
+func runeSet(rn rune, ignoreCase bool) charSet {
+	rs := []rune{rn, rn}
+	if ignoreCase {
+		// the parsers compare the lowercase characters
+		lower := unicode.ToLower(rn)
+		rs = append(rs, lower, lower)
+		for f := unicode.SimpleFold(lower); f != lower; f = unicode.SimpleFold(f) {
+			rs = append(rs, f, f)
+		}
+		for _, f := range lowerOnly()[lower] {
+			rs = append(rs, f, f)
+		}
+	}
+	if rn >= utf8.RuneSelf {
+		var b [utf8.UTFMax]byte
+		utf8.EncodeRune(b[:], rn)
+		rs = append(rs, rune(b[0]), rune(b[0]))
+	}
+	return newCharSet(rs)
+}
+
+// lowerOnly returns the characters that are not in the case folding
+// orbits of their lowercase, e.g. U+0130 for 'i', by lowercase.
+var lowerOnly = sync.OnceValue(func() map[rune][]rune {
+	m := make(map[rune][]rune)
+	for rn := rune(0); rn <= unicode.MaxRune; rn++ {
+		lower := unicode.ToLower(rn)
+		if lower == rn {
+			continue
+		}
+		f := unicode.SimpleFold(lower)
+		for f != lower && f != rn {
+			f = unicode.SimpleFold(f)
+		}
+		if f != rn {
+			m[lower] = append(m[lower], rn)
+		}
+	}
+	return m
+})
+
+// maxFoldRange is the size of the largest range of a character class
+// that ignores case that is analyzed.
+const maxFoldRange = 256
+
+// classSet returns the set of the characters matched by the character
+// class c, or false if it is not analyzed.
+func classSet(c *CharClassMatcher) (charSet, bool) {
+	if c.IgnoreCase && (c.Inverted || len(c.UnicodeClasses) > 0) {
+		return nil, false
+	}
+
+	var rs []rune
+	for _, rn := range c.Chars {
+		rs = append(rs, runeSet(rn, c.IgnoreCase)...)
+	}
+	for i := 0; i+1 < len(c.Ranges); i += 2 {
+		lo, hi := c.Ranges[i], c.Ranges[i+1]
+		if !c.IgnoreCase {
+			rs = append(rs, lo, hi)
+			continue
+		}
+		if hi-lo >= maxFoldRange {
+			return nil, false
+		}
+		for rn := lo; rn <= hi; rn++ {
+			rs = append(rs, runeSet(rn, true)...)
+		}
+	}
+	for _, name := range c.UnicodeClasses {
+		rt := unicode.Categories[name]
+		if rt == nil {
+			rt = unicode.Properties[name]
+		}
+		if rt == nil {
+			rt = unicode.Scripts[name]
+		}
+		if rt == nil {
+			return nil, false
+		}
+		rs = append(rs, tableRanges(rt)...)
+	}
+
+	s := newCharSet(rs)
+	if c.Inverted {
+		s = s.complement()
+	}
+	if n := len(s); n > 0 && s[n-1] >= utf8.RuneSelf {
+		// the parsers in byte mode compare the bytes to the characters
+		s = s.union(charSet{utf8.RuneSelf, 0xFF})
+	}
+	return s, true
+}
+
+// tableRanges returns the ranges of the characters of rt.
+func tableRanges(rt *unicode.RangeTable) []rune {
+	var rs []rune
+	add := func(lo, hi, stride rune) {
+		if stride == 1 {
+			rs = append(rs, lo, hi)
+			return
+		}
+		for rn := lo; rn <= hi; rn += stride {
+			rs = append(rs, rn, rn)
+		}
+	}
+	for _, r := range rt.R16 {
+		add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	for _, r := range rt.R32 {
+		add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	return rs
+}
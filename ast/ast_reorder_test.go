@@ -0,0 +1,176 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReorderChoices(t *testing.T) {
+	lit := func(v string) Expression { return NewLitMatcher(Pos{}, v) }
+	choice := func(line int, alts ...Expression) *ChoiceExpr {
+		ch := NewChoiceExpr(Pos{Line: line, Col: 5})
+		ch.Alternatives = alts
+		return ch
+	}
+	opt := func(e Expression) Expression {
+		z := NewZeroOrOneExpr(Pos{})
+		z.Expr = e
+		return z
+	}
+	rule := func(name string, expr Expression) *Rule {
+		r := NewRule(Pos{}, NewIdentifier(Pos{}, name))
+		r.Expr = expr
+		return r
+	}
+
+	a, b, ac := lit("a"), lit("b"), NewCharClassMatcher(Pos{}, "[a-c]")
+	ch1 := choice(1, a, b, ac)
+	x, y := opt(lit("x")), lit("y")
+	ch2 := choice(2, x, y)
+	code, z := NewAndCodeExpr(Pos{}), lit("z")
+	ch3 := choice(3, code, z)
+	c, d, e := lit("c"), lit("d"), lit("e")
+	ch4 := choice(4, c, d, e)
+
+	g := NewGrammar(Pos{})
+	g.Rules = []*Rule{rule("A", ch1), rule("B", ch2), rule("C", ch3), rule("D", ch4)}
+	stats := map[string]map[string]int{
+		"A 1:5":     {"1": 1, "2": 3, "3": 10},
+		"Other 1:5": {"2": 2, "no match": 100},
+		"B 2:5":     {"1": 1, "2": 9},
+		"C 3:5":     {"1": 1, "2": 9},
+		"D 4:5":     {"3": 5},
+		"E 9:5":     {"1": 1, "2": 9},
+	}
+
+	got := ReorderChoices(g, stats)
+	want := []ChoiceReorder{
+		{Rule: "A", Pos: Pos{Line: 1, Col: 5}, Order: []int{2, 1, 3}},
+		{Rule: "D", Pos: Pos{Line: 4, Col: 5}, Order: []int{3, 1, 2}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want changes %v, got %v", want, got)
+	}
+	if s, w := got[1].String(), "rule D 4:5: alternatives 1, 2, 3 reordered as 3, 1, 2"; s != w {
+		t.Errorf("want %q, got %q", w, s)
+	}
+
+	alts := map[*ChoiceExpr][]Expression{
+		ch1: {b, a, ac},
+		ch2: {x, y},
+		ch3: {code, z},
+		ch4: {e, c, d},
+	}
+	for ch, want := range alts {
+		if !reflect.DeepEqual(ch.Alternatives, want) {
+			t.Errorf("choice %s: want %v, got %v", ch.Pos(), want, ch.Alternatives)
+		}
+	}
+}
+
+func TestFirstDisjoint(t *testing.T) {
+	lit := func(v string, ignoreCase bool) Expression {
+		l := NewLitMatcher(Pos{}, v)
+		l.IgnoreCase = ignoreCase
+		return l
+	}
+	class := func(raw string) Expression { return NewCharClassMatcher(Pos{}, raw) }
+	ref := func(nm string) Expression {
+		r := NewRuleRefExpr(Pos{})
+		r.Name = NewIdentifier(Pos{}, nm)
+		return r
+	}
+	seq := func(exprs ...Expression) Expression {
+		s := NewSeqExpr(Pos{})
+		s.Exprs = exprs
+		return s
+	}
+	not := func(e Expression) Expression {
+		n := NewNotExpr(Pos{})
+		n.Expr = e
+		return n
+	}
+	action := func(e Expression) Expression {
+		a := NewActionExpr(Pos{})
+		a.Expr = e
+		return a
+	}
+	star := func(e Expression) Expression {
+		z := NewZeroOrMoreExpr(Pos{})
+		z.Expr = e
+		return z
+	}
+
+	g := NewGrammar(Pos{})
+	for nm, expr := range map[string]Expression{
+		"Digit": class("[0-9]"),
+		"Code":  action(lit("c", false)),
+		"Rec":   seq(ref("Rec"), lit("r", false)),
+	} {
+		r := NewRule(Pos{}, NewIdentifier(Pos{}, nm))
+		r.Expr = expr
+		g.Rules = append(g.Rules, r)
+	}
+
+	cases := []struct {
+		a, b Expression
+		want bool
+	}{
+		{lit("a", false), lit("b", false), true},
+		{lit("a", false), lit("ab", false), false},
+		{lit("a", false), lit("A", true), false},
+		{lit("k", true), class("[K]"), false},
+		{lit("i", true), lit("İ", false), false},
+		{lit("é", false), lit("a", false), true},
+		{lit("é", false), lit("è", false), false},
+		{lit("", false), lit("b", false), false},
+		{class("[a-z]"), class("[0-9]"), true},
+		{class("[a-z]"), class("[A-Z]i"), false},
+		{class("[^a-z]"), lit("b", false), true},
+		{class("[^a-z]"), lit("0", false), false},
+		{class("[^a]"), lit("é", false), false},
+		{class("[\\pL]"), ref("Digit"), true},
+		{class("[\\pL]"), lit("é", false), false},
+		{NewAnyMatcher(Pos{}, "."), lit("a", false), false},
+		{seq(star(lit("-", false)), ref("Digit")), lit("a", false), true},
+		{seq(star(lit("-", false)), ref("Digit")), lit("-", false), false},
+		{seq(not(lit("x", false)), lit("a", false)), lit("b", false), true},
+		{seq(not(ref("Code")), lit("a", false)), lit("b", false), false},
+		{action(lit("a", false)), ref("Code"), true},
+		{action(star(lit("a", false))), lit("b", false), false},
+		{ref("Rec"), lit("a", false), false},
+		{ref("Undefined"), lit("a", false), false},
+	}
+	for i, c := range cases {
+		fa := newFirstAnalyzer(g)
+		if got := disjoint(fa.first(c.a), fa.first(c.b)); got != c.want {
+			t.Errorf("%d: want %t, got %t", i, c.want, got)
+		}
+	}
+}
+
+func TestCharSet(t *testing.T) {
+	s := newCharSet([]rune{'x', 'z', 'a', 'c', 'd', 'f', 'b', 'b'})
+	if want := (charSet{'a', 'f', 'x', 'z'}); !reflect.DeepEqual(s, want) {
+		t.Errorf("want %q, got %q", want, s)
+	}
+	if want := (charSet{0, 'a' - 1, 'g', 'w', '{', 0x10FFFF}); !reflect.DeepEqual(s.complement(), want) {
+		t.Errorf("want complement %q, got %q", want, s.complement())
+	}
+	if !s.intersects(charSet{'e', 'e'}) || s.intersects(charSet{'g', 'w'}) || s.intersects(nil) {
+		t.Errorf("invalid intersections of %q", s)
+	}
+}
+
+func TestChoiceCounts(t *testing.T) {
+	stats := map[string]map[string]int{
+		"A 1:5":   {"1": 2, "3": 4, "no match": 9},
+		"B 1:5":   {"1": 1},
+		"C 2:3":   {"0": 5, "x": 1},
+		"invalid": {"1": 1},
+	}
+	got := ChoiceCounts(stats)
+	if len(got) != 1 || !reflect.DeepEqual(got["1:5"], []int{3, 0, 4}) {
+		t.Errorf("want the counts [3 0 4] of 1:5, got %v", got)
+	}
+}
@@ -1,6 +1,7 @@
 package ast
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 	"unicode/utf8"
@@ -105,3 +106,110 @@ func TestCharClassParse(t *testing.T) {
 		}
 	}
 }
+
+func TestRuleDocText(t *testing.T) {
+	cases := []struct {
+		doc  []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"//"}, ""},
+		{[]string{"// a", "//b  "}, "a\nb\n"},
+		{[]string{"// a", "//", "//", "// b", "//"}, "a\n\nb\n"},
+		{[]string{"/* a\n\t  b\n\t    c\n*/"}, "a\nb\n  c\n"},
+		{[]string{"/**/", "// a"}, "a\n"},
+	}
+	for _, tc := range cases {
+		r := NewRule(Pos{}, NewIdentifier(Pos{}, "r"))
+		for _, c := range tc.doc {
+			r.Doc = append(r.Doc, NewComment(Pos{}, c))
+		}
+		if got := r.DocText(); got != tc.want {
+			t.Errorf("%q: want %q, got %q", tc.doc, tc.want, got)
+		}
+	}
+}
+
+func TestRuleMemoized(t *testing.T) {
+	cases := []struct {
+		doc  []string
+		want bool
+	}{
+		{nil, false},
+		{[]string{"// @memoize"}, true},
+		{[]string{"// a", "//", "//   @memoize  "}, true},
+		{[]string{"/* a\n   @memoize\n*/"}, true},
+		{[]string{"// see @memoize"}, false},
+		{[]string{"// @memoized"}, false},
+	}
+	for _, tc := range cases {
+		r := NewRule(Pos{}, NewIdentifier(Pos{}, "r"))
+		for _, c := range tc.doc {
+			r.Doc = append(r.Doc, NewComment(Pos{}, c))
+		}
+		if got := r.Memoized(); got != tc.want {
+			t.Errorf("%q: want %t, got %t", tc.doc, tc.want, got)
+		}
+	}
+
+	r := NewRule(Pos{}, NewIdentifier(Pos{}, "r"))
+	r.Memoize = true
+	if !r.Memoized() {
+		t.Errorf("want a memoized rule with the Memoize field")
+	}
+	r.Doc = []*Comment{NewComment(Pos{}, "// @commit")}
+	if !r.HasAnnotation(CommitAnnotation) || r.HasAnnotation("@other") {
+		t.Errorf("want the @commit annotation only")
+	}
+}
+
+func TestRulePrecedence(t *testing.T) {
+	cases := []struct {
+		doc  []string
+		want string
+		err  string
+	}{
+		{doc: nil, want: "<nil>"},
+		{doc: []string{"// @memoize"}, want: "<nil>"},
+		{doc: []string{`// @left "+" "-"`, `// @right "^"`, "// a", `// @prefix "-"`}, want: "{[{0 [+ -]} {1 [^]} {3 [-]}] }"},
+		{doc: []string{`// @nonassoc "==" "<"`, `// @postfix "!" "\n"`, "// @skip _"}, want: "{[{2 [== <]} {4 [! \n]}] _}"},
+		{doc: []string{`// @left "+" "+"`}, err: `duplicate operator "+"`},
+		{doc: []string{`// @left "!"`, `// @postfix "!"`}, err: `duplicate operator "!"`},
+		{doc: []string{`// @left "+"`, `// @skip a b`}, err: "want a single @skip rule"},
+		{doc: []string{`// @left "+" -`}, err: "invalid operator: -"},
+		{doc: []string{`// @left ""`}, err: "empty operator"},
+		{doc: []string{`// @right`}, err: "@right without operator"},
+	}
+	for _, tc := range cases {
+		r := NewRule(Pos{}, NewIdentifier(Pos{}, "r"))
+		r.Expr = NewActionExpr(Pos{})
+		for _, c := range tc.doc {
+			r.Doc = append(r.Doc, NewComment(Pos{}, c))
+		}
+		table, err := r.Precedence()
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%q: want error %q, got %v", tc.doc, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tc.doc, err)
+			continue
+		}
+		got := "<nil>"
+		if table != nil {
+			got = fmt.Sprint(*table)
+		}
+		if got != tc.want {
+			t.Errorf("%q: want %q, got %q", tc.doc, tc.want, got)
+		}
+	}
+
+	r := NewRule(Pos{}, NewIdentifier(Pos{}, "r"))
+	r.Expr = NewAnyMatcher(Pos{}, ".")
+	r.Doc = []*Comment{NewComment(Pos{}, `// @left "+"`)}
+	if _, err := r.Precedence(); err == nil || !strings.Contains(err.Error(), "must have a code block") {
+		t.Errorf("want a code block error, got %v", err)
+	}
+}
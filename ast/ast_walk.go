@@ -29,6 +29,8 @@ func Walk(v Visitor, expr Expression) {
 		Walk(v, expr.Expr)
 	case *AnyMatcher:
 		// Nothing to do
+	case *BackRefExpr:
+		// Nothing to do
 	case *CharClassMatcher:
 		// Nothing to do
 	case *ChoiceExpr:
@@ -39,6 +41,8 @@ func Walk(v Visitor, expr Expression) {
 		for _, e := range expr.Rules {
 			Walk(v, e)
 		}
+	case *IndentExpr:
+		// Nothing to do
 	case *LabeledExpr:
 		Walk(v, expr.Expr)
 	case *LitMatcher:
@@ -49,6 +53,9 @@ func Walk(v Visitor, expr Expression) {
 		Walk(v, expr.Expr)
 	case *OneOrMoreExpr:
 		Walk(v, expr.Expr)
+	case *RecoveryExpr:
+		Walk(v, expr.Expr)
+		Walk(v, expr.RecoverExpr)
 	case *Rule:
 		Walk(v, expr.Expr)
 	case *RuleRefExpr:
@@ -59,6 +66,8 @@ func Walk(v Visitor, expr Expression) {
 		}
 	case *StateCodeExpr:
 		// Nothing to do
+	case *ThrowExpr:
+		// Nothing to do
 	case *ZeroOrMoreExpr:
 		Walk(v, expr.Expr)
 	case *ZeroOrOneExpr:
@@ -132,18 +132,62 @@ func (p *Parser) grammar() *ast.Grammar {
 	}
 
 	for {
+		doc := p.docComments(g)
 		if p.tok.id == eof {
 			return g
 		}
 		r := p.rule()
 		if r != nil {
+			r.Doc = doc
 			g.Rules = append(g.Rules, r)
 		}
+		if p.tok.id == lcomment || p.tok.id == mlcomment {
+			// comment at the end of the rule
+			continue
+		}
 		p.read()
 		p.skip(eol, semicolon)
 	}
 }
 
+// docComments skips the comments, end of lines and semicolons before a
+// rule. It adds the comments to g and returns those that end on the line
+// before the current token, starting at its column.
+func (p *Parser) docComments(g *ast.Grammar) []*ast.Comment {
+	var doc []*ast.Comment
+	endLine := func() int {
+		c := doc[len(doc)-1]
+		return c.Pos().Line + strings.Count(c.Val, "\n")
+	}
+
+	for {
+		switch p.tok.id {
+		case lcomment, mlcomment:
+			c := ast.NewComment(p.tok.pos, p.tok.lit)
+			g.Comments = append(g.Comments, c)
+			if len(doc) > 0 && endLine() != c.Pos().Line-1 {
+				doc = nil
+			}
+			doc = append(doc, c)
+		case eol, semicolon:
+		default:
+			if len(doc) > 0 && endLine() != p.tok.pos.Line-1 {
+				return nil
+			}
+			var res []*ast.Comment
+			for _, c := range doc {
+				if c.Pos().Col != p.tok.pos.Col {
+					res = nil
+					continue
+				}
+				res = append(res, c)
+			}
+			return res
+		}
+		p.read()
+	}
+}
+
 func (p *Parser) expect(ids ...tid) bool {
 	if len(ids) == 0 {
 		return true
@@ -198,7 +242,7 @@ func (p *Parser) rule() *ast.Rule {
 	}
 	r.Expr = expr
 
-	if !p.expect(eol, eof, semicolon) {
+	if !p.expect(eol, eof, semicolon, lcomment, mlcomment) {
 		p.errs.add(p.tok.pos, errors.New("rule not terminated"))
 		return nil
 	}
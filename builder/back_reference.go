@@ -0,0 +1,131 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mna/pigeon/ast"
+)
+
+// ErrUndefinedBackRef is returned when a back-reference expression refers
+// to a label that is not in scope.
+var ErrUndefinedBackRef = errors.New("back-reference to undefined label")
+
+// backRefs holds the result of the back-references analysis of a grammar.
+type backRefs struct {
+	// rules that contain at least one back-reference expression
+	rules map[string]struct{}
+	// labeled expressions whose matched text is referenced
+	captures map[*ast.LabeledExpr]struct{}
+}
+
+// computeBackRefs validates the back-reference expressions of the grammar
+// and records the rules and the labeled expressions they depend on.
+//
+// A label is in scope of a back-reference if it is defined earlier in the
+// same sequence, or in a sequence enclosing the back-reference in the same
+// rule. Labels defined inside a choice alternative, a repetition or a
+// predicate are not visible outside of it.
+func computeBackRefs(g *ast.Grammar) (*backRefs, error) {
+	refs := &backRefs{
+		rules:    make(map[string]struct{}),
+		captures: make(map[*ast.LabeledExpr]struct{}),
+	}
+	for _, rule := range g.Rules {
+		found, err := refs.check(rule.Expr, nil)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.Name.Val, err)
+		}
+		if found {
+			refs.rules[rule.Name.Val] = struct{}{}
+		}
+	}
+	return refs, nil
+}
+
+// check validates the back-references in expr, with the labels in scope,
+// and returns true if expr contains at least one back-reference.
+func (r *backRefs) check(expr ast.Expression, scope map[string]*ast.LabeledExpr) (bool, error) {
+	switch expr := expr.(type) {
+	case *ast.BackRefExpr:
+		lab, ok := scope[expr.Label.Val]
+		if !ok {
+			return false, fmt.Errorf("%s: %w %s", expr.Pos(), ErrUndefinedBackRef, expr.Label.Val)
+		}
+		r.captures[lab] = struct{}{}
+		return true, nil
+
+	case *ast.SeqExpr:
+		// labels defined by an element of the sequence are in scope for
+		// the following elements.
+		local := make(map[string]*ast.LabeledExpr, len(scope))
+		for k, v := range scope {
+			local[k] = v
+		}
+		found := false
+		for _, e := range expr.Exprs {
+			f, err := r.check(e, local)
+			if err != nil {
+				return false, err
+			}
+			found = found || f
+			definedLabels(e, local)
+		}
+		return found, nil
+
+	case *ast.ChoiceExpr:
+		found := false
+		for _, alt := range expr.Alternatives {
+			f, err := r.check(alt, scope)
+			if err != nil {
+				return false, err
+			}
+			found = found || f
+		}
+		return found, nil
+
+	case *ast.RecoveryExpr:
+		f1, err := r.check(expr.Expr, scope)
+		if err != nil {
+			return false, err
+		}
+		f2, err := r.check(expr.RecoverExpr, scope)
+		if err != nil {
+			return false, err
+		}
+		return f1 || f2, nil
+
+	case *ast.ActionExpr:
+		return r.check(expr.Expr, scope)
+	case *ast.LabeledExpr:
+		return r.check(expr.Expr, scope)
+	case *ast.AndExpr:
+		return r.check(expr.Expr, scope)
+	case *ast.NotExpr:
+		return r.check(expr.Expr, scope)
+	case *ast.ZeroOrOneExpr:
+		return r.check(expr.Expr, scope)
+	case *ast.ZeroOrMoreExpr:
+		return r.check(expr.Expr, scope)
+	case *ast.OneOrMoreExpr:
+		return r.check(expr.Expr, scope)
+	}
+	return false, nil
+}
+
+// definedLabels adds to scope the labels that expr sets in the variables
+// set of its enclosing sequence.
+func definedLabels(expr ast.Expression, scope map[string]*ast.LabeledExpr) {
+	switch expr := expr.(type) {
+	case *ast.LabeledExpr:
+		if expr.Label != nil {
+			scope[expr.Label.Val] = expr
+		}
+	case *ast.SeqExpr:
+		for _, e := range expr.Exprs {
+			definedLabels(e, scope)
+		}
+	case *ast.ActionExpr:
+		definedLabels(expr.Expr, scope)
+	}
+}
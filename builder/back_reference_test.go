@@ -0,0 +1,65 @@
+package builder
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/mna/pigeon/ast"
+)
+
+func TestBackRefScope(t *testing.T) {
+	lab := func(name string, expr ast.Expression) *ast.LabeledExpr {
+		l := ast.NewLabeledExpr(ast.Pos{})
+		l.Label = ast.NewIdentifier(ast.Pos{}, name)
+		l.Expr = expr
+		return l
+	}
+	ref := func(name string) *ast.BackRefExpr {
+		r := ast.NewBackRefExpr(ast.Pos{})
+		r.Label = ast.NewIdentifier(ast.Pos{}, name)
+		return r
+	}
+	seq := func(exprs ...ast.Expression) *ast.SeqExpr {
+		s := ast.NewSeqExpr(ast.Pos{})
+		s.Exprs = exprs
+		return s
+	}
+	lit := func(v string) *ast.LitMatcher {
+		return ast.NewLitMatcher(ast.Pos{}, v)
+	}
+	star := func(expr ast.Expression) *ast.ZeroOrMoreExpr {
+		z := ast.NewZeroOrMoreExpr(ast.Pos{})
+		z.Expr = expr
+		return z
+	}
+
+	cases := []struct {
+		name  string
+		expr  ast.Expression
+		valid bool
+	}{
+		{"same sequence", seq(lab("x", lit("a")), ref("x")), true},
+		{"enclosing sequence", seq(lab("x", lit("a")), star(seq(lit("b"), ref("x")))), true},
+		{"nested sequence", seq(seq(lab("x", lit("a")), lit("b")), ref("x")), true},
+		{"undefined", seq(lab("x", lit("a")), ref("y")), false},
+		{"defined after", seq(ref("x"), lab("x", lit("a"))), false},
+		{"self", lab("x", ref("x")), false},
+		{"inside repetition", seq(star(lab("x", lit("a"))), ref("x")), false},
+	}
+
+	for _, tc := range cases {
+		g := ast.NewGrammar(ast.Pos{})
+		rule := ast.NewRule(ast.Pos{}, ast.NewIdentifier(ast.Pos{}, "A"))
+		rule.Expr = tc.expr
+		g.Rules = []*ast.Rule{rule}
+
+		err := BuildParser(io.Discard, g)
+		if tc.valid && err != nil {
+			t.Errorf("%s: want no error, got %v", tc.name, err)
+		}
+		if !tc.valid && !errors.Is(err, ErrUndefinedBackRef) {
+			t.Errorf("%s: want error %v, got %v", tc.name, ErrUndefinedBackRef, err)
+		}
+	}
+}
@@ -110,6 +110,91 @@ func BasicLatinLookupTable(basicLatinLookupTable bool) Option {
 	}
 }
 
+// ByteMode returns an option that specifies the byteMode option.
+// If byteMode is true, the generated parser matches the input byte by byte
+// instead of decoding UTF-8 runes: the any matcher matches a single byte,
+// and literals and character classes match byte values.
+func ByteMode(byteMode bool) Option {
+	return func(b *builder) Option {
+		prev := b.byteMode
+		b.byteMode = byteMode
+		return ByteMode(prev)
+	}
+}
+
+// Tracing returns an option that specifies the tracing option.
+// If tracing is true, the Trace option and the Tracer, Position and Span
+// types are generated, to receive the events of a parse. They are not
+// generated with the Optimize option.
+func Tracing(tracing bool) Option {
+	return func(b *builder) Option {
+		prev := b.tracing
+		b.tracing = tracing
+		return Tracing(prev)
+	}
+}
+
+// Profiling returns an option that specifies the profiling option.
+// If profiling is true, the Profiling option and the Profile,
+// ProfileEntry and ProfileStack types are generated, to gather the
+// profile of a parse. They are not generated with the Optimize option.
+func Profiling(profiling bool) Option {
+	return func(b *builder) Option {
+		prev := b.profiling
+		b.profiling = profiling
+		return Profiling(prev)
+	}
+}
+
+// Coverage returns an option that specifies the coverage option.
+// If coverage is true, the Coverage option and the CoverProfile type are
+// generated, to gather the coverage of the grammar by a parse. They are
+// not generated with the Optimize option.
+func Coverage(coverage bool) Option {
+	return func(b *builder) Option {
+		prev := b.coverage
+		b.coverage = coverage
+		return Coverage(prev)
+	}
+}
+
+// InputDecoder returns an option that specifies the inputDecoder option.
+// If inputDecoder is true, the InputDecoder option, the Decoder type and
+// the decoders of the supported encodings are generated, to parse input
+// that is not UTF-8 encoded. They are not generated with the ByteMode
+// option.
+func InputDecoder(inputDecoder bool) Option {
+	return func(b *builder) Option {
+		prev := b.inputDecoder
+		b.inputDecoder = inputDecoder
+		return InputDecoder(prev)
+	}
+}
+
+// PositionConverter returns an option that specifies the positionConverter
+// option. If positionConverter is true, the PositionConverter and
+// ColumnUnit types and the TabWidth option are generated, to convert the
+// byte offsets of an input to lines and columns in other units.
+func PositionConverter(positionConverter bool) Option {
+	return func(b *builder) Option {
+		prev := b.positionConverter
+		b.positionConverter = positionConverter
+		return PositionConverter(prev)
+	}
+}
+
+// FuzzTest returns an option that specifies the fuzzTest option.
+// If w is not nil, a test file with a FuzzParse fuzz test of the parser is
+// written to w, with the seeds as its seed corpus. See writeFuzzTest for
+// the checks of the fuzz test.
+func FuzzTest(w io.Writer, seeds ...[]byte) Option {
+	return func(b *builder) Option {
+		prevW, prevSeeds := b.fuzzTest, b.fuzzSeeds
+		b.fuzzTest, b.fuzzSeeds = w, seeds
+		return FuzzTest(prevW, prevSeeds...)
+	}
+}
+
 // BuildParser builds the PEG parser using the provider grammar. The code is
 // written to the specified w.
 func BuildParser(w io.Writer, g *ast.Grammar, opts ...Option) error {
@@ -118,6 +203,23 @@ func BuildParser(w io.Writer, g *ast.Grammar, opts ...Option) error {
 	return b.buildParser(g)
 }
 
+// CheckGrammar returns the errors of the grammar that prevent building its
+// parser whatever the options: the left recursions that cannot be parsed,
+// the back-references to undefined labels and the invalid precedence
+// tables. It sets the flags of the rules computed by PrepareGrammar.
+func CheckGrammar(g *ast.Grammar) error {
+	if _, err := PrepareGrammar(g); err != nil {
+		return fmt.Errorf("incorrect grammar: %w", err)
+	}
+	if _, err := computeBackRefs(g); err != nil {
+		return fmt.Errorf("incorrect grammar: %w", err)
+	}
+	if _, err := computePrecedence(g); err != nil {
+		return fmt.Errorf("incorrect grammar: %w", err)
+	}
+	return nil
+}
+
 type builder struct {
 	w   io.Writer
 	err error
@@ -126,12 +228,26 @@ type builder struct {
 	recvName              string
 	optimize              bool
 	basicLatinLookupTable bool
+	byteMode              bool
 	globalState           bool
+	indentation           bool
 	nolint                bool
+	tracing               bool
+	profiling             bool
+	coverage              bool
+	inputDecoder          bool
+	positionConverter     bool
 	supportLeftRecursion  bool
 	haveLeftRecursion     bool
+	backRefs              *backRefs
+	memoRules             map[*ast.Rule]bool
+	commitRules           map[*ast.Rule]bool
+	precedence            map[*ast.Rule]*ast.PrecedenceTable
+	fuzzTest              io.Writer
+	fuzzSeeds             [][]byte
 
 	ruleName  string
+	ruleDoc   string
 	exprIndex int
 	argsStack [][]string
 
@@ -154,6 +270,41 @@ func (b *builder) buildParser(grammar *ast.Grammar) error {
 	}
 	b.haveLeftRecursion = haveLeftRecursion
 
+	backRefs, err := computeBackRefs(grammar)
+	if err != nil {
+		return fmt.Errorf("incorrect grammar: %w", err)
+	}
+	b.backRefs = backRefs
+
+	b.memoRules = make(map[*ast.Rule]bool)
+	for _, r := range grammar.Rules {
+		if r.Memoized() {
+			b.memoRules[r] = true
+		}
+	}
+	// the commit points discard the memoized results, the optimized
+	// parsers only memoize the rules with the @memoize annotation
+	b.commitRules = make(map[*ast.Rule]bool)
+	if !b.optimize || len(b.memoRules) > 0 {
+		for _, r := range grammar.Rules {
+			if r.HasAnnotation(ast.CommitAnnotation) {
+				b.commitRules[r] = true
+			}
+		}
+	}
+
+	precedence, err := computePrecedence(grammar)
+	if err != nil {
+		return fmt.Errorf("incorrect grammar: %w", err)
+	}
+	b.precedence = precedence
+
+	if b.byteMode {
+		if err := checkByteMode(grammar); err != nil {
+			return fmt.Errorf("incorrect grammar: %w", err)
+		}
+	}
+
 	b.writeInit(grammar.Init)
 	b.writeGrammar(grammar)
 	for _, rule := range grammar.Rules {
@@ -161,6 +312,9 @@ func (b *builder) buildParser(grammar *ast.Grammar) error {
 	}
 	b.writeStaticCode()
 
+	if b.fuzzTest != nil && b.err == nil {
+		b.err = writeFuzzTest(b.fuzzTest, grammar, b.fuzzSeeds)
+	}
 	return b.err
 }
 
@@ -194,6 +348,7 @@ func (b *builder) writeRule(r *ast.Rule) {
 	b.exprIndex = 0
 	b.ruleName = r.Name.Val
 
+	b.writeDoc(r.DocText())
 	b.writelnf("{")
 	b.writelnf("\tname: %q,", r.Name.Val)
 	if r.DisplayName != nil && r.DisplayName.Val != "" {
@@ -202,11 +357,24 @@ func (b *builder) writeRule(r *ast.Rule) {
 	pos := r.Pos()
 	b.writelnf("\tpos: position{line: %d, col: %d, offset: %d},", pos.Line, pos.Col, pos.Off)
 	b.writef("\texpr: ")
-	b.writeExpr(r.Expr)
+	if table := b.precedence[r]; table != nil {
+		b.writePrecedenceExpr(r, table)
+	} else {
+		b.writeExpr(r.Expr)
+	}
 	if b.haveLeftRecursion {
 		b.writelnf("\tleader: %t,", r.Leader)
 		b.writelnf("\tleftRecursive: %t,", r.LeftRecursive)
 	}
+	if _, ok := b.backRefs.rules[r.Name.Val]; ok {
+		b.writelnf("\tbackRef: true,")
+	}
+	if b.memoRules[r] {
+		b.writelnf("\tmemoize: true,")
+	}
+	if b.commitRules[r] {
+		b.writelnf("\tcommit: true,")
+	}
 	b.writelnf("},")
 }
 
@@ -221,10 +389,14 @@ func (b *builder) writeExpr(expr ast.Expression) {
 		b.writeAndExpr(expr)
 	case *ast.AnyMatcher:
 		b.writeAnyMatcher(expr)
+	case *ast.BackRefExpr:
+		b.writeBackRefExpr(expr)
 	case *ast.CharClassMatcher:
 		b.writeCharClassMatcher(expr)
 	case *ast.ChoiceExpr:
 		b.writeChoiceExpr(expr)
+	case *ast.IndentExpr:
+		b.writeIndentExpr(expr)
 	case *ast.LabeledExpr:
 		b.writeLabeledExpr(expr)
 	case *ast.LitMatcher:
@@ -310,6 +482,18 @@ func (b *builder) writeAnyMatcher(any *ast.AnyMatcher) {
 	b.writelnf("},")
 }
 
+func (b *builder) writeBackRefExpr(ref *ast.BackRefExpr) {
+	if ref == nil {
+		b.writelnf("nil,")
+		return
+	}
+	b.writelnf("&backRefExpr{")
+	pos := ref.Pos()
+	b.writelnf("\tpos: position{line: %d, col: %d, offset: %d},", pos.Line, pos.Col, pos.Off)
+	b.writelnf("\tlabel: %q,", ref.Label.Val)
+	b.writelnf("},")
+}
+
 func (b *builder) writeCharClassMatcher(ch *ast.CharClassMatcher) {
 	if ch == nil {
 		b.writelnf("nil,")
@@ -323,7 +507,7 @@ func (b *builder) writeCharClassMatcher(ch *ast.CharClassMatcher) {
 		b.writef("\tchars: []rune{")
 		for _, rn := range ch.Chars {
 			if ch.IgnoreCase {
-				b.writef("%q,", unicode.ToLower(rn))
+				b.writef("%q,", b.toLower(rn))
 			} else {
 				b.writef("%q,", rn)
 			}
@@ -334,7 +518,7 @@ func (b *builder) writeCharClassMatcher(ch *ast.CharClassMatcher) {
 		b.writef("\tranges: []rune{")
 		for _, rn := range ch.Ranges {
 			if ch.IgnoreCase {
-				b.writef("%q,", unicode.ToLower(rn))
+				b.writef("%q,", b.toLower(rn))
 			} else {
 				b.writef("%q,", rn)
 			}
@@ -357,6 +541,17 @@ func (b *builder) writeCharClassMatcher(ch *ast.CharClassMatcher) {
 	b.writelnf("},")
 }
 
+// toLower returns the lower case of rn, only for ASCII letters in byte mode.
+func (b *builder) toLower(rn rune) rune {
+	if b.byteMode {
+		if rn >= 'A' && rn <= 'Z' {
+			return rn + 'a' - 'A'
+		}
+		return rn
+	}
+	return unicode.ToLower(rn)
+}
+
 // BasicLatinLookup calculates the decision results for the first 256 characters of the UTF-8 character
 // set for a given set of chars, ranges and unicodeClasses to speedup the CharClassMatcher.
 func BasicLatinLookup(chars, ranges []rune, unicodeClasses []string, ignoreCase bool) (basicLatinChars [128]bool) {
@@ -415,6 +610,27 @@ func (b *builder) writeChoiceExpr(ch *ast.ChoiceExpr) {
 	b.writelnf("},")
 }
 
+func (b *builder) writeIndentExpr(ind *ast.IndentExpr) {
+	if ind == nil {
+		b.writelnf("nil,")
+		return
+	}
+	b.indentation = true
+	b.writelnf("&indentExpr{")
+	pos := ind.Pos()
+	b.writelnf("\tpos: position{line: %d, col: %d, offset: %d},", pos.Line, pos.Col, pos.Off)
+	switch ind.Kind {
+	case ast.IndentKindIndent:
+		b.writelnf("\top: indentOp,")
+	case ast.IndentKindDedent:
+		b.writelnf("\top: dedentOp,")
+	default:
+		b.writelnf("\top: samedentOp,")
+	}
+	b.writelnf("\twant: %q,", "%"+ind.Kind.String())
+	b.writelnf("},")
+}
+
 func (b *builder) writeLabeledExpr(lab *ast.LabeledExpr) {
 	if lab == nil {
 		b.writelnf("nil,")
@@ -426,6 +642,9 @@ func (b *builder) writeLabeledExpr(lab *ast.LabeledExpr) {
 	if lab.Label != nil && lab.Label.Val != "" {
 		b.writelnf("\tlabel: %q,", lab.Label.Val)
 	}
+	if _, ok := b.backRefs.captures[lab]; ok {
+		b.writelnf("\ttextCapture: true,")
+	}
 	b.writef("\texpr: ")
 	b.writeExpr(lab.Expr)
 	b.writelnf("},")
@@ -439,7 +658,9 @@ func (b *builder) writeLitMatcher(lit *ast.LitMatcher) {
 	b.writelnf("&litMatcher{")
 	pos := lit.Pos()
 	b.writelnf("\tpos: position{line: %d, col: %d, offset: %d},", pos.Line, pos.Col, pos.Off)
-	if lit.IgnoreCase {
+	if lit.IgnoreCase && b.byteMode {
+		b.writelnf("\tval: %q,", toLowerASCII(lit.Val))
+	} else if lit.IgnoreCase {
 		b.writelnf("\tval: %q,", strings.ToLower(lit.Val))
 	} else {
 		b.writelnf("\tval: %q,", lit.Val)
@@ -609,6 +830,11 @@ func (b *builder) writeRuleCode(rule *ast.Rule) {
 	// keep trace of the current rule, as the code blocks are created
 	// in functions named "on<RuleName><#ExprIndex>".
 	b.ruleName = rule.Name.Val
+	b.ruleDoc = rule.DocText()
+	if b.precedence[rule] != nil {
+		b.writePrecedenceCode(rule)
+		return
+	}
 	b.pushArgsSet()
 	b.writeExprCode(rule.Expr)
 	b.popArgsSet()
@@ -762,6 +988,11 @@ func (b *builder) writeFunc(funcIx int, code *ast.CodeBlock, callTpl, funcTpl st
 	}
 
 	fnNm := b.funcName(funcIx)
+	if b.ruleDoc != "" {
+		b.writelnf("// %s is a code block of rule %s:", fnNm, b.ruleName)
+		b.writelnf("//")
+		b.writeDoc(b.ruleDoc)
+	}
 	b.writelnf(funcTpl, b.recvName, fnNm, args.String(), val)
 
 	args.Reset()
@@ -783,12 +1014,34 @@ func (b *builder) writeStaticCode() {
 		BasicLatinLookupTable bool
 		GlobalState           bool
 		LeftRecursion         bool
+		BackReference         bool
+		ByteMode              bool
+		Indentation           bool
+		MemoRules             bool
+		CommitRules           bool
+		Precedence            bool
+		Tracing               bool
+		Profiling             bool
+		Coverage              bool
+		InputDecoder          bool
+		PositionConverter     bool
 		Nolint                bool
 	}{
 		Optimize:              b.optimize,
 		BasicLatinLookupTable: b.basicLatinLookupTable,
 		GlobalState:           b.globalState,
 		LeftRecursion:         b.haveLeftRecursion,
+		BackReference:         len(b.backRefs.rules) > 0,
+		ByteMode:              b.byteMode,
+		Indentation:           b.indentation,
+		MemoRules:             len(b.memoRules) > 0,
+		CommitRules:           len(b.commitRules) > 0,
+		Precedence:            len(b.precedence) > 0,
+		Tracing:               b.tracing && !b.optimize,
+		Profiling:             b.profiling && !b.optimize,
+		Coverage:              b.coverage && !b.optimize,
+		InputDecoder:          b.inputDecoder && !b.byteMode,
+		PositionConverter:     b.positionConverter,
 		Nolint:                b.nolint,
 	}
 	t := template.Must(template.New("static_code").Parse(staticCode))
@@ -821,6 +1074,20 @@ func (b *builder) writeStaticCode() {
 	}
 }
 
+// writeDoc writes doc, the doc comment text of a rule, as a Go comment.
+func (b *builder) writeDoc(doc string) {
+	if doc == "" {
+		return
+	}
+	for _, l := range strings.Split(strings.TrimSuffix(doc, "\n"), "\n") {
+		if l == "" {
+			b.writelnf("//")
+			continue
+		}
+		b.writelnf("// %s", l)
+	}
+}
+
 func (b *builder) funcName(ix int) string {
 	return "on" + b.ruleName + strconv.Itoa(ix)
 }
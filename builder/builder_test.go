@@ -1,10 +1,12 @@
 package builder
 
 import (
+	"bytes"
 	"io"
 	"strings"
 	"testing"
 
+	"github.com/mna/pigeon/ast"
 	"github.com/mna/pigeon/bootstrap"
 )
 
@@ -38,3 +40,108 @@ func TestBuildParser(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestBuildParserDoc(t *testing.T) {
+	src := `
+// start is the entry point.
+//
+// It matches a list.
+start = 'a'+ { return nil, nil } // not a doc comment
+
+/* b matches
+     the letter b.
+*/
+b = 'b'
+`
+	p := bootstrap.NewParser()
+	g, err := p.Parse("", strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := BuildParser(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"// start is the entry point.\n//\n// It matches a list.\n{\n\tname: \"start\",",
+		"// b matches\n// the letter b.\n{\n\tname: \"b\",",
+		"// onstart1 is a code block of rule start:\n//\n// start is the entry point.\n//\n// It matches a list.\nfunc (c *current) onstart1()",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("want output to contain %q", want)
+		}
+	}
+	if strings.Contains(out, "not a doc comment") {
+		t.Errorf("want trailing comment not to be a doc comment")
+	}
+}
+
+func TestBuildParserAPI(t *testing.T) {
+	p := bootstrap.NewParser()
+	g, err := p.Parse("", strings.NewReader(grammar))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		opt   Option
+		decls []string
+	}{
+		{"tracing", Tracing(true), []string{"func Trace(", "type Tracer ", "type Position ", "type Span "}},
+		{"profiling", Profiling(true), []string{"func Profiling(", "type Profile ", "type ProfileEntry ", "type ProfileStack "}},
+		{"coverage", Coverage(true), []string{"func Coverage(", "type CoverProfile "}},
+		{"inputDecoder", InputDecoder(true), []string{"func InputDecoder(", "type Decoder ", "func DecodeUTF8(", "func DecodeLatin1("}},
+		{"positionConverter", PositionConverter(true), []string{"type PositionConverter ", "func NewPositionConverter(", "type ColumnUnit ", "func TabWidth("}},
+	} {
+		for _, want := range []bool{false, true} {
+			var opts []Option
+			if want {
+				opts = append(opts, tc.opt)
+			}
+			var buf bytes.Buffer
+			if err := BuildParser(&buf, g, opts...); err != nil {
+				t.Fatal(err)
+			}
+			for _, decl := range tc.decls {
+				if got := strings.Contains(buf.String(), decl); got != want {
+					t.Errorf("%s %t: want %q declared %t, got %t", tc.name, want, decl, want, got)
+				}
+			}
+		}
+	}
+}
+
+func TestBuildParserStateClone(t *testing.T) {
+	for _, state := range []bool{false, true} {
+		seq := ast.NewSeqExpr(ast.Pos{})
+		if state {
+			st := ast.NewStateCodeExpr(ast.Pos{})
+			st.Code = ast.NewCodeBlock(ast.Pos{}, "{ return nil }")
+			seq.Exprs = append(seq.Exprs, st)
+		}
+		seq.Exprs = append(seq.Exprs, ast.NewLitMatcher(ast.Pos{}, "b"))
+		ch := ast.NewChoiceExpr(ast.Pos{})
+		ch.Alternatives = []ast.Expression{ast.NewLitMatcher(ast.Pos{}, "a"), seq}
+
+		g := ast.NewGrammar(ast.Pos{})
+		rule := ast.NewRule(ast.Pos{}, ast.NewIdentifier(ast.Pos{}, "A"))
+		rule.Expr = ch
+		g.Rules = []*ast.Rule{rule}
+
+		var buf bytes.Buffer
+		if err := BuildParser(&buf, g); err != nil {
+			t.Fatal(err)
+		}
+		// only the state code blocks change the state between the
+		// alternatives of a choice
+		code := buf.String()
+		start := strings.Index(code, "func (p *parser) parseChoiceExpr(")
+		end := start + strings.Index(code[start:], "\n}\n")
+		if got := strings.Contains(code[start:end], "p.cloneState()"); got != state {
+			t.Errorf("state %t: want the choice to clone the state %t, got %t", state, state, got)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mna/pigeon/ast"
+)
+
+// ErrInvalidByteClass is returned when a character class matcher cannot
+// be matched against single bytes in byte mode.
+var ErrInvalidByteClass = errors.New("invalid character class in byte mode")
+
+// checkByteMode validates that all character class matchers of the grammar
+// only contain byte values, as there is no rune decoding in byte mode.
+func checkByteMode(g *ast.Grammar) error {
+	var err error
+	for _, rule := range g.Rules {
+		ast.Inspect(rule.Expr, func(expr ast.Expression) bool {
+			chr, ok := expr.(*ast.CharClassMatcher)
+			if !ok || err != nil {
+				return err == nil
+			}
+			if len(chr.UnicodeClasses) > 0 {
+				err = fmt.Errorf("rule %s: %s: %w %s: Unicode classes are not supported",
+					rule.Name.Val, chr.Pos(), ErrInvalidByteClass, chr.Val)
+				return false
+			}
+			for _, rn := range append(chr.Chars, chr.Ranges...) {
+				if rn > 0xff {
+					err = fmt.Errorf("rule %s: %s: %w %s: %#U is not a byte value",
+						rule.Name.Val, chr.Pos(), ErrInvalidByteClass, chr.Val, rn)
+					return false
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toLowerASCII returns s with the ASCII letters mapped to lower case. All
+// other bytes are left untouched, valid UTF-8 or not.
+func toLowerASCII(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,40 @@
+package builder
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/mna/pigeon/ast"
+)
+
+func TestByteModeCharClass(t *testing.T) {
+	cases := map[string]bool{
+		`[\x00-\x1f]`: true,
+		`[^\xff]`:     true,
+		`[a-zé]i`:     true,
+		`[\pL]`:       false,
+		`[aĀ]`:        false,
+		`[\x00-ā]`:    false,
+	}
+
+	for val, valid := range cases {
+		g := ast.NewGrammar(ast.Pos{})
+		rule := ast.NewRule(ast.Pos{}, ast.NewIdentifier(ast.Pos{}, "A"))
+		rule.Expr = ast.NewCharClassMatcher(ast.Pos{}, val)
+		g.Rules = []*ast.Rule{rule}
+
+		err := BuildParser(io.Discard, g, ByteMode(true))
+		if valid && err != nil {
+			t.Errorf("%s: want no error, got %v", val, err)
+		}
+		if !valid && !errors.Is(err, ErrInvalidByteClass) {
+			t.Errorf("%s: want %v, got %v", val, ErrInvalidByteClass, err)
+		}
+
+		// all classes are valid when decoding runes
+		if err := BuildParser(io.Discard, g); err != nil {
+			t.Errorf("%s: want no error without byte mode, got %v", val, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package builder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"strconv"
+
+	"github.com/mna/pigeon/ast"
+)
+
+// FuzzMaxExpressions is the maximum number of expressions of the parses
+// of the fuzz test written by the FuzzTest option, past which a parse is
+// considered not to terminate and fails the test.
+const FuzzMaxExpressions = 1_000_000
+
+// ErrNoPackage is returned by the FuzzTest option if the package of the
+// parser cannot be found in the initializer of the grammar.
+var ErrNoPackage = errors.New("no package clause in the initializer of the grammar")
+
+const fuzzTestHeader = `// Code generated by pigeon; DO NOT EDIT.
+
+package %s
+
+import (
+	"errors"
+	"testing"
+)
+
+// FuzzParse checks that the parser does not panic, that it completes
+// the parse within %d expressions and that the positions of its errors
+// are in the input. A parse that reaches the limit of expressions fails
+// the test, as it likely does not terminate.
+func FuzzParse(f *testing.F) {
+`
+
+const fuzzTestFooter = `	f.Fuzz(func(t *testing.T, b []byte) {
+		defer func() {
+			if e := recover(); e != nil {
+				if e != errMaxExprCnt {
+					panic(e)
+				}
+				t.Errorf("parse not completed within %%d expressions", %d)
+			}
+		}()
+		_, err := Parse("fuzz", b, Recover(false), MaxExpressions(%d))
+		var errs errList
+		if !errors.As(err, &errs) {
+			return
+		}
+		for _, err := range errs {
+			var pe *parserError
+			if !errors.As(err, &pe) {
+				continue
+			}
+			if pe.pos.line < 1 || pe.pos.col < 0 || pe.pos.offset < 0 || pe.pos.offset > len(b) {
+				t.Errorf("error position %%s out of the input of %%d bytes: %%v", pe.pos, len(b), err)
+			}
+		}
+	})
+}
+`
+
+// writeFuzzTest writes to w the fuzz test of the parser of g, in the
+// package of its initializer, with seeds as its seed corpus.
+func writeFuzzTest(w io.Writer, g *ast.Grammar, seeds [][]byte) error {
+	if g.Init == nil {
+		return ErrNoPackage
+	}
+	// remove opening and closing braces
+	src := g.Init.Val[1 : len(g.Init.Val)-1]
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.PackageClauseOnly)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNoPackage, err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, fuzzTestHeader, f.Name.Name, FuzzMaxExpressions)
+	for _, seed := range seeds {
+		fmt.Fprintf(&buf, "\tf.Add([]byte(%s))\n", strconv.Quote(string(seed)))
+	}
+	if len(seeds) > 0 {
+		buf.WriteString("\n")
+	}
+	fmt.Fprintf(&buf, fuzzTestFooter, FuzzMaxExpressions, FuzzMaxExpressions)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,45 @@
+package builder
+
+import (
+	"bytes"
+	"errors"
+	"go/parser"
+	"go/token"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mna/pigeon/bootstrap"
+)
+
+func TestFuzzTest(t *testing.T) {
+	p := bootstrap.NewParser()
+	g, err := p.Parse("", strings.NewReader("{\n// Package calc.\npackage calc\n}\nstart = 'a'+\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := BuildParser(io.Discard, g, FuzzTest(&buf, []byte("a"), []byte("\x00\n"))); err != nil {
+		t.Fatal(err)
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), "calc_test.go", buf.Bytes(), 0)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, buf.Bytes())
+	}
+	if f.Name.Name != "calc" {
+		t.Errorf("want package calc, got %s", f.Name.Name)
+	}
+	for _, want := range []string{"func FuzzParse(f *testing.F)", `f.Add([]byte("a"))`, `f.Add([]byte("\x00\n"))`} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("want %s in the fuzz test:\n%s", want, buf.Bytes())
+		}
+	}
+
+	g, err = p.Parse("", strings.NewReader("start = 'a'+\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := BuildParser(io.Discard, g, FuzzTest(io.Discard)); !errors.Is(err, ErrNoPackage) {
+		t.Errorf("want %v, got %v", ErrNoPackage, err)
+	}
+}
@@ -18,6 +18,13 @@ var (
 	// errMaxExprCnt is used to signal that the maximum number of
 	// expressions have been parsed.
 	errMaxExprCnt = errors.New("max number of expressions parsed")
+	// ==template== {{ if .Indentation }}
+
+	// errInconsistentIndent is returned when comparing the indentation
+	// of a line to the current indentation level depends on the width of
+	// a tab.
+	errInconsistentIndent = errors.New("inconsistent use of tabs and spaces in indentation")
+	// {{ end }} ==template==
 )
 
 // Option is a function that can set an option on the parser. It returns
@@ -88,20 +95,108 @@ func Statistics(stats *Stats, choiceNoMatch string) Option {
 
 // Debug creates an Option to set the debug flag to b. When set to true,
 // debugging information is printed to stdout while parsing.
+// ==template== {{ if .Tracing }}
+// This replaces the Tracer set with the Trace option.
+// {{ end }} ==template==
 //
 // The default is false.
 func Debug(b bool) Option {
 	return func(p *parser) Option {
-		old := p.debug
-		p.debug = b
-		return Debug(old)
+		old := p.tracer
+		if b {
+			p.tracer = &debugTracer{p: p}
+		} else if _, ok := old.(*debugTracer); ok {
+			p.tracer = nil
+		}
+		return setTracer(old)
+	}
+}
+
+// ==template== {{ if .Tracing }}
+// Trace creates an Option to set the Tracer that receives the events
+// of the parse, e.g. to log them or to visualize the parse. A nil
+// Tracer disables the tracing.
+//
+// The default is nil.
+func Trace(t Tracer) Option {
+	if t == nil {
+		return setTracer(nil)
+	}
+	return setTracer(userTracer{t: t})
+}
+
+// {{ end }} ==template==
+// setTracer creates an Option to set the tracer that receives the events
+// of the parse.
+func setTracer(t tracer) Option {
+	return func(p *parser) Option {
+		old := p.tracer
+		p.tracer = t
+		return setTracer(old)
 	}
 }
 
+// ==template== {{ if .Profiling }}
+// Profiling creates an Option to gather the profile of the parse in
+// prof: the evaluations, matches and failures of the rules and of the
+// expressions, the bytes that they consumed and backtracked, their hits
+// in the memoization table and the time spent. The profiles of several
+// parses can be gathered in the same prof. Its JSON encoding is read by
+// the pigeon profile command, that reports it.
+//
+// The default is nil, no profiling.
+func Profiling(prof *Profile) Option {
+	return func(p *parser) Option {
+		var old *Profile
+		if p.prof != nil {
+			old = p.prof.Profile
+		}
+		p.prof = nil
+		if prof != nil {
+			p.prof = newProfiler(prof)
+		}
+		return Profiling(old)
+	}
+}
+
+// {{ end }} ==template==
+// ==template== {{ if .Coverage }}
+// Coverage creates an Option to gather the coverage of the grammar by
+// the parse in cov: the matches of the rules and of the alternatives of
+// the choices, and the input characters in each branch of the character
+// classes. The coverage of several parses can be gathered in the same
+// cov. Its JSON encoding is read by the pigeon cover command, that
+// reports it.
+//
+// The default is nil, no coverage.
+func Coverage(cov *CoverProfile) Option {
+	return func(p *parser) Option {
+		old := p.cov
+		p.cov = cov
+		if cov != nil {
+			if cov.Rules == nil {
+				cov.Rules = make(map[string]int64)
+			}
+			if cov.Alternatives == nil {
+				cov.Alternatives = make(map[string]int64)
+			}
+			if cov.Classes == nil {
+				cov.Classes = make(map[string]int64)
+			}
+		}
+		return Coverage(old)
+	}
+}
+
+// {{ end }} ==template==
+
 // Memoize creates an Option to set the memoize flag to b. When set to true,
 // the parser will cache all results so each expression is evaluated only
 // once. This guarantees linear parsing time even for pathological cases,
 // at the expense of more memory and slower times for typical cases.
+// The results of the rules with the @memoize annotation in the grammar
+// are cached even if b is false, without the cost of caching every
+// expression.
 //
 // The default is false.
 func Memoize(b bool) Option {
@@ -114,10 +209,35 @@ func Memoize(b bool) Option {
 
 // {{ end }} ==template==
 
+// ==template== {{ if or .MemoRules (not .Optimize) }}
+
+// MemoWindow creates an Option to bound the memory of the memoization
+// table: the results are kept only for the n offsets behind the furthest
+// offset where a result was cached, as the parser rarely backtracks
+// further. The results evicted from the window are evaluated again if
+// needed, the memory is O(n) instead of O(len(input)). The rules with
+// the @commit annotation in the grammar also discard the results before
+// the end of their match. If n is 0, all the results are kept.
+//
+// The default is 0.
+func MemoWindow(n int) Option {
+	return func(p *parser) Option {
+		old := p.memoWindow
+		p.memoWindow = n
+		return MemoWindow(old)
+	}
+}
+
+// {{ end }} ==template==
+
 // AllowInvalidUTF8 creates an Option to allow invalid UTF-8 bytes.
 // Every invalid UTF-8 byte is treated as a utf8.RuneError (U+FFFD)
 // by character class matchers and is matched by the any matcher.
 // The returned matched value, c.text and c.offset are NOT affected.
+// ==template== {{ if .InputDecoder }}
+// It also allows the bytes that are invalid for the InputDecoder, which
+// are then transcoded to U+FFFD in the matched value and c.text.
+// {{ end }} ==template==
 //
 // The default is false.
 func AllowInvalidUTF8(b bool) Option {
@@ -128,6 +248,105 @@ func AllowInvalidUTF8(b bool) Option {
 	}
 }
 
+// ==template== {{ if or .Indentation .PositionConverter }}
+
+// TabWidth creates an Option to set the number of columns a tab advances
+// ==template== {{ if and .Indentation .PositionConverter }}
+// to, for the ColumnTabs unit of a PositionConverter and when computing
+// the width of the indentation for the %INDENT, %DEDENT and %SAMEDENT
+// expressions. Values lower than 1 are treated as 1.
+// {{ else if .Indentation }}
+// to when computing the width of the indentation for the %INDENT, %DEDENT
+// and %SAMEDENT expressions. Values lower than 1 are treated as 1.
+// {{ else }}
+// to, for the ColumnTabs unit of a PositionConverter. Values lower than 1
+// are treated as 1.
+// {{ end }} ==template==
+//
+// The default is 8.
+func TabWidth(n int) Option {
+	return func(p *parser) Option {
+		old := p.tabWidth
+		p.tabWidth = max(n, 1)
+		return TabWidth(old)
+	}
+}
+
+// {{ end }} ==template==
+
+// ==template== {{ if .InputDecoder }}
+
+// Decoder decodes the first character of b in the encoding of the input.
+// It returns the character and its width in bytes, and a width of 0 if b
+// is empty. If the first bytes of b are not valid in the encoding, valid is
+// false and rn is utf8.RuneError.
+type Decoder func(b []byte) (rn rune, width int, valid bool)
+
+// InputDecoder creates an Option to set the decoder of the input. The
+// input is transcoded to UTF-8 as it is parsed: the matchers see the
+// decoded characters and the matched text is UTF-8, while the offsets in
+// positions remain relative to the original bytes. DecodeUTF8,
+// DecodeLatin1, DecodeUTF16LE and DecodeUTF16BE are available, any other
+// encoding can be supported with a custom Decoder. Passing nil decodes
+// the input as UTF-8.
+//
+// The default is to decode the input as UTF-8.
+func InputDecoder(dec Decoder) Option {
+	return func(p *parser) Option {
+		old := p.decoder
+		p.decoder = dec
+		return InputDecoder(old)
+	}
+}
+
+// DecodeUTF8 is the Decoder of UTF-8 encoded input.
+func DecodeUTF8(b []byte) (rune, int, bool) {
+	rn, n := utf8.DecodeRune(b)
+	return rn, n, rn != utf8.RuneError || n != 1 // see utf8.DecodeRune
+}
+
+// DecodeLatin1 is the Decoder of ISO-8859-1 (Latin-1) encoded input,
+// where each byte is the code point of a character.
+func DecodeLatin1(b []byte) (rune, int, bool) {
+	if len(b) == 0 {
+		return utf8.RuneError, 0, true
+	}
+	return rune(b[0]), 1, true
+}
+
+// DecodeUTF16LE is the Decoder of little-endian UTF-16 encoded input.
+func DecodeUTF16LE(b []byte) (rune, int, bool) {
+	return decodeUTF16(b, func(b []byte) rune { return rune(b[0]) | rune(b[1])<<8 })
+}
+
+// DecodeUTF16BE is the Decoder of big-endian UTF-16 encoded input.
+func DecodeUTF16BE(b []byte) (rune, int, bool) {
+	return decodeUTF16(b, func(b []byte) rune { return rune(b[0])<<8 | rune(b[1]) })
+}
+
+func decodeUTF16(b []byte, unit func([]byte) rune) (rune, int, bool) {
+	switch {
+	case len(b) == 0:
+		return utf8.RuneError, 0, true
+	case len(b) == 1:
+		// truncated code unit
+		return utf8.RuneError, 1, false
+	}
+	r1 := unit(b)
+	if !utf16.IsSurrogate(r1) {
+		return r1, 2, true
+	}
+	if len(b) >= 4 {
+		if rn := utf16.DecodeRune(r1, unit(b[2:])); rn != utf8.RuneError {
+			return rn, 4, true
+		}
+	}
+	// unpaired surrogate
+	return utf8.RuneError, 2, false
+}
+
+// {{ end }} ==template==
+
 // Recover creates an Option to set the recover flag to b. When set to
 // true, this causes the parser to recover from panics and convert it
 // to an error. Setting it to false can be useful while debugging to
@@ -206,12 +425,164 @@ func (p position) String() string {
 	return strconv.Itoa(p.line) + ":" + strconv.Itoa(p.col) + " [" + strconv.Itoa(p.offset) + "]"
 }
 
+// ==template== {{ if .PositionConverter }}
+
+// ColumnUnit is the unit in which a PositionConverter counts columns.
+type ColumnUnit int
+
+// List of column units.
+const (
+	// ColumnBytes counts the bytes of the input, i.e. UTF-8 code units
+	// for UTF-8 encoded input.
+	ColumnBytes ColumnUnit = iota
+	// ColumnRunes counts the characters, as the positions of the parser.
+	ColumnRunes
+	// ColumnUTF16 counts the UTF-16 code units of the characters, as the
+	// default position encoding of the Language Server Protocol.
+	ColumnUTF16
+	// ColumnTabs counts the characters, with tabs advancing to the next
+	// tab stop (see the TabWidth option), as displayed by a terminal.
+	ColumnTabs
+)
+
+// PositionConverter maps the byte offsets of an input, such as the offset
+// of c.pos in the code blocks, to lines and columns in a ColumnUnit, and
+// back. Lines and columns are 1-based, a newline ends the line it is on.
+type PositionConverter struct {
+	p *parser
+	// offsets of the start of the lines, built on first use
+	lines []int
+}
+
+// NewPositionConverter creates a PositionConverter for the input b. The
+// options that decode the input and TabWidth must be the ones used to
+// parse b, the other options are ignored.
+func NewPositionConverter(b []byte, opts ...Option) *PositionConverter { //{{ if .Nolint }} nolint: deadcode {{else}} ==template== {{ end }}
+	return &PositionConverter{p: newParser("", b, opts...)}
+}
+
+// Position returns the line and the column in unit of the byte offset in
+// the input. An offset inside a multi-byte character is at the column of
+// that character.
+func (pc *PositionConverter) Position(offset int, unit ColumnUnit) (line, col int) {
+	pc.index()
+	offset = min(max(offset, 0), len(pc.p.data))
+	line = sort.Search(len(pc.lines), func(i int) bool { return pc.lines[i] > offset })
+
+	col = 1
+	for i := pc.lines[line-1]; i < offset; {
+		rn, n := pc.decode(i)
+		if i+n > offset {
+			break
+		}
+		col = pc.advance(col, rn, n, unit)
+		i += n
+	}
+	return line, col
+}
+
+// Offset returns the byte offset in the input of the line and the column
+// in unit. A column past the end of the line is at the end of the line,
+// before the newline, and a line past the end of the input is at the end
+// of the input.
+func (pc *PositionConverter) Offset(line, col int, unit ColumnUnit) int {
+	pc.index()
+	if line < 1 {
+		return 0
+	}
+	if line > len(pc.lines) {
+		return len(pc.p.data)
+	}
+
+	i, c := pc.lines[line-1], 1
+	for i < len(pc.p.data) {
+		rn, n := pc.decode(i)
+		next := pc.advance(c, rn, n, unit)
+		if rn == '\n' || next > col {
+			break
+		}
+		i, c = i+n, next
+	}
+	return i
+}
+
+// ErrorPosition returns the line and the column in unit at which err
+// occurred, for an error returned by the parser. When the parser returns
+// multiple errors, it is the position of the first one, the others are
+// available with the Unwrap() []error method of the returned error. It
+// returns false if err has no position.
+func (pc *PositionConverter) ErrorPosition(err error, unit ColumnUnit) (line, col int, ok bool) {
+	var perr *parserError
+	if !errors.As(err, &perr) {
+		return 0, 0, false
+	}
+	line, col = pc.Position(perr.pos.offset, unit)
+	return line, col, true
+}
+
+// index builds the index of the start of the lines. The input is decoded,
+// as the bytes of a newline may be part of another character in some
+// encodings, e.g. UTF-16.
+func (pc *PositionConverter) index() {
+	if pc.lines != nil {
+		return
+	}
+	pc.lines = append(pc.lines, 0)
+	for i := 0; i < len(pc.p.data); {
+		rn, n := pc.decode(i)
+		i += n
+		if rn == '\n' {
+			pc.lines = append(pc.lines, i)
+		}
+	}
+}
+
+// decode decodes the character at offset i of the input.
+func (pc *PositionConverter) decode(i int) (rune, int) {
+	// ==template== {{ if .ByteMode }}
+	return rune(pc.p.data[i]), 1
+	// {{ else }}
+	rn, n, _ := pc.p.decode(pc.p.data[i:])
+	if n == 0 {
+		// truncated input, should not happen with a valid decoder
+		n = len(pc.p.data) - i
+	}
+	return rn, n
+	// {{ end }} ==template==
+}
+
+// advance returns the column after the character rn of n bytes at column
+// col.
+func (pc *PositionConverter) advance(col int, rn rune, n int, unit ColumnUnit) int {
+	switch unit {
+	case ColumnBytes:
+		return col + n
+	case ColumnUTF16:
+		if rn >= 0x10000 && rn <= utf8.MaxRune {
+			return col + 2
+		}
+		return col + 1
+	case ColumnTabs:
+		if rn == '\t' {
+			return col + pc.p.tabWidth - (col-1)%pc.p.tabWidth
+		}
+		return col + 1
+	default:
+		return col + 1
+	}
+}
+
+// {{ end }} ==template==
+
 // savepoint stores all state required to go back to this point in the
 // parser.
 type savepoint struct {
 	position
 	rn rune
 	w  int
+	// ==template== {{ if .Indentation }}
+	indent *indentLevel
+	// {{ end }} ==template==
 }
 
 type current struct {
@@ -256,6 +627,15 @@ type rule struct {
 	leader        bool
 	leftRecursive bool
 	// {{ end }} ==template==
+	// ==template== {{ if .BackReference }}
+	backRef bool
+	// {{ end }} ==template==
+	// ==template== {{ if .MemoRules }}
+	memoize bool
+	// {{ end }} ==template==
+	// ==template== {{ if .CommitRules }}
+	commit bool
+	// {{ end }} ==template==
 }
 
 // {{ if .Nolint }} nolint: structcheck {{else}} ==template== {{ end }}
@@ -271,6 +651,34 @@ type actionExpr struct {
 	run  func(*parser) (any, error)
 }
 
+// ==template== {{ if .Precedence }}
+
+// precedenceExpr matches the operations of a precedence table by
+// precedence climbing, with primary as the operand. The operators are
+// sorted by decreasing length so that the longest one matches.
+// {{ if .Nolint }} nolint: structcheck {{else}} ==template== {{ end }}
+type precedenceExpr struct {
+	pos     position
+	primary any
+	skip    any
+	prefix  []*precedenceOp
+	infix   []*precedenceOp
+	postfix []*precedenceOp
+	run     func(*parser) (any, error)
+}
+
+// precedenceOp is an operator of a precedence table, the level of the
+// lowest precedence is 1.
+// {{ if .Nolint }} nolint: structcheck {{else}} ==template== {{ end }}
+type precedenceOp struct {
+	lit      *litMatcher
+	level    int
+	right    bool
+	nonAssoc bool
+}
+
+// {{ end }} ==template==
+
 // {{ if .Nolint }} nolint: structcheck {{else}} ==template== {{ end }}
 type recoveryExpr struct {
 	pos          position
@@ -296,6 +704,9 @@ type labeledExpr struct {
 	pos   position
 	label string
 	expr  any
+	// ==template== {{ if .BackReference }}
+	textCapture bool
+	// {{ end }} ==template==
 }
 
 // {{ if .Nolint }} nolint: structcheck {{else}} ==template== {{ end }}
@@ -312,6 +723,33 @@ type (
 	oneOrMoreExpr  expr //{{ if .Nolint }} nolint: structcheck {{else}} ==template== {{ end }}
 )
 
+// ==template== {{ if .BackReference }}
+
+// {{ if .Nolint }} nolint: structcheck {{else}} ==template== {{ end }}
+type backRefExpr struct {
+	pos   position
+	label string
+}
+
+// {{ end }} ==template==
+
+// ==template== {{ if .Indentation }}
+
+const (
+	indentOp = iota
+	dedentOp
+	samedentOp
+)
+
+// {{ if .Nolint }} nolint: structcheck {{else}} ==template== {{ end }}
+type indentExpr struct {
+	pos  position
+	op   int
+	want string
+}
+
+// {{ end }} ==template==
+
 // {{ if .Nolint }} nolint: structcheck {{else}} ==template== {{ end }}
 type ruleRefExpr struct {
 	pos  position
@@ -389,6 +827,11 @@ func (e *errList) dedupe() {
 	*e = cleaned
 }
 
+// Unwrap returns the errors of the list.
+func (e errList) Unwrap() []error {
+	return e
+}
+
 func (e errList) Error() string {
 	switch len(e) {
 	case 0:
@@ -445,6 +888,9 @@ func newParser(filename string, b []byte, opts ...Option) *parser {
 		Stats:           &stats,
 		// start rule is rule [0] unless an alternate entrypoint is specified
 		entrypoint: g.rules[0].name,
+		// ==template== {{ if or .Indentation .PositionConverter }}
+		tabWidth: 8,
+		// {{ end }} ==template==
 	}
 	p.setOptions(opts)
 
@@ -482,7 +928,8 @@ type Stats struct {
 	// ChoiceAltCnt is used to count for each ordered choice expression,
 	// which alternative is used how may times.
 	// These numbers allow to optimize the order of the ordered choice expression
-	// to increase the performance of the parser
+	// to increase the performance of the parser, saved as JSON for the
+	// -choice-stats flag of pigeon.
 	//
 	// The outer key of ChoiceAltCnt is composed of the name of the rule as well
 	// as the line and the column of the ordered choice.
@@ -494,6 +941,147 @@ type Stats struct {
 	ChoiceAltCnt map[string]map[string]int
 }
 
+// ==template== {{ if not .Optimize }}
+
+// ==template== {{ if .Tracing }}
+
+// Tracer receives the events of a parse, as set with the Trace option.
+type Tracer interface {
+	// EnterRule is called when the parser starts to match rule at pos.
+	EnterRule(rule string, pos Position)
+	// ExitRule is called when the parser is done with rule, with whether
+	// it matched and the span of the input that it consumed.
+	ExitRule(rule string, matched bool, span Span)
+	// EnterExpr and ExitExpr are called around the other steps of the
+	// parser, e.g. "parseSeqExpr", with the position at that time.
+	EnterExpr(step string, pos Position)
+	ExitExpr(step string, pos Position)
+	// Backtrack is called when the parser moves back from the position
+	// from to the earlier position to.
+	Backtrack(from, to Position)
+	// Memo is called when the parser looks up the result of rule at pos
+	// in the memoization table, hit reports whether it was found.
+	Memo(rule string, pos Position, hit bool)
+	// Error is called with each error recorded by the parser.
+	Error(err error)
+}
+
+// Position is a position in the input, as reported to a Tracer. Line
+// and Col are 1-based, Col counts characters and Offset bytes.
+type Position struct {
+	Line, Col, Offset int
+}
+
+// Span is the range of the input from Start to End, End excluded.
+type Span struct {
+	Start, End Position
+}
+
+// {{ end }} ==template==
+// ==template== {{ if .Profiling }}
+
+// Profile stores the statistics of the rules and the expressions of the
+// grammar, gathered with the Profiling option.
+type Profile struct {
+	// Rules maps the name of the rules to their statistics.
+	Rules map[string]*ProfileEntry
+	// Exprs maps the expressions to their statistics. The key is
+	// composed of the name of the rule, the line and the column of the
+	// expression and its type, e.g. "Sum 3:7 choiceExpr".
+	Exprs map[string]*ProfileEntry
+	// Stacks maps the stacks of rules, the names of the rules from the
+	// outermost one separated by ";", to the evaluations of the innermost
+	// rule and the time spent in it, excluding the rules that it invoked.
+	Stacks map[string]*ProfileStack
+}
+
+// ProfileEntry stores the statistics of a rule or an expression.
+type ProfileEntry struct {
+	// Calls is the number of evaluations, Matches and Failures their
+	// outcomes, and Revisits the evaluations at an offset where the same
+	// rule or expression was already evaluated in the parse. The results
+	// found in the memoization table are counted in MemoHits instead.
+	Calls    int64
+	Matches  int64
+	Failures int64
+	Revisits int64
+	MemoHits int64
+	// Bytes is the number of bytes consumed by the matches, Backtracked
+	// the number of bytes given back when backtracking.
+	Bytes       int64
+	Backtracked int64
+	// Time is the cumulative time of the evaluations, the recursive ones
+	// being counted once.
+	Time time.Duration
+}
+
+// ProfileStack stores the statistics of a stack of rules.
+type ProfileStack struct {
+	Calls int64
+	Time  time.Duration
+}
+
+// {{ end }} ==template==
+// ==template== {{ if .Coverage }}
+
+// CoverProfile stores the coverage of the grammar, gathered with the
+// Coverage option. The results found in the memoization table are not
+// counted.
+type CoverProfile struct {
+	// Rules maps the name of the rules to their number of matches.
+	Rules map[string]int64
+	// Alternatives maps the alternatives of the choices to their number
+	// of matches. The key is composed of the name of the rule, the line
+	// and the column of the choice and the one-based index of the
+	// alternative, e.g. "Sum 3:7 2".
+	Alternatives map[string]int64
+	// Classes maps the branches of the character classes, their
+	// characters, ranges and Unicode classes in this order, to the number
+	// of input characters that they contain. The key is composed as for
+	// Alternatives, with the one-based index of the branch, e.g.
+	// "Digit 5:9 1". The index 0 counts the matches of the class.
+	Classes map[string]int64
+}
+
+// {{ end }} ==template==
+// {{ end }} ==template==
+
+// ==template== {{ if .BackReference }}
+
+// capture stores the text matched by a labeled expression that is the
+// target of a back-reference, along with the depth of the vstack at which
+// the label is visible.
+type capture struct {
+	label string
+	text  []byte
+	depth int
+}
+
+// {{ end }} ==template==
+
+// ==template== {{ if .Indentation }}
+
+// indentLevel is a level of the indentation stack. Levels are never
+// modified once created, so the stack is saved and restored along with
+// the savepoint when the parser backtracks. The nil level is the
+// outermost one, at width 0.
+type indentLevel struct {
+	// width of the indentation, with tabs advancing to the next tab stop
+	width int
+	// width of the indentation, with tabs counting as a single column
+	altWidth int
+	parent   *indentLevel
+}
+
+// indentMemoKey is the memoization key of an expression when the result
+// depends on the indentation stack.
+type indentMemoKey struct {
+	node   any
+	indent *indentLevel
+}
+
+// {{ end }} ==template==
+
 // ==template== {{ if .LeftRecursion }}
 type ruleWithExpsStack struct {
 	rule   *rule
@@ -511,23 +1099,43 @@ type parser struct {
 	data []byte
 	errs *errList
 
-	depth   int
 	recover bool
 	// ==template== {{ if not .Optimize }}
-	debug bool
+	tracer tracer
+	// ==template== {{ if .Profiling }}
+	prof *profiler
+	// {{ end }} ==template==
+	// ==template== {{ if .Coverage }}
+	cov *CoverProfile
+	// {{ end }} ==template==
 
 	memoize bool
 	// {{ end }} ==template==
-	// ==template== {{ if or .LeftRecursion (not .Optimize) }}
-	// memoization table for the packrat algorithm:
-	// map[offset in source] map[expression or rule] {value, match}
-	memo map[int]map[any]resultTuple
+	// ==template== {{ if or .MemoRules (not .Optimize) }}
+	// memoization table for the packrat algorithm, the results of the
+	// expressions and the rules by offset in source, in a ring of
+	// memoWindow slots if set
+	memo       []memoSlot
+	memoWindow int
+	// the results before this offset are discarded by the commit points
+	memoFloor int
+	// {{ end }} ==template==
+	// ==template== {{ if .LeftRecursion }}
+	// results of the left-recursive leaders by offset in source, out of
+	// the memoization table so that they are never discarded
+	leaders map[leaderKey]resultTuple
+	// left-recursive leaders being grown, innermost last
+	growing []leaderGrowth
 	// {{ end }} ==template==
 
 	// rules table, maps the rule identifier to the rule node
 	rules map[string]*rule
 	// variables stack, map of label to value
 	vstack []map[string]any
+	// ==template== {{ if .BackReference }}
+	// captures stack, text matched by the labels referenced by back-references
+	captures []capture
+	// {{ end }} ==template==
 	// rule stack, allows identification of the current rule in errors
 	rstack []*rule
 
@@ -542,6 +1150,14 @@ type parser struct {
 	entrypoint string
 
 	allowInvalidUTF8 bool
+	// ==template== {{ if .InputDecoder }}
+	// decoder of the input, nil for UTF-8
+	decoder Decoder
+	// {{ end }} ==template==
+	// ==template== {{ if or .Indentation .PositionConverter }}
+	// number of columns of a tab
+	tabWidth int
+	// {{ end }} ==template==
 
 	*Stats
 
@@ -580,6 +1196,13 @@ func (p *parser) popV() {
 		p.vstack[len(p.vstack)-1] = nil
 	}
 	p.vstack = p.vstack[:len(p.vstack)-1]
+	// ==template== {{ if .BackReference }}
+
+	// drop the captures that are no longer in scope
+	for len(p.captures) > 0 && p.captures[len(p.captures)-1].depth > len(p.vstack) {
+		p.captures = p.captures[:len(p.captures)-1]
+	}
+	// {{ end }} ==template==
 }
 
 // push a recovery expression with its labels to the recoveryStack
@@ -608,31 +1231,323 @@ func (p *parser) popRecovery() {
 }
 
 // ==template== {{ if not .Optimize }}
-func (p *parser) print(prefix, s string) string {
-	if !p.debug {
-		return s
-	}
+func (p *parser) in(s string) string {
+	p.tracer.enterExpr(s, p.pt.position)
+	return s
+}
 
-	fmt.Printf("%s %d:%d:%d: %s [%#U]\n",
-		prefix, p.pt.line, p.pt.col, p.pt.offset, s, p.pt.rn)
+func (p *parser) out(s string) string {
+	p.tracer.exitExpr(s, p.pt.position)
 	return s
 }
 
-func (p *parser) printIndent(mark string, s string) string {
-	return p.print(strings.Repeat(" ", p.depth)+mark, s)
+// tracer receives the events of a parse, with the positions of the
+// parser.
+type tracer interface {
+	enterRule(rule string, pos position)
+	exitRule(rule string, matched bool, start, end position)
+	enterExpr(step string, pos position)
+	exitExpr(step string, pos position)
+	backtrack(from, to position)
+	memo(rule string, pos position, hit bool)
+	error(err error)
+}
+
+// debugTracer is the tracer of the Debug option, it prints the events
+// to stdout, indented by the depth of the steps.
+type debugTracer struct {
+	p     *parser
+	depth int
 }
 
-func (p *parser) in(s string) string {
-	res := p.printIndent(">", s)
-	p.depth++
-	return res
+func (t *debugTracer) print(mark string, pos position, s string) {
+	fmt.Printf("%s%s %d:%d:%d: %s [%#U]\n",
+		strings.Repeat(" ", t.depth), mark, pos.line, pos.col, pos.offset, s, t.p.pt.rn)
 }
 
-func (p *parser) out(s string) string {
-	p.depth--
-	return p.printIndent("<", s)
+func (t *debugTracer) enterRule(rule string, pos position) {
+	t.enterExpr("parseRule "+rule, pos)
+}
+
+func (t *debugTracer) exitRule(rule string, matched bool, start, end position) {
+	if matched {
+		t.print("MATCH", end, string(t.p.slice(start.offset, end.offset)))
+	}
+	t.exitExpr("parseRule "+rule, end)
+}
+
+func (t *debugTracer) enterExpr(step string, pos position) {
+	t.print(">", pos, step)
+	t.depth++
+}
+
+func (t *debugTracer) exitExpr(step string, pos position) {
+	t.depth--
+	t.print("<", pos, step)
+}
+
+func (t *debugTracer) backtrack(from, to position) {
+	t.print("BACKTRACK", to, fmt.Sprintf("from %d:%d:%d", from.line, from.col, from.offset))
+}
+
+func (t *debugTracer) memo(rule string, pos position, hit bool) {
+	if hit {
+		t.print("MEMO", pos, rule)
+	}
+}
+
+func (t *debugTracer) error(err error) {
+	t.print("ERROR", t.p.pt.position, err.Error())
+}
+
+// ==template== {{ if .Tracing }}
+
+// userTracer is the tracer of the Trace option, it reports the events
+// to the Tracer t.
+type userTracer struct {
+	t Tracer
+}
+
+func (u userTracer) enterRule(rule string, pos position) {
+	u.t.EnterRule(rule, pos.toPosition())
+}
+
+func (u userTracer) exitRule(rule string, matched bool, start, end position) {
+	u.t.ExitRule(rule, matched, Span{Start: start.toPosition(), End: end.toPosition()})
+}
+
+func (u userTracer) enterExpr(step string, pos position) {
+	u.t.EnterExpr(step, pos.toPosition())
+}
+
+func (u userTracer) exitExpr(step string, pos position) {
+	u.t.ExitExpr(step, pos.toPosition())
+}
+
+func (u userTracer) backtrack(from, to position) {
+	u.t.Backtrack(from.toPosition(), to.toPosition())
+}
+
+func (u userTracer) memo(rule string, pos position, hit bool) {
+	u.t.Memo(rule, pos.toPosition(), hit)
+}
+
+func (u userTracer) error(err error) {
+	u.t.Error(err)
+}
+
+func (p position) toPosition() Position {
+	return Position{Line: p.line, Col: p.col, Offset: p.offset}
 }
 
+// {{ end }} ==template==
+// ==template== {{ if .Profiling }}
+
+// profiler gathers the Profile of the Profiling option.
+type profiler struct {
+	*Profile
+
+	// entries of the expressions, number of evaluations in progress of
+	// each entry, and stacks of the rules and expressions being evaluated
+	exprs  map[any]*ProfileEntry
+	active map[*ProfileEntry]int
+	rules  []profileFrame
+	stack  []profileFrame
+	// offsets of the evaluations of each entry, to count the revisits
+	visited map[profileVisit]bool
+}
+
+// profileVisit is the evaluation of an entry at an offset.
+type profileVisit struct {
+	entry  *ProfileEntry
+	offset int
+}
+
+// profileFrame is an evaluation in progress of a rule or an expression.
+type profileFrame struct {
+	entry *ProfileEntry
+	start time.Time
+	// for the rules, stack of rules and time spent in the nested rules
+	stack string
+	child time.Duration
+}
+
+func newProfiler(prof *Profile) *profiler {
+	if prof.Rules == nil {
+		prof.Rules = make(map[string]*ProfileEntry)
+	}
+	if prof.Exprs == nil {
+		prof.Exprs = make(map[string]*ProfileEntry)
+	}
+	if prof.Stacks == nil {
+		prof.Stacks = make(map[string]*ProfileStack)
+	}
+	return &profiler{
+		Profile: prof,
+		exprs:   make(map[any]*ProfileEntry),
+		active:  make(map[*ProfileEntry]int),
+		visited: make(map[profileVisit]bool),
+	}
+}
+
+// rule returns the entry of the rule name.
+func (pr *profiler) rule(name string) *ProfileEntry {
+	e := pr.Rules[name]
+	if e == nil {
+		e = new(ProfileEntry)
+		pr.Rules[name] = e
+	}
+	return e
+}
+
+// expr returns the entry of the expression expr of the rule name.
+func (pr *profiler) expr(name string, expr any) *ProfileEntry {
+	e := pr.exprs[expr]
+	if e == nil {
+		pos := exprPos(expr)
+		typ := fmt.Sprintf("%T", expr)
+		key := fmt.Sprintf("%s %d:%d %s", name, pos.line, pos.col, typ[strings.LastIndexByte(typ, '.')+1:])
+		if e = pr.Exprs[key]; e == nil {
+			e = new(ProfileEntry)
+			pr.Exprs[key] = e
+		}
+		pr.exprs[expr] = e
+	}
+	return e
+}
+
+func (pr *profiler) enter(e *ProfileEntry, offset int) profileFrame {
+	e.Calls++
+	if v := (profileVisit{entry: e, offset: offset}); pr.visited[v] {
+		e.Revisits++
+	} else {
+		pr.visited[v] = true
+	}
+	pr.active[e]++
+	return profileFrame{entry: e, start: time.Now()}
+}
+
+// exit records the outcome of the evaluation f, that consumed n bytes,
+// and returns its duration.
+func (pr *profiler) exit(f profileFrame, ok bool, n int) time.Duration {
+	d := time.Since(f.start)
+	if ok {
+		f.entry.Matches++
+		f.entry.Bytes += int64(n)
+	} else {
+		f.entry.Failures++
+	}
+	pr.active[f.entry]--
+	if pr.active[f.entry] == 0 {
+		f.entry.Time += d
+	}
+	return d
+}
+
+func (pr *profiler) enterRule(name string, offset int) {
+	f := pr.enter(pr.rule(name), offset)
+	f.stack = name
+	if len(pr.rules) > 0 {
+		f.stack = pr.rules[len(pr.rules)-1].stack + ";" + name
+	}
+	pr.rules = append(pr.rules, f)
+}
+
+func (pr *profiler) exitRule(ok bool, n int) {
+	f := pr.rules[len(pr.rules)-1]
+	pr.rules = pr.rules[:len(pr.rules)-1]
+	d := pr.exit(f, ok, n)
+
+	s := pr.Stacks[f.stack]
+	if s == nil {
+		s = new(ProfileStack)
+		pr.Stacks[f.stack] = s
+	}
+	s.Calls++
+	s.Time += d - f.child
+	if len(pr.rules) > 0 {
+		pr.rules[len(pr.rules)-1].child += d
+	}
+}
+
+func (pr *profiler) enterExpr(name string, expr any, offset int) {
+	pr.stack = append(pr.stack, pr.enter(pr.expr(name, expr), offset))
+}
+
+func (pr *profiler) exitExpr(ok bool, n int) {
+	f := pr.stack[len(pr.stack)-1]
+	pr.stack = pr.stack[:len(pr.stack)-1]
+	pr.exit(f, ok, n)
+}
+
+// backtrack records the n bytes given back by the innermost rule and
+// expression being evaluated.
+func (pr *profiler) backtrack(n int) {
+	if len(pr.rules) > 0 {
+		pr.rules[len(pr.rules)-1].entry.Backtracked += int64(n)
+	}
+	if len(pr.stack) > 0 {
+		pr.stack[len(pr.stack)-1].entry.Backtracked += int64(n)
+	}
+}
+
+// exprPos returns the position of the expression expr in the grammar.
+func exprPos(expr any) position {
+	switch expr := expr.(type) {
+	case *actionExpr:
+		return expr.pos
+	case *andCodeExpr:
+		return expr.pos
+	case *andExpr:
+		return expr.pos
+	case *anyMatcher:
+		return position(*expr)
+	// ==template== {{ if .BackReference }}
+	case *backRefExpr:
+		return expr.pos
+	// {{ end }} ==template==
+	case *charClassMatcher:
+		return expr.pos
+	case *choiceExpr:
+		return expr.pos
+	// ==template== {{ if .Indentation }}
+	case *indentExpr:
+		return expr.pos
+	// {{ end }} ==template==
+	case *labeledExpr:
+		return expr.pos
+	case *litMatcher:
+		return expr.pos
+	case *notCodeExpr:
+		return expr.pos
+	case *notExpr:
+		return expr.pos
+	// ==template== {{ if .Precedence }}
+	case *precedenceExpr:
+		return expr.pos
+	// {{ end }} ==template==
+	case *oneOrMoreExpr:
+		return expr.pos
+	case *recoveryExpr:
+		return expr.pos
+	case *ruleRefExpr:
+		return expr.pos
+	case *seqExpr:
+		return expr.pos
+	case *stateCodeExpr:
+		return expr.pos
+	case *throwExpr:
+		return expr.pos
+	case *zeroOrMoreExpr:
+		return expr.pos
+	case *zeroOrOneExpr:
+		return expr.pos
+	default:
+		panic(fmt.Sprintf("unknown expression type %T", expr))
+	}
+}
+
+// {{ end }} ==template==
 // {{ end }} ==template==
 
 func (p *parser) addErr(err error) {
@@ -661,6 +1576,11 @@ func (p *parser) addErrAt(err error, pos position, expected []string) {
 	}
 	pe := &parserError{Inner: err, pos: pos, prefix: buf.String(), expected: expected}
 	p.errs.add(pe)
+	// ==template== {{ if not .Optimize }}
+	if p.tracer != nil {
+		p.tracer.error(pe)
+	}
+	// {{ end }} ==template==
 }
 
 func (p *parser) failAt(fail bool, pos position, want string) {
@@ -682,10 +1602,29 @@ func (p *parser) failAt(fail bool, pos position, want string) {
 	}
 }
 
+// ==template== {{ if .ByteMode }}
+// read advances the parser to the next byte.
+func (p *parser) read() {
+	p.pt.offset += p.pt.w
+	// the end of the input is signaled as utf8.DecodeRune does
+	rn, n := utf8.RuneError, 0
+	if p.pt.offset < len(p.data) {
+		rn, n = rune(p.data[p.pt.offset]), 1
+	}
+	p.pt.rn = rn
+	p.pt.w = n
+	p.pt.col++
+	if rn == '\n' {
+		p.pt.line++
+		p.pt.col = 0
+	}
+}
+
+// {{ else }}
 // read advances the parser to the next rune.
 func (p *parser) read() {
 	p.pt.offset += p.pt.w
-	rn, n := utf8.DecodeRune(p.data[p.pt.offset:])
+	rn, n, valid := p.decode(p.data[p.pt.offset:])
 	p.pt.rn = rn
 	p.pt.w = n
 	p.pt.col++
@@ -694,23 +1633,66 @@ func (p *parser) read() {
 		p.pt.col = 0
 	}
 
-	if rn == utf8.RuneError && n == 1 { // see utf8.DecodeRune
+	if !valid {
 		if !p.allowInvalidUTF8 {
 			p.addErr(errInvalidEncoding)
 		}
 	}
 }
 
-// restore parser position to the savepoint pt.
-func (p *parser) restore(pt savepoint) {
-	// ==template== {{ if not .Optimize }}
-	if p.debug {
-		defer p.out(p.in("restore"))
+// decode decodes the first character of b in the encoding of the input.
+func (p *parser) decode(b []byte) (rune, int, bool) {
+	// ==template== {{ if .InputDecoder }}
+	if p.decoder != nil {
+		return p.decoder(b)
 	}
+	return DecodeUTF8(b)
+	// {{ else }}
+	rn, n := utf8.DecodeRune(b)
+	return rn, n, rn != utf8.RuneError || n != 1 // see utf8.DecodeRune
 	// {{ end }} ==template==
+}
+
+// ==template== {{ if .InputDecoder }}
+// decodeText returns the text b of the input transcoded to UTF-8.
+func (p *parser) decodeText(b []byte) []byte {
+	if p.decoder == nil {
+		return b
+	}
+	text := make([]byte, 0, len(b))
+	for len(b) > 0 {
+		rn, n, _ := p.decoder(b)
+		if n == 0 {
+			break
+		}
+		text = utf8.AppendRune(text, rn)
+		b = b[n:]
+	}
+	return text
+}
+
+// {{ end }} ==template==
+// {{ end }} ==template==
+
+// restore parser position to the savepoint pt.
+func (p *parser) restore(pt savepoint) {
+	// ==template== {{ if .Indentation }}
+	if pt.offset == p.pt.offset && pt.indent == p.pt.indent {
+	// {{ else }}
 	if pt.offset == p.pt.offset {
+	// {{ end }} ==template==
 		return
 	}
+	// ==template== {{ if not .Optimize }}
+	if p.tracer != nil && pt.offset < p.pt.offset {
+		p.tracer.backtrack(p.pt.position, pt.position)
+	}
+	// ==template== {{ if .Profiling }}
+	if p.prof != nil && pt.offset < p.pt.offset {
+		p.prof.backtrack(p.pt.offset - pt.offset)
+	}
+	// {{ end }} ==template==
+	// {{ end }} ==template==
 	p.pt = pt
 }
 
@@ -741,7 +1723,7 @@ func (sd storeDict) Discard() {
 // clone and return parser current state.
 func (p *parser) cloneState() storeDict {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("cloneState"))
 	}
 	// {{ end }} ==template==
@@ -761,7 +1743,7 @@ func (p *parser) cloneState() storeDict {
 // every restoreState should applied only one time for every cloned state
 func (p *parser) restoreState(state storeDict) {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("restoreState"))
 	}
 	// {{ end }} ==template==
@@ -771,34 +1753,156 @@ func (p *parser) restoreState(state storeDict) {
 
 // {{ end }} ==template==
 
-// get the slice of bytes from the savepoint start to the current position.
-func (p *parser) sliceFrom(start savepoint) []byte {
-	return p.data[start.position.offset:p.pt.position.offset]
+// get the slice of bytes from the savepoint start to the current position.
+func (p *parser) sliceFrom(start savepoint) []byte {
+	return p.slice(start.position.offset, p.pt.position.offset)
+}
+
+// get the slice of bytes between the offsets start and end.
+func (p *parser) slice(start, end int) []byte {
+	// ==template== {{ if not .InputDecoder }}
+	return p.data[start:end]
+	// {{ else }}
+	return p.decodeText(p.data[start:end])
+	// {{ end }} ==template==
+}
+
+// ==template== {{ if or .MemoRules (not .Optimize) }}
+
+// memoEntry is the result of an expression or a rule in the memoization
+// table. The few results at an offset are searched linearly.
+type memoEntry struct {
+	node any
+	res  resultTuple
+}
+
+// memoSlot holds the results at an offset of the memoization table, the
+// slots are reused for the offsets that are memoWindow apart.
+type memoSlot struct {
+	offset  int
+	entries []memoEntry
+}
+
+func (p *parser) getMemoized(node any) (resultTuple, bool) {
+	if len(p.memo) == 0 || p.pt.offset < p.memoFloor {
+		return resultTuple{}, false
+	}
+	slot := &p.memo[p.pt.offset%len(p.memo)]
+	if slot.offset != p.pt.offset {
+		return resultTuple{}, false
+	}
+	// ==template== {{ if .Indentation }}
+	node = indentMemoKey{node: node, indent: p.pt.indent}
+	// {{ end }} ==template==
+	for _, e := range slot.entries {
+		if e.node == node {
+			return e.res, true
+		}
+	}
+	return resultTuple{}, false
+}
+
+func (p *parser) setMemoized(pt savepoint, node any, tuple resultTuple) {
+	if p.memo == nil {
+		n := len(p.data) + 1
+		if p.memoWindow > 0 && p.memoWindow < n {
+			n = p.memoWindow
+		}
+		p.memo = make([]memoSlot, n)
+	}
+	if pt.offset < p.memoFloor {
+		return
+	}
+	slot := &p.memo[pt.offset%len(p.memo)]
+	if slot.offset != pt.offset {
+		if slot.offset > pt.offset {
+			// out of the window
+			return
+		}
+		for i := range slot.entries {
+			slot.entries[i] = memoEntry{}
+		}
+		slot.offset, slot.entries = pt.offset, slot.entries[:0]
+	}
+	// ==template== {{ if .Indentation }}
+	node = indentMemoKey{node: node, indent: pt.indent}
+	// {{ end }} ==template==
+	for i := range slot.entries {
+		if slot.entries[i].node == node {
+			slot.entries[i].res = tuple
+			return
+		}
+	}
+	slot.entries = append(slot.entries, memoEntry{node: node, res: tuple})
+}
+
+// ==template== {{ if .CommitRules }}
+
+// commitMemo discards the memoized results before offset, the parser
+// does not backtrack past the match of a commit rule in practice.
+func (p *parser) commitMemo(offset int) {
+	if offset <= p.memoFloor {
+		return
+	}
+	for i := p.memoFloor; i < offset && i-p.memoFloor < len(p.memo); i++ {
+		slot := &p.memo[i%len(p.memo)]
+		if slot.offset < offset {
+			slot.entries = nil
+		}
+	}
+	p.memoFloor = offset
+}
+
+// {{ end }} ==template==
+
+// {{ end }} ==template==
+
+// ==template== {{ if .LeftRecursion }}
+
+// leaderKey is the key of the result of a left-recursive leader.
+type leaderKey struct {
+	offset int
+	node   any
 }
 
-// ==template== {{ if or .LeftRecursion (not .Optimize) }}
-func (p *parser) getMemoized(node any) (resultTuple, bool) {
-	if len(p.memo) == 0 {
-		return resultTuple{}, false
-	}
-	m := p.memo[p.pt.offset]
-	if len(m) == 0 {
-		return resultTuple{}, false
-	}
-	res, ok := m[node]
-	return res, ok
+// leaderGrowth is a left-recursive leader being grown, involved is set
+// if its result depends on the seed of an enclosing leader.
+type leaderGrowth struct {
+	key      leaderKey
+	involved bool
 }
 
-func (p *parser) setMemoized(pt savepoint, node any, tuple resultTuple) {
-	if p.memo == nil {
-		p.memo = make(map[int]map[any]resultTuple)
+func (p *parser) newLeaderKey(rule *rule) leaderKey {
+	var node any = rule
+	// ==template== {{ if .Indentation }}
+	node = indentMemoKey{node: node, indent: p.pt.indent}
+	// {{ end }} ==template==
+	return leaderKey{offset: p.pt.offset, node: node}
+}
+
+func (p *parser) getLeader(key leaderKey) (resultTuple, bool) {
+	res, ok := p.leaders[key]
+	if !ok {
+		return res, false
+	}
+	for i := len(p.growing) - 1; i >= 0; i-- {
+		if p.growing[i].key == key {
+			// the seed of a leader being grown, the results of the leaders
+			// grown since depend on it
+			for j := i + 1; j < len(p.growing); j++ {
+				p.growing[j].involved = true
+			}
+			break
+		}
 	}
-	m := p.memo[pt.offset]
-	if m == nil {
-		m = make(map[any]resultTuple)
-		p.memo[pt.offset] = m
+	return res, true
+}
+
+func (p *parser) setLeader(key leaderKey, tuple resultTuple) {
+	if p.leaders == nil {
+		p.leaders = make(map[leaderKey]resultTuple)
 	}
-	m[node] = tuple
+	p.leaders[key] = tuple
 }
 
 // {{ end }} ==template==
@@ -826,7 +1930,7 @@ func (p *parser) parse(g *grammar) (val any, err error) {
 		defer func() {
 			if e := recover(); e != nil {
 				// ==template== {{ if not .Optimize }}
-				if p.debug {
+				if p.tracer != nil {
 					defer p.out(p.in("panic handler"))
 				}
 				// {{ end }} ==template==
@@ -893,14 +1997,25 @@ func listJoin(list []string, sep string, lastSep string) string {
 // ==template== {{ if .LeftRecursion }}
 
 func (p *parser) parseRuleRecursiveLeader(rule *rule) (any, bool) {
-	result, ok := p.getMemoized(rule)
+	key := p.newLeaderKey(rule)
+	result, ok := p.getLeader(key)
+	// ==template== {{ if not .Optimize }}
+	if p.tracer != nil {
+		p.tracer.memo(rule.name, p.pt.position, ok)
+	}
+	// ==template== {{ if .Profiling }}
+	if ok && p.prof != nil {
+		p.prof.rule(rule.name).MemoHits++
+	}
+	// {{ end }} ==template==
+	// {{ end }} ==template==
 	if ok {
 		p.restore(result.end)
 		return result.v, result.b
 	}
 
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("recursive " + rule.name))
 	}
 	// {{ end }} ==template==
@@ -912,22 +2027,16 @@ func (p *parser) parseRuleRecursiveLeader(rule *rule) (any, bool) {
 		lastErrors = *p.errs
 	)
 
+	p.growing = append(p.growing, leaderGrowth{key: key})
 	for {
-		// ==template== {{ if or .GlobalState (not .Optimize) }}
+		// ==template== {{ if .GlobalState }}
 		lastState := p.cloneState()
 		// {{ end }} ==template==
-		p.setMemoized(startMark, rule, lastResult)
+		p.setLeader(key, lastResult)
 		val, ok := p.parseRule(rule)
 		endMark := p.pt
-		// ==template== {{ if not .Optimize }}
-		if p.debug {
-			p.printIndent("RECURSIVE", fmt.Sprintf(
-				"Rule %s depth %d: %t -> %s",
-				rule.name, depth, ok, string(p.sliceFrom(startMark))))
-		}
-		// {{ end }} ==template==
 		if (!ok) || (endMark.offset <= lastResult.end.offset && depth != 0) {
-			// ==template== {{ if or .GlobalState (not .Optimize) }}
+			// ==template== {{ if .GlobalState }}
 			p.restoreState(lastState)
 			// {{ end }} ==template==
 			*p.errs = lastErrors
@@ -939,8 +2048,17 @@ func (p *parser) parseRuleRecursiveLeader(rule *rule) (any, bool) {
 		depth++
 	}
 
+	involved := p.growing[len(p.growing)-1].involved
+	p.growing = p.growing[:len(p.growing)-1]
+
 	p.restore(lastResult.end)
-	p.setMemoized(startMark, rule, lastResult)
+	if involved {
+		// the result depends on the seed of an enclosing leader, it is
+		// evaluated again with the next seed
+		delete(p.leaders, key)
+	} else {
+		p.setLeader(key, lastResult)
+	}
 	return lastResult.v, lastResult.b
 }
 
@@ -950,9 +2068,32 @@ func (p *parser) parseRuleRecursiveNoLeader(rule *rule) (any, bool) {
 
 // {{ end }} ==template==
 
-// ==template== {{ if not .Optimize }}
+// ==template== {{ if or .MemoRules (not .Optimize) }}
+
+// memoizeRule returns true if the results of the rule are memoized, with
+// the Memoize option or with the @memoize annotation of the rule.
+func (p *parser) memoizeRule(rule *rule) bool {
+	// ==template== {{ if and .MemoRules (not .Optimize) }}
+	return p.memoize || rule.memoize
+	// {{ else if .MemoRules }}
+	return rule.memoize
+	// {{ else }}
+	return p.memoize
+	// {{ end }} ==template==
+}
+
 func (p *parser) parseRuleMemoize(rule *rule) (any, bool) {
 	res, ok := p.getMemoized(rule)
+	// ==template== {{ if not .Optimize }}
+	if p.tracer != nil {
+		p.tracer.memo(rule.name, p.pt.position, ok)
+	}
+	// ==template== {{ if .Profiling }}
+	if ok && p.prof != nil {
+		p.prof.rule(rule.name).MemoHits++
+	}
+	// {{ end }} ==template==
+	// {{ end }} ==template==
 	if ok {
 		p.restore(res.end)
 		return res.v, res.b
@@ -969,8 +2110,8 @@ func (p *parser) parseRuleMemoize(rule *rule) (any, bool) {
 
 func (p *parser) parseRuleWrap(rule *rule) (any, bool) {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
-		defer p.out(p.in("parseRule " + rule.name))
+	if p.tracer != nil {
+		p.tracer.enterRule(rule.name, p.pt.position)
 	}
 	// {{ end }} ==template==
 	var (
@@ -981,67 +2122,109 @@ func (p *parser) parseRuleWrap(rule *rule) (any, bool) {
 		// {{ end }} ==template==
 	)
 
-	// ==template== {{ if and .LeftRecursion (not .Optimize) }}
-	if p.memoize || rule.leftRecursive {
-		if rule.leader {
-			val, ok = p.parseRuleRecursiveLeader(rule)
-		} else if p.memoize && !rule.leftRecursive {
-			val, ok = p.parseRuleMemoize(rule)
-		} else {
-			val, ok = p.parseRuleRecursiveNoLeader(rule)
-		}
-	} else {
-		val, ok = p.parseRule(rule)
-	}
-	// {{ else if not .Optimize }}
-	if p.memoize {
+	// ==template== {{ if .LeftRecursion }}
+	switch {
+	case rule.leader:
+		val, ok = p.parseRuleRecursiveLeader(rule)
+	case rule.leftRecursive:
+		val, ok = p.parseRuleRecursiveNoLeader(rule)
+	// ==template== {{ if or .MemoRules (not .Optimize) }}
+	case p.memoizeRule(rule):
 		val, ok = p.parseRuleMemoize(rule)
-	} else {
+	// {{ end }} ==template==
+	default:
 		val, ok = p.parseRule(rule)
 	}
-	// {{ else if .LeftRecursion }}
-	if rule.leftRecursive {
-		if rule.leader {
-			val, ok = p.parseRuleRecursiveLeader(rule)
-		} else {
-			val, ok = p.parseRuleRecursiveNoLeader(rule)
-		}
+	// {{ else if or .MemoRules (not .Optimize) }}
+	if p.memoizeRule(rule) {
+		val, ok = p.parseRuleMemoize(rule)
 	} else {
 		val, ok = p.parseRule(rule)
 	}
 	// {{ else }}
 	val, ok = p.parseRule(rule)
 	// {{ end }} ==template==
+	// ==template== {{ if .CommitRules }}
+	if ok && rule.commit {
+		p.commitMemo(p.pt.offset)
+	}
+	// {{ end }} ==template==
 
 	// ==template== {{ if not .Optimize }}
-	if ok && p.debug {
-		p.printIndent("MATCH", string(p.sliceFrom(startMark)))
+	if p.tracer != nil {
+		p.tracer.exitRule(rule.name, ok, startMark.position, p.pt.position)
 	}
 	// {{ end }} ==template==
 	return val, ok
 }
 
 func (p *parser) parseRule(rule *rule) (any, bool) {
+	// ==template== {{ if not .Optimize }}
+	// ==template== {{ if .Profiling }}
+	if p.prof != nil {
+		p.prof.enterRule(rule.name, p.pt.offset)
+	}
+	start := p.pt
+	// {{ end }} ==template==
+	// {{ end }} ==template==
 	p.rstack = append(p.rstack, rule)
 	p.pushV()
 	val, ok := p.parseExprWrap(rule.expr)
 	p.popV()
 	p.rstack = p.rstack[:len(p.rstack)-1]
+	// ==template== {{ if not .Optimize }}
+	// ==template== {{ if .Profiling }}
+	if p.prof != nil {
+		p.prof.exitRule(ok, p.pt.offset-start.offset)
+	}
+	// {{ end }} ==template==
+	// ==template== {{ if .Coverage }}
+	if p.cov != nil && ok {
+		p.cov.Rules[rule.name]++
+	}
+	// {{ end }} ==template==
+	// {{ end }} ==template==
 	return val, ok
 }
 
+// ==template== {{ if and (not .Optimize) (or .LeftRecursion .BackReference) }}
+
+// memoizeExprs returns true if the results of the expressions of the rule
+// may be memoized. This is not the case for left-recursive rules and for
+// rules with back-references, as their results depend on the context.
+func (r *rule) memoizeExprs() bool {
+	// ==template== {{ if .LeftRecursion }}
+	if r.leftRecursive {
+		return false
+	}
+	// {{ end }} ==template==
+	// ==template== {{ if .BackReference }}
+	if r.backRef {
+		return false
+	}
+	// {{ end }} ==template==
+	return true
+}
+
+// {{ end }} ==template==
+
 func (p *parser) parseExprWrap(expr any) (any, bool) {
 	// ==template== {{ if not .Optimize }}
 	var pt savepoint
 
-	// ==template== {{ if .LeftRecursion }}
-	isLeftRecursion := p.rstack[len(p.rstack)-1].leftRecursive
-	if p.memoize && !isLeftRecursion {
+	// ==template== {{ if or .LeftRecursion .BackReference }}
+	memoize := p.memoize && p.rstack[len(p.rstack)-1].memoizeExprs()
+	if memoize {
 	// {{ else }}
 	if p.memoize {
 	// {{ end }} ==template==
 		res, ok := p.getMemoized(expr)
 		if ok {
+			// ==template== {{ if .Profiling }}
+			if p.prof != nil {
+				p.prof.expr(p.rstack[len(p.rstack)-1].name, expr).MemoHits++
+			}
+			// {{ end }} ==template==
 			p.restore(res.end)
 			return res.v, res.b
 		}
@@ -1052,8 +2235,8 @@ func (p *parser) parseExprWrap(expr any) (any, bool) {
 	val, ok := p.parseExpr(expr)
 
 	// ==template== {{ if not .Optimize }}
-	// ==template== {{ if .LeftRecursion }}
-	if p.memoize && !isLeftRecursion {
+	// ==template== {{ if or .LeftRecursion .BackReference }}
+	if memoize {
 	// {{ else }}
 	if p.memoize {
 	// {{ end }} ==template==
@@ -1064,14 +2247,23 @@ func (p *parser) parseExprWrap(expr any) (any, bool) {
 }
 
 // {{ if .Nolint }} nolint: gocyclo {{else}} ==template== {{ end }}
-func (p *parser) parseExpr(expr any) (any, bool) {
+func (p *parser) parseExpr(expr any) (val any, ok bool) {
 	p.ExprCnt++
 	if p.ExprCnt > p.maxExprCnt {
 		panic(errMaxExprCnt)
 	}
+	// ==template== {{ if not .Optimize }}
+	// ==template== {{ if .Profiling }}
+	if p.prof != nil {
+		start := p.pt.offset
+		p.prof.enterExpr(p.rstack[len(p.rstack)-1].name, expr, start)
+		defer func() {
+			p.prof.exitExpr(ok, p.pt.offset-start)
+		}()
+	}
+	// {{ end }} ==template==
+	// {{ end }} ==template==
 
-	var val any
-	var ok bool
 	switch expr := expr.(type) {
 	case *actionExpr:
 		val, ok = p.parseActionExpr(expr)
@@ -1081,10 +2273,18 @@ func (p *parser) parseExpr(expr any) (any, bool) {
 		val, ok = p.parseAndExpr(expr)
 	case *anyMatcher:
 		val, ok = p.parseAnyMatcher(expr)
+	// ==template== {{ if .BackReference }}
+	case *backRefExpr:
+		val, ok = p.parseBackRefExpr(expr)
+	// {{ end }} ==template==
 	case *charClassMatcher:
 		val, ok = p.parseCharClassMatcher(expr)
 	case *choiceExpr:
 		val, ok = p.parseChoiceExpr(expr)
+	// ==template== {{ if .Indentation }}
+	case *indentExpr:
+		val, ok = p.parseIndentExpr(expr)
+	// {{ end }} ==template==
 	case *labeledExpr:
 		val, ok = p.parseLabeledExpr(expr)
 	case *litMatcher:
@@ -1095,6 +2295,10 @@ func (p *parser) parseExpr(expr any) (any, bool) {
 		val, ok = p.parseNotExpr(expr)
 	case *oneOrMoreExpr:
 		val, ok = p.parseOneOrMoreExpr(expr)
+	// ==template== {{ if .Precedence }}
+	case *precedenceExpr:
+		val, ok = p.parsePrecedenceExpr(expr)
+	// {{ end }} ==template==
 	case *recoveryExpr:
 		val, ok = p.parseRecoveryExpr(expr)
 	case *ruleRefExpr:
@@ -1119,7 +2323,7 @@ func (p *parser) parseExpr(expr any) (any, bool) {
 
 func (p *parser) parseActionExpr(act *actionExpr) (any, bool) {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseActionExpr"))
 	}
 
@@ -1142,17 +2346,120 @@ func (p *parser) parseActionExpr(act *actionExpr) (any, bool) {
 
 		val = actVal
 	}
+	return val, ok
+}
+
+// ==template== {{ if .Precedence }}
+
+func (p *parser) parsePrecedenceExpr(prec *precedenceExpr) (any, bool) {
 	// ==template== {{ if not .Optimize }}
-	if ok && p.debug {
-		p.printIndent("MATCH", string(p.sliceFrom(start)))
+	if p.tracer != nil {
+		defer p.out(p.in("parsePrecedenceExpr"))
 	}
+
 	// {{ end }} ==template==
-	return val, ok
+	return p.parseOperation(prec, 1)
+}
+
+// parseOperation matches an operand of prec and the operations whose
+// operators have at least the level minLevel.
+func (p *parser) parseOperation(prec *precedenceExpr, minLevel int) (any, bool) {
+	start := p.pt
+	var left any
+	if op, opVal := p.parseOperator(prec.prefix); op != nil {
+		p.parseSkip(prec)
+		right, ok := p.parseOperation(prec, op.level)
+		if !ok {
+			p.restore(start)
+			return nil, false
+		}
+		left = p.runOperation(prec, start, opVal, nil, right)
+	} else {
+		val, ok := p.parseExprWrap(prec.primary)
+		if !ok {
+			return nil, false
+		}
+		left = val
+	}
+
+	// the operators of a non-associative level cannot follow each other
+	nonAssocLevel := 0
+	for {
+		pt := p.pt
+		p.parseSkip(prec)
+		op, opVal := p.parseOperator(prec.postfix)
+		postfix := op != nil
+		if !postfix {
+			op, opVal = p.parseOperator(prec.infix)
+		}
+		if op == nil || op.level < minLevel || op.level == nonAssocLevel {
+			p.restore(pt)
+			return left, true
+		}
+		if postfix {
+			left = p.runOperation(prec, start, opVal, left, nil)
+			continue
+		}
+
+		p.parseSkip(prec)
+		next := op.level + 1
+		if op.right {
+			next = op.level
+		}
+		right, ok := p.parseOperation(prec, next)
+		if !ok {
+			p.restore(pt)
+			return left, true
+		}
+		left = p.runOperation(prec, start, opVal, left, right)
+		if op.nonAssoc {
+			nonAssocLevel = op.level
+		}
+	}
 }
 
+// parseOperator matches the first operator of ops, and returns it with its
+// value, or nil if none matches.
+func (p *parser) parseOperator(ops []*precedenceOp) (*precedenceOp, any) {
+	for _, op := range ops {
+		if val, ok := p.parseExpr(op.lit); ok {
+			return op, val
+		}
+	}
+	return nil, nil
+}
+
+func (p *parser) parseSkip(prec *precedenceExpr) {
+	if prec.skip != nil {
+		p.parseExprWrap(prec.skip)
+	}
+}
+
+// runOperation runs the code block of prec for the operation that starts
+// at start, with its operator and operands.
+func (p *parser) runOperation(prec *precedenceExpr, start savepoint, op, left, right any) any {
+	p.cur.pos = start.position
+	p.cur.text = p.sliceFrom(start)
+	stack := p.vstack[len(p.vstack)-1]
+	stack["op"], stack["left"], stack["right"] = op, left, right
+	// ==template== {{ if or .GlobalState (not .Optimize) }}
+	state := p.cloneState()
+	// {{ end }} ==template==
+	val, err := prec.run(p)
+	if err != nil {
+		p.addErrAt(err, start.position, []string{})
+	}
+	// ==template== {{ if or .GlobalState (not .Optimize) }}
+	p.restoreState(state)
+	// {{ end }} ==template==
+	return val
+}
+
+// {{ end }} ==template==
+
 func (p *parser) parseAndCodeExpr(and *andCodeExpr) (any, bool) {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseAndCodeExpr"))
 	}
 
@@ -1174,19 +2481,19 @@ func (p *parser) parseAndCodeExpr(and *andCodeExpr) (any, bool) {
 
 func (p *parser) parseAndExpr(and *andExpr) (any, bool) {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseAndExpr"))
 	}
 
 	// {{ end }} ==template==
 	pt := p.pt
-	// ==template== {{ if or .GlobalState (not .Optimize) }}
+	// ==template== {{ if .GlobalState }}
 	state := p.cloneState()
 	// {{ end }} ==template==
 	p.pushV()
 	_, ok := p.parseExprWrap(and.expr)
 	p.popV()
-	// ==template== {{ if or .GlobalState (not .Optimize) }}
+	// ==template== {{ if .GlobalState }}
 	p.restoreState(state)
 	// {{ end }} ==template==
 	p.restore(pt)
@@ -1196,7 +2503,7 @@ func (p *parser) parseAndExpr(and *andExpr) (any, bool) {
 
 func (p *parser) parseAnyMatcher(any *anyMatcher) (any, bool) {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseAnyMatcher"))
 	}
 
@@ -1212,13 +2519,56 @@ func (p *parser) parseAnyMatcher(any *anyMatcher) (any, bool) {
 	return p.sliceFrom(start), true
 }
 
+// ==template== {{ if .BackReference }}
+
+func (p *parser) parseBackRefExpr(ref *backRefExpr) (any, bool) {
+	// ==template== {{ if not .Optimize }}
+	if p.tracer != nil {
+		defer p.out(p.in("parseBackRefExpr " + ref.label))
+	}
+
+	// {{ end }} ==template==
+	for i := len(p.captures) - 1; i >= 0; i-- {
+		if p.captures[i].label != ref.label {
+			continue
+		}
+
+		text := p.captures[i].text
+		start := p.pt
+		// ==template== {{ if not .InputDecoder }}
+		want := strconv.Quote(string(text))
+		// {{ else }}
+		want := strconv.Quote(string(p.decodeText(text)))
+		// {{ end }} ==template==
+		if !bytes.HasPrefix(p.data[start.offset:], text) {
+			p.failAt(false, start.position, want)
+			return nil, false
+		}
+		for p.pt.offset < start.offset+len(text) {
+			p.read()
+		}
+		p.failAt(true, start.position, want)
+		return p.sliceFrom(start), true
+	}
+
+	p.addErr(fmt.Errorf("undefined back-reference: %s", ref.label))
+	return nil, false
+}
+
+// {{ end }} ==template==
+
 // {{ if .Nolint }} nolint: gocyclo {{else}} ==template== {{ end }}
 func (p *parser) parseCharClassMatcher(chr *charClassMatcher) (any, bool) {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseCharClassMatcher"))
 	}
 
+	// ==template== {{ if .Coverage }}
+	if p.cov != nil {
+		p.coverClass(chr)
+	}
+	// {{ end }} ==template==
 	// {{ end }} ==template==
 	cur := p.pt.rn
 	start := p.pt
@@ -1242,7 +2592,11 @@ func (p *parser) parseCharClassMatcher(chr *charClassMatcher) (any, bool) {
 	}
 
 	if chr.ignoreCase {
+		// ==template== {{ if .ByteMode }}
+		cur = lowerASCII(cur)
+		// {{ else }}
 		cur = unicode.ToLower(cur)
+		// {{ end }} ==template==
 	}
 
 	// try to match in the list of available chars
@@ -1310,11 +2664,54 @@ func (p *parser) incChoiceAltCnt(ch *choiceExpr, altI int) {
 	m[alt]++
 }
 
+// ==template== {{ if .Coverage }}
+// coverKey returns the key of the expression at pos in the CoverProfile.
+func (p *parser) coverKey(pos position, i int) string {
+	return fmt.Sprintf("%s %d:%d %d", p.rstack[len(p.rstack)-1].name, pos.line, pos.col, i)
+}
+
+// coverClass records in the CoverProfile the branch of the character
+// class chr that contains the current character, and whether chr
+// matches it.
+func (p *parser) coverClass(chr *charClassMatcher) {
+	cur := p.pt.rn
+	if cur == utf8.RuneError && p.pt.w == 0 {
+		return
+	}
+	if chr.ignoreCase {
+		// ==template== {{ if .ByteMode }}
+		cur = lowerASCII(cur)
+		// {{ else }}
+		cur = unicode.ToLower(cur)
+		// {{ end }} ==template==
+	}
+
+	branch := slices.Index(chr.chars, cur) + 1
+	for i := 0; branch == 0 && i < len(chr.ranges); i += 2 {
+		if cur >= chr.ranges[i] && cur <= chr.ranges[i+1] {
+			branch = len(chr.chars) + i/2 + 1
+		}
+	}
+	for i := 0; branch == 0 && i < len(chr.classes); i++ {
+		if unicode.Is(chr.classes[i], cur) {
+			branch = len(chr.chars) + len(chr.ranges)/2 + i + 1
+		}
+	}
+
+	if branch > 0 {
+		p.cov.Classes[p.coverKey(chr.pos, branch)]++
+	}
+	if (branch > 0) != chr.inverted {
+		p.cov.Classes[p.coverKey(chr.pos, 0)]++
+	}
+}
+
+// {{ end }} ==template==
 // {{ end }} ==template==
 
 func (p *parser) parseChoiceExpr(ch *choiceExpr) (any, bool) {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseChoiceExpr"))
 	}
 	// {{ end }} ==template==
@@ -1323,7 +2720,7 @@ func (p *parser) parseChoiceExpr(ch *choiceExpr) (any, bool) {
 		// dummy assignment to prevent compile error if optimized
 		_ = altI
 
-		// ==template== {{ if or .GlobalState (not .Optimize) }}
+		// ==template== {{ if .GlobalState }}
 		state := p.cloneState()
 		// {{ end }} ==template==
 
@@ -1333,10 +2730,15 @@ func (p *parser) parseChoiceExpr(ch *choiceExpr) (any, bool) {
 		if ok {
 			// ==template== {{ if not .Optimize }}
 			p.incChoiceAltCnt(ch, altI)
+			// ==template== {{ if .Coverage }}
+			if p.cov != nil {
+				p.cov.Alternatives[p.coverKey(ch.pos, altI+1)]++
+			}
+			// {{ end }} ==template==
 			// {{ end }} ==template==
 			return val, ok
 		}
-		// ==template== {{ if or .GlobalState (not .Optimize) }}
+		// ==template== {{ if .GlobalState }}
 		p.restoreState(state)
 		// {{ end }} ==template==
 	}
@@ -1346,12 +2748,121 @@ func (p *parser) parseChoiceExpr(ch *choiceExpr) (any, bool) {
 	return nil, false
 }
 
+// ==template== {{ if .Indentation }}
+
+func (p *parser) parseIndentExpr(ind *indentExpr) (any, bool) {
+	// ==template== {{ if not .Optimize }}
+	if p.tracer != nil {
+		defer p.out(p.in("parseIndentExpr " + ind.want))
+	}
+
+	// {{ end }} ==template==
+	start := p.pt
+	width, altWidth, end := p.indentation()
+	var cmp, altCmp int
+	if cur := start.indent; cur != nil {
+		cmp, altCmp = compareInts(width, cur.width), compareInts(altWidth, cur.altWidth)
+	} else {
+		cmp, altCmp = compareInts(width, 0), compareInts(altWidth, 0)
+	}
+	if cmp != altCmp {
+		p.addErr(errInconsistentIndent)
+		p.failAt(false, start.position, ind.want)
+		return nil, false
+	}
+
+	ok := false
+	switch ind.op {
+	case indentOp:
+		if cmp > 0 {
+			p.pt.indent = &indentLevel{width: width, altWidth: altWidth, parent: start.indent}
+			ok = true
+		}
+	case dedentOp:
+		// the indentation must match one of the enclosing levels
+		if parent := start.indent.parentOf(); cmp < 0 && width <= parent.widthOf() {
+			p.pt.indent = parent
+			ok = true
+		}
+	case samedentOp:
+		if cmp == 0 {
+			for p.pt.offset < end {
+				p.read()
+			}
+			ok = true
+		}
+	}
+	p.failAt(ok, start.position, ind.want)
+	if !ok {
+		return nil, false
+	}
+	return p.sliceFrom(start), true
+}
+
+// indentation returns the width of the indentation of the next non-blank
+// line, starting at the current position, and the offset of its first
+// non-whitespace character. Lines made only of spaces and tabs are
+// skipped, and the end of the input has a width of 0.
+func (p *parser) indentation() (width, altWidth, end int) {
+	// ==template== {{ if .ByteMode }}
+	for i := p.pt.offset; i < len(p.data); i++ {
+		switch p.data[i] {
+	// {{ else }}
+	for i, n := p.pt.offset, 0; i < len(p.data); i += n {
+		var rn rune
+		rn, n, _ = p.decode(p.data[i:])
+		switch rn {
+	// {{ end }} ==template==
+		case ' ':
+			width++
+			altWidth++
+		case '\t':
+			width += p.tabWidth - width%p.tabWidth
+			altWidth++
+		case '\r', '\n':
+			width, altWidth = 0, 0
+		default:
+			return width, altWidth, i
+		}
+	}
+	return 0, 0, len(p.data)
+}
+
+func (l *indentLevel) parentOf() *indentLevel {
+	if l == nil {
+		return nil
+	}
+	return l.parent
+}
+
+func (l *indentLevel) widthOf() int {
+	if l == nil {
+		return 0
+	}
+	return l.width
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// {{ end }} ==template==
+
 func (p *parser) parseLabeledExpr(lab *labeledExpr) (any, bool) {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseLabeledExpr"))
 	}
 
+	// {{ end }} ==template==
+	// ==template== {{ if .BackReference }}
+	start := p.pt
 	// {{ end }} ==template==
 	p.pushV()
 	val, ok := p.parseExprWrap(lab.expr)
@@ -1359,23 +2870,37 @@ func (p *parser) parseLabeledExpr(lab *labeledExpr) (any, bool) {
 	if ok && lab.label != "" {
 		m := p.vstack[len(p.vstack)-1]
 		m[lab.label] = val
+		// ==template== {{ if .BackReference }}
+		if lab.textCapture {
+			p.captures = append(p.captures, capture{label: lab.label, text: p.data[start.offset:p.pt.offset], depth: len(p.vstack)})
+		}
+		// {{ end }} ==template==
 	}
 	return val, ok
 }
 
 func (p *parser) parseLitMatcher(lit *litMatcher) (any, bool) {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseLitMatcher"))
 	}
 
 	// {{ end }} ==template==
 	start := p.pt
+	// ==template== {{ if .ByteMode }}
+	for i := 0; i < len(lit.val); i++ {
+		want := rune(lit.val[i])
+		cur := p.pt.rn
+		if lit.ignoreCase {
+			cur = lowerASCII(cur)
+		}
+	// {{ else }}
 	for _, want := range lit.val {
 		cur := p.pt.rn
 		if lit.ignoreCase {
 			cur = unicode.ToLower(cur)
 		}
+	// {{ end }} ==template==
 		if cur != want {
 			p.failAt(false, start.position, lit.want)
 			p.restore(start)
@@ -1387,9 +2912,22 @@ func (p *parser) parseLitMatcher(lit *litMatcher) (any, bool) {
 	return p.sliceFrom(start), true
 }
 
+// ==template== {{ if .ByteMode }}
+
+// lowerASCII returns the lower case of rn if it is an ASCII letter, rn
+// otherwise.
+func lowerASCII(rn rune) rune {
+	if rn >= 'A' && rn <= 'Z' {
+		return rn + 'a' - 'A'
+	}
+	return rn
+}
+
+// {{ end }} ==template==
+
 func (p *parser) parseNotCodeExpr(not *notCodeExpr) (any, bool) {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseNotCodeExpr"))
 	}
 
@@ -1411,13 +2949,13 @@ func (p *parser) parseNotCodeExpr(not *notCodeExpr) (any, bool) {
 
 func (p *parser) parseNotExpr(not *notExpr) (any, bool) {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseNotExpr"))
 	}
 
 	// {{ end }} ==template==
 	pt := p.pt
-	// ==template== {{ if or .GlobalState (not .Optimize) }}
+	// ==template== {{ if .GlobalState }}
 	state := p.cloneState()
 	// {{ end }} ==template==
 	p.pushV()
@@ -1425,7 +2963,7 @@ func (p *parser) parseNotExpr(not *notExpr) (any, bool) {
 	_, ok := p.parseExprWrap(not.expr)
 	p.maxFailInvertExpected = !p.maxFailInvertExpected
 	p.popV()
-	// ==template== {{ if or .GlobalState (not .Optimize) }}
+	// ==template== {{ if .GlobalState }}
 	p.restoreState(state)
 	// {{ end }} ==template==
 	p.restore(pt)
@@ -1435,7 +2973,7 @@ func (p *parser) parseNotExpr(not *notExpr) (any, bool) {
 
 func (p *parser) parseOneOrMoreExpr(expr *oneOrMoreExpr) (any, bool) {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseOneOrMoreExpr"))
 	}
 
@@ -1459,7 +2997,7 @@ func (p *parser) parseOneOrMoreExpr(expr *oneOrMoreExpr) (any, bool) {
 
 func (p *parser) parseRecoveryExpr(recover *recoveryExpr) (any, bool) {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseRecoveryExpr (" + strings.Join(recover.failureLabel, ",") + ")"))
 	}
 
@@ -1474,7 +3012,7 @@ func (p *parser) parseRecoveryExpr(recover *recoveryExpr) (any, bool) {
 
 func (p *parser) parseRuleRefExpr(ref *ruleRefExpr) (any, bool) {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseRuleRefExpr " + ref.name))
 	}
 
@@ -1493,7 +3031,7 @@ func (p *parser) parseRuleRefExpr(ref *ruleRefExpr) (any, bool) {
 
 func (p *parser) parseSeqExpr(seq *seqExpr) (any, bool) {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseSeqExpr"))
 	}
 
@@ -1501,13 +3039,13 @@ func (p *parser) parseSeqExpr(seq *seqExpr) (any, bool) {
 	vals := make([]any, 0, len(seq.exprs))
 
 	pt := p.pt
-	// ==template== {{ if or .GlobalState (not .Optimize) }}
+	// ==template== {{ if .GlobalState }}
 	state := p.cloneState()
 	// {{ end }} ==template==
 	for _, expr := range seq.exprs {
 		val, ok := p.parseExprWrap(expr)
 		if !ok {
-			// ==template== {{ if or .GlobalState (not .Optimize) }}
+			// ==template== {{ if .GlobalState }}
 			p.restoreState(state)
 			// {{ end }} ==template==
 			p.restore(pt)
@@ -1522,7 +3060,7 @@ func (p *parser) parseSeqExpr(seq *seqExpr) (any, bool) {
 
 func (p *parser) parseStateCodeExpr(state *stateCodeExpr) (any, bool) {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseStateCodeExpr"))
 	}
 
@@ -1538,7 +3076,7 @@ func (p *parser) parseStateCodeExpr(state *stateCodeExpr) (any, bool) {
 
 func (p *parser) parseThrowExpr(expr *throwExpr) (any, bool) {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseThrowExpr"))
 	}
 
@@ -1557,7 +3095,7 @@ func (p *parser) parseThrowExpr(expr *throwExpr) (any, bool) {
 
 func (p *parser) parseZeroOrMoreExpr(expr *zeroOrMoreExpr) (any, bool) {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseZeroOrMoreExpr"))
 	}
 
@@ -1577,7 +3115,7 @@ func (p *parser) parseZeroOrMoreExpr(expr *zeroOrMoreExpr) (any, bool) {
 
 func (p *parser) parseZeroOrOneExpr(expr *zeroOrOneExpr) (any, bool) {
 	// ==template== {{ if not .Optimize }}
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseZeroOrOneExpr"))
 	}
 
@@ -3,12 +3,16 @@ package builder
 import (
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/mna/pigeon/ast"
 )
 
 var (
 	// ErrNoLeader is no leader error.
+	//
+	// Deprecated: the SCCs without a rule in all their cycles have several
+	// leaders, this error is no longer returned.
 	ErrNoLeader = errors.New(
 		"SCC has no leadership candidate (no element is included in all cycles)")
 	// ErrHaveLeftRecursion is recursion error.
@@ -37,41 +41,52 @@ func ComputeNullables(rules map[string]*ast.Rule) {
 	}
 }
 
-func findLeader(
+// findLeaders returns the leaders of the SCC, the rules that grow the
+// seeds of its left recursion, such that every cycle of the SCC goes
+// through at least one of them. The rules in the most cycles are picked
+// first, so that a rule in all the cycles is the only leader.
+func findLeaders(
 	graph map[string]map[string]struct{}, scc map[string]struct{},
-) (string, error) {
-	// Try to find a leader such that all cycles go through it.
-	leaders := make(map[string]struct{}, len(scc))
+) ([]string, error) {
+	starts := make([]string, 0, len(scc))
 	for k := range scc {
-		leaders[k] = struct{}{}
+		starts = append(starts, k)
 	}
-	for start := range scc {
-		cycles, err := FindCyclesInSCC(graph, scc, start)
+	slices.Sort(starts)
+
+	var cycles [][]string
+	for _, start := range starts {
+		paths, err := FindCyclesInSCC(graph, scc, start)
 		if err != nil {
-			return "", fmt.Errorf("error find cycles: %w", err)
+			return nil, fmt.Errorf("error find cycles: %w", err)
+		}
+		for _, path := range paths {
+			// the cycle starts at the first occurrence of its last rule
+			last := path[len(path)-1]
+			cycles = append(cycles, path[slices.Index(path, last):len(path)-1])
 		}
+	}
+
+	var leaders []string
+	for len(cycles) > 0 {
+		counts := make(map[string]int, len(scc))
 		for _, cycle := range cycles {
-			mapCycle := make(map[string]struct{}, len(cycle))
 			for _, k := range cycle {
-				mapCycle[k] = struct{}{}
+				counts[k]++
 			}
-			for k := range scc {
-				if _, okCycle := mapCycle[k]; !okCycle {
-					delete(leaders, k)
-				}
-			}
-			if len(leaders) == 0 {
-				return "", ErrNoLeader
+		}
+		var leader string
+		for _, k := range starts {
+			if counts[k] > counts[leader] {
+				leader = k
 			}
 		}
+		leaders = append(leaders, leader)
+		cycles = slices.DeleteFunc(cycles, func(cycle []string) bool {
+			return slices.Contains(cycle, leader)
+		})
 	}
-	// Pick an arbitrary leader from the candidates.
-	var leader string
-	for k := range leaders {
-		leader = k // The only element.
-		break
-	}
-	return leader, nil
+	return leaders, nil
 }
 
 // ComputeLeftRecursives evaluates left recursion.
@@ -89,11 +104,13 @@ func ComputeLeftRecursives(rules map[string]*ast.Rule) (bool, error) {
 				rules[name].LeftRecursive = true
 				haveLeftRecursion = true
 			}
-			leader, err := findLeader(graph, scc)
+			leaders, err := findLeaders(graph, scc)
 			if err != nil {
 				return false, fmt.Errorf("error find leader %v: %w", scc, err)
 			}
-			rules[leader].Leader = true
+			for _, leader := range leaders {
+				rules[leader].Leader = true
+			}
 		} else {
 			var name string
 			for k := range scc {
@@ -0,0 +1,121 @@
+package builder
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/mna/pigeon/ast"
+)
+
+// precedenceArgs are the labels of the operators and of the operands
+// passed to the code block of a precedence table.
+var precedenceArgs = []string{"op", "left", "right"}
+
+// computePrecedence returns the precedence tables declared by the rules of
+// the grammar, and validates their skip rules.
+func computePrecedence(g *ast.Grammar) (map[*ast.Rule]*ast.PrecedenceTable, error) {
+	rules := make(map[string]bool, len(g.Rules))
+	for _, rule := range g.Rules {
+		rules[rule.Name.Val] = true
+	}
+
+	tables := make(map[*ast.Rule]*ast.PrecedenceTable)
+	for _, rule := range g.Rules {
+		table, err := rule.Precedence()
+		if err != nil {
+			return nil, err
+		}
+		if table == nil {
+			continue
+		}
+		if table.Skip != "" && !rules[table.Skip] {
+			return nil, fmt.Errorf("%s: rule %s: undefined skip rule %s", rule.Pos(), rule.Name.Val, table.Skip)
+		}
+		tables[rule] = table
+	}
+	return tables, nil
+}
+
+// writePrecedenceExpr writes the expression of the rule r, that declares
+// the precedence table, as a precedence climbing matcher.
+func (b *builder) writePrecedenceExpr(r *ast.Rule, table *ast.PrecedenceTable) {
+	act := r.Expr.(*ast.ActionExpr)
+	b.exprIndex++
+	if act.FuncIx == 0 {
+		act.FuncIx = b.exprIndex
+	}
+
+	b.writelnf("&precedenceExpr{")
+	pos := act.Pos()
+	b.writelnf("\tpos: position{line: %d, col: %d, offset: %d},", pos.Line, pos.Col, pos.Off)
+	b.writelnf("\trun: (*parser).call%s,", b.funcName(act.FuncIx))
+	b.writef("\tprimary: ")
+	b.writeExpr(act.Expr)
+	if table.Skip != "" {
+		ref := ast.NewRuleRefExpr(pos)
+		ref.Name = ast.NewIdentifier(pos, table.Skip)
+		b.writef("\tskip: ")
+		b.writeRuleRefExpr(ref)
+	}
+
+	type operator struct {
+		val   string
+		level int
+		kind  ast.OperatorKind
+	}
+	var prefix, infix, postfix []operator
+	for i, level := range table.Levels {
+		for _, op := range level.Operators {
+			o := operator{val: op, level: i + 1, kind: level.Kind}
+			switch {
+			case level.Kind == ast.Prefix:
+				prefix = append(prefix, o)
+			case level.Kind == ast.Postfix:
+				postfix = append(postfix, o)
+			default:
+				infix = append(infix, o)
+			}
+		}
+	}
+
+	for _, ops := range []struct {
+		field string
+		ops   []operator
+	}{{"prefix", prefix}, {"infix", infix}, {"postfix", postfix}} {
+		if len(ops.ops) == 0 {
+			continue
+		}
+		// the longest operator that matches is used
+		slices.SortStableFunc(ops.ops, func(a, b operator) int {
+			return len(b.val) - len(a.val)
+		})
+		b.writelnf("\t%s: []*precedenceOp{", ops.field)
+		for _, op := range ops.ops {
+			b.writelnf("\t{")
+			b.writef("\tlit: ")
+			b.writeLitMatcher(ast.NewLitMatcher(pos, op.val))
+			b.writelnf("\tlevel: %d,", op.level)
+			switch op.kind {
+			case ast.InfixRight:
+				b.writelnf("\tright: true,")
+			case ast.InfixNonAssoc:
+				b.writelnf("\tnonAssoc: true,")
+			}
+			b.writelnf("\t},")
+		}
+		b.writelnf("\t},")
+	}
+	b.writelnf("},")
+}
+
+// writePrecedenceCode writes the code blocks of the operand of the rule r
+// and its code block, that combines the operators with their operands.
+func (b *builder) writePrecedenceCode(r *ast.Rule) {
+	act := r.Expr.(*ast.ActionExpr)
+	b.pushArgsSet()
+	b.writeExprCode(act.Expr)
+	b.popArgsSet()
+	b.argsStack = append(b.argsStack, precedenceArgs)
+	b.writeActionExprCode(act)
+	b.popArgsSet()
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/cover"
+	"github.com/mna/pigeon/interp"
+)
+
+// coverMain implements the cover command, that reports the coverage of
+// a grammar by the parses of inputs.
+func coverMain(args []string) {
+	fs := flag.NewFlagSet("cover", flag.ExitOnError)
+
+	var (
+		htmlFlag      = fs.Bool("html", false, "write the grammar as an HTML page colored by coverage")
+		loadFlag      = fs.String("load", "", "read the coverage profile from a JSON file instead of parsing inputs")
+		memoizeFlag   = fs.Bool("memoize", false, "memoize the parsing results")
+		outputFlag    = fs.String("o", "", "output file, defaults to stdout")
+		ruleFlag      = fs.String("rule", "", "rule to use as entrypoint, defaults to the first rule")
+		summaryFlag   = fs.Bool("summary", false, "print the percentage of coverage of each rule instead of annotating the grammar")
+		shortHelpFlag = fs.Bool("h", false, "show help page")
+		longHelpFlag  = fs.Bool("help", false, "show help page")
+	)
+
+	fs.Usage = coverUsage
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(os.Stderr, "args parse error:\n", err)
+		exit(6)
+	}
+
+	if *shortHelpFlag || *longHelpFlag {
+		fs.Usage()
+		exit(0)
+	}
+
+	if fs.NArg() == 0 {
+		argError(1, "expected a grammar file")
+	}
+	if fs.NArg() > 1 && *loadFlag != "" {
+		argError(1, "unexpected input files with -load")
+	}
+	if *htmlFlag && *summaryFlag {
+		argError(1, "-html and -summary are mutually exclusive")
+	}
+
+	grammarFile := fs.Arg(0)
+	src, err := os.ReadFile(grammarFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "read error:\n", err)
+		exit(2)
+	}
+	g, err := readGrammar(grammarFile, src, "peg")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse error(s):\n %v\n", err)
+		exit(3)
+	}
+
+	var (
+		cov      *cover.Profile
+		parseErr error
+	)
+	if *loadFlag != "" {
+		f, err := os.Open(*loadFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "read error:\n", err)
+			exit(2)
+		}
+		cov, err = cover.Read(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "read error:\n", err)
+			exit(2)
+		}
+	} else {
+		if *ruleFlag != "" && !hasRule(g, *ruleFlag) {
+			fmt.Fprintf(os.Stderr, "argument error:\nunknown rule %s\n", *ruleFlag)
+			exit(9)
+		}
+		inputs := fs.Args()[1:]
+		if len(inputs) == 0 {
+			inputs = []string{""}
+		}
+		cov, parseErr = coverInputs(g, *ruleFlag, *memoizeFlag, inputs)
+		if cov == nil {
+			fmt.Fprintf(os.Stderr, "parse error(s):\n %v\n", parseErr)
+			exit(3)
+		}
+	}
+
+	blocks := cover.Blocks(g, cov)
+	out := output(*outputFlag)
+	switch {
+	case *htmlFlag:
+		err = cover.WriteHTML(out, grammarFile, src, blocks)
+	case *summaryFlag:
+		err = cover.WriteSummary(out, grammarFile, blocks)
+	default:
+		err = cover.Annotate(out, src, blocks)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "write error:\n", err)
+		exit(7)
+	}
+	if err := out.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, "close file error:\n", err)
+		exit(8)
+	}
+
+	if parseErr != nil {
+		fmt.Fprintf(os.Stderr, "parse error(s):\n %v\n", parseErr)
+		exit(3)
+	}
+}
+
+// coverInputs parses the input files with the grammar g and returns the
+// coverage of g by the parses. The empty file name is stdin. The inputs
+// that cannot be parsed are part of the coverage, and their errors are
+// returned. The coverage is nil if g cannot be interpreted.
+func coverInputs(g *ast.Grammar, rule string, memoize bool, inputs []string) (*cover.Profile, error) {
+	p, err := interp.New(g)
+	if err != nil {
+		return nil, err
+	}
+
+	cov := new(cover.Profile)
+	opts := []interp.Option{interp.Coverage(cov), interp.Memoize(memoize)}
+	if rule != "" {
+		opts = append(opts, interp.Entrypoint(rule))
+	}
+
+	var errs []error
+	for _, in := range inputs {
+		nm, rc := input(in)
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "read error:\n", err)
+			exit(2)
+		}
+		if _, err := p.Parse(nm, b, opts...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return cov, errors.Join(errs...)
+}
+
+var coverUsagePage = `usage: %s cover [options] GRAMMAR_FILE [INPUT_FILE...]
+
+Cover parses the inputs with a grammar, by interpreting it as the
+run command does, and prints the source of the grammar annotated
+with the coverage of the grammar by the parses: before each rule, a
+comment with the matches of the rule and its percentage of coverage,
+followed by a comment for each of its alternatives and character
+classes, identified by their position, with their matches and the
+characters in each branch of the classes. The lines of the parts
+that are not covered start with "//!".
+
+The rules, the alternatives and the branches of the character
+classes are covered if they matched at least once. An inverted
+class is also covered if it matched, as is a class without branch.
+
+The inputs are read from the INPUT_FILEs, or from stdin. The errors
+of the inputs that cannot be parsed are printed after the coverage.
+With the -load flag, the coverage is read from a file instead: the
+JSON encoding of the CoverProfile gathered by a generated parser
+with its Coverage option, generated with the -coverage flag.
+
+	-h -help
+		display this help message.
+	-html
+		write the grammar as an HTML page where the names of the
+		rules, the alternatives and the character classes are
+		colored by their coverage, as "go tool cover -html" does.
+	-load COVER_FILE
+		read the coverage from COVER_FILE instead of parsing inputs.
+	-memoize
+		memoize the parsing results. The results found in the
+		memoization table are not counted.
+	-o OUTPUT_FILE
+		write the report to OUTPUT_FILE. Defaults to stdout.
+	-rule NAME
+		use the rule NAME as entrypoint, defaults to the first
+		rule of the grammar.
+	-summary
+		print the percentage of coverage of each rule and of the
+		grammar, as "go tool cover -func" does, instead of
+		annotating the grammar.
+
+See https://godoc.org/github.com/mna/pigeon for more information.
+`
+
+// coverUsage prints the help page of the cover command.
+func coverUsage() {
+	fmt.Printf(coverUsagePage, os.Args[0])
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/mna/pigeon/cover"
+	"github.com/mna/pigeon/test/trace"
+)
+
+// TestCoverConformance checks that the coverage gathered by the
+// interpreter is the one of the generated parser.
+func TestCoverConformance(t *testing.T) {
+	const file = "test/trace/trace.peg"
+	var inputs []string
+	for _, s := range []string{"1+23+4", "5", "x"} {
+		in, err := os.CreateTemp(t.TempDir(), "input")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := in.WriteString(s); err != nil {
+			t.Fatal(err)
+		}
+		in.Close()
+		inputs = append(inputs, in.Name())
+	}
+
+	src, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := readGrammar(file, src, "peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := coverInputs(g, "", false, inputs)
+	if err == nil {
+		t.Fatal("want an error for the invalid input")
+	}
+
+	var gen trace.CoverProfile
+	for _, in := range inputs {
+		trace.ParseFile(in, trace.Coverage(&gen))
+	}
+	b, err := json.Marshal(gen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := cover.Read(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+	if len(want.Rules) != 2 || len(want.Alternatives) != 2 || len(want.Classes) != 2 {
+		t.Errorf("want 2 rules, alternatives and branches, got %+v", want)
+	}
+}
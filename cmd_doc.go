@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/docgen"
+	"github.com/mna/pigeon/parse"
+)
+
+// docMain implements the doc command, that generates the documentation
+// of a grammar.
+func docMain(args []string) {
+	fs := flag.NewFlagSet("doc", flag.ExitOnError)
+
+	var (
+		formatFlag    = fs.String("format", "html", "output format, one of html, svg, markdown or ebnf")
+		outputFlag    = fs.String("o", "", "output file, defaults to stdout")
+		titleFlag     = fs.String("title", "", "title of the html or markdown document, defaults to the grammar file name")
+		shortHelpFlag = fs.Bool("h", false, "show help page")
+		longHelpFlag  = fs.Bool("help", false, "show help page")
+	)
+
+	fs.Usage = docUsage
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(os.Stderr, "args parse error:\n", err)
+		exit(6)
+	}
+
+	if *shortHelpFlag || *longHelpFlag {
+		fs.Usage()
+		exit(0)
+	}
+
+	switch *formatFlag {
+	case "html", "svg", "markdown", "ebnf":
+	default:
+		argError(1, "invalid -format value %q", *formatFlag)
+	}
+	if fs.NArg() > 1 {
+		argError(1, "expected one argument, got %q", strings.Join(fs.Args(), " "))
+	}
+
+	nm, rc := input(fs.Arg(0))
+	src, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "read error:\n", err)
+		exit(2)
+	}
+
+	g, err := parse.ParseGrammar(nm, bytes.NewReader(src), parse.Validate(false))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse error(s):\n %v\n", err)
+		exit(3)
+	}
+
+	title := *titleFlag
+	if title == "" && fs.NArg() == 1 {
+		title = filepath.Base(fs.Arg(0))
+	}
+
+	out := output(*outputFlag)
+	defer func() {
+		if err := out.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, "close file error:\n", err)
+			exit(8)
+		}
+	}()
+	if err := writeDoc(out, g, *formatFlag, title); err != nil {
+		fmt.Fprintln(os.Stderr, "write error: ", err)
+		exit(7)
+	}
+}
+
+// writeDoc writes the documentation of g to w in the given format.
+func writeDoc(w io.Writer, g *ast.Grammar, format, title string) error {
+	switch format {
+	case "svg":
+		return docgen.WriteSVG(w, g)
+	case "markdown":
+		return docgen.WriteMarkdown(w, g, title)
+	case "ebnf":
+		return docgen.WriteEBNF(w, g)
+	default:
+		return docgen.WriteHTML(w, g, title)
+	}
+}
+
+var docUsagePage = `usage: %s doc [options] [GRAMMAR_FILE]
+
+Doc generates the documentation of a PEG grammar.
+
+By default, a self-contained HTML page with the railroad diagram
+of each rule is written to stdout. If no GRAMMAR_FILE is specified,
+the grammar is read from stdin.
+
+The references to other rules link to their documentation, the
+doc comments of the rules and their display names are included.
+
+	-format FORMAT
+		write the documentation in FORMAT, one of html, svg (all
+		the diagrams in a single SVG image), markdown (the rules in
+		EBNF, with their doc comments and cross-references) or ebnf
+		(the rules in the EBNF notation of the Go specification).
+		Defaults to html.
+	-h -help
+		display this help message.
+	-o OUTPUT_FILE
+		write the documentation to OUTPUT_FILE. Defaults to stdout.
+	-title TITLE
+		use TITLE as title of the html and markdown documents.
+		Defaults to the name of GRAMMAR_FILE.
+
+See https://godoc.org/github.com/mna/pigeon for more information.
+`
+
+// docUsage prints the help page of the doc command.
+func docUsage() {
+	fmt.Printf(docUsagePage, os.Args[0])
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/mna/pigeon/parse"
+)
+
+func TestWriteDocGrammars(t *testing.T) {
+	for _, file := range grammarFiles(t) {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, err := parse.ParseGrammar(file, bytes.NewReader(src), parse.Validate(false))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, format := range []string{"html", "svg", "markdown", "ebnf"} {
+			if err := writeDoc(io.Discard, g, format, file); err != nil {
+				t.Errorf("%s: %s: %v", file, format, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/parse"
+)
+
+// exportMain implements the export command, that converts a grammar to
+// other formats.
+func exportMain(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+
+	var (
+		formatFlag    = fs.String("format", "json", "output format, one of json, ebnf, pegjs, tree-sitter or peg")
+		fromFlag      = fs.String("from", "peg", "input format, one of peg or json")
+		nameFlag      = fs.String("name", "", "name of the tree-sitter grammar, defaults to the grammar file name")
+		outputFlag    = fs.String("o", "", "output file, defaults to stdout")
+		stubCodeFlag  = fs.Bool("stub-code", false, "replace the code blocks by stubs in the pegjs format")
+		shortHelpFlag = fs.Bool("h", false, "show help page")
+		longHelpFlag  = fs.Bool("help", false, "show help page")
+	)
+
+	fs.Usage = exportUsage
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(os.Stderr, "args parse error:\n", err)
+		exit(6)
+	}
+
+	if *shortHelpFlag || *longHelpFlag {
+		fs.Usage()
+		exit(0)
+	}
+
+	switch *formatFlag {
+	case "json", "ebnf", "pegjs", "tree-sitter", "peg":
+	default:
+		argError(1, "invalid -format value %q", *formatFlag)
+	}
+	switch *fromFlag {
+	case "peg", "json":
+	default:
+		argError(1, "invalid -from value %q", *fromFlag)
+	}
+	if fs.NArg() > 1 {
+		argError(1, "expected one argument, got %q", strings.Join(fs.Args(), " "))
+	}
+
+	nm, rc := input(fs.Arg(0))
+	src, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "read error:\n", err)
+		exit(2)
+	}
+
+	g, err := readGrammar(nm, src, *fromFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse error(s):\n %v\n", err)
+		exit(3)
+	}
+
+	name := *nameFlag
+	if name == "" {
+		name = treeSitterName(fs.Arg(0))
+	}
+
+	out := output(*outputFlag)
+	defer func() {
+		if err := out.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, "close file error:\n", err)
+			exit(8)
+		}
+	}()
+	if err := exportGrammar(out, g, *formatFlag, name, *stubCodeFlag); err != nil {
+		fmt.Fprintln(os.Stderr, "write error: ", err)
+		exit(7)
+	}
+}
+
+// readGrammar returns the grammar in src, in the format from.
+func readGrammar(filename string, src []byte, from string) (*ast.Grammar, error) {
+	if from == "json" {
+		var g ast.Grammar
+		if err := json.Unmarshal(src, &g); err != nil {
+			return nil, err
+		}
+		return &g, nil
+	}
+	g, err := parse.ParseGrammar(filename, bytes.NewReader(src), parse.Validate(false))
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// exportGrammar writes g to w in the given format.
+func exportGrammar(w io.Writer, g *ast.Grammar, format, name string, stubCode bool) error {
+	switch format {
+	case "ebnf":
+		return ast.ExportW3CEBNF(w, g)
+	case "pegjs":
+		return ast.ExportPEGjs(w, g, stubCode)
+	case "tree-sitter":
+		return ast.ExportTreeSitter(w, g, name)
+	case "peg":
+		return ast.Fprint(w, g, nil)
+	default:
+		b, err := json.MarshalIndent(g, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(b, '\n'))
+		return err
+	}
+}
+
+// treeSitterName returns the name of the tree-sitter grammar of the
+// grammar file filename: its base name without extension, in lowercase
+// and with the characters other than letters and digits replaced by
+// underscores.
+func treeSitterName(filename string) string {
+	base := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	var buf bytes.Buffer
+	for _, r := range strings.ToLower(base) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			buf.WriteRune(r)
+		} else {
+			buf.WriteByte('_')
+		}
+	}
+	if buf.Len() == 0 || filename == "" || unicode.IsDigit(rune(buf.Bytes()[0])) {
+		return "grammar"
+	}
+	return buf.String()
+}
+
+var exportUsagePage = `usage: %s export [options] [GRAMMAR_FILE]
+
+Export converts a PEG grammar to other formats.
+
+By default, the grammar is written to stdout as JSON. If no
+GRAMMAR_FILE is specified, the grammar is read from stdin.
+
+The JSON format keeps the positions of the nodes and can be
+converted back to a PEG grammar with -from json -format peg.
+The other formats cannot express all of the PEG syntax: the
+code blocks are left out, and the predicates and the other
+constructs that the format does not support are left out or
+written as comments.
+
+	-format FORMAT
+		write the grammar in FORMAT, one of json, ebnf (the
+		EBNF notation of the W3C XML specification), pegjs,
+		tree-sitter (a grammar.js skeleton) or peg. Defaults
+		to json.
+	-from FORMAT
+		read the grammar in FORMAT, one of peg or json.
+		Defaults to peg.
+	-h -help
+		display this help message.
+	-name NAME
+		use NAME as name of the tree-sitter grammar. Defaults
+		to the name of GRAMMAR_FILE.
+	-o OUTPUT_FILE
+		write the grammar to OUTPUT_FILE. Defaults to stdout.
+	-stub-code
+		with -format pegjs, replace the code blocks by stubs
+		instead of leaving them out.
+
+See https://godoc.org/github.com/mna/pigeon for more information.
+`
+
+// exportUsage prints the help page of the export command.
+func exportUsage() {
+	fmt.Printf(exportUsagePage, os.Args[0])
+}
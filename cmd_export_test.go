@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/mna/pigeon/testutils"
+)
+
+func TestExportJSONRoundTrip(t *testing.T) {
+	for _, file := range grammarFiles(t) {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, err := readGrammar(file, src, "peg")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		if err := exportGrammar(&buf, g, "json", "", false); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		got, err := readGrammar(file, buf.Bytes(), "json")
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		if !testutils.CompareGrammars(t, file, g, got) {
+			continue
+		}
+
+		// the positions are kept
+		b1, _ := json.Marshal(g)
+		b2, _ := json.Marshal(got)
+		if !bytes.Equal(b1, b2) {
+			t.Errorf("%s: the decoded grammar differs", file)
+		}
+
+		for _, format := range []string{"ebnf", "pegjs", "tree-sitter", "peg"} {
+			if err := exportGrammar(io.Discard, got, format, "test", true); err != nil {
+				t.Errorf("%s: %s: %v", file, format, err)
+			}
+		}
+	}
+}
+
+func TestExportFormats(t *testing.T) {
+	src := `{ package x }
+// A is the
+// start rule.
+A "start" ← x:B ( ',' B )* !. { return nil, nil }
+B ← [-a-c]i+ &{ return true, nil } / "x\n"i / ( 'y' / 'z' )? \x
+`
+	cases := []struct {
+		format string
+		want   string
+	}{
+		{"ebnf", `/* A is the
+ * start rule. */
+A ::= B ( "," B )* /* !. */
+
+B ::= [#x2Da-cA-C]+ /* &{…} */
+    | [xX] #xA
+    | ( "y" | "z" )? /* \x */
+`},
+		{"pegjs", `{
+  // TODO: port the initializer of the Go grammar.
+}
+
+// A is the
+// start rule.
+A "start"
+  = x:B ( "," B )* !. { return text(); }
+
+B
+  = [\-a-c]i+ &{ return true; }
+  / "x\n"i
+  / ( "y" / "z" )? "" /* \x */
+`},
+		{"tree-sitter", `module.exports = grammar({
+  name: "test",
+
+  extras: $ => [],
+
+  rules: {
+    // A is the
+    // start rule.
+    A: $ => seq(field("x", $.B), repeat(seq(",", $.B))),
+
+    B: $ => choice(
+      repeat1(/[\-a-c]/i),
+      /x\n/i,
+      optional(choice("y", "z")),
+    ),
+  },
+});
+`},
+	}
+	g, err := readGrammar("", []byte(src), "peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range cases {
+		var buf bytes.Buffer
+		if err := exportGrammar(&buf, g, tc.format, "test", true); err != nil {
+			t.Fatalf("%s: %v", tc.format, err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("%s: want:\n%s\ngot:\n%s", tc.format, tc.want, got)
+		}
+	}
+}
+
+func TestTreeSitterName(t *testing.T) {
+	cases := map[string]string{
+		"":                   "grammar",
+		"json.peg":           "json",
+		"dir/My-Lang.v2.peg": "my_lang_v2",
+		"1st.peg":            "grammar",
+	}
+	for in, want := range cases {
+		if got := treeSitterName(in); got != want {
+			t.Errorf("%q: want %q, got %q", in, want, got)
+		}
+	}
+}
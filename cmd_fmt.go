@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/parse"
+)
+
+// fmtMain implements the fmt command, that formats PEG grammars in the
+// canonical style of ast.Fprint.
+func fmtMain(args []string) {
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+
+	var (
+		arrowFlag     = fs.String("arrow", "←", "rule definition operator, one of =, <-, ← or ⟵")
+		listFlag      = fs.Bool("l", false, "list files whose formatting differs")
+		writeFlag     = fs.Bool("w", false, "write result to the source file instead of stdout")
+		shortHelpFlag = fs.Bool("h", false, "show help page")
+		longHelpFlag  = fs.Bool("help", false, "show help page")
+	)
+
+	fs.Usage = fmtUsage
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(os.Stderr, "args parse error:\n", err)
+		exit(6)
+	}
+
+	if *shortHelpFlag || *longHelpFlag {
+		fs.Usage()
+		exit(0)
+	}
+
+	switch *arrowFlag {
+	case "=", "<-", "←", "⟵":
+	default:
+		argError(1, "invalid -arrow value %q", *arrowFlag)
+	}
+	if *writeFlag && fs.NArg() == 0 {
+		argError(1, "cannot use -w with standard input")
+	}
+
+	cfg := &ast.PrintConfig{RuleDefOp: *arrowFlag}
+	if fs.NArg() == 0 {
+		if !formatFile("", cfg, *listFlag, false) {
+			exit(3)
+		}
+		return
+	}
+
+	ok := true
+	for _, file := range fs.Args() {
+		ok = formatFile(file, cfg, *listFlag, *writeFlag) && ok
+	}
+	if !ok {
+		exit(3)
+	}
+}
+
+// formatFile formats the grammar in filename, or stdin if filename is
+// empty. It reports parse errors to stderr and returns false if the
+// grammar could not be formatted.
+func formatFile(filename string, cfg *ast.PrintConfig, list, write bool) bool {
+	nm, rc := input(filename)
+	src, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "read error:\n", err)
+		exit(2)
+	}
+
+	res, err := formatGrammar(nm, src, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse error(s):\n %v\n", err)
+		return false
+	}
+
+	if list {
+		if !bytes.Equal(src, res) {
+			fmt.Fprintln(os.Stdout, nm)
+		}
+		return true
+	}
+	if write {
+		if bytes.Equal(src, res) {
+			return true
+		}
+		if err := os.WriteFile(filename, res, 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, "write error: ", err)
+			exit(7)
+		}
+		return true
+	}
+	if _, err := os.Stdout.Write(res); err != nil {
+		fmt.Fprintln(os.Stderr, "write error: ", err)
+		exit(7)
+	}
+	return true
+}
+
+// formatGrammar parses the grammar in src and returns its canonical
+// source.
+func formatGrammar(filename string, src []byte, cfg *ast.PrintConfig) ([]byte, error) {
+	g, err := parse.ParseGrammar(filename, bytes.NewReader(src), parse.Validate(false))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := ast.Fprint(&buf, g, cfg); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var fmtUsagePage = `usage: %s fmt [options] [GRAMMAR_FILE...]
+
+Fmt formats PEG grammars in the canonical style.
+
+By default, the formatted grammar is written to stdout. If no
+GRAMMAR_FILE is specified, the grammar is read from stdin.
+
+Comments are kept, the alternatives of a rule that do not fit on
+a line are aligned under the rule definition operator and the
+lines of the code blocks are indented consistently.
+
+	-arrow OP
+		use OP as rule definition operator, one of =, <-, ← or ⟵.
+		Defaults to ←.
+	-h -help
+		display this help message.
+	-l
+		do not print the formatted grammars, list the files whose
+		formatting differs from the canonical style instead.
+	-w
+		do not print the formatted grammars, write them to the
+		source files instead.
+
+See https://godoc.org/github.com/mna/pigeon for more information.
+`
+
+// fmtUsage prints the help page of the fmt command.
+func fmtUsage() {
+	fmt.Printf(fmtUsagePage, os.Args[0])
+}
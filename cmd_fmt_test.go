@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/parse"
+	"github.com/mna/pigeon/testutils"
+)
+
+// grammarFiles returns the grammars of the repository.
+func grammarFiles(t *testing.T) []string {
+	var files []string
+	for _, pattern := range []string{"grammar/*.peg", "examples/*/*.peg", "test/*/*.peg"} {
+		more, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, more...)
+	}
+	return files
+}
+
+func TestFormatGrammars(t *testing.T) {
+	for _, file := range grammarFiles(t) {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, op := range []string{"←", "<-"} {
+			cfg := &ast.PrintConfig{RuleDefOp: op}
+			once, err := formatGrammar(file, src, cfg)
+			if err != nil {
+				t.Errorf("%s: %v", file, err)
+				continue
+			}
+			twice, err := formatGrammar(file, once, cfg)
+			if err != nil {
+				t.Errorf("%s: formatted grammar does not parse: %v", file, err)
+				continue
+			}
+			if string(once) != string(twice) {
+				t.Errorf("%s: formatting is not idempotent:\n%s\n----\n%s", file, once, twice)
+				continue
+			}
+
+			exp, err := parse.ParseGrammar(file, bytes.NewReader(src), parse.Validate(false))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := parse.ParseGrammar(file, bytes.NewReader(once), parse.Validate(false))
+			if err != nil {
+				t.Fatal(err)
+			}
+			testutils.CompareGrammars(t, file, normalizeCode(exp), normalizeCode(got))
+
+			if n := strings.Count(string(once), "//") + strings.Count(string(once), "/*"); n < len(exp.Comments) {
+				t.Errorf("%s: want at least %d comments, got %d", file, len(exp.Comments), n)
+			}
+		}
+	}
+}
+
+func TestFormatComments(t *testing.T) {
+	src := `// header
+
+/* block
+   comment */
+A <- 'a' // trailing
+  // between
+  / "bc"
+B = x:'b'  !{ return true, nil }
+// end
+`
+	want := `// header
+
+/* block
+   comment */
+A ⟵ 'a' // trailing
+  // between
+  / "bc"
+
+B ⟵ x:'b' !{ return true, nil }
+// end
+`
+	got, err := formatGrammar("", []byte(src), &ast.PrintConfig{RuleDefOp: "⟵"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestFormatLiterals(t *testing.T) {
+	src := `A = "\xff" / "\xc3" / "\xc3\xa9" / "é" / "日本" / "\xffa" / 'a'i`
+	want := "A ← \"\\xff\" / \"\\xc3\" / 'é' / 'é' / \"日本\" / \"\\xffa\" / 'a'i\n"
+	got, err := formatGrammar("", []byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+
+	exp, err := parse.ParseGrammar("", strings.NewReader(src), parse.Validate(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := parse.ParseGrammar("", bytes.NewReader(got), parse.Validate(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutils.CompareGrammars(t, "", exp, back)
+}
+
+// normalizeCode replaces the whitespace in the code blocks of g with
+// single spaces, so that grammars differing only by the indentation of
+// their code compare equal.
+func normalizeCode(g *ast.Grammar) *ast.Grammar {
+	normalize := func(code *ast.CodeBlock) {
+		code.Val = "{" + strings.Join(strings.Fields(code.Val[1:len(code.Val)-1]), " ") + "}"
+	}
+	if g.Init != nil {
+		normalize(g.Init)
+	}
+	for _, r := range g.Rules {
+		ast.Inspect(r.Expr, func(expr ast.Expression) bool {
+			switch expr := expr.(type) {
+			case *ast.ActionExpr:
+				normalize(expr.Code)
+			case *ast.AndCodeExpr:
+				normalize(expr.Code)
+			case *ast.NotCodeExpr:
+				normalize(expr.Code)
+			case *ast.StateCodeExpr:
+				normalize(expr.Code)
+			}
+			return true
+		})
+	}
+	return g
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+
+	"github.com/mna/pigeon/gen"
+)
+
+// genMain implements the gen command, that generates random sentences of
+// a grammar.
+func genMain(args []string) {
+	fs := flag.NewFlagSet("gen", flag.ExitOnError)
+
+	var (
+		corpusFlag    = fs.String("corpus", "", "write the sentences as a fuzz test corpus to this directory")
+		depthFlag     = fs.Int("depth", gen.DefaultMaxDepth, "depth of the rules past which the sentences are completed")
+		invalidFlag   = fs.Bool("invalid", false, "generate near misses that the grammar rejects")
+		nFlag         = fs.Int("n", 10, "number of sentences")
+		outputFlag    = fs.String("o", "", "output file, defaults to stdout")
+		quoteFlag     = fs.Bool("quote", false, "print the sentences as Go strings")
+		repeatFlag    = fs.Int("repeat", gen.DefaultMaxRepeat, "maximum number of repetitions")
+		ruleFlag      = fs.String("rule", "", "rule to use as entrypoint, defaults to the first rule")
+		seedFlag      = fs.Uint64("seed", 0, "seed of the random generator, random if 0")
+		weightsFlag   = fs.String("weights", "", "weight the alternatives by the choice statistics of this file")
+		shortHelpFlag = fs.Bool("h", false, "show help page")
+		longHelpFlag  = fs.Bool("help", false, "show help page")
+	)
+
+	fs.Usage = genUsage
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(os.Stderr, "args parse error:\n", err)
+		exit(6)
+	}
+
+	if *shortHelpFlag || *longHelpFlag {
+		fs.Usage()
+		exit(0)
+	}
+
+	if fs.NArg() != 1 {
+		argError(1, "expected one grammar file")
+	}
+	if *corpusFlag != "" && *outputFlag != "" {
+		argError(1, "-corpus and -o are mutually exclusive")
+	}
+	if *nFlag < 0 || *depthFlag < 1 || *repeatFlag < 1 {
+		argError(1, "-n must be positive, -depth and -repeat greater than 0")
+	}
+
+	grammarFile := fs.Arg(0)
+	src, err := os.ReadFile(grammarFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "read error:\n", err)
+		exit(2)
+	}
+	g, err := readGrammar(grammarFile, src, "peg")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse error(s):\n %v\n", err)
+		exit(3)
+	}
+	if *ruleFlag != "" && !hasRule(g, *ruleFlag) {
+		fmt.Fprintf(os.Stderr, "argument error:\nunknown rule %s\n", *ruleFlag)
+		exit(9)
+	}
+
+	cfg := &gen.Config{
+		Entrypoint: *ruleFlag,
+		MaxDepth:   *depthFlag,
+		MaxRepeat:  *repeatFlag,
+	}
+	if *seedFlag != 0 {
+		cfg.Rand = rand.New(rand.NewPCG(*seedFlag, 0))
+	}
+	if *weightsFlag != "" {
+		cfg.Weights = readChoiceStats(*weightsFlag)
+	}
+	gn, err := gen.New(g, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse error(s):\n %v\n", err)
+		exit(3)
+	}
+
+	sentences := make([][]byte, 0, *nFlag)
+	for range *nFlag {
+		var b []byte
+		if *invalidFlag {
+			b, err = gn.NearMiss()
+		} else {
+			b, err = gn.Sentence()
+		}
+		if err != nil {
+			if errors.Is(err, gen.ErrNoSentence) {
+				fmt.Fprintf(os.Stderr, "generate error:\n %v\n", err)
+				exit(11)
+			}
+			fmt.Fprintf(os.Stderr, "parse error(s):\n %v\n", err)
+			exit(3)
+		}
+		sentences = append(sentences, b)
+	}
+
+	if *corpusFlag != "" {
+		if err := gen.WriteCorpus(*corpusFlag, sentences...); err != nil {
+			fmt.Fprintln(os.Stderr, "write error:\n", err)
+			exit(7)
+		}
+		return
+	}
+
+	out := output(*outputFlag)
+	bw := bufio.NewWriter(out)
+	for _, b := range sentences {
+		if *quoteFlag {
+			bw.WriteString(strconv.Quote(string(b)))
+		} else {
+			bw.Write(b)
+		}
+		bw.WriteByte('\n')
+	}
+	if err := bw.Flush(); err != nil {
+		fmt.Fprintln(os.Stderr, "write error:\n", err)
+		exit(7)
+	}
+	if err := out.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, "close file error:\n", err)
+		exit(8)
+	}
+}
+
+var genUsagePage = `usage: %s gen [options] GRAMMAR_FILE
+
+Gen generates random sentences of a grammar, inputs that the grammar
+accepts, e.g. to seed the corpus of the fuzz tests of its parser. The
+alternatives of the choices, the repetitions and the characters of
+the classes are picked at random, the predicates and the code blocks
+are not run: each sentence is parsed by interpreting the grammar as
+the run command does, and rejected if it does not match.
+
+With the -invalid flag, near misses are generated instead: sentences
+where a character matched by a class is replaced with a character
+that it does not match, and that the grammar rejects.
+
+The sentences are printed one per line, or written as the seed corpus
+of a fuzz test with the -corpus flag. The exit code is 11 if no
+sentence is found after a number of attempts.
+
+	-corpus DIR
+		write each sentence to a file of DIR in the format of the
+		corpus of the fuzz tests, e.g. testdata/fuzz/FuzzParse.
+	-depth N
+		complete the sentences as fast as possible past a depth of N
+		rules. Defaults to 16.
+	-h -help
+		display this help message.
+	-invalid
+		generate near misses that the grammar rejects.
+	-n N
+		generate N sentences. Defaults to 10.
+	-o OUTPUT_FILE
+		write the sentences to OUTPUT_FILE. Defaults to stdout.
+	-quote
+		print the sentences as quoted Go strings.
+	-repeat N
+		repeat the expressions of *, + and ? at most N times.
+		Defaults to 3.
+	-rule NAME
+		use the rule NAME as entrypoint, defaults to the first
+		rule of the grammar.
+	-seed N
+		seed the random generator with N to generate the same
+		sentences, random if 0.
+	-weights STATS_FILE
+		weight the alternatives of the choices by their number of
+		matches in STATS_FILE, the JSON encoding of the
+		Stats.ChoiceAltCnt gathered by a generated parser.
+
+See https://godoc.org/github.com/mna/pigeon for more information.
+`
+
+// genUsage prints the help page of the gen command.
+func genUsage() {
+	fmt.Printf(genUsagePage, os.Args[0])
+}
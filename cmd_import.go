@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/importer"
+)
+
+// importMain implements the import command, that converts the grammars
+// of other PEG parser generators to pigeon grammars.
+func importMain(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+
+	var (
+		fromFlag      = fs.String("from", "", "input format, one of pegjs or pegen, defaults to the extension of the grammar file")
+		outputFlag    = fs.String("o", "", "output file, defaults to stdout")
+		shortHelpFlag = fs.Bool("h", false, "show help page")
+		longHelpFlag  = fs.Bool("help", false, "show help page")
+	)
+
+	fs.Usage = importUsage
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(os.Stderr, "args parse error:\n", err)
+		exit(6)
+	}
+
+	if *shortHelpFlag || *longHelpFlag {
+		fs.Usage()
+		exit(0)
+	}
+
+	if fs.NArg() > 1 {
+		argError(1, "expected one argument, got %q", strings.Join(fs.Args(), " "))
+	}
+	from := *fromFlag
+	if from == "" {
+		from = importFormat(fs.Arg(0))
+	}
+	switch from {
+	case "pegjs", "pegen":
+	case "":
+		argError(1, "-from is required for %q", fs.Arg(0))
+	default:
+		argError(1, "invalid -from value %q", from)
+	}
+
+	nm, rc := input(fs.Arg(0))
+	g, err := importGrammar(nm, rc, from)
+	rc.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse error(s):\n %v\n", err)
+		exit(3)
+	}
+
+	out := output(*outputFlag)
+	defer func() {
+		if err := out.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, "close file error:\n", err)
+			exit(8)
+		}
+	}()
+	if err := ast.Fprint(out, g, nil); err != nil {
+		fmt.Fprintln(os.Stderr, "write error: ", err)
+		exit(7)
+	}
+}
+
+// importGrammar returns the grammar read from r, in the format from.
+func importGrammar(filename string, r io.Reader, from string) (*ast.Grammar, error) {
+	if from == "pegen" {
+		return importer.ParsePegen(filename, r)
+	}
+	return importer.ParsePEGjs(filename, r)
+}
+
+// importFormat returns the import format of the grammar file filename,
+// based on its extension, or an empty string if it is unknown.
+func importFormat(filename string) string {
+	switch filepath.Ext(filename) {
+	case ".pegjs", ".peggy":
+		return "pegjs"
+	case ".gram":
+		return "pegen"
+	}
+	return ""
+}
+
+var importUsagePage = `usage: %s import [options] [GRAMMAR_FILE]
+
+Import converts a PEG.js, Peggy or pegen grammar to a pigeon
+grammar.
+
+By default, the pigeon grammar is written to stdout. If no
+GRAMMAR_FILE is specified, the grammar is read from stdin and
+the -from flag is required.
+
+The rules carry over, while the code blocks of the grammar are
+replaced by TODO stubs that keep the original code in comments
+and must be ported to Go.
+
+	-from FORMAT
+		read the grammar in FORMAT, one of pegjs (PEG.js and
+		Peggy) or pegen (the parser generator of CPython).
+		Defaults to pegjs for the .pegjs and .peggy files and
+		to pegen for the .gram files.
+	-h -help
+		display this help message.
+	-o OUTPUT_FILE
+		write the grammar to OUTPUT_FILE. Defaults to stdout.
+
+See https://godoc.org/github.com/mna/pigeon for more information.
+`
+
+// importUsage prints the help page of the import command.
+func importUsage() {
+	fmt.Printf(importUsagePage, os.Args[0])
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/parse"
+)
+
+func TestImportGrammars(t *testing.T) {
+	cases := []struct {
+		from string
+		src  string
+	}{
+		{"pegjs", `{ const x = 1; }
+start = head:item tail:(_ "," _ @item)* { return [head, ...tail]; }
+item "item" = $[a-z]i+ / n:num &{ return n > 0; } / !"}" .
+num = len:[0-9]|1..3, "_"| / @"x" @"y"
+_ = [ \t\n]*
+`},
+		{"pegen", `@class Parser
+file: a=[statements] ENDMARKER { a }
+statements: statement+
+statement: &'if' 'if' ~ NAME ':' block | ','.expr+ NEWLINE
+block: NEWLINE INDENT statements DEDENT
+expr: NUMBER | STRING | !'x' type=TYPE_COMMENT | $
+`},
+	}
+	for _, tc := range cases {
+		g, err := importGrammar("test", strings.NewReader(tc.src), tc.from)
+		if err != nil {
+			t.Errorf("%s: %v", tc.from, err)
+			continue
+		}
+		var buf bytes.Buffer
+		if err := ast.Fprint(&buf, g, nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parse.ParseGrammar("test", bytes.NewReader(buf.Bytes()), parse.Validate(false)); err != nil {
+			t.Errorf("%s: the imported grammar is invalid: %v\n%s", tc.from, err, buf.String())
+		}
+	}
+}
+
+func TestImportFormat(t *testing.T) {
+	cases := map[string]string{
+		"a.pegjs":     "pegjs",
+		"a/b.peggy":   "pegjs",
+		"python.gram": "pegen",
+		"a.peg":       "",
+		"":            "",
+	}
+	for in, want := range cases {
+		if got := importFormat(in); got != want {
+			t.Errorf("%q: want %q, got %q", in, want, got)
+		}
+	}
+}
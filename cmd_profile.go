@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/interp"
+	"github.com/mna/pigeon/profile"
+)
+
+// profileMain implements the profile command, that reports the profile
+// of the parses of inputs with a grammar.
+func profileMain(args []string) {
+	fs := flag.NewFlagSet("profile", flag.ExitOnError)
+
+	var (
+		loadFlag      = fs.String("load", "", "read the profile from a JSON file instead of parsing inputs")
+		memoizeFlag   = fs.Bool("memoize", false, "memoize the parsing results")
+		outputFlag    = fs.String("o", "", "output file, defaults to stdout")
+		pprofFlag     = fs.String("pprof", "", "write the stacks of rules as a pprof profile to this file")
+		ruleFlag      = fs.String("rule", "", "rule to use as entrypoint, defaults to the first rule")
+		textFlag      = fs.Bool("text", false, "print the statistics as tables instead of annotating the grammar")
+		shortHelpFlag = fs.Bool("h", false, "show help page")
+		longHelpFlag  = fs.Bool("help", false, "show help page")
+	)
+
+	fs.Usage = profileUsage
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(os.Stderr, "args parse error:\n", err)
+		exit(6)
+	}
+
+	if *shortHelpFlag || *longHelpFlag {
+		fs.Usage()
+		exit(0)
+	}
+
+	if fs.NArg() == 0 {
+		argError(1, "expected a grammar file")
+	}
+	if fs.NArg() > 1 && *loadFlag != "" {
+		argError(1, "unexpected input files with -load")
+	}
+
+	grammarFile := fs.Arg(0)
+	src, err := os.ReadFile(grammarFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "read error:\n", err)
+		exit(2)
+	}
+	g, err := readGrammar(grammarFile, src, "peg")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse error(s):\n %v\n", err)
+		exit(3)
+	}
+
+	var (
+		prof     *profile.Profile
+		parseErr error
+	)
+	if *loadFlag != "" {
+		f, err := os.Open(*loadFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "read error:\n", err)
+			exit(2)
+		}
+		prof, err = profile.Read(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "read error:\n", err)
+			exit(2)
+		}
+	} else {
+		if *ruleFlag != "" && !hasRule(g, *ruleFlag) {
+			fmt.Fprintf(os.Stderr, "argument error:\nunknown rule %s\n", *ruleFlag)
+			exit(9)
+		}
+		inputs := fs.Args()[1:]
+		if len(inputs) == 0 {
+			inputs = []string{""}
+		}
+		prof, parseErr = profileInputs(g, *ruleFlag, *memoizeFlag, inputs)
+		if prof == nil {
+			fmt.Fprintf(os.Stderr, "parse error(s):\n %v\n", parseErr)
+			exit(3)
+		}
+	}
+
+	if *pprofFlag != "" {
+		f, err := os.Create(*pprofFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "output file error:\n", err)
+			exit(4)
+		}
+		if err := prof.WritePprof(f, grammarFile, g); err != nil {
+			fmt.Fprintln(os.Stderr, "write error:\n", err)
+			exit(7)
+		}
+		if err := f.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, "close file error:\n", err)
+			exit(8)
+		}
+	}
+
+	out := output(*outputFlag)
+	if *textFlag {
+		err = prof.WriteText(out)
+	} else {
+		err = profile.Annotate(out, src, g, prof)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "write error:\n", err)
+		exit(7)
+	}
+	if err := out.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, "close file error:\n", err)
+		exit(8)
+	}
+
+	if parseErr != nil {
+		fmt.Fprintf(os.Stderr, "parse error(s):\n %v\n", parseErr)
+		exit(3)
+	}
+}
+
+// profileInputs parses the input files with the grammar g and returns
+// the profile of the parses. The empty file name is stdin. The inputs
+// that cannot be parsed are part of the profile, and their errors are
+// returned. The profile is nil if g cannot be interpreted.
+func profileInputs(g *ast.Grammar, rule string, memoize bool, inputs []string) (*profile.Profile, error) {
+	p, err := interp.New(g)
+	if err != nil {
+		return nil, err
+	}
+
+	prof := new(profile.Profile)
+	opts := []interp.Option{interp.Profile(prof), interp.Memoize(memoize)}
+	if rule != "" {
+		opts = append(opts, interp.Entrypoint(rule))
+	}
+
+	var errs []error
+	for _, in := range inputs {
+		nm, rc := input(in)
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "read error:\n", err)
+			exit(2)
+		}
+		if _, err := p.Parse(nm, b, opts...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return prof, errors.Join(errs...)
+}
+
+var profileUsagePage = `usage: %s profile [options] GRAMMAR_FILE [INPUT_FILE...]
+
+Profile parses the inputs with a grammar, by interpreting it as the
+run command does, and prints the source of the grammar annotated
+with the profile of the parses: before each rule, a comment with
+the evaluations, matches and failures of the rule, the hits in the
+memoization table, the bytes consumed and backtracked and the time
+spent, followed by a comment with the same statistics for each of
+its expressions, identified by their position.
+
+The inputs are read from the INPUT_FILEs, or from stdin. The errors
+of the inputs that cannot be parsed are printed after the profile.
+With the -load flag, the profile is read from a file instead: the
+JSON encoding of the Profile gathered by a generated parser with its
+Profiling option, generated with the -profiling flag.
+
+	-h -help
+		display this help message.
+	-load PROFILE_FILE
+		read the profile from PROFILE_FILE instead of parsing
+		inputs.
+	-memoize
+		memoize the parsing results.
+	-o OUTPUT_FILE
+		write the report to OUTPUT_FILE. Defaults to stdout.
+	-pprof PPROF_FILE
+		write the stacks of rules to PPROF_FILE as a profile for
+		"go tool pprof", with the evaluations and the time as
+		sample values and the rules as functions.
+	-rule NAME
+		use the rule NAME as entrypoint, defaults to the first
+		rule of the grammar.
+	-text
+		print the statistics of the rules and of the expressions
+		as tables sorted by decreasing time instead of annotating
+		the grammar.
+
+See https://godoc.org/github.com/mna/pigeon for more information.
+`
+
+// profileUsage prints the help page of the profile command.
+func profileUsage() {
+	fmt.Printf(profileUsagePage, os.Args[0])
+}
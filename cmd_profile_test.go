@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/mna/pigeon/profile"
+	"github.com/mna/pigeon/test/trace"
+)
+
+// TestProfileConformance checks that the profile gathered by the
+// interpreter is the one of the generated parser.
+func TestProfileConformance(t *testing.T) {
+	const file = "test/trace/trace.peg"
+	in, err := os.CreateTemp(t.TempDir(), "input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := in.WriteString("1+23+4"); err != nil {
+		t.Fatal(err)
+	}
+	in.Close()
+
+	src, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := readGrammar(file, src, "peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := profileInputs(g, "", false, []string{in.Name()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gen trace.Profile
+	if _, err := trace.ParseFile(in.Name(), trace.Profiling(&gen)); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(gen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := profile.Read(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Rules) != len(want.Rules) || len(got.Exprs) != len(want.Exprs) || len(got.Stacks) != len(want.Stacks) {
+		t.Fatalf("want %d rules, %d expressions and %d stacks, got %d, %d and %d",
+			len(want.Rules), len(want.Exprs), len(want.Stacks), len(got.Rules), len(got.Exprs), len(got.Stacks))
+	}
+	for _, m := range []struct{ got, want map[string]*profile.Entry }{{got.Rules, want.Rules}, {got.Exprs, want.Exprs}} {
+		for k, w := range m.want {
+			g := m.got[k]
+			if g == nil {
+				t.Errorf("%s: missing", k)
+				continue
+			}
+			g.Time, w.Time = 0, 0
+			if *g != *w {
+				t.Errorf("%s: want %+v, got %+v", k, *w, *g)
+			}
+		}
+	}
+	for k, w := range want.Stacks {
+		if g := got.Stacks[k]; g == nil || g.Calls != w.Calls {
+			t.Errorf("stack %s: want %d calls, got %+v", k, w.Calls, g)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/interp"
+	"github.com/mna/pigeon/pegtest"
+)
+
+// runMain implements the run command, that parses an input with a grammar
+// without generating its parser.
+func runMain(args []string) {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+
+	var (
+		exprFlag        = fs.String("e", "", "input text, instead of INPUT_FILE")
+		interactiveFlag = fs.Bool("i", false, "start an interactive session")
+		memoizeFlag     = fs.Bool("memoize", false, "memoize the parsing results")
+		ruleFlag        = fs.String("rule", "", "rule to use as entrypoint, defaults to the first rule")
+		valueFlag       = fs.Bool("value", false, "print the value instead of the syntax tree")
+		shortHelpFlag   = fs.Bool("h", false, "show help page")
+		longHelpFlag    = fs.Bool("help", false, "show help page")
+	)
+
+	fs.Usage = runUsage
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(os.Stderr, "args parse error:\n", err)
+		exit(6)
+	}
+
+	if *shortHelpFlag || *longHelpFlag {
+		fs.Usage()
+		exit(0)
+	}
+
+	if fs.NArg() == 0 {
+		argError(1, "expected a grammar file")
+	}
+	if fs.NArg() > 2 {
+		argError(1, "expected at most two arguments, got %q", strings.Join(fs.Args(), " "))
+	}
+	if fs.NArg() == 2 && (*exprFlag != "" || *interactiveFlag) {
+		argError(1, "unexpected input file with -e or -i")
+	}
+
+	s := &runSession{grammarFile: fs.Arg(0), memoize: *memoizeFlag, value: *valueFlag}
+	if err := s.load(); err != nil {
+		fmt.Fprintf(os.Stderr, "parse error(s):\n %v\n", err)
+		exit(3)
+	}
+	if *ruleFlag != "" {
+		if err := s.setRule(*ruleFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "argument error:\n%v\n", err)
+			exit(9)
+		}
+	}
+
+	if *interactiveFlag {
+		s.repl(os.Stdin, os.Stdout)
+		return
+	}
+
+	var (
+		nm  = "input"
+		src = []byte(*exprFlag)
+	)
+	if *exprFlag == "" {
+		var rc io.ReadCloser
+		nm, rc = input(fs.Arg(1))
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "read error:\n", err)
+			exit(2)
+		}
+		src = b
+	}
+
+	out, err := s.run(nm, src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse error(s):\n %v\n", err)
+		exit(3)
+	}
+	fmt.Print(out)
+}
+
+// runSession is the state of the run command: the grammar, its parser and
+// the options of the parse.
+type runSession struct {
+	grammarFile string
+	rule        string
+	memoize     bool
+	value       bool
+
+	grammar *ast.Grammar
+	parser  *interp.Parser
+}
+
+// load reads the grammar file and creates its parser.
+func (s *runSession) load() error {
+	src, err := os.ReadFile(s.grammarFile)
+	if err != nil {
+		return err
+	}
+	return s.compile(s.grammarFile, src)
+}
+
+// compile parses the grammar source src and creates its parser. The
+// session is unchanged if src is invalid.
+func (s *runSession) compile(filename string, src []byte) error {
+	g, err := readGrammar(filename, src, "peg")
+	if err != nil {
+		return err
+	}
+	p, err := interp.New(g)
+	if err != nil {
+		return err
+	}
+	if s.rule != "" && !hasRule(g, s.rule) {
+		return fmt.Errorf("unknown rule %s", s.rule)
+	}
+	s.grammar, s.parser = g, p
+	return nil
+}
+
+// setRule sets the entrypoint rule of the session.
+func (s *runSession) setRule(name string) error {
+	if !hasRule(s.grammar, name) {
+		return fmt.Errorf("unknown rule %s", name)
+	}
+	s.rule = name
+	return nil
+}
+
+func hasRule(g *ast.Grammar, name string) bool {
+	for _, r := range g.Rules {
+		if r.Name.Val == name {
+			return true
+		}
+	}
+	return false
+}
+
+// run parses src and returns the syntax tree of the match, or its value
+// if the value flag is set. The match must cover the whole input.
+func (s *runSession) run(filename string, src []byte) (string, error) {
+	opts := []interp.Option{interp.Memoize(s.memoize)}
+	if s.rule != "" {
+		opts = append(opts, interp.Entrypoint(s.rule))
+	}
+	n, err := s.parser.ParseTree(filename, src, opts...)
+	if err != nil {
+		return "", err
+	}
+	if n.End.Offset < len(src) {
+		return "", fmt.Errorf("%s:%d:%d (%d): unexpected input after the match", filename, n.End.Line, n.End.Col, n.End.Offset)
+	}
+	if s.value {
+		return pegtest.Snapshot(n.Value) + "\n", nil
+	}
+	return n.String(), nil
+}
+
+// define adds the rules of the PEG source src to the grammar, replacing
+// the rules with the same names, and recreates the parser.
+func (s *runSession) define(src string) error {
+	def, err := readGrammar("def", []byte(src), "peg")
+	if err != nil {
+		return err
+	}
+	if len(def.Rules) == 0 {
+		return fmt.Errorf("expected a rule definition")
+	}
+
+	g := *s.grammar
+	g.Rules = append([]*ast.Rule(nil), s.grammar.Rules...)
+next:
+	for _, dr := range def.Rules {
+		for i, r := range g.Rules {
+			if r.Name.Val == dr.Name.Val {
+				g.Rules[i] = dr
+				continue next
+			}
+		}
+		g.Rules = append(g.Rules, dr)
+	}
+
+	// print and parse the new grammar, so that the parser gets a fresh AST
+	var buf bytes.Buffer
+	if err := ast.Fprint(&buf, &g, nil); err != nil {
+		return err
+	}
+	return s.compile(s.grammarFile, buf.Bytes())
+}
+
+// show writes the PEG source of the rule name to w.
+func (s *runSession) show(w io.Writer, name string) error {
+	for _, r := range s.grammar.Rules {
+		if r.Name.Val == name {
+			return ast.Fprint(w, &ast.Grammar{Rules: []*ast.Rule{r}}, nil)
+		}
+	}
+	return fmt.Errorf("unknown rule %s", name)
+}
+
+// repl runs the interactive session, reading the commands and the inputs
+// from r and writing the results to w.
+func (s *runSession) repl(r io.Reader, w io.Writer) {
+	sc := bufio.NewScanner(r)
+	for {
+		fmt.Fprint(w, "> ")
+		if !sc.Scan() {
+			fmt.Fprintln(w)
+			return
+		}
+		line := sc.Text()
+
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+		arg = strings.TrimSpace(arg)
+		var err error
+		switch cmd {
+		case ":q", ":quit":
+			return
+		case ":h", ":help":
+			fmt.Fprint(w, replHelp)
+		case ":rule":
+			if arg == "" {
+				fmt.Fprintln(w, s.entrypoint())
+				continue
+			}
+			err = s.setRule(arg)
+		case ":rules":
+			for _, r := range s.grammar.Rules {
+				fmt.Fprintln(w, r.Name.Val)
+			}
+		case ":show":
+			err = s.show(w, arg)
+		case ":def":
+			err = s.define(arg)
+		case ":reload":
+			err = s.load()
+		case ":value":
+			s.value = !s.value
+			fmt.Fprintf(w, "value: %t\n", s.value)
+		default:
+			if strings.HasPrefix(cmd, ":") {
+				err = fmt.Errorf("unknown command %s, see :help", cmd)
+				break
+			}
+			input := line
+			if v, uerr := strconv.Unquote(strings.TrimSpace(line)); uerr == nil {
+				input = v
+			}
+			var out string
+			if out, err = s.run("input", []byte(input)); err == nil {
+				fmt.Fprint(w, out)
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(w, "error:", strings.ReplaceAll(err.Error(), "\n", "\n       "))
+		}
+	}
+}
+
+// entrypoint returns the name of the entrypoint rule of the session.
+func (s *runSession) entrypoint() string {
+	if s.rule != "" || len(s.grammar.Rules) == 0 {
+		return s.rule
+	}
+	return s.grammar.Rules[0].Name.Val
+}
+
+const replHelp = `Enter an input to parse it, as is or as a Go string literal, e.g. "1\n2".
+Commands:
+  :def RULE     add or replace a rule, e.g. :def Num ← [0-9]+
+  :help         show this help
+  :quit         end the session
+  :reload       read the grammar file again
+  :rule [NAME]  print or set the entrypoint rule
+  :rules        list the rules
+  :show NAME    print a rule
+  :value        toggle printing the value instead of the syntax tree
+`
+
+var runUsagePage = `usage: %s run [options] GRAMMAR_FILE [INPUT_FILE]
+
+Run parses an input with a grammar, by interpreting the grammar
+instead of generating its parser, and prints the concrete syntax
+tree of the match: a line per match of a rule, indented by its
+depth, with the positions and the text of the match. If the input
+is not matched, the error with the expected matchers is printed.
+
+The input is read from INPUT_FILE, from the -e flag or from stdin.
+The match must cover the whole input. The code blocks of the
+grammar are not run: the actions return the value of their
+expression, the code predicates match and the state code blocks
+do nothing.
+
+With the -i flag, run starts an interactive session that parses
+each line read from stdin. Rules can be added or replaced during
+the session with the :def command, see :help for the commands.
+
+	-e TEXT
+		parse TEXT instead of INPUT_FILE.
+	-h -help
+		display this help message.
+	-i
+		start an interactive session.
+	-memoize
+		memoize the parsing results.
+	-rule NAME
+		use the rule NAME as entrypoint, defaults to the first
+		rule of the grammar.
+	-value
+		print the snapshot of the value returned by the
+		entrypoint rule instead of the syntax tree.
+
+See https://godoc.org/github.com/mna/pigeon for more information.
+`
+
+// runUsage prints the help page of the run command.
+func runUsage() {
+	fmt.Printf(runUsagePage, os.Args[0])
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mna/pigeon/interp"
+)
+
+// newRunSession returns the run session of the grammar file.
+func newRunSession(t *testing.T, file string) *runSession {
+	t.Helper()
+	s := &runSession{grammarFile: file}
+	if err := s.load(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// TestRunConformance checks that the interpreter reports the errors of
+// the generated parsers of the test grammars.
+func TestRunConformance(t *testing.T) {
+	cases := []struct {
+		file, in string
+		tabWidth int
+		err      string
+	}{
+		{file: "test/errorpos/errorpos.peg", in: "case01 zero"},
+		{file: "test/errorpos/errorpos.peg", in: "kase01", err: `1:1 (0): no match found, expected: "case01", "case02", "case03", "case04", "case05", "case06", "case07", "case08", "case09", "case10", "case11" or [ \t\n\r]`},
+		{file: "test/errorpos/errorpos.peg", in: "case01 zero ink", err: `1:13 (12): no match found, expected: "dec", "inc", "zero", [ \t\n\r] or EOF`},
+		{file: "test/errorpos/errorpos.peg", in: "case02 xya", err: `1:10 (9): no match found, expected: [^abc] or EOF`},
+		{file: "test/errorpos/errorpos.peg", in: "case04 0xx", err: `1:10 (9): no match found, expected: [\pN]`},
+		{file: "test/errorpos/errorpos.peg", in: "case05 not", err: `1:8 (7): no match found, expected: !"not"`},
+		{file: "test/errorpos/errorpos.peg", in: "case07 a", err: `1:8 (7): no match found, expected: ![a-c]i`},
+		{file: "test/errorpos/errorpos.peg", in: "case08 b", err: `1:8 (7): no match found, expected: "a"i`},
+		{file: "test/errorpos/errorpos.peg", in: "case10 x", err: `1:8 (7): no match found, expected: "0", [012], [3-9] or [\pN]`},
+		{file: "test/backref/backref.peg", in: `r##"a"#b"##`},
+		{file: "test/backref/backref.peg", in: `r##"a"#`, err: `1:8 (7): no match found, expected: "\"" or .`},
+		{file: "test/backref/backref.peg", in: "<<EOT\nabc\nEOF", err: `3:4 (13): no match found, expected: "\n" or .`},
+		{file: "test/backref/backref.peg", in: "<a><a></a>y</a>"},
+		{file: "test/backref/backref.peg", in: "<a><b></a></b>", err: `1:9 (8): no match found, expected: "b"`},
+		{file: "test/indent/indent.peg", in: "a\n  b\n  c\n    d\ne"},
+		{file: "test/indent/indent.peg", in: "a\n\tb\n\t\tc\nd"},
+		{file: "test/indent/indent.peg", in: "a\n\tb\n    c", tabWidth: 4, err: "3:1 (5): rule Block: inconsistent use of tabs and spaces in indentation\n3:1 (5): rule Lines: inconsistent use of tabs and spaces in indentation"},
+		{file: "test/indent/indent.peg", in: "a\n    b\n  c", err: "3:1 (8): no match found, expected: %DEDENT, %INDENT or %SAMEDENT"},
+		{file: "test/left_recursion/left_recursion.peg", in: "7+10/2*-4+5*3%6-8*6"},
+		{file: "test/left_recursion/left_recursion.peg", in: "7+", err: `1:3 (2): no match found, expected: "+", "-" or [0-9]`},
+	}
+
+	sessions := make(map[string]*runSession)
+	for _, memoize := range []bool{false, true} {
+		for _, c := range cases {
+			s := sessions[c.file]
+			if s == nil {
+				s = newRunSession(t, c.file)
+				sessions[c.file] = s
+			}
+			opts := []interp.Option{interp.Memoize(memoize)}
+			if c.tabWidth > 0 {
+				opts = append(opts, interp.TabWidth(c.tabWidth))
+			}
+			_, err := s.parser.Parse("", []byte(c.in), opts...)
+			var got string
+			if err != nil {
+				got = err.Error()
+			}
+			if got != c.err {
+				t.Errorf("%s: %q: want error %q, got %q", c.file, c.in, c.err, got)
+			}
+		}
+	}
+}
+
+func TestRunLeftRecursion(t *testing.T) {
+	s := newRunSession(t, "test/left_recursion/left_recursion.peg")
+	s.rule = "expr"
+	got, err := s.run("", []byte("2+1*7"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `expr 1:1-1:6 "2+1*7"
+  expr 1:1-1:2 "2"
+    term 1:1-1:2 "2"
+      factor 1:1-1:2 "2"
+        atom 1:1-1:2 "2"
+  term 1:3-1:6 "1*7"
+    term 1:3-1:4 "1"
+      factor 1:3-1:4 "1"
+        atom 1:3-1:4 "1"
+    factor 1:5-1:6 "7"
+      atom 1:5-1:6 "7"
+`
+	if got != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+
+	if _, err := s.run("in", []byte("2+1)")); err == nil || err.Error() != "in:1:4 (3): unexpected input after the match" {
+		t.Errorf("want unexpected input error, got %v", err)
+	}
+}
+
+func TestRunREPL(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "g.peg")
+	if err := os.WriteFile(file, []byte("List ← Num ( ',' Num )*\nNum ← [0-9]+\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := newRunSession(t, file)
+
+	in := strings.Join([]string{
+		`1,2`,
+		`:rule Num`,
+		`"12"`,
+		`:rule Nope`,
+		`:value`,
+		`3`,
+		`:value`,
+		`:rule List`,
+		`:def Num ← [a-z]+ / '"' [0-9]+ '"'`,
+		`:show Num`,
+		`a,"1"`,
+		`:def Num ←`,
+		`:rules`,
+		`:nope`,
+		`:quit`,
+		`not read`,
+	}, "\n")
+	var out strings.Builder
+	s.repl(strings.NewReader(in), &out)
+
+	want := `> List 1:1-1:4 "1,2"
+  Num 1:1-1:2 "1"
+  Num 1:3-1:4 "2"
+> > Num 1:1-1:3 "12"
+> error: unknown rule Nope
+> value: true
+> ["3"]
+> value: false
+> > > Num ← [a-z]+ / '"' [0-9]+ '"'
+> List 1:1-1:6 "a,\"1\""
+  Num 1:1-1:2 "a"
+  Num 1:3-1:6 "\"1\""
+`
+	got := out.String()
+	if !strings.HasPrefix(got, want) {
+		t.Fatalf("want\n%s\ngot\n%s", want, got)
+	}
+	rest := got[len(want):]
+	for _, s := range []string{"> error: def:1:", "> List\nNum\n", "> error: unknown command :nope, see :help\n> "} {
+		if !strings.Contains(rest, s) {
+			t.Errorf("want %q in\n%s", s, rest)
+		}
+	}
+	if strings.Contains(rest, "not read") {
+		t.Errorf("want the session to end at :quit, got\n%s", rest)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/builder"
+	"github.com/mna/pigeon/pegtest"
+)
+
+// testGrammarMain implements the test command, that runs the examples of
+// the rules of a grammar.
+func testGrammarMain(args []string) {
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+
+	var (
+		optimizeGrammarFlag = fs.Bool("optimize-grammar", false, "optimize the grammar, keeping the tested rules")
+		optimizeParserFlag  = fs.Bool("optimize-parser", false, "generate an optimized parser")
+		runFlag             = fs.String("run", "", "run only the examples of the rules that match the regular expression")
+		leftRecursionFlag   = fs.Bool("support-left-recursion", false, "add support for left recursion")
+		verboseFlag         = fs.Bool("v", false, "print the result of each example")
+		workFlag            = fs.String("work", "", "directory of the generated parser, defaults to a temporary Go module")
+		shortHelpFlag       = fs.Bool("h", false, "show help page")
+		longHelpFlag        = fs.Bool("help", false, "show help page")
+	)
+
+	fs.Usage = testGrammarUsage
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(os.Stderr, "args parse error:\n", err)
+		exit(6)
+	}
+
+	if *shortHelpFlag || *longHelpFlag {
+		fs.Usage()
+		exit(0)
+	}
+
+	if fs.NArg() == 0 {
+		argError(1, "expected a grammar file")
+	}
+	runRe, err := regexp.Compile(*runFlag)
+	if err != nil {
+		argError(1, "invalid -run value: %v", err)
+	}
+
+	grammarFile := fs.Arg(0)
+	src, err := os.ReadFile(grammarFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "read error:\n", err)
+		exit(2)
+	}
+	g, err := readGrammar(grammarFile, src, "peg")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse error(s):\n %v\n", err)
+		exit(3)
+	}
+	cases, err := pegtest.FromComments(grammarFile, g)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse error(s):\n %v\n", err)
+		exit(3)
+	}
+
+	examplesFiles := fs.Args()[1:]
+	if len(examplesFiles) == 0 {
+		examplesFiles = sidecarFiles(grammarFile)
+	}
+	for _, file := range examplesFiles {
+		f, err := os.Open(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "read error:\n", err)
+			exit(2)
+		}
+		fc, err := pegtest.ReadYAML(file, f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "parse error(s):\n %v\n", err)
+			exit(3)
+		}
+		cases = append(cases, fc...)
+	}
+
+	var run []*pegtest.Case
+	var entrypoints []string
+	for _, c := range cases {
+		if runRe.MatchString(c.Rule) {
+			run = append(run, c)
+			entrypoints = append(entrypoints, c.Rule)
+		}
+	}
+	if len(run) == 0 {
+		fmt.Println("no examples to run")
+		return
+	}
+
+	if *optimizeGrammarFlag {
+		ast.Optimize(g, entrypoints...)
+	}
+	results, err := pegtest.Run(g, run, *workFlag,
+		builder.Optimize(*optimizeParserFlag),
+		builder.SupportLeftRecursion(*leftRecursionFlag))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "build error: ", err)
+		exit(5)
+	}
+
+	if failed := printResults(os.Stdout, results, *verboseFlag); failed > 0 {
+		fmt.Printf("FAIL\t%d of %d examples failed\n", failed, len(results))
+		exit(10)
+	}
+	fmt.Printf("ok\t%d examples\n", len(results))
+}
+
+// sidecarFiles returns the YAML examples file of the grammar file
+// filename, if it exists: the file with the same name and the .yaml or
+// .yml extension.
+func sidecarFiles(filename string) []string {
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	for _, ext := range []string{".yaml", ".yml"} {
+		if _, err := os.Stat(base + ext); err == nil {
+			return []string{base + ext}
+		}
+	}
+	return nil
+}
+
+// printResults prints the failed results to w, or all the results if
+// verbose is set, and returns the number of failed results.
+func printResults(w io.Writer, results []*pegtest.Result, verbose bool) int {
+	failed := 0
+	for _, r := range results {
+		passed := r.Passed()
+		if !passed {
+			failed++
+		}
+		if passed && !verbose {
+			continue
+		}
+
+		status := "ok  "
+		if !passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "%s %s\n", status, r.Case)
+		switch {
+		case r.Err != "" && (!r.Reject || verbose):
+			fmt.Fprintf(w, "\terror: %s\n", strings.ReplaceAll(r.Err, "\n", "\n\t       "))
+		case r.Err == "" && r.Reject:
+			fmt.Fprintf(w, "\tgot:  %s\n", r.Got)
+		case r.Err == "" && r.Want != "" && !passed:
+			fmt.Fprintf(w, "\twant: %s\n\tgot:  %s\n", r.Want, r.Got)
+		case r.Err == "" && verbose:
+			fmt.Fprintf(w, "\tgot:  %s\n", r.Got)
+		}
+	}
+	return failed
+}
+
+var testGrammarUsagePage = `usage: %s test [options] GRAMMAR_FILE [EXAMPLES_FILE...]
+
+Test runs example inputs against the rules of a grammar.
+
+The examples are read from the doc comments of the rules, in the
+lines that start with @accept or @reject followed by the input as
+a Go string literal, and from the EXAMPLES_FILE YAML files. If no
+EXAMPLES_FILE is specified, the YAML file with the name of
+GRAMMAR_FILE and the .yaml or .yml extension is read, if it
+exists.
+
+An accepted example may be followed by => and the snapshot of the
+value returned by the parser, e.g.:
+
+	// @accept "1+2" => ["1" "+" "2"]
+	// @reject "1+"
+
+The parser of the grammar is generated in a temporary Go module and
+the examples are run with the go test command, each one using its
+rule as entrypoint. An accepted example must match the whole input.
+
+	-h -help
+		display this help message.
+	-optimize-grammar
+		optimize the grammar, with the tested rules as alternate
+		entrypoints.
+	-optimize-parser
+		generate an optimized parser.
+	-run REGEXP
+		run only the examples of the rules that match REGEXP.
+	-support-left-recursion
+		add support for left recursion.
+	-v
+		print the result of each example, not only the failures.
+	-work DIR
+		generate the parser and its test in DIR and keep them, e.g.
+		to use a directory of the Go module that provides the
+		imports of the code blocks. Defaults to a temporary Go
+		module where only the standard library is available.
+
+See https://godoc.org/github.com/mna/pigeon for more information.
+`
+
+// testGrammarUsage prints the help page of the test command.
+func testGrammarUsage() {
+	fmt.Printf(testGrammarUsagePage, os.Args[0])
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mna/pigeon/pegtest"
+)
+
+func TestTestGrammarExamples(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the go command")
+	}
+
+	src := `{ package calc }
+// Sum is a sum of digits.
+//
+// @accept "1+2" => ["1" [["+" "2"]]]
+// @accept "1+2+" => nil
+// @reject "+"
+Sum ← Digit ( '+' Digit )*
+
+// @accept "9"
+// @reject "x"
+// @reject "1"
+Digit ← [0-9]
+`
+	g, err := readGrammar("calc.peg", []byte(src), "peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases, err := pegtest.FromComments("calc.peg", g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := pegtest.Run(g, cases, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	failed := printResults(&buf, results, false)
+	want := `FAIL calc.peg:5: Sum accept "1+2+"
+	error: input:1:4 (3): unexpected input after the match
+FAIL calc.peg:11: Digit reject "1"
+	got:  "1"
+`
+	if failed != 2 || buf.String() != want {
+		t.Errorf("want 2 failures:\n%s\ngot %d:\n%s", want, failed, buf.String())
+	}
+}
+
+func TestSidecarFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.peg", "a.yaml", "b.peg", "b.yml", "c.peg"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := map[string]string{"a.peg": "a.yaml", "b.peg": "b.yml", "c.peg": ""}
+	for in, want := range cases {
+		got := sidecarFiles(filepath.Join(dir, in))
+		if want == "" {
+			if len(got) != 0 {
+				t.Errorf("%s: want no file, got %v", in, got)
+			}
+			continue
+		}
+		if len(got) != 1 || got[0] != filepath.Join(dir, want) {
+			t.Errorf("%s: want %s, got %v", in, want, got)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package cover
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mna/pigeon/ast"
+)
+
+// snippetLen is the maximum number of characters of the source of an
+// alternative quoted in the annotations.
+const snippetLen = 30
+
+// Annotate writes src, the source of the grammar of blocks, to w with
+// the coverage of blocks as comments. The coverage of each rule and of
+// its alternatives and character classes is inserted before the line
+// where the rule starts, the expressions being identified by their
+// position and the start of their source. The blocks that are not
+// covered are marked with "!".
+func Annotate(w io.Writer, src []byte, blocks []Block) error {
+	notes := make(map[int][]string)
+	ends := blockEnds(src, blocks)
+	var line int
+	for i := 0; i < len(blocks); i++ {
+		b := &blocks[i]
+		mark := " "
+		if covered, total := b.Points(); covered < total {
+			mark = "!"
+		}
+
+		var note string
+		switch b.Kind {
+		case RuleBlock:
+			j := i + 1
+			for j < len(blocks) && blocks[j].Kind != RuleBlock {
+				j++
+			}
+			line = b.Pos.Line
+			note = fmt.Sprintf("//%s%s: %d matches, %.1f%% covered", mark, b.Rule, b.Count, Percent(blocks[i:j]))
+		case AlternativeBlock:
+			note = fmt.Sprintf("//%s  %d:%d alternative %d %s: %d matches", mark, b.Pos.Line, b.Pos.Col,
+				b.Index, snippet(src[b.Pos.Off:ends[i]]), b.Count)
+		case ClassBlock:
+			branches := make([]string, len(b.Branches))
+			for i, br := range b.Branches {
+				branches[i] = fmt.Sprintf("%s %d", br.Label, br.Count)
+			}
+			note = fmt.Sprintf("//%s  %d:%d class %s: %d matches", mark, b.Pos.Line, b.Pos.Col,
+				b.Expr.(*ast.CharClassMatcher).Val, b.Count)
+			if len(branches) > 0 {
+				note += " (" + strings.Join(branches, ", ") + ")"
+			}
+		}
+		notes[line] = append(notes[line], note)
+	}
+
+	bw := bufio.NewWriter(w)
+	for i, line := range bytes.SplitAfter(src, []byte("\n")) {
+		for _, note := range notes[i+1] {
+			bw.WriteString(note + "\n")
+		}
+		bw.Write(line)
+	}
+	return bw.Flush()
+}
+
+// snippet returns the start of src, up to the end of the line.
+func snippet(src []byte) string {
+	s := string(src)
+	if i := strings.IndexAny(s, "\r\n"); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+	if utf8.RuneCountInString(s) > snippetLen {
+		s = strings.TrimSpace(string([]rune(s)[:snippetLen])) + "…"
+	}
+	return s
+}
@@ -0,0 +1,234 @@
+// Package cover reports the coverage of a grammar by the parses of a
+// corpus: the matches of its rules and of the alternatives of its
+// choices, and the input characters in each branch of its character
+// classes, to find the parts of the grammar that the corpus never
+// exercises.
+//
+// A Profile is gathered by the Coverage option of the interp package,
+// or by the Coverage option of a generated parser, which stores it in a
+// type with the same JSON encoding. It is reported as a summary of the
+// coverage of the rules, as comments added to the source of the grammar
+// or as an HTML page of the source, as "go tool cover" does.
+package cover
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mna/pigeon/ast"
+)
+
+// Profile stores the coverage of a grammar.
+type Profile struct {
+	// Rules maps the name of the rules to their number of matches.
+	Rules map[string]int64
+	// Alternatives maps the alternatives of the choices to their number
+	// of matches. The key is composed of the name of the rule, the line
+	// and the column of the choice and the one-based index of the
+	// alternative, e.g. "Sum 3:7 2".
+	Alternatives map[string]int64
+	// Classes maps the branches of the character classes, their
+	// characters, ranges and Unicode classes in this order, to the number
+	// of input characters that they contain. The key is composed as for
+	// Alternatives, with the one-based index of the branch, e.g.
+	// "Digit 5:9 1". The index 0 counts the matches of the class.
+	Classes map[string]int64
+}
+
+// Read decodes the JSON encoding of a coverage profile from r, as
+// written by a generated parser.
+func Read(r io.Reader) (*Profile, error) {
+	var p Profile
+	if err := json.NewDecoder(r).Decode(&p); err != nil {
+		return nil, fmt.Errorf("invalid coverage profile: %w", err)
+	}
+	return &p, nil
+}
+
+// Kind is the kind of a Block.
+type Kind int
+
+// List of kinds of blocks.
+const (
+	RuleBlock Kind = iota
+	AlternativeBlock
+	ClassBlock
+)
+
+// String returns the name of the kind.
+func (k Kind) String() string {
+	switch k {
+	case RuleBlock:
+		return "rule"
+	case AlternativeBlock:
+		return "alternative"
+	case ClassBlock:
+		return "class"
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Block is the coverage of a part of a grammar: a rule, an alternative
+// of a choice or a character class.
+type Block struct {
+	Kind Kind
+	// Rule is the name of the rule that contains the block, and Pos the
+	// position of its name or of the expression of the block.
+	Rule string
+	Pos  ast.Pos
+	// Expr is the expression of the alternative or the character class,
+	// and Index the one-based index of the alternative.
+	Expr  ast.Expression
+	Index int
+	// Count is the number of matches.
+	Count int64
+	// Branches is the coverage of the characters, ranges and Unicode
+	// classes of a character class, in this order.
+	Branches []Branch
+}
+
+// Branch is the coverage of a branch of a character class: the number of
+// input characters that it contains.
+type Branch struct {
+	Label string
+	Count int64
+}
+
+// Points returns the number of points of coverage of b, and how many of
+// them are covered. The rules and the alternatives have one point,
+// covered if they matched, the character classes have one point per
+// branch, and one more for their matches if they are inverted or have no
+// branch.
+func (b *Block) Points() (covered, total int) {
+	if b.Kind == ClassBlock {
+		for _, br := range b.Branches {
+			total++
+			if br.Count > 0 {
+				covered++
+			}
+		}
+		if cl, ok := b.Expr.(*ast.CharClassMatcher); ok && !cl.Inverted && len(b.Branches) > 0 {
+			return covered, total
+		}
+	}
+	total++
+	if b.Count > 0 {
+		covered++
+	}
+	return covered, total
+}
+
+// Blocks returns the coverage of the blocks of g in p, in the order of
+// the source of g. The counts of the expressions are looked up by
+// position only, and the counts of an expression reached from several
+// rules, e.g. if the grammar was optimized, are added.
+func Blocks(g *ast.Grammar, p *Profile) []Block {
+	alts, classes := byPos(p.Alternatives), byPos(p.Classes)
+	key := func(pos ast.Pos, i int) string {
+		return fmt.Sprintf("%d:%d %d", pos.Line, pos.Col, i)
+	}
+
+	var blocks []Block
+	for _, r := range g.Rules {
+		name := r.Name.Val
+		blocks = append(blocks, Block{Kind: RuleBlock, Rule: name, Pos: r.Name.Pos(), Count: p.Rules[name]})
+		start := len(blocks)
+		ast.Inspect(r.Expr, func(expr ast.Expression) bool {
+			switch expr := expr.(type) {
+			case *ast.ChoiceExpr:
+				for i, alt := range expr.Alternatives {
+					blocks = append(blocks, Block{
+						Kind:  AlternativeBlock,
+						Rule:  name,
+						Pos:   alt.Pos(),
+						Expr:  alt,
+						Index: i + 1,
+						Count: alts[key(expr.Pos(), i+1)],
+					})
+				}
+			case *ast.CharClassMatcher:
+				b := Block{Kind: ClassBlock, Rule: name, Pos: expr.Pos(), Expr: expr, Count: classes[key(expr.Pos(), 0)]}
+				for i, label := range branchLabels(expr) {
+					b.Branches = append(b.Branches, Branch{Label: label, Count: classes[key(expr.Pos(), i+1)]})
+				}
+				blocks = append(blocks, b)
+			}
+			return true
+		})
+		// the blocks at the same position are from the outermost one
+		slices.SortStableFunc(blocks[start:], func(a, b Block) int {
+			return cmp.Compare(a.Pos.Off, b.Pos.Off)
+		})
+	}
+	return blocks
+}
+
+// byPos returns the counts of m by key without the name of the rule.
+func byPos(m map[string]int64) map[string]int64 {
+	counts := make(map[string]int64, len(m))
+	for k, n := range m {
+		if _, pos, ok := strings.Cut(k, " "); ok {
+			counts[pos] += n
+		}
+	}
+	return counts
+}
+
+// branchLabels returns the labels of the branches of the character
+// class c, e.g. "'a'", "'0'-'9'" and "\pL".
+func branchLabels(c *ast.CharClassMatcher) []string {
+	var labels []string
+	for _, rn := range c.Chars {
+		labels = append(labels, strconv.QuoteRune(rn))
+	}
+	for i := 0; i+1 < len(c.Ranges); i += 2 {
+		labels = append(labels, strconv.QuoteRune(c.Ranges[i])+"-"+strconv.QuoteRune(c.Ranges[i+1]))
+	}
+	for _, cl := range c.UnicodeClasses {
+		if len(cl) == 1 {
+			labels = append(labels, `\p`+cl)
+		} else {
+			labels = append(labels, `\p{`+cl+`}`)
+		}
+	}
+	return labels
+}
+
+// Percent returns the percentage of the points of coverage of blocks
+// that are covered, 100 if there is none.
+func Percent(blocks []Block) float64 {
+	var covered, total int
+	for i := range blocks {
+		c, t := blocks[i].Points()
+		covered += c
+		total += t
+	}
+	if total == 0 {
+		return 100
+	}
+	return 100 * float64(covered) / float64(total)
+}
+
+// WriteSummary writes the percentage of coverage of each rule of
+// blocks, by the position of the rule in the file filename, followed by
+// the percentage of coverage of the grammar, as "go tool cover -func"
+// does.
+func WriteSummary(w io.Writer, filename string, blocks []Block) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 1, '\t', 0)
+	for i := 0; i < len(blocks); {
+		j := i + 1
+		for j < len(blocks) && blocks[j].Kind != RuleBlock {
+			j++
+		}
+		fmt.Fprintf(tw, "%s:%d:\t%s\t%.1f%%\n", filename, blocks[i].Pos.Line, blocks[i].Rule, Percent(blocks[i:j]))
+		i = j
+	}
+	fmt.Fprintf(tw, "total:\t(points)\t%.1f%%\n", Percent(blocks))
+	return tw.Flush()
+}
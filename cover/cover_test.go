@@ -0,0 +1,143 @@
+package cover
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mna/pigeon/parse"
+)
+
+const grammar = `Sum ← Num '+' Sum / Num
+
+// Num is a number.
+Num ← [0-9_\pL] / [^a-z] / "{" ( x:'}' { return x, nil } / [,)] )
+`
+
+func testBlocks(t *testing.T) ([]byte, []Block) {
+	t.Helper()
+	g, err := parse.ParseGrammar("", strings.NewReader(grammar), parse.Validate(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Read(strings.NewReader(`{
+	"Rules": {"Sum": 2, "Num": 3},
+	"Alternatives": {"Sum 1:7 1": 1, "Sum 1:21 2": 1, "Num 4:7 1": 3},
+	"Classes": {"Num 4:7 0": 3, "Num 4:7 1": 2, "Other 4:7 3": 1, "Num 4:19 0": 4}
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []byte(grammar), Blocks(g, p)
+}
+
+func TestBlocks(t *testing.T) {
+	_, blocks := testBlocks(t)
+
+	want := []string{
+		"rule Sum 1:1 2 100.0",
+		"alternative Sum 1:7 1 100.0",
+		"alternative Sum 1:21 0 0.0",
+		"rule Num 4:1 3 100.0",
+		"alternative Num 4:7 3 100.0",
+		"class Num 4:7 3 66.7",
+		"alternative Num 4:19 0 0.0",
+		"class Num 4:19 4 50.0",
+		"alternative Num 4:28 0 0.0",
+		"alternative Num 4:34 0 0.0",
+		"alternative Num 4:60 0 0.0",
+		"class Num 4:60 0 0.0",
+	}
+	var got []string
+	for _, b := range blocks {
+		got = append(got, fmt.Sprintf("%s %s %d:%d %d %.1f", b.Kind, b.Rule, b.Pos.Line, b.Pos.Col, b.Count, Percent([]Block{b})))
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want blocks:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+	if got, want := Percent(blocks), 100*7/16.0; got != want {
+		t.Errorf("want %.1f%% covered, got %.1f%%", want, got)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	src, blocks := testBlocks(t)
+	var buf bytes.Buffer
+	if err := Annotate(&buf, src, blocks); err != nil {
+		t.Fatal(err)
+	}
+	want := `// Sum: 2 matches, 66.7% covered
+//   1:7 alternative 1 Num '+' Sum: 1 matches
+//!  1:21 alternative 2 Num: 0 matches
+Sum ← Num '+' Sum / Num
+
+// Num is a number.
+// Num: 3 matches, 38.5% covered
+//   4:7 alternative 1 [0-9_\pL]: 3 matches
+//!  4:7 class [0-9_\pL]: 3 matches ('_' 2, '0'-'9' 0, \pL 1)
+//!  4:19 alternative 2 [^a-z]: 0 matches
+//!  4:19 class [^a-z]: 4 matches ('a'-'z' 0)
+//!  4:28 alternative 3 "{" ( x:'}' { return x, nil }…: 0 matches
+//!  4:34 alternative 1 x:'}' { return x, nil }: 0 matches
+//!  4:60 alternative 2 [,)]: 0 matches
+//!  4:60 class [,)]: 0 matches (',' 0, ')' 0)
+Num ← [0-9_\pL] / [^a-z] / "{" ( x:'}' { return x, nil } / [,)] )
+`
+	if got := buf.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	_, blocks := testBlocks(t)
+	var buf bytes.Buffer
+	if err := WriteSummary(&buf, "g.peg", blocks); err != nil {
+		t.Fatal(err)
+	}
+	want := "g.peg:1:\tSum\t\t66.7%\ng.peg:4:\tNum\t\t38.5%\ntotal:\t\t(points)\t43.8%\n"
+	if got := buf.String(); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	src, blocks := testBlocks(t)
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, "g.peg", src, blocks); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		`<p>g.peg: 43.8% covered:`,
+		`<span class="cov1" title="2 matches">Sum</span> ← <span class="cov1" title="1 matches">Num &#39;+&#39; Sum</span> / <span class="cov0" title="0 matches">Num</span>`,
+		`<span class="cov1" title="3 matches"><span class="covp" title="3 matches, &#39;_&#39;: 2, &#39;0&#39;-&#39;9&#39;: 0, \pL: 1">[0-9_\pL]</span></span>`,
+		`<span class="cov0" title="0 matches">x:&#39;}&#39; { return x, nil }</span> / `,
+		`<span class="cov0" title="0 matches"><span class="cov0" title="0 matches, &#39;,&#39;: 0, &#39;)&#39;: 0">[,)]</span></span> )</span>` + "\n</pre>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %s in:\n%s", want, got)
+		}
+	}
+}
+
+func TestExprEnd(t *testing.T) {
+	cases := map[string]string{
+		"'a' / 'b'":                                 "'a'",
+		"'a' 'b'  // c / d\n":                       "'a' 'b'",
+		"( 'a' / 'b' ) 'c' ) / 'd'":                 "( 'a' / 'b' ) 'c'",
+		`"/" [/\]] '\''`:                            `"/" [/\]] '\''`,
+		"x:'a' { return \"}\", nil }":               "x:'a' { return \"}\", nil }",
+		"&{ /* } */ return true, nil } 'a' /* / */": "&{ /* } */ return true, nil } 'a'",
+		"'a'\n\nNext ← 'b'":                         "'a'",
+	}
+	for src, want := range cases {
+		limit := len(src)
+		if i := strings.Index(src, "Next"); i >= 0 {
+			limit = i
+		}
+		if got := src[:exprEnd([]byte(src), 0, limit)]; got != want {
+			t.Errorf("%q: want %q, got %q", src, want, got)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package cover
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"html"
+	"io"
+	"slices"
+)
+
+// htmlHeader is the start of the HTML page written by WriteHTML, with
+// the file name and the percentage of coverage as arguments.
+const htmlHeader = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%[1]s coverage</title>
+<style>
+body { font-family: monospace; }
+.cov0 { color: rgb(192, 0, 0); }
+.covp { color: rgb(192, 128, 0); }
+.cov1 { color: rgb(0, 128, 0); }
+</style>
+</head>
+<body>
+<p>%[1]s: %.1[2]f%% covered:
+<span class="cov0">not covered</span>,
+<span class="covp">partially covered</span>,
+<span class="cov1">covered</span></p>
+<pre>
+`
+
+const htmlFooter = `</pre>
+</body>
+</html>
+`
+
+// span is a colored part of the source in the HTML page.
+type span struct {
+	start, end int
+	class      string
+	title      string
+}
+
+// WriteHTML writes src, the source of the grammar of blocks in the file
+// filename, to w as an HTML page where the names of the rules, the
+// alternatives and the character classes are colored by their coverage,
+// with their counts as tooltips.
+func WriteHTML(w io.Writer, filename string, src []byte, blocks []Block) error {
+	var spans []span
+	ends := blockEnds(src, blocks)
+	for i := range blocks {
+		b := &blocks[i]
+		covered, total := b.Points()
+		s := span{start: b.Pos.Off, end: ends[i], class: "cov1", title: fmt.Sprintf("%d matches", b.Count)}
+		if covered == 0 {
+			s.class = "cov0"
+		} else if covered < total {
+			s.class = "covp"
+		}
+		for _, br := range b.Branches {
+			s.title += fmt.Sprintf(", %s: %d", br.Label, br.Count)
+		}
+		if s.start < s.end && s.end <= len(src) {
+			spans = append(spans, s)
+		}
+	}
+	// the outer spans first, the blocks are from the outermost one
+	slices.SortStableFunc(spans, func(a, b span) int {
+		if c := cmp.Compare(a.start, b.start); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.end, a.end)
+	})
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, htmlHeader, html.EscapeString(filename), Percent(blocks))
+	var (
+		pos  int
+		open []int // ends of the open spans
+	)
+	closeSpans := func(before int) {
+		for len(open) > 0 && open[len(open)-1] <= before {
+			end := open[len(open)-1]
+			open = open[:len(open)-1]
+			bw.WriteString(html.EscapeString(string(src[pos:end])))
+			bw.WriteString("</span>")
+			pos = end
+		}
+	}
+	for _, s := range spans {
+		closeSpans(s.start)
+		if len(open) > 0 {
+			s.end = min(s.end, open[len(open)-1])
+		}
+		bw.WriteString(html.EscapeString(string(src[pos:s.start])))
+		fmt.Fprintf(bw, `<span class="%s" title="%s">`, s.class, html.EscapeString(s.title))
+		pos = s.start
+		open = append(open, s.end)
+	}
+	closeSpans(len(src))
+	bw.WriteString(html.EscapeString(string(src[pos:])))
+	bw.WriteString(htmlFooter)
+	return bw.Flush()
+}
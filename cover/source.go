@@ -0,0 +1,124 @@
+package cover
+
+import (
+	"bytes"
+
+	"github.com/mna/pigeon/ast"
+)
+
+// blockEnds returns the offsets of the end of the source of blocks in
+// src, the source of their grammar.
+func blockEnds(src []byte, blocks []Block) []int {
+	ends := make([]int, len(blocks))
+	limit := len(src)
+	// the blocks of a rule are before the next rule
+	for i := len(blocks) - 1; i >= 0; i-- {
+		b := &blocks[i]
+		switch b.Kind {
+		case RuleBlock:
+			ends[i] = b.Pos.Off + len(b.Rule)
+			limit = b.Pos.Off
+		case AlternativeBlock:
+			ends[i] = exprEnd(src, b.Pos.Off, limit)
+		case ClassBlock:
+			ends[i] = b.Pos.Off + len(b.Expr.(*ast.CharClassMatcher).Val)
+		}
+	}
+	return ends
+}
+
+// exprEnd returns the offset of the end of the source of the alternative
+// of a choice that starts at offset off in src: the end of its last
+// token before a "/" or a ")" that is not nested, or before limit.
+func exprEnd(src []byte, off, limit int) int {
+	var depth int
+	end := off
+	for i := off; i < limit; {
+		rest := src[i:limit]
+		switch c := src[i]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+			continue
+		case bytes.HasPrefix(rest, []byte("//")):
+			i = skipUntil(src, i, limit, "\n")
+			continue
+		case bytes.HasPrefix(rest, []byte("/*")):
+			i = skipUntil(src, i+2, limit, "*/")
+			continue
+		case depth == 0 && (c == '/' || c == ')'):
+			return end
+		case c == '(':
+			depth++
+			i++
+		case c == ')':
+			depth--
+			i++
+		case c == '\'' || c == '"':
+			i = skipQuoted(src, i, limit, c)
+		case c == '`':
+			i = skipUntil(src, i+1, limit, "`")
+		case c == '[':
+			i = skipQuoted(src, i, limit, ']')
+		case c == '{':
+			i = skipCode(src, i, limit)
+		default:
+			i++
+		}
+		end = i
+	}
+	return end
+}
+
+// skipUntil returns the offset after the first s in src from offset i,
+// or limit.
+func skipUntil(src []byte, i, limit int, s string) int {
+	if j := bytes.Index(src[i:limit], []byte(s)); j >= 0 {
+		return i + j + len(s)
+	}
+	return limit
+}
+
+// skipQuoted returns the offset after the quoted text that starts at
+// offset i in src and ends with the unescaped close, or limit.
+func skipQuoted(src []byte, i, limit int, close byte) int {
+	for i++; i < limit; i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case close:
+			return i + 1
+		}
+	}
+	return limit
+}
+
+// skipCode returns the offset after the code block that starts at
+// offset i in src, or limit.
+func skipCode(src []byte, i, limit int) int {
+	var depth int
+	for i < limit {
+		rest := src[i:limit]
+		switch c := src[i]; {
+		case bytes.HasPrefix(rest, []byte("//")):
+			i = skipUntil(src, i, limit, "\n")
+		case bytes.HasPrefix(rest, []byte("/*")):
+			i = skipUntil(src, i+2, limit, "*/")
+		case c == '"' || c == '\'':
+			i = skipQuoted(src, i, limit, c)
+		case c == '`':
+			i = skipUntil(src, i+1, limit, "`")
+		case c == '{':
+			depth++
+			i++
+		case c == '}':
+			depth--
+			i++
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return limit
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines around the changes in a
+// unified diff.
+const diffContext = 3
+
+// unifiedDiff returns the unified diff of the lines of a and b, named
+// aName and bName in the header. It returns nil if a and b are equal.
+func unifiedDiff(aName, bName string, a, b []byte) []byte {
+	if bytes.Equal(a, b) {
+		return nil
+	}
+	al, bl := splitLines(a), splitLines(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// al[i:] and bl[j:].
+	lcs := make([][]int, len(al)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bl)+1)
+	}
+	for i := len(al) - 1; i >= 0; i-- {
+		for j := len(bl) - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// edit script: ' ', '-' or '+' followed by the line
+	var edits []string
+	i, j := 0, 0
+	for i < len(al) || j < len(bl) {
+		switch {
+		case i < len(al) && j < len(bl) && al[i] == bl[j]:
+			edits = append(edits, " "+al[i])
+			i++
+			j++
+		case j == len(bl) || (i < len(al) && lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, "-"+al[i])
+			i++
+		default:
+			edits = append(edits, "+"+bl[j])
+			j++
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", aName, bName)
+
+	// line numbers in a and b of edits[k]
+	aLine, bLine := make([]int, len(edits)+1), make([]int, len(edits)+1)
+	for k, e := range edits {
+		aLine[k+1], bLine[k+1] = aLine[k], bLine[k]
+		if e[0] != '+' {
+			aLine[k+1]++
+		}
+		if e[0] != '-' {
+			bLine[k+1]++
+		}
+	}
+
+	for k := 0; k < len(edits); {
+		if edits[k][0] == ' ' {
+			k++
+			continue
+		}
+
+		// extend the hunk while the changes are separated by at most
+		// 2*diffContext unchanged lines.
+		start, end := max(k-diffContext, 0), k
+		for end < len(edits) {
+			if edits[end][0] != ' ' {
+				end++
+				continue
+			}
+			n := end
+			for n < len(edits) && edits[n][0] == ' ' {
+				n++
+			}
+			if n == len(edits) || n-end > 2*diffContext {
+				end = min(end+diffContext, len(edits))
+				break
+			}
+			end = n
+		}
+
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n",
+			hunkRange(aLine[start], aLine[end]), hunkRange(bLine[start], bLine[end]))
+		for _, e := range edits[start:end] {
+			buf.WriteString(e)
+			buf.WriteByte('\n')
+		}
+		k = end
+	}
+	return buf.Bytes()
+}
+
+// hunkRange returns the range of lines [from, to) in the format of the
+// hunk header, with 1-based lines.
+func hunkRange(from, to int) string {
+	if to-from == 1 {
+		return fmt.Sprint(from + 1)
+	}
+	if to == from {
+		return fmt.Sprintf("%d,0", from)
+	}
+	return fmt.Sprintf("%d,%d", from+1, to-from)
+}
+
+func splitLines(b []byte) []string {
+	s := strings.TrimSuffix(string(b), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -29,12 +29,64 @@ by default.
 
 The following options can be specified:
 
+	-byte-mode : boolean, if set, the generated parser matches the input
+	byte by byte instead of decoding UTF-8 runes, to parse binary file formats
+	and network protocols. The any matcher "." matches a single byte, literals
+	match their bytes and character classes match byte values, e.g.
+	"[\x00-\x1f]". Character classes can only contain values up to "\xff"
+	and no Unicode classes, and the case-insensitive matchers only ignore
+	the case of ASCII letters. Line and column positions count bytes. With
+	-optimize-grammar, only the literals of a single ASCII character are
+	combined into character classes (default: false).
+
 	-cache : cache parser results to avoid exponential parsing time in
 	pathological cases. Can make the parsing slower for typical
 	cases and uses more memory (default: false).
 
+	-choice-stats=FILE : string, file of statistics used to reorder the
+	alternatives of the ordered choices by decreasing number of matches:
+	the JSON encoding of the Stats.ChoiceAltCnt gathered over a corpus by
+	the generated parser with its Statistics option. To preserve the PEG
+	semantics, only the alternatives that are disjoint are reordered:
+	neither matches the empty string nor runs code before it consumes
+	a character, and the sets of the first characters that they match
+	do not intersect. The reordering is done before the optimizations of
+	-optimize-grammar, the statistics must be gathered with the parser
+	of the grammar as written, and the changes are printed to stderr
+	(default: none).
+
+	-coverage : boolean, if set, the Coverage option and the CoverProfile type
+	are generated, to gather the coverage of the grammar by the parses, see
+	"Using the generated parser" (default: false).
+
 	-debug : boolean, print debugging info to stdout (default: false).
 
+	-diff-grammar : boolean, if set, do not build the parser, write the unified
+	diff between the grammar and the grammar optimized as with -optimize-grammar
+	instead, both formatted as with "pigeon fmt" (default: false).
+
+	-fuzz-test : string, write a test file with a FuzzParse fuzz test of the
+	parser to this file, e.g. parser_test.go next to the parser. The fuzz test
+	checks that the parser does not panic with Recover(false), that it
+	completes the parse within a maximum number of expressions, a parse that
+	reaches the limit failing the test as it likely does not terminate, and
+	that the positions of its errors are in the input. Its seed corpus is made of the examples of the
+	entrypoint in the doc comments of the grammar, see "Testing grammars",
+	and of random sentences of the grammar, see "Generating sentences"
+	(default: none).
+
+	-input-decoder : boolean, if set, the InputDecoder option, the Decoder type
+	and the decoders of Latin-1, UTF-8 and UTF-16 are generated, to parse input
+	in other encodings than UTF-8. Ignored with -byte-mode (default: false).
+
+	-memo-profile=FILE : string, profile used to memoize the rules that are
+	evaluated again at the same offsets: the JSON encoding of the Profile
+	gathered over a corpus by a parser generated with -profiling, or by the
+	interp package. The rules with at least a quarter of revisits
+	or memo hits among their evaluations are memoized as if they had the
+	@memoize annotation, see "Memoization", and are printed to stderr
+	(default: none).
+
 	-nolint: add '// nolint: ...' comments for generated parser to suppress
 	warnings by gometalinter (https://github.com/alecthomas/gometalinter) or
 	golangci-lint (https://golangci-lint.run/).
@@ -46,6 +98,17 @@ The following options can be specified:
 	-o=FILE : string, output file where the generated parser will be
 	written (default: stdout).
 
+	-position-converter : boolean, if set, the PositionConverter and ColumnUnit
+	types, the NewPositionConverter function and the TabWidth option are
+	generated, to convert the byte offsets of an input to lines and columns in
+	other units, see "Using the generated parser" (default: false).
+
+	-print-grammar : boolean, if set, do not build the parser, write the grammar
+	as PEG source instead, formatted as with "pigeon fmt". With -optimize-grammar,
+	the optimized grammar is written, which shows the result of the optimizations
+	and can be used as a golden file in tests. The doc comments of the rules are
+	written with their annotations, the other comments are not (default: false).
+
 	-optimize-basic-latin : boolean, if set, a lookup table for the first 128
 	characters of the Unicode table (Basic Latin) is generated for each character
 	class matcher. This speeds up the parsing, if parsed data mainly consists
@@ -68,12 +131,21 @@ The following options can be specified:
 	more optimizations have applied). This process takes some time, depending on the
 	optimization potential of the grammar.
 
-	-optimize-parser : boolean, if set, the options Debug, Memoize and Statistics are
-	removed	from the resulting parser. The global "state" is optimized as well by
-	either removing all related code if no state change expression is present in the
-	grammar or by removing the restoration of the global "state" store after action
-	and predicate code blocks. This saves a few cpu cycles, when using the generated
-	parser (default: false).
+	-optimize-parser : boolean, if set, the options Coverage, Debug, Memoize,
+	Profiling, Statistics and Trace are removed from the resulting parser. The
+	global "state" is optimized as well by either removing all related code if
+	no state change expression is present in the grammar or by removing the
+	restoration of the global "state" store after action and predicate code
+	blocks. This saves a few cpu cycles, when using the generated parser
+	(default: false).
+
+	-profiling : boolean, if set, the Profiling option and the Profile,
+	ProfileEntry and ProfileStack types are generated, to gather the profile
+	of the parses. Ignored with -optimize-parser (default: false).
+
+	-tracing : boolean, if set, the Trace option and the Tracer, Position and
+	Span types are generated, to receive the events of the parses. Ignored
+	with -optimize-parser (default: false).
 
 	-x : boolean, if set, do not build the parser, just parse the input grammar
 	(default: false).
@@ -90,9 +162,9 @@ The following options can be specified:
 	necessary if the -optimize-parser flag is set, as some rules may be optimized
 	out of the resulting parser.
 
-	-support-left-recursion : boolean, (EXPERIMENTAL FEATURE) if set, add support
-	for left recursion rules, including those with indirect recursion
-	(default: false).
+	-support-left-recursion : boolean, if set, add support for left recursion
+	rules, including those with indirect and mutual recursion, see "Left
+	recursion" (default: false).
 	E.g.:
 		expr = expr '*' term / expr '+' term
 
@@ -103,6 +175,373 @@ and go vet-compliant.
 The generated code doesn't use any third-party dependency unless code blocks
 in the grammar require such a dependency.
 
+The grammar parser of the pigeon tool is available as the parse package:
+parse.ParseGrammar returns the AST of a grammar, validated as the pigeon tool
+does before generating its parser unless the parse.Validate(false) option is
+set.
+
+Formatting grammars
+
+The fmt command formats grammars in a canonical style, the same way gofmt
+does for Go source files:
+
+	pigeon fmt [options] [GRAMMAR_FILE...]
+
+Without GRAMMAR_FILE, the grammar is read from stdin. The formatted grammar
+is written to stdout, unless one of the following options is specified:
+
+	-l : boolean, list the files whose formatting differs from the
+	canonical style (default: false).
+
+	-w : boolean, write the formatted grammars to their source files
+	(default: false).
+
+	-arrow : string, the rule definition operator to use, one of "=", "<-",
+	"←" or "⟵" (default: "←").
+
+The canonical style writes the alternatives of a rule on a single line if it
+fits, otherwise one per line, aligned under the rule definition operator.
+Single-character literals are written with single quotes, the others with
+double quotes. The comments outside of the code blocks are kept, and the
+lines of multi-line code blocks are re-indented by 4 spaces relative to the
+line where the block starts. To check that the grammars of a project are
+formatted, e.g. in a pre-commit hook, test that "pigeon fmt -l" prints
+nothing. The printer is available as ast.Fprint.
+
+Documenting grammars
+
+The doc command generates the documentation of a grammar:
+
+	pigeon doc [options] [GRAMMAR_FILE]
+
+Without GRAMMAR_FILE, the grammar is read from stdin. By default, a
+self-contained HTML page with the railroad diagram of each rule is written
+to stdout. The following options are supported:
+
+	-format : string, the format of the documentation, one of "html", "svg",
+	"markdown" or "ebnf" (default: "html").
+
+	-o : string, the output file (default: stdout).
+
+	-title : string, the title of the html and markdown documents
+	(default: the name of GRAMMAR_FILE).
+
+The svg format writes the diagrams of all the rules in a single image, the
+markdown format a section per rule with its production in EBNF, and the ebnf
+format the productions only, in the EBNF notation of the Go specification
+(see bootstrap/peg.ebnf for an example) with the character classes in the
+W3C EBNF notation, e.g. [a-z]. In all formats, the references to other rules
+link to their documentation, and the rules are shown with their display name
+and doc comment. The diagrams and productions show the syntax only: labels
+and code blocks are left out, and the predicates and the Unicode classes
+that EBNF cannot express are written as comments. The
+generators are available in the docgen package.
+
+Exporting grammars
+
+The export command converts a grammar to other formats:
+
+	pigeon export [options] [GRAMMAR_FILE]
+
+Without GRAMMAR_FILE, the grammar is read from stdin. The converted grammar
+is written to stdout. The following options are supported:
+
+	-format : string, the output format, one of "json", "ebnf", "pegjs",
+	"tree-sitter" or "peg" (default: "json").
+
+	-from : string, the input format, one of "peg" or "json" (default: "peg").
+
+	-name : string, the name of the tree-sitter grammar (default: the name of
+	GRAMMAR_FILE).
+
+	-o : string, the output file (default: stdout).
+
+	-stub-code : boolean, with the pegjs format, replace the code blocks by
+	stubs instead of leaving them out (default: false).
+
+The json format encodes the AST of the grammar, including the positions of
+its nodes, its comments and its code blocks. It can be converted back with
+"-from json", e.g. to PEG source with "-from json -format peg", and it is
+the encoding of ast.Grammar by the encoding/json package. The ebnf format
+uses the notation of the W3C XML specification, pegjs writes a PEG.js
+grammar and tree-sitter the grammar.js skeleton of a tree-sitter grammar,
+with the labels as fields. These formats have no equivalent for the Go code
+blocks, which are left out, nor for some of the PEG constructs: the
+predicates, the state blocks, the back references, the indentation and the
+throw expressions are written as comments or left out, and the character
+classes with Unicode classes may be approximated by a class that matches more
+characters. The exporters are available as ast.ExportW3CEBNF,
+ast.ExportPEGjs and ast.ExportTreeSitter.
+
+Importing grammars
+
+The import command converts the grammars of other PEG parser generators to
+pigeon grammars:
+
+	pigeon import [options] [GRAMMAR_FILE]
+
+Without GRAMMAR_FILE, the grammar is read from stdin. The pigeon grammar is
+written to stdout. The following options are supported:
+
+	-from : string, the input format, one of "pegjs" (PEG.js and Peggy) or
+	"pegen" (the parser generator of CPython) (default: "pegjs" for the .pegjs
+	and .peggy files, "pegen" for the .gram files).
+
+	-o : string, the output file (default: stdout).
+
+The rules, choices, sequences, labels, predicates and repetitions carry over.
+The initializers, actions and semantic predicates, written in JavaScript or
+Python, are replaced by TODO stubs that keep the original code in comments:
+the actions return nil and the predicates true or false until they are
+ported to Go. The text operator $ of PEG.js becomes an action that returns
+the matched text and the plucks @ an action that returns the plucked
+values. The repetitions |min..max| of Peggy and the gathers s.e+ of pegen
+are expanded, the cuts ~ of pegen are dropped and the tokens that a pegen
+grammar uses without defining them, such as NAME or NEWLINE, get a stub rule.
+The importers are available in the importer package.
+
+Testing grammars
+
+The test command runs example inputs against the rules of a grammar,
+without writing a Go test for each one:
+
+	pigeon test [options] GRAMMAR_FILE [EXAMPLES_FILE...]
+
+The examples are read from the doc comments of the rules, in the lines that
+start with @accept or @reject followed by the input as a Go string literal.
+An accepted example may be followed by => and the snapshot of the value
+returned by the parser, where strings and byte slices are quoted and the
+slices of values are written in brackets:
+
+	// Sum is a sum of digits.
+	//
+	// @accept "1+2" => ["1" [["+" "2"]]]
+	// @reject "1+"
+	Sum ← Digit ( '+' Digit )*
+
+The examples are also read from the EXAMPLES_FILE YAML files, or by default
+from the YAML file with the name of GRAMMAR_FILE and the .yaml or .yml
+extension, if it exists. It maps the names of the rules to their accepted
+and rejected examples, each one the input or a mapping with the input and
+the snapshot:
+
+	Sum:
+	  accept:
+	    - "1"
+	    - input: "1+2"
+	      want: '["1" [["+" "2"]]]'
+	  reject:
+	    - "1+"
+
+The parser of the grammar is generated in a temporary Go module and the
+examples are run with the go test command, each one using its rule as
+entrypoint. An accepted example must match the whole input. The command
+exits with the status code 10 if an example fails. The following options are
+supported:
+
+	-optimize-grammar : boolean, optimize the grammar, with the tested rules
+	as alternate entrypoints (default: false).
+
+	-optimize-parser : boolean, generate an optimized parser (default:
+	false).
+
+	-run : string, run only the examples of the rules that match this regular
+	expression (default: all the rules).
+
+	-support-left-recursion : boolean, add support for left recursion
+	(default: false).
+
+	-v : boolean, print the result of each example, not only the failures
+	(default: false).
+
+	-work : string, the directory where the parser and its test are generated
+	and kept, e.g. in the Go module that provides the imports of the code
+	blocks (default: a temporary Go module where only the standard library is
+	available).
+
+The examples can be run from Go code with the pegtest package.
+
+Running grammars
+
+The run command parses an input with a grammar by interpreting the grammar,
+without generating its parser:
+
+	pigeon run [options] GRAMMAR_FILE [INPUT_FILE]
+
+It prints the concrete syntax tree of the match, a line per match of a rule
+indented by its depth, with the positions and the text of the match:
+
+	$ pigeon run -e '1+2' sum.peg
+	Sum 1:1-1:4 "1+2"
+	  Digit 1:1-1:2 "1"
+	  Digit 1:3-1:4 "2"
+
+If the input is not matched, the error with the expected matchers is printed
+and the command exits with the status code 3. The input is read from
+INPUT_FILE, from the -e flag or from stdin, and the match must cover the whole
+input. The code blocks of the grammar are not run: the actions return the
+value of their expression, the code predicates match and the state code
+blocks do nothing. The following options are supported:
+
+	-e : string, the input to parse instead of INPUT_FILE (default: none).
+
+	-i : boolean, start an interactive session that parses each line read
+	from stdin, as is or as a Go string literal. The :def command adds or
+	replaces a rule of the grammar, e.g. ":def Digit ← [0-9]+", and :help
+	lists the other commands (default: false).
+
+	-memoize : boolean, memoize the parsing results (default: false).
+
+	-rule : string, the rule to use as entrypoint (default: the first rule).
+
+	-value : boolean, print the snapshot of the value returned by the
+	entrypoint rule, in the format of the test command, instead of the syntax
+	tree (default: false).
+
+Grammars can be interpreted from Go code with the interp package, where Go
+callbacks registered by rule name and alternative replace the actions.
+
+Profiling grammars
+
+The profile command finds the hot spots of a grammar. It parses inputs by
+interpreting the grammar, as the run command does, and prints the source of
+the grammar annotated with the profile of the parses:
+
+	pigeon profile [options] GRAMMAR_FILE [INPUT_FILE...]
+
+Before each rule, a comment gives the evaluations, matches and failures of
+the rule, its revisits, the evaluations at an offset where it was already
+evaluated, its hits in the memoization table, the bytes that it consumed and
+backtracked and the time spent, followed by a comment with the same numbers
+for each of its expressions, identified by their position:
+
+	// 3 calls, 3 matches, 0 failures, 1 revisits, 0 memo hits, 9 bytes, 1 backtracked, 61µs
+	//   5:7 choiceExpr Num '+' Sum / Num: 3 calls, 3 matches, 0 failures, ...
+	Sum ← Num '+' Sum / Num
+
+The inputs are read from the INPUT_FILEs or from stdin. The errors of the
+inputs that cannot be parsed are printed after the profile, and the command
+exits with the status code 3. The following options are supported:
+
+	-load : string, read the profile from this file instead of parsing inputs,
+	as the JSON encoding of the Profile gathered by a parser generated with
+	-profiling (default: none).
+
+	-memoize : boolean, memoize the parsing results (default: false).
+
+	-o : string, output file where the report is written (default: stdout).
+
+	-pprof : string, write the stacks of rules to this file as a profile for
+	"go tool pprof", with the evaluations and the time as sample values and
+	the rules as functions (default: none).
+
+	-rule : string, the rule to use as entrypoint (default: the first rule).
+
+	-text : boolean, print the statistics of the rules and of the expressions
+	as tables sorted by decreasing time instead of annotating the grammar
+	(default: false).
+
+The reports are written by the profile package, from the profiles gathered by
+the Profile option of the interp package or read from JSON.
+
+Measuring the coverage of grammars
+
+The cover command finds the parts of a grammar that a corpus of inputs never
+exercises. It parses the inputs by interpreting the grammar, as the run
+command does, and prints the source of the grammar annotated with its
+coverage:
+
+	pigeon cover [options] GRAMMAR_FILE [INPUT_FILE...]
+
+The rules and the alternatives of the choices are covered if they matched at
+least once, and the branches of the character classes, their characters,
+ranges and Unicode classes, if they contained an input character. Before each
+rule, a comment gives the matches of the rule and its percentage of coverage,
+followed by a comment for each of its alternatives and character classes,
+identified by their position. The comments of the parts that are not covered
+start with "//!":
+
+	// Num: 4 matches, 60.0% covered
+	//   3:7 alternative 1 [0-9_]+: 4 matches
+	//!  3:7 class [0-9_]: 5 matches ('_' 0, '0'-'9' 5)
+	//!  3:17 alternative 2 "-": 0 matches
+	Num ← [0-9_]+ / "-"
+
+The inputs are read from the INPUT_FILEs or from stdin. The errors of the
+inputs that cannot be parsed are printed after the coverage, and the command
+exits with the status code 3. The following options are supported:
+
+	-html : boolean, write the grammar as an HTML page where the names of the
+	rules, the alternatives and the character classes are colored by their
+	coverage, as "go tool cover -html" does (default: false).
+
+	-load : string, read the coverage from this file instead of parsing
+	inputs, as the JSON encoding of the CoverProfile gathered by a parser
+	generated with -coverage (default: none).
+
+	-memoize : boolean, memoize the parsing results. The results found in the
+	memoization table are not counted (default: false).
+
+	-o : string, output file where the report is written (default: stdout).
+
+	-rule : string, the rule to use as entrypoint (default: the first rule).
+
+	-summary : boolean, print the percentage of coverage of each rule and of
+	the grammar, as "go tool cover -func" does, instead of annotating the
+	grammar (default: false).
+
+The reports are written by the cover package, from the coverage gathered by
+the Coverage option of the interp package or read from JSON.
+
+Generating sentences
+
+The gen command generates random sentences of a grammar, inputs that the
+grammar accepts, e.g. to seed the corpus of the fuzz tests of its parser:
+
+	pigeon gen [options] GRAMMAR_FILE
+
+The alternatives of the choices, the number of repetitions and the characters
+of the classes are picked at random. Past a maximum depth of rules, the
+alternatives that complete the sentence the fastest are picked. The
+predicates and the code blocks are not run: each sentence is parsed by
+interpreting the grammar, and rejected if it does not match. If no sentence
+is found after a number of attempts, the command exits with the status code
+11. The following options are supported:
+
+	-corpus : string, write each sentence to a file of this directory, in the
+	format of the seed corpus of the fuzz tests, e.g. testdata/fuzz/FuzzParse
+	(default: none).
+
+	-depth : int, the depth of the rules past which the sentences are
+	completed as fast as possible (default: 16).
+
+	-invalid : boolean, generate near misses instead of sentences: inputs
+	where a character matched by a class is replaced with a character that it
+	does not match, and that the grammar rejects (default: false).
+
+	-n : int, the number of sentences (default: 10).
+
+	-o : string, output file where the sentences are written, one per line
+	(default: stdout).
+
+	-quote : boolean, print the sentences as quoted Go strings
+	(default: false).
+
+	-repeat : int, the maximum number of repetitions of the expressions of *,
+	+ and ? (default: 3).
+
+	-rule : string, the rule to use as entrypoint (default: the first rule).
+
+	-seed : int, the seed of the random generator, to generate the same
+	sentences again (default: random).
+
+	-weights : string, weight the alternatives of the choices by their number
+	of matches in this file, the JSON encoding of the Stats.ChoiceAltCnt
+	gathered by a generated parser, as for the -choice-stats option
+	(default: none).
+
+The sentences are generated by the gen package.
+
 PEG syntax
 
 The accepted syntax for the grammar is formally defined in the
@@ -136,6 +575,13 @@ be specified after the rule identifier. E.g.:
 The rule definition operator can be any one of those:
 	=, <-, ← (U+2190), ⟵ (U+27F5)
 
+The comments on the lines directly before a rule, starting at the same column,
+are the doc comment of the rule, as in Go. They are kept in the Doc field of
+the ast.Rule and written as Go comments on the rule in the generated grammar
+table and on the methods generated for its code blocks. E.g.:
+	// Number matches an integer with an optional sign.
+	Number = [+-]? [0-9]+
+
 Expressions
 
 A rule is defined by an expression. The following sections describe the
@@ -207,6 +653,22 @@ is used. E.g.:
 	}
 	RuleB = label:RuleA { // label is int }
 
+Back-reference expression
+
+A back-reference expression consists of a backslash "\" followed by the
+name of a label. It matches exactly the text that was matched by the
+labeled expression, which must be in scope: the label must be defined
+earlier in the same sequence, or in a sequence that encloses the
+back-reference in the same rule. Labels defined inside a choice
+alternative, a repetition or a predicate are not visible outside of it.
+E.g.:
+	RawString = 'r' hashes:'#'* '"' ( !( '"' \hashes ) . )* '"' \hashes
+	Element = '<' name:Name '>' Content* "</" \name '>'
+
+The value of a back-reference expression is the matched text as []byte.
+Rules that contain back-references are never memoized at the expression
+level, as the result depends on the text captured by the label.
+
 And and not expressions
 
 An expression prefixed with the ampersand "&" is the "and" predicate
@@ -229,6 +691,38 @@ defined in its scope. E.g.:
 		return true, nil
 	}
 
+Indentation expressions
+
+The "%INDENT", "%DEDENT" and "%SAMEDENT" expressions support grammars where
+the indentation is significant, such as Python or YAML. The parser keeps a
+stack of indentation levels, initially at width 0, and each expression
+compares the indentation of the next non-blank line against the top of
+that stack. Blank lines, made only of spaces and tabs, are skipped and the
+end of the input has an indentation of 0.
+
+	- %INDENT matches if the line is indented more than the current level,
+	and pushes a new level. It does not consume any input.
+	- %DEDENT matches if the line is indented less than the current level,
+	and at most as much as the enclosing level, and pops the current level.
+	It does not consume any input.
+	- %SAMEDENT matches if the line is indented as much as the current level,
+	and consumes the blank lines and the indentation of that line.
+
+E.g.:
+	Lines = ( %SAMEDENT Statement )+
+	Statement = "if" _ Expr ':' EOL %INDENT Lines %DEDENT / Simple EOL
+
+Each tab advances the width to the next tab stop, every 8 columns by
+default (see the TabWidth option). If the comparison of a line to the
+current level would give a different result with a tab counting as a
+single column, the expression fails with an "inconsistent use of tabs and
+spaces in indentation" error.
+
+The indentation stack is part of the position of the parser, so it is
+restored when the parser backtracks, without requiring the global state
+and its cloning. The value of an indentation expression is the consumed
+text as []byte.
+
 Repeating expressions
 
 An expression followed by "*", "?" or "+" is a match if the expression
@@ -364,7 +858,11 @@ in the "state" use state change code blocks ("#{}").
 
 IMPORTANT:
 	- In order to properly roll back the state if a rule fails to match the
-	  parser must clone the state before trying to match a rule.
+	  parser must clone the state before trying to match a rule. As only the
+	  state change code blocks update the "state", it is cloned this way only
+	  in the parsers of the grammars that contain such blocks; e.g. the
+	  indentation stack of the indentation expressions is not part of the
+	  "state" and does not require it.
 	- The default clone mechanism makes a "shallow" copy of each value in the
 	  "state", this implies that pointers, maps, slices, channels, and structs
 	  containing any of the previous types are not properly copied.
@@ -400,12 +898,100 @@ The implementation is based on the [Left-recursive PEG Grammars][9] article that
 links to [Left Recursion in Parsing Expression Grammars][10] and
 [Packrat Parsers Can Support Left Recursion][11] papers.
 
+The rules that can call each other at the same position form the cycles of
+the left recursion, and some of them are leaders: the parser grows the result
+of a leader by parsing it again as long as it matches more input, with its
+previous result as the result of its recursive calls. Every cycle goes through
+a leader, and the rules in all the cycles are picked first, so that several
+rules are leaders only if no single rule breaks all the cycles:
+
+	Foo = Bar '+' / Baz '+' / '+'
+	Bar = Baz '-' / Foo '-' / '-'
+	Baz = Foo '*' / Bar '*' / '*'
+
+The result of a leader grown while another leader is grown at the same position
+is evaluated again with each new result of the other. As the choices are
+ordered, a result grows with the first alternative that matches, and a rule
+that is not a leader returns the first alternative that matches, not the
+longest. The left recursion works with the -optimize-parser and
+-optimize-grammar options, as well as with the Memoize and MemoWindow options.
+
 References:
 
 	[9]: https://medium.com/@gvanrossum_83706/left-recursive-peg-grammars-65dab3c580e1
 	[10]: https://arxiv.org/pdf/1207.0443.pdf
 	[11]: http://web.cs.ucla.edu/~todd/research/pepm08.pdf
 
+Memoization
+
+The Memoize option of the generated parser caches the result of every
+expression at every offset, which guarantees a linear parsing time but makes
+the parsing of typical inputs slower. Instead, the rules that a grammar
+evaluates again at the same offsets, e.g. because several alternatives of a
+choice start with them, can be memoized on their own with the @memoize
+annotation, on a line of their doc comment:
+
+	// @memoize
+	Term = Number / '(' Expr ')'
+
+The results of the annotated rules are cached even without the Memoize
+option, and in the parsers generated with -optimize-parser, while their
+expressions are evaluated as usual. The -optimize-grammar option does not
+inline the annotated rules. The revisits of the rules are counted by the
+profile command, and the -memo-profile option memoizes the rules that are
+revisited the most in a profile, without annotating the grammar.
+
+The memoization table grows with the input, for each offset where results are
+cached. The MemoWindow option bounds its memory: the results are kept only for
+a window of offsets behind the furthest offset where a result was cached, and
+evaluated again in the rare cases where the parser backtracks further. The
+rules that end a unit of the input that the parser never backtracks into, e.g.
+a statement, can be marked as commit points with the @commit annotation:
+
+	// @commit
+	Statement = Expr ';'
+
+Once an annotated rule matches, the results cached before the end of its
+match are discarded. The -optimize-grammar option does not inline the
+annotated rules. The results of the left-recursive rules are kept out of the
+window and of the commit points, as they are required to parse the left
+recursion.
+
+Precedence tables
+
+The binary and unary operators of an expression language can be declared as a
+precedence table instead of a rule per level. The table is a list of
+annotations on the lines of the doc comment of a rule, each annotation being a
+level of operators, from the lowest to the highest precedence:
+
+	// @nonassoc "==" "<"
+	// @left "+" "-"
+	// @left "*" "/"
+	// @prefix "-" "!"
+	// @right "**"
+	// @postfix "!"
+	// @skip _
+	Expr = Primary {
+		return eval(op, left, right)
+	}
+
+The @left, @right and @nonassoc annotations declare the infix operators that
+associate to the left, to the right or not at all, e.g. "1 < 2 < 3" does not
+match; @prefix and @postfix declare the unary operators. The operators are
+quoted as string literals, and the longest operator that matches is used. The
+@skip annotation names the rule matched before and after the operators, e.g.
+the whitespace.
+
+The expression of the rule matches the operands and must have a code block.
+The rule is compiled to a precedence climbing matcher, that calls the code
+block for each operation with the labels op, left and right, the operator and
+its operands: left is nil for the prefix operators and right for the postfix
+operators. A prefix operator applies to the operations of the higher levels,
+e.g. "-2 ** 2" is "-(2 ** 2)" with the table above. The -optimize-grammar
+option does not inline the rule. The interp package calls the action
+registered for the rule instead of the code block, and without action returns
+the operations as lists of their operator and operands.
+
 Failure labels, throw and recover
 
 pigeon supports an extension of the classical PEG syntax called failure labels,
@@ -465,13 +1051,59 @@ as a package with public functions to parse input text. The exported API is:
 	- Memoize(bool) Option
 	- Recover(bool) Option
 	- Statistics(*Stats) Option
+	- TabWidth(int) Option, if the grammar uses the indentation expressions
+
+The following options and their types are only generated with the flag
+that follows them, as their names could collide with the declarations of
+the initializer code block:
+	- Coverage(*CoverProfile) Option: -coverage
+	- InputDecoder(Decoder) Option, DecodeLatin1, DecodeUTF8, DecodeUTF16BE,
+	  DecodeUTF16LE Decoder: -input-decoder
+	- NewPositionConverter([]byte, ...Option) *PositionConverter, ColumnBytes,
+	  ColumnRunes, ColumnUTF16, ColumnTabs ColumnUnit, TabWidth(int) Option:
+	  -position-converter
+	- Profiling(*Profile) Option: -profiling
+	- Trace(Tracer) Option, Position, Span: -tracing
 
 See the godoc page of the generated parser for the test/predicates grammar
 for an example documentation page of the exported API:
 http://godoc.org/github.com/mna/pigeon/test/predicates.
 
-Like the grammar used to generate the parser, the input text must be
-UTF-8-encoded Unicode.
+Like the grammar used to generate the parser, the input text is expected
+to be UTF-8-encoded Unicode, unless the parser was generated with the
+-byte-mode flag. Input in another encoding is supported with the
+InputDecoder option of the parsers generated with -input-decoder, which
+transcodes the input to UTF-8 as it is parsed while the offsets of the
+positions remain relative to the original bytes.
+
+The line and column of the positions in the errors and in c.pos count
+characters. The PositionConverter of the parsers generated with
+-position-converter maps the byte offsets of an input, such as
+c.pos.offset, to lines and columns counted in bytes, characters, UTF-16
+code units as used by language servers, or characters with tabs expanded
+to the next tab stop, and back. Its ErrorPosition method returns the
+position of an error returned by the parser. The multiple errors returned
+by the parser are available with the Unwrap() []error method of the error.
+
+The Trace option of the parsers generated with -tracing sets a Tracer, an
+interface with methods called as the parser enters and exits the rules and
+its other steps, backtracks, looks up the memoization table and records
+errors, with the positions of these events. It allows to log a parse, e.g.
+with log/slog, or to visualize it. The Debug option prints the events to
+stdout as indented lines, it replaces the Tracer.
+
+The Profiling option of the parsers generated with -profiling gathers the
+profile of the parses in a Profile: the evaluations, matches and failures
+of the rules and expressions, the bytes that they consumed and
+backtracked, their hits in the memoization table and the time spent. Its
+JSON encoding is reported by the profile command with its -load flag.
+
+The Coverage option of the parsers generated with -coverage gathers the
+coverage of the grammar by the parses in a CoverProfile: the matches of the
+rules and of the alternatives of the choices, and the input characters in
+each branch of the character classes. Its JSON encoding is reported by the
+cover command with its -load flag, so that the coverage of the tests of a
+package using the parser can be gathered across all of its Parse calls.
 
 The start rule of the parser is the first rule in the PEG grammar used
 to generate the parser. A call to any of the Parse* functions returns
@@ -579,6 +1211,15 @@ the future):
 	- The explicitly exported API generated by pigeon. See [6] for the
 	documentation of this API on a generated parser.
 
+	Compatibility note: the Coverage, InputDecoder, Profiling and Trace options
+	and their types are only generated with the -coverage, -input-decoder,
+	-profiling and -tracing flags, so that their exported names do not
+	collide with the types of the user code, e.g. a Position or a Span in
+	the AST. The same goes for the PositionConverter, its types and the
+	TabWidth option, generated with the -position-converter flag, TabWidth
+	being also generated for the grammars with indentation expressions. The
+	parsers that use them must be generated again with these flags.
+
 	- The PEG syntax, as documented above.
 
 	- The code blocks (except the initializer) will always be generated as
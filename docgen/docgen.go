@@ -0,0 +1,218 @@
+// Package docgen generates the documentation of a grammar: railroad
+// diagrams as HTML or SVG, and the rules in EBNF as Markdown or plain
+// text.
+//
+// The diagrams show the syntax of the rules only: the labels, the code
+// blocks of the actions and the recovery expressions are left out.
+package docgen
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/mna/pigeon/ast"
+)
+
+// WriteHTML writes a self-contained HTML page with the railroad diagram
+// of each rule of g to w. The references to other rules link to their
+// diagram. If title is empty, "Grammar" is used.
+func WriteHTML(w io.Writer, g *ast.Grammar, title string) error {
+	if title == "" {
+		title = "Grammar"
+	}
+	b := newDiagramBuilder(g)
+	usedBy := usedBy(g)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+section { margin-bottom: 2em; }
+pre.doc { font-family: inherit; white-space: pre-wrap; }
+p.refs { font-size: small; color: #666; }
+%s</style>
+</head>
+<body>
+<h1>%[1]s</h1>
+<ul>
+`, html.EscapeString(title), style)
+	for _, r := range g.Rules {
+		fmt.Fprintf(&buf, `<li><a href="#%s">%s</a></li>`+"\n",
+			anchor(r.Name.Val), html.EscapeString(r.Name.Val))
+	}
+	buf.WriteString("</ul>\n")
+
+	for _, r := range g.Rules {
+		fmt.Fprintf(&buf, `<section id="%s">`+"\n", anchor(r.Name.Val))
+		fmt.Fprintf(&buf, "<h2>%s</h2>\n", html.EscapeString(displayName(r)))
+		if doc := r.DocText(); doc != "" {
+			fmt.Fprintf(&buf, "<pre class=\"doc\">%s</pre>\n", html.EscapeString(strings.TrimSuffix(doc, "\n")))
+		}
+		var s svgWriter
+		b.diagram(&s, r, 0, 0, "", false)
+		buf.Write(s.Bytes())
+		if names := usedBy[r.Name.Val]; len(names) > 0 {
+			buf.WriteString(`<p class="refs">Used by: `)
+			for i, nm := range names {
+				if i > 0 {
+					buf.WriteString(", ")
+				}
+				fmt.Fprintf(&buf, `<a href="#%s">%s</a>`, anchor(nm), html.EscapeString(nm))
+			}
+			buf.WriteString("</p>\n")
+		}
+		buf.WriteString("</section>\n")
+	}
+	buf.WriteString("</body>\n</html>\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteSVG writes a single SVG document with the railroad diagrams of
+// all the rules of g to w, one under the other.
+func WriteSVG(w io.Writer, g *ast.Grammar) error {
+	b := newDiagramBuilder(g)
+
+	var s svgWriter
+	var width, height int
+	for _, r := range g.Rules {
+		var d svgWriter
+		h, rw := b.diagram(&d, r, 0, height, anchor(r.Name.Val), true)
+		s.Write(d.Bytes())
+		height += h
+		width = max(width, rw)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %[1]d %[2]d">`+"\n", width, height)
+	fmt.Fprintf(&buf, "<style>\n%s</style>\n", style)
+	buf.Write(s.Bytes())
+	buf.WriteString("</svg>\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteMarkdown writes the documentation of g to w as Markdown: a section
+// per rule with its doc comment, its production in EBNF and links to the
+// rules it references and to the rules that use it. If title is empty,
+// "Grammar" is used.
+func WriteMarkdown(w io.Writer, g *ast.Grammar, title string) error {
+	if title == "" {
+		title = "Grammar"
+	}
+	usedBy := usedBy(g)
+	rules := make(map[string]bool, len(g.Rules))
+	for _, r := range g.Rules {
+		rules[r.Name.Val] = true
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# %s\n", markdownText(title))
+	for _, r := range g.Rules {
+		fmt.Fprintf(&buf, "\n<a id=\"%s\"></a>\n## %s\n\n", anchor(r.Name.Val), markdownText(displayName(r)))
+		if doc := r.DocText(); doc != "" {
+			buf.WriteString(doc)
+			buf.WriteString("\n")
+		}
+		buf.WriteString("```ebnf\n")
+		writeProduction(&buf, r)
+		buf.WriteString("```\n")
+
+		refs := slices.DeleteFunc(references(r), func(nm string) bool { return !rules[nm] })
+		writeLinks(&buf, "References", refs)
+		writeLinks(&buf, "Used by", usedBy[r.Name.Val])
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// markdownReplacer escapes the HTML and the Markdown emphasis and links.
+var markdownReplacer = strings.NewReplacer(
+	"&", "&amp;", "<", "&lt;", ">", "&gt;",
+	"\\", "\\\\", "`", "\\`", "*", "\\*", "_", "\\_", "[", "\\[", "]", "\\]",
+)
+
+// markdownText returns s escaped to be written as Markdown text.
+func markdownText(s string) string {
+	return markdownReplacer.Replace(s)
+}
+
+func writeLinks(buf *bytes.Buffer, head string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	fmt.Fprintf(buf, "\n%s: ", head)
+	for i, nm := range names {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(buf, "[%s](#%s)", nm, anchor(nm))
+	}
+	buf.WriteString("\n")
+}
+
+// WriteEBNF writes the rules of g to w in the EBNF notation of the Go
+// language specification, with their doc comments. The character classes
+// are written in the W3C EBNF notation, e.g. [a-z]. The parts of the
+// grammar that cannot be expressed in EBNF, such as the predicates and
+// the Unicode classes, are written as comments.
+func WriteEBNF(w io.Writer, g *ast.Grammar) error {
+	var buf bytes.Buffer
+	for i, r := range g.Rules {
+		doc := r.DocText()
+		if i > 0 && doc != "" {
+			buf.WriteString("\n")
+		}
+		for _, line := range strings.SplitAfter(doc, "\n") {
+			if line == "" {
+				continue
+			}
+			if line == "\n" {
+				buf.WriteString("//\n")
+				continue
+			}
+			buf.WriteString("// " + line)
+		}
+		writeProduction(&buf, r)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// references returns the names of the rules referenced by r, in order
+// of first reference.
+func references(r *ast.Rule) []string {
+	var names []string
+	ast.Inspect(r.Expr, func(expr ast.Expression) bool {
+		if ref, ok := expr.(*ast.RuleRefExpr); ok && !slices.Contains(names, ref.Name.Val) {
+			names = append(names, ref.Name.Val)
+		}
+		return true
+	})
+	return names
+}
+
+// usedBy returns the names of the rules that reference each rule of g.
+func usedBy(g *ast.Grammar) map[string][]string {
+	m := make(map[string][]string)
+	for _, r := range g.Rules {
+		for _, nm := range references(r) {
+			if !slices.Contains(m[nm], r.Name.Val) {
+				m[nm] = append(m[nm], r.Name.Val)
+			}
+		}
+	}
+	return m
+}
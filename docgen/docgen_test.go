@@ -0,0 +1,138 @@
+package docgen
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/bootstrap"
+)
+
+const testGrammar = `
+// Expr is an arithmetic
+// expression.
+Expr "expression" <- Term ( op:[-+] Term )* EOF
+Term <- Factor ( [*/] Factor )+ / Factor
+Factor <- '(' Expr ')' / [0-9]+ / &'x' "abc"i / !. [^a-z] / [<&>]
+EOF <- !.
+`
+
+func parseGrammar(t *testing.T, src string) *ast.Grammar {
+	t.Helper()
+	p := bootstrap.NewParser()
+	g, err := p.Parse("", strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestWriteEBNF(t *testing.T) {
+	want := `// Expr is an arithmetic
+// expression.
+Expr = Term { [#x2D+] Term } EOF .
+Term = Factor [*/] Factor { [*/] Factor } | Factor .
+Factor = "(" Expr ")" |
+    [0-9] { [0-9] } |
+    /* &"x" */ "abc" /* ignore case */ |
+    /* !any character */ [^a-z] |
+    [<&>] .
+EOF = /* !any character */ .
+Class = /* [<&>\pL] */ .
+`
+	var buf bytes.Buffer
+	if err := WriteEBNF(&buf, parseGrammar(t, testGrammar+"Class <- [<&>\\pL]\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, parseGrammar(t, testGrammar+"Bold \"<b>x</b> *y*\" <- 'x'\n"), "Arith"); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"# Arith\n",
+		"<a id=\"rule-Expr\"></a>\n## Expr \"expression\"\n\nExpr is an arithmetic\nexpression.\n",
+		"References: [Term](#rule-Term), [EOF](#rule-EOF)\n",
+		"Used by: [Term](#rule-Term)\n\n<a id=\"rule-EOF\"></a>",
+		"## Bold \"&lt;b&gt;x&lt;/b&gt; \\*y\\*\"\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in:\n%s", want, got)
+		}
+	}
+}
+
+var hrefRx = regexp.MustCompile(`href="#([^"]+)"`)
+
+func TestWriteSVG(t *testing.T) {
+	g := parseGrammar(t, testGrammar)
+
+	var buf bytes.Buffer
+	if err := WriteSVG(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	checkXML(t, buf.Bytes())
+
+	got := buf.String()
+	for _, r := range g.Rules {
+		if !strings.Contains(got, `id="`+anchor(r.Name.Val)+`"`) {
+			t.Errorf("missing diagram of rule %s", r.Name.Val)
+		}
+	}
+	for _, m := range hrefRx.FindAllStringSubmatch(got, -1) {
+		if !strings.Contains(got, `id="`+m[1]+`"`) {
+			t.Errorf("link to unknown id %s", m[1])
+		}
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	g := parseGrammar(t, testGrammar)
+
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, g, ""); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"<title>Grammar</title>",
+		`<section id="rule-Factor">`,
+		"<h2>Expr &#34;expression&#34;</h2>",
+		`<pre class="doc">Expr is an arithmetic` + "\nexpression.</pre>",
+		`<a href="#rule-Expr"><title>Expr &#34;expression&#34;</title>`,
+		`<p class="refs">Used by: <a href="#rule-Expr">Expr</a></p>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in:\n%s", want, got)
+		}
+	}
+
+	// the diagrams are well-formed
+	for _, svg := range regexp.MustCompile(`(?s)<svg .*?</svg>`).FindAllString(got, -1) {
+		checkXML(t, []byte(svg))
+	}
+}
+
+func checkXML(t *testing.T, b []byte) {
+	t.Helper()
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		_, err := dec.Token()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Fatalf("invalid XML: %v\n%s", err, b)
+		}
+	}
+}
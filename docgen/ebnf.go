@@ -0,0 +1,142 @@
+package docgen
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mna/pigeon/ast"
+)
+
+// maxEBNFLine is the length of line after which the alternatives of a
+// production are written on continuation lines.
+const maxEBNFLine = 80
+
+// precedence of the EBNF expressions
+const (
+	ebnfAlt = iota
+	ebnfSeq
+	ebnfPrimary
+)
+
+// writeProduction writes r to buf as an EBNF production.
+func writeProduction(buf *bytes.Buffer, r *ast.Rule) {
+	head := r.Name.Val + " = "
+	var alts []string
+	if ch, ok := unwrap(r.Expr).(*ast.ChoiceExpr); ok {
+		for _, alt := range ch.Alternatives {
+			alts = append(alts, ebnf(alt, ebnfSeq))
+		}
+	} else {
+		alts = append(alts, ebnf(r.Expr, ebnfAlt))
+	}
+
+	line := head + strings.Join(alts, " | ") + " ."
+	if utf8.RuneCountInString(line) <= maxEBNFLine || len(alts) == 1 {
+		buf.WriteString(line + "\n")
+		return
+	}
+	buf.WriteString(head + alts[0])
+	for _, alt := range alts[1:] {
+		buf.WriteString(" |\n    " + alt)
+	}
+	buf.WriteString(" .\n")
+}
+
+// unwrap returns the expression of the labels and actions around expr,
+// that EBNF does not represent.
+func unwrap(expr ast.Expression) ast.Expression {
+	for {
+		switch e := expr.(type) {
+		case *ast.ActionExpr:
+			expr = e.Expr
+		case *ast.LabeledExpr:
+			expr = e.Expr
+		case *ast.RecoveryExpr:
+			expr = e.Expr
+		default:
+			return expr
+		}
+	}
+}
+
+// ebnf returns expr in EBNF, in parentheses if its precedence is lower
+// than prec.
+func ebnf(expr ast.Expression, prec int) string {
+	var s string
+	var p int
+	switch expr := unwrap(expr).(type) {
+	case *ast.ChoiceExpr:
+		alts := make([]string, len(expr.Alternatives))
+		for i, alt := range expr.Alternatives {
+			alts[i] = ebnf(alt, ebnfSeq)
+		}
+		s, p = strings.Join(alts, " | "), ebnfAlt
+	case *ast.SeqExpr:
+		exprs := make([]string, 0, len(expr.Exprs))
+		for _, e := range expr.Exprs {
+			if e := ebnf(e, ebnfSeq); e != "" {
+				exprs = append(exprs, e)
+			}
+		}
+		s, p = strings.Join(exprs, " "), ebnfSeq
+	case *ast.ZeroOrOneExpr:
+		s, p = "[ "+ebnf(expr.Expr, ebnfAlt)+" ]", ebnfPrimary
+	case *ast.ZeroOrMoreExpr:
+		s, p = "{ "+ebnf(expr.Expr, ebnfAlt)+" }", ebnfPrimary
+	case *ast.OneOrMoreExpr:
+		s, p = ebnf(expr.Expr, ebnfSeq)+" { "+ebnf(expr.Expr, ebnfAlt)+" }", ebnfSeq
+	case *ast.AndExpr:
+		s, p = "/* &"+uncomment(ebnf(expr.Expr, ebnfPrimary))+" */", ebnfPrimary
+	case *ast.NotExpr:
+		s, p = "/* !"+uncomment(ebnf(expr.Expr, ebnfPrimary))+" */", ebnfPrimary
+	case *ast.AndCodeExpr:
+		s, p = "/* &{…} */", ebnfPrimary
+	case *ast.NotCodeExpr:
+		s, p = "/* !{…} */", ebnfPrimary
+	case *ast.StateCodeExpr:
+		// no syntax
+		return ""
+	case *ast.RuleRefExpr:
+		s, p = expr.Name.Val, ebnfPrimary
+	case *ast.LitMatcher:
+		s, p = strconv.Quote(expr.Val), ebnfPrimary
+		if expr.IgnoreCase {
+			s += " /* ignore case */"
+		}
+	case *ast.CharClassMatcher:
+		s, p = charClass(expr)
+	case *ast.AnyMatcher:
+		s, p = "/* any character */", ebnfPrimary
+	case *ast.BackRefExpr:
+		s, p = "/* \\"+expr.Label.Val+" */", ebnfPrimary
+	case *ast.IndentExpr:
+		s, p = "/* %"+expr.Kind.String()+" */", ebnfPrimary
+	case *ast.ThrowExpr:
+		s, p = "/* %{"+expr.Label+"} */", ebnfPrimary
+	default:
+		panic(fmt.Sprintf("unknown expression type %T", expr))
+	}
+	if p < prec {
+		return "( " + s + " )"
+	}
+	return s
+}
+
+// uncomment removes the comment markers in s, to write it in a comment.
+func uncomment(s string) string {
+	return strings.NewReplacer("/* ", "", " */", "").Replace(s)
+}
+
+// charClass returns the class c in the W3C EBNF notation, e.g. [a-z],
+// or a comment if it has Unicode classes. The characters of the classes
+// that are not printable ASCII characters are written as #xN, so that the
+// classes do not contain spaces, unlike the options.
+func charClass(c *ast.CharClassMatcher) (string, int) {
+	if len(c.UnicodeClasses) > 0 {
+		return "/* " + c.Val + " */", ebnfPrimary
+	}
+	return ast.W3CClass(c), ebnfPrimary
+}
@@ -0,0 +1,389 @@
+package docgen
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/mna/pigeon/ast"
+)
+
+// layout of the railroad diagrams, in pixels.
+const (
+	arcRadius  = 10
+	boxHalf    = 12 // half of the height of a box
+	charWidth  = 9  // width of a character of the monospace font
+	boxPadding = 10 // horizontal padding of the text in a box
+	vertGap    = 10 // vertical space between the alternatives of a choice
+	groupPad   = 8  // padding of the dashed box of a predicate
+	labelSpace = 14 // space for the label of a predicate
+	margin     = 20
+)
+
+// style is the CSS of the railroad diagrams.
+const style = `svg.railroad path { stroke-width: 2; stroke: #333; fill: none; }
+svg.railroad rect { stroke-width: 2; stroke: #333; }
+svg.railroad rect.terminal { fill: #fefccf; }
+svg.railroad rect.nonterminal { fill: #e3f1fc; }
+svg.railroad rect.special { fill: #eee; }
+svg.railroad rect.group { fill: none; stroke: #999; stroke-width: 1; stroke-dasharray: 4 3; }
+svg.railroad text { font: 14px monospace; text-anchor: middle; }
+svg.railroad text.label { font-size: 12px; text-anchor: start; fill: #666; }
+svg.railroad text.title { font: bold 16px sans-serif; text-anchor: start; }
+svg.railroad a text { fill: #0645ad; text-decoration: underline; }
+`
+
+// dims is the size of a node of a diagram. The line enters the node on
+// the left and exits on the right at the baseline, up is the height above
+// the baseline and down the height below.
+type dims struct {
+	w, up, down int
+}
+
+type node interface {
+	size() dims
+	// render writes the node with the entry of its line at x, y.
+	render(s *svgWriter, x, y int)
+}
+
+type svgWriter struct {
+	bytes.Buffer
+}
+
+func (s *svgWriter) path(format string, args ...any) {
+	fmt.Fprintf(s, `<path d="`+format+`"/>`+"\n", args...)
+}
+
+// hline draws a horizontal line from x1 to x2 at y.
+func (s *svgWriter) hline(x1, x2, y int) {
+	if x1 != x2 {
+		s.path("M%d %dH%d", x1, y, x2)
+	}
+}
+
+// arc draws a quarter circle from x, y to x+dx, y+dy, clockwise if cw.
+func (s *svgWriter) arc(x, y, dx, dy int, cw bool) {
+	sweep := 0
+	if cw {
+		sweep = 1
+	}
+	s.path("M%d %dA%d %d 0 0 %d %d %d", x, y, arcRadius, arcRadius, sweep, x+dx, y+dy)
+}
+
+// vline draws a vertical line from y1 to y2 at x.
+func (s *svgWriter) vline(x, y1, y2 int) {
+	if y1 != y2 {
+		s.path("M%d %dV%d", x, y1, y2)
+	}
+}
+
+// box is a terminal, a reference to a rule or another leaf of a diagram.
+type box struct {
+	text  string
+	class string // terminal, nonterminal or special
+	href  string
+	title string
+}
+
+func (b *box) size() dims {
+	return dims{w: utf8.RuneCountInString(b.text)*charWidth + 2*boxPadding, up: boxHalf, down: boxHalf}
+}
+
+func (b *box) render(s *svgWriter, x, y int) {
+	w := b.size().w
+	rx := 0
+	if b.class == "terminal" {
+		rx = boxHalf
+	}
+	if b.href != "" {
+		fmt.Fprintf(s, `<a href="%s">`, html.EscapeString(b.href))
+	}
+	if b.title != "" {
+		fmt.Fprintf(s, `<title>%s</title>`, html.EscapeString(b.title))
+	}
+	fmt.Fprintf(s, `<rect class="%s" x="%d" y="%d" width="%d" height="%d" rx="%d"/>`,
+		b.class, x, y-boxHalf, w, 2*boxHalf, rx)
+	fmt.Fprintf(s, `<text x="%d" y="%d">%s</text>`, x+w/2, y+5, html.EscapeString(b.text))
+	if b.href != "" {
+		s.WriteString(`</a>`)
+	}
+	s.WriteString("\n")
+}
+
+// skip is an empty path, the alternative of an optional node.
+type skip struct{}
+
+func (skip) size() dims                    { return dims{} }
+func (skip) render(s *svgWriter, x, y int) {}
+
+type sequence struct {
+	items []node
+}
+
+func (q *sequence) size() dims {
+	var d dims
+	for i, it := range q.items {
+		sz := it.size()
+		if i > 0 {
+			d.w += arcRadius
+		}
+		d.w += sz.w
+		d.up = max(d.up, sz.up)
+		d.down = max(d.down, sz.down)
+	}
+	return d
+}
+
+func (q *sequence) render(s *svgWriter, x, y int) {
+	for i, it := range q.items {
+		if i > 0 {
+			s.hline(x, x+arcRadius, y)
+			x += arcRadius
+		}
+		it.render(s, x, y)
+		x += it.size().w
+	}
+}
+
+// choice stacks its alternatives, the first one on the baseline.
+type choice struct {
+	items []node
+}
+
+func (c *choice) size() dims {
+	var d dims
+	for i, it := range c.items {
+		sz := it.size()
+		d.w = max(d.w, sz.w)
+		if i == 0 {
+			d.up, d.down = sz.up, sz.down
+			continue
+		}
+		d.down += max(vertGap+sz.up, 2*arcRadius) + sz.down
+	}
+	d.w += 4 * arcRadius
+	return d
+}
+
+func (c *choice) render(s *svgWriter, x, y int) {
+	d := c.size()
+	xr := x + d.w
+	var down int
+	for i, it := range c.items {
+		sz := it.size()
+		if i == 0 {
+			s.hline(x, x+2*arcRadius, y)
+			it.render(s, x+2*arcRadius, y)
+			s.hline(x+2*arcRadius+sz.w, xr, y)
+			down = sz.down
+			continue
+		}
+
+		yi := y + down + max(vertGap+sz.up, 2*arcRadius)
+		s.arc(x, y, arcRadius, arcRadius, true)
+		s.vline(x+arcRadius, y+arcRadius, yi-arcRadius)
+		s.arc(x+arcRadius, yi-arcRadius, arcRadius, arcRadius, false)
+		it.render(s, x+2*arcRadius, yi)
+		s.hline(x+2*arcRadius+sz.w, xr-2*arcRadius, yi)
+		s.arc(xr-2*arcRadius, yi, arcRadius, -arcRadius, false)
+		s.vline(xr-arcRadius, yi-arcRadius, y+arcRadius)
+		s.arc(xr-arcRadius, y+arcRadius, arcRadius, -arcRadius, true)
+		down = yi - y + sz.down
+	}
+}
+
+// loop repeats its item, going back under it.
+type loop struct {
+	item node
+}
+
+func (l *loop) size() dims {
+	sz := l.item.size()
+	return dims{w: sz.w + 4*arcRadius, up: sz.up, down: max(sz.down+arcRadius, 2*arcRadius)}
+}
+
+func (l *loop) render(s *svgWriter, x, y int) {
+	d := l.size()
+	xs, xe := x+2*arcRadius, x+2*arcRadius+l.item.size().w
+	yl := y + d.down
+
+	s.hline(x, xs, y)
+	l.item.render(s, xs, y)
+	s.hline(xe, x+d.w, y)
+
+	s.arc(xe, y, arcRadius, arcRadius, true)
+	s.vline(xe+arcRadius, y+arcRadius, yl-arcRadius)
+	s.arc(xe+arcRadius, yl-arcRadius, -arcRadius, arcRadius, true)
+	s.hline(xe, xs, yl)
+	s.arc(xs, yl, -arcRadius, -arcRadius, true)
+	s.vline(xs-arcRadius, yl-arcRadius, y+arcRadius)
+	s.arc(xs-arcRadius, y+arcRadius, arcRadius, -arcRadius, true)
+}
+
+// group draws a dashed box with a label around its item, for the
+// predicates.
+type group struct {
+	label string
+	item  node
+}
+
+func (g *group) size() dims {
+	sz := g.item.size()
+	w := max(sz.w, utf8.RuneCountInString(g.label)*charWidth)
+	return dims{w: w + 2*groupPad, up: sz.up + groupPad + labelSpace, down: sz.down + groupPad}
+}
+
+func (g *group) render(s *svgWriter, x, y int) {
+	d := g.size()
+	sz := g.item.size()
+	fmt.Fprintf(s, `<rect class="group" x="%d" y="%d" width="%d" height="%d"/>`+"\n",
+		x, y-d.up, d.w, d.up+d.down)
+	fmt.Fprintf(s, `<text class="label" x="%d" y="%d">%s</text>`+"\n",
+		x+4, y-d.up+labelSpace-2, html.EscapeString(g.label))
+	xi := x + (d.w-sz.w)/2
+	s.hline(x, xi, y)
+	g.item.render(s, xi, y)
+	s.hline(xi+sz.w, x+d.w, y)
+}
+
+// diagramBuilder converts the expressions of a grammar to the nodes of
+// railroad diagrams.
+type diagramBuilder struct {
+	rules map[string]*ast.Rule
+}
+
+func newDiagramBuilder(g *ast.Grammar) *diagramBuilder {
+	b := &diagramBuilder{rules: make(map[string]*ast.Rule, len(g.Rules))}
+	for _, r := range g.Rules {
+		b.rules[r.Name.Val] = r
+	}
+	return b
+}
+
+func (b *diagramBuilder) node(expr ast.Expression) node {
+	switch expr := expr.(type) {
+	case *ast.ActionExpr:
+		return b.node(expr.Expr)
+	case *ast.AndCodeExpr:
+		return &box{text: "&{…}", class: "special", title: expr.Code.Val}
+	case *ast.AndExpr:
+		return &group{label: "followed by", item: b.node(expr.Expr)}
+	case *ast.AnyMatcher:
+		return &box{text: "any character", class: "special"}
+	case *ast.BackRefExpr:
+		return &box{text: "\\" + expr.Label.Val, class: "special", title: "text matched by " + expr.Label.Val}
+	case *ast.CharClassMatcher:
+		return &box{text: expr.Val, class: "terminal"}
+	case *ast.ChoiceExpr:
+		c := &choice{}
+		for _, alt := range expr.Alternatives {
+			c.items = append(c.items, b.node(alt))
+		}
+		return c
+	case *ast.IndentExpr:
+		return &box{text: "%" + expr.Kind.String(), class: "special"}
+	case *ast.LabeledExpr:
+		return b.node(expr.Expr)
+	case *ast.LitMatcher:
+		text := strconv.Quote(expr.Val)
+		if expr.IgnoreCase {
+			text += "i"
+		}
+		return &box{text: text, class: "terminal"}
+	case *ast.NotCodeExpr:
+		return &box{text: "!{…}", class: "special", title: expr.Code.Val}
+	case *ast.NotExpr:
+		return &group{label: "not followed by", item: b.node(expr.Expr)}
+	case *ast.OneOrMoreExpr:
+		return &loop{item: b.node(expr.Expr)}
+	case *ast.RecoveryExpr:
+		return b.node(expr.Expr)
+	case *ast.RuleRefExpr:
+		bx := &box{text: expr.Name.Val, class: "nonterminal"}
+		if r := b.rules[expr.Name.Val]; r != nil {
+			bx.href = "#" + anchor(r.Name.Val)
+			bx.title = displayName(r)
+		}
+		return bx
+	case *ast.SeqExpr:
+		q := &sequence{}
+		for _, e := range expr.Exprs {
+			q.items = append(q.items, b.node(e))
+		}
+		return q
+	case *ast.StateCodeExpr:
+		return &box{text: "#{…}", class: "special", title: expr.Code.Val}
+	case *ast.ThrowExpr:
+		return &box{text: "%{" + expr.Label + "}", class: "special", title: "throw " + expr.Label}
+	case *ast.ZeroOrMoreExpr:
+		return &choice{items: []node{&loop{item: b.node(expr.Expr)}, skip{}}}
+	case *ast.ZeroOrOneExpr:
+		return &choice{items: []node{b.node(expr.Expr), skip{}}}
+	default:
+		panic(fmt.Sprintf("unknown expression type %T", expr))
+	}
+}
+
+// diagram writes the railroad diagram of r as an svg element at x, y
+// of its parent, with the given id if it is not empty. It returns the
+// height and width of the diagram. If title is set, the name of the rule
+// is written above the diagram.
+func (b *diagramBuilder) diagram(s *svgWriter, r *ast.Rule, x, y int, id string, title bool) (int, int) {
+	n := b.node(r.Expr)
+	d := n.size()
+
+	top := margin
+	if title {
+		top += 2 * margin
+	}
+	w := d.w + 2*margin + 4*arcRadius
+	h := top + d.up + d.down + margin
+
+	s.WriteString(`<svg class="railroad"`)
+	if id != "" {
+		fmt.Fprintf(s, ` id="%s"`, id)
+	}
+	fmt.Fprintf(s, ` x="%d" y="%d" width="%d" height="%d" viewBox="0 0 %[3]d %[4]d" xmlns="http://www.w3.org/2000/svg">`+"\n",
+		x, y, w, h)
+	if title {
+		fmt.Fprintf(s, `<text class="title" x="%d" y="%d">%s</text>`+"\n",
+			margin, margin+4, html.EscapeString(displayName(r)))
+	}
+
+	// start and end markers
+	yb := top + d.up
+	s.path("M%d %dv%dM%d %dv%d", margin, yb-8, 16, margin+4, yb-8, 16)
+	s.hline(margin, margin+2*arcRadius, yb)
+	n.render(s, margin+2*arcRadius, yb)
+	xe := margin + 2*arcRadius + d.w
+	s.hline(xe, xe+2*arcRadius, yb)
+	s.path("M%d %dv%dM%d %dv%d", xe+2*arcRadius, yb-8, 16, xe+2*arcRadius-4, yb-8, 16)
+
+	s.WriteString("</svg>\n")
+	return h, w
+}
+
+// anchor returns the id of the element that documents the rule name.
+func anchor(name string) string {
+	return "rule-" + name
+}
+
+// displayName returns the name of r, followed by its display name if it
+// has one.
+func displayName(r *ast.Rule) string {
+	if r.DisplayName == nil || r.DisplayName.Val == "" {
+		return r.Name.Val
+	}
+	return r.Name.Val + " " + displayNameVal(r)
+}
+
+// displayNameVal returns the display name of r, in double quotes.
+func displayNameVal(r *ast.Rule) string {
+	val := r.DisplayName.Val
+	if s, err := strconv.Unquote(val); err == nil {
+		val = s
+	}
+	return strconv.Quote(val)
+}
@@ -542,9 +542,23 @@ func Statistics(stats *Stats, choiceNoMatch string) Option {
 // The default is false.
 func Debug(b bool) Option {
 	return func(p *parser) Option {
-		old := p.debug
-		p.debug = b
-		return Debug(old)
+		old := p.tracer
+		if b {
+			p.tracer = &debugTracer{p: p}
+		} else if _, ok := old.(*debugTracer); ok {
+			p.tracer = nil
+		}
+		return setTracer(old)
+	}
+}
+
+// setTracer creates an Option to set the tracer that receives the events
+// of the parse.
+func setTracer(t tracer) Option {
+	return func(p *parser) Option {
+		old := p.tracer
+		p.tracer = t
+		return setTracer(old)
 	}
 }
 
@@ -552,6 +566,9 @@ func Debug(b bool) Option {
 // the parser will cache all results so each expression is evaluated only
 // once. This guarantees linear parsing time even for pathological cases,
 // at the expense of more memory and slower times for typical cases.
+// The results of the rules with the @memoize annotation in the grammar
+// are cached even if b is false, without the cost of caching every
+// expression.
 //
 // The default is false.
 func Memoize(b bool) Option {
@@ -562,6 +579,23 @@ func Memoize(b bool) Option {
 	}
 }
 
+// MemoWindow creates an Option to bound the memory of the memoization
+// table: the results are kept only for the n offsets behind the furthest
+// offset where a result was cached, as the parser rarely backtracks
+// further. The results evicted from the window are evaluated again if
+// needed, the memory is O(n) instead of O(len(input)). The rules with
+// the @commit annotation in the grammar also discard the results before
+// the end of their match. If n is 0, all the results are kept.
+//
+// The default is 0.
+func MemoWindow(n int) Option {
+	return func(p *parser) Option {
+		old := p.memoWindow
+		p.memoWindow = n
+		return MemoWindow(old)
+	}
+}
+
 // AllowInvalidUTF8 creates an Option to allow invalid UTF-8 bytes.
 // Every invalid UTF-8 byte is treated as a utf8.RuneError (U+FFFD)
 // by character class matchers and is matched by the any matcher.
@@ -820,6 +854,11 @@ func (e *errList) dedupe() {
 	*e = cleaned
 }
 
+// Unwrap returns the errors of the list.
+func (e errList) Unwrap() []error {
+	return e
+}
+
 func (e errList) Error() string {
 	switch len(e) {
 	case 0:
@@ -911,7 +950,8 @@ type Stats struct {
 	// ChoiceAltCnt is used to count for each ordered choice expression,
 	// which alternative is used how may times.
 	// These numbers allow to optimize the order of the ordered choice expression
-	// to increase the performance of the parser
+	// to increase the performance of the parser, saved as JSON for the
+	// -choice-stats flag of pigeon.
 	//
 	// The outer key of ChoiceAltCnt is composed of the name of the rule as well
 	// as the line and the column of the ordered choice.
@@ -932,14 +972,17 @@ type parser struct {
 	data []byte
 	errs *errList
 
-	depth   int
 	recover bool
-	debug   bool
+	tracer  tracer
 
 	memoize bool
-	// memoization table for the packrat algorithm:
-	// map[offset in source] map[expression or rule] {value, match}
-	memo map[int]map[any]resultTuple
+	// memoization table for the packrat algorithm, the results of the
+	// expressions and the rules by offset in source, in a ring of
+	// memoWindow slots if set
+	memo       []memoSlot
+	memoWindow int
+	// the results before this offset are discarded by the commit points
+	memoFloor int
 
 	// rules table, maps the rule identifier to the rule node
 	rules map[string]*rule
@@ -1024,29 +1067,73 @@ func (p *parser) popRecovery() {
 	p.recoveryStack = p.recoveryStack[:len(p.recoveryStack)-1]
 }
 
-func (p *parser) print(prefix, s string) string {
-	if !p.debug {
-		return s
-	}
+func (p *parser) in(s string) string {
+	p.tracer.enterExpr(s, p.pt.position)
+	return s
+}
 
-	fmt.Printf("%s %d:%d:%d: %s [%#U]\n",
-		prefix, p.pt.line, p.pt.col, p.pt.offset, s, p.pt.rn)
+func (p *parser) out(s string) string {
+	p.tracer.exitExpr(s, p.pt.position)
 	return s
 }
 
-func (p *parser) printIndent(mark string, s string) string {
-	return p.print(strings.Repeat(" ", p.depth)+mark, s)
+// tracer receives the events of a parse, with the positions of the
+// parser.
+type tracer interface {
+	enterRule(rule string, pos position)
+	exitRule(rule string, matched bool, start, end position)
+	enterExpr(step string, pos position)
+	exitExpr(step string, pos position)
+	backtrack(from, to position)
+	memo(rule string, pos position, hit bool)
+	error(err error)
 }
 
-func (p *parser) in(s string) string {
-	res := p.printIndent(">", s)
-	p.depth++
-	return res
+// debugTracer is the tracer of the Debug option, it prints the events
+// to stdout, indented by the depth of the steps.
+type debugTracer struct {
+	p     *parser
+	depth int
 }
 
-func (p *parser) out(s string) string {
-	p.depth--
-	return p.printIndent("<", s)
+func (t *debugTracer) print(mark string, pos position, s string) {
+	fmt.Printf("%s%s %d:%d:%d: %s [%#U]\n",
+		strings.Repeat(" ", t.depth), mark, pos.line, pos.col, pos.offset, s, t.p.pt.rn)
+}
+
+func (t *debugTracer) enterRule(rule string, pos position) {
+	t.enterExpr("parseRule "+rule, pos)
+}
+
+func (t *debugTracer) exitRule(rule string, matched bool, start, end position) {
+	if matched {
+		t.print("MATCH", end, string(t.p.slice(start.offset, end.offset)))
+	}
+	t.exitExpr("parseRule "+rule, end)
+}
+
+func (t *debugTracer) enterExpr(step string, pos position) {
+	t.print(">", pos, step)
+	t.depth++
+}
+
+func (t *debugTracer) exitExpr(step string, pos position) {
+	t.depth--
+	t.print("<", pos, step)
+}
+
+func (t *debugTracer) backtrack(from, to position) {
+	t.print("BACKTRACK", to, fmt.Sprintf("from %d:%d:%d", from.line, from.col, from.offset))
+}
+
+func (t *debugTracer) memo(rule string, pos position, hit bool) {
+	if hit {
+		t.print("MEMO", pos, rule)
+	}
+}
+
+func (t *debugTracer) error(err error) {
+	t.print("ERROR", t.p.pt.position, err.Error())
 }
 
 func (p *parser) addErr(err error) {
@@ -1075,6 +1162,9 @@ func (p *parser) addErrAt(err error, pos position, expected []string) {
 	}
 	pe := &parserError{Inner: err, pos: pos, prefix: buf.String(), expected: expected}
 	p.errs.add(pe)
+	if p.tracer != nil {
+		p.tracer.error(pe)
+	}
 }
 
 func (p *parser) failAt(fail bool, pos position, want string) {
@@ -1099,7 +1189,7 @@ func (p *parser) failAt(fail bool, pos position, want string) {
 // read advances the parser to the next rune.
 func (p *parser) read() {
 	p.pt.offset += p.pt.w
-	rn, n := utf8.DecodeRune(p.data[p.pt.offset:])
+	rn, n, valid := p.decode(p.data[p.pt.offset:])
 	p.pt.rn = rn
 	p.pt.w = n
 	p.pt.col++
@@ -1108,21 +1198,27 @@ func (p *parser) read() {
 		p.pt.col = 0
 	}
 
-	if rn == utf8.RuneError && n == 1 { // see utf8.DecodeRune
+	if !valid {
 		if !p.allowInvalidUTF8 {
 			p.addErr(errInvalidEncoding)
 		}
 	}
 }
 
+// decode decodes the first character of b in the encoding of the input.
+func (p *parser) decode(b []byte) (rune, int, bool) {
+	rn, n := utf8.DecodeRune(b)
+	return rn, n, rn != utf8.RuneError || n != 1 // see utf8.DecodeRune
+}
+
 // restore parser position to the savepoint pt.
 func (p *parser) restore(pt savepoint) {
-	if p.debug {
-		defer p.out(p.in("restore"))
-	}
 	if pt.offset == p.pt.offset {
 		return
 	}
+	if p.tracer != nil && pt.offset < p.pt.offset {
+		p.tracer.backtrack(p.pt.position, pt.position)
+	}
 	p.pt = pt
 }
 
@@ -1150,7 +1246,7 @@ func (sd storeDict) Discard() {
 
 // clone and return parser current state.
 func (p *parser) cloneState() storeDict {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("cloneState"))
 	}
 
@@ -1168,7 +1264,7 @@ func (p *parser) cloneState() storeDict {
 // restore parser current state to the state storeDict.
 // every restoreState should applied only one time for every cloned state
 func (p *parser) restoreState(state storeDict) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("restoreState"))
 	}
 	p.cur.state.Discard()
@@ -1177,31 +1273,73 @@ func (p *parser) restoreState(state storeDict) {
 
 // get the slice of bytes from the savepoint start to the current position.
 func (p *parser) sliceFrom(start savepoint) []byte {
-	return p.data[start.position.offset:p.pt.position.offset]
+	return p.slice(start.position.offset, p.pt.position.offset)
+}
+
+// get the slice of bytes between the offsets start and end.
+func (p *parser) slice(start, end int) []byte {
+	return p.data[start:end]
+}
+
+// memoEntry is the result of an expression or a rule in the memoization
+// table. The few results at an offset are searched linearly.
+type memoEntry struct {
+	node any
+	res  resultTuple
+}
+
+// memoSlot holds the results at an offset of the memoization table, the
+// slots are reused for the offsets that are memoWindow apart.
+type memoSlot struct {
+	offset  int
+	entries []memoEntry
 }
 
 func (p *parser) getMemoized(node any) (resultTuple, bool) {
-	if len(p.memo) == 0 {
+	if len(p.memo) == 0 || p.pt.offset < p.memoFloor {
 		return resultTuple{}, false
 	}
-	m := p.memo[p.pt.offset]
-	if len(m) == 0 {
+	slot := &p.memo[p.pt.offset%len(p.memo)]
+	if slot.offset != p.pt.offset {
 		return resultTuple{}, false
 	}
-	res, ok := m[node]
-	return res, ok
+	for _, e := range slot.entries {
+		if e.node == node {
+			return e.res, true
+		}
+	}
+	return resultTuple{}, false
 }
 
 func (p *parser) setMemoized(pt savepoint, node any, tuple resultTuple) {
 	if p.memo == nil {
-		p.memo = make(map[int]map[any]resultTuple)
+		n := len(p.data) + 1
+		if p.memoWindow > 0 && p.memoWindow < n {
+			n = p.memoWindow
+		}
+		p.memo = make([]memoSlot, n)
 	}
-	m := p.memo[pt.offset]
-	if m == nil {
-		m = make(map[any]resultTuple)
-		p.memo[pt.offset] = m
+	if pt.offset < p.memoFloor {
+		return
+	}
+	slot := &p.memo[pt.offset%len(p.memo)]
+	if slot.offset != pt.offset {
+		if slot.offset > pt.offset {
+			// out of the window
+			return
+		}
+		for i := range slot.entries {
+			slot.entries[i] = memoEntry{}
+		}
+		slot.offset, slot.entries = pt.offset, slot.entries[:0]
+	}
+	for i := range slot.entries {
+		if slot.entries[i].node == node {
+			slot.entries[i].res = tuple
+			return
+		}
 	}
-	m[node] = tuple
+	slot.entries = append(slot.entries, memoEntry{node: node, res: tuple})
 }
 
 func (p *parser) buildRulesTable(g *grammar) {
@@ -1226,7 +1364,7 @@ func (p *parser) parse(g *grammar) (val any, err error) {
 		// and return the panic as an error.
 		defer func() {
 			if e := recover(); e != nil {
-				if p.debug {
+				if p.tracer != nil {
 					defer p.out(p.in("panic handler"))
 				}
 				val = nil
@@ -1289,8 +1427,17 @@ func listJoin(list []string, sep string, lastSep string) string {
 	}
 }
 
+// memoizeRule returns true if the results of the rule are memoized, with
+// the Memoize option or with the @memoize annotation of the rule.
+func (p *parser) memoizeRule(rule *rule) bool {
+	return p.memoize
+}
+
 func (p *parser) parseRuleMemoize(rule *rule) (any, bool) {
 	res, ok := p.getMemoized(rule)
+	if p.tracer != nil {
+		p.tracer.memo(rule.name, p.pt.position, ok)
+	}
 	if ok {
 		p.restore(res.end)
 		return res.v, res.b
@@ -1304,8 +1451,8 @@ func (p *parser) parseRuleMemoize(rule *rule) (any, bool) {
 }
 
 func (p *parser) parseRuleWrap(rule *rule) (any, bool) {
-	if p.debug {
-		defer p.out(p.in("parseRule " + rule.name))
+	if p.tracer != nil {
+		p.tracer.enterRule(rule.name, p.pt.position)
 	}
 	var (
 		val       any
@@ -1313,14 +1460,14 @@ func (p *parser) parseRuleWrap(rule *rule) (any, bool) {
 		startMark = p.pt
 	)
 
-	if p.memoize {
+	if p.memoizeRule(rule) {
 		val, ok = p.parseRuleMemoize(rule)
 	} else {
 		val, ok = p.parseRule(rule)
 	}
 
-	if ok && p.debug {
-		p.printIndent("MATCH", string(p.sliceFrom(startMark)))
+	if p.tracer != nil {
+		p.tracer.exitRule(rule.name, ok, startMark.position, p.pt.position)
 	}
 	return val, ok
 }
@@ -1355,14 +1502,12 @@ func (p *parser) parseExprWrap(expr any) (any, bool) {
 }
 
 // nolint: gocyclo
-func (p *parser) parseExpr(expr any) (any, bool) {
+func (p *parser) parseExpr(expr any) (val any, ok bool) {
 	p.ExprCnt++
 	if p.ExprCnt > p.maxExprCnt {
 		panic(errMaxExprCnt)
 	}
 
-	var val any
-	var ok bool
 	switch expr := expr.(type) {
 	case *actionExpr:
 		val, ok = p.parseActionExpr(expr)
@@ -1407,7 +1552,7 @@ func (p *parser) parseExpr(expr any) (any, bool) {
 }
 
 func (p *parser) parseActionExpr(act *actionExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseActionExpr"))
 	}
 
@@ -1425,14 +1570,11 @@ func (p *parser) parseActionExpr(act *actionExpr) (any, bool) {
 
 		val = actVal
 	}
-	if ok && p.debug {
-		p.printIndent("MATCH", string(p.sliceFrom(start)))
-	}
 	return val, ok
 }
 
 func (p *parser) parseAndCodeExpr(and *andCodeExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseAndCodeExpr"))
 	}
 
@@ -1448,23 +1590,21 @@ func (p *parser) parseAndCodeExpr(and *andCodeExpr) (any, bool) {
 }
 
 func (p *parser) parseAndExpr(and *andExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseAndExpr"))
 	}
 
 	pt := p.pt
-	state := p.cloneState()
 	p.pushV()
 	_, ok := p.parseExprWrap(and.expr)
 	p.popV()
-	p.restoreState(state)
 	p.restore(pt)
 
 	return nil, ok
 }
 
 func (p *parser) parseAnyMatcher(any *anyMatcher) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseAnyMatcher"))
 	}
 
@@ -1481,7 +1621,7 @@ func (p *parser) parseAnyMatcher(any *anyMatcher) (any, bool) {
 
 // nolint: gocyclo
 func (p *parser) parseCharClassMatcher(chr *charClassMatcher) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseCharClassMatcher"))
 	}
 
@@ -1562,7 +1702,7 @@ func (p *parser) incChoiceAltCnt(ch *choiceExpr, altI int) {
 }
 
 func (p *parser) parseChoiceExpr(ch *choiceExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseChoiceExpr"))
 	}
 
@@ -1570,8 +1710,6 @@ func (p *parser) parseChoiceExpr(ch *choiceExpr) (any, bool) {
 		// dummy assignment to prevent compile error if optimized
 		_ = altI
 
-		state := p.cloneState()
-
 		p.pushV()
 		val, ok := p.parseExprWrap(alt)
 		p.popV()
@@ -1579,14 +1717,13 @@ func (p *parser) parseChoiceExpr(ch *choiceExpr) (any, bool) {
 			p.incChoiceAltCnt(ch, altI)
 			return val, ok
 		}
-		p.restoreState(state)
 	}
 	p.incChoiceAltCnt(ch, choiceNoMatch)
 	return nil, false
 }
 
 func (p *parser) parseLabeledExpr(lab *labeledExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseLabeledExpr"))
 	}
 
@@ -1601,7 +1738,7 @@ func (p *parser) parseLabeledExpr(lab *labeledExpr) (any, bool) {
 }
 
 func (p *parser) parseLitMatcher(lit *litMatcher) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseLitMatcher"))
 	}
 
@@ -1623,7 +1760,7 @@ func (p *parser) parseLitMatcher(lit *litMatcher) (any, bool) {
 }
 
 func (p *parser) parseNotCodeExpr(not *notCodeExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseNotCodeExpr"))
 	}
 
@@ -1639,25 +1776,23 @@ func (p *parser) parseNotCodeExpr(not *notCodeExpr) (any, bool) {
 }
 
 func (p *parser) parseNotExpr(not *notExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseNotExpr"))
 	}
 
 	pt := p.pt
-	state := p.cloneState()
 	p.pushV()
 	p.maxFailInvertExpected = !p.maxFailInvertExpected
 	_, ok := p.parseExprWrap(not.expr)
 	p.maxFailInvertExpected = !p.maxFailInvertExpected
 	p.popV()
-	p.restoreState(state)
 	p.restore(pt)
 
 	return nil, !ok
 }
 
 func (p *parser) parseOneOrMoreExpr(expr *oneOrMoreExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseOneOrMoreExpr"))
 	}
 
@@ -1679,7 +1814,7 @@ func (p *parser) parseOneOrMoreExpr(expr *oneOrMoreExpr) (any, bool) {
 }
 
 func (p *parser) parseRecoveryExpr(recover *recoveryExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseRecoveryExpr (" + strings.Join(recover.failureLabel, ",") + ")"))
 	}
 
@@ -1691,7 +1826,7 @@ func (p *parser) parseRecoveryExpr(recover *recoveryExpr) (any, bool) {
 }
 
 func (p *parser) parseRuleRefExpr(ref *ruleRefExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseRuleRefExpr " + ref.name))
 	}
 
@@ -1708,18 +1843,16 @@ func (p *parser) parseRuleRefExpr(ref *ruleRefExpr) (any, bool) {
 }
 
 func (p *parser) parseSeqExpr(seq *seqExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseSeqExpr"))
 	}
 
 	vals := make([]any, 0, len(seq.exprs))
 
 	pt := p.pt
-	state := p.cloneState()
 	for _, expr := range seq.exprs {
 		val, ok := p.parseExprWrap(expr)
 		if !ok {
-			p.restoreState(state)
 			p.restore(pt)
 			return nil, false
 		}
@@ -1729,7 +1862,7 @@ func (p *parser) parseSeqExpr(seq *seqExpr) (any, bool) {
 }
 
 func (p *parser) parseStateCodeExpr(state *stateCodeExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseStateCodeExpr"))
 	}
 
@@ -1741,7 +1874,7 @@ func (p *parser) parseStateCodeExpr(state *stateCodeExpr) (any, bool) {
 }
 
 func (p *parser) parseThrowExpr(expr *throwExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseThrowExpr"))
 	}
 
@@ -1757,7 +1890,7 @@ func (p *parser) parseThrowExpr(expr *throwExpr) (any, bool) {
 }
 
 func (p *parser) parseZeroOrMoreExpr(expr *zeroOrMoreExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseZeroOrMoreExpr"))
 	}
 
@@ -1775,7 +1908,7 @@ func (p *parser) parseZeroOrMoreExpr(expr *zeroOrMoreExpr) (any, bool) {
 }
 
 func (p *parser) parseZeroOrOneExpr(expr *zeroOrOneExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseZeroOrOneExpr"))
 	}
 
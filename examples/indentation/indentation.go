@@ -17,46 +17,40 @@ import (
 	"unicode/utf8"
 )
 
-func toAnySlice(v any) []any {
-	if v == nil {
-		return nil
-	}
-	return v.([]any)
-}
-
 var g = &grammar{
 	rules: []*rule{
 		{
 			name: "Input",
-			pos:  position{line: 13, col: 1, offset: 117},
+			pos:  position{line: 5, col: 1, offset: 18},
 			expr: &actionExpr{
-				pos: position{line: 13, col: 15, offset: 133},
+				pos: position{line: 5, col: 15, offset: 34},
 				run: (*parser).callonInput1,
 				expr: &seqExpr{
-					pos: position{line: 13, col: 15, offset: 133},
+					pos: position{line: 5, col: 15, offset: 34},
 					exprs: []any{
-						&stateCodeExpr{
-							pos: position{line: 13, col: 15, offset: 133},
-							run: (*parser).callonInput3,
-						},
 						&labeledExpr{
-							pos:   position{line: 13, col: 59, offset: 177},
+							pos:   position{line: 5, col: 15, offset: 34},
 							label: "s",
 							expr: &ruleRefExpr{
-								pos:  position{line: 13, col: 61, offset: 179},
+								pos:  position{line: 5, col: 17, offset: 36},
 								name: "Statements",
 							},
 						},
+						&indentExpr{
+							pos:  position{line: 5, col: 28, offset: 47},
+							op:   samedentOp,
+							want: "%SAMEDENT",
+						},
 						&labeledExpr{
-							pos:   position{line: 13, col: 73, offset: 191},
+							pos:   position{line: 5, col: 38, offset: 57},
 							label: "r",
 							expr: &ruleRefExpr{
-								pos:  position{line: 13, col: 75, offset: 193},
+								pos:  position{line: 5, col: 40, offset: 59},
 								name: "ReturnOp",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 13, col: 84, offset: 202},
+							pos:  position{line: 5, col: 49, offset: 68},
 							name: "EOF",
 						},
 					},
@@ -65,17 +59,17 @@ var g = &grammar{
 		},
 		{
 			name: "Statements",
-			pos:  position{line: 15, col: 1, offset: 312},
+			pos:  position{line: 7, col: 1, offset: 177},
 			expr: &actionExpr{
-				pos: position{line: 15, col: 15, offset: 328},
+				pos: position{line: 7, col: 15, offset: 193},
 				run: (*parser).callonStatements1,
 				expr: &labeledExpr{
-					pos:   position{line: 15, col: 15, offset: 328},
+					pos:   position{line: 7, col: 15, offset: 193},
 					label: "s",
 					expr: &oneOrMoreExpr{
-						pos: position{line: 15, col: 17, offset: 330},
+						pos: position{line: 7, col: 17, offset: 195},
 						expr: &ruleRefExpr{
-							pos:  position{line: 15, col: 17, offset: 330},
+							pos:  position{line: 7, col: 17, offset: 195},
 							name: "Line",
 						},
 					},
@@ -84,22 +78,23 @@ var g = &grammar{
 		},
 		{
 			name: "Line",
-			pos:  position{line: 16, col: 1, offset: 389},
+			pos:  position{line: 8, col: 1, offset: 254},
 			expr: &actionExpr{
-				pos: position{line: 16, col: 15, offset: 405},
+				pos: position{line: 8, col: 15, offset: 270},
 				run: (*parser).callonLine1,
 				expr: &seqExpr{
-					pos: position{line: 16, col: 15, offset: 405},
+					pos: position{line: 8, col: 15, offset: 270},
 					exprs: []any{
-						&ruleRefExpr{
-							pos:  position{line: 16, col: 15, offset: 405},
-							name: "INDENTATION",
+						&indentExpr{
+							pos:  position{line: 8, col: 15, offset: 270},
+							op:   samedentOp,
+							want: "%SAMEDENT",
 						},
 						&labeledExpr{
-							pos:   position{line: 16, col: 27, offset: 417},
+							pos:   position{line: 8, col: 25, offset: 280},
 							label: "s",
 							expr: &ruleRefExpr{
-								pos:  position{line: 16, col: 29, offset: 419},
+								pos:  position{line: 8, col: 27, offset: 282},
 								name: "Statement",
 							},
 						},
@@ -109,33 +104,33 @@ var g = &grammar{
 		},
 		{
 			name: "ReturnOp",
-			pos:  position{line: 17, col: 1, offset: 452},
+			pos:  position{line: 9, col: 1, offset: 317},
 			expr: &actionExpr{
-				pos: position{line: 17, col: 15, offset: 468},
+				pos: position{line: 9, col: 15, offset: 333},
 				run: (*parser).callonReturnOp1,
 				expr: &seqExpr{
-					pos: position{line: 17, col: 15, offset: 468},
+					pos: position{line: 9, col: 15, offset: 333},
 					exprs: []any{
 						&litMatcher{
-							pos:        position{line: 17, col: 15, offset: 468},
+							pos:        position{line: 9, col: 15, offset: 333},
 							val:        "return",
 							ignoreCase: false,
 							want:       "\"return\"",
 						},
 						&ruleRefExpr{
-							pos:  position{line: 17, col: 24, offset: 477},
+							pos:  position{line: 9, col: 24, offset: 342},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 17, col: 26, offset: 479},
+							pos:   position{line: 9, col: 26, offset: 344},
 							label: "arg",
 							expr: &ruleRefExpr{
-								pos:  position{line: 17, col: 30, offset: 483},
+								pos:  position{line: 9, col: 30, offset: 348},
 								name: "Identifier",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 17, col: 41, offset: 494},
+							pos:  position{line: 9, col: 41, offset: 359},
 							name: "EOL",
 						},
 					},
@@ -144,87 +139,89 @@ var g = &grammar{
 		},
 		{
 			name: "Statement",
-			pos:  position{line: 19, col: 1, offset: 545},
+			pos:  position{line: 11, col: 1, offset: 410},
 			expr: &choiceExpr{
-				pos: position{line: 19, col: 15, offset: 561},
+				pos: position{line: 11, col: 15, offset: 426},
 				alternatives: []any{
 					&actionExpr{
-						pos: position{line: 19, col: 15, offset: 561},
+						pos: position{line: 11, col: 15, offset: 426},
 						run: (*parser).callonStatement2,
 						expr: &seqExpr{
-							pos: position{line: 19, col: 15, offset: 561},
+							pos: position{line: 11, col: 15, offset: 426},
 							exprs: []any{
 								&labeledExpr{
-									pos:   position{line: 19, col: 15, offset: 561},
+									pos:   position{line: 11, col: 15, offset: 426},
 									label: "s",
 									expr: &ruleRefExpr{
-										pos:  position{line: 19, col: 17, offset: 563},
+										pos:  position{line: 11, col: 17, offset: 428},
 										name: "Assignment",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 19, col: 28, offset: 574},
+									pos:  position{line: 11, col: 28, offset: 439},
 									name: "EOL",
 								},
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 20, col: 7, offset: 631},
+						pos: position{line: 12, col: 7, offset: 496},
 						run: (*parser).callonStatement7,
 						expr: &seqExpr{
-							pos: position{line: 20, col: 7, offset: 631},
+							pos: position{line: 12, col: 7, offset: 496},
 							exprs: []any{
 								&litMatcher{
-									pos:        position{line: 20, col: 7, offset: 631},
+									pos:        position{line: 12, col: 7, offset: 496},
 									val:        "if",
 									ignoreCase: false,
 									want:       "\"if\"",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 20, col: 12, offset: 636},
+									pos:  position{line: 12, col: 12, offset: 501},
 									name: "_",
 								},
 								&labeledExpr{
-									pos:   position{line: 20, col: 14, offset: 638},
+									pos:   position{line: 12, col: 14, offset: 503},
 									label: "arg",
 									expr: &ruleRefExpr{
-										pos:  position{line: 20, col: 18, offset: 642},
+										pos:  position{line: 12, col: 18, offset: 507},
 										name: "LogicalExpression",
 									},
 								},
 								&zeroOrOneExpr{
-									pos: position{line: 20, col: 36, offset: 660},
+									pos: position{line: 12, col: 36, offset: 525},
 									expr: &ruleRefExpr{
-										pos:  position{line: 20, col: 36, offset: 660},
+										pos:  position{line: 12, col: 36, offset: 525},
 										name: "_",
 									},
 								},
 								&litMatcher{
-									pos:        position{line: 20, col: 39, offset: 663},
+									pos:        position{line: 12, col: 39, offset: 528},
 									val:        ":",
 									ignoreCase: false,
 									want:       "\":\"",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 20, col: 43, offset: 667},
+									pos:  position{line: 12, col: 43, offset: 532},
 									name: "EOL",
 								},
-								&ruleRefExpr{
-									pos:  position{line: 20, col: 47, offset: 671},
-									name: "INDENT",
+								&indentExpr{
+									pos:  position{line: 12, col: 47, offset: 536},
+									op:   indentOp,
+									want: "%INDENT",
 								},
 								&labeledExpr{
-									pos:   position{line: 20, col: 54, offset: 678},
+									pos:   position{line: 12, col: 55, offset: 544},
 									label: "s",
 									expr: &ruleRefExpr{
-										pos:  position{line: 20, col: 56, offset: 680},
+										pos:  position{line: 12, col: 57, offset: 546},
 										name: "Statements",
 									},
 								},
-								&ruleRefExpr{
-									pos:  position{line: 20, col: 67, offset: 691},
-									name: "DEDENT",
+								&indentExpr{
+									pos:  position{line: 12, col: 68, offset: 557},
+									op:   dedentOp,
+									want: "%DEDENT",
 								},
 							},
 						},
@@ -234,46 +231,46 @@ var g = &grammar{
 		},
 		{
 			name: "Assignment",
-			pos:  position{line: 24, col: 1, offset: 818},
+			pos:  position{line: 16, col: 1, offset: 684},
 			expr: &actionExpr{
-				pos: position{line: 24, col: 14, offset: 833},
+				pos: position{line: 16, col: 14, offset: 699},
 				run: (*parser).callonAssignment1,
 				expr: &seqExpr{
-					pos: position{line: 24, col: 14, offset: 833},
+					pos: position{line: 16, col: 14, offset: 699},
 					exprs: []any{
 						&labeledExpr{
-							pos:   position{line: 24, col: 14, offset: 833},
+							pos:   position{line: 16, col: 14, offset: 699},
 							label: "lvalue",
 							expr: &ruleRefExpr{
-								pos:  position{line: 24, col: 21, offset: 840},
+								pos:  position{line: 16, col: 21, offset: 706},
 								name: "Identifier",
 							},
 						},
 						&zeroOrOneExpr{
-							pos: position{line: 24, col: 32, offset: 851},
+							pos: position{line: 16, col: 32, offset: 717},
 							expr: &ruleRefExpr{
-								pos:  position{line: 24, col: 32, offset: 851},
+								pos:  position{line: 16, col: 32, offset: 717},
 								name: "_",
 							},
 						},
 						&litMatcher{
-							pos:        position{line: 24, col: 35, offset: 854},
+							pos:        position{line: 16, col: 35, offset: 720},
 							val:        "=",
 							ignoreCase: false,
 							want:       "\"=\"",
 						},
 						&zeroOrOneExpr{
-							pos: position{line: 24, col: 39, offset: 858},
+							pos: position{line: 16, col: 39, offset: 724},
 							expr: &ruleRefExpr{
-								pos:  position{line: 24, col: 39, offset: 858},
+								pos:  position{line: 16, col: 39, offset: 724},
 								name: "_",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 24, col: 42, offset: 861},
+							pos:   position{line: 16, col: 42, offset: 727},
 							label: "rvalue",
 							expr: &ruleRefExpr{
-								pos:  position{line: 24, col: 49, offset: 868},
+								pos:  position{line: 16, col: 49, offset: 734},
 								name: "AdditiveExpression",
 							},
 						},
@@ -283,15 +280,15 @@ var g = &grammar{
 		},
 		{
 			name: "LogicalExpression",
-			pos:  position{line: 27, col: 1, offset: 1018},
+			pos:  position{line: 19, col: 1, offset: 884},
 			expr: &actionExpr{
-				pos: position{line: 27, col: 23, offset: 1042},
+				pos: position{line: 19, col: 23, offset: 908},
 				run: (*parser).callonLogicalExpression1,
 				expr: &labeledExpr{
-					pos:   position{line: 27, col: 23, offset: 1042},
+					pos:   position{line: 19, col: 23, offset: 908},
 					label: "arg",
 					expr: &ruleRefExpr{
-						pos:  position{line: 27, col: 27, offset: 1046},
+						pos:  position{line: 19, col: 27, offset: 912},
 						name: "PrimaryExpression",
 					},
 				},
@@ -299,43 +296,43 @@ var g = &grammar{
 		},
 		{
 			name: "AdditiveExpression",
-			pos:  position{line: 28, col: 1, offset: 1129},
+			pos:  position{line: 20, col: 1, offset: 995},
 			expr: &actionExpr{
-				pos: position{line: 28, col: 23, offset: 1153},
+				pos: position{line: 20, col: 23, offset: 1019},
 				run: (*parser).callonAdditiveExpression1,
 				expr: &seqExpr{
-					pos: position{line: 28, col: 23, offset: 1153},
+					pos: position{line: 20, col: 23, offset: 1019},
 					exprs: []any{
 						&labeledExpr{
-							pos:   position{line: 28, col: 23, offset: 1153},
+							pos:   position{line: 20, col: 23, offset: 1019},
 							label: "arg",
 							expr: &ruleRefExpr{
-								pos:  position{line: 28, col: 27, offset: 1157},
+								pos:  position{line: 20, col: 27, offset: 1023},
 								name: "PrimaryExpression",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 28, col: 45, offset: 1175},
+							pos:   position{line: 20, col: 45, offset: 1041},
 							label: "rest",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 28, col: 50, offset: 1180},
+								pos: position{line: 20, col: 50, offset: 1046},
 								expr: &seqExpr{
-									pos: position{line: 28, col: 52, offset: 1182},
+									pos: position{line: 20, col: 52, offset: 1048},
 									exprs: []any{
 										&ruleRefExpr{
-											pos:  position{line: 28, col: 52, offset: 1182},
+											pos:  position{line: 20, col: 52, offset: 1048},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 28, col: 54, offset: 1184},
+											pos:  position{line: 20, col: 54, offset: 1050},
 											name: "AddOp",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 28, col: 60, offset: 1190},
+											pos:  position{line: 20, col: 60, offset: 1056},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 28, col: 62, offset: 1192},
+											pos:  position{line: 20, col: 62, offset: 1058},
 											name: "PrimaryExpression",
 										},
 									},
@@ -348,22 +345,22 @@ var g = &grammar{
 		},
 		{
 			name: "PrimaryExpression",
-			pos:  position{line: 30, col: 1, offset: 1328},
+			pos:  position{line: 22, col: 1, offset: 1194},
 			expr: &actionExpr{
-				pos: position{line: 30, col: 23, offset: 1352},
+				pos: position{line: 22, col: 23, offset: 1218},
 				run: (*parser).callonPrimaryExpression1,
 				expr: &labeledExpr{
-					pos:   position{line: 30, col: 23, offset: 1352},
+					pos:   position{line: 22, col: 23, offset: 1218},
 					label: "arg",
 					expr: &choiceExpr{
-						pos: position{line: 30, col: 28, offset: 1357},
+						pos: position{line: 22, col: 28, offset: 1223},
 						alternatives: []any{
 							&ruleRefExpr{
-								pos:  position{line: 30, col: 28, offset: 1357},
+								pos:  position{line: 22, col: 28, offset: 1223},
 								name: "Integer",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 30, col: 38, offset: 1367},
+								pos:  position{line: 22, col: 38, offset: 1233},
 								name: "Identifier",
 							},
 						},
@@ -373,14 +370,14 @@ var g = &grammar{
 		},
 		{
 			name: "Integer",
-			pos:  position{line: 33, col: 1, offset: 1466},
+			pos:  position{line: 25, col: 1, offset: 1332},
 			expr: &actionExpr{
-				pos: position{line: 33, col: 11, offset: 1478},
+				pos: position{line: 25, col: 11, offset: 1344},
 				run: (*parser).callonInteger1,
 				expr: &oneOrMoreExpr{
-					pos: position{line: 33, col: 11, offset: 1478},
+					pos: position{line: 25, col: 11, offset: 1344},
 					expr: &charClassMatcher{
-						pos:        position{line: 33, col: 11, offset: 1478},
+						pos:        position{line: 25, col: 11, offset: 1344},
 						val:        "[0-9]",
 						ranges:     []rune{'0', '9'},
 						ignoreCase: false,
@@ -391,24 +388,24 @@ var g = &grammar{
 		},
 		{
 			name: "Identifier",
-			pos:  position{line: 34, col: 1, offset: 1554},
+			pos:  position{line: 26, col: 1, offset: 1420},
 			expr: &actionExpr{
-				pos: position{line: 34, col: 14, offset: 1569},
+				pos: position{line: 26, col: 14, offset: 1435},
 				run: (*parser).callonIdentifier1,
 				expr: &seqExpr{
-					pos: position{line: 34, col: 14, offset: 1569},
+					pos: position{line: 26, col: 14, offset: 1435},
 					exprs: []any{
 						&charClassMatcher{
-							pos:        position{line: 34, col: 14, offset: 1569},
+							pos:        position{line: 26, col: 14, offset: 1435},
 							val:        "[a-zA-Z]",
 							ranges:     []rune{'a', 'z', 'A', 'Z'},
 							ignoreCase: false,
 							inverted:   false,
 						},
 						&zeroOrMoreExpr{
-							pos: position{line: 34, col: 23, offset: 1578},
+							pos: position{line: 26, col: 23, offset: 1444},
 							expr: &charClassMatcher{
-								pos:        position{line: 34, col: 23, offset: 1578},
+								pos:        position{line: 26, col: 23, offset: 1444},
 								val:        "[a-zA-Z0-9]",
 								ranges:     []rune{'a', 'z', 'A', 'Z', '0', '9'},
 								ignoreCase: false,
@@ -421,21 +418,21 @@ var g = &grammar{
 		},
 		{
 			name: "AddOp",
-			pos:  position{line: 36, col: 1, offset: 1646},
+			pos:  position{line: 28, col: 1, offset: 1512},
 			expr: &actionExpr{
-				pos: position{line: 36, col: 9, offset: 1656},
+				pos: position{line: 28, col: 9, offset: 1522},
 				run: (*parser).callonAddOp1,
 				expr: &choiceExpr{
-					pos: position{line: 36, col: 11, offset: 1658},
+					pos: position{line: 28, col: 11, offset: 1524},
 					alternatives: []any{
 						&litMatcher{
-							pos:        position{line: 36, col: 11, offset: 1658},
+							pos:        position{line: 28, col: 11, offset: 1524},
 							val:        "+",
 							ignoreCase: false,
 							want:       "\"+\"",
 						},
 						&litMatcher{
-							pos:        position{line: 36, col: 17, offset: 1664},
+							pos:        position{line: 28, col: 17, offset: 1530},
 							val:        "-",
 							ignoreCase: false,
 							want:       "\"-\"",
@@ -446,11 +443,11 @@ var g = &grammar{
 		},
 		{
 			name: "_",
-			pos:  position{line: 38, col: 1, offset: 1723},
+			pos:  position{line: 30, col: 1, offset: 1589},
 			expr: &oneOrMoreExpr{
-				pos: position{line: 38, col: 5, offset: 1729},
+				pos: position{line: 30, col: 5, offset: 1595},
 				expr: &charClassMatcher{
-					pos:        position{line: 38, col: 5, offset: 1729},
+					pos:        position{line: 30, col: 5, offset: 1595},
 					val:        "[ \\t]",
 					chars:      []rune{' ', '\t'},
 					ignoreCase: false,
@@ -460,53 +457,53 @@ var g = &grammar{
 		},
 		{
 			name: "EOL",
-			pos:  position{line: 40, col: 1, offset: 1737},
+			pos:  position{line: 32, col: 1, offset: 1603},
 			expr: &seqExpr{
-				pos: position{line: 40, col: 7, offset: 1745},
+				pos: position{line: 32, col: 7, offset: 1611},
 				exprs: []any{
 					&zeroOrOneExpr{
-						pos: position{line: 40, col: 7, offset: 1745},
+						pos: position{line: 32, col: 7, offset: 1611},
 						expr: &ruleRefExpr{
-							pos:  position{line: 40, col: 7, offset: 1745},
+							pos:  position{line: 32, col: 7, offset: 1611},
 							name: "_",
 						},
 					},
 					&zeroOrOneExpr{
-						pos: position{line: 40, col: 10, offset: 1748},
+						pos: position{line: 32, col: 10, offset: 1614},
 						expr: &ruleRefExpr{
-							pos:  position{line: 40, col: 10, offset: 1748},
+							pos:  position{line: 32, col: 10, offset: 1614},
 							name: "Comment",
 						},
 					},
 					&choiceExpr{
-						pos: position{line: 40, col: 20, offset: 1758},
+						pos: position{line: 32, col: 20, offset: 1624},
 						alternatives: []any{
 							&litMatcher{
-								pos:        position{line: 40, col: 20, offset: 1758},
+								pos:        position{line: 32, col: 20, offset: 1624},
 								val:        "\r\n",
 								ignoreCase: false,
 								want:       "\"\\r\\n\"",
 							},
 							&litMatcher{
-								pos:        position{line: 40, col: 29, offset: 1767},
+								pos:        position{line: 32, col: 29, offset: 1633},
 								val:        "\n\r",
 								ignoreCase: false,
 								want:       "\"\\n\\r\"",
 							},
 							&litMatcher{
-								pos:        position{line: 40, col: 38, offset: 1776},
+								pos:        position{line: 32, col: 38, offset: 1642},
 								val:        "\r",
 								ignoreCase: false,
 								want:       "\"\\r\"",
 							},
 							&litMatcher{
-								pos:        position{line: 40, col: 45, offset: 1783},
+								pos:        position{line: 32, col: 45, offset: 1649},
 								val:        "\n",
 								ignoreCase: false,
 								want:       "\"\\n\"",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 40, col: 52, offset: 1790},
+								pos:  position{line: 32, col: 52, offset: 1656},
 								name: "EOF",
 							},
 						},
@@ -516,20 +513,20 @@ var g = &grammar{
 		},
 		{
 			name: "Comment",
-			pos:  position{line: 42, col: 1, offset: 1796},
+			pos:  position{line: 34, col: 1, offset: 1662},
 			expr: &seqExpr{
-				pos: position{line: 42, col: 11, offset: 1808},
+				pos: position{line: 34, col: 11, offset: 1674},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 42, col: 11, offset: 1808},
+						pos:        position{line: 34, col: 11, offset: 1674},
 						val:        "//",
 						ignoreCase: false,
 						want:       "\"//\"",
 					},
 					&zeroOrMoreExpr{
-						pos: position{line: 42, col: 16, offset: 1813},
+						pos: position{line: 34, col: 16, offset: 1679},
 						expr: &charClassMatcher{
-							pos:        position{line: 42, col: 16, offset: 1813},
+							pos:        position{line: 34, col: 16, offset: 1679},
 							val:        "[^\\r\\n]",
 							chars:      []rune{'\r', '\n'},
 							ignoreCase: false,
@@ -541,70 +538,17 @@ var g = &grammar{
 		},
 		{
 			name: "EOF",
-			pos:  position{line: 44, col: 1, offset: 1823},
+			pos:  position{line: 36, col: 1, offset: 1689},
 			expr: &notExpr{
-				pos: position{line: 44, col: 7, offset: 1831},
+				pos: position{line: 36, col: 7, offset: 1697},
 				expr: &anyMatcher{
-					line: 44, col: 8, offset: 1832,
-				},
-			},
-		},
-		{
-			name: "INDENTATION",
-			pos:  position{line: 46, col: 1, offset: 1835},
-			expr: &seqExpr{
-				pos: position{line: 46, col: 15, offset: 1851},
-				exprs: []any{
-					&labeledExpr{
-						pos:   position{line: 46, col: 15, offset: 1851},
-						label: "spaces",
-						expr: &zeroOrMoreExpr{
-							pos: position{line: 46, col: 22, offset: 1858},
-							expr: &litMatcher{
-								pos:        position{line: 46, col: 22, offset: 1858},
-								val:        " ",
-								ignoreCase: false,
-								want:       "\" \"",
-							},
-						},
-					},
-					&andCodeExpr{
-						pos: position{line: 46, col: 27, offset: 1863},
-						run: (*parser).callonINDENTATION5,
-					},
+					line: 36, col: 8, offset: 1698,
 				},
 			},
 		},
-		{
-			name: "INDENT",
-			pos:  position{line: 48, col: 1, offset: 1937},
-			expr: &stateCodeExpr{
-				pos: position{line: 48, col: 10, offset: 1948},
-				run: (*parser).callonINDENT1,
-			},
-		},
-		{
-			name: "DEDENT",
-			pos:  position{line: 50, col: 1, offset: 2024},
-			expr: &stateCodeExpr{
-				pos: position{line: 50, col: 10, offset: 2035},
-				run: (*parser).callonDEDENT1,
-			},
-		},
 	},
 }
 
-func (c *current) onInput3() error {
-	c.state["Indentation"] = 0
-	return nil
-}
-
-func (p *parser) callonInput3() error {
-	stack := p.vstack[len(p.vstack)-1]
-	_ = stack
-	return p.cur.onInput3()
-}
-
 func (c *current) onInput1(s, r any) (any, error) {
 	return newProgramNode(s.(StatementsNode), r.(ReturnNode))
 }
@@ -735,38 +679,6 @@ func (p *parser) callonAddOp1() (any, error) {
 	return p.cur.onAddOp1()
 }
 
-func (c *current) onINDENTATION5(spaces any) (bool, error) {
-	return len(toAnySlice(spaces)) == c.state["Indentation"].(int), nil
-}
-
-func (p *parser) callonINDENTATION5() (bool, error) {
-	stack := p.vstack[len(p.vstack)-1]
-	_ = stack
-	return p.cur.onINDENTATION5(stack["spaces"])
-}
-
-func (c *current) onINDENT1() error {
-	c.state["Indentation"] = c.state["Indentation"].(int) + 4
-	return nil
-}
-
-func (p *parser) callonINDENT1() error {
-	stack := p.vstack[len(p.vstack)-1]
-	_ = stack
-	return p.cur.onINDENT1()
-}
-
-func (c *current) onDEDENT1() error {
-	c.state["Indentation"] = c.state["Indentation"].(int) - 4
-	return nil
-}
-
-func (p *parser) callonDEDENT1() error {
-	stack := p.vstack[len(p.vstack)-1]
-	_ = stack
-	return p.cur.onDEDENT1()
-}
-
 var (
 	// errNoRule is returned when the grammar to parse has no rule.
 	errNoRule = errors.New("grammar has no rule")
@@ -782,6 +694,11 @@ var (
 	// errMaxExprCnt is used to signal that the maximum number of
 	// expressions have been parsed.
 	errMaxExprCnt = errors.New("max number of expressions parsed")
+
+	// errInconsistentIndent is returned when comparing the indentation
+	// of a line to the current indentation level depends on the width of
+	// a tab.
+	errInconsistentIndent = errors.New("inconsistent use of tabs and spaces in indentation")
 )
 
 // Option is a function that can set an option on the parser. It returns
@@ -855,9 +772,23 @@ func Statistics(stats *Stats, choiceNoMatch string) Option {
 // The default is false.
 func Debug(b bool) Option {
 	return func(p *parser) Option {
-		old := p.debug
-		p.debug = b
-		return Debug(old)
+		old := p.tracer
+		if b {
+			p.tracer = &debugTracer{p: p}
+		} else if _, ok := old.(*debugTracer); ok {
+			p.tracer = nil
+		}
+		return setTracer(old)
+	}
+}
+
+// setTracer creates an Option to set the tracer that receives the events
+// of the parse.
+func setTracer(t tracer) Option {
+	return func(p *parser) Option {
+		old := p.tracer
+		p.tracer = t
+		return setTracer(old)
 	}
 }
 
@@ -865,6 +796,9 @@ func Debug(b bool) Option {
 // the parser will cache all results so each expression is evaluated only
 // once. This guarantees linear parsing time even for pathological cases,
 // at the expense of more memory and slower times for typical cases.
+// The results of the rules with the @memoize annotation in the grammar
+// are cached even if b is false, without the cost of caching every
+// expression.
 //
 // The default is false.
 func Memoize(b bool) Option {
@@ -875,6 +809,23 @@ func Memoize(b bool) Option {
 	}
 }
 
+// MemoWindow creates an Option to bound the memory of the memoization
+// table: the results are kept only for the n offsets behind the furthest
+// offset where a result was cached, as the parser rarely backtracks
+// further. The results evicted from the window are evaluated again if
+// needed, the memory is O(n) instead of O(len(input)). The rules with
+// the @commit annotation in the grammar also discard the results before
+// the end of their match. If n is 0, all the results are kept.
+//
+// The default is 0.
+func MemoWindow(n int) Option {
+	return func(p *parser) Option {
+		old := p.memoWindow
+		p.memoWindow = n
+		return MemoWindow(old)
+	}
+}
+
 // AllowInvalidUTF8 creates an Option to allow invalid UTF-8 bytes.
 // Every invalid UTF-8 byte is treated as a utf8.RuneError (U+FFFD)
 // by character class matchers and is matched by the any matcher.
@@ -889,6 +840,19 @@ func AllowInvalidUTF8(b bool) Option {
 	}
 }
 
+// TabWidth creates an Option to set the number of columns a tab advances
+// to when computing the width of the indentation for the %INDENT, %DEDENT
+// and %SAMEDENT expressions. Values lower than 1 are treated as 1.
+//
+// The default is 8.
+func TabWidth(n int) Option {
+	return func(p *parser) Option {
+		old := p.tabWidth
+		p.tabWidth = max(n, 1)
+		return TabWidth(old)
+	}
+}
+
 // Recover creates an Option to set the recover flag to b. When set to
 // true, this causes the parser to recover from panics and convert it
 // to an error. Setting it to false can be useful while debugging to
@@ -967,8 +931,9 @@ func (p position) String() string {
 // parser.
 type savepoint struct {
 	position
-	rn rune
-	w  int
+	rn     rune
+	w      int
+	indent *indentLevel
 }
 
 type current struct {
@@ -1060,6 +1025,19 @@ type (
 	oneOrMoreExpr  expr // nolint: structcheck
 )
 
+const (
+	indentOp = iota
+	dedentOp
+	samedentOp
+)
+
+// nolint: structcheck
+type indentExpr struct {
+	pos  position
+	op   int
+	want string
+}
+
 // nolint: structcheck
 type ruleRefExpr struct {
 	pos  position
@@ -1133,6 +1111,11 @@ func (e *errList) dedupe() {
 	*e = cleaned
 }
 
+// Unwrap returns the errors of the list.
+func (e errList) Unwrap() []error {
+	return e
+}
+
 func (e errList) Error() string {
 	switch len(e) {
 	case 0:
@@ -1187,6 +1170,7 @@ func newParser(filename string, b []byte, opts ...Option) *parser {
 		Stats:           &stats,
 		// start rule is rule [0] unless an alternate entrypoint is specified
 		entrypoint: g.rules[0].name,
+		tabWidth:   8,
 	}
 	p.setOptions(opts)
 
@@ -1224,7 +1208,8 @@ type Stats struct {
 	// ChoiceAltCnt is used to count for each ordered choice expression,
 	// which alternative is used how may times.
 	// These numbers allow to optimize the order of the ordered choice expression
-	// to increase the performance of the parser
+	// to increase the performance of the parser, saved as JSON for the
+	// -choice-stats flag of pigeon.
 	//
 	// The outer key of ChoiceAltCnt is composed of the name of the rule as well
 	// as the line and the column of the ordered choice.
@@ -1236,6 +1221,25 @@ type Stats struct {
 	ChoiceAltCnt map[string]map[string]int
 }
 
+// indentLevel is a level of the indentation stack. Levels are never
+// modified once created, so the stack is saved and restored along with
+// the savepoint when the parser backtracks. The nil level is the
+// outermost one, at width 0.
+type indentLevel struct {
+	// width of the indentation, with tabs advancing to the next tab stop
+	width int
+	// width of the indentation, with tabs counting as a single column
+	altWidth int
+	parent   *indentLevel
+}
+
+// indentMemoKey is the memoization key of an expression when the result
+// depends on the indentation stack.
+type indentMemoKey struct {
+	node   any
+	indent *indentLevel
+}
+
 // nolint: structcheck,maligned
 type parser struct {
 	filename string
@@ -1245,14 +1249,17 @@ type parser struct {
 	data []byte
 	errs *errList
 
-	depth   int
 	recover bool
-	debug   bool
+	tracer  tracer
 
 	memoize bool
-	// memoization table for the packrat algorithm:
-	// map[offset in source] map[expression or rule] {value, match}
-	memo map[int]map[any]resultTuple
+	// memoization table for the packrat algorithm, the results of the
+	// expressions and the rules by offset in source, in a ring of
+	// memoWindow slots if set
+	memo       []memoSlot
+	memoWindow int
+	// the results before this offset are discarded by the commit points
+	memoFloor int
 
 	// rules table, maps the rule identifier to the rule node
 	rules map[string]*rule
@@ -1272,6 +1279,8 @@ type parser struct {
 	entrypoint string
 
 	allowInvalidUTF8 bool
+	// number of columns of a tab
+	tabWidth int
 
 	*Stats
 
@@ -1337,29 +1346,73 @@ func (p *parser) popRecovery() {
 	p.recoveryStack = p.recoveryStack[:len(p.recoveryStack)-1]
 }
 
-func (p *parser) print(prefix, s string) string {
-	if !p.debug {
-		return s
-	}
+func (p *parser) in(s string) string {
+	p.tracer.enterExpr(s, p.pt.position)
+	return s
+}
 
-	fmt.Printf("%s %d:%d:%d: %s [%#U]\n",
-		prefix, p.pt.line, p.pt.col, p.pt.offset, s, p.pt.rn)
+func (p *parser) out(s string) string {
+	p.tracer.exitExpr(s, p.pt.position)
 	return s
 }
 
-func (p *parser) printIndent(mark string, s string) string {
-	return p.print(strings.Repeat(" ", p.depth)+mark, s)
+// tracer receives the events of a parse, with the positions of the
+// parser.
+type tracer interface {
+	enterRule(rule string, pos position)
+	exitRule(rule string, matched bool, start, end position)
+	enterExpr(step string, pos position)
+	exitExpr(step string, pos position)
+	backtrack(from, to position)
+	memo(rule string, pos position, hit bool)
+	error(err error)
 }
 
-func (p *parser) in(s string) string {
-	res := p.printIndent(">", s)
-	p.depth++
-	return res
+// debugTracer is the tracer of the Debug option, it prints the events
+// to stdout, indented by the depth of the steps.
+type debugTracer struct {
+	p     *parser
+	depth int
 }
 
-func (p *parser) out(s string) string {
-	p.depth--
-	return p.printIndent("<", s)
+func (t *debugTracer) print(mark string, pos position, s string) {
+	fmt.Printf("%s%s %d:%d:%d: %s [%#U]\n",
+		strings.Repeat(" ", t.depth), mark, pos.line, pos.col, pos.offset, s, t.p.pt.rn)
+}
+
+func (t *debugTracer) enterRule(rule string, pos position) {
+	t.enterExpr("parseRule "+rule, pos)
+}
+
+func (t *debugTracer) exitRule(rule string, matched bool, start, end position) {
+	if matched {
+		t.print("MATCH", end, string(t.p.slice(start.offset, end.offset)))
+	}
+	t.exitExpr("parseRule "+rule, end)
+}
+
+func (t *debugTracer) enterExpr(step string, pos position) {
+	t.print(">", pos, step)
+	t.depth++
+}
+
+func (t *debugTracer) exitExpr(step string, pos position) {
+	t.depth--
+	t.print("<", pos, step)
+}
+
+func (t *debugTracer) backtrack(from, to position) {
+	t.print("BACKTRACK", to, fmt.Sprintf("from %d:%d:%d", from.line, from.col, from.offset))
+}
+
+func (t *debugTracer) memo(rule string, pos position, hit bool) {
+	if hit {
+		t.print("MEMO", pos, rule)
+	}
+}
+
+func (t *debugTracer) error(err error) {
+	t.print("ERROR", t.p.pt.position, err.Error())
 }
 
 func (p *parser) addErr(err error) {
@@ -1388,6 +1441,9 @@ func (p *parser) addErrAt(err error, pos position, expected []string) {
 	}
 	pe := &parserError{Inner: err, pos: pos, prefix: buf.String(), expected: expected}
 	p.errs.add(pe)
+	if p.tracer != nil {
+		p.tracer.error(pe)
+	}
 }
 
 func (p *parser) failAt(fail bool, pos position, want string) {
@@ -1412,7 +1468,7 @@ func (p *parser) failAt(fail bool, pos position, want string) {
 // read advances the parser to the next rune.
 func (p *parser) read() {
 	p.pt.offset += p.pt.w
-	rn, n := utf8.DecodeRune(p.data[p.pt.offset:])
+	rn, n, valid := p.decode(p.data[p.pt.offset:])
 	p.pt.rn = rn
 	p.pt.w = n
 	p.pt.col++
@@ -1421,21 +1477,27 @@ func (p *parser) read() {
 		p.pt.col = 0
 	}
 
-	if rn == utf8.RuneError && n == 1 { // see utf8.DecodeRune
+	if !valid {
 		if !p.allowInvalidUTF8 {
 			p.addErr(errInvalidEncoding)
 		}
 	}
 }
 
+// decode decodes the first character of b in the encoding of the input.
+func (p *parser) decode(b []byte) (rune, int, bool) {
+	rn, n := utf8.DecodeRune(b)
+	return rn, n, rn != utf8.RuneError || n != 1 // see utf8.DecodeRune
+}
+
 // restore parser position to the savepoint pt.
 func (p *parser) restore(pt savepoint) {
-	if p.debug {
-		defer p.out(p.in("restore"))
-	}
-	if pt.offset == p.pt.offset {
+	if pt.offset == p.pt.offset && pt.indent == p.pt.indent {
 		return
 	}
+	if p.tracer != nil && pt.offset < p.pt.offset {
+		p.tracer.backtrack(p.pt.position, pt.position)
+	}
 	p.pt = pt
 }
 
@@ -1463,7 +1525,7 @@ func (sd storeDict) Discard() {
 
 // clone and return parser current state.
 func (p *parser) cloneState() storeDict {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("cloneState"))
 	}
 
@@ -1481,7 +1543,7 @@ func (p *parser) cloneState() storeDict {
 // restore parser current state to the state storeDict.
 // every restoreState should applied only one time for every cloned state
 func (p *parser) restoreState(state storeDict) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("restoreState"))
 	}
 	p.cur.state.Discard()
@@ -1490,31 +1552,75 @@ func (p *parser) restoreState(state storeDict) {
 
 // get the slice of bytes from the savepoint start to the current position.
 func (p *parser) sliceFrom(start savepoint) []byte {
-	return p.data[start.position.offset:p.pt.position.offset]
+	return p.slice(start.position.offset, p.pt.position.offset)
+}
+
+// get the slice of bytes between the offsets start and end.
+func (p *parser) slice(start, end int) []byte {
+	return p.data[start:end]
+}
+
+// memoEntry is the result of an expression or a rule in the memoization
+// table. The few results at an offset are searched linearly.
+type memoEntry struct {
+	node any
+	res  resultTuple
+}
+
+// memoSlot holds the results at an offset of the memoization table, the
+// slots are reused for the offsets that are memoWindow apart.
+type memoSlot struct {
+	offset  int
+	entries []memoEntry
 }
 
 func (p *parser) getMemoized(node any) (resultTuple, bool) {
-	if len(p.memo) == 0 {
+	if len(p.memo) == 0 || p.pt.offset < p.memoFloor {
 		return resultTuple{}, false
 	}
-	m := p.memo[p.pt.offset]
-	if len(m) == 0 {
+	slot := &p.memo[p.pt.offset%len(p.memo)]
+	if slot.offset != p.pt.offset {
 		return resultTuple{}, false
 	}
-	res, ok := m[node]
-	return res, ok
+	node = indentMemoKey{node: node, indent: p.pt.indent}
+	for _, e := range slot.entries {
+		if e.node == node {
+			return e.res, true
+		}
+	}
+	return resultTuple{}, false
 }
 
 func (p *parser) setMemoized(pt savepoint, node any, tuple resultTuple) {
 	if p.memo == nil {
-		p.memo = make(map[int]map[any]resultTuple)
+		n := len(p.data) + 1
+		if p.memoWindow > 0 && p.memoWindow < n {
+			n = p.memoWindow
+		}
+		p.memo = make([]memoSlot, n)
 	}
-	m := p.memo[pt.offset]
-	if m == nil {
-		m = make(map[any]resultTuple)
-		p.memo[pt.offset] = m
+	if pt.offset < p.memoFloor {
+		return
 	}
-	m[node] = tuple
+	slot := &p.memo[pt.offset%len(p.memo)]
+	if slot.offset != pt.offset {
+		if slot.offset > pt.offset {
+			// out of the window
+			return
+		}
+		for i := range slot.entries {
+			slot.entries[i] = memoEntry{}
+		}
+		slot.offset, slot.entries = pt.offset, slot.entries[:0]
+	}
+	node = indentMemoKey{node: node, indent: pt.indent}
+	for i := range slot.entries {
+		if slot.entries[i].node == node {
+			slot.entries[i].res = tuple
+			return
+		}
+	}
+	slot.entries = append(slot.entries, memoEntry{node: node, res: tuple})
 }
 
 func (p *parser) buildRulesTable(g *grammar) {
@@ -1539,7 +1645,7 @@ func (p *parser) parse(g *grammar) (val any, err error) {
 		// and return the panic as an error.
 		defer func() {
 			if e := recover(); e != nil {
-				if p.debug {
+				if p.tracer != nil {
 					defer p.out(p.in("panic handler"))
 				}
 				val = nil
@@ -1602,8 +1708,17 @@ func listJoin(list []string, sep string, lastSep string) string {
 	}
 }
 
+// memoizeRule returns true if the results of the rule are memoized, with
+// the Memoize option or with the @memoize annotation of the rule.
+func (p *parser) memoizeRule(rule *rule) bool {
+	return p.memoize
+}
+
 func (p *parser) parseRuleMemoize(rule *rule) (any, bool) {
 	res, ok := p.getMemoized(rule)
+	if p.tracer != nil {
+		p.tracer.memo(rule.name, p.pt.position, ok)
+	}
 	if ok {
 		p.restore(res.end)
 		return res.v, res.b
@@ -1617,8 +1732,8 @@ func (p *parser) parseRuleMemoize(rule *rule) (any, bool) {
 }
 
 func (p *parser) parseRuleWrap(rule *rule) (any, bool) {
-	if p.debug {
-		defer p.out(p.in("parseRule " + rule.name))
+	if p.tracer != nil {
+		p.tracer.enterRule(rule.name, p.pt.position)
 	}
 	var (
 		val       any
@@ -1626,14 +1741,14 @@ func (p *parser) parseRuleWrap(rule *rule) (any, bool) {
 		startMark = p.pt
 	)
 
-	if p.memoize {
+	if p.memoizeRule(rule) {
 		val, ok = p.parseRuleMemoize(rule)
 	} else {
 		val, ok = p.parseRule(rule)
 	}
 
-	if ok && p.debug {
-		p.printIndent("MATCH", string(p.sliceFrom(startMark)))
+	if p.tracer != nil {
+		p.tracer.exitRule(rule.name, ok, startMark.position, p.pt.position)
 	}
 	return val, ok
 }
@@ -1668,14 +1783,12 @@ func (p *parser) parseExprWrap(expr any) (any, bool) {
 }
 
 // nolint: gocyclo
-func (p *parser) parseExpr(expr any) (any, bool) {
+func (p *parser) parseExpr(expr any) (val any, ok bool) {
 	p.ExprCnt++
 	if p.ExprCnt > p.maxExprCnt {
 		panic(errMaxExprCnt)
 	}
 
-	var val any
-	var ok bool
 	switch expr := expr.(type) {
 	case *actionExpr:
 		val, ok = p.parseActionExpr(expr)
@@ -1689,6 +1802,8 @@ func (p *parser) parseExpr(expr any) (any, bool) {
 		val, ok = p.parseCharClassMatcher(expr)
 	case *choiceExpr:
 		val, ok = p.parseChoiceExpr(expr)
+	case *indentExpr:
+		val, ok = p.parseIndentExpr(expr)
 	case *labeledExpr:
 		val, ok = p.parseLabeledExpr(expr)
 	case *litMatcher:
@@ -1720,7 +1835,7 @@ func (p *parser) parseExpr(expr any) (any, bool) {
 }
 
 func (p *parser) parseActionExpr(act *actionExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseActionExpr"))
 	}
 
@@ -1738,14 +1853,11 @@ func (p *parser) parseActionExpr(act *actionExpr) (any, bool) {
 
 		val = actVal
 	}
-	if ok && p.debug {
-		p.printIndent("MATCH", string(p.sliceFrom(start)))
-	}
 	return val, ok
 }
 
 func (p *parser) parseAndCodeExpr(and *andCodeExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseAndCodeExpr"))
 	}
 
@@ -1761,23 +1873,21 @@ func (p *parser) parseAndCodeExpr(and *andCodeExpr) (any, bool) {
 }
 
 func (p *parser) parseAndExpr(and *andExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseAndExpr"))
 	}
 
 	pt := p.pt
-	state := p.cloneState()
 	p.pushV()
 	_, ok := p.parseExprWrap(and.expr)
 	p.popV()
-	p.restoreState(state)
 	p.restore(pt)
 
 	return nil, ok
 }
 
 func (p *parser) parseAnyMatcher(any *anyMatcher) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseAnyMatcher"))
 	}
 
@@ -1794,7 +1904,7 @@ func (p *parser) parseAnyMatcher(any *anyMatcher) (any, bool) {
 
 // nolint: gocyclo
 func (p *parser) parseCharClassMatcher(chr *charClassMatcher) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseCharClassMatcher"))
 	}
 
@@ -1875,7 +1985,7 @@ func (p *parser) incChoiceAltCnt(ch *choiceExpr, altI int) {
 }
 
 func (p *parser) parseChoiceExpr(ch *choiceExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseChoiceExpr"))
 	}
 
@@ -1883,8 +1993,6 @@ func (p *parser) parseChoiceExpr(ch *choiceExpr) (any, bool) {
 		// dummy assignment to prevent compile error if optimized
 		_ = altI
 
-		state := p.cloneState()
-
 		p.pushV()
 		val, ok := p.parseExprWrap(alt)
 		p.popV()
@@ -1892,14 +2000,108 @@ func (p *parser) parseChoiceExpr(ch *choiceExpr) (any, bool) {
 			p.incChoiceAltCnt(ch, altI)
 			return val, ok
 		}
-		p.restoreState(state)
 	}
 	p.incChoiceAltCnt(ch, choiceNoMatch)
 	return nil, false
 }
 
+func (p *parser) parseIndentExpr(ind *indentExpr) (any, bool) {
+	if p.tracer != nil {
+		defer p.out(p.in("parseIndentExpr " + ind.want))
+	}
+
+	start := p.pt
+	width, altWidth, end := p.indentation()
+	var cmp, altCmp int
+	if cur := start.indent; cur != nil {
+		cmp, altCmp = compareInts(width, cur.width), compareInts(altWidth, cur.altWidth)
+	} else {
+		cmp, altCmp = compareInts(width, 0), compareInts(altWidth, 0)
+	}
+	if cmp != altCmp {
+		p.addErr(errInconsistentIndent)
+		p.failAt(false, start.position, ind.want)
+		return nil, false
+	}
+
+	ok := false
+	switch ind.op {
+	case indentOp:
+		if cmp > 0 {
+			p.pt.indent = &indentLevel{width: width, altWidth: altWidth, parent: start.indent}
+			ok = true
+		}
+	case dedentOp:
+		// the indentation must match one of the enclosing levels
+		if parent := start.indent.parentOf(); cmp < 0 && width <= parent.widthOf() {
+			p.pt.indent = parent
+			ok = true
+		}
+	case samedentOp:
+		if cmp == 0 {
+			for p.pt.offset < end {
+				p.read()
+			}
+			ok = true
+		}
+	}
+	p.failAt(ok, start.position, ind.want)
+	if !ok {
+		return nil, false
+	}
+	return p.sliceFrom(start), true
+}
+
+// indentation returns the width of the indentation of the next non-blank
+// line, starting at the current position, and the offset of its first
+// non-whitespace character. Lines made only of spaces and tabs are
+// skipped, and the end of the input has a width of 0.
+func (p *parser) indentation() (width, altWidth, end int) {
+	for i, n := p.pt.offset, 0; i < len(p.data); i += n {
+		var rn rune
+		rn, n, _ = p.decode(p.data[i:])
+		switch rn {
+		case ' ':
+			width++
+			altWidth++
+		case '\t':
+			width += p.tabWidth - width%p.tabWidth
+			altWidth++
+		case '\r', '\n':
+			width, altWidth = 0, 0
+		default:
+			return width, altWidth, i
+		}
+	}
+	return 0, 0, len(p.data)
+}
+
+func (l *indentLevel) parentOf() *indentLevel {
+	if l == nil {
+		return nil
+	}
+	return l.parent
+}
+
+func (l *indentLevel) widthOf() int {
+	if l == nil {
+		return 0
+	}
+	return l.width
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
 func (p *parser) parseLabeledExpr(lab *labeledExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseLabeledExpr"))
 	}
 
@@ -1914,7 +2116,7 @@ func (p *parser) parseLabeledExpr(lab *labeledExpr) (any, bool) {
 }
 
 func (p *parser) parseLitMatcher(lit *litMatcher) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseLitMatcher"))
 	}
 
@@ -1936,7 +2138,7 @@ func (p *parser) parseLitMatcher(lit *litMatcher) (any, bool) {
 }
 
 func (p *parser) parseNotCodeExpr(not *notCodeExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseNotCodeExpr"))
 	}
 
@@ -1952,25 +2154,23 @@ func (p *parser) parseNotCodeExpr(not *notCodeExpr) (any, bool) {
 }
 
 func (p *parser) parseNotExpr(not *notExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseNotExpr"))
 	}
 
 	pt := p.pt
-	state := p.cloneState()
 	p.pushV()
 	p.maxFailInvertExpected = !p.maxFailInvertExpected
 	_, ok := p.parseExprWrap(not.expr)
 	p.maxFailInvertExpected = !p.maxFailInvertExpected
 	p.popV()
-	p.restoreState(state)
 	p.restore(pt)
 
 	return nil, !ok
 }
 
 func (p *parser) parseOneOrMoreExpr(expr *oneOrMoreExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseOneOrMoreExpr"))
 	}
 
@@ -1992,7 +2192,7 @@ func (p *parser) parseOneOrMoreExpr(expr *oneOrMoreExpr) (any, bool) {
 }
 
 func (p *parser) parseRecoveryExpr(recover *recoveryExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseRecoveryExpr (" + strings.Join(recover.failureLabel, ",") + ")"))
 	}
 
@@ -2004,7 +2204,7 @@ func (p *parser) parseRecoveryExpr(recover *recoveryExpr) (any, bool) {
 }
 
 func (p *parser) parseRuleRefExpr(ref *ruleRefExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseRuleRefExpr " + ref.name))
 	}
 
@@ -2021,18 +2221,16 @@ func (p *parser) parseRuleRefExpr(ref *ruleRefExpr) (any, bool) {
 }
 
 func (p *parser) parseSeqExpr(seq *seqExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseSeqExpr"))
 	}
 
 	vals := make([]any, 0, len(seq.exprs))
 
 	pt := p.pt
-	state := p.cloneState()
 	for _, expr := range seq.exprs {
 		val, ok := p.parseExprWrap(expr)
 		if !ok {
-			p.restoreState(state)
 			p.restore(pt)
 			return nil, false
 		}
@@ -2042,7 +2240,7 @@ func (p *parser) parseSeqExpr(seq *seqExpr) (any, bool) {
 }
 
 func (p *parser) parseStateCodeExpr(state *stateCodeExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseStateCodeExpr"))
 	}
 
@@ -2054,7 +2252,7 @@ func (p *parser) parseStateCodeExpr(state *stateCodeExpr) (any, bool) {
 }
 
 func (p *parser) parseThrowExpr(expr *throwExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseThrowExpr"))
 	}
 
@@ -2070,7 +2268,7 @@ func (p *parser) parseThrowExpr(expr *throwExpr) (any, bool) {
 }
 
 func (p *parser) parseZeroOrMoreExpr(expr *zeroOrMoreExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseZeroOrMoreExpr"))
 	}
 
@@ -2088,7 +2286,7 @@ func (p *parser) parseZeroOrMoreExpr(expr *zeroOrMoreExpr) (any, bool) {
 }
 
 func (p *parser) parseZeroOrOneExpr(expr *zeroOrOneExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseZeroOrOneExpr"))
 	}
 
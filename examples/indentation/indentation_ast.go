@@ -254,3 +254,11 @@ func (n IdentifierNode) exec() (int, error) {
 	}
 	return v, nil
 }
+
+// toAnySlice converts the value of a repeated expression to a slice.
+func toAnySlice(v any) []any {
+	if v == nil {
+		return nil
+	}
+	return v.([]any)
+}
@@ -7,6 +7,7 @@ import (
 
 var cases = map[string]int{
 	"significant_whitespace.txt": 42,
+	"blank_lines_tabs.txt":       42,
 }
 
 func TestIndentation(t *testing.T) {
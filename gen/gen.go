@@ -0,0 +1,502 @@
+// Package gen generates random sentences of grammars, e.g. to seed the
+// corpus of the fuzz tests of their parsers.
+//
+// A Generator walks the expressions of a grammar from its entrypoint and
+// picks the alternatives of the choices, the number of repetitions and
+// the characters of the classes at random. The depth of the rules is
+// bounded: past the maximum depth, the alternatives that terminate the
+// fastest are picked and the repetitions are as few as possible. The
+// alternatives may be weighted by the statistics of a parser.
+//
+// The predicates and the code of the grammar are not run while walking
+// it. Instead, each sentence is parsed by interpreting the grammar, and
+// rejected if it does not match, which also rejects the sentences that
+// a PEG does not accept because of its ordered choices and its greedy
+// repetitions. The near misses, inputs that are rejected by the grammar,
+// are generated by replacing a character of a class in a sentence with a
+// character that the class does not match.
+package gen
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/interp"
+)
+
+// Default values of the Config.
+const (
+	DefaultMaxDepth  = 16
+	DefaultMaxRepeat = 3
+	DefaultAttempts  = 100
+)
+
+// ErrNoSentence is returned when no sentence or near miss was found in
+// the number of attempts of the Config.
+var ErrNoSentence = errors.New("no sentence found")
+
+// Config configures a Generator. The zero value is a valid Config.
+type Config struct {
+	// Rand is the source of randomness, a random source if nil.
+	Rand *rand.Rand
+	// Entrypoint is the name of the rule of the sentences, the first rule
+	// of the grammar if empty.
+	Entrypoint string
+	// MaxDepth is the depth of the rules past which the sentences are
+	// completed as fast as possible, DefaultMaxDepth if 0.
+	MaxDepth int
+	// MaxRepeat is the maximum number of repetitions of the expressions
+	// of *, + and ?, DefaultMaxRepeat if 0.
+	MaxRepeat int
+	// Weights weights the alternatives of the choices by their number of
+	// matches, in the format of the Stats.ChoiceAltCnt of the generated
+	// parsers: the key of a choice is the name of a rule and its position,
+	// e.g. "Expr 3:8", and its alternatives are counted by one-based
+	// index. The weight of an alternative is its count plus one, and the
+	// alternatives of the choices without statistics are equally likely.
+	Weights map[string]map[string]int
+	// Attempts is the number of sentences generated to find one that the
+	// grammar accepts, or one that it rejects for the near misses,
+	// DefaultAttempts if 0.
+	Attempts int
+}
+
+// Generator generates random sentences of a grammar.
+type Generator struct {
+	cfg     Config
+	p       *interp.Parser
+	entry   *ast.Rule
+	rules   map[string]*ast.Rule
+	weights map[string][]int
+	// minimum depth of the rules needed to complete a sentence from the
+	// rules and the expressions
+	ruleCosts map[string]int
+	costs     map[ast.Expression]int
+}
+
+// infinite is the cost of the expressions that cannot complete a
+// sentence.
+const infinite = math.MaxInt / 2
+
+// New returns a Generator of the sentences of g. It returns an error if
+// g cannot be interpreted, or if its entrypoint cannot complete a
+// sentence. The Generator keeps g, that must not be modified afterwards.
+func New(g *ast.Grammar, cfg *Config) (*Generator, error) {
+	gn := &Generator{
+		rules:     make(map[string]*ast.Rule, len(g.Rules)),
+		ruleCosts: make(map[string]int, len(g.Rules)),
+		costs:     make(map[ast.Expression]int),
+	}
+	if cfg != nil {
+		gn.cfg = *cfg
+	}
+	if gn.cfg.Rand == nil {
+		gn.cfg.Rand = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+	if gn.cfg.MaxDepth <= 0 {
+		gn.cfg.MaxDepth = DefaultMaxDepth
+	}
+	if gn.cfg.MaxRepeat <= 0 {
+		gn.cfg.MaxRepeat = DefaultMaxRepeat
+	}
+	if gn.cfg.Attempts <= 0 {
+		gn.cfg.Attempts = DefaultAttempts
+	}
+
+	p, err := interp.New(g)
+	if err != nil {
+		return nil, err
+	}
+	gn.p = p
+
+	for _, r := range g.Rules {
+		gn.rules[r.Name.Val] = r
+		gn.ruleCosts[r.Name.Val] = infinite
+	}
+	if gn.cfg.Entrypoint == "" && len(g.Rules) > 0 {
+		gn.cfg.Entrypoint = g.Rules[0].Name.Val
+	}
+	gn.entry = gn.rules[gn.cfg.Entrypoint]
+	if gn.entry == nil {
+		return nil, fmt.Errorf("unknown rule %s", gn.cfg.Entrypoint)
+	}
+
+	// the cost of the rules is their minimum depth, computed until a fixed
+	// point is reached
+	for changed := true; changed; {
+		changed = false
+		clear(gn.costs)
+		for _, r := range g.Rules {
+			if c := gn.cost(r.Expr); c < gn.ruleCosts[r.Name.Val] {
+				gn.ruleCosts[r.Name.Val] = c
+				changed = true
+			}
+		}
+	}
+	if gn.ruleCosts[gn.entry.Name.Val] >= infinite {
+		return nil, fmt.Errorf("rule %s cannot complete a sentence", gn.entry.Name.Val)
+	}
+
+	gn.weights = ast.ChoiceCounts(gn.cfg.Weights)
+	return gn, nil
+}
+
+// cost returns the minimum depth of the rules needed to complete a
+// sentence from expr, infinite if it cannot.
+func (gn *Generator) cost(expr ast.Expression) int {
+	if c, ok := gn.costs[expr]; ok {
+		return c
+	}
+
+	var c int
+	switch expr := expr.(type) {
+	case *ast.ActionExpr:
+		c = gn.cost(expr.Expr)
+	case *ast.ChoiceExpr:
+		c = infinite
+		for _, alt := range expr.Alternatives {
+			c = min(c, gn.cost(alt))
+		}
+	case *ast.LabeledExpr:
+		c = gn.cost(expr.Expr)
+	case *ast.OneOrMoreExpr:
+		c = gn.cost(expr.Expr)
+	case *ast.RecoveryExpr:
+		c = gn.cost(expr.Expr)
+	case *ast.RuleRefExpr:
+		c = infinite
+		if rc, ok := gn.ruleCosts[expr.Name.Val]; ok && rc < infinite {
+			c = rc + 1
+		}
+	case *ast.SeqExpr:
+		for _, e := range expr.Exprs {
+			c = max(c, gn.cost(e))
+		}
+	case *ast.ThrowExpr:
+		// the parse fails
+		c = infinite
+	}
+	gn.costs[expr] = c
+	return c
+}
+
+// Sentence returns a random sentence of the grammar, that its entrypoint
+// matches entirely. It returns ErrNoSentence if none of the sentences
+// generated in the number of attempts of the Config is accepted.
+func (gn *Generator) Sentence() ([]byte, error) {
+	for range gn.cfg.Attempts {
+		s := gn.generate()
+		if gn.accepts(s.buf) {
+			return s.buf, nil
+		}
+	}
+	return nil, ErrNoSentence
+}
+
+// NearMiss returns a random input that the grammar rejects: a sentence
+// of the grammar where the character matched by a character class is
+// replaced with a character that the class does not match. It returns
+// ErrNoSentence if none is found in the number of attempts of the
+// Config, e.g. if the grammar has no character class.
+func (gn *Generator) NearMiss() ([]byte, error) {
+	for range gn.cfg.Attempts {
+		s := gn.generate()
+		if len(s.classes) == 0 || !gn.accepts(s.buf) {
+			continue
+		}
+		m := s.classes[gn.cfg.Rand.IntN(len(s.classes))]
+		rn, ok := gn.classChar(m.class, true)
+		if !ok {
+			continue
+		}
+		b := append(append(append([]byte(nil), s.buf[:m.start]...), string(rn)...), s.buf[m.end:]...)
+		if !gn.accepts(b) {
+			return b, nil
+		}
+	}
+	return nil, ErrNoSentence
+}
+
+// accepts returns true if the entrypoint matches b entirely.
+func (gn *Generator) accepts(b []byte) bool {
+	n, err := gn.p.ParseTree("", b, interp.Entrypoint(gn.entry.Name.Val), interp.Memoize(true))
+	return err == nil && n != nil && n.End.Offset == len(b)
+}
+
+// sentence is a sentence being generated.
+type sentence struct {
+	buf []byte
+	// text of the labels of the rules being generated, for the
+	// back-references
+	labels []map[string]string
+	// characters generated by the character classes
+	classes []classMatch
+}
+
+// classMatch is a character of a sentence generated by a class.
+type classMatch struct {
+	class      *ast.CharClassMatcher
+	start, end int
+}
+
+func (gn *Generator) generate() *sentence {
+	s := &sentence{labels: []map[string]string{{}}}
+	gn.gen(s, gn.entry.Expr, 0)
+	return s
+}
+
+// gen appends a random match of expr to s. The depth is the number of
+// rules being generated.
+func (gn *Generator) gen(s *sentence, expr ast.Expression, depth int) {
+	deep := depth >= gn.cfg.MaxDepth
+	switch expr := expr.(type) {
+	case *ast.ActionExpr:
+		gn.gen(s, expr.Expr, depth)
+	case *ast.AnyMatcher:
+		s.buf = utf8.AppendRune(s.buf, gn.anyChar())
+	case *ast.BackRefExpr:
+		s.buf = append(s.buf, s.labels[len(s.labels)-1][expr.Label.Val]...)
+	case *ast.CharClassMatcher:
+		if rn, ok := gn.classChar(expr, false); ok {
+			start := len(s.buf)
+			s.buf = utf8.AppendRune(s.buf, rn)
+			s.classes = append(s.classes, classMatch{class: expr, start: start, end: len(s.buf)})
+		}
+	case *ast.ChoiceExpr:
+		if alt := gn.pick(expr, deep); alt != nil {
+			gn.gen(s, alt, depth)
+		}
+	case *ast.LabeledExpr:
+		start := len(s.buf)
+		gn.gen(s, expr.Expr, depth)
+		if expr.Label != nil {
+			s.labels[len(s.labels)-1][expr.Label.Val] = string(s.buf[start:])
+		}
+	case *ast.LitMatcher:
+		for _, rn := range expr.Val {
+			if expr.IgnoreCase {
+				rn = gn.fold(rn)
+			}
+			s.buf = utf8.AppendRune(s.buf, rn)
+		}
+	case *ast.OneOrMoreExpr:
+		for range 1 + gn.repeat(expr.Expr, deep) {
+			gn.gen(s, expr.Expr, depth)
+		}
+	case *ast.RecoveryExpr:
+		gn.gen(s, expr.Expr, depth)
+	case *ast.RuleRefExpr:
+		s.labels = append(s.labels, map[string]string{})
+		gn.gen(s, gn.rules[expr.Name.Val].Expr, depth+1)
+		s.labels = s.labels[:len(s.labels)-1]
+	case *ast.SeqExpr:
+		for _, e := range expr.Exprs {
+			gn.gen(s, e, depth)
+		}
+	case *ast.ZeroOrMoreExpr:
+		for range gn.repeat(expr.Expr, deep) {
+			gn.gen(s, expr.Expr, depth)
+		}
+	case *ast.ZeroOrOneExpr:
+		if gn.repeat(expr.Expr, deep) > 0 {
+			gn.gen(s, expr.Expr, depth)
+		}
+	}
+	// the predicates are checked when the sentence is parsed, and the code,
+	// the indentation and the throw expressions match nothing
+}
+
+// pick returns a random alternative of ch, one that completes the
+// sentence the fastest if deep is set, or nil if none can complete it.
+func (gn *Generator) pick(ch *ast.ChoiceExpr, deep bool) ast.Expression {
+	best := infinite
+	for _, alt := range ch.Alternatives {
+		best = min(best, gn.cost(alt))
+	}
+	if best >= infinite {
+		return nil
+	}
+
+	weights := gn.weights[fmt.Sprintf("%d:%d", ch.Pos().Line, ch.Pos().Col)]
+	var (
+		alts  []ast.Expression
+		total []int
+		sum   int
+	)
+	for i, alt := range ch.Alternatives {
+		c := gn.cost(alt)
+		if c >= infinite || (deep && c > best) {
+			continue
+		}
+		w := 1
+		if i < len(weights) {
+			w += weights[i]
+		}
+		sum += w
+		alts = append(alts, alt)
+		total = append(total, sum)
+	}
+	n := gn.cfg.Rand.IntN(sum)
+	for i, t := range total {
+		if n < t {
+			return alts[i]
+		}
+	}
+	return alts[len(alts)-1]
+}
+
+// repeat returns a random number of repetitions of expr, 0 if deep is set
+// or if expr cannot complete the sentence.
+func (gn *Generator) repeat(expr ast.Expression, deep bool) int {
+	if deep || gn.cost(expr) >= infinite {
+		return 0
+	}
+	return gn.cfg.Rand.IntN(gn.cfg.MaxRepeat + 1)
+}
+
+// otherChars are the characters other than printable ASCII generated for
+// the any matcher and the inverted classes.
+var otherChars = []rune{'\t', '\n', '\r', 0, 0x7f, 'é', 'ß', 'Ω', 'ж', '中', '€', ' ', ' ', '😀', utf8.RuneError}
+
+// anyChar returns a random character, a printable ASCII character most of
+// the time.
+func (gn *Generator) anyChar() rune {
+	if gn.cfg.Rand.IntN(4) == 0 {
+		return otherChars[gn.cfg.Rand.IntN(len(otherChars))]
+	}
+	return rune(' ' + gn.cfg.Rand.IntN('~'-' '+1))
+}
+
+// fold returns a random case of rn.
+func (gn *Generator) fold(rn rune) rune {
+	cases := []rune{rn}
+	for f := unicode.SimpleFold(rn); f != rn; f = unicode.SimpleFold(f) {
+		cases = append(cases, f)
+	}
+	return cases[gn.cfg.Rand.IntN(len(cases))]
+}
+
+// classChar returns a random character that the class c matches, or that
+// it does not match if miss is set. It returns false if there is none.
+func (gn *Generator) classChar(c *ast.CharClassMatcher, miss bool) (rune, bool) {
+	if c.Inverted == miss {
+		return gn.branchChar(c)
+	}
+	// a character outside of the branches
+	for range 100 {
+		if rn := gn.anyChar(); !inClass(c, rn) {
+			return rn, true
+		}
+	}
+	for rn := rune(' '); rn <= unicode.MaxRune; rn++ {
+		if !inClass(c, rn) {
+			return rn, true
+		}
+	}
+	return 0, false
+}
+
+// branchChar returns a random character of a random branch of the class
+// c, false if it has no branch.
+func (gn *Generator) branchChar(c *ast.CharClassMatcher) (rune, bool) {
+	n := len(c.Chars) + len(c.Ranges)/2 + len(c.UnicodeClasses)
+	if n == 0 {
+		return 0, false
+	}
+	var rn rune
+	switch i := gn.cfg.Rand.IntN(n); {
+	case i < len(c.Chars):
+		rn = c.Chars[i]
+	case i < len(c.Chars)+len(c.Ranges)/2:
+		i = 2 * (i - len(c.Chars))
+		rn = c.Ranges[i] + gn.cfg.Rand.Int32N(c.Ranges[i+1]-c.Ranges[i]+1)
+	default:
+		rt := rangeTable(c.UnicodeClasses[i-len(c.Chars)-len(c.Ranges)/2])
+		if rt == nil {
+			return 0, false
+		}
+		rn = gn.tableChar(rt)
+	}
+	if c.IgnoreCase {
+		rn = gn.fold(rn)
+	}
+	return rn, true
+}
+
+// tableChar returns a random character of rt.
+func (gn *Generator) tableChar(rt *unicode.RangeTable) rune {
+	i := gn.cfg.Rand.IntN(len(rt.R16) + len(rt.R32))
+	var lo, hi, stride rune
+	if i < len(rt.R16) {
+		r := rt.R16[i]
+		lo, hi, stride = rune(r.Lo), rune(r.Hi), rune(r.Stride)
+	} else {
+		r := rt.R32[i-len(rt.R16)]
+		lo, hi, stride = rune(r.Lo), rune(r.Hi), rune(r.Stride)
+	}
+	return lo + stride*gn.cfg.Rand.Int32N((hi-lo)/stride+1)
+}
+
+// inClass returns true if rn is in a branch of the class c.
+func inClass(c *ast.CharClassMatcher, rn rune) bool {
+	lower := func(rn rune) rune { return rn }
+	if c.IgnoreCase {
+		lower = unicode.ToLower
+	}
+	rn = lower(rn)
+	for _, ch := range c.Chars {
+		if lower(ch) == rn {
+			return true
+		}
+	}
+	for i := 0; i+1 < len(c.Ranges); i += 2 {
+		if rn >= lower(c.Ranges[i]) && rn <= lower(c.Ranges[i+1]) {
+			return true
+		}
+	}
+	for _, name := range c.UnicodeClasses {
+		if rt := rangeTable(name); rt != nil && unicode.Is(rt, rn) {
+			return true
+		}
+	}
+	return false
+}
+
+// rangeTable returns the Unicode range table of the class, or nil if it
+// does not exist.
+func rangeTable(class string) *unicode.RangeTable {
+	if rt, ok := unicode.Categories[class]; ok {
+		return rt
+	}
+	if rt, ok := unicode.Properties[class]; ok {
+		return rt
+	}
+	return unicode.Scripts[class]
+}
+
+// WriteCorpus writes the inputs to the directory dir, created if needed,
+// as files of the seed corpus of a fuzz test that takes a []byte
+// argument, e.g. to "testdata/fuzz/FuzzParse" in the directory of the
+// package of the test. The files are named and formatted as the go
+// command does.
+func WriteCorpus(dir string, inputs ...[]byte) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, in := range inputs {
+		data := fmt.Appendf(nil, "go test fuzz v1\n[]byte(%q)\n", in)
+		name := fmt.Sprintf("%x", sha256.Sum256(data))[:16]
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
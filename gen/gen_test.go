@@ -0,0 +1,184 @@
+package gen
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/examples/json"
+	"github.com/mna/pigeon/parse"
+	"github.com/mna/pigeon/test/backref"
+)
+
+func parseGrammar(t *testing.T, src string) *ast.Grammar {
+	t.Helper()
+	g, err := parse.ParseGrammar("", strings.NewReader(src), parse.Validate(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func parseGrammarFile(t *testing.T, file string) *ast.Grammar {
+	t.Helper()
+	src, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parseGrammar(t, string(src))
+}
+
+func newGenerator(t *testing.T, g *ast.Grammar, cfg *Config) *Generator {
+	t.Helper()
+	if cfg.Rand == nil {
+		cfg.Rand = rand.New(rand.NewPCG(1, 2))
+	}
+	gn, err := New(g, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gn
+}
+
+func TestSentence(t *testing.T) {
+	cases := []struct {
+		file  string
+		parse func(b []byte) error
+	}{
+		{"../examples/json/json.peg", func(b []byte) error { _, err := json.Parse("", b); return err }},
+		{"../test/backref/backref.peg", func(b []byte) error { _, err := backref.Parse("", b); return err }},
+	}
+	for _, c := range cases {
+		gn := newGenerator(t, parseGrammarFile(t, c.file), &Config{})
+		seen := make(map[string]bool)
+		for range 50 {
+			b, err := gn.Sentence()
+			if err != nil {
+				t.Fatalf("%s: %v", c.file, err)
+			}
+			// the actions are not run by the generator, the numbers of the
+			// sentences can be out of range
+			if err := c.parse(b); err != nil && !strings.Contains(err.Error(), "out of range") {
+				t.Errorf("%s: %q: %v", c.file, b, err)
+			}
+			seen[string(b)] = true
+		}
+		if len(seen) < 25 {
+			t.Errorf("%s: want various sentences, got %d distinct ones", c.file, len(seen))
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	g := parseGrammar(t, `E ← '(' E ')' / '[' E? ']' / 'x'`)
+	gn := newGenerator(t, g, &Config{MaxDepth: 3})
+	for range 50 {
+		b, err := gn.Sentence()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the rules past the maximum depth still match '[]' or 'x'
+		if len(b) > 8 {
+			t.Errorf("want at most 4 nested rules, got %q", b)
+		}
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	g := parseGrammar(t, `
+Id ← !Keyword [a-z]i+ !.
+Keyword ← "if" / "do"
+`)
+	gn := newGenerator(t, g, &Config{MaxRepeat: 2})
+	for range 50 {
+		b, err := gn.Sentence()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := string(b); strings.HasPrefix(s, "if") || strings.HasPrefix(s, "do") {
+			t.Errorf("want no keyword, got %q", b)
+		}
+	}
+}
+
+func TestWeights(t *testing.T) {
+	g := parseGrammar(t, `A ← ( 'a' / 'b' / 'c' )+ !.`)
+	gn := newGenerator(t, g, &Config{Weights: map[string]map[string]int{"A 1:7": {"2": 1000, "no match": 10}}})
+	var buf bytes.Buffer
+	for range 20 {
+		b, err := gn.Sentence()
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(b)
+	}
+	if n, nb := buf.Len(), bytes.Count(buf.Bytes(), []byte("b")); nb < n*9/10 {
+		t.Errorf("want mostly b, got %d of %d: %s", nb, n, buf.Bytes())
+	}
+}
+
+func TestNearMiss(t *testing.T) {
+	g := parseGrammar(t, `N ← '-'? [0-9]+ ( '.' [^a-z\n] )? !.`)
+	gn := newGenerator(t, g, &Config{})
+	for range 50 {
+		b, err := gn.NearMiss()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gn.accepts(b) {
+			t.Errorf("want a rejected input, got %q", b)
+		}
+	}
+
+	gn = newGenerator(t, parseGrammar(t, `A ← 'a'+`), &Config{Attempts: 5})
+	if _, err := gn.NearMiss(); err != ErrNoSentence {
+		t.Errorf("want %v without character class, got %v", ErrNoSentence, err)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	cases := map[string]*Config{
+		"A ← 'a' A":          {},
+		"A ← 'a' B":          {},
+		"A ← 'a'\nB ← 'b' B": {Entrypoint: "B"},
+		"A ← 'a'":            {Entrypoint: "C"},
+	}
+	for src, cfg := range cases {
+		if _, err := New(parseGrammar(t, src), cfg); err == nil {
+			t.Errorf("%q: want an error", src)
+		}
+	}
+}
+
+func TestWriteCorpus(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "testdata", "fuzz", "FuzzParse")
+	if err := WriteCorpus(dir, []byte("a\"b\n"), []byte{0xff}); err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(filepath.Base(f)) != 16 {
+			t.Errorf("want a 16 characters file name, got %s", f)
+		}
+		got = append(got, string(b))
+	}
+	want := map[string]bool{
+		"go test fuzz v1\n[]byte(\"a\\\"b\\n\")\n": true,
+		"go test fuzz v1\n[]byte(\"\\xff\")\n":     true,
+	}
+	if len(got) != 2 || !want[got[0]] || !want[got[1]] {
+		t.Errorf("want files %v, got %q", want, got)
+	}
+}
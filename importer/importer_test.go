@@ -0,0 +1,170 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mna/pigeon/ast"
+)
+
+func TestParsePEGjs(t *testing.T) {
+	src := `{{ const x = 1; }}
+{ let y = 2; }
+// The start rule.
+start "the start" = head:item tail:(_ "," _ @item)* { return [head, ...tail]; }
+item = $[a-z]i+ / n:num &{ return n > 0; } / "null"i / !"}" .
+num = d:[0-9]|1..3| { return +d.join(""); }
+list = @item|2.., ","| ";"
+pair = "(" @item "," @item ")"
+len = string:item|..2| ;
+_ = [ \t\n]*
+`
+	want := `{
+// TODO: port the JavaScript initializer:
+//   const x = 1;
+//   let y = 2;
+}
+
+// The start rule.
+start "the start" ← head:item tail:( _ ',' _ p1:item { return p1, nil } )* {
+    // TODO: port the JavaScript action:
+    //   return [head, ...tail];
+    return nil, nil
+}
+
+item ← [a-z]i+ { return string(c.text), nil }
+     / n:num &{
+         // TODO: port the JavaScript predicate:
+         //   return n > 0;
+         return true, nil
+     }
+     / "null"i
+     / !'}' .
+
+num ← d:( [0-9] [0-9]? [0-9]? ) {
+    // TODO: port the JavaScript action:
+    //   return +d.join("");
+    return nil, nil
+}
+
+list ← p1:( item ( ',' item ) ( ',' item )* ) ';' { return p1, nil }
+pair ← '(' p1:item ',' p2:item ')' { return []any{p1, p2}, nil }
+len ← string_:( item item? )?
+_ ← [ \t\n]*
+`
+	g, err := ParsePEGjs("test.pegjs", strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGrammar(t, g, want)
+}
+
+func TestParsePegen(t *testing.T) {
+	src := `@subheader """
+import ast
+"""
+@class Parser
+
+# The start rule.
+file[mod_ty]: a=[statements] ENDMARKER { _PyPegen_make_module(p, a) }
+statements[asdl_stmt_seq*]: a=statement+ { a }
+statement (memo):
+    | &'if' if_stmt
+    | simple_stmts
+if_stmt: 'if' ~ named_expression ':' block
+block: NEWLINE INDENT a=statements DEDENT { a } | simple_stmts
+simple_stmts: ';'.NAME+ [';'] NEWLINE
+named_expression: NAME | NUMBER | STRING | TYPE_COMMENT | $
+`
+	want := `{
+// TODO: port the pegen meta-information:
+//   @subheader """
+//   import ast
+//   """
+//   @class Parser
+}
+
+// The start rule.
+file ← a:statements? ENDMARKER {
+    // TODO: port the pegen action:
+    //   _PyPegen_make_module(p, a)
+    return nil, nil
+}
+
+statements ← a:statement+ {
+    // TODO: port the pegen action:
+    //   a
+    return nil, nil
+}
+
+statement ← &"if" if_stmt / simple_stmts
+if_stmt ← "if" named_expression ':' block
+block ← NEWLINE INDENT a:statements DEDENT {
+    // TODO: port the pegen action:
+    //   a
+    return nil, nil
+}
+      / simple_stmts
+
+simple_stmts ← ( NAME ( ';' NAME )* ) ';'? NEWLINE
+named_expression ← NAME / NUMBER / STRING / TYPE_COMMENT / !.
+
+ENDMARKER ← !.
+NEWLINE ← '\r'? '\n'
+INDENT ← %INDENT
+DEDENT ← %DEDENT
+NAME ← [_\pL] [_\pL\p{Nd}]*
+NUMBER ← [0-9]+ ( '.' [0-9]+ )?
+STRING ← '"' [^"\\\n]* '"' / '\'' [^'\\\n]* '\''
+TYPE_COMMENT ← &{
+    // TODO: match the TYPE_COMMENT token
+    return false, nil
+}
+`
+	g, err := ParsePegen("test.gram", strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGrammar(t, g, want)
+}
+
+func TestImportErrors(t *testing.T) {
+	cases := []struct {
+		pegen bool
+		src   string
+		err   string
+	}{
+		{false, `a = "x`, "test:1:7 (6): string literal not terminated"},
+		{false, `a = [x`, "test:1:7 (6): character class not terminated"},
+		{false, `a = x { return 1;`, "test:1:18 (17): code block not terminated"},
+		{false, `a = x|2..a|`, `test:1:10 (9): expected "|"`},
+		{false, `a = x|{n}|`, "test:1:7 (6): repetition bounds in code blocks are not supported"},
+		{false, `= x`, "test:1:1 (0): expected rule name"},
+		{true, `a: `, "test:1:4 (3): expected item"},
+		{true, `a x`, `test:1:3 (2): expected ":"`},
+		{true, `a: (b`, `test:1:6 (5): expected ")"`},
+	}
+	for _, tc := range cases {
+		var err error
+		if tc.pegen {
+			_, err = ParsePegen("test", strings.NewReader(tc.src))
+		} else {
+			_, err = ParsePEGjs("test", strings.NewReader(tc.src))
+		}
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("%q: want error %q, got %v", tc.src, tc.err, err)
+		}
+	}
+}
+
+func checkGrammar(t *testing.T, g *ast.Grammar, want string) {
+	t.Helper()
+
+	var buf strings.Builder
+	if err := ast.Fprint(&buf, g, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,440 @@
+// Package importer converts the grammars of other PEG parser generators
+// to pigeon grammars.
+//
+// The structure of the rules carries over, while the code blocks, written
+// in the language of the other generator, are replaced by TODO stubs that
+// keep the original code in comments.
+package importer
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mna/pigeon/ast"
+)
+
+// Error is an error in the grammar being imported.
+type Error struct {
+	Pos ast.Pos
+	Msg string
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// input reads the source of a grammar rune by rune. The syntax errors
+// panic with an *Error, recovered by parse.
+type input struct {
+	filename string
+	src      string
+	off      int
+	line     int
+	col      int
+
+	// lineComment starts a comment to the end of the line, block comments
+	// are /* */ if blockComments is set.
+	lineComment   string
+	blockComments bool
+	comments      []*ast.Comment
+}
+
+// state is the position of the input, to backtrack.
+type state struct {
+	off, line, col, comments int
+}
+
+func newInput(filename string, r io.Reader, lineComment string, blockComments bool) (*input, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return &input{
+		filename:      filename,
+		src:           string(b),
+		line:          1,
+		col:           1,
+		lineComment:   lineComment,
+		blockComments: blockComments,
+	}, nil
+}
+
+// parse calls fn and returns its grammar, with the comments of the input
+// and the doc comments of the rules set, or the syntax error.
+func (in *input) parse(fn func() *ast.Grammar) (g *ast.Grammar, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			ie, ok := e.(*Error)
+			if !ok {
+				panic(e)
+			}
+			g, err = nil, ie
+		}
+	}()
+
+	g = fn()
+	g.Comments = in.comments
+	setRuleDocs(g)
+	return g, nil
+}
+
+func (in *input) pos() ast.Pos {
+	return ast.Pos{Filename: in.filename, Line: in.line, Col: in.col, Off: in.off}
+}
+
+func (in *input) save() state {
+	return state{in.off, in.line, in.col, len(in.comments)}
+}
+
+func (in *input) restore(s state) {
+	in.off, in.line, in.col = s.off, s.line, s.col
+	in.comments = in.comments[:s.comments]
+}
+
+func (in *input) errorf(format string, args ...any) {
+	panic(&Error{Pos: in.pos(), Msg: fmt.Sprintf(format, args...)})
+}
+
+func (in *input) eof() bool {
+	return in.off >= len(in.src)
+}
+
+// peek returns the next rune, or -1 at the end of the input.
+func (in *input) peek() rune {
+	if in.eof() {
+		return -1
+	}
+	r, _ := utf8.DecodeRuneInString(in.src[in.off:])
+	return r
+}
+
+// next reads the next rune, or returns -1 at the end of the input.
+func (in *input) next() rune {
+	if in.eof() {
+		return -1
+	}
+	r, n := utf8.DecodeRuneInString(in.src[in.off:])
+	in.off += n
+	if r == '\n' {
+		in.line++
+		in.col = 1
+	} else {
+		in.col++
+	}
+	return r
+}
+
+// at returns true if the input continues with s.
+func (in *input) at(s string) bool {
+	return strings.HasPrefix(in.src[in.off:], s)
+}
+
+// accept reads s if the input continues with it.
+func (in *input) accept(s string) bool {
+	if !in.at(s) {
+		return false
+	}
+	for range s {
+		in.next()
+	}
+	return true
+}
+
+func (in *input) expect(s string) {
+	if !in.accept(s) {
+		in.errorf("expected %q", s)
+	}
+}
+
+// skip skips the whitespace and the comments, that are added to the
+// comments of the input.
+func (in *input) skip() {
+	for !in.eof() {
+		switch {
+		case unicode.IsSpace(in.peek()):
+			in.next()
+		case in.at(in.lineComment):
+			p := in.pos()
+			start := in.off
+			for !in.eof() && in.peek() != '\n' {
+				in.next()
+			}
+			text := strings.TrimRight(in.src[start:in.off], " \t\r")
+			if in.lineComment != "//" {
+				text = "//" + strings.TrimPrefix(text, in.lineComment)
+			}
+			in.comments = append(in.comments, ast.NewComment(p, text))
+		case in.blockComments && in.at("/*"):
+			p := in.pos()
+			start := in.off
+			for !in.accept("*/") {
+				if in.next() < 0 {
+					in.errorf("comment not terminated")
+				}
+			}
+			in.comments = append(in.comments, ast.NewComment(p, in.src[start:in.off]))
+		default:
+			return
+		}
+	}
+}
+
+// ident reads an identifier, with $ accepted in identifiers if dollar is
+// set. It returns an empty string if the input does not continue with an
+// identifier.
+func (in *input) ident(dollar bool) string {
+	start := in.off
+	for !in.eof() {
+		r := in.peek()
+		if r == '_' || unicode.IsLetter(r) || (dollar && r == '$') || (in.off > start && unicode.IsDigit(r)) {
+			in.next()
+			continue
+		}
+		break
+	}
+	return in.src[start:in.off]
+}
+
+// code reads a block of code in braces and returns its content. The
+// braces in the strings and comments of the code are ignored, the strings
+// are delimited by the runes in quotes and the comments are those of the
+// grammar.
+func (in *input) code(quotes string) string {
+	in.expect("{")
+	start := in.off
+	depth := 1
+	for {
+		switch r := in.next(); {
+		case r < 0:
+			in.errorf("code block not terminated")
+		case r == '{':
+			depth++
+		case r == '}':
+			if depth--; depth == 0 {
+				return in.src[start : in.off-1]
+			}
+		case strings.ContainsRune(quotes, r):
+			for c := in.next(); c != r; c = in.next() {
+				if c < 0 {
+					in.errorf("string not terminated")
+				}
+				if c == '\\' {
+					in.next()
+				}
+			}
+		case strings.HasPrefix(in.src[in.off-1:], in.lineComment):
+			for !in.eof() && in.peek() != '\n' {
+				in.next()
+			}
+		case in.blockComments && r == '/' && in.accept("*"):
+			for !in.accept("*/") {
+				if in.next() < 0 {
+					in.errorf("comment not terminated")
+				}
+			}
+		}
+	}
+}
+
+// stringLit reads a string literal delimited by the quote that starts
+// it, with the escapes of JavaScript and Python, and returns its value.
+func (in *input) stringLit() string {
+	q := in.next()
+	var buf strings.Builder
+	for {
+		r := in.next()
+		switch r {
+		case -1, '\n':
+			in.errorf("string literal not terminated")
+		case q:
+			return buf.String()
+		case '\\':
+			in.escape(&buf)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+}
+
+// escape reads the escape sequence that follows a backslash, and writes
+// its value to buf.
+func (in *input) escape(buf *strings.Builder) {
+	r := in.next()
+	switch r {
+	case -1:
+		in.errorf("escape sequence not terminated")
+	case 'n':
+		buf.WriteByte('\n')
+	case 'r':
+		buf.WriteByte('\r')
+	case 't':
+		buf.WriteByte('\t')
+	case 'b':
+		buf.WriteByte('\b')
+	case 'f':
+		buf.WriteByte('\f')
+	case 'v':
+		buf.WriteByte('\v')
+	case '0':
+		buf.WriteByte(0)
+	case '\n':
+		// line continuation
+	case 'x', 'u':
+		var hex string
+		switch {
+		case r == 'u' && in.accept("{"):
+			start := in.off
+			for !in.eof() && in.peek() != '}' {
+				in.next()
+			}
+			hex = in.src[start:in.off]
+			in.expect("}")
+		default:
+			n := 2
+			if r == 'u' {
+				n = 4
+			}
+			start := in.off
+			for i := 0; i < n && !in.eof(); i++ {
+				in.next()
+			}
+			hex = in.src[start:in.off]
+		}
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || v > unicode.MaxRune {
+			in.errorf("invalid escape sequence")
+		}
+		buf.WriteRune(rune(v))
+	default:
+		buf.WriteRune(r)
+	}
+}
+
+// classItems reads the characters and ranges of a character class, after
+// the opening bracket and the optional ^, up to and including the closing
+// bracket.
+func (in *input) classItems() (chars, ranges []rune) {
+	read := func() rune {
+		r := in.next()
+		switch r {
+		case -1, '\n':
+			in.errorf("character class not terminated")
+		case '\\':
+			var buf strings.Builder
+			in.escape(&buf)
+			r, _ = utf8.DecodeRuneInString(buf.String())
+		}
+		return r
+	}
+	for !in.accept("]") {
+		lo := read()
+		if in.at("-") && !in.at("-]") {
+			in.next()
+			ranges = append(ranges, lo, read())
+			continue
+		}
+		chars = append(chars, lo)
+	}
+	return chars, ranges
+}
+
+// ruleName returns name as the name of a rule of a pigeon grammar, with
+// the runes that are not valid in Go identifiers replaced by underscores.
+func ruleName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// label returns name as a label of a pigeon grammar, as ruleName, with an
+// underscore appended to the Go keywords and predeclared identifiers.
+func label(name string) string {
+	name = ruleName(name)
+	if token.IsKeyword(name) || types.Universe.Lookup(name) != nil {
+		name += "_"
+	}
+	return name
+}
+
+// todoCode returns a code block that does not implement the code of the
+// other generator, in language lang, but keeps it in comments, and
+// returns result.
+func todoCode(what, lang, code, result string) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "{\n    // TODO: port the %s %s", lang, what)
+	lines := strings.Split(strings.Trim(code, "\n"), "\n")
+	trimIndent(lines)
+	if strings.TrimSpace(code) != "" {
+		buf.WriteString(":")
+		for _, l := range lines {
+			buf.WriteString("\n    //")
+			if l = strings.TrimRight(l, " \t\r"); l != "" {
+				buf.WriteString("   " + l)
+			}
+		}
+	}
+	if result != "" {
+		buf.WriteString("\n    " + result)
+	}
+	buf.WriteString("\n}")
+	return buf.String()
+}
+
+// trimIndent removes the leading whitespace common to the non-blank
+// lines.
+func trimIndent(lines []string) {
+	prefix := ""
+	first := true
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		indent := l[:len(l)-len(strings.TrimLeft(l, " \t"))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	for i, l := range lines {
+		lines[i] = strings.TrimPrefix(l, prefix)
+	}
+}
+
+// setRuleDocs sets the doc comments of the rules of g, as the pigeon
+// parser does: the comments on the lines that directly precede a rule,
+// at the same column.
+func setRuleDocs(g *ast.Grammar) {
+	ci := 0
+	for _, r := range g.Rules {
+		rp := r.Pos()
+		for ci < len(g.Comments) && g.Comments[ci].Pos().Off < rp.Off {
+			ci++
+		}
+		line := rp.Line
+		var doc []*ast.Comment
+		for i := ci - 1; i >= 0; i-- {
+			c := g.Comments[i]
+			cp := c.Pos()
+			end := cp.Line + strings.Count(c.Val, "\n")
+			if end != line-1 || cp.Col != rp.Col {
+				break
+			}
+			doc = append([]*ast.Comment{c}, doc...)
+			line = cp.Line
+		}
+		r.Doc = doc
+	}
+}
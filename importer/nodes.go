@@ -0,0 +1,124 @@
+package importer
+
+import (
+	"github.com/mna/pigeon/ast"
+)
+
+// The functions below create the nodes of the imported grammars.
+
+func newSeq(p ast.Pos, exprs ...ast.Expression) ast.Expression {
+	if len(exprs) == 1 {
+		return exprs[0]
+	}
+	s := ast.NewSeqExpr(p)
+	s.Exprs = exprs
+	return s
+}
+
+func newChoice(p ast.Pos, alts ...ast.Expression) ast.Expression {
+	if len(alts) == 1 {
+		return alts[0]
+	}
+	c := ast.NewChoiceExpr(p)
+	c.Alternatives = alts
+	return c
+}
+
+func newAction(p ast.Pos, expr ast.Expression, code string) ast.Expression {
+	a := ast.NewActionExpr(p)
+	a.Expr = expr
+	a.Code = ast.NewCodeBlock(p, code)
+	return a
+}
+
+func newLabeled(p ast.Pos, label string, expr ast.Expression) ast.Expression {
+	l := ast.NewLabeledExpr(p)
+	l.Label = ast.NewIdentifier(p, label)
+	l.Expr = expr
+	return l
+}
+
+func newAnd(p ast.Pos, expr ast.Expression) ast.Expression {
+	a := ast.NewAndExpr(p)
+	a.Expr = expr
+	return a
+}
+
+func newNot(p ast.Pos, expr ast.Expression) ast.Expression {
+	n := ast.NewNotExpr(p)
+	n.Expr = expr
+	return n
+}
+
+func newOpt(p ast.Pos, expr ast.Expression) ast.Expression {
+	z := ast.NewZeroOrOneExpr(p)
+	z.Expr = expr
+	return z
+}
+
+func newStar(p ast.Pos, expr ast.Expression) ast.Expression {
+	z := ast.NewZeroOrMoreExpr(p)
+	z.Expr = expr
+	return z
+}
+
+func newPlus(p ast.Pos, expr ast.Expression) ast.Expression {
+	o := ast.NewOneOrMoreExpr(p)
+	o.Expr = expr
+	return o
+}
+
+func newRef(p ast.Pos, name string) ast.Expression {
+	r := ast.NewRuleRefExpr(p)
+	r.Name = ast.NewIdentifier(p, name)
+	return r
+}
+
+func newLit(p ast.Pos, val string, ignoreCase bool) ast.Expression {
+	l := ast.NewLitMatcher(p, val)
+	l.IgnoreCase = ignoreCase
+	return l
+}
+
+func newAndCode(p ast.Pos, code string) ast.Expression {
+	a := ast.NewAndCodeExpr(p)
+	a.Code = ast.NewCodeBlock(p, code)
+	return a
+}
+
+func newNotCode(p ast.Pos, code string) ast.Expression {
+	n := ast.NewNotCodeExpr(p)
+	n.Code = ast.NewCodeBlock(p, code)
+	return n
+}
+
+// newRepetition returns the expression that matches min to max items,
+// or at least min items if max is negative, separated by sep if it is not
+// nil. The functions item and sep return a new node at each call.
+func newRepetition(p ast.Pos, item, sep func() ast.Expression, min, max int) ast.Expression {
+	if max == 0 {
+		return newLit(p, "", false)
+	}
+	if min == 0 {
+		return newOpt(p, newRepetition(p, item, sep, 1, max))
+	}
+
+	// sepItem matches the separator, if any, followed by an item
+	sepItem := func() ast.Expression {
+		if sep == nil {
+			return item()
+		}
+		return newSeq(p, sep(), item())
+	}
+	exprs := []ast.Expression{item()}
+	for i := 1; i < min; i++ {
+		exprs = append(exprs, sepItem())
+	}
+	if max < 0 {
+		exprs = append(exprs, newStar(p, sepItem()))
+	}
+	for i := min; i < max; i++ {
+		exprs = append(exprs, newOpt(p, sepItem()))
+	}
+	return newSeq(p, exprs...)
+}
@@ -0,0 +1,369 @@
+package importer
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/mna/pigeon/ast"
+)
+
+// pyQuotes are the delimiters of the Python strings.
+const pyQuotes = `'"`
+
+// ParsePegen parses the grammar read from r, in the syntax of pegen, the
+// parser generator of CPython, and returns the equivalent pigeon grammar.
+// The meta-information and the actions are replaced by TODO stubs, the
+// cuts ~ are dropped and the gathers s.e+ are expanded. The tokens that
+// the grammar uses but does not define, such as NAME or NEWLINE, get a
+// stub rule at the end of the grammar.
+func ParsePegen(filename string, r io.Reader) (*ast.Grammar, error) {
+	in, err := newInput(filename, r, "#", false)
+	if err != nil {
+		return nil, err
+	}
+	p := &pegenParser{in: in, defined: make(map[string]bool)}
+	return in.parse(p.grammar)
+}
+
+type pegenParser struct {
+	in *input
+
+	// defined are the names of the rules, tokens are the undefined tokens
+	// used by the grammar, in order of first use.
+	defined map[string]bool
+	tokens  []string
+}
+
+func (p *pegenParser) grammar() *ast.Grammar {
+	in := p.in
+	g := ast.NewGrammar(in.pos())
+
+	in.skip()
+	var meta []string
+	pos := in.pos()
+	for in.accept("@") {
+		meta = append(meta, "@"+in.ident(false)+p.metaValue())
+		in.skip()
+	}
+	if len(meta) > 0 {
+		g.Init = ast.NewCodeBlock(pos, todoCode("meta-information", "pegen", strings.Join(meta, "\n"), ""))
+	}
+
+	for !in.eof() {
+		g.Rules = append(g.Rules, p.rule())
+	}
+
+	// stub rules for the undefined tokens, after the end of the source
+	for i, name := range p.tokens {
+		if p.defined[name] {
+			continue
+		}
+		pos := ast.Pos{Filename: in.filename, Line: in.line + 1 + i, Col: 1, Off: len(in.src) + i}
+		r := ast.NewRule(pos, ast.NewIdentifier(pos, name))
+		r.Expr = tokenStub(pos, name)
+		g.Rules = append(g.Rules, r)
+	}
+	return g
+}
+
+// metaValue reads the value of a meta-information, on the same line or
+// in a triple-quoted string, and returns its source.
+func (p *pegenParser) metaValue() string {
+	in := p.in
+	start := in.off
+	for r := in.peek(); r == ' ' || r == '\t'; r = in.peek() {
+		in.next()
+	}
+	for _, q := range []string{`"""`, `'''`} {
+		if in.accept(q) {
+			for !in.accept(q) {
+				if in.next() < 0 {
+					in.errorf("string literal not terminated")
+				}
+			}
+			return in.src[start:in.off]
+		}
+	}
+	for !in.eof() && in.peek() != '\n' && !in.at("#") {
+		if strings.ContainsRune(pyQuotes, in.peek()) {
+			in.stringLit()
+			continue
+		}
+		in.next()
+	}
+	return strings.TrimRight(in.src[start:in.off], " \t\r")
+}
+
+func (p *pegenParser) rule() *ast.Rule {
+	in := p.in
+	pos := in.pos()
+	name := in.ident(false)
+	if name == "" {
+		in.errorf("expected rule name")
+	}
+	p.header()
+	in.expect(":")
+	in.skip()
+
+	p.defined[name] = true
+	r := ast.NewRule(pos, ast.NewIdentifier(pos, name))
+	r.Expr = p.alts()
+	in.skip()
+	return r
+}
+
+// header skips the return type and the memo flag of a rule, that have no
+// equivalent in pigeon.
+func (p *pegenParser) header() {
+	in := p.in
+	in.skip()
+	if in.accept("[") {
+		for depth := 1; depth > 0; {
+			switch in.next() {
+			case -1:
+				in.errorf("rule type not terminated")
+			case '[':
+				depth++
+			case ']':
+				depth--
+			}
+		}
+		in.skip()
+	}
+	if in.accept("(") {
+		in.skip()
+		in.ident(false)
+		in.skip()
+		in.expect(")")
+		in.skip()
+	}
+}
+
+// atRule returns true if the input continues with the start of a rule.
+func (p *pegenParser) atRule() bool {
+	in := p.in
+	st := in.save()
+	defer in.restore(st)
+
+	if in.ident(false) == "" {
+		return false
+	}
+	if r := in.peek(); r != ':' && r != '[' && r != '(' && !unicode.IsSpace(r) {
+		return false
+	}
+	in.skip()
+	if in.at("(") {
+		// a memo flag, not a group
+		in.next()
+		in.skip()
+		if in.ident(false) == "" {
+			return false
+		}
+		in.skip()
+		if !in.accept(")") {
+			return false
+		}
+		in.skip()
+		return in.at(":")
+	}
+	p.header()
+	return in.at(":")
+}
+
+func (p *pegenParser) alts() ast.Expression {
+	in := p.in
+	pos := in.pos()
+	in.accept("|")
+	in.skip()
+	alts := []ast.Expression{p.alt()}
+	for {
+		in.skip()
+		if !in.accept("|") {
+			break
+		}
+		in.skip()
+		alts = append(alts, p.alt())
+	}
+	return newChoice(pos, alts...)
+}
+
+func (p *pegenParser) alt() ast.Expression {
+	in := p.in
+	pos := in.pos()
+	var exprs []ast.Expression
+	for {
+		in.skip()
+		if !p.atItem() {
+			break
+		}
+		if e := p.namedItem(); e != nil {
+			exprs = append(exprs, e)
+		}
+	}
+	if len(exprs) == 0 {
+		in.errorf("expected item")
+	}
+	e := newSeq(pos, exprs...)
+	in.skip()
+	if in.at("{") {
+		e = newAction(pos, e, todoCode("action", "pegen", in.code(pyQuotes), "return nil, nil"))
+	}
+	return e
+}
+
+// atItem returns true if the input continues with an item of an
+// alternative.
+func (p *pegenParser) atItem() bool {
+	switch r := p.in.peek(); {
+	case strings.ContainsRune(`"'([&!~$`, r):
+		return true
+	case r == '_' || unicode.IsLetter(r):
+		return !p.atRule()
+	}
+	return false
+}
+
+func (p *pegenParser) namedItem() ast.Expression {
+	in := p.in
+	pos := in.pos()
+	st := in.save()
+	if name := in.ident(false); name != "" {
+		in.skip()
+		if in.at("=") && !in.at("==") {
+			in.next()
+			in.skip()
+			return newLabeled(pos, label(name), p.item())
+		}
+	}
+	in.restore(st)
+	return p.item()
+}
+
+// item returns the item, or nil for a cut.
+func (p *pegenParser) item() ast.Expression {
+	in := p.in
+	pos := in.pos()
+	switch {
+	case in.accept("&&"):
+		// forced token, matched as a plain token
+		in.skip()
+		return p.item()
+	case in.accept("&"):
+		in.skip()
+		return newAnd(pos, p.item())
+	case in.accept("!"):
+		in.skip()
+		return newNot(pos, p.item())
+	case in.accept("~"):
+		return nil
+	}
+
+	start := in.save()
+	e := p.atom()
+	switch {
+	case in.accept("?"):
+		return newOpt(pos, e)
+	case in.accept("*"):
+		return newStar(pos, e)
+	case in.accept("+"):
+		return newPlus(pos, e)
+	case in.at(".") && !in.at(".."):
+		// gather s.e+, e (s e)*
+		in.next()
+		itemStart := in.save()
+		p.atom()
+		in.expect("+")
+		sep := func() ast.Expression { return p.reparse(start, p.atom) }
+		item := func() ast.Expression { return p.reparse(itemStart, p.atom) }
+		return newRepetition(pos, item, sep, 1, -1)
+	}
+	return e
+}
+
+// reparse parses the input at from with fn again, to get a copy of the
+// expression, and returns to the current position.
+func (p *pegenParser) reparse(from state, fn func() ast.Expression) ast.Expression {
+	in := p.in
+	cur, comments := in.save(), slices.Clone(in.comments)
+	in.restore(from)
+	e := fn()
+	in.restore(cur)
+	in.comments = comments
+	return e
+}
+
+func (p *pegenParser) atom() ast.Expression {
+	in := p.in
+	pos := in.pos()
+	switch in.peek() {
+	case '(':
+		in.next()
+		in.skip()
+		e := p.alts()
+		in.skip()
+		in.expect(")")
+		return e
+	case '[':
+		in.next()
+		in.skip()
+		e := p.alts()
+		in.skip()
+		in.expect("]")
+		return newOpt(pos, e)
+	case '\'', '"':
+		return newLit(pos, in.stringLit(), false)
+	case '$':
+		in.next()
+		return newNot(pos, ast.NewAnyMatcher(pos, "."))
+	}
+	name := in.ident(false)
+	if name == "" {
+		in.errorf("expected item")
+	}
+	if isToken(name) && !slices.Contains(p.tokens, name) {
+		p.tokens = append(p.tokens, name)
+	}
+	return newRef(pos, name)
+}
+
+// isToken returns true if name is the name of a token, in uppercase.
+func isToken(name string) bool {
+	return strings.ToUpper(name) == name && strings.IndexFunc(name, unicode.IsLetter) >= 0
+}
+
+// tokenStub returns the expression of the stub rule of the token name,
+// that approximates the token of the Python tokenizer.
+func tokenStub(p ast.Pos, name string) ast.Expression {
+	class := func(chars, ranges []rune, classes []string, inverted bool) ast.Expression {
+		return ast.NewCharClassMatcherRunes(p, chars, ranges, classes, inverted, false)
+	}
+	digits := func() ast.Expression { return newPlus(p, class(nil, []rune{'0', '9'}, nil, false)) }
+	str := func(q rune) ast.Expression {
+		return newSeq(p,
+			newLit(p, string(q), false),
+			newStar(p, class([]rune{q, '\\', '\n'}, nil, nil, true)),
+			newLit(p, string(q), false))
+	}
+
+	switch name {
+	case "NAME":
+		return newSeq(p,
+			class([]rune{'_'}, nil, []string{"L"}, false),
+			newStar(p, class([]rune{'_'}, nil, []string{"L", "Nd"}, false)))
+	case "NUMBER":
+		return newSeq(p, digits(), newOpt(p, newSeq(p, newLit(p, ".", false), digits())))
+	case "STRING":
+		return newChoice(p, str('"'), str('\''))
+	case "NEWLINE":
+		return newSeq(p, newOpt(p, newLit(p, "\r", false)), newLit(p, "\n", false))
+	case "INDENT":
+		return ast.NewIndentExpr(p, ast.IndentKindIndent)
+	case "DEDENT":
+		return ast.NewIndentExpr(p, ast.IndentKindDedent)
+	case "ENDMARKER":
+		return newNot(p, ast.NewAnyMatcher(p, "."))
+	}
+	return newAndCode(p, "{\n    // TODO: match the "+name+" token\n    return false, nil\n}")
+}
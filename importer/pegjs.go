@@ -0,0 +1,348 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mna/pigeon/ast"
+)
+
+// jsQuotes are the delimiters of the JavaScript strings.
+const jsQuotes = "'\"`"
+
+// ParsePEGjs parses the PEG.js or Peggy grammar read from r and returns
+// the equivalent pigeon grammar. The initializers, actions and semantic
+// predicates are replaced by TODO stubs, the text operator $ by an action
+// that returns the matched text and the plucks @ by an action that
+// returns the plucked values. The repetitions of Peggy are expanded.
+func ParsePEGjs(filename string, r io.Reader) (*ast.Grammar, error) {
+	in, err := newInput(filename, r, "//", true)
+	if err != nil {
+		return nil, err
+	}
+	p := &pegjsParser{in: in}
+	return in.parse(p.grammar)
+}
+
+type pegjsParser struct {
+	in *input
+}
+
+func (p *pegjsParser) grammar() *ast.Grammar {
+	in := p.in
+	g := ast.NewGrammar(in.pos())
+
+	in.skip()
+	var init []string
+	pos := in.pos()
+	for in.at("{") {
+		code := in.code(jsQuotes)
+		if strings.HasPrefix(code, "{") && strings.HasSuffix(code, "}") {
+			// global initializer of Peggy, in double braces
+			code = code[1 : len(code)-1]
+		}
+		init = append(init, code)
+		in.skip()
+		in.accept(";")
+		in.skip()
+	}
+	if len(init) > 0 {
+		g.Init = ast.NewCodeBlock(pos, todoCode("initializer", "JavaScript", strings.Join(init, "\n"), ""))
+	}
+
+	for !in.eof() {
+		g.Rules = append(g.Rules, p.rule())
+	}
+	return g
+}
+
+func (p *pegjsParser) rule() *ast.Rule {
+	in := p.in
+	pos := in.pos()
+	name := in.ident(true)
+	if name == "" {
+		in.errorf("expected rule name")
+	}
+	r := ast.NewRule(pos, ast.NewIdentifier(pos, ruleName(name)))
+	in.skip()
+	if q := in.peek(); q == '"' || q == '\'' {
+		dpos := in.pos()
+		r.DisplayName = ast.NewStringLit(dpos, strconv.Quote(in.stringLit()))
+		in.skip()
+	}
+	in.expect("=")
+	in.skip()
+	r.Expr = p.choice()
+	in.skip()
+	in.accept(";")
+	in.skip()
+	return r
+}
+
+// atRule returns true if the input continues with the start of a rule.
+func (p *pegjsParser) atRule() bool {
+	in := p.in
+	st := in.save()
+	defer in.restore(st)
+
+	if in.ident(true) == "" {
+		return false
+	}
+	in.skip()
+	if q := in.peek(); q == '"' || q == '\'' {
+		in.stringLit()
+		in.skip()
+	}
+	return in.at("=")
+}
+
+func (p *pegjsParser) choice() ast.Expression {
+	in := p.in
+	pos := in.pos()
+	alts := []ast.Expression{p.action()}
+	for {
+		in.skip()
+		if !in.accept("/") {
+			break
+		}
+		in.skip()
+		alts = append(alts, p.action())
+	}
+	return newChoice(pos, alts...)
+}
+
+func (p *pegjsParser) action() ast.Expression {
+	in := p.in
+	pos := in.pos()
+	expr, plucks := p.sequence()
+	in.skip()
+	if in.at("{") {
+		code := in.code(jsQuotes)
+		return newAction(pos, expr, todoCode("action", "JavaScript", code, "return nil, nil"))
+	}
+	switch len(plucks) {
+	case 0:
+		return expr
+	case 1:
+		return newAction(pos, expr, "{ return "+plucks[0]+", nil }")
+	default:
+		return newAction(pos, expr, "{ return []any{"+strings.Join(plucks, ", ")+"}, nil }")
+	}
+}
+
+// sequence returns the sequence and the labels of its plucked elements.
+func (p *pegjsParser) sequence() (ast.Expression, []string) {
+	in := p.in
+	pos := in.pos()
+	var exprs []ast.Expression
+	var plucks []string
+	for {
+		in.skip()
+		if !p.atElement() {
+			break
+		}
+		epos := in.pos()
+		pluck := in.accept("@")
+		lbl := p.label()
+		e := p.prefixed()
+		if pluck && lbl == "" {
+			lbl = fmt.Sprintf("p%d", len(plucks)+1)
+		}
+		if lbl != "" {
+			e = newLabeled(epos, lbl, e)
+		}
+		if pluck {
+			plucks = append(plucks, lbl)
+		}
+		exprs = append(exprs, e)
+	}
+	if len(exprs) == 0 {
+		in.errorf("expected expression")
+	}
+	return newSeq(pos, exprs...), plucks
+}
+
+// atElement returns true if the input continues with an element of a
+// sequence.
+func (p *pegjsParser) atElement() bool {
+	switch r := p.in.peek(); {
+	case strings.ContainsRune(`"'[.(&!$@`, r):
+		return true
+	case r == '_' || unicode.IsLetter(r):
+		return !p.atRule()
+	}
+	return false
+}
+
+// label reads the label of an element, if any, and returns it as a label
+// of a pigeon grammar.
+func (p *pegjsParser) label() string {
+	in := p.in
+	st := in.save()
+	if name := in.ident(true); name != "" {
+		in.skip()
+		if in.accept(":") {
+			in.skip()
+			return label(name)
+		}
+	}
+	in.restore(st)
+	return ""
+}
+
+func (p *pegjsParser) prefixed() ast.Expression {
+	in := p.in
+	pos := in.pos()
+	switch {
+	case in.accept("$"):
+		in.skip()
+		return newAction(pos, p.suffixed(), "{ return string(c.text), nil }")
+	case in.accept("&"):
+		in.skip()
+		if in.at("{") {
+			return newAndCode(pos, todoCode("predicate", "JavaScript", in.code(jsQuotes), "return true, nil"))
+		}
+		return newAnd(pos, p.suffixed())
+	case in.accept("!"):
+		in.skip()
+		if in.at("{") {
+			return newNotCode(pos, todoCode("predicate", "JavaScript", in.code(jsQuotes), "return false, nil"))
+		}
+		return newNot(pos, p.suffixed())
+	}
+	return p.suffixed()
+}
+
+func (p *pegjsParser) suffixed() ast.Expression {
+	in := p.in
+	pos := in.pos()
+	start := in.save()
+	e := p.primary()
+
+	st := in.save()
+	in.skip()
+	switch {
+	case in.accept("?"):
+		return newOpt(pos, e)
+	case in.accept("*"):
+		return newStar(pos, e)
+	case in.accept("+"):
+		return newPlus(pos, e)
+	case p.atRepetition():
+		in.next()
+		return p.repetition(pos, start)
+	}
+	in.restore(st)
+	return e
+}
+
+// atRepetition returns true if the input continues with the bounds of a
+// Peggy repetition, and not with the closing | of a repetition separator.
+func (p *pegjsParser) atRepetition() bool {
+	in := p.in
+	st := in.save()
+	defer in.restore(st)
+
+	if !in.accept("|") {
+		return false
+	}
+	in.skip()
+	r := in.peek()
+	return (r >= '0' && r <= '9') || r == '{' || in.at("..")
+}
+
+// repetition reads the bounds and the separator of a Peggy repetition,
+// after the opening |, of the primary expression that starts at start.
+func (p *pegjsParser) repetition(pos ast.Pos, start state) ast.Expression {
+	in := p.in
+	in.skip()
+	if in.at("{") {
+		in.errorf("repetition bounds in code blocks are not supported")
+	}
+	min, max := 0, -1
+	n, ok := p.number()
+	in.skip()
+	if in.accept("..") {
+		in.skip()
+		if ok {
+			min = n
+		}
+		if m, ok := p.number(); ok {
+			max = m
+		}
+	} else {
+		if !ok {
+			in.errorf("expected repetition bounds")
+		}
+		min, max = n, n
+	}
+	in.skip()
+
+	var sep func() ast.Expression
+	if in.accept(",") {
+		in.skip()
+		sepStart := in.save()
+		p.choice()
+		sep = func() ast.Expression { return p.reparse(sepStart, p.choice) }
+		in.skip()
+	}
+	in.expect("|")
+	item := func() ast.Expression { return p.reparse(start, p.primary) }
+	return newRepetition(pos, item, sep, min, max)
+}
+
+// reparse parses the input at from with fn again, to get a copy of the
+// expression, and returns to the current position.
+func (p *pegjsParser) reparse(from state, fn func() ast.Expression) ast.Expression {
+	in := p.in
+	cur, comments := in.save(), slices.Clone(in.comments)
+	in.restore(from)
+	e := fn()
+	in.restore(cur)
+	in.comments = comments
+	return e
+}
+
+func (p *pegjsParser) number() (int, bool) {
+	in := p.in
+	start := in.off
+	for r := in.peek(); r >= '0' && r <= '9'; r = in.peek() {
+		in.next()
+	}
+	n, err := strconv.Atoi(in.src[start:in.off])
+	return n, err == nil
+}
+
+func (p *pegjsParser) primary() ast.Expression {
+	in := p.in
+	pos := in.pos()
+	switch in.peek() {
+	case '"', '\'':
+		val := in.stringLit()
+		return newLit(pos, val, in.accept("i"))
+	case '[':
+		in.next()
+		inverted := in.accept("^")
+		chars, ranges := in.classItems()
+		return ast.NewCharClassMatcherRunes(pos, chars, ranges, nil, inverted, in.accept("i"))
+	case '.':
+		in.next()
+		return ast.NewAnyMatcher(pos, ".")
+	case '(':
+		in.next()
+		in.skip()
+		e := p.choice()
+		in.skip()
+		in.expect(")")
+		return e
+	}
+	name := in.ident(true)
+	if name == "" {
+		in.errorf("expected expression")
+	}
+	return newRef(pos, ruleName(name))
+}
@@ -0,0 +1,105 @@
+package interp
+
+import (
+	"fmt"
+
+	"github.com/mna/pigeon/ast"
+)
+
+// Action is a callback that replaces the code blocks of an alternative of a
+// rule. It is called when the alternative matches, and returns the value of
+// the match. If it returns an error, the error is recorded and the parse
+// goes on with the returned value, as for the actions of the generated
+// parsers.
+type Action func(c *Context) (any, error)
+
+// Context is the state of the parser when an Action is called.
+type Context struct {
+	// Rule and Alt are the name of the rule and the index of the
+	// alternative that matched.
+	Rule string
+	Alt  int
+
+	// Pos is the start position of the match and Text the matched input.
+	Pos  Position
+	Text []byte
+
+	// Value is the value of the alternative, as it would be returned
+	// without the action.
+	Value any
+
+	// GlobalStore is the store of the parse, shared by all the actions and
+	// initialized by the GlobalStore option.
+	GlobalStore map[string]any
+
+	labels map[string]any
+}
+
+// Label returns the value of the labeled expression name of the
+// alternative, or nil if it did not match.
+func (c *Context) Label(name string) any {
+	return c.labels[name]
+}
+
+// Register registers fn as the action of the alternative alt of the rule,
+// alt being the index of the alternative in the top-level choice of the
+// rule, or 0 if the rule has no choice. The action is called whether or
+// not the alternative has a code block in the grammar, and replaces the
+// previous action of the alternative, if any. A nil fn removes the action.
+//
+// Actions must be registered before the parser is used.
+func (p *Parser) Register(rule string, alt int, fn Action) error {
+	r := p.rules[rule]
+	if r == nil {
+		return fmt.Errorf("undefined rule: %s", rule)
+	}
+	alts := []ast.Expression{r.Expr}
+	if ch, ok := r.Expr.(*ast.ChoiceExpr); ok {
+		alts = ch.Alternatives
+	}
+	if alt < 0 || alt >= len(alts) {
+		return fmt.Errorf("rule %s has no alternative %d", rule, alt)
+	}
+
+	if p.actions == nil {
+		p.actions = make(map[ast.Expression]*action)
+	}
+	if fn == nil {
+		delete(p.actions, alts[alt])
+		return nil
+	}
+	p.actions[alts[alt]] = &action{rule: rule, alt: alt, fn: fn}
+	return nil
+}
+
+// action is a registered Action, with its alternative.
+type action struct {
+	rule string
+	alt  int
+	fn   Action
+}
+
+// parseAction parses the alternative expr and calls its action if it
+// matches.
+func (p *parser) parseAction(expr ast.Expression, act *action) (any, bool) {
+	start := p.pt
+	val, ok := p.parseNode(expr)
+	if !ok {
+		return nil, false
+	}
+
+	c := &Context{
+		Rule:        act.rule,
+		Alt:         act.alt,
+		Pos:         start.Position,
+		Text:        p.sliceFrom(start),
+		Value:       val,
+		GlobalStore: p.globalStore,
+		labels:      p.vstack[len(p.vstack)-1],
+	}
+	actVal, err := act.fn(c)
+	if err != nil {
+		p.addErrAt(err, start.Position, []string{})
+	}
+	return actVal, true
+}
@@ -0,0 +1,74 @@
+package interp
+
+import (
+	"fmt"
+	"slices"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/cover"
+)
+
+// Coverage creates an Option to gather the coverage of the grammar by
+// the parse in cov, as the Coverage option of the generated parsers.
+// The coverage of several parses can be gathered in the same cov.
+//
+// The default is nil, no coverage.
+func Coverage(cov *cover.Profile) Option {
+	return func(p *parser) Option {
+		old := p.cov
+		p.cov = cov
+		if cov != nil {
+			if cov.Rules == nil {
+				cov.Rules = make(map[string]int64)
+			}
+			if cov.Alternatives == nil {
+				cov.Alternatives = make(map[string]int64)
+			}
+			if cov.Classes == nil {
+				cov.Classes = make(map[string]int64)
+			}
+		}
+		return Coverage(old)
+	}
+}
+
+// coverKey returns the key of the expression at pos in the coverage
+// profile.
+func (p *parser) coverKey(pos ast.Pos, i int) string {
+	return fmt.Sprintf("%s %d:%d %d", p.rstack[len(p.rstack)-1].Name.Val, pos.Line, pos.Col, i)
+}
+
+// coverClass records in the coverage profile the branch of the
+// character class chr that contains the current character, and whether
+// chr matches it.
+func (p *parser) coverClass(chr *ast.CharClassMatcher) {
+	cl := p.classes[chr]
+	cur := p.pt.rn
+	if cur == utf8.RuneError && p.pt.w == 0 {
+		return
+	}
+	if chr.IgnoreCase {
+		cur = unicode.ToLower(cur)
+	}
+
+	branch := slices.Index(cl.chars, cur) + 1
+	for i := 0; branch == 0 && i < len(cl.ranges); i += 2 {
+		if cur >= cl.ranges[i] && cur <= cl.ranges[i+1] {
+			branch = len(cl.chars) + i/2 + 1
+		}
+	}
+	for i := 0; branch == 0 && i < len(cl.classes); i++ {
+		if unicode.Is(cl.classes[i], cur) {
+			branch = len(cl.chars) + len(cl.ranges)/2 + i + 1
+		}
+	}
+
+	if branch > 0 {
+		p.cov.Classes[p.coverKey(chr.Pos(), branch)]++
+	}
+	if (branch > 0) != chr.Inverted {
+		p.cov.Classes[p.coverKey(chr.Pos(), 0)]++
+	}
+}
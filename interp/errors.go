@@ -0,0 +1,77 @@
+package interp
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Error is an error found by the parser, with the position and the rule
+// at which it occurred. The original error is stored in the Inner field.
+type Error struct {
+	Inner    error
+	Filename string
+	Pos      Position
+
+	// Rule is the display name or the name of the rule being parsed, if
+	// any.
+	Rule string
+
+	// Expected is the sorted list of the matchers expected at Pos, when
+	// no rule matched the input.
+	Expected []string
+}
+
+// Error returns the error message, in the format of the generated parsers.
+func (e *Error) Error() string {
+	var buf strings.Builder
+	if e.Filename != "" {
+		buf.WriteString(e.Filename + ":")
+	}
+	buf.WriteString(strconv.Itoa(e.Pos.Line) + ":" + strconv.Itoa(e.Pos.Col) + " (" + strconv.Itoa(e.Pos.Offset) + ")")
+	if e.Rule != "" {
+		buf.WriteString(": rule " + e.Rule)
+	}
+	return buf.String() + ": " + e.Inner.Error()
+}
+
+// Unwrap returns the original error.
+func (e *Error) Unwrap() error {
+	return e.Inner
+}
+
+// ErrorList is the list of the errors found by the parser.
+type ErrorList []error
+
+func (e ErrorList) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e.dedupe()
+}
+
+// dedupe returns the list without the errors with the same message as a
+// previous one.
+func (e ErrorList) dedupe() ErrorList {
+	var cleaned ErrorList
+	set := make(map[string]bool)
+	for _, err := range e {
+		if msg := err.Error(); !set[msg] {
+			set[msg] = true
+			cleaned = append(cleaned, err)
+		}
+	}
+	return cleaned
+}
+
+// Unwrap returns the errors of the list.
+func (e ErrorList) Unwrap() []error {
+	return e
+}
+
+func (e ErrorList) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
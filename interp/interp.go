@@ -0,0 +1,402 @@
+// Package interp parses input with a grammar at run time, by interpreting
+// its AST instead of generating a parser.
+//
+// The interpreter follows the semantics of the generated parsers: it
+// returns the same values and reports the same errors, and supports the
+// back-references, the indentation expressions, the recovery expressions,
+// the precedence tables and the left recursion. The code blocks of the
+// grammar are not run: an action returns the value of its expression, the
+// code predicates match and the state code blocks do nothing.
+//
+// The actions are replaced by Go callbacks registered by rule name and
+// alternative, so that a grammar supplied at run time can build values:
+//
+//	g, err := parse.ParseGrammar("sum.peg", strings.NewReader(`
+//		Sum ← a:Num '+' b:Num / Num
+//		Num ← [0-9]+
+//	`))
+//	if err != nil {
+//		return err
+//	}
+//	p, err := interp.New(g)
+//	if err != nil {
+//		return err
+//	}
+//	p.Register("Sum", 0, func(c *interp.Context) (any, error) {
+//		return c.Label("a").(int) + c.Label("b").(int), nil
+//	})
+//	p.Register("Num", 0, func(c *interp.Context) (any, error) {
+//		return strconv.Atoi(string(c.Text))
+//	})
+//	v, err := p.Parse("input", []byte("1+2"))
+//
+// Besides the value, ParseTree returns the concrete syntax tree of the
+// input, with a node for each match of a rule.
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/builder"
+)
+
+var (
+	// errNoRule is returned when the grammar to parse has no rule.
+	errNoRule = errors.New("grammar has no rule")
+
+	// errInvalidEntrypoint is returned when the specified entrypoint rule
+	// does not exit.
+	errInvalidEntrypoint = errors.New("invalid entrypoint")
+
+	// errInvalidEncoding is returned when the source is not properly
+	// utf8-encoded.
+	errInvalidEncoding = errors.New("invalid encoding")
+
+	// errMaxExprCnt is used to signal that the maximum number of
+	// expressions have been parsed.
+	errMaxExprCnt = errors.New("max number of expressions parsed")
+
+	// errInconsistentIndent is returned when comparing the indentation
+	// of a line to the current indentation level depends on the width of
+	// a tab.
+	errInconsistentIndent = errors.New("inconsistent use of tabs and spaces in indentation")
+)
+
+// Parser parses input with a grammar. It is safe for concurrent use once
+// its actions are registered.
+type Parser struct {
+	g     *ast.Grammar
+	rules map[string]*ast.Rule
+
+	// the matchers of the grammar prepared for matching, the rules that
+	// contain back-references and the labels they refer to, and the rules
+	// that are always memoized or that are commit points
+	lits     map[*ast.LitMatcher]*litMatcher
+	classes  map[*ast.CharClassMatcher]*classMatcher
+	backRef  map[*ast.Rule]bool
+	captured map[*ast.LabeledExpr]bool
+	memoized map[*ast.Rule]bool
+	commits  map[*ast.Rule]bool
+
+	// the precedence tables, by expression of their rule
+	precedences map[ast.Expression]*precedence
+
+	// the registered actions, by alternative
+	actions map[ast.Expression]*action
+}
+
+type litMatcher struct {
+	val  string
+	want string
+}
+
+type classMatcher struct {
+	chars   []rune
+	ranges  []rune
+	classes []*unicode.RangeTable
+}
+
+// New returns the parser of the grammar g. It returns an error if g refers
+// to undefined rules or Unicode classes, if its precedence tables are
+// invalid, or if its left recursion cannot be parsed. The parser keeps g,
+// that must not be modified afterwards.
+func New(g *ast.Grammar) (*Parser, error) {
+	p := &Parser{
+		g:        g,
+		rules:    make(map[string]*ast.Rule, len(g.Rules)),
+		lits:     make(map[*ast.LitMatcher]*litMatcher),
+		classes:  make(map[*ast.CharClassMatcher]*classMatcher),
+		backRef:  make(map[*ast.Rule]bool),
+		captured: make(map[*ast.LabeledExpr]bool),
+		memoized: make(map[*ast.Rule]bool),
+		commits:  make(map[*ast.Rule]bool),
+
+		precedences: make(map[ast.Expression]*precedence),
+	}
+	for _, r := range g.Rules {
+		p.rules[r.Name.Val] = r
+		if r.Memoized() {
+			p.memoized[r] = true
+		}
+		if r.HasAnnotation(ast.CommitAnnotation) {
+			p.commits[r] = true
+		}
+	}
+
+	var err error
+	for _, r := range g.Rules {
+		refs := make(map[string]bool)
+		var labels []*ast.LabeledExpr
+		ast.Inspect(r.Expr, func(expr ast.Expression) bool {
+			switch expr := expr.(type) {
+			case *ast.RuleRefExpr:
+				if p.rules[expr.Name.Val] == nil && err == nil {
+					err = fmt.Errorf("%s: undefined rule: %s", expr.Pos(), expr.Name.Val)
+				}
+			case *ast.LitMatcher:
+				lit := &litMatcher{val: expr.Val, want: strconv.Quote(expr.Val)}
+				if expr.IgnoreCase {
+					lit.val = strings.ToLower(expr.Val)
+					lit.want += "i"
+				}
+				p.lits[expr] = lit
+			case *ast.CharClassMatcher:
+				cl, cerr := newClassMatcher(expr)
+				if cerr != nil && err == nil {
+					err = cerr
+				}
+				p.classes[expr] = cl
+			case *ast.BackRefExpr:
+				p.backRef[r] = true
+				refs[expr.Label.Val] = true
+			case *ast.LabeledExpr:
+				labels = append(labels, expr)
+			}
+			return true
+		})
+		for _, lab := range labels {
+			if lab.Label != nil && refs[lab.Label.Val] {
+				p.captured[lab] = true
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range g.Rules {
+		prec, err := p.newPrecedence(r)
+		if err != nil {
+			return nil, err
+		}
+		if prec != nil {
+			p.precedences[r.Expr] = prec
+		}
+	}
+
+	if _, err := builder.PrepareGrammar(g); err != nil {
+		return nil, fmt.Errorf("incorrect grammar: %w", err)
+	}
+	return p, nil
+}
+
+func newClassMatcher(ch *ast.CharClassMatcher) (*classMatcher, error) {
+	cl := &classMatcher{chars: ch.Chars, ranges: ch.Ranges}
+	if ch.IgnoreCase {
+		cl.chars = make([]rune, len(ch.Chars))
+		for i, rn := range ch.Chars {
+			cl.chars[i] = unicode.ToLower(rn)
+		}
+		cl.ranges = make([]rune, len(ch.Ranges))
+		for i, rn := range ch.Ranges {
+			cl.ranges[i] = unicode.ToLower(rn)
+		}
+	}
+	for _, name := range ch.UnicodeClasses {
+		rt := rangeTable(name)
+		if rt == nil {
+			return nil, fmt.Errorf("%s: invalid Unicode class: %s", ch.Pos(), name)
+		}
+		cl.classes = append(cl.classes, rt)
+	}
+	return cl, nil
+}
+
+// rangeTable returns the Unicode range table of the class, or nil if it
+// does not exist.
+func rangeTable(class string) *unicode.RangeTable {
+	if rt, ok := unicode.Categories[class]; ok {
+		return rt
+	}
+	if rt, ok := unicode.Properties[class]; ok {
+		return rt
+	}
+	return unicode.Scripts[class]
+}
+
+// Parse parses the data from b using filename as information in the
+// error messages, and returns the value of the entrypoint rule.
+func (p *Parser) Parse(filename string, b []byte, opts ...Option) (any, error) {
+	return newParser(p, filename, b, false, opts...).parse()
+}
+
+// ParseFile parses the file identified by filename, and returns the value
+// of the entrypoint rule.
+func (p *Parser) ParseFile(filename string, opts ...Option) (any, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return p.Parse(filename, b, opts...)
+}
+
+// ParseReader parses the data from r using filename as information in the
+// error messages, and returns the value of the entrypoint rule.
+func (p *Parser) ParseReader(filename string, r io.Reader, opts ...Option) (any, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return p.Parse(filename, b, opts...)
+}
+
+// ParseTree parses the data from b as Parse, and returns the concrete
+// syntax tree of the match of the entrypoint rule.
+func (p *Parser) ParseTree(filename string, b []byte, opts ...Option) (*Node, error) {
+	ps := newParser(p, filename, b, true, opts...)
+	_, err := ps.parse()
+	if len(ps.children) == 0 {
+		return nil, err
+	}
+	return ps.children[0], err
+}
+
+// Option is a function that can set an option on the parser. It returns
+// the previous setting as an Option.
+type Option func(*parser) Option
+
+// MaxExpressions creates an Option to stop parsing after the provided
+// number of expressions have been parsed, if the value is 0 then the
+// parser will parse for as many steps as needed (possibly an infinite
+// number).
+//
+// The default for maxExprCnt is 0.
+func MaxExpressions(maxExprCnt uint64) Option {
+	return func(p *parser) Option {
+		oldMaxExprCnt := p.maxExprCnt
+		p.maxExprCnt = maxExprCnt
+		return MaxExpressions(oldMaxExprCnt)
+	}
+}
+
+// Entrypoint creates an Option to set the rule name to use as entrypoint.
+// The rule name must have been specified in the grammar. If no entrypoint
+// is specified, the first rule of the grammar is used.
+func Entrypoint(ruleName string) Option {
+	return func(p *parser) Option {
+		oldEntrypoint := p.entrypoint
+		p.entrypoint = ruleName
+		return Entrypoint(oldEntrypoint)
+	}
+}
+
+// Memoize creates an Option to set the memoize flag to b. When set to
+// true, the parser will cache all results so each expression is evaluated
+// only once. The results of the rules with the @memoize annotation, see
+// ast.Rule.Memoized, are cached even if b is false.
+//
+// The default is false.
+func Memoize(b bool) Option {
+	return func(p *parser) Option {
+		old := p.memoize
+		p.memoize = b
+		return Memoize(old)
+	}
+}
+
+// MemoWindow creates an Option to bound the memory of the memoization
+// table: the results are kept only for the n offsets behind the furthest
+// offset where a result was cached. The rules with the @commit annotation
+// also discard the results before the end of their match. If n is 0, all
+// the results are kept.
+//
+// The default is 0.
+func MemoWindow(n int) Option {
+	return func(p *parser) Option {
+		old := p.memoWindow
+		p.memoWindow = n
+		return MemoWindow(old)
+	}
+}
+
+// AllowInvalidUTF8 creates an Option to allow invalid UTF-8 bytes. Every
+// invalid UTF-8 byte is treated as a utf8.RuneError (U+FFFD) by character
+// class matchers and is matched by the any matcher.
+//
+// The default is false.
+func AllowInvalidUTF8(b bool) Option {
+	return func(p *parser) Option {
+		old := p.allowInvalidUTF8
+		p.allowInvalidUTF8 = b
+		return AllowInvalidUTF8(old)
+	}
+}
+
+// TabWidth creates an Option to set the number of columns a tab advances
+// to when computing the width of the indentation for the %INDENT, %DEDENT
+// and %SAMEDENT expressions. Values lower than 1 are treated as 1.
+//
+// The default is 8.
+func TabWidth(n int) Option {
+	return func(p *parser) Option {
+		old := p.tabWidth
+		p.tabWidth = max(n, 1)
+		return TabWidth(old)
+	}
+}
+
+// GlobalStore creates an Option to set a key to a certain value in the
+// global store of the parse, available to the actions.
+func GlobalStore(key string, value any) Option {
+	return func(p *parser) Option {
+		old := p.globalStore[key]
+		p.globalStore[key] = value
+		return GlobalStore(key, old)
+	}
+}
+
+// Recover creates an Option to set the recover flag to b. When set to
+// true, this causes the parser to recover from panics and convert it to
+// an error.
+//
+// The default is true.
+func Recover(b bool) Option {
+	return func(p *parser) Option {
+		old := p.recover
+		p.recover = b
+		return Recover(old)
+	}
+}
+
+// Position is a position in the input.
+type Position struct {
+	Line, Col, Offset int
+}
+
+func (p Position) String() string {
+	return strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Col) + " [" + strconv.Itoa(p.Offset) + "]"
+}
+
+// Node is a node of the concrete syntax tree: the match of a rule, with
+// the matches of the rules it invokes as children.
+type Node struct {
+	Rule       string
+	Start, End Position
+	Text       string
+	Children   []*Node
+
+	// Value is the value returned by the rule.
+	Value any
+}
+
+// String returns the tree rooted at n, a node per line indented by its
+// depth, with the rule, the positions and the text of the match.
+func (n *Node) String() string {
+	var buf strings.Builder
+	n.write(&buf, 0)
+	return buf.String()
+}
+
+func (n *Node) write(buf *strings.Builder, depth int) {
+	fmt.Fprintf(buf, "%s%s %d:%d-%d:%d %q\n", strings.Repeat("  ", depth),
+		n.Rule, n.Start.Line, n.Start.Col, n.End.Line, n.End.Col, n.Text)
+	for _, c := range n.Children {
+		c.write(buf, depth+1)
+	}
+}
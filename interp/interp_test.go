@@ -0,0 +1,328 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/importer"
+	"github.com/mna/pigeon/parse"
+)
+
+// newTestParser returns the parser of the grammar src, in the PEG.js
+// syntax.
+func newTestParser(t *testing.T, src string) *Parser {
+	t.Helper()
+	g, err := importer.ParsePEGjs("test.pegjs", strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := New(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// snapshot formats the values returned by the parser.
+func snapshot(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case []byte:
+		return fmt.Sprintf("%q", v)
+	case []any:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			parts[i] = snapshot(e)
+		}
+		return "[" + strings.Join(parts, " ") + "]"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func TestParse(t *testing.T) {
+	p := newTestParser(t, `
+list = items !.
+items = head:item tail:("," item)*
+item = num / "null"i / "(" items ")"
+num = "-"? [0-9]+ &{ return false; }
+`)
+	cases := []struct {
+		in, want, err string
+	}{
+		{in: "1", want: `[[[nil ["1"] nil] []] nil]`},
+		{in: "-12,NULL", want: `[[["-" ["1" "2"] nil] [["," "NULL"]]] nil]`},
+		{in: "(1,2)", want: `[[["(" [[nil ["1"] nil] [["," [nil ["2"] nil]]]] ")"] []] nil]`},
+		{in: "", err: `test:1:1 (0): no match found, expected: "(", "-", "null"i or [0-9]`},
+		{in: "1,", err: `test:1:3 (2): no match found, expected: "(", "-", "null"i or [0-9]`},
+		{in: "1 2", err: `test:1:2 (1): no match found, expected: ",", [0-9] or EOF`},
+		{in: "\xff", err: "test:1:1 (0): invalid encoding"},
+	}
+	for _, opts := range [][]Option{{Memoize(false)}, {Memoize(true)}, {Memoize(true), MemoWindow(2)}} {
+		for _, c := range cases {
+			v, err := p.Parse("test", []byte(c.in), opts...)
+			var got string
+			if err != nil {
+				got = err.Error()
+			}
+			if got != c.err {
+				t.Errorf("%q: want error %q, got %q", c.in, c.err, got)
+				continue
+			}
+			if err == nil && snapshot(v) != c.want {
+				t.Errorf("%q: want %s, got %s", c.in, c.want, snapshot(v))
+			}
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	p := newTestParser(t, `
+a = "x" b
+b = "y" b / "z"
+`)
+	_, err := p.Parse("", []byte("xq"), Entrypoint("c"))
+	if err == nil || err.Error() != "1:0 (0): invalid entrypoint" {
+		t.Errorf("want invalid entrypoint, got %v", err)
+	}
+
+	_, err = p.Parse("", []byte("xyyyz"), MaxExpressions(5))
+	if !errors.Is(err, errMaxExprCnt) {
+		t.Errorf("want max expressions error, got %v", err)
+	}
+
+	_, err = p.Parse("f", []byte("xq"))
+	var perr *Error
+	if !errors.As(err, &perr) {
+		t.Fatalf("want an *Error, got %T", err)
+	}
+	want := Error{Filename: "f", Pos: Position{Line: 1, Col: 2, Offset: 1}, Expected: []string{`"y"`, `"z"`}}
+	if perr.Filename != want.Filename || perr.Pos != want.Pos || perr.Rule != want.Rule || strings.Join(perr.Expected, " ") != strings.Join(want.Expected, " ") {
+		t.Errorf("want %+v, got %+v", want, *perr)
+	}
+}
+
+func TestParseTree(t *testing.T) {
+	p := newTestParser(t, `
+sum = sum "+" num / num
+num = [0-9]+
+`)
+	n, err := p.ParseTree("", []byte("1+22+3"), Memoize(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `sum 1:1-1:7 "1+22+3"
+  sum 1:1-1:5 "1+22"
+    sum 1:1-1:2 "1"
+      num 1:1-1:2 "1"
+    num 1:3-1:5 "22"
+  num 1:6-1:7 "3"
+`
+	if got := n.String(); got != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+	if got := snapshot(n.Value); got != `[[["1"] "+" ["2" "2"]] "+" ["3"]]` {
+		t.Errorf("want the value of sum, got %s", got)
+	}
+
+	// the results of the left-recursive rules are not evicted by the window
+	n, err = p.ParseTree("", []byte("1+22+3"), Memoize(true), MemoWindow(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := n.String(); got != want {
+		t.Errorf("want with a window\n%s\ngot\n%s", want, got)
+	}
+
+	// the nodes of the failed alternatives are dropped
+	p = newTestParser(t, `
+a = b "x" / b "y"
+b = "b"
+`)
+	n, err = p.ParseTree("", []byte("by"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `a 1:1-1:3 "by"
+  b 1:1-1:2 "b"
+`
+	if got := n.String(); got != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestNew(t *testing.T) {
+	g := ast.NewGrammar(ast.Pos{})
+	r := ast.NewRule(ast.Pos{Line: 1, Col: 1}, ast.NewIdentifier(ast.Pos{Line: 1, Col: 1}, "a"))
+	ref := ast.NewRuleRefExpr(ast.Pos{Line: 1, Col: 5})
+	ref.Name = ast.NewIdentifier(ast.Pos{Line: 1, Col: 5}, "b")
+	r.Expr = ref
+	g.Rules = append(g.Rules, r)
+	if _, err := New(g); err == nil || err.Error() != "1:5 (0): undefined rule: b" {
+		t.Errorf("want undefined rule error, got %v", err)
+	}
+
+	r.Expr = ast.NewCharClassMatcherRunes(ast.Pos{Line: 1, Col: 5}, nil, nil, []string{"Nope"}, false, false)
+	if _, err := New(g); err == nil || err.Error() != "1:5 (0): invalid Unicode class: Nope" {
+		t.Errorf("want invalid Unicode class error, got %v", err)
+	}
+}
+
+func TestActions(t *testing.T) {
+	p := newTestParser(t, `
+expr = a:term "+" b:expr { return a + b; } / term
+term = "(" e:expr ")" / num
+num = [0-9]+ / "x"
+`)
+	register := func(rule string, alt int, fn Action) {
+		t.Helper()
+		if err := p.Register(rule, alt, fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	register("expr", 0, func(c *Context) (any, error) {
+		return c.Label("a").(int) + c.Label("b").(int), nil
+	})
+	register("term", 0, func(c *Context) (any, error) {
+		return c.Label("e"), nil
+	})
+	register("num", 0, func(c *Context) (any, error) {
+		return strconv.Atoi(string(c.Text))
+	})
+	register("num", 1, func(c *Context) (any, error) {
+		c.GlobalStore["x"] = c.GlobalStore["x"].(int) + 1
+		return c.GlobalStore["x"], fmt.Errorf("%s %d at %d: x is deprecated", c.Rule, c.Alt, c.Pos.Offset)
+	})
+
+	v, err := p.Parse("", []byte("1+(2+30)"))
+	if err != nil || v != 33 {
+		t.Errorf("want 33, got %v, %v", v, err)
+	}
+
+	// the actions run again when the parser backtracks, 11 + 13
+	v, err = p.Parse("", []byte("x+x"), GlobalStore("x", 10))
+	want := "1:1 (0): rule num: num 1 at 0: x is deprecated\n1:3 (2): rule num: num 1 at 2: x is deprecated"
+	if err == nil || err.Error() != want || v != 24 {
+		t.Errorf("want 24 and %q, got %v, %v", want, v, err)
+	}
+
+	// the actions are removed with a nil callback
+	register("expr", 0, nil)
+	v, err = p.Parse("", []byte("1+2"))
+	if err != nil || snapshot(v) != `[1 "+" 2]` {
+		t.Errorf("want [1 \"+\" 2], got %s, %v", snapshot(v), err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	p := newTestParser(t, `
+a = "a" / "b"
+b = "c"
+`)
+	cases := []struct {
+		rule string
+		alt  int
+		err  string
+	}{
+		{rule: "a", alt: 1},
+		{rule: "b", alt: 0},
+		{rule: "a", alt: 2, err: "rule a has no alternative 2"},
+		{rule: "b", alt: -1, err: "rule b has no alternative -1"},
+		{rule: "c", alt: 0, err: "undefined rule: c"},
+	}
+	for _, c := range cases {
+		err := p.Register(c.rule, c.alt, func(*Context) (any, error) { return nil, nil })
+		var got string
+		if err != nil {
+			got = err.Error()
+		}
+		if got != c.err {
+			t.Errorf("%s %d: want error %q, got %q", c.rule, c.alt, c.err, got)
+		}
+	}
+}
+
+func TestParsedGrammar(t *testing.T) {
+	g, err := parse.ParseGrammar("sum.peg", strings.NewReader(`
+		Sum ← a:Num '+' b:Num / Num
+		Num ← [0-9]+
+	`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := New(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Register("Sum", 0, func(c *Context) (any, error) {
+		return c.Label("a").(int) + c.Label("b").(int), nil
+	})
+	p.Register("Num", 0, func(c *Context) (any, error) {
+		return strconv.Atoi(string(c.Text))
+	})
+	for in, want := range map[string]int{"1+2": 3, "42": 42} {
+		v, err := p.Parse("", []byte(in))
+		if err != nil || v != want {
+			t.Errorf("%q: want %d, got %v, %v", in, want, v, err)
+		}
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	src := `
+		// @left "+" "-"
+		// @left "*"
+		// @prefix "-"
+		// @skip _
+		Expr ← Num { return nil, nil }
+		Num ← [0-9]+
+		_ ← ' '*
+	`
+	g, err := parse.ParseGrammar("calc.peg", strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := New(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// without action, the operations are lists
+	v, err := p.Parse("", []byte("1 - -2 * 3"))
+	want := `["-" ["1"] ["*" ["-" nil ["2"]] ["3"]]]`
+	if err != nil || snapshot(v) != want {
+		t.Errorf("want %s, got %s, %v", want, snapshot(v), err)
+	}
+
+	p.Register("Expr", 0, func(c *Context) (any, error) {
+		left, _ := c.Label("left").(int)
+		right := c.Label("right").(int)
+		switch string(c.Label("op").([]byte)) {
+		case "+":
+			return left + right, nil
+		case "-":
+			return left - right, nil
+		default:
+			return left * right, nil
+		}
+	})
+	p.Register("Num", 0, func(c *Context) (any, error) {
+		return strconv.Atoi(string(c.Text))
+	})
+	for in, want := range map[string]int{"1 - -2 * 3": 7, "2*3+4": 10, "1-2-3": -4, "--5": 5} {
+		v, err := p.Parse("", []byte(in))
+		if err != nil || v != want {
+			t.Errorf("%q: want %d, got %v, %v", in, want, v, err)
+		}
+	}
+
+	g.Rules[2].Name.Val = "ws"
+	if _, err := New(g); err == nil || !strings.Contains(err.Error(), "undefined skip rule _") {
+		t.Errorf("want undefined skip rule error, got %v", err)
+	}
+}
@@ -0,0 +1,986 @@
+package interp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/cover"
+)
+
+// savepoint is the state of the parser at a position of the input.
+type savepoint struct {
+	Position
+	rn     rune
+	w      int
+	indent *indentLevel
+}
+
+// indentLevel is a level of the indentation stack. Levels are never
+// modified once created, so the stack is saved and restored along with
+// the savepoint when the parser backtracks. The nil level is the
+// outermost one, at width 0.
+type indentLevel struct {
+	// width of the indentation, with tabs advancing to the next tab stop
+	width int
+	// width of the indentation, with tabs counting as a single column
+	altWidth int
+	parent   *indentLevel
+}
+
+// capture stores the text matched by a labeled expression that is the
+// target of a back-reference, along with the depth of the vstack at which
+// the label is visible.
+type capture struct {
+	label string
+	text  []byte
+	depth int
+}
+
+// memoKey is the memoization key of a rule or an expression.
+type memoKey struct {
+	node   any
+	indent *indentLevel
+}
+
+// memoEntry is a result in the memoization table. The few results at an
+// offset are searched linearly.
+type memoEntry struct {
+	key memoKey
+	res resultTuple
+}
+
+// memoSlot holds the results at an offset of the memoization table, the
+// slots are reused for the offsets that are memoWindow apart.
+type memoSlot struct {
+	offset  int
+	entries []memoEntry
+}
+
+// leaderKey is the key of the result of a left-recursive leader.
+type leaderKey struct {
+	offset int
+	key    memoKey
+}
+
+// leaderGrowth is a left-recursive leader being grown, involved is set
+// if its result depends on the seed of an enclosing leader.
+type leaderGrowth struct {
+	key      leaderKey
+	involved bool
+}
+
+// resultTuple is a memoized result, with the nodes of the syntax tree
+// created by the match.
+type resultTuple struct {
+	v     any
+	b     bool
+	end   savepoint
+	nodes []*Node
+}
+
+// parser is the state of a parse.
+type parser struct {
+	*Parser
+
+	filename string
+	pt       savepoint
+	data     []byte
+	errs     ErrorList
+
+	recover          bool
+	memoize          bool
+	memoWindow       int
+	maxExprCnt       uint64
+	exprCnt          uint64
+	entrypoint       string
+	allowInvalidUTF8 bool
+	tabWidth         int
+
+	// memoization table: the results of the expressions and the rules by
+	// offset in source, in a ring of memoWindow slots if set
+	memo []memoSlot
+	// the results before this offset are discarded by the commit points
+	memoFloor int
+	// results of the left-recursive leaders by offset in source, out of
+	// the memoization table so that they are never discarded
+	leaders map[leaderKey]resultTuple
+	// left-recursive leaders being grown, innermost last
+	growing []leaderGrowth
+
+	// variables stack, map of label to value
+	vstack []map[string]any
+	// captures stack, text matched by the labels referenced by
+	// back-references
+	captures []capture
+	// rule stack, allows identification of the current rule in errors
+	rstack []*ast.Rule
+	// recovery expression stack
+	recoveryStack []map[string]ast.Expression
+	// store shared by the actions
+	globalStore map[string]any
+
+	// parse fail
+	maxFailPos            Position
+	maxFailExpected       []string
+	maxFailInvertExpected bool
+
+	// tree is set to build the syntax tree, children are the nodes of the
+	// current rule
+	tree     bool
+	children []*Node
+
+	// prof is set to gather a profile, cov the coverage
+	prof *profiler
+	cov  *cover.Profile
+}
+
+func newParser(p *Parser, filename string, b []byte, tree bool, opts ...Option) *parser {
+	ps := &parser{
+		Parser:          p,
+		filename:        filename,
+		data:            b,
+		pt:              savepoint{Position: Position{Line: 1}},
+		recover:         true,
+		tabWidth:        8,
+		maxFailPos:      Position{Col: 1, Line: 1},
+		maxFailExpected: make([]string, 0, 20),
+		globalStore:     make(map[string]any),
+		tree:            tree,
+	}
+	if len(p.g.Rules) > 0 {
+		ps.entrypoint = p.g.Rules[0].Name.Val
+	}
+	for _, opt := range opts {
+		opt(ps)
+	}
+	if ps.maxExprCnt == 0 {
+		ps.maxExprCnt = math.MaxUint64
+	}
+	return ps
+}
+
+func (p *parser) parse() (val any, err error) {
+	if len(p.g.Rules) == 0 {
+		p.addErr(errNoRule)
+		return nil, p.errs.err()
+	}
+
+	if p.recover {
+		// panic can be used to stop parsing immediately and return the panic
+		// as an error.
+		defer func() {
+			if e := recover(); e != nil {
+				val = nil
+				switch e := e.(type) {
+				case error:
+					p.addErr(e)
+				default:
+					p.addErr(fmt.Errorf("%v", e))
+				}
+				err = p.errs.err()
+			}
+		}()
+	}
+
+	startRule, ok := p.rules[p.entrypoint]
+	if !ok {
+		p.addErr(errInvalidEntrypoint)
+		return nil, p.errs.err()
+	}
+
+	p.read() // advance to first rune
+	val, ok = p.parseRuleWrap(startRule)
+	if !ok {
+		if len(p.errs) == 0 {
+			// If parsing fails, but no errors have been recorded, the expected
+			// values for the farthest parser position are returned as error.
+			maxFailExpectedMap := make(map[string]struct{}, len(p.maxFailExpected))
+			for _, v := range p.maxFailExpected {
+				maxFailExpectedMap[v] = struct{}{}
+			}
+			expected := make([]string, 0, len(maxFailExpectedMap))
+			eof := false
+			if _, ok := maxFailExpectedMap["!."]; ok {
+				delete(maxFailExpectedMap, "!.")
+				eof = true
+			}
+			for k := range maxFailExpectedMap {
+				expected = append(expected, k)
+			}
+			sort.Strings(expected)
+			if eof {
+				expected = append(expected, "EOF")
+			}
+			p.addErrAt(errors.New("no match found, expected: "+listJoin(expected, ", ", "or")), p.maxFailPos, expected)
+		}
+		return nil, p.errs.err()
+	}
+	return val, p.errs.err()
+}
+
+func listJoin(list []string, sep string, lastSep string) string {
+	switch len(list) {
+	case 0:
+		return ""
+	case 1:
+		return list[0]
+	default:
+		return strings.Join(list[:len(list)-1], sep) + " " + lastSep + " " + list[len(list)-1]
+	}
+}
+
+func (p *parser) addErr(err error) {
+	p.addErrAt(err, p.pt.Position, []string{})
+}
+
+func (p *parser) addErrAt(err error, pos Position, expected []string) {
+	e := &Error{Filename: p.filename, Pos: pos, Expected: expected, Inner: err}
+	if len(p.rstack) > 0 {
+		rule := p.rstack[len(p.rstack)-1]
+		e.Rule = rule.Name.Val
+		if rule.DisplayName != nil && rule.DisplayName.Val != "" {
+			e.Rule = rule.DisplayName.Val
+		}
+	}
+	p.errs = append(p.errs, e)
+}
+
+func (p *parser) failAt(fail bool, pos Position, want string) {
+	// process fail if parsing fails and not inverted or parsing succeeds
+	// and invert is set
+	if fail == p.maxFailInvertExpected {
+		if pos.Offset < p.maxFailPos.Offset {
+			return
+		}
+
+		if pos.Offset > p.maxFailPos.Offset {
+			p.maxFailPos = pos
+			p.maxFailExpected = p.maxFailExpected[:0]
+		}
+
+		if p.maxFailInvertExpected {
+			want = "!" + want
+		}
+		p.maxFailExpected = append(p.maxFailExpected, want)
+	}
+}
+
+// read advances the parser to the next rune.
+func (p *parser) read() {
+	p.pt.Offset += p.pt.w
+	rn, n := utf8.DecodeRune(p.data[p.pt.Offset:])
+	p.pt.rn = rn
+	p.pt.w = n
+	p.pt.Col++
+	if rn == '\n' {
+		p.pt.Line++
+		p.pt.Col = 0
+	}
+
+	if rn == utf8.RuneError && n == 1 { // see utf8.DecodeRune
+		if !p.allowInvalidUTF8 {
+			p.addErr(errInvalidEncoding)
+		}
+	}
+}
+
+// restore parser position to the savepoint pt.
+func (p *parser) restore(pt savepoint) {
+	if pt.Offset == p.pt.Offset && pt.indent == p.pt.indent {
+		return
+	}
+	if p.prof != nil && pt.Offset < p.pt.Offset {
+		p.prof.backtrack(p.pt.Offset - pt.Offset)
+	}
+	p.pt = pt
+}
+
+// sliceFrom returns the slice of bytes from the savepoint start to the
+// current position.
+func (p *parser) sliceFrom(start savepoint) []byte {
+	return p.data[start.Offset:p.pt.Offset:p.pt.Offset]
+}
+
+// push a variable set on the vstack.
+func (p *parser) pushV() {
+	p.vstack = append(p.vstack, nil)
+}
+
+// pop a variable set from the vstack.
+func (p *parser) popV() {
+	p.vstack = p.vstack[:len(p.vstack)-1]
+
+	// drop the captures that are no longer in scope
+	for len(p.captures) > 0 && p.captures[len(p.captures)-1].depth > len(p.vstack) {
+		p.captures = p.captures[:len(p.captures)-1]
+	}
+}
+
+// push a recovery expression with its labels to the recoveryStack
+func (p *parser) pushRecovery(labels []ast.FailureLabel, expr ast.Expression) {
+	m := make(map[string]ast.Expression, len(labels))
+	for _, fl := range labels {
+		m[string(fl)] = expr
+	}
+	p.recoveryStack = append(p.recoveryStack, m)
+}
+
+// pop a recovery expression from the recoveryStack
+func (p *parser) popRecovery() {
+	p.recoveryStack = p.recoveryStack[:len(p.recoveryStack)-1]
+}
+
+func (p *parser) getMemoized(node any) (resultTuple, bool) {
+	if len(p.memo) == 0 || p.pt.Offset < p.memoFloor {
+		return resultTuple{}, false
+	}
+	slot := &p.memo[p.pt.Offset%len(p.memo)]
+	if slot.offset != p.pt.Offset {
+		return resultTuple{}, false
+	}
+	key := memoKey{node: node, indent: p.pt.indent}
+	for _, e := range slot.entries {
+		if e.key == key {
+			return e.res, true
+		}
+	}
+	return resultTuple{}, false
+}
+
+func (p *parser) setMemoized(pt savepoint, node any, tuple resultTuple) {
+	if p.memo == nil {
+		n := len(p.data) + 1
+		if p.memoWindow > 0 && p.memoWindow < n {
+			n = p.memoWindow
+		}
+		p.memo = make([]memoSlot, n)
+	}
+	if pt.Offset < p.memoFloor {
+		return
+	}
+	slot := &p.memo[pt.Offset%len(p.memo)]
+	if slot.offset != pt.Offset {
+		if slot.offset > pt.Offset {
+			// out of the window
+			return
+		}
+		clear(slot.entries)
+		slot.offset, slot.entries = pt.Offset, slot.entries[:0]
+	}
+	key := memoKey{node: node, indent: pt.indent}
+	for i := range slot.entries {
+		if slot.entries[i].key == key {
+			slot.entries[i].res = tuple
+			return
+		}
+	}
+	slot.entries = append(slot.entries, memoEntry{key: key, res: tuple})
+}
+
+// commitMemo discards the memoized results before offset, the parser
+// does not backtrack past the match of a commit rule in practice.
+func (p *parser) commitMemo(offset int) {
+	if offset <= p.memoFloor {
+		return
+	}
+	for i := p.memoFloor; i < offset && i-p.memoFloor < len(p.memo); i++ {
+		slot := &p.memo[i%len(p.memo)]
+		if slot.offset < offset {
+			slot.entries = nil
+		}
+	}
+	p.memoFloor = offset
+}
+
+func (p *parser) newLeaderKey(rule *ast.Rule) leaderKey {
+	return leaderKey{offset: p.pt.Offset, key: memoKey{node: rule, indent: p.pt.indent}}
+}
+
+func (p *parser) getLeader(key leaderKey) (resultTuple, bool) {
+	res, ok := p.leaders[key]
+	if !ok {
+		return res, false
+	}
+	for i := len(p.growing) - 1; i >= 0; i-- {
+		if p.growing[i].key == key {
+			// the seed of a leader being grown, the results of the leaders
+			// grown since depend on it
+			for j := i + 1; j < len(p.growing); j++ {
+				p.growing[j].involved = true
+			}
+			break
+		}
+	}
+	return res, true
+}
+
+func (p *parser) setLeader(key leaderKey, tuple resultTuple) {
+	if p.leaders == nil {
+		p.leaders = make(map[leaderKey]resultTuple)
+	}
+	p.leaders[key] = tuple
+}
+
+// restoreMemoized restores the end position and the syntax tree nodes of
+// the memoized result res.
+func (p *parser) restoreMemoized(res resultTuple) (any, bool) {
+	p.restore(res.end)
+	p.children = append(p.children, res.nodes...)
+	return res.v, res.b
+}
+
+// nodesFrom returns a copy of the syntax tree nodes created since the
+// current rule had n nodes.
+func (p *parser) nodesFrom(n int) []*Node {
+	if len(p.children) == n {
+		return nil
+	}
+	return slices.Clone(p.children[n:])
+}
+
+func (p *parser) parseRuleWrap(rule *ast.Rule) (any, bool) {
+	var (
+		val any
+		ok  bool
+	)
+	switch {
+	case rule.Leader:
+		val, ok = p.parseRuleRecursiveLeader(rule)
+	case (p.memoize || p.memoized[rule]) && !rule.LeftRecursive:
+		val, ok = p.parseRuleMemoize(rule)
+	default:
+		val, ok = p.parseRule(rule)
+	}
+	if ok && p.commits[rule] {
+		p.commitMemo(p.pt.Offset)
+	}
+	return val, ok
+}
+
+func (p *parser) parseRuleRecursiveLeader(rule *ast.Rule) (any, bool) {
+	key := p.newLeaderKey(rule)
+	result, ok := p.getLeader(key)
+	if ok {
+		if p.prof != nil {
+			p.prof.rule(rule.Name.Val).MemoHits++
+		}
+		return p.restoreMemoized(result)
+	}
+
+	var (
+		depth      = 0
+		startMark  = p.pt
+		lastResult = resultTuple{nil, false, startMark, nil}
+		lastErrors = slices.Clone(p.errs)
+		n          = len(p.children)
+	)
+
+	p.growing = append(p.growing, leaderGrowth{key: key})
+	for {
+		p.setLeader(key, lastResult)
+		val, ok := p.parseRule(rule)
+		endMark := p.pt
+		nodes := p.nodesFrom(n)
+		p.children = p.children[:n]
+		if (!ok) || (endMark.Offset <= lastResult.end.Offset && depth != 0) {
+			p.errs = lastErrors
+			break
+		}
+		lastResult = resultTuple{val, ok, endMark, nodes}
+		lastErrors = slices.Clone(p.errs)
+		p.restore(startMark)
+		depth++
+	}
+
+	involved := p.growing[len(p.growing)-1].involved
+	p.growing = p.growing[:len(p.growing)-1]
+
+	if involved {
+		// the result depends on the seed of an enclosing leader, it is
+		// evaluated again with the next seed
+		delete(p.leaders, key)
+	} else {
+		p.setLeader(key, lastResult)
+	}
+	return p.restoreMemoized(lastResult)
+}
+
+func (p *parser) parseRuleMemoize(rule *ast.Rule) (any, bool) {
+	res, ok := p.getMemoized(rule)
+	if ok {
+		if p.prof != nil {
+			p.prof.rule(rule.Name.Val).MemoHits++
+		}
+		return p.restoreMemoized(res)
+	}
+
+	startMark := p.pt
+	n := len(p.children)
+	val, ok := p.parseRule(rule)
+	p.setMemoized(startMark, rule, resultTuple{val, ok, p.pt, p.nodesFrom(n)})
+
+	return val, ok
+}
+
+func (p *parser) parseRule(rule *ast.Rule) (any, bool) {
+	if p.prof != nil {
+		p.prof.enterRule(rule.Name.Val, p.pt.Offset)
+	}
+	start := p.pt
+	parent := p.children
+	p.children = nil
+
+	p.rstack = append(p.rstack, rule)
+	p.pushV()
+	val, ok := p.parseExprWrap(rule.Expr)
+	p.popV()
+	p.rstack = p.rstack[:len(p.rstack)-1]
+
+	if p.prof != nil {
+		p.prof.exitRule(ok, p.pt.Offset-start.Offset)
+	}
+	if p.cov != nil && ok {
+		p.cov.Rules[rule.Name.Val]++
+	}
+
+	children := p.children
+	p.children = parent
+	if ok && p.tree {
+		p.children = append(p.children, &Node{
+			Rule:     rule.Name.Val,
+			Start:    start.Position,
+			End:      p.pt.Position,
+			Text:     string(p.sliceFrom(start)),
+			Children: children,
+			Value:    val,
+		})
+	}
+	return val, ok
+}
+
+// memoizeExprs returns true if the results of the expressions of the rule
+// may be memoized. This is not the case for left-recursive rules and for
+// rules with back-references, as their results depend on the context.
+func (p *parser) memoizeExprs(r *ast.Rule) bool {
+	return !r.LeftRecursive && !p.backRef[r]
+}
+
+func (p *parser) parseExprWrap(expr ast.Expression) (any, bool) {
+	var pt savepoint
+
+	memoize := p.memoize && p.memoizeExprs(p.rstack[len(p.rstack)-1])
+	if memoize {
+		res, ok := p.getMemoized(expr)
+		if ok {
+			if p.prof != nil {
+				p.prof.expr(p.rstack[len(p.rstack)-1].Name.Val, expr).MemoHits++
+			}
+			return p.restoreMemoized(res)
+		}
+		pt = p.pt
+	}
+
+	n := len(p.children)
+	val, ok := p.parseExpr(expr)
+	if !ok {
+		// drop the nodes of the partial match
+		p.children = p.children[:n]
+	}
+
+	if memoize {
+		p.setMemoized(pt, expr, resultTuple{val, ok, p.pt, p.nodesFrom(n)})
+	}
+	return val, ok
+}
+
+func (p *parser) parseExpr(expr ast.Expression) (val any, ok bool) {
+	p.exprCnt++
+	if p.exprCnt > p.maxExprCnt {
+		panic(errMaxExprCnt)
+	}
+	if p.prof != nil {
+		start := p.pt.Offset
+		p.prof.enterExpr(p.rstack[len(p.rstack)-1].Name.Val, expr, start)
+		defer func() {
+			p.prof.exitExpr(ok, p.pt.Offset-start)
+		}()
+	}
+
+	if prec := p.precedences[expr]; prec != nil {
+		return p.parsePrecedence(prec)
+	}
+	if act := p.actions[expr]; act != nil {
+		return p.parseAction(expr, act)
+	}
+	return p.parseNode(expr)
+}
+
+// parseNode parses the expression according to its type.
+func (p *parser) parseNode(expr ast.Expression) (any, bool) {
+	switch expr := expr.(type) {
+	case *ast.ActionExpr:
+		return p.parseExprWrap(expr.Expr)
+	case *ast.AndCodeExpr, *ast.NotCodeExpr, *ast.StateCodeExpr:
+		// the code is not run
+		return nil, true
+	case *ast.AndExpr:
+		return p.parseAndExpr(expr)
+	case *ast.AnyMatcher:
+		return p.parseAnyMatcher()
+	case *ast.BackRefExpr:
+		return p.parseBackRefExpr(expr)
+	case *ast.CharClassMatcher:
+		return p.parseCharClassMatcher(expr)
+	case *ast.ChoiceExpr:
+		return p.parseChoiceExpr(expr)
+	case *ast.IndentExpr:
+		return p.parseIndentExpr(expr)
+	case *ast.LabeledExpr:
+		return p.parseLabeledExpr(expr)
+	case *ast.LitMatcher:
+		return p.parseLitMatcher(expr)
+	case *ast.NotExpr:
+		return p.parseNotExpr(expr)
+	case *ast.OneOrMoreExpr:
+		return p.parseOneOrMoreExpr(expr)
+	case *ast.RecoveryExpr:
+		return p.parseRecoveryExpr(expr)
+	case *ast.RuleRefExpr:
+		return p.parseRuleWrap(p.rules[expr.Name.Val])
+	case *ast.SeqExpr:
+		return p.parseSeqExpr(expr)
+	case *ast.ThrowExpr:
+		return p.parseThrowExpr(expr)
+	case *ast.ZeroOrMoreExpr:
+		return p.parseZeroOrMoreExpr(expr)
+	case *ast.ZeroOrOneExpr:
+		return p.parseZeroOrOneExpr(expr)
+	default:
+		panic(fmt.Sprintf("unknown expression type %T", expr))
+	}
+}
+
+func (p *parser) parseAndExpr(and *ast.AndExpr) (any, bool) {
+	pt := p.pt
+	n := len(p.children)
+	p.pushV()
+	_, ok := p.parseExprWrap(and.Expr)
+	p.popV()
+	p.children = p.children[:n]
+	p.restore(pt)
+
+	return nil, ok
+}
+
+func (p *parser) parseAnyMatcher() (any, bool) {
+	if p.pt.rn == utf8.RuneError && p.pt.w == 0 {
+		// EOF - see utf8.DecodeRune
+		p.failAt(false, p.pt.Position, ".")
+		return nil, false
+	}
+	start := p.pt
+	p.read()
+	p.failAt(true, start.Position, ".")
+	return p.sliceFrom(start), true
+}
+
+func (p *parser) parseBackRefExpr(ref *ast.BackRefExpr) (any, bool) {
+	label := ref.Label.Val
+	for i := len(p.captures) - 1; i >= 0; i-- {
+		if p.captures[i].label != label {
+			continue
+		}
+
+		text := p.captures[i].text
+		start := p.pt
+		want := strconv.Quote(string(text))
+		if !bytes.HasPrefix(p.data[start.Offset:], text) {
+			p.failAt(false, start.Position, want)
+			return nil, false
+		}
+		for p.pt.Offset < start.Offset+len(text) {
+			p.read()
+		}
+		p.failAt(true, start.Position, want)
+		return p.sliceFrom(start), true
+	}
+
+	p.addErr(fmt.Errorf("undefined back-reference: %s", label))
+	return nil, false
+}
+
+func (p *parser) parseCharClassMatcher(chr *ast.CharClassMatcher) (any, bool) {
+	if p.cov != nil {
+		p.coverClass(chr)
+	}
+	cl := p.classes[chr]
+	cur := p.pt.rn
+	start := p.pt
+
+	// can't match EOF
+	if cur == utf8.RuneError && p.pt.w == 0 { // see utf8.DecodeRune
+		p.failAt(false, start.Position, chr.Val)
+		return nil, false
+	}
+
+	if chr.IgnoreCase {
+		cur = unicode.ToLower(cur)
+	}
+
+	matched := slices.Contains(cl.chars, cur)
+	for i := 0; !matched && i < len(cl.ranges); i += 2 {
+		matched = cur >= cl.ranges[i] && cur <= cl.ranges[i+1]
+	}
+	for i := 0; !matched && i < len(cl.classes); i++ {
+		matched = unicode.Is(cl.classes[i], cur)
+	}
+
+	if matched == chr.Inverted {
+		p.failAt(false, start.Position, chr.Val)
+		return nil, false
+	}
+	p.read()
+	p.failAt(true, start.Position, chr.Val)
+	return p.sliceFrom(start), true
+}
+
+func (p *parser) parseChoiceExpr(ch *ast.ChoiceExpr) (any, bool) {
+	for i, alt := range ch.Alternatives {
+		p.pushV()
+		val, ok := p.parseExprWrap(alt)
+		p.popV()
+		if ok {
+			if p.cov != nil {
+				p.cov.Alternatives[p.coverKey(ch.Pos(), i+1)]++
+			}
+			return val, ok
+		}
+	}
+	return nil, false
+}
+
+func (p *parser) parseIndentExpr(ind *ast.IndentExpr) (any, bool) {
+	want := "%" + ind.Kind.String()
+	start := p.pt
+	width, altWidth, end := p.indentation()
+	var cmp, altCmp int
+	if cur := start.indent; cur != nil {
+		cmp, altCmp = compareInts(width, cur.width), compareInts(altWidth, cur.altWidth)
+	} else {
+		cmp, altCmp = compareInts(width, 0), compareInts(altWidth, 0)
+	}
+	if cmp != altCmp {
+		p.addErr(errInconsistentIndent)
+		p.failAt(false, start.Position, want)
+		return nil, false
+	}
+
+	ok := false
+	switch ind.Kind {
+	case ast.IndentKindIndent:
+		if cmp > 0 {
+			p.pt.indent = &indentLevel{width: width, altWidth: altWidth, parent: start.indent}
+			ok = true
+		}
+	case ast.IndentKindDedent:
+		// the indentation must match one of the enclosing levels
+		if parent := start.indent.parentOf(); cmp < 0 && width <= parent.widthOf() {
+			p.pt.indent = parent
+			ok = true
+		}
+	default:
+		if cmp == 0 {
+			for p.pt.Offset < end {
+				p.read()
+			}
+			ok = true
+		}
+	}
+	p.failAt(ok, start.Position, want)
+	if !ok {
+		return nil, false
+	}
+	return p.sliceFrom(start), true
+}
+
+// indentation returns the width of the indentation of the next non-blank
+// line, starting at the current position, and the offset of its first
+// non-whitespace character. Lines made only of spaces and tabs are
+// skipped, and the end of the input has a width of 0.
+func (p *parser) indentation() (width, altWidth, end int) {
+	for i := p.pt.Offset; i < len(p.data); i++ {
+		switch p.data[i] {
+		case ' ':
+			width++
+			altWidth++
+		case '\t':
+			width += p.tabWidth - width%p.tabWidth
+			altWidth++
+		case '\r', '\n':
+			width, altWidth = 0, 0
+		default:
+			return width, altWidth, i
+		}
+	}
+	return 0, 0, len(p.data)
+}
+
+func (l *indentLevel) parentOf() *indentLevel {
+	if l == nil {
+		return nil
+	}
+	return l.parent
+}
+
+func (l *indentLevel) widthOf() int {
+	if l == nil {
+		return 0
+	}
+	return l.width
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (p *parser) parseLabeledExpr(lab *ast.LabeledExpr) (any, bool) {
+	start := p.pt
+	p.pushV()
+	val, ok := p.parseExprWrap(lab.Expr)
+	p.popV()
+	if ok && lab.Label != nil && lab.Label.Val != "" {
+		m := p.vstack[len(p.vstack)-1]
+		if m == nil {
+			m = make(map[string]any)
+			p.vstack[len(p.vstack)-1] = m
+		}
+		m[lab.Label.Val] = val
+		if p.captured[lab] {
+			p.captures = append(p.captures, capture{label: lab.Label.Val, text: p.data[start.Offset:p.pt.Offset], depth: len(p.vstack)})
+		}
+	}
+	return val, ok
+}
+
+func (p *parser) parseLitMatcher(lit *ast.LitMatcher) (any, bool) {
+	lm := p.lits[lit]
+	start := p.pt
+	for _, want := range lm.val {
+		cur := p.pt.rn
+		if lit.IgnoreCase {
+			cur = unicode.ToLower(cur)
+		}
+		if cur != want {
+			p.failAt(false, start.Position, lm.want)
+			p.restore(start)
+			return nil, false
+		}
+		p.read()
+	}
+	p.failAt(true, start.Position, lm.want)
+	return p.sliceFrom(start), true
+}
+
+func (p *parser) parseNotExpr(not *ast.NotExpr) (any, bool) {
+	pt := p.pt
+	n := len(p.children)
+	p.pushV()
+	p.maxFailInvertExpected = !p.maxFailInvertExpected
+	_, ok := p.parseExprWrap(not.Expr)
+	p.maxFailInvertExpected = !p.maxFailInvertExpected
+	p.popV()
+	p.children = p.children[:n]
+	p.restore(pt)
+
+	return nil, !ok
+}
+
+func (p *parser) parseOneOrMoreExpr(expr *ast.OneOrMoreExpr) (any, bool) {
+	var vals []any
+
+	for {
+		p.pushV()
+		val, ok := p.parseExprWrap(expr.Expr)
+		p.popV()
+		if !ok {
+			if len(vals) == 0 {
+				// did not match once, no match
+				return nil, false
+			}
+			return vals, true
+		}
+		vals = append(vals, val)
+	}
+}
+
+func (p *parser) parseRecoveryExpr(recover *ast.RecoveryExpr) (any, bool) {
+	p.pushRecovery(recover.Labels, recover.RecoverExpr)
+	val, ok := p.parseExprWrap(recover.Expr)
+	p.popRecovery()
+
+	return val, ok
+}
+
+func (p *parser) parseSeqExpr(seq *ast.SeqExpr) (any, bool) {
+	vals := make([]any, 0, len(seq.Exprs))
+
+	pt := p.pt
+	for _, expr := range seq.Exprs {
+		val, ok := p.parseExprWrap(expr)
+		if !ok {
+			p.restore(pt)
+			return nil, false
+		}
+		vals = append(vals, val)
+	}
+	return vals, true
+}
+
+func (p *parser) parseThrowExpr(expr *ast.ThrowExpr) (any, bool) {
+	for i := len(p.recoveryStack) - 1; i >= 0; i-- {
+		if recoverExpr, ok := p.recoveryStack[i][expr.Label]; ok {
+			if val, ok := p.parseExprWrap(recoverExpr); ok {
+				return val, ok
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func (p *parser) parseZeroOrMoreExpr(expr *ast.ZeroOrMoreExpr) (any, bool) {
+	var vals []any
+
+	for {
+		p.pushV()
+		val, ok := p.parseExprWrap(expr.Expr)
+		p.popV()
+		if !ok {
+			return vals, true
+		}
+		vals = append(vals, val)
+	}
+}
+
+func (p *parser) parseZeroOrOneExpr(expr *ast.ZeroOrOneExpr) (any, bool) {
+	p.pushV()
+	val, _ := p.parseExprWrap(expr.Expr)
+	p.popV()
+	// whether it matched or not, consider it a match
+	return val, true
+}
@@ -0,0 +1,176 @@
+package interp
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/mna/pigeon/ast"
+)
+
+// precedence is the precedence table of a rule prepared for matching, as
+// the precedenceExpr of the generated parsers.
+type precedence struct {
+	expr    *ast.ActionExpr
+	skip    *ast.Rule
+	prefix  []*precedenceOp
+	infix   []*precedenceOp
+	postfix []*precedenceOp
+}
+
+// precedenceOp is an operator of a precedence table, the operators of the
+// lowest level have the level 1.
+type precedenceOp struct {
+	lit      *ast.LitMatcher
+	level    int
+	right    bool
+	nonAssoc bool
+}
+
+// newPrecedence returns the precedence table of the rule r, or nil if it
+// declares none.
+func (p *Parser) newPrecedence(r *ast.Rule) (*precedence, error) {
+	table, err := r.Precedence()
+	if err != nil || table == nil {
+		return nil, err
+	}
+	act := r.Expr.(*ast.ActionExpr)
+	prec := &precedence{expr: act}
+	if table.Skip != "" {
+		if prec.skip = p.rules[table.Skip]; prec.skip == nil {
+			return nil, fmt.Errorf("%s: rule %s: undefined skip rule %s", r.Pos(), r.Name.Val, table.Skip)
+		}
+	}
+
+	for i, level := range table.Levels {
+		for _, val := range level.Operators {
+			op := &precedenceOp{
+				lit:      ast.NewLitMatcher(act.Pos(), val),
+				level:    i + 1,
+				right:    level.Kind == ast.InfixRight,
+				nonAssoc: level.Kind == ast.InfixNonAssoc,
+			}
+			p.lits[op.lit] = &litMatcher{val: val, want: strconv.Quote(val)}
+			switch level.Kind {
+			case ast.Prefix:
+				prec.prefix = append(prec.prefix, op)
+			case ast.Postfix:
+				prec.postfix = append(prec.postfix, op)
+			default:
+				prec.infix = append(prec.infix, op)
+			}
+		}
+	}
+	// the longest operator that matches is used
+	for _, ops := range [][]*precedenceOp{prec.prefix, prec.infix, prec.postfix} {
+		slices.SortStableFunc(ops, func(a, b *precedenceOp) int {
+			return len(b.lit.Val) - len(a.lit.Val)
+		})
+	}
+	return prec, nil
+}
+
+// parsePrecedence parses the operations of the precedence table prec.
+func (p *parser) parsePrecedence(prec *precedence) (any, bool) {
+	return p.parseOperation(prec, 1)
+}
+
+// parseOperation matches an operand of prec and the operations whose
+// operators have at least the level minLevel.
+func (p *parser) parseOperation(prec *precedence, minLevel int) (any, bool) {
+	start := p.pt
+	var left any
+	if op, opVal := p.parseOperator(prec.prefix); op != nil {
+		p.parseSkip(prec)
+		right, ok := p.parseOperation(prec, op.level)
+		if !ok {
+			p.restore(start)
+			return nil, false
+		}
+		left = p.runOperation(prec, start, opVal, nil, right)
+	} else {
+		val, ok := p.parseExprWrap(prec.expr.Expr)
+		if !ok {
+			return nil, false
+		}
+		left = val
+	}
+
+	// the operators of a non-associative level cannot follow each other
+	nonAssocLevel := 0
+	for {
+		pt := p.pt
+		p.parseSkip(prec)
+		op, opVal := p.parseOperator(prec.postfix)
+		postfix := op != nil
+		if !postfix {
+			op, opVal = p.parseOperator(prec.infix)
+		}
+		if op == nil || op.level < minLevel || op.level == nonAssocLevel {
+			p.restore(pt)
+			return left, true
+		}
+		if postfix {
+			left = p.runOperation(prec, start, opVal, left, nil)
+			continue
+		}
+
+		p.parseSkip(prec)
+		next := op.level + 1
+		if op.right {
+			next = op.level
+		}
+		right, ok := p.parseOperation(prec, next)
+		if !ok {
+			p.restore(pt)
+			return left, true
+		}
+		left = p.runOperation(prec, start, opVal, left, right)
+		if op.nonAssoc {
+			nonAssocLevel = op.level
+		}
+	}
+}
+
+// parseOperator matches the first operator of ops, and returns it with its
+// value, or nil if none matches.
+func (p *parser) parseOperator(ops []*precedenceOp) (*precedenceOp, any) {
+	for _, op := range ops {
+		if val, ok := p.parseExpr(op.lit); ok {
+			return op, val
+		}
+	}
+	return nil, nil
+}
+
+func (p *parser) parseSkip(prec *precedence) {
+	if prec.skip != nil {
+		p.parseRuleWrap(prec.skip)
+	}
+}
+
+// runOperation calls the action registered for the rule of prec, for the
+// operation that starts at start. Without action, the value of the
+// operation is the list of its operator and operands.
+func (p *parser) runOperation(prec *precedence, start savepoint, op, left, right any) any {
+	val := []any{op, left, right}
+	act := p.actions[prec.expr]
+	if act == nil {
+		return val
+	}
+
+	c := &Context{
+		Rule:        act.rule,
+		Alt:         act.alt,
+		Pos:         start.Position,
+		Text:        p.sliceFrom(start),
+		Value:       val,
+		GlobalStore: p.globalStore,
+		labels:      map[string]any{"op": op, "left": left, "right": right},
+	}
+	actVal, err := act.fn(c)
+	if err != nil {
+		p.addErrAt(err, start.Position, []string{})
+	}
+	return actVal
+}
@@ -0,0 +1,173 @@
+package interp
+
+import (
+	"time"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/profile"
+)
+
+// Profile creates an Option to gather the profile of the parse in prof,
+// as the Profiling option of the generated parsers. The profiles of
+// several parses can be gathered in the same prof.
+//
+// The default is nil, no profiling.
+func Profile(prof *profile.Profile) Option {
+	return func(p *parser) Option {
+		var old *profile.Profile
+		if p.prof != nil {
+			old = p.prof.Profile
+		}
+		p.prof = nil
+		if prof != nil {
+			p.prof = newProfiler(prof)
+		}
+		return Profile(old)
+	}
+}
+
+// profiler gathers the profile of the Profile option.
+type profiler struct {
+	*profile.Profile
+
+	// entries of the expressions, number of evaluations in progress of
+	// each entry, and stacks of the rules and expressions being evaluated
+	exprs  map[ast.Expression]*profile.Entry
+	active map[*profile.Entry]int
+	rules  []profileFrame
+	stack  []profileFrame
+	// offsets of the evaluations of each entry, to count the revisits
+	visited map[profileVisit]bool
+}
+
+// profileVisit is the evaluation of an entry at an offset.
+type profileVisit struct {
+	entry  *profile.Entry
+	offset int
+}
+
+// profileFrame is an evaluation in progress of a rule or an expression.
+type profileFrame struct {
+	entry *profile.Entry
+	start time.Time
+	// for the rules, stack of rules and time spent in the nested rules
+	stack string
+	child time.Duration
+}
+
+func newProfiler(prof *profile.Profile) *profiler {
+	if prof.Rules == nil {
+		prof.Rules = make(map[string]*profile.Entry)
+	}
+	if prof.Exprs == nil {
+		prof.Exprs = make(map[string]*profile.Entry)
+	}
+	if prof.Stacks == nil {
+		prof.Stacks = make(map[string]*profile.Stack)
+	}
+	return &profiler{
+		Profile: prof,
+		exprs:   make(map[ast.Expression]*profile.Entry),
+		active:  make(map[*profile.Entry]int),
+		visited: make(map[profileVisit]bool),
+	}
+}
+
+// rule returns the entry of the rule name.
+func (pr *profiler) rule(name string) *profile.Entry {
+	e := pr.Rules[name]
+	if e == nil {
+		e = new(profile.Entry)
+		pr.Rules[name] = e
+	}
+	return e
+}
+
+// expr returns the entry of the expression expr of the rule name.
+func (pr *profiler) expr(name string, expr ast.Expression) *profile.Entry {
+	e := pr.exprs[expr]
+	if e == nil {
+		key := profile.ExprKey(name, expr)
+		if e = pr.Exprs[key]; e == nil {
+			e = new(profile.Entry)
+			pr.Exprs[key] = e
+		}
+		pr.exprs[expr] = e
+	}
+	return e
+}
+
+func (pr *profiler) enter(e *profile.Entry, offset int) profileFrame {
+	e.Calls++
+	if v := (profileVisit{entry: e, offset: offset}); pr.visited[v] {
+		e.Revisits++
+	} else {
+		pr.visited[v] = true
+	}
+	pr.active[e]++
+	return profileFrame{entry: e, start: time.Now()}
+}
+
+// exit records the outcome of the evaluation f, that consumed n bytes,
+// and returns its duration.
+func (pr *profiler) exit(f profileFrame, ok bool, n int) time.Duration {
+	d := time.Since(f.start)
+	if ok {
+		f.entry.Matches++
+		f.entry.Bytes += int64(n)
+	} else {
+		f.entry.Failures++
+	}
+	pr.active[f.entry]--
+	if pr.active[f.entry] == 0 {
+		f.entry.Time += d
+	}
+	return d
+}
+
+func (pr *profiler) enterRule(name string, offset int) {
+	f := pr.enter(pr.rule(name), offset)
+	f.stack = name
+	if len(pr.rules) > 0 {
+		f.stack = pr.rules[len(pr.rules)-1].stack + ";" + name
+	}
+	pr.rules = append(pr.rules, f)
+}
+
+func (pr *profiler) exitRule(ok bool, n int) {
+	f := pr.rules[len(pr.rules)-1]
+	pr.rules = pr.rules[:len(pr.rules)-1]
+	d := pr.exit(f, ok, n)
+
+	s := pr.Stacks[f.stack]
+	if s == nil {
+		s = new(profile.Stack)
+		pr.Stacks[f.stack] = s
+	}
+	s.Calls++
+	s.Time += d - f.child
+	if len(pr.rules) > 0 {
+		pr.rules[len(pr.rules)-1].child += d
+	}
+}
+
+func (pr *profiler) enterExpr(name string, expr ast.Expression, offset int) {
+	pr.stack = append(pr.stack, pr.enter(pr.expr(name, expr), offset))
+}
+
+func (pr *profiler) exitExpr(ok bool, n int) {
+	f := pr.stack[len(pr.stack)-1]
+	pr.stack = pr.stack[:len(pr.stack)-1]
+	pr.exit(f, ok, n)
+}
+
+// backtrack records the n bytes given back by the innermost rule and
+// expression being evaluated.
+func (pr *profiler) backtrack(n int) {
+	if len(pr.rules) > 0 {
+		pr.rules[len(pr.rules)-1].entry.Backtracked += int64(n)
+	}
+	if len(pr.stack) > 0 {
+		pr.stack[len(pr.stack)-1].entry.Backtracked += int64(n)
+	}
+}
@@ -3,18 +3,23 @@ package main
 import (
 	"bufio"
 	"bytes"
-	"errors"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"os"
-	"strconv"
+	"slices"
 	"strings"
 
 	"golang.org/x/tools/imports"
 
 	"github.com/mna/pigeon/ast"
 	"github.com/mna/pigeon/builder"
+	"github.com/mna/pigeon/gen"
+	"github.com/mna/pigeon/parse"
+	"github.com/mna/pigeon/pegtest"
+	"github.com/mna/pigeon/profile"
 )
 
 // exit function mockable for tests
@@ -34,24 +39,55 @@ func (r *ruleNamesFlag) Set(value string) error {
 	return nil
 }
 
+// commands are the subcommands of the command-line tool, by name.
+var commands = map[string]func(args []string){
+	"cover":   coverMain,
+	"doc":     docMain,
+	"export":  exportMain,
+	"fmt":     fmtMain,
+	"gen":     genMain,
+	"import":  importMain,
+	"profile": profileMain,
+	"run":     runMain,
+	"test":    testGrammarMain,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if cmd := commands[os.Args[1]]; cmd != nil {
+			cmd(os.Args[2:])
+			return
+		}
+	}
+
 	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
 
 	// define command-line flags
 	var (
+		byteModeFlag           = fs.Bool("byte-mode", false, "generate a parser that matches bytes instead of UTF-8 encoded runes")
 		cacheFlag              = fs.Bool("cache", false, "cache parsing results")
+		choiceStatsFlag        = fs.String("choice-stats", "", "reorder the choice alternatives by the counts in this JSON file")
+		coverageFlag           = fs.Bool("coverage", false, "generate the Coverage option of the parser")
 		dbgFlag                = fs.Bool("debug", false, "set debug mode")
+		diffGrammarFlag        = fs.Bool("diff-grammar", false, "write the diff of the optimized grammar instead of the parser")
+		fuzzTestFlag           = fs.String("fuzz-test", "", "write a fuzz test of the parser to this file")
+		memoProfileFlag        = fs.String("memo-profile", "", "memoize the rules revisited in the profile of this JSON file")
 		shortHelpFlag          = fs.Bool("h", false, "show help page")
 		longHelpFlag           = fs.Bool("help", false, "show help page")
+		inputDecoderFlag       = fs.Bool("input-decoder", false, "generate the InputDecoder option of the parser")
 		nolint                 = fs.Bool("nolint", false, "add '// nolint: ...' comments to suppress warnings by gometalinter or golangci-lint")
 		noRecoverFlag          = fs.Bool("no-recover", false, "do not recover from panic")
 		outputFlag             = fs.String("o", "", "output file, defaults to stdout")
+		printGrammarFlag       = fs.Bool("print-grammar", false, "write the grammar as PEG instead of the parser")
+		positionConvFlag       = fs.Bool("position-converter", false, "generate the PositionConverter of the parser")
 		optimizeBasicLatinFlag = fs.Bool("optimize-basic-latin", false, "generate optimized parser for Unicode Basic Latin character sets")
 		optimizeGrammar        = fs.Bool("optimize-grammar", false, "optimize the given grammar (EXPERIMENTAL FEATURE)")
 		optimizeParserFlag     = fs.Bool("optimize-parser", false, "generate optimized parser without Debug and Memoize options")
+		profilingFlag          = fs.Bool("profiling", false, "generate the Profiling option of the parser")
 		recvrNmFlag            = fs.String("receiver-name", "c", "receiver name for the generated methods")
 		noBuildFlag            = fs.Bool("x", false, "do not build, only parse")
-		supportLeftRecursion   = fs.Bool("support-left-recursion", false, "add support left recursion (EXPERIMENTAL FEATURE)")
+		supportLeftRecursion   = fs.Bool("support-left-recursion", false, "add support for left recursion")
+		tracingFlag            = fs.Bool("tracing", false, "generate the Trace option of the parser")
 
 		altEntrypointsFlag ruleNamesFlag
 	)
@@ -93,14 +129,13 @@ func main() {
 	}()
 
 	// parse input
-	g, err := ParseReader(nm, rc, Debug(*dbgFlag), Memoize(*cacheFlag), Recover(!*noRecoverFlag))
+	grammar, err := parse.ParseGrammar(nm, rc, parse.Debug(*dbgFlag), parse.Memoize(*cacheFlag), parse.Recover(!*noRecoverFlag), parse.Validate(false))
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "parse error(s):\n", err)
 		exit(3)
 	}
 
 	// validate alternate entrypoints
-	grammar := g.(*ast.Grammar)
 	rules := make(map[string]struct{}, len(grammar.Rules))
 	for _, rule := range grammar.Rules {
 		rules[rule.Name.Val] = struct{}{}
@@ -116,9 +151,55 @@ func main() {
 		}
 	}
 
+	var choiceStats map[string]map[string]int
+	if *choiceStatsFlag != "" {
+		choiceStats = readChoiceStats(*choiceStatsFlag)
+	}
+
+	if *printGrammarFlag || *diffGrammarFlag {
+		out := output(*outputFlag)
+		defer func() {
+			err := out.Close()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "close file error:\n", err)
+				exit(8)
+			}
+		}()
+
+		if err := writeGrammar(out, nm, grammar, *optimizeGrammar, *diffGrammarFlag, *byteModeFlag, altEntrypointsFlag, choiceStats); err != nil {
+			fmt.Fprintln(os.Stderr, "write error: ", err)
+			exit(7)
+		}
+		return
+	}
+
 	if !*noBuildFlag {
+		fuzzTestOpt := builder.FuzzTest(nil)
+		if *fuzzTestFlag != "" {
+			// the seeds are generated before the grammar is modified
+			seeds := fuzzSeeds(nm, grammar)
+			f, err := os.Create(*fuzzTestFlag)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "output file error:\n", err)
+				exit(4)
+			}
+			defer func() {
+				if err := f.Close(); err != nil {
+					fmt.Fprintln(os.Stderr, "close file error:\n", err)
+					exit(8)
+				}
+			}()
+			fuzzTestOpt = builder.FuzzTest(f, seeds...)
+		}
+
+		if choiceStats != nil {
+			reorderChoices(grammar, choiceStats)
+		}
+		if *memoProfileFlag != "" {
+			memoizeRules(grammar, *memoProfileFlag)
+		}
 		if *optimizeGrammar {
-			ast.Optimize(grammar, altEntrypointsFlag...)
+			optimizeForParser(grammar, *byteModeFlag, altEntrypointsFlag)
 		}
 
 		// generate parser
@@ -138,9 +219,16 @@ func main() {
 		basicLatinOptimize := builder.BasicLatinLookupTable(*optimizeBasicLatinFlag)
 		nolintOpt := builder.Nolint(*nolint)
 		leftRecursionSupporter := builder.SupportLeftRecursion(*supportLeftRecursion)
+		byteMode := builder.ByteMode(*byteModeFlag)
+		tracing := builder.Tracing(*tracingFlag)
+		profiling := builder.Profiling(*profilingFlag)
+		coverage := builder.Coverage(*coverageFlag)
+		inputDecoder := builder.InputDecoder(*inputDecoderFlag)
+		positionConverter := builder.PositionConverter(*positionConvFlag)
 		if err := builder.BuildParser(
 			outBuf, grammar, curNmOpt, optimizeParser, basicLatinOptimize,
-			nolintOpt, leftRecursionSupporter); err != nil {
+			nolintOpt, leftRecursionSupporter, byteMode, tracing, profiling,
+			coverage, inputDecoder, positionConverter, fuzzTestOpt); err != nil {
 			fmt.Fprintln(os.Stderr, "build error: ", err)
 			exit(5)
 		}
@@ -171,6 +259,15 @@ func main() {
 }
 
 var usagePage = `usage: %s [options] [GRAMMAR_FILE]
+       %s fmt [options] [GRAMMAR_FILE...]
+       %s doc [options] [GRAMMAR_FILE]
+       %s export [options] [GRAMMAR_FILE]
+       %s import [options] [GRAMMAR_FILE]
+       %s test [options] GRAMMAR_FILE [EXAMPLES_FILE...]
+       %s run [options] GRAMMAR_FILE [INPUT_FILE]
+       %s profile [options] GRAMMAR_FILE [INPUT_FILE...]
+       %s cover [options] GRAMMAR_FILE [INPUT_FILE...]
+       %s gen [options] GRAMMAR_FILE
 
 Pigeon generates a parser based on a PEG grammar.
 
@@ -179,14 +276,44 @@ generated parser to stdout. If GRAMMAR_FILE is specified, the
 grammar is read from this file instead. If the -o flag is set,
 the generated code is written to this file instead.
 
+	-byte-mode
+		generate a parser that matches the input byte by byte instead
+		of decoding UTF-8 runes, to parse binary data.
 	-cache
 		cache parser results to avoid exponential parsing time in
 		pathological cases. Can make the parsing slower for typical
 		cases and uses more memory.
+	-choice-stats STATS_FILE
+		reorder the alternatives of the choices by decreasing number
+		of matches in STATS_FILE, the JSON encoding of the
+		Stats.ChoiceAltCnt gathered by the generated parser with its
+		Statistics option. Only the alternatives that cannot match the
+		same input are reordered, the changes are printed to stderr.
+	-coverage
+		generate the Coverage option of the parser and its
+		CoverProfile type, to gather the coverage of the grammar.
+		Ignored with -optimize-parser.
 	-debug
 		output debugging information while parsing the grammar.
+	-diff-grammar
+		do not generate the parser, write the unified diff between the
+		grammar and the grammar optimized as with -optimize-grammar
+		instead.
+	-fuzz-test TEST_FILE
+		write a FuzzParse fuzz test of the parser to TEST_FILE,
+		seeded with the examples of the entrypoint and random
+		sentences of the grammar.
 	-h -help
 		display this help message.
+	-input-decoder
+		generate the InputDecoder option of the parser, its Decoder
+		type and the decoders of Latin-1 and UTF-16, to parse input
+		in other encodings than UTF-8. Ignored with -byte-mode.
+	-memo-profile PROFILE_FILE
+		memoize the rules that are evaluated again at the same
+		offsets in PROFILE_FILE, the JSON encoding of the Profile
+		gathered by a parser generated with -profiling.
+		The memoized rules are printed to stderr.
 	-nolint
 		add '// nolint: ...' comments for generated parser to suppress
 		warnings by gometalinter (https://github.com/alecthomas/gometalinter) or
@@ -196,6 +323,14 @@ the generated code is written to this file instead.
 		when debugging, otherwise the panic is converted to an error.
 	-o OUTPUT_FILE
 		write the generated parser to OUTPUT_FILE. Defaults to stdout.
+	-position-converter
+		generate the PositionConverter of the parser, to convert the
+		byte offsets of an input to lines and columns in bytes, runes,
+		UTF-16 code units or with tabs expanded, and its TabWidth
+		option.
+	-print-grammar
+		do not generate the parser, write the grammar as PEG source
+		instead. With -optimize-grammar, the optimized grammar is written.
 	-optimize-basic-latin
 		generate optimized parser for Unicode Basic Latin character set
 	-optimize-grammar
@@ -203,6 +338,10 @@ the generated code is written to this file instead.
 	-optimize-parser
 		generate optimized parser without Debug and Memoize options and
 		with some other optimizations applied.
+	-profiling
+		generate the Profiling option of the parser and its Profile
+		types, to gather the profile of the parse. Ignored with
+		-optimize-parser.
 	-receiver-name NAME
 		use NAME as for the receiver name of the generated methods
 		for the grammar's code blocks. Defaults to "c".
@@ -213,14 +352,178 @@ the generated code is written to this file instead.
 		entrypoints for the parser, in addition to the first rule in the
 		grammar.
 	-support-left-recursion
-		add support left recursion (EXPERIMENTAL FEATURE)
+		add support for left recursion.
+	-tracing
+		generate the Trace option of the parser and its Tracer,
+		Position and Span types, to receive the events of the parse.
+		Ignored with -optimize-parser.
+
+The fmt command formats grammars in the canonical style, see
+"pigeon fmt -h" for its options. The doc command generates the
+railroad diagrams of a grammar, see "pigeon doc -h". The export
+command converts a grammar to JSON, EBNF, PEG.js or tree-sitter, see
+"pigeon export -h", and the import command converts PEG.js and pegen
+grammars to pigeon grammars, see "pigeon import -h". The test command
+runs the examples of the rules of a grammar, see "pigeon test -h",
+the run command parses an input with a grammar without generating
+its parser, see "pigeon run -h", the profile command reports the
+profile of the parses of inputs with a grammar, see "pigeon profile -h",
+the cover command reports the coverage of a grammar by the parses of
+inputs, see "pigeon cover -h", and the gen command generates random
+sentences of a grammar, see "pigeon gen -h".
 
 See https://godoc.org/github.com/mna/pigeon for more information.
 `
 
+// writeGrammar writes g to w as PEG source, optimized if optimize is
+// set, for a parser that matches bytes if byteMode is set, and with its
+// choices reordered by choiceStats if it is not nil. If diff is set, it
+// writes the unified diff between g and the optimized g instead. Only
+// the doc comments of the rules are written, with the annotations that
+// change their meaning, as the other comments cannot be placed in the
+// optimized grammar.
+func writeGrammar(w io.Writer, filename string, g *ast.Grammar, optimize, diff, byteMode bool, altEntrypoints []string, choiceStats map[string]map[string]int) error {
+	var orig bytes.Buffer
+	if diff {
+		g.Comments = ruleDocs(g)
+		if err := ast.Fprint(&orig, g, nil); err != nil {
+			return err
+		}
+		optimize = true
+	}
+	if choiceStats != nil {
+		reorderChoices(g, choiceStats)
+	}
+	if optimize {
+		optimizeForParser(g, byteMode, altEntrypoints)
+	}
+
+	// the doc comments of the rules removed by the optimizations are dropped
+	g.Comments = ruleDocs(g)
+	var buf bytes.Buffer
+	if err := ast.Fprint(&buf, g, nil); err != nil {
+		return err
+	}
+	if diff {
+		_, err := w.Write(unifiedDiff(filename, filename+" (optimized)", orig.Bytes(), buf.Bytes()))
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// optimizeForParser optimizes g for a parser that matches bytes if
+// byteMode is set, or runes otherwise.
+func optimizeForParser(g *ast.Grammar, byteMode bool, altEntrypoints []string) {
+	if byteMode {
+		ast.OptimizeBytes(g, altEntrypoints...)
+		return
+	}
+	ast.Optimize(g, altEntrypoints...)
+}
+
+// ruleDocs returns the doc comments of the rules of g, in source order.
+func ruleDocs(g *ast.Grammar) []*ast.Comment {
+	var docs []*ast.Comment
+	for _, r := range g.Rules {
+		docs = append(docs, r.Doc...)
+	}
+	return docs
+}
+
+// fuzzSeedSentences is the number of random sentences of the grammar in
+// the seed corpus of the fuzz test written with -fuzz-test.
+const fuzzSeedSentences = 8
+
+// fuzzSeeds returns the seed corpus of the fuzz test of the parser of g,
+// read from the file filename: the examples of the doc comments of its
+// entrypoint, followed by random sentences. The sentences are the same
+// for a given grammar, and are omitted if they cannot be generated.
+func fuzzSeeds(filename string, g *ast.Grammar) [][]byte {
+	cases, err := pegtest.FromComments(filename, g)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse error(s):\n %v\n", err)
+		exit(3)
+	}
+	var seeds [][]byte
+	for _, c := range cases {
+		if len(g.Rules) > 0 && c.Rule == g.Rules[0].Name.Val {
+			seeds = append(seeds, []byte(c.Input))
+		}
+	}
+
+	gn, err := gen.New(g, &gen.Config{Rand: rand.New(rand.NewPCG(1, 2))})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "fuzz test: no random seeds:", err)
+		return seeds
+	}
+	for range fuzzSeedSentences {
+		b, err := gn.Sentence()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "fuzz test: no random seeds:", err)
+			break
+		}
+		seeds = append(seeds, b)
+	}
+	return seeds
+}
+
+// memoizeRules memoizes the rules of g that are revisited the most in the
+// profile read from the file filename, and prints them to stderr.
+func memoizeRules(g *ast.Grammar, filename string) {
+	f, err := os.Open(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "read error:\n", err)
+		exit(2)
+	}
+	prof, err := profile.Read(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read error:\n %s: %v\n", filename, err)
+		exit(2)
+	}
+
+	names := prof.MemoRules(profile.DefaultMemoRatio)
+	for _, r := range g.Rules {
+		if _, ok := slices.BinarySearch(names, r.Name.Val); ok && !r.Memoized() {
+			e := prof.Rules[r.Name.Val]
+			r.Memoize = true
+			fmt.Fprintf(os.Stderr, "rule %s: memoized, %d revisits and %d memo hits of %d evaluations\n",
+				r.Name.Val, e.Revisits, e.MemoHits, e.Calls+e.MemoHits)
+		}
+	}
+}
+
+// readChoiceStats reads the JSON encoding of the Stats.ChoiceAltCnt of
+// a generated parser from the file filename.
+func readChoiceStats(filename string) map[string]map[string]int {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "read error:\n", err)
+		exit(2)
+	}
+	var stats map[string]map[string]int
+	if err := json.Unmarshal(b, &stats); err != nil {
+		fmt.Fprintf(os.Stderr, "read error:\n invalid choice statistics %s: %v\n", filename, err)
+		exit(2)
+	}
+	if stats == nil {
+		stats = make(map[string]map[string]int)
+	}
+	return stats
+}
+
+// reorderChoices reorders the alternatives of the choices of g by the
+// counts of stats and prints the changes to stderr.
+func reorderChoices(g *ast.Grammar, stats map[string]map[string]int) {
+	for _, c := range ast.ReorderChoices(g, stats) {
+		fmt.Fprintln(os.Stderr, c)
+	}
+}
+
 // usage prints the help page of the command-line tool.
 func usage() {
-	fmt.Printf(usagePage, os.Args[0])
+	fmt.Printf(usagePage, os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0])
 }
 
 // argError prints an error message to stderr, prints the command usage
@@ -271,31 +574,3 @@ func makeReadCloser(r io.Reader, c io.Closer) io.ReadCloser {
 	}{r, c}
 	return io.ReadCloser(rc)
 }
-
-// astPos is a helper method for the PEG grammar parser. It returns the
-// position of the current match as an ast.Pos.
-func (c *current) astPos() ast.Pos {
-	return ast.Pos{Line: c.pos.line, Col: c.pos.col, Off: c.pos.offset}
-}
-
-// toAnySlice is a helper function for the PEG grammar parser. It converts
-// v to a slice of empty interfaces.
-func toAnySlice(v any) []any {
-	if v == nil {
-		return nil
-	}
-	return v.([]any)
-}
-
-// validateUnicodeEscape checks that the provided escape sequence is a
-// valid Unicode escape sequence.
-func validateUnicodeEscape(escape, errMsg string) (any, error) {
-	r, _, _, err := strconv.UnquoteChar("\\"+escape, '"')
-	if err != nil {
-		return nil, errors.New(errMsg)
-	}
-	if 0xD800 <= r && r <= 0xDFFF {
-		return nil, errors.New(errMsg)
-	}
-	return nil, nil
-}
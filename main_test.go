@@ -1,9 +1,20 @@
 package main
 
 import (
+	"bytes"
+	stdjson "encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/examples/json"
+	"github.com/mna/pigeon/interp"
+	"github.com/mna/pigeon/parse"
+	"github.com/mna/pigeon/profile"
+	"github.com/mna/pigeon/testutils"
 )
 
 func TestMain(t *testing.T) {
@@ -23,10 +34,31 @@ func TestMain(t *testing.T) {
 		args string
 		code int
 	}{
-		{args: "", code: 3},            // stdin: no match found
-		{args: "-h", code: 0},          // help
-		{args: "FILE1 FILE2", code: 1}, // want only 1 non-flag arg
-		{args: "-x", code: 3},          // stdin: no match found
+		{args: "", code: 3},                  // stdin: no match found
+		{args: "-h", code: 0},                // help
+		{args: "FILE1 FILE2", code: 1},       // want only 1 non-flag arg
+		{args: "-x", code: 3},                // stdin: no match found
+		{args: "fmt -h", code: 0},            // fmt help
+		{args: "fmt -w", code: 1},            // fmt: -w requires files
+		{args: "doc -h", code: 0},            // doc help
+		{args: "doc -format pdf", code: 1},   // doc: invalid format
+		{args: "export -h", code: 0},         // export help
+		{args: "export -from yaml", code: 1}, // export: invalid input format
+		{args: "import -h", code: 0},         // import help
+		{args: "import", code: 1},            // import: -from required for stdin
+		{args: "test -h", code: 0},           // test help
+		{args: "test", code: 1},              // test: grammar file required
+		{args: "run -h", code: 0},            // run help
+		{args: "run", code: 1},               // run: grammar file required
+		{args: "profile -h", code: 0},        // profile help
+		{args: "profile", code: 1},           // profile: grammar file required
+		{args: "cover -h", code: 0},          // cover help
+		{args: "cover", code: 1},             // cover: grammar file required
+		{args: "gen -h", code: 0},            // gen help
+		{args: "gen", code: 1},               // gen: grammar file required
+		{args: "-x -choice-stats testdata/missing.json grammar/pigeon.peg", code: 2}, // unreadable choice statistics
+		{args: "-fuzz-test testdata/missing/x_test.go test/fuzz/fuzz.peg", code: 4},  // fuzz test not created
+		{args: "-memo-profile testdata/missing.json test/memo/memo.peg", code: 2},    // unreadable profile
 	}
 
 	for _, tc := range cases {
@@ -52,3 +84,230 @@ func runMainRecover() (code int) {
 	main()
 	return 0
 }
+
+func TestWriteOptimizedGrammar(t *testing.T) {
+	files, err := filepath.Glob("grammar/*.peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pattern := range []string{"examples/*/*.peg", "test/*/*.peg"} {
+		more, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, more...)
+	}
+
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, err := parse.ParseGrammar(file, bytes.NewReader(src), parse.Validate(false))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		if err := writeGrammar(&buf, file, g, true, false, false, nil, nil); err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		got, err := parse.ParseGrammar(file, bytes.NewReader(buf.Bytes()), parse.Validate(false))
+		if err != nil {
+			t.Errorf("%s: optimized grammar does not parse: %v\n%s", file, err, buf.Bytes())
+			continue
+		}
+		testutils.CompareGrammars(t, file, normalizeCode(g), normalizeCode(got))
+	}
+}
+
+func TestWriteGrammarDiff(t *testing.T) {
+	src := `A = B 'x' / C
+B = 'b'
+C = 'c' / 'd'
+`
+	want := `--- g.peg
++++ g.peg (optimized)
+@@ -1,3 +1 @@
+-A ← B 'x' / C
+-B ← 'b'
+-C ← 'c' / 'd'
++A ← "bx" / [cd]
+`
+	g, err := parse.ParseGrammar("g.peg", strings.NewReader(src), parse.Validate(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := writeGrammar(&buf, "g.peg", g, false, true, false, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestWriteGrammarAnnotations(t *testing.T) {
+	src := `// A is the entrypoint.
+A = B C
+
+// B is memoized.
+// @memoize
+B = 'b' / 'x'
+
+// C is inlined, its doc is dropped.
+C = 'c'
+`
+	want := `--- g.peg
++++ g.peg (optimized)
+@@ -1,9 +1,6 @@
+ // A is the entrypoint.
+-A ← B C
++A ← B 'c'
+ 
+ // B is memoized.
+ // @memoize
+-B ← 'b' / 'x'
+-
+-// C is inlined, its doc is dropped.
+-C ← 'c'
++B ← [bx]
+`
+	g, err := parse.ParseGrammar("g.peg", strings.NewReader(src), parse.Validate(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := writeGrammar(&buf, "g.peg", g, false, true, false, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+// TestWritePrecedenceGrammar prints the grammar with a precedence table,
+// as written and optimized, and checks that the printed grammar parses
+// back to the same table.
+func TestWritePrecedenceGrammar(t *testing.T) {
+	const file = "test/precedence/precedence.peg"
+	src, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, optimize := range []bool{false, true} {
+		g, err := parse.ParseGrammar(file, bytes.NewReader(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := g.Rules[1].Precedence()
+		if err != nil || want == nil {
+			t.Fatalf("want the precedence table of %s, got %v, %v", g.Rules[1].Name.Val, want, err)
+		}
+
+		var buf bytes.Buffer
+		if err := writeGrammar(&buf, file, g, optimize, false, false, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		got, err := parse.ParseGrammar(file, &buf)
+		if err != nil {
+			t.Errorf("optimize %t: printed grammar does not parse: %v", optimize, err)
+			continue
+		}
+		var table *ast.PrecedenceTable
+		for _, r := range got.Rules {
+			if r.Name.Val == "Expr" {
+				table, err = r.Precedence()
+			}
+		}
+		if err != nil || fmt.Sprint(table) != fmt.Sprint(want) {
+			t.Errorf("optimize %t: want the table %v, got %v, %v", optimize, want, table, err)
+		}
+	}
+}
+
+// TestReorderChoicesConformance reorders the choices of the JSON grammar
+// by the statistics of its generated parser and checks that the parses
+// are unchanged.
+func TestReorderChoicesConformance(t *testing.T) {
+	const file = "examples/json/json.peg"
+	inputs, err := filepath.Glob("examples/json/testdata/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srcs := [][]byte{[]byte(`[1, -2.5e3, "aé\n", {"t": true, "f": false}, null]`), []byte(`{"x": tru}`), []byte(`[01]`)}
+	for _, in := range inputs {
+		b, err := os.ReadFile(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srcs = append(srcs, b)
+	}
+
+	stats := json.Stats{}
+	for _, src := range srcs {
+		json.Parse(file, src, json.Statistics(&stats, "no match"))
+	}
+
+	src, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parseTrees := func(g *ast.Grammar) []string {
+		p, err := interp.New(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var trees []string
+		for _, src := range srcs {
+			n, err := p.ParseTree(file, src)
+			trees = append(trees, fmt.Sprintf("%v\n%v", n, err))
+		}
+		return trees
+	}
+	g, err := readGrammar(file, src, "peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := parseTrees(g)
+
+	changes := ast.ReorderChoices(g, stats.ChoiceAltCnt)
+	if len(changes) == 0 {
+		t.Fatal("want reordered choices, got none")
+	}
+	got := parseTrees(g)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%d: want\n%s\ngot\n%s", i, want[i], got[i])
+		}
+	}
+}
+
+func TestMemoizeRules(t *testing.T) {
+	g, err := readGrammar("", []byte("Expr ← Term '+' Expr / Term '-' Expr / Term\nTerm ← [0-9]+\n"), "peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := interp.New(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var prof profile.Profile
+	if _, err := p.Parse("", []byte("1-2"), interp.Profile(&prof)); err != nil {
+		t.Fatal(err)
+	}
+	b, err := stdjson.Marshal(&prof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "profile.json")
+	if err := os.WriteFile(file, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	memoizeRules(g, file)
+	if g.Rules[0].Memoize || !g.Rules[1].Memoize {
+		t.Errorf("want only Term memoized, got Expr %t and Term %t", g.Rules[0].Memoize, g.Rules[1].Memoize)
+	}
+}
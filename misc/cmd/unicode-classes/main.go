@@ -31,7 +31,7 @@ func main() {
 // Do not edit.
 
 `)
-	fmt.Println("package main")
+	fmt.Println("package parser")
 	fmt.Println("\nvar unicodeClasses = map[string]bool{")
 	for _, s := range classes {
 		fmt.Printf("\t%q: true,\n", s)
@@ -1,24 +1,26 @@
-package main
+package parser
 
 import (
 	"testing"
 
 	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/testutils"
 )
 
 var invalidParseCases = map[string]string{
-	"":           `file:1:1 (0): no match found, expected: "/*", "//", "\n", "{", [ \t\r] or [\pL_]`,
-	"a":          `file:1:2 (1): no match found, expected: "'", "/*", "//", "<-", "=", "\"", "\n", "` + "`" + `", "←", "⟵", [ \t\r], [\pL_] or [\p{Nd}]`,
-	"abc":        `file:1:4 (3): no match found, expected: "'", "/*", "//", "<-", "=", "\"", "\n", "` + "`" + `", "←", "⟵", [ \t\r], [\pL_] or [\p{Nd}]`,
-	" ":          `file:1:2 (1): no match found, expected: "/*", "//", "\n", "{", [ \t\r] or [\pL_]`,
-	`a = +`:      `file:1:5 (4): no match found, expected: "!", "#", "%", "&", "'", "(", ".", "/*", "//", "[", "\"", "\n", "` + "`" + `", [ \t\r] or [\pL_]`,
-	`a = *`:      `file:1:5 (4): no match found, expected: "!", "#", "%", "&", "'", "(", ".", "/*", "//", "[", "\"", "\n", "` + "`" + `", [ \t\r] or [\pL_]`,
-	`a = ?`:      `file:1:5 (4): no match found, expected: "!", "#", "%", "&", "'", "(", ".", "/*", "//", "[", "\"", "\n", "` + "`" + `", [ \t\r] or [\pL_]`,
-	"a ←":        `file:1:4 (5): no match found, expected: "!", "#", "%", "&", "'", "(", ".", "/*", "//", "[", "\"", "\n", "` + "`" + `", [ \t\r] or [\pL_]`,
-	"a ← b\nb ←": `file:2:4 (13): no match found, expected: "!", "#", "%", "&", "'", "(", ".", "/*", "//", "[", "\"", "\n", "` + "`" + `", [ \t\r] or [\pL_]`,
-	"a ← nil:b":  "file:1:5 (6): rule Identifier: identifier is a reserved word",
-	"\xfe":       "file:1:1 (0): invalid encoding",
-	"{}{}":       `file:1:3 (2): no match found, expected: "/*", "//", ";", "\n", [ \t\r] or EOF`,
+	"":             `file:1:1 (0): no match found, expected: "/*", "//", "\n", "{", [ \t\r] or [\pL_]`,
+	"a":            `file:1:2 (1): no match found, expected: "'", "/*", "//", "<-", "=", "\"", "\n", "` + "`" + `", "←", "⟵", [ \t\r], [\pL_] or [\p{Nd}]`,
+	"abc":          `file:1:4 (3): no match found, expected: "'", "/*", "//", "<-", "=", "\"", "\n", "` + "`" + `", "←", "⟵", [ \t\r], [\pL_] or [\p{Nd}]`,
+	" ":            `file:1:2 (1): no match found, expected: "/*", "//", "\n", "{", [ \t\r] or [\pL_]`,
+	`a = +`:        `file:1:5 (4): no match found, expected: "!", "#", "%", "&", "'", "(", ".", "/*", "//", "[", "\"", "\\", "\n", "` + "`" + `", [ \t\r] or [\pL_]`,
+	`a = %INDENTS`: `file:1:12 (11): no match found, expected: ![\pL_]`,
+	`a = *`:        `file:1:5 (4): no match found, expected: "!", "#", "%", "&", "'", "(", ".", "/*", "//", "[", "\"", "\\", "\n", "` + "`" + `", [ \t\r] or [\pL_]`,
+	`a = ?`:        `file:1:5 (4): no match found, expected: "!", "#", "%", "&", "'", "(", ".", "/*", "//", "[", "\"", "\\", "\n", "` + "`" + `", [ \t\r] or [\pL_]`,
+	"a ←":          `file:1:4 (5): no match found, expected: "!", "#", "%", "&", "'", "(", ".", "/*", "//", "[", "\"", "\\", "\n", "` + "`" + `", [ \t\r] or [\pL_]`,
+	"a ← b\nb ←":   `file:2:4 (13): no match found, expected: "!", "#", "%", "&", "'", "(", ".", "/*", "//", "[", "\"", "\\", "\n", "` + "`" + `", [ \t\r] or [\pL_]`,
+	"a ← nil:b":    "file:1:5 (6): rule Identifier: identifier is a reserved word",
+	"\xfe":         "file:1:1 (0): invalid encoding",
+	"{}{}":         `file:1:3 (2): no match found, expected: "/*", "//", ";", "\n", [ \t\r] or EOF`,
 
 	// non-terminated, empty, EOF "quoted" tokens
 	"{":         "file:1:1 (0): rule CodeBlock: code block not terminated",
@@ -412,6 +414,22 @@ var validParseCases = map[string]*ast.Grammar{
 			},
 		},
 	},
+	`a = l:"x" \l`: {
+		Rules: []*ast.Rule{
+			{
+				Name: ast.NewIdentifier(ast.Pos{}, "a"),
+				Expr: &ast.SeqExpr{
+					Exprs: []ast.Expression{
+						&ast.LabeledExpr{
+							Label: ast.NewIdentifier(ast.Pos{}, "l"),
+							Expr:  ast.NewLitMatcher(ast.Pos{}, "x"),
+						},
+						&ast.BackRefExpr{Label: ast.NewIdentifier(ast.Pos{}, "l")},
+					},
+				},
+			},
+		},
+	},
 	"a = ``": {
 		Rules: []*ast.Rule{
 			{
@@ -420,6 +438,37 @@ var validParseCases = map[string]*ast.Grammar{
 			},
 		},
 	},
+	"// a doc\n/* more\n   doc */\na = b // not doc\n\n  // not doc\nb = 'b'": {
+		Rules: []*ast.Rule{
+			{
+				Name: ast.NewIdentifier(ast.Pos{}, "a"),
+				Doc: []*ast.Comment{
+					ast.NewComment(ast.Pos{}, "// a doc"),
+					ast.NewComment(ast.Pos{}, "/* more\n   doc */"),
+				},
+				Expr: &ast.RuleRefExpr{Name: ast.NewIdentifier(ast.Pos{}, "b")},
+			},
+			{
+				Name: ast.NewIdentifier(ast.Pos{}, "b"),
+				Expr: ast.NewLitMatcher(ast.Pos{}, "b"),
+			},
+		},
+	},
+	"a = %INDENT b+ %DEDENT %SAMEDENT": {
+		Rules: []*ast.Rule{
+			{
+				Name: ast.NewIdentifier(ast.Pos{}, "a"),
+				Expr: &ast.SeqExpr{
+					Exprs: []ast.Expression{
+						ast.NewIndentExpr(ast.Pos{}, ast.IndentKindIndent),
+						&ast.OneOrMoreExpr{Expr: &ast.RuleRefExpr{Name: ast.NewIdentifier(ast.Pos{}, "b")}},
+						ast.NewIndentExpr(ast.Pos{}, ast.IndentKindDedent),
+						ast.NewIndentExpr(ast.Pos{}, ast.IndentKindSamedent),
+					},
+				},
+			},
+		},
+	},
 }
 
 func TestValidParseCases(t *testing.T) {
@@ -436,7 +485,7 @@ again:
 			t.Errorf("%q: want grammar type %T, got %T", tc, exp, got)
 			continue
 		}
-		compareGrammars(t, tc, exp, gotg)
+		testutils.CompareGrammars(t, tc, exp, gotg)
 	}
 	if !memo {
 		memo = true
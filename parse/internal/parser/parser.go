@@ -0,0 +1,102 @@
+// Package parser is the parser of pigeon grammars, generated from
+// grammar/pigeon.peg. It is internal to the parse package, which exposes it
+// through ParseGrammar.
+package parser
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mna/pigeon/ast"
+)
+
+// astPos is a helper method for the PEG grammar parser. It returns the
+// position of the current match as an ast.Pos.
+func (c *current) astPos() ast.Pos {
+	return ast.Pos{Line: c.pos.line, Col: c.pos.col, Off: c.pos.offset}
+}
+
+// commentsKey is the key of the comments of the grammar in the global
+// store of the PEG grammar parser.
+const commentsKey = "comments"
+
+// addComment is a helper method for the PEG grammar parser. It records the
+// current match as a comment of the grammar. A comment may be matched more
+// than once when the parser backtracks, so comments are keyed by offset.
+func (c *current) addComment() {
+	comments, _ := c.globalStore[commentsKey].(map[int]*ast.Comment)
+	if comments == nil {
+		comments = make(map[int]*ast.Comment)
+		c.globalStore[commentsKey] = comments
+	}
+	if _, ok := comments[c.pos.offset]; !ok {
+		comments[c.pos.offset] = ast.NewComment(c.astPos(), string(c.text))
+	}
+}
+
+// comments is a helper method for the PEG grammar parser. It returns the
+// comments recorded by addComment, in source order.
+func (c *current) comments() []*ast.Comment {
+	comments, _ := c.globalStore[commentsKey].(map[int]*ast.Comment)
+	if len(comments) == 0 {
+		return nil
+	}
+	list := make([]*ast.Comment, 0, len(comments))
+	for _, cmt := range comments {
+		list = append(list, cmt)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Pos().Off < list[j].Pos().Off })
+	return list
+}
+
+// setRuleDocs is a helper function for the PEG grammar parser. It sets the
+// doc comments of the rules of g from its comments: the consecutive
+// comments that end on the line before a rule and start at its column.
+func setRuleDocs(g *ast.Grammar) {
+	ci := 0
+	for _, r := range g.Rules {
+		start := ci
+		for ci < len(g.Comments) && g.Comments[ci].Pos().Off < r.Pos().Off {
+			ci++
+		}
+
+		line := r.Pos().Line
+		i := ci
+		for i > start {
+			c := g.Comments[i-1]
+			if c.Pos().Col != r.Pos().Col || c.Pos().Line+strings.Count(c.Val, "\n") != line-1 {
+				break
+			}
+			line = c.Pos().Line
+			i--
+		}
+		if i < ci {
+			r.Doc = slices.Clone(g.Comments[i:ci])
+		}
+	}
+}
+
+// toAnySlice is a helper function for the PEG grammar parser. It converts
+// v to a slice of empty interfaces.
+func toAnySlice(v any) []any {
+	if v == nil {
+		return nil
+	}
+	return v.([]any)
+}
+
+// validateUnicodeEscape checks that the provided escape sequence is a
+// valid Unicode escape sequence.
+func validateUnicodeEscape(escape, errMsg string) (any, error) {
+	r, _, _, err := strconv.UnquoteChar("\\"+escape, '"')
+	if err != nil {
+		return nil, errors.New(errMsg)
+	}
+	if 0xD800 <= r && r <= 0xDFFF {
+		return nil, errors.New(errMsg)
+	}
+	return nil, nil
+}
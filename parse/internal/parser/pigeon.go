@@ -1,6 +1,6 @@
 // Code generated by pigeon; DO NOT EDIT.
 
-package main
+package parser
 
 import (
 	"bytes"
@@ -23,31 +23,31 @@ var g = &grammar{
 	rules: []*rule{
 		{
 			name: "Grammar",
-			pos:  position{line: 5, col: 1, offset: 18},
+			pos:  position{line: 5, col: 1, offset: 20},
 			expr: &actionExpr{
-				pos: position{line: 5, col: 11, offset: 30},
+				pos: position{line: 5, col: 11, offset: 32},
 				run: (*parser).callonGrammar1,
 				expr: &seqExpr{
-					pos: position{line: 5, col: 11, offset: 30},
+					pos: position{line: 5, col: 11, offset: 32},
 					exprs: []any{
 						&ruleRefExpr{
-							pos:  position{line: 5, col: 11, offset: 30},
+							pos:  position{line: 5, col: 11, offset: 32},
 							name: "__",
 						},
 						&labeledExpr{
-							pos:   position{line: 5, col: 14, offset: 33},
+							pos:   position{line: 5, col: 14, offset: 35},
 							label: "initializer",
 							expr: &zeroOrOneExpr{
-								pos: position{line: 5, col: 26, offset: 45},
+								pos: position{line: 5, col: 26, offset: 47},
 								expr: &seqExpr{
-									pos: position{line: 5, col: 28, offset: 47},
+									pos: position{line: 5, col: 28, offset: 49},
 									exprs: []any{
 										&ruleRefExpr{
-											pos:  position{line: 5, col: 28, offset: 47},
+											pos:  position{line: 5, col: 28, offset: 49},
 											name: "Initializer",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 5, col: 40, offset: 59},
+											pos:  position{line: 5, col: 40, offset: 61},
 											name: "__",
 										},
 									},
@@ -55,19 +55,19 @@ var g = &grammar{
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 5, col: 46, offset: 65},
+							pos:   position{line: 5, col: 46, offset: 67},
 							label: "rules",
 							expr: &oneOrMoreExpr{
-								pos: position{line: 5, col: 52, offset: 71},
+								pos: position{line: 5, col: 52, offset: 73},
 								expr: &seqExpr{
-									pos: position{line: 5, col: 54, offset: 73},
+									pos: position{line: 5, col: 54, offset: 75},
 									exprs: []any{
 										&ruleRefExpr{
-											pos:  position{line: 5, col: 54, offset: 73},
+											pos:  position{line: 5, col: 54, offset: 75},
 											name: "Rule",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 5, col: 59, offset: 78},
+											pos:  position{line: 5, col: 59, offset: 80},
 											name: "__",
 										},
 									},
@@ -75,7 +75,7 @@ var g = &grammar{
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 5, col: 65, offset: 84},
+							pos:  position{line: 5, col: 65, offset: 86},
 							name: "EOF",
 						},
 					},
@@ -84,23 +84,23 @@ var g = &grammar{
 		},
 		{
 			name: "Initializer",
-			pos:  position{line: 24, col: 1, offset: 513},
+			pos:  position{line: 26, col: 1, offset: 564},
 			expr: &actionExpr{
-				pos: position{line: 24, col: 15, offset: 529},
+				pos: position{line: 26, col: 15, offset: 580},
 				run: (*parser).callonInitializer1,
 				expr: &seqExpr{
-					pos: position{line: 24, col: 15, offset: 529},
+					pos: position{line: 26, col: 15, offset: 580},
 					exprs: []any{
 						&labeledExpr{
-							pos:   position{line: 24, col: 15, offset: 529},
+							pos:   position{line: 26, col: 15, offset: 580},
 							label: "code",
 							expr: &ruleRefExpr{
-								pos:  position{line: 24, col: 20, offset: 534},
+								pos:  position{line: 26, col: 20, offset: 585},
 								name: "CodeBlock",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 24, col: 30, offset: 544},
+							pos:  position{line: 26, col: 30, offset: 595},
 							name: "EOS",
 						},
 					},
@@ -109,39 +109,39 @@ var g = &grammar{
 		},
 		{
 			name: "Rule",
-			pos:  position{line: 28, col: 1, offset: 574},
+			pos:  position{line: 30, col: 1, offset: 625},
 			expr: &actionExpr{
-				pos: position{line: 28, col: 8, offset: 583},
+				pos: position{line: 30, col: 8, offset: 634},
 				run: (*parser).callonRule1,
 				expr: &seqExpr{
-					pos: position{line: 28, col: 8, offset: 583},
+					pos: position{line: 30, col: 8, offset: 634},
 					exprs: []any{
 						&labeledExpr{
-							pos:   position{line: 28, col: 8, offset: 583},
+							pos:   position{line: 30, col: 8, offset: 634},
 							label: "name",
 							expr: &ruleRefExpr{
-								pos:  position{line: 28, col: 13, offset: 588},
+								pos:  position{line: 30, col: 13, offset: 639},
 								name: "IdentifierName",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 28, col: 28, offset: 603},
+							pos:  position{line: 30, col: 28, offset: 654},
 							name: "__",
 						},
 						&labeledExpr{
-							pos:   position{line: 28, col: 31, offset: 606},
+							pos:   position{line: 30, col: 31, offset: 657},
 							label: "display",
 							expr: &zeroOrOneExpr{
-								pos: position{line: 28, col: 39, offset: 614},
+								pos: position{line: 30, col: 39, offset: 665},
 								expr: &seqExpr{
-									pos: position{line: 28, col: 41, offset: 616},
+									pos: position{line: 30, col: 41, offset: 667},
 									exprs: []any{
 										&ruleRefExpr{
-											pos:  position{line: 28, col: 41, offset: 616},
+											pos:  position{line: 30, col: 41, offset: 667},
 											name: "StringLiteral",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 28, col: 55, offset: 630},
+											pos:  position{line: 30, col: 55, offset: 681},
 											name: "__",
 										},
 									},
@@ -149,23 +149,23 @@ var g = &grammar{
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 28, col: 61, offset: 636},
+							pos:  position{line: 30, col: 61, offset: 687},
 							name: "RuleDefOp",
 						},
 						&ruleRefExpr{
-							pos:  position{line: 28, col: 71, offset: 646},
+							pos:  position{line: 30, col: 71, offset: 697},
 							name: "__",
 						},
 						&labeledExpr{
-							pos:   position{line: 28, col: 74, offset: 649},
+							pos:   position{line: 30, col: 74, offset: 700},
 							label: "expr",
 							expr: &ruleRefExpr{
-								pos:  position{line: 28, col: 79, offset: 654},
+								pos:  position{line: 30, col: 79, offset: 705},
 								name: "Expression",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 28, col: 90, offset: 665},
+							pos:  position{line: 30, col: 90, offset: 716},
 							name: "EOS",
 						},
 					},
@@ -174,71 +174,71 @@ var g = &grammar{
 		},
 		{
 			name: "Expression",
-			pos:  position{line: 41, col: 1, offset: 947},
+			pos:  position{line: 43, col: 1, offset: 998},
 			expr: &ruleRefExpr{
-				pos:  position{line: 41, col: 14, offset: 962},
+				pos:  position{line: 43, col: 14, offset: 1013},
 				name: "RecoveryExpr",
 			},
 		},
 		{
 			name: "RecoveryExpr",
-			pos:  position{line: 43, col: 1, offset: 976},
+			pos:  position{line: 45, col: 1, offset: 1027},
 			expr: &actionExpr{
-				pos: position{line: 43, col: 16, offset: 993},
+				pos: position{line: 45, col: 16, offset: 1044},
 				run: (*parser).callonRecoveryExpr1,
 				expr: &seqExpr{
-					pos: position{line: 43, col: 16, offset: 993},
+					pos: position{line: 45, col: 16, offset: 1044},
 					exprs: []any{
 						&labeledExpr{
-							pos:   position{line: 43, col: 16, offset: 993},
+							pos:   position{line: 45, col: 16, offset: 1044},
 							label: "expr",
 							expr: &ruleRefExpr{
-								pos:  position{line: 43, col: 21, offset: 998},
+								pos:  position{line: 45, col: 21, offset: 1049},
 								name: "ChoiceExpr",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 43, col: 32, offset: 1009},
+							pos:   position{line: 45, col: 32, offset: 1060},
 							label: "recoverExprs",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 43, col: 45, offset: 1022},
+								pos: position{line: 45, col: 45, offset: 1073},
 								expr: &seqExpr{
-									pos: position{line: 43, col: 47, offset: 1024},
+									pos: position{line: 45, col: 47, offset: 1075},
 									exprs: []any{
 										&ruleRefExpr{
-											pos:  position{line: 43, col: 47, offset: 1024},
+											pos:  position{line: 45, col: 47, offset: 1075},
 											name: "__",
 										},
 										&litMatcher{
-											pos:        position{line: 43, col: 50, offset: 1027},
+											pos:        position{line: 45, col: 50, offset: 1078},
 											val:        "//{",
 											ignoreCase: false,
 											want:       "\"//{\"",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 43, col: 56, offset: 1033},
+											pos:  position{line: 45, col: 56, offset: 1084},
 											name: "__",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 43, col: 59, offset: 1036},
+											pos:  position{line: 45, col: 59, offset: 1087},
 											name: "Labels",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 43, col: 66, offset: 1043},
+											pos:  position{line: 45, col: 66, offset: 1094},
 											name: "__",
 										},
 										&litMatcher{
-											pos:        position{line: 43, col: 69, offset: 1046},
+											pos:        position{line: 45, col: 69, offset: 1097},
 											val:        "}",
 											ignoreCase: false,
 											want:       "\"}\"",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 43, col: 73, offset: 1050},
+											pos:  position{line: 45, col: 73, offset: 1101},
 											name: "__",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 43, col: 76, offset: 1053},
+											pos:  position{line: 45, col: 76, offset: 1104},
 											name: "ChoiceExpr",
 										},
 									},
@@ -251,45 +251,45 @@ var g = &grammar{
 		},
 		{
 			name: "Labels",
-			pos:  position{line: 58, col: 1, offset: 1449},
+			pos:  position{line: 60, col: 1, offset: 1500},
 			expr: &actionExpr{
-				pos: position{line: 58, col: 10, offset: 1460},
+				pos: position{line: 60, col: 10, offset: 1511},
 				run: (*parser).callonLabels1,
 				expr: &seqExpr{
-					pos: position{line: 58, col: 10, offset: 1460},
+					pos: position{line: 60, col: 10, offset: 1511},
 					exprs: []any{
 						&labeledExpr{
-							pos:   position{line: 58, col: 10, offset: 1460},
+							pos:   position{line: 60, col: 10, offset: 1511},
 							label: "label",
 							expr: &ruleRefExpr{
-								pos:  position{line: 58, col: 16, offset: 1466},
+								pos:  position{line: 60, col: 16, offset: 1517},
 								name: "IdentifierName",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 58, col: 31, offset: 1481},
+							pos:   position{line: 60, col: 31, offset: 1532},
 							label: "labels",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 58, col: 38, offset: 1488},
+								pos: position{line: 60, col: 38, offset: 1539},
 								expr: &seqExpr{
-									pos: position{line: 58, col: 40, offset: 1490},
+									pos: position{line: 60, col: 40, offset: 1541},
 									exprs: []any{
 										&ruleRefExpr{
-											pos:  position{line: 58, col: 40, offset: 1490},
+											pos:  position{line: 60, col: 40, offset: 1541},
 											name: "__",
 										},
 										&litMatcher{
-											pos:        position{line: 58, col: 43, offset: 1493},
+											pos:        position{line: 60, col: 43, offset: 1544},
 											val:        ",",
 											ignoreCase: false,
 											want:       "\",\"",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 58, col: 47, offset: 1497},
+											pos:  position{line: 60, col: 47, offset: 1548},
 											name: "__",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 58, col: 50, offset: 1500},
+											pos:  position{line: 60, col: 50, offset: 1551},
 											name: "IdentifierName",
 										},
 									},
@@ -302,45 +302,45 @@ var g = &grammar{
 		},
 		{
 			name: "ChoiceExpr",
-			pos:  position{line: 67, col: 1, offset: 1819},
+			pos:  position{line: 69, col: 1, offset: 1870},
 			expr: &actionExpr{
-				pos: position{line: 67, col: 14, offset: 1834},
+				pos: position{line: 69, col: 14, offset: 1885},
 				run: (*parser).callonChoiceExpr1,
 				expr: &seqExpr{
-					pos: position{line: 67, col: 14, offset: 1834},
+					pos: position{line: 69, col: 14, offset: 1885},
 					exprs: []any{
 						&labeledExpr{
-							pos:   position{line: 67, col: 14, offset: 1834},
+							pos:   position{line: 69, col: 14, offset: 1885},
 							label: "first",
 							expr: &ruleRefExpr{
-								pos:  position{line: 67, col: 20, offset: 1840},
+								pos:  position{line: 69, col: 20, offset: 1891},
 								name: "ActionExpr",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 67, col: 31, offset: 1851},
+							pos:   position{line: 69, col: 31, offset: 1902},
 							label: "rest",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 67, col: 36, offset: 1856},
+								pos: position{line: 69, col: 36, offset: 1907},
 								expr: &seqExpr{
-									pos: position{line: 67, col: 38, offset: 1858},
+									pos: position{line: 69, col: 38, offset: 1909},
 									exprs: []any{
 										&ruleRefExpr{
-											pos:  position{line: 67, col: 38, offset: 1858},
+											pos:  position{line: 69, col: 38, offset: 1909},
 											name: "__",
 										},
 										&litMatcher{
-											pos:        position{line: 67, col: 41, offset: 1861},
+											pos:        position{line: 69, col: 41, offset: 1912},
 											val:        "/",
 											ignoreCase: false,
 											want:       "\"/\"",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 67, col: 45, offset: 1865},
+											pos:  position{line: 69, col: 45, offset: 1916},
 											name: "__",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 67, col: 48, offset: 1868},
+											pos:  position{line: 69, col: 48, offset: 1919},
 											name: "ActionExpr",
 										},
 									},
@@ -353,35 +353,35 @@ var g = &grammar{
 		},
 		{
 			name: "ActionExpr",
-			pos:  position{line: 82, col: 1, offset: 2263},
+			pos:  position{line: 84, col: 1, offset: 2314},
 			expr: &actionExpr{
-				pos: position{line: 82, col: 14, offset: 2278},
+				pos: position{line: 84, col: 14, offset: 2329},
 				run: (*parser).callonActionExpr1,
 				expr: &seqExpr{
-					pos: position{line: 82, col: 14, offset: 2278},
+					pos: position{line: 84, col: 14, offset: 2329},
 					exprs: []any{
 						&labeledExpr{
-							pos:   position{line: 82, col: 14, offset: 2278},
+							pos:   position{line: 84, col: 14, offset: 2329},
 							label: "expr",
 							expr: &ruleRefExpr{
-								pos:  position{line: 82, col: 19, offset: 2283},
+								pos:  position{line: 84, col: 19, offset: 2334},
 								name: "SeqExpr",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 82, col: 27, offset: 2291},
+							pos:   position{line: 84, col: 27, offset: 2342},
 							label: "code",
 							expr: &zeroOrOneExpr{
-								pos: position{line: 82, col: 32, offset: 2296},
+								pos: position{line: 84, col: 32, offset: 2347},
 								expr: &seqExpr{
-									pos: position{line: 82, col: 34, offset: 2298},
+									pos: position{line: 84, col: 34, offset: 2349},
 									exprs: []any{
 										&ruleRefExpr{
-											pos:  position{line: 82, col: 34, offset: 2298},
+											pos:  position{line: 84, col: 34, offset: 2349},
 											name: "__",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 82, col: 37, offset: 2301},
+											pos:  position{line: 84, col: 37, offset: 2352},
 											name: "CodeBlock",
 										},
 									},
@@ -394,35 +394,35 @@ var g = &grammar{
 		},
 		{
 			name: "SeqExpr",
-			pos:  position{line: 96, col: 1, offset: 2565},
+			pos:  position{line: 98, col: 1, offset: 2616},
 			expr: &actionExpr{
-				pos: position{line: 96, col: 11, offset: 2577},
+				pos: position{line: 98, col: 11, offset: 2628},
 				run: (*parser).callonSeqExpr1,
 				expr: &seqExpr{
-					pos: position{line: 96, col: 11, offset: 2577},
+					pos: position{line: 98, col: 11, offset: 2628},
 					exprs: []any{
 						&labeledExpr{
-							pos:   position{line: 96, col: 11, offset: 2577},
+							pos:   position{line: 98, col: 11, offset: 2628},
 							label: "first",
 							expr: &ruleRefExpr{
-								pos:  position{line: 96, col: 17, offset: 2583},
+								pos:  position{line: 98, col: 17, offset: 2634},
 								name: "LabeledExpr",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 96, col: 29, offset: 2595},
+							pos:   position{line: 98, col: 29, offset: 2646},
 							label: "rest",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 96, col: 34, offset: 2600},
+								pos: position{line: 98, col: 34, offset: 2651},
 								expr: &seqExpr{
-									pos: position{line: 96, col: 36, offset: 2602},
+									pos: position{line: 98, col: 36, offset: 2653},
 									exprs: []any{
 										&ruleRefExpr{
-											pos:  position{line: 96, col: 36, offset: 2602},
+											pos:  position{line: 98, col: 36, offset: 2653},
 											name: "__",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 96, col: 39, offset: 2605},
+											pos:  position{line: 98, col: 39, offset: 2656},
 											name: "LabeledExpr",
 										},
 									},
@@ -435,43 +435,43 @@ var g = &grammar{
 		},
 		{
 			name: "LabeledExpr",
-			pos:  position{line: 109, col: 1, offset: 2946},
+			pos:  position{line: 111, col: 1, offset: 2997},
 			expr: &choiceExpr{
-				pos: position{line: 109, col: 15, offset: 2962},
+				pos: position{line: 111, col: 15, offset: 3013},
 				alternatives: []any{
 					&actionExpr{
-						pos: position{line: 109, col: 15, offset: 2962},
+						pos: position{line: 111, col: 15, offset: 3013},
 						run: (*parser).callonLabeledExpr2,
 						expr: &seqExpr{
-							pos: position{line: 109, col: 15, offset: 2962},
+							pos: position{line: 111, col: 15, offset: 3013},
 							exprs: []any{
 								&labeledExpr{
-									pos:   position{line: 109, col: 15, offset: 2962},
+									pos:   position{line: 111, col: 15, offset: 3013},
 									label: "label",
 									expr: &ruleRefExpr{
-										pos:  position{line: 109, col: 21, offset: 2968},
+										pos:  position{line: 111, col: 21, offset: 3019},
 										name: "Identifier",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 109, col: 32, offset: 2979},
+									pos:  position{line: 111, col: 32, offset: 3030},
 									name: "__",
 								},
 								&litMatcher{
-									pos:        position{line: 109, col: 35, offset: 2982},
+									pos:        position{line: 111, col: 35, offset: 3033},
 									val:        ":",
 									ignoreCase: false,
 									want:       "\":\"",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 109, col: 39, offset: 2986},
+									pos:  position{line: 111, col: 39, offset: 3037},
 									name: "__",
 								},
 								&labeledExpr{
-									pos:   position{line: 109, col: 42, offset: 2989},
+									pos:   position{line: 111, col: 42, offset: 3040},
 									label: "expr",
 									expr: &ruleRefExpr{
-										pos:  position{line: 109, col: 47, offset: 2994},
+										pos:  position{line: 111, col: 47, offset: 3045},
 										name: "PrefixedExpr",
 									},
 								},
@@ -479,11 +479,11 @@ var g = &grammar{
 						},
 					},
 					&ruleRefExpr{
-						pos:  position{line: 115, col: 5, offset: 3167},
+						pos:  position{line: 117, col: 5, offset: 3218},
 						name: "PrefixedExpr",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 115, col: 20, offset: 3182},
+						pos:  position{line: 117, col: 20, offset: 3233},
 						name: "ThrowExpr",
 					},
 				},
@@ -491,33 +491,33 @@ var g = &grammar{
 		},
 		{
 			name: "PrefixedExpr",
-			pos:  position{line: 117, col: 1, offset: 3193},
+			pos:  position{line: 119, col: 1, offset: 3244},
 			expr: &choiceExpr{
-				pos: position{line: 117, col: 16, offset: 3210},
+				pos: position{line: 119, col: 16, offset: 3261},
 				alternatives: []any{
 					&actionExpr{
-						pos: position{line: 117, col: 16, offset: 3210},
+						pos: position{line: 119, col: 16, offset: 3261},
 						run: (*parser).callonPrefixedExpr2,
 						expr: &seqExpr{
-							pos: position{line: 117, col: 16, offset: 3210},
+							pos: position{line: 119, col: 16, offset: 3261},
 							exprs: []any{
 								&labeledExpr{
-									pos:   position{line: 117, col: 16, offset: 3210},
+									pos:   position{line: 119, col: 16, offset: 3261},
 									label: "op",
 									expr: &ruleRefExpr{
-										pos:  position{line: 117, col: 19, offset: 3213},
+										pos:  position{line: 119, col: 19, offset: 3264},
 										name: "PrefixedOp",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 117, col: 30, offset: 3224},
+									pos:  position{line: 119, col: 30, offset: 3275},
 									name: "__",
 								},
 								&labeledExpr{
-									pos:   position{line: 117, col: 33, offset: 3227},
+									pos:   position{line: 119, col: 33, offset: 3278},
 									label: "expr",
 									expr: &ruleRefExpr{
-										pos:  position{line: 117, col: 38, offset: 3232},
+										pos:  position{line: 119, col: 38, offset: 3283},
 										name: "SuffixedExpr",
 									},
 								},
@@ -525,7 +525,7 @@ var g = &grammar{
 						},
 					},
 					&ruleRefExpr{
-						pos:  position{line: 128, col: 5, offset: 3514},
+						pos:  position{line: 130, col: 5, offset: 3565},
 						name: "SuffixedExpr",
 					},
 				},
@@ -533,21 +533,21 @@ var g = &grammar{
 		},
 		{
 			name: "PrefixedOp",
-			pos:  position{line: 130, col: 1, offset: 3528},
+			pos:  position{line: 132, col: 1, offset: 3579},
 			expr: &actionExpr{
-				pos: position{line: 130, col: 14, offset: 3543},
+				pos: position{line: 132, col: 14, offset: 3594},
 				run: (*parser).callonPrefixedOp1,
 				expr: &choiceExpr{
-					pos: position{line: 130, col: 16, offset: 3545},
+					pos: position{line: 132, col: 16, offset: 3596},
 					alternatives: []any{
 						&litMatcher{
-							pos:        position{line: 130, col: 16, offset: 3545},
+							pos:        position{line: 132, col: 16, offset: 3596},
 							val:        "&",
 							ignoreCase: false,
 							want:       "\"&\"",
 						},
 						&litMatcher{
-							pos:        position{line: 130, col: 22, offset: 3551},
+							pos:        position{line: 132, col: 22, offset: 3602},
 							val:        "!",
 							ignoreCase: false,
 							want:       "\"!\"",
@@ -558,33 +558,33 @@ var g = &grammar{
 		},
 		{
 			name: "SuffixedExpr",
-			pos:  position{line: 134, col: 1, offset: 3593},
+			pos:  position{line: 136, col: 1, offset: 3644},
 			expr: &choiceExpr{
-				pos: position{line: 134, col: 16, offset: 3610},
+				pos: position{line: 136, col: 16, offset: 3661},
 				alternatives: []any{
 					&actionExpr{
-						pos: position{line: 134, col: 16, offset: 3610},
+						pos: position{line: 136, col: 16, offset: 3661},
 						run: (*parser).callonSuffixedExpr2,
 						expr: &seqExpr{
-							pos: position{line: 134, col: 16, offset: 3610},
+							pos: position{line: 136, col: 16, offset: 3661},
 							exprs: []any{
 								&labeledExpr{
-									pos:   position{line: 134, col: 16, offset: 3610},
+									pos:   position{line: 136, col: 16, offset: 3661},
 									label: "expr",
 									expr: &ruleRefExpr{
-										pos:  position{line: 134, col: 21, offset: 3615},
+										pos:  position{line: 136, col: 21, offset: 3666},
 										name: "PrimaryExpr",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 134, col: 33, offset: 3627},
+									pos:  position{line: 136, col: 33, offset: 3678},
 									name: "__",
 								},
 								&labeledExpr{
-									pos:   position{line: 134, col: 36, offset: 3630},
+									pos:   position{line: 136, col: 36, offset: 3681},
 									label: "op",
 									expr: &ruleRefExpr{
-										pos:  position{line: 134, col: 39, offset: 3633},
+										pos:  position{line: 136, col: 39, offset: 3684},
 										name: "SuffixedOp",
 									},
 								},
@@ -592,7 +592,7 @@ var g = &grammar{
 						},
 					},
 					&ruleRefExpr{
-						pos:  position{line: 153, col: 5, offset: 4163},
+						pos:  position{line: 155, col: 5, offset: 4214},
 						name: "PrimaryExpr",
 					},
 				},
@@ -600,27 +600,27 @@ var g = &grammar{
 		},
 		{
 			name: "SuffixedOp",
-			pos:  position{line: 155, col: 1, offset: 4176},
+			pos:  position{line: 157, col: 1, offset: 4227},
 			expr: &actionExpr{
-				pos: position{line: 155, col: 14, offset: 4191},
+				pos: position{line: 157, col: 14, offset: 4242},
 				run: (*parser).callonSuffixedOp1,
 				expr: &choiceExpr{
-					pos: position{line: 155, col: 16, offset: 4193},
+					pos: position{line: 157, col: 16, offset: 4244},
 					alternatives: []any{
 						&litMatcher{
-							pos:        position{line: 155, col: 16, offset: 4193},
+							pos:        position{line: 157, col: 16, offset: 4244},
 							val:        "?",
 							ignoreCase: false,
 							want:       "\"?\"",
 						},
 						&litMatcher{
-							pos:        position{line: 155, col: 22, offset: 4199},
+							pos:        position{line: 157, col: 22, offset: 4250},
 							val:        "*",
 							ignoreCase: false,
 							want:       "\"*\"",
 						},
 						&litMatcher{
-							pos:        position{line: 155, col: 28, offset: 4205},
+							pos:        position{line: 157, col: 28, offset: 4256},
 							val:        "+",
 							ignoreCase: false,
 							want:       "\"+\"",
@@ -631,60 +631,68 @@ var g = &grammar{
 		},
 		{
 			name: "PrimaryExpr",
-			pos:  position{line: 159, col: 1, offset: 4247},
+			pos:  position{line: 161, col: 1, offset: 4298},
 			expr: &choiceExpr{
-				pos: position{line: 159, col: 15, offset: 4263},
+				pos: position{line: 161, col: 15, offset: 4314},
 				alternatives: []any{
 					&ruleRefExpr{
-						pos:  position{line: 159, col: 15, offset: 4263},
+						pos:  position{line: 161, col: 15, offset: 4314},
 						name: "LitMatcher",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 159, col: 28, offset: 4276},
+						pos:  position{line: 161, col: 28, offset: 4327},
 						name: "CharClassMatcher",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 159, col: 47, offset: 4295},
+						pos:  position{line: 161, col: 47, offset: 4346},
 						name: "AnyMatcher",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 159, col: 60, offset: 4308},
+						pos:  position{line: 161, col: 60, offset: 4359},
 						name: "RuleRefExpr",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 159, col: 74, offset: 4322},
+						pos:  position{line: 161, col: 74, offset: 4373},
 						name: "SemanticPredExpr",
 					},
+					&ruleRefExpr{
+						pos:  position{line: 161, col: 93, offset: 4392},
+						name: "BackRefExpr",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 161, col: 107, offset: 4406},
+						name: "IndentExpr",
+					},
 					&actionExpr{
-						pos: position{line: 159, col: 93, offset: 4341},
-						run: (*parser).callonPrimaryExpr7,
+						pos: position{line: 161, col: 120, offset: 4419},
+						run: (*parser).callonPrimaryExpr9,
 						expr: &seqExpr{
-							pos: position{line: 159, col: 93, offset: 4341},
+							pos: position{line: 161, col: 120, offset: 4419},
 							exprs: []any{
 								&litMatcher{
-									pos:        position{line: 159, col: 93, offset: 4341},
+									pos:        position{line: 161, col: 120, offset: 4419},
 									val:        "(",
 									ignoreCase: false,
 									want:       "\"(\"",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 159, col: 97, offset: 4345},
+									pos:  position{line: 161, col: 124, offset: 4423},
 									name: "__",
 								},
 								&labeledExpr{
-									pos:   position{line: 159, col: 100, offset: 4348},
+									pos:   position{line: 161, col: 127, offset: 4426},
 									label: "expr",
 									expr: &ruleRefExpr{
-										pos:  position{line: 159, col: 105, offset: 4353},
+										pos:  position{line: 161, col: 132, offset: 4431},
 										name: "Expression",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 159, col: 116, offset: 4364},
+									pos:  position{line: 161, col: 143, offset: 4442},
 									name: "__",
 								},
 								&litMatcher{
-									pos:        position{line: 159, col: 119, offset: 4367},
+									pos:        position{line: 161, col: 146, offset: 4445},
 									val:        ")",
 									ignoreCase: false,
 									want:       "\")\"",
@@ -697,48 +705,48 @@ var g = &grammar{
 		},
 		{
 			name: "RuleRefExpr",
-			pos:  position{line: 162, col: 1, offset: 4396},
+			pos:  position{line: 164, col: 1, offset: 4474},
 			expr: &actionExpr{
-				pos: position{line: 162, col: 15, offset: 4412},
+				pos: position{line: 164, col: 15, offset: 4490},
 				run: (*parser).callonRuleRefExpr1,
 				expr: &seqExpr{
-					pos: position{line: 162, col: 15, offset: 4412},
+					pos: position{line: 164, col: 15, offset: 4490},
 					exprs: []any{
 						&labeledExpr{
-							pos:   position{line: 162, col: 15, offset: 4412},
+							pos:   position{line: 164, col: 15, offset: 4490},
 							label: "name",
 							expr: &ruleRefExpr{
-								pos:  position{line: 162, col: 20, offset: 4417},
+								pos:  position{line: 164, col: 20, offset: 4495},
 								name: "IdentifierName",
 							},
 						},
 						&notExpr{
-							pos: position{line: 162, col: 35, offset: 4432},
+							pos: position{line: 164, col: 35, offset: 4510},
 							expr: &seqExpr{
-								pos: position{line: 162, col: 38, offset: 4435},
+								pos: position{line: 164, col: 38, offset: 4513},
 								exprs: []any{
 									&ruleRefExpr{
-										pos:  position{line: 162, col: 38, offset: 4435},
+										pos:  position{line: 164, col: 38, offset: 4513},
 										name: "__",
 									},
 									&zeroOrOneExpr{
-										pos: position{line: 162, col: 41, offset: 4438},
+										pos: position{line: 164, col: 41, offset: 4516},
 										expr: &seqExpr{
-											pos: position{line: 162, col: 43, offset: 4440},
+											pos: position{line: 164, col: 43, offset: 4518},
 											exprs: []any{
 												&ruleRefExpr{
-													pos:  position{line: 162, col: 43, offset: 4440},
+													pos:  position{line: 164, col: 43, offset: 4518},
 													name: "StringLiteral",
 												},
 												&ruleRefExpr{
-													pos:  position{line: 162, col: 57, offset: 4454},
+													pos:  position{line: 164, col: 57, offset: 4532},
 													name: "__",
 												},
 											},
 										},
 									},
 									&ruleRefExpr{
-										pos:  position{line: 162, col: 63, offset: 4460},
+										pos:  position{line: 164, col: 63, offset: 4538},
 										name: "RuleDefOp",
 									},
 								},
@@ -748,32 +756,112 @@ var g = &grammar{
 				},
 			},
 		},
+		{
+			name: "BackRefExpr",
+			pos:  position{line: 169, col: 1, offset: 4654},
+			expr: &actionExpr{
+				pos: position{line: 169, col: 15, offset: 4670},
+				run: (*parser).callonBackRefExpr1,
+				expr: &seqExpr{
+					pos: position{line: 169, col: 15, offset: 4670},
+					exprs: []any{
+						&litMatcher{
+							pos:        position{line: 169, col: 15, offset: 4670},
+							val:        "\\",
+							ignoreCase: false,
+							want:       "\"\\\\\"",
+						},
+						&labeledExpr{
+							pos:   position{line: 169, col: 20, offset: 4675},
+							label: "label",
+							expr: &ruleRefExpr{
+								pos:  position{line: 169, col: 26, offset: 4681},
+								name: "IdentifierName",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "IndentExpr",
+			pos:  position{line: 174, col: 1, offset: 4802},
+			expr: &actionExpr{
+				pos: position{line: 174, col: 14, offset: 4817},
+				run: (*parser).callonIndentExpr1,
+				expr: &seqExpr{
+					pos: position{line: 174, col: 14, offset: 4817},
+					exprs: []any{
+						&litMatcher{
+							pos:        position{line: 174, col: 14, offset: 4817},
+							val:        "%",
+							ignoreCase: false,
+							want:       "\"%\"",
+						},
+						&labeledExpr{
+							pos:   position{line: 174, col: 18, offset: 4821},
+							label: "op",
+							expr: &choiceExpr{
+								pos: position{line: 174, col: 23, offset: 4826},
+								alternatives: []any{
+									&litMatcher{
+										pos:        position{line: 174, col: 23, offset: 4826},
+										val:        "INDENT",
+										ignoreCase: false,
+										want:       "\"INDENT\"",
+									},
+									&litMatcher{
+										pos:        position{line: 174, col: 34, offset: 4837},
+										val:        "DEDENT",
+										ignoreCase: false,
+										want:       "\"DEDENT\"",
+									},
+									&litMatcher{
+										pos:        position{line: 174, col: 45, offset: 4848},
+										val:        "SAMEDENT",
+										ignoreCase: false,
+										want:       "\"SAMEDENT\"",
+									},
+								},
+							},
+						},
+						&notExpr{
+							pos: position{line: 174, col: 58, offset: 4861},
+							expr: &ruleRefExpr{
+								pos:  position{line: 174, col: 59, offset: 4862},
+								name: "IdentifierPart",
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "SemanticPredExpr",
-			pos:  position{line: 167, col: 1, offset: 4576},
+			pos:  position{line: 186, col: 1, offset: 5161},
 			expr: &actionExpr{
-				pos: position{line: 167, col: 20, offset: 4597},
+				pos: position{line: 186, col: 20, offset: 5182},
 				run: (*parser).callonSemanticPredExpr1,
 				expr: &seqExpr{
-					pos: position{line: 167, col: 20, offset: 4597},
+					pos: position{line: 186, col: 20, offset: 5182},
 					exprs: []any{
 						&labeledExpr{
-							pos:   position{line: 167, col: 20, offset: 4597},
+							pos:   position{line: 186, col: 20, offset: 5182},
 							label: "op",
 							expr: &ruleRefExpr{
-								pos:  position{line: 167, col: 23, offset: 4600},
+								pos:  position{line: 186, col: 23, offset: 5185},
 								name: "SemanticPredOp",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 167, col: 38, offset: 4615},
+							pos:  position{line: 186, col: 38, offset: 5200},
 							name: "__",
 						},
 						&labeledExpr{
-							pos:   position{line: 167, col: 41, offset: 4618},
+							pos:   position{line: 186, col: 41, offset: 5203},
 							label: "code",
 							expr: &ruleRefExpr{
-								pos:  position{line: 167, col: 46, offset: 4623},
+								pos:  position{line: 186, col: 46, offset: 5208},
 								name: "CodeBlock",
 							},
 						},
@@ -783,27 +871,27 @@ var g = &grammar{
 		},
 		{
 			name: "SemanticPredOp",
-			pos:  position{line: 187, col: 1, offset: 5070},
+			pos:  position{line: 206, col: 1, offset: 5655},
 			expr: &actionExpr{
-				pos: position{line: 187, col: 18, offset: 5089},
+				pos: position{line: 206, col: 18, offset: 5674},
 				run: (*parser).callonSemanticPredOp1,
 				expr: &choiceExpr{
-					pos: position{line: 187, col: 20, offset: 5091},
+					pos: position{line: 206, col: 20, offset: 5676},
 					alternatives: []any{
 						&litMatcher{
-							pos:        position{line: 187, col: 20, offset: 5091},
+							pos:        position{line: 206, col: 20, offset: 5676},
 							val:        "#",
 							ignoreCase: false,
 							want:       "\"#\"",
 						},
 						&litMatcher{
-							pos:        position{line: 187, col: 26, offset: 5097},
+							pos:        position{line: 206, col: 26, offset: 5682},
 							val:        "&",
 							ignoreCase: false,
 							want:       "\"&\"",
 						},
 						&litMatcher{
-							pos:        position{line: 187, col: 32, offset: 5103},
+							pos:        position{line: 206, col: 32, offset: 5688},
 							val:        "!",
 							ignoreCase: false,
 							want:       "\"!\"",
@@ -814,30 +902,30 @@ var g = &grammar{
 		},
 		{
 			name: "RuleDefOp",
-			pos:  position{line: 191, col: 1, offset: 5145},
+			pos:  position{line: 210, col: 1, offset: 5730},
 			expr: &choiceExpr{
-				pos: position{line: 191, col: 13, offset: 5159},
+				pos: position{line: 210, col: 13, offset: 5744},
 				alternatives: []any{
 					&litMatcher{
-						pos:        position{line: 191, col: 13, offset: 5159},
+						pos:        position{line: 210, col: 13, offset: 5744},
 						val:        "=",
 						ignoreCase: false,
 						want:       "\"=\"",
 					},
 					&litMatcher{
-						pos:        position{line: 191, col: 19, offset: 5165},
+						pos:        position{line: 210, col: 19, offset: 5750},
 						val:        "<-",
 						ignoreCase: false,
 						want:       "\"<-\"",
 					},
 					&litMatcher{
-						pos:        position{line: 191, col: 26, offset: 5172},
+						pos:        position{line: 210, col: 26, offset: 5757},
 						val:        "←",
 						ignoreCase: false,
 						want:       "\"←\"",
 					},
 					&litMatcher{
-						pos:        position{line: 191, col: 37, offset: 5183},
+						pos:        position{line: 210, col: 37, offset: 5768},
 						val:        "⟵",
 						ignoreCase: false,
 						want:       "\"⟵\"",
@@ -847,23 +935,23 @@ var g = &grammar{
 		},
 		{
 			name: "SourceChar",
-			pos:  position{line: 193, col: 1, offset: 5193},
+			pos:  position{line: 212, col: 1, offset: 5778},
 			expr: &anyMatcher{
-				line: 193, col: 14, offset: 5208,
+				line: 212, col: 14, offset: 5793,
 			},
 		},
 		{
 			name: "Comment",
-			pos:  position{line: 194, col: 1, offset: 5210},
+			pos:  position{line: 213, col: 1, offset: 5795},
 			expr: &choiceExpr{
-				pos: position{line: 194, col: 11, offset: 5222},
+				pos: position{line: 213, col: 11, offset: 5807},
 				alternatives: []any{
 					&ruleRefExpr{
-						pos:  position{line: 194, col: 11, offset: 5222},
+						pos:  position{line: 213, col: 11, offset: 5807},
 						name: "MultiLineComment",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 194, col: 30, offset: 5241},
+						pos:  position{line: 213, col: 30, offset: 5826},
 						name: "SingleLineComment",
 					},
 				},
@@ -871,39 +959,39 @@ var g = &grammar{
 		},
 		{
 			name: "MultiLineComment",
-			pos:  position{line: 195, col: 1, offset: 5259},
+			pos:  position{line: 214, col: 1, offset: 5844},
 			expr: &seqExpr{
-				pos: position{line: 195, col: 20, offset: 5280},
+				pos: position{line: 214, col: 20, offset: 5865},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 195, col: 20, offset: 5280},
+						pos:        position{line: 214, col: 20, offset: 5865},
 						val:        "/*",
 						ignoreCase: false,
 						want:       "\"/*\"",
 					},
 					&zeroOrMoreExpr{
-						pos: position{line: 195, col: 25, offset: 5285},
+						pos: position{line: 214, col: 25, offset: 5870},
 						expr: &seqExpr{
-							pos: position{line: 195, col: 27, offset: 5287},
+							pos: position{line: 214, col: 27, offset: 5872},
 							exprs: []any{
 								&notExpr{
-									pos: position{line: 195, col: 27, offset: 5287},
+									pos: position{line: 214, col: 27, offset: 5872},
 									expr: &litMatcher{
-										pos:        position{line: 195, col: 28, offset: 5288},
+										pos:        position{line: 214, col: 28, offset: 5873},
 										val:        "*/",
 										ignoreCase: false,
 										want:       "\"*/\"",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 195, col: 33, offset: 5293},
+									pos:  position{line: 214, col: 33, offset: 5878},
 									name: "SourceChar",
 								},
 							},
 						},
 					},
 					&litMatcher{
-						pos:        position{line: 195, col: 47, offset: 5307},
+						pos:        position{line: 214, col: 47, offset: 5892},
 						val:        "*/",
 						ignoreCase: false,
 						want:       "\"*/\"",
@@ -913,48 +1001,48 @@ var g = &grammar{
 		},
 		{
 			name: "MultiLineCommentNoLineTerminator",
-			pos:  position{line: 196, col: 1, offset: 5312},
+			pos:  position{line: 215, col: 1, offset: 5897},
 			expr: &seqExpr{
-				pos: position{line: 196, col: 36, offset: 5349},
+				pos: position{line: 215, col: 36, offset: 5934},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 196, col: 36, offset: 5349},
+						pos:        position{line: 215, col: 36, offset: 5934},
 						val:        "/*",
 						ignoreCase: false,
 						want:       "\"/*\"",
 					},
 					&zeroOrMoreExpr{
-						pos: position{line: 196, col: 41, offset: 5354},
+						pos: position{line: 215, col: 41, offset: 5939},
 						expr: &seqExpr{
-							pos: position{line: 196, col: 43, offset: 5356},
+							pos: position{line: 215, col: 43, offset: 5941},
 							exprs: []any{
 								&notExpr{
-									pos: position{line: 196, col: 43, offset: 5356},
+									pos: position{line: 215, col: 43, offset: 5941},
 									expr: &choiceExpr{
-										pos: position{line: 196, col: 46, offset: 5359},
+										pos: position{line: 215, col: 46, offset: 5944},
 										alternatives: []any{
 											&litMatcher{
-												pos:        position{line: 196, col: 46, offset: 5359},
+												pos:        position{line: 215, col: 46, offset: 5944},
 												val:        "*/",
 												ignoreCase: false,
 												want:       "\"*/\"",
 											},
 											&ruleRefExpr{
-												pos:  position{line: 196, col: 53, offset: 5366},
+												pos:  position{line: 215, col: 53, offset: 5951},
 												name: "EOL",
 											},
 										},
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 196, col: 59, offset: 5372},
+									pos:  position{line: 215, col: 59, offset: 5957},
 									name: "SourceChar",
 								},
 							},
 						},
 					},
 					&litMatcher{
-						pos:        position{line: 196, col: 73, offset: 5386},
+						pos:        position{line: 215, col: 73, offset: 5971},
 						val:        "*/",
 						ignoreCase: false,
 						want:       "\"*/\"",
@@ -964,39 +1052,39 @@ var g = &grammar{
 		},
 		{
 			name: "SingleLineComment",
-			pos:  position{line: 197, col: 1, offset: 5391},
+			pos:  position{line: 216, col: 1, offset: 5976},
 			expr: &seqExpr{
-				pos: position{line: 197, col: 21, offset: 5413},
+				pos: position{line: 216, col: 21, offset: 5998},
 				exprs: []any{
 					&notExpr{
-						pos: position{line: 197, col: 21, offset: 5413},
+						pos: position{line: 216, col: 21, offset: 5998},
 						expr: &litMatcher{
-							pos:        position{line: 197, col: 23, offset: 5415},
+							pos:        position{line: 216, col: 23, offset: 6000},
 							val:        "//{",
 							ignoreCase: false,
 							want:       "\"//{\"",
 						},
 					},
 					&litMatcher{
-						pos:        position{line: 197, col: 30, offset: 5422},
+						pos:        position{line: 216, col: 30, offset: 6007},
 						val:        "//",
 						ignoreCase: false,
 						want:       "\"//\"",
 					},
 					&zeroOrMoreExpr{
-						pos: position{line: 197, col: 35, offset: 5427},
+						pos: position{line: 216, col: 35, offset: 6012},
 						expr: &seqExpr{
-							pos: position{line: 197, col: 37, offset: 5429},
+							pos: position{line: 216, col: 37, offset: 6014},
 							exprs: []any{
 								&notExpr{
-									pos: position{line: 197, col: 37, offset: 5429},
+									pos: position{line: 216, col: 37, offset: 6014},
 									expr: &ruleRefExpr{
-										pos:  position{line: 197, col: 38, offset: 5430},
+										pos:  position{line: 216, col: 38, offset: 6015},
 										name: "EOL",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 197, col: 42, offset: 5434},
+									pos:  position{line: 216, col: 42, offset: 6019},
 									name: "SourceChar",
 								},
 							},
@@ -1005,17 +1093,29 @@ var g = &grammar{
 				},
 			},
 		},
+		{
+			name: "GrammarComment",
+			pos:  position{line: 217, col: 1, offset: 6033},
+			expr: &actionExpr{
+				pos: position{line: 217, col: 18, offset: 6052},
+				run: (*parser).callonGrammarComment1,
+				expr: &ruleRefExpr{
+					pos:  position{line: 217, col: 18, offset: 6052},
+					name: "Comment",
+				},
+			},
+		},
 		{
 			name: "Identifier",
-			pos:  position{line: 199, col: 1, offset: 5449},
+			pos:  position{line: 222, col: 1, offset: 6104},
 			expr: &actionExpr{
-				pos: position{line: 199, col: 14, offset: 5464},
+				pos: position{line: 222, col: 14, offset: 6119},
 				run: (*parser).callonIdentifier1,
 				expr: &labeledExpr{
-					pos:   position{line: 199, col: 14, offset: 5464},
+					pos:   position{line: 222, col: 14, offset: 6119},
 					label: "ident",
 					expr: &ruleRefExpr{
-						pos:  position{line: 199, col: 20, offset: 5470},
+						pos:  position{line: 222, col: 20, offset: 6125},
 						name: "IdentifierName",
 					},
 				},
@@ -1023,21 +1123,21 @@ var g = &grammar{
 		},
 		{
 			name: "IdentifierName",
-			pos:  position{line: 207, col: 1, offset: 5689},
+			pos:  position{line: 230, col: 1, offset: 6344},
 			expr: &actionExpr{
-				pos: position{line: 207, col: 18, offset: 5708},
+				pos: position{line: 230, col: 18, offset: 6363},
 				run: (*parser).callonIdentifierName1,
 				expr: &seqExpr{
-					pos: position{line: 207, col: 18, offset: 5708},
+					pos: position{line: 230, col: 18, offset: 6363},
 					exprs: []any{
 						&ruleRefExpr{
-							pos:  position{line: 207, col: 18, offset: 5708},
+							pos:  position{line: 230, col: 18, offset: 6363},
 							name: "IdentifierStart",
 						},
 						&zeroOrMoreExpr{
-							pos: position{line: 207, col: 34, offset: 5724},
+							pos: position{line: 230, col: 34, offset: 6379},
 							expr: &ruleRefExpr{
-								pos:  position{line: 207, col: 34, offset: 5724},
+								pos:  position{line: 230, col: 34, offset: 6379},
 								name: "IdentifierPart",
 							},
 						},
@@ -1047,9 +1147,9 @@ var g = &grammar{
 		},
 		{
 			name: "IdentifierStart",
-			pos:  position{line: 210, col: 1, offset: 5806},
+			pos:  position{line: 233, col: 1, offset: 6461},
 			expr: &charClassMatcher{
-				pos:        position{line: 210, col: 19, offset: 5826},
+				pos:        position{line: 233, col: 19, offset: 6481},
 				val:        "[\\pL_]",
 				chars:      []rune{'_'},
 				classes:    []*unicode.RangeTable{rangeTable("L")},
@@ -1059,16 +1159,16 @@ var g = &grammar{
 		},
 		{
 			name: "IdentifierPart",
-			pos:  position{line: 211, col: 1, offset: 5833},
+			pos:  position{line: 234, col: 1, offset: 6488},
 			expr: &choiceExpr{
-				pos: position{line: 211, col: 18, offset: 5852},
+				pos: position{line: 234, col: 18, offset: 6507},
 				alternatives: []any{
 					&ruleRefExpr{
-						pos:  position{line: 211, col: 18, offset: 5852},
+						pos:  position{line: 234, col: 18, offset: 6507},
 						name: "IdentifierStart",
 					},
 					&charClassMatcher{
-						pos:        position{line: 211, col: 36, offset: 5870},
+						pos:        position{line: 234, col: 36, offset: 6525},
 						val:        "[\\p{Nd}]",
 						classes:    []*unicode.RangeTable{rangeTable("Nd")},
 						ignoreCase: false,
@@ -1079,28 +1179,28 @@ var g = &grammar{
 		},
 		{
 			name: "LitMatcher",
-			pos:  position{line: 213, col: 1, offset: 5880},
+			pos:  position{line: 236, col: 1, offset: 6535},
 			expr: &actionExpr{
-				pos: position{line: 213, col: 14, offset: 5895},
+				pos: position{line: 236, col: 14, offset: 6550},
 				run: (*parser).callonLitMatcher1,
 				expr: &seqExpr{
-					pos: position{line: 213, col: 14, offset: 5895},
+					pos: position{line: 236, col: 14, offset: 6550},
 					exprs: []any{
 						&labeledExpr{
-							pos:   position{line: 213, col: 14, offset: 5895},
+							pos:   position{line: 236, col: 14, offset: 6550},
 							label: "lit",
 							expr: &ruleRefExpr{
-								pos:  position{line: 213, col: 18, offset: 5899},
+								pos:  position{line: 236, col: 18, offset: 6554},
 								name: "StringLiteral",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 213, col: 32, offset: 5913},
+							pos:   position{line: 236, col: 32, offset: 6568},
 							label: "ignore",
 							expr: &zeroOrOneExpr{
-								pos: position{line: 213, col: 39, offset: 5920},
+								pos: position{line: 236, col: 39, offset: 6575},
 								expr: &litMatcher{
-									pos:        position{line: 213, col: 39, offset: 5920},
+									pos:        position{line: 236, col: 39, offset: 6575},
 									val:        "i",
 									ignoreCase: false,
 									want:       "\"i\"",
@@ -1113,34 +1213,34 @@ var g = &grammar{
 		},
 		{
 			name: "StringLiteral",
-			pos:  position{line: 226, col: 1, offset: 6319},
+			pos:  position{line: 249, col: 1, offset: 6974},
 			expr: &choiceExpr{
-				pos: position{line: 226, col: 17, offset: 6337},
+				pos: position{line: 249, col: 17, offset: 6992},
 				alternatives: []any{
 					&actionExpr{
-						pos: position{line: 226, col: 17, offset: 6337},
+						pos: position{line: 249, col: 17, offset: 6992},
 						run: (*parser).callonStringLiteral2,
 						expr: &choiceExpr{
-							pos: position{line: 226, col: 19, offset: 6339},
+							pos: position{line: 249, col: 19, offset: 6994},
 							alternatives: []any{
 								&seqExpr{
-									pos: position{line: 226, col: 19, offset: 6339},
+									pos: position{line: 249, col: 19, offset: 6994},
 									exprs: []any{
 										&litMatcher{
-											pos:        position{line: 226, col: 19, offset: 6339},
+											pos:        position{line: 249, col: 19, offset: 6994},
 											val:        "\"",
 											ignoreCase: false,
 											want:       "\"\\\"\"",
 										},
 										&zeroOrMoreExpr{
-											pos: position{line: 226, col: 23, offset: 6343},
+											pos: position{line: 249, col: 23, offset: 6998},
 											expr: &ruleRefExpr{
-												pos:  position{line: 226, col: 23, offset: 6343},
+												pos:  position{line: 249, col: 23, offset: 6998},
 												name: "DoubleStringChar",
 											},
 										},
 										&litMatcher{
-											pos:        position{line: 226, col: 41, offset: 6361},
+											pos:        position{line: 249, col: 41, offset: 7016},
 											val:        "\"",
 											ignoreCase: false,
 											want:       "\"\\\"\"",
@@ -1148,20 +1248,20 @@ var g = &grammar{
 									},
 								},
 								&seqExpr{
-									pos: position{line: 226, col: 47, offset: 6367},
+									pos: position{line: 249, col: 47, offset: 7022},
 									exprs: []any{
 										&litMatcher{
-											pos:        position{line: 226, col: 47, offset: 6367},
+											pos:        position{line: 249, col: 47, offset: 7022},
 											val:        "'",
 											ignoreCase: false,
 											want:       "\"'\"",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 226, col: 51, offset: 6371},
+											pos:  position{line: 249, col: 51, offset: 7026},
 											name: "SingleStringChar",
 										},
 										&litMatcher{
-											pos:        position{line: 226, col: 68, offset: 6388},
+											pos:        position{line: 249, col: 68, offset: 7043},
 											val:        "'",
 											ignoreCase: false,
 											want:       "\"'\"",
@@ -1169,23 +1269,23 @@ var g = &grammar{
 									},
 								},
 								&seqExpr{
-									pos: position{line: 226, col: 74, offset: 6394},
+									pos: position{line: 249, col: 74, offset: 7049},
 									exprs: []any{
 										&litMatcher{
-											pos:        position{line: 226, col: 74, offset: 6394},
+											pos:        position{line: 249, col: 74, offset: 7049},
 											val:        "`",
 											ignoreCase: false,
 											want:       "\"`\"",
 										},
 										&zeroOrMoreExpr{
-											pos: position{line: 226, col: 78, offset: 6398},
+											pos: position{line: 249, col: 78, offset: 7053},
 											expr: &ruleRefExpr{
-												pos:  position{line: 226, col: 78, offset: 6398},
+												pos:  position{line: 249, col: 78, offset: 7053},
 												name: "RawStringChar",
 											},
 										},
 										&litMatcher{
-											pos:        position{line: 226, col: 93, offset: 6413},
+											pos:        position{line: 249, col: 93, offset: 7068},
 											val:        "`",
 											ignoreCase: false,
 											want:       "\"`\"",
@@ -1196,36 +1296,36 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 228, col: 5, offset: 6486},
+						pos: position{line: 251, col: 5, offset: 7141},
 						run: (*parser).callonStringLiteral18,
 						expr: &choiceExpr{
-							pos: position{line: 228, col: 7, offset: 6488},
+							pos: position{line: 251, col: 7, offset: 7143},
 							alternatives: []any{
 								&seqExpr{
-									pos: position{line: 228, col: 9, offset: 6490},
+									pos: position{line: 251, col: 9, offset: 7145},
 									exprs: []any{
 										&litMatcher{
-											pos:        position{line: 228, col: 9, offset: 6490},
+											pos:        position{line: 251, col: 9, offset: 7145},
 											val:        "\"",
 											ignoreCase: false,
 											want:       "\"\\\"\"",
 										},
 										&zeroOrMoreExpr{
-											pos: position{line: 228, col: 13, offset: 6494},
+											pos: position{line: 251, col: 13, offset: 7149},
 											expr: &ruleRefExpr{
-												pos:  position{line: 228, col: 13, offset: 6494},
+												pos:  position{line: 251, col: 13, offset: 7149},
 												name: "DoubleStringChar",
 											},
 										},
 										&choiceExpr{
-											pos: position{line: 228, col: 33, offset: 6514},
+											pos: position{line: 251, col: 33, offset: 7169},
 											alternatives: []any{
 												&ruleRefExpr{
-													pos:  position{line: 228, col: 33, offset: 6514},
+													pos:  position{line: 251, col: 33, offset: 7169},
 													name: "EOL",
 												},
 												&ruleRefExpr{
-													pos:  position{line: 228, col: 39, offset: 6520},
+													pos:  position{line: 251, col: 39, offset: 7175},
 													name: "EOF",
 												},
 											},
@@ -1233,30 +1333,30 @@ var g = &grammar{
 									},
 								},
 								&seqExpr{
-									pos: position{line: 228, col: 51, offset: 6532},
+									pos: position{line: 251, col: 51, offset: 7187},
 									exprs: []any{
 										&litMatcher{
-											pos:        position{line: 228, col: 51, offset: 6532},
+											pos:        position{line: 251, col: 51, offset: 7187},
 											val:        "'",
 											ignoreCase: false,
 											want:       "\"'\"",
 										},
 										&zeroOrOneExpr{
-											pos: position{line: 228, col: 55, offset: 6536},
+											pos: position{line: 251, col: 55, offset: 7191},
 											expr: &ruleRefExpr{
-												pos:  position{line: 228, col: 55, offset: 6536},
+												pos:  position{line: 251, col: 55, offset: 7191},
 												name: "SingleStringChar",
 											},
 										},
 										&choiceExpr{
-											pos: position{line: 228, col: 75, offset: 6556},
+											pos: position{line: 251, col: 75, offset: 7211},
 											alternatives: []any{
 												&ruleRefExpr{
-													pos:  position{line: 228, col: 75, offset: 6556},
+													pos:  position{line: 251, col: 75, offset: 7211},
 													name: "EOL",
 												},
 												&ruleRefExpr{
-													pos:  position{line: 228, col: 81, offset: 6562},
+													pos:  position{line: 251, col: 81, offset: 7217},
 													name: "EOF",
 												},
 											},
@@ -1264,23 +1364,23 @@ var g = &grammar{
 									},
 								},
 								&seqExpr{
-									pos: position{line: 228, col: 91, offset: 6572},
+									pos: position{line: 251, col: 91, offset: 7227},
 									exprs: []any{
 										&litMatcher{
-											pos:        position{line: 228, col: 91, offset: 6572},
+											pos:        position{line: 251, col: 91, offset: 7227},
 											val:        "`",
 											ignoreCase: false,
 											want:       "\"`\"",
 										},
 										&zeroOrMoreExpr{
-											pos: position{line: 228, col: 95, offset: 6576},
+											pos: position{line: 251, col: 95, offset: 7231},
 											expr: &ruleRefExpr{
-												pos:  position{line: 228, col: 95, offset: 6576},
+												pos:  position{line: 251, col: 95, offset: 7231},
 												name: "RawStringChar",
 											},
 										},
 										&ruleRefExpr{
-											pos:  position{line: 228, col: 110, offset: 6591},
+											pos:  position{line: 251, col: 110, offset: 7246},
 											name: "EOF",
 										},
 									},
@@ -1293,54 +1393,54 @@ var g = &grammar{
 		},
 		{
 			name: "DoubleStringChar",
-			pos:  position{line: 232, col: 1, offset: 6693},
+			pos:  position{line: 255, col: 1, offset: 7348},
 			expr: &choiceExpr{
-				pos: position{line: 232, col: 20, offset: 6714},
+				pos: position{line: 255, col: 20, offset: 7369},
 				alternatives: []any{
 					&seqExpr{
-						pos: position{line: 232, col: 20, offset: 6714},
+						pos: position{line: 255, col: 20, offset: 7369},
 						exprs: []any{
 							&notExpr{
-								pos: position{line: 232, col: 20, offset: 6714},
+								pos: position{line: 255, col: 20, offset: 7369},
 								expr: &choiceExpr{
-									pos: position{line: 232, col: 23, offset: 6717},
+									pos: position{line: 255, col: 23, offset: 7372},
 									alternatives: []any{
 										&litMatcher{
-											pos:        position{line: 232, col: 23, offset: 6717},
+											pos:        position{line: 255, col: 23, offset: 7372},
 											val:        "\"",
 											ignoreCase: false,
 											want:       "\"\\\"\"",
 										},
 										&litMatcher{
-											pos:        position{line: 232, col: 29, offset: 6723},
+											pos:        position{line: 255, col: 29, offset: 7378},
 											val:        "\\",
 											ignoreCase: false,
 											want:       "\"\\\\\"",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 232, col: 36, offset: 6730},
+											pos:  position{line: 255, col: 36, offset: 7385},
 											name: "EOL",
 										},
 									},
 								},
 							},
 							&ruleRefExpr{
-								pos:  position{line: 232, col: 42, offset: 6736},
+								pos:  position{line: 255, col: 42, offset: 7391},
 								name: "SourceChar",
 							},
 						},
 					},
 					&seqExpr{
-						pos: position{line: 232, col: 55, offset: 6749},
+						pos: position{line: 255, col: 55, offset: 7404},
 						exprs: []any{
 							&litMatcher{
-								pos:        position{line: 232, col: 55, offset: 6749},
+								pos:        position{line: 255, col: 55, offset: 7404},
 								val:        "\\",
 								ignoreCase: false,
 								want:       "\"\\\\\"",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 232, col: 60, offset: 6754},
+								pos:  position{line: 255, col: 60, offset: 7409},
 								name: "DoubleStringEscape",
 							},
 						},
@@ -1350,54 +1450,54 @@ var g = &grammar{
 		},
 		{
 			name: "SingleStringChar",
-			pos:  position{line: 233, col: 1, offset: 6773},
+			pos:  position{line: 256, col: 1, offset: 7428},
 			expr: &choiceExpr{
-				pos: position{line: 233, col: 20, offset: 6794},
+				pos: position{line: 256, col: 20, offset: 7449},
 				alternatives: []any{
 					&seqExpr{
-						pos: position{line: 233, col: 20, offset: 6794},
+						pos: position{line: 256, col: 20, offset: 7449},
 						exprs: []any{
 							&notExpr{
-								pos: position{line: 233, col: 20, offset: 6794},
+								pos: position{line: 256, col: 20, offset: 7449},
 								expr: &choiceExpr{
-									pos: position{line: 233, col: 23, offset: 6797},
+									pos: position{line: 256, col: 23, offset: 7452},
 									alternatives: []any{
 										&litMatcher{
-											pos:        position{line: 233, col: 23, offset: 6797},
+											pos:        position{line: 256, col: 23, offset: 7452},
 											val:        "'",
 											ignoreCase: false,
 											want:       "\"'\"",
 										},
 										&litMatcher{
-											pos:        position{line: 233, col: 29, offset: 6803},
+											pos:        position{line: 256, col: 29, offset: 7458},
 											val:        "\\",
 											ignoreCase: false,
 											want:       "\"\\\\\"",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 233, col: 36, offset: 6810},
+											pos:  position{line: 256, col: 36, offset: 7465},
 											name: "EOL",
 										},
 									},
 								},
 							},
 							&ruleRefExpr{
-								pos:  position{line: 233, col: 42, offset: 6816},
+								pos:  position{line: 256, col: 42, offset: 7471},
 								name: "SourceChar",
 							},
 						},
 					},
 					&seqExpr{
-						pos: position{line: 233, col: 55, offset: 6829},
+						pos: position{line: 256, col: 55, offset: 7484},
 						exprs: []any{
 							&litMatcher{
-								pos:        position{line: 233, col: 55, offset: 6829},
+								pos:        position{line: 256, col: 55, offset: 7484},
 								val:        "\\",
 								ignoreCase: false,
 								want:       "\"\\\\\"",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 233, col: 60, offset: 6834},
+								pos:  position{line: 256, col: 60, offset: 7489},
 								name: "SingleStringEscape",
 							},
 						},
@@ -1407,21 +1507,21 @@ var g = &grammar{
 		},
 		{
 			name: "RawStringChar",
-			pos:  position{line: 234, col: 1, offset: 6853},
+			pos:  position{line: 257, col: 1, offset: 7508},
 			expr: &seqExpr{
-				pos: position{line: 234, col: 17, offset: 6871},
+				pos: position{line: 257, col: 17, offset: 7526},
 				exprs: []any{
 					&notExpr{
-						pos: position{line: 234, col: 17, offset: 6871},
+						pos: position{line: 257, col: 17, offset: 7526},
 						expr: &litMatcher{
-							pos:        position{line: 234, col: 18, offset: 6872},
+							pos:        position{line: 257, col: 18, offset: 7527},
 							val:        "`",
 							ignoreCase: false,
 							want:       "\"`\"",
 						},
 					},
 					&ruleRefExpr{
-						pos:  position{line: 234, col: 22, offset: 6876},
+						pos:  position{line: 257, col: 22, offset: 7531},
 						name: "SourceChar",
 					},
 				},
@@ -1429,41 +1529,41 @@ var g = &grammar{
 		},
 		{
 			name: "DoubleStringEscape",
-			pos:  position{line: 236, col: 1, offset: 6888},
+			pos:  position{line: 259, col: 1, offset: 7543},
 			expr: &choiceExpr{
-				pos: position{line: 236, col: 22, offset: 6911},
+				pos: position{line: 259, col: 22, offset: 7566},
 				alternatives: []any{
 					&choiceExpr{
-						pos: position{line: 236, col: 24, offset: 6913},
+						pos: position{line: 259, col: 24, offset: 7568},
 						alternatives: []any{
 							&litMatcher{
-								pos:        position{line: 236, col: 24, offset: 6913},
+								pos:        position{line: 259, col: 24, offset: 7568},
 								val:        "\"",
 								ignoreCase: false,
 								want:       "\"\\\"\"",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 236, col: 30, offset: 6919},
+								pos:  position{line: 259, col: 30, offset: 7574},
 								name: "CommonEscapeSequence",
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 237, col: 7, offset: 6948},
+						pos: position{line: 260, col: 7, offset: 7603},
 						run: (*parser).callonDoubleStringEscape5,
 						expr: &choiceExpr{
-							pos: position{line: 237, col: 9, offset: 6950},
+							pos: position{line: 260, col: 9, offset: 7605},
 							alternatives: []any{
 								&ruleRefExpr{
-									pos:  position{line: 237, col: 9, offset: 6950},
+									pos:  position{line: 260, col: 9, offset: 7605},
 									name: "SourceChar",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 237, col: 22, offset: 6963},
+									pos:  position{line: 260, col: 22, offset: 7618},
 									name: "EOL",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 237, col: 28, offset: 6969},
+									pos:  position{line: 260, col: 28, offset: 7624},
 									name: "EOF",
 								},
 							},
@@ -1474,41 +1574,41 @@ var g = &grammar{
 		},
 		{
 			name: "SingleStringEscape",
-			pos:  position{line: 240, col: 1, offset: 7034},
+			pos:  position{line: 263, col: 1, offset: 7689},
 			expr: &choiceExpr{
-				pos: position{line: 240, col: 22, offset: 7057},
+				pos: position{line: 263, col: 22, offset: 7712},
 				alternatives: []any{
 					&choiceExpr{
-						pos: position{line: 240, col: 24, offset: 7059},
+						pos: position{line: 263, col: 24, offset: 7714},
 						alternatives: []any{
 							&litMatcher{
-								pos:        position{line: 240, col: 24, offset: 7059},
+								pos:        position{line: 263, col: 24, offset: 7714},
 								val:        "'",
 								ignoreCase: false,
 								want:       "\"'\"",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 240, col: 30, offset: 7065},
+								pos:  position{line: 263, col: 30, offset: 7720},
 								name: "CommonEscapeSequence",
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 241, col: 7, offset: 7094},
+						pos: position{line: 264, col: 7, offset: 7749},
 						run: (*parser).callonSingleStringEscape5,
 						expr: &choiceExpr{
-							pos: position{line: 241, col: 9, offset: 7096},
+							pos: position{line: 264, col: 9, offset: 7751},
 							alternatives: []any{
 								&ruleRefExpr{
-									pos:  position{line: 241, col: 9, offset: 7096},
+									pos:  position{line: 264, col: 9, offset: 7751},
 									name: "SourceChar",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 241, col: 22, offset: 7109},
+									pos:  position{line: 264, col: 22, offset: 7764},
 									name: "EOL",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 241, col: 28, offset: 7115},
+									pos:  position{line: 264, col: 28, offset: 7770},
 									name: "EOF",
 								},
 							},
@@ -1519,28 +1619,28 @@ var g = &grammar{
 		},
 		{
 			name: "CommonEscapeSequence",
-			pos:  position{line: 245, col: 1, offset: 7181},
+			pos:  position{line: 268, col: 1, offset: 7836},
 			expr: &choiceExpr{
-				pos: position{line: 245, col: 24, offset: 7206},
+				pos: position{line: 268, col: 24, offset: 7861},
 				alternatives: []any{
 					&ruleRefExpr{
-						pos:  position{line: 245, col: 24, offset: 7206},
+						pos:  position{line: 268, col: 24, offset: 7861},
 						name: "SingleCharEscape",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 245, col: 43, offset: 7225},
+						pos:  position{line: 268, col: 43, offset: 7880},
 						name: "OctalEscape",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 245, col: 57, offset: 7239},
+						pos:  position{line: 268, col: 57, offset: 7894},
 						name: "HexEscape",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 245, col: 69, offset: 7251},
+						pos:  position{line: 268, col: 69, offset: 7906},
 						name: "LongUnicodeEscape",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 245, col: 89, offset: 7271},
+						pos:  position{line: 268, col: 89, offset: 7926},
 						name: "ShortUnicodeEscape",
 					},
 				},
@@ -1548,54 +1648,54 @@ var g = &grammar{
 		},
 		{
 			name: "SingleCharEscape",
-			pos:  position{line: 246, col: 1, offset: 7290},
+			pos:  position{line: 269, col: 1, offset: 7945},
 			expr: &choiceExpr{
-				pos: position{line: 246, col: 20, offset: 7311},
+				pos: position{line: 269, col: 20, offset: 7966},
 				alternatives: []any{
 					&litMatcher{
-						pos:        position{line: 246, col: 20, offset: 7311},
+						pos:        position{line: 269, col: 20, offset: 7966},
 						val:        "a",
 						ignoreCase: false,
 						want:       "\"a\"",
 					},
 					&litMatcher{
-						pos:        position{line: 246, col: 26, offset: 7317},
+						pos:        position{line: 269, col: 26, offset: 7972},
 						val:        "b",
 						ignoreCase: false,
 						want:       "\"b\"",
 					},
 					&litMatcher{
-						pos:        position{line: 246, col: 32, offset: 7323},
+						pos:        position{line: 269, col: 32, offset: 7978},
 						val:        "n",
 						ignoreCase: false,
 						want:       "\"n\"",
 					},
 					&litMatcher{
-						pos:        position{line: 246, col: 38, offset: 7329},
+						pos:        position{line: 269, col: 38, offset: 7984},
 						val:        "f",
 						ignoreCase: false,
 						want:       "\"f\"",
 					},
 					&litMatcher{
-						pos:        position{line: 246, col: 44, offset: 7335},
+						pos:        position{line: 269, col: 44, offset: 7990},
 						val:        "r",
 						ignoreCase: false,
 						want:       "\"r\"",
 					},
 					&litMatcher{
-						pos:        position{line: 246, col: 50, offset: 7341},
+						pos:        position{line: 269, col: 50, offset: 7996},
 						val:        "t",
 						ignoreCase: false,
 						want:       "\"t\"",
 					},
 					&litMatcher{
-						pos:        position{line: 246, col: 56, offset: 7347},
+						pos:        position{line: 269, col: 56, offset: 8002},
 						val:        "v",
 						ignoreCase: false,
 						want:       "\"v\"",
 					},
 					&litMatcher{
-						pos:        position{line: 246, col: 62, offset: 7353},
+						pos:        position{line: 269, col: 62, offset: 8008},
 						val:        "\\",
 						ignoreCase: false,
 						want:       "\"\\\\\"",
@@ -1605,50 +1705,50 @@ var g = &grammar{
 		},
 		{
 			name: "OctalEscape",
-			pos:  position{line: 247, col: 1, offset: 7358},
+			pos:  position{line: 270, col: 1, offset: 8013},
 			expr: &choiceExpr{
-				pos: position{line: 247, col: 15, offset: 7374},
+				pos: position{line: 270, col: 15, offset: 8029},
 				alternatives: []any{
 					&seqExpr{
-						pos: position{line: 247, col: 15, offset: 7374},
+						pos: position{line: 270, col: 15, offset: 8029},
 						exprs: []any{
 							&ruleRefExpr{
-								pos:  position{line: 247, col: 15, offset: 7374},
+								pos:  position{line: 270, col: 15, offset: 8029},
 								name: "OctalDigit",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 247, col: 26, offset: 7385},
+								pos:  position{line: 270, col: 26, offset: 8040},
 								name: "OctalDigit",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 247, col: 37, offset: 7396},
+								pos:  position{line: 270, col: 37, offset: 8051},
 								name: "OctalDigit",
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 248, col: 7, offset: 7413},
+						pos: position{line: 271, col: 7, offset: 8068},
 						run: (*parser).callonOctalEscape6,
 						expr: &seqExpr{
-							pos: position{line: 248, col: 7, offset: 7413},
+							pos: position{line: 271, col: 7, offset: 8068},
 							exprs: []any{
 								&ruleRefExpr{
-									pos:  position{line: 248, col: 7, offset: 7413},
+									pos:  position{line: 271, col: 7, offset: 8068},
 									name: "OctalDigit",
 								},
 								&choiceExpr{
-									pos: position{line: 248, col: 20, offset: 7426},
+									pos: position{line: 271, col: 20, offset: 8081},
 									alternatives: []any{
 										&ruleRefExpr{
-											pos:  position{line: 248, col: 20, offset: 7426},
+											pos:  position{line: 271, col: 20, offset: 8081},
 											name: "SourceChar",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 248, col: 33, offset: 7439},
+											pos:  position{line: 271, col: 33, offset: 8094},
 											name: "EOL",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 248, col: 39, offset: 7445},
+											pos:  position{line: 271, col: 39, offset: 8100},
 											name: "EOF",
 										},
 									},
@@ -1661,54 +1761,54 @@ var g = &grammar{
 		},
 		{
 			name: "HexEscape",
-			pos:  position{line: 251, col: 1, offset: 7506},
+			pos:  position{line: 274, col: 1, offset: 8161},
 			expr: &choiceExpr{
-				pos: position{line: 251, col: 13, offset: 7520},
+				pos: position{line: 274, col: 13, offset: 8175},
 				alternatives: []any{
 					&seqExpr{
-						pos: position{line: 251, col: 13, offset: 7520},
+						pos: position{line: 274, col: 13, offset: 8175},
 						exprs: []any{
 							&litMatcher{
-								pos:        position{line: 251, col: 13, offset: 7520},
+								pos:        position{line: 274, col: 13, offset: 8175},
 								val:        "x",
 								ignoreCase: false,
 								want:       "\"x\"",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 251, col: 17, offset: 7524},
+								pos:  position{line: 274, col: 17, offset: 8179},
 								name: "HexDigit",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 251, col: 26, offset: 7533},
+								pos:  position{line: 274, col: 26, offset: 8188},
 								name: "HexDigit",
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 252, col: 7, offset: 7548},
+						pos: position{line: 275, col: 7, offset: 8203},
 						run: (*parser).callonHexEscape6,
 						expr: &seqExpr{
-							pos: position{line: 252, col: 7, offset: 7548},
+							pos: position{line: 275, col: 7, offset: 8203},
 							exprs: []any{
 								&litMatcher{
-									pos:        position{line: 252, col: 7, offset: 7548},
+									pos:        position{line: 275, col: 7, offset: 8203},
 									val:        "x",
 									ignoreCase: false,
 									want:       "\"x\"",
 								},
 								&choiceExpr{
-									pos: position{line: 252, col: 13, offset: 7554},
+									pos: position{line: 275, col: 13, offset: 8209},
 									alternatives: []any{
 										&ruleRefExpr{
-											pos:  position{line: 252, col: 13, offset: 7554},
+											pos:  position{line: 275, col: 13, offset: 8209},
 											name: "SourceChar",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 252, col: 26, offset: 7567},
+											pos:  position{line: 275, col: 26, offset: 8222},
 											name: "EOL",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 252, col: 32, offset: 7573},
+											pos:  position{line: 275, col: 32, offset: 8228},
 											name: "EOF",
 										},
 									},
@@ -1721,82 +1821,82 @@ var g = &grammar{
 		},
 		{
 			name: "LongUnicodeEscape",
-			pos:  position{line: 255, col: 1, offset: 7640},
+			pos:  position{line: 278, col: 1, offset: 8295},
 			expr: &choiceExpr{
-				pos: position{line: 256, col: 5, offset: 7666},
+				pos: position{line: 279, col: 5, offset: 8321},
 				alternatives: []any{
 					&actionExpr{
-						pos: position{line: 256, col: 5, offset: 7666},
+						pos: position{line: 279, col: 5, offset: 8321},
 						run: (*parser).callonLongUnicodeEscape2,
 						expr: &seqExpr{
-							pos: position{line: 256, col: 5, offset: 7666},
+							pos: position{line: 279, col: 5, offset: 8321},
 							exprs: []any{
 								&litMatcher{
-									pos:        position{line: 256, col: 5, offset: 7666},
+									pos:        position{line: 279, col: 5, offset: 8321},
 									val:        "U",
 									ignoreCase: false,
 									want:       "\"U\"",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 256, col: 9, offset: 7670},
+									pos:  position{line: 279, col: 9, offset: 8325},
 									name: "HexDigit",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 256, col: 18, offset: 7679},
+									pos:  position{line: 279, col: 18, offset: 8334},
 									name: "HexDigit",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 256, col: 27, offset: 7688},
+									pos:  position{line: 279, col: 27, offset: 8343},
 									name: "HexDigit",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 256, col: 36, offset: 7697},
+									pos:  position{line: 279, col: 36, offset: 8352},
 									name: "HexDigit",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 256, col: 45, offset: 7706},
+									pos:  position{line: 279, col: 45, offset: 8361},
 									name: "HexDigit",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 256, col: 54, offset: 7715},
+									pos:  position{line: 279, col: 54, offset: 8370},
 									name: "HexDigit",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 256, col: 63, offset: 7724},
+									pos:  position{line: 279, col: 63, offset: 8379},
 									name: "HexDigit",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 256, col: 72, offset: 7733},
+									pos:  position{line: 279, col: 72, offset: 8388},
 									name: "HexDigit",
 								},
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 259, col: 7, offset: 7835},
+						pos: position{line: 282, col: 7, offset: 8490},
 						run: (*parser).callonLongUnicodeEscape13,
 						expr: &seqExpr{
-							pos: position{line: 259, col: 7, offset: 7835},
+							pos: position{line: 282, col: 7, offset: 8490},
 							exprs: []any{
 								&litMatcher{
-									pos:        position{line: 259, col: 7, offset: 7835},
+									pos:        position{line: 282, col: 7, offset: 8490},
 									val:        "U",
 									ignoreCase: false,
 									want:       "\"U\"",
 								},
 								&choiceExpr{
-									pos: position{line: 259, col: 13, offset: 7841},
+									pos: position{line: 282, col: 13, offset: 8496},
 									alternatives: []any{
 										&ruleRefExpr{
-											pos:  position{line: 259, col: 13, offset: 7841},
+											pos:  position{line: 282, col: 13, offset: 8496},
 											name: "SourceChar",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 259, col: 26, offset: 7854},
+											pos:  position{line: 282, col: 26, offset: 8509},
 											name: "EOL",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 259, col: 32, offset: 7860},
+											pos:  position{line: 282, col: 32, offset: 8515},
 											name: "EOF",
 										},
 									},
@@ -1809,66 +1909,66 @@ var g = &grammar{
 		},
 		{
 			name: "ShortUnicodeEscape",
-			pos:  position{line: 262, col: 1, offset: 7923},
+			pos:  position{line: 285, col: 1, offset: 8578},
 			expr: &choiceExpr{
-				pos: position{line: 263, col: 5, offset: 7950},
+				pos: position{line: 286, col: 5, offset: 8605},
 				alternatives: []any{
 					&actionExpr{
-						pos: position{line: 263, col: 5, offset: 7950},
+						pos: position{line: 286, col: 5, offset: 8605},
 						run: (*parser).callonShortUnicodeEscape2,
 						expr: &seqExpr{
-							pos: position{line: 263, col: 5, offset: 7950},
+							pos: position{line: 286, col: 5, offset: 8605},
 							exprs: []any{
 								&litMatcher{
-									pos:        position{line: 263, col: 5, offset: 7950},
+									pos:        position{line: 286, col: 5, offset: 8605},
 									val:        "u",
 									ignoreCase: false,
 									want:       "\"u\"",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 263, col: 9, offset: 7954},
+									pos:  position{line: 286, col: 9, offset: 8609},
 									name: "HexDigit",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 263, col: 18, offset: 7963},
+									pos:  position{line: 286, col: 18, offset: 8618},
 									name: "HexDigit",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 263, col: 27, offset: 7972},
+									pos:  position{line: 286, col: 27, offset: 8627},
 									name: "HexDigit",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 263, col: 36, offset: 7981},
+									pos:  position{line: 286, col: 36, offset: 8636},
 									name: "HexDigit",
 								},
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 266, col: 7, offset: 8083},
+						pos: position{line: 289, col: 7, offset: 8738},
 						run: (*parser).callonShortUnicodeEscape9,
 						expr: &seqExpr{
-							pos: position{line: 266, col: 7, offset: 8083},
+							pos: position{line: 289, col: 7, offset: 8738},
 							exprs: []any{
 								&litMatcher{
-									pos:        position{line: 266, col: 7, offset: 8083},
+									pos:        position{line: 289, col: 7, offset: 8738},
 									val:        "u",
 									ignoreCase: false,
 									want:       "\"u\"",
 								},
 								&choiceExpr{
-									pos: position{line: 266, col: 13, offset: 8089},
+									pos: position{line: 289, col: 13, offset: 8744},
 									alternatives: []any{
 										&ruleRefExpr{
-											pos:  position{line: 266, col: 13, offset: 8089},
+											pos:  position{line: 289, col: 13, offset: 8744},
 											name: "SourceChar",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 266, col: 26, offset: 8102},
+											pos:  position{line: 289, col: 26, offset: 8757},
 											name: "EOL",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 266, col: 32, offset: 8108},
+											pos:  position{line: 289, col: 32, offset: 8763},
 											name: "EOF",
 										},
 									},
@@ -1881,9 +1981,9 @@ var g = &grammar{
 		},
 		{
 			name: "OctalDigit",
-			pos:  position{line: 270, col: 1, offset: 8172},
+			pos:  position{line: 293, col: 1, offset: 8827},
 			expr: &charClassMatcher{
-				pos:        position{line: 270, col: 14, offset: 8187},
+				pos:        position{line: 293, col: 14, offset: 8842},
 				val:        "[0-7]",
 				ranges:     []rune{'0', '7'},
 				ignoreCase: false,
@@ -1892,9 +1992,9 @@ var g = &grammar{
 		},
 		{
 			name: "DecimalDigit",
-			pos:  position{line: 271, col: 1, offset: 8193},
+			pos:  position{line: 294, col: 1, offset: 8848},
 			expr: &charClassMatcher{
-				pos:        position{line: 271, col: 16, offset: 8210},
+				pos:        position{line: 294, col: 16, offset: 8865},
 				val:        "[0-9]",
 				ranges:     []rune{'0', '9'},
 				ignoreCase: false,
@@ -1903,9 +2003,9 @@ var g = &grammar{
 		},
 		{
 			name: "HexDigit",
-			pos:  position{line: 272, col: 1, offset: 8216},
+			pos:  position{line: 295, col: 1, offset: 8871},
 			expr: &charClassMatcher{
-				pos:        position{line: 272, col: 12, offset: 8229},
+				pos:        position{line: 295, col: 12, offset: 8884},
 				val:        "[0-9a-f]i",
 				ranges:     []rune{'0', '9', 'a', 'f'},
 				ignoreCase: true,
@@ -1914,46 +2014,46 @@ var g = &grammar{
 		},
 		{
 			name: "CharClassMatcher",
-			pos:  position{line: 274, col: 1, offset: 8240},
+			pos:  position{line: 297, col: 1, offset: 8895},
 			expr: &choiceExpr{
-				pos: position{line: 274, col: 20, offset: 8261},
+				pos: position{line: 297, col: 20, offset: 8916},
 				alternatives: []any{
 					&actionExpr{
-						pos: position{line: 274, col: 20, offset: 8261},
+						pos: position{line: 297, col: 20, offset: 8916},
 						run: (*parser).callonCharClassMatcher2,
 						expr: &seqExpr{
-							pos: position{line: 274, col: 20, offset: 8261},
+							pos: position{line: 297, col: 20, offset: 8916},
 							exprs: []any{
 								&litMatcher{
-									pos:        position{line: 274, col: 20, offset: 8261},
+									pos:        position{line: 297, col: 20, offset: 8916},
 									val:        "[",
 									ignoreCase: false,
 									want:       "\"[\"",
 								},
 								&zeroOrMoreExpr{
-									pos: position{line: 274, col: 24, offset: 8265},
+									pos: position{line: 297, col: 24, offset: 8920},
 									expr: &choiceExpr{
-										pos: position{line: 274, col: 26, offset: 8267},
+										pos: position{line: 297, col: 26, offset: 8922},
 										alternatives: []any{
 											&ruleRefExpr{
-												pos:  position{line: 274, col: 26, offset: 8267},
+												pos:  position{line: 297, col: 26, offset: 8922},
 												name: "ClassCharRange",
 											},
 											&ruleRefExpr{
-												pos:  position{line: 274, col: 43, offset: 8284},
+												pos:  position{line: 297, col: 43, offset: 8939},
 												name: "ClassChar",
 											},
 											&seqExpr{
-												pos: position{line: 274, col: 55, offset: 8296},
+												pos: position{line: 297, col: 55, offset: 8951},
 												exprs: []any{
 													&litMatcher{
-														pos:        position{line: 274, col: 55, offset: 8296},
+														pos:        position{line: 297, col: 55, offset: 8951},
 														val:        "\\",
 														ignoreCase: false,
 														want:       "\"\\\\\"",
 													},
 													&ruleRefExpr{
-														pos:  position{line: 274, col: 60, offset: 8301},
+														pos:  position{line: 297, col: 60, offset: 8956},
 														name: "UnicodeClassEscape",
 													},
 												},
@@ -1962,15 +2062,15 @@ var g = &grammar{
 									},
 								},
 								&litMatcher{
-									pos:        position{line: 274, col: 82, offset: 8323},
+									pos:        position{line: 297, col: 82, offset: 8978},
 									val:        "]",
 									ignoreCase: false,
 									want:       "\"]\"",
 								},
 								&zeroOrOneExpr{
-									pos: position{line: 274, col: 86, offset: 8327},
+									pos: position{line: 297, col: 86, offset: 8982},
 									expr: &litMatcher{
-										pos:        position{line: 274, col: 86, offset: 8327},
+										pos:        position{line: 297, col: 86, offset: 8982},
 										val:        "i",
 										ignoreCase: false,
 										want:       "\"i\"",
@@ -1980,45 +2080,45 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 278, col: 5, offset: 8434},
+						pos: position{line: 301, col: 5, offset: 9089},
 						run: (*parser).callonCharClassMatcher15,
 						expr: &seqExpr{
-							pos: position{line: 278, col: 5, offset: 8434},
+							pos: position{line: 301, col: 5, offset: 9089},
 							exprs: []any{
 								&litMatcher{
-									pos:        position{line: 278, col: 5, offset: 8434},
+									pos:        position{line: 301, col: 5, offset: 9089},
 									val:        "[",
 									ignoreCase: false,
 									want:       "\"[\"",
 								},
 								&zeroOrMoreExpr{
-									pos: position{line: 278, col: 9, offset: 8438},
+									pos: position{line: 301, col: 9, offset: 9093},
 									expr: &seqExpr{
-										pos: position{line: 278, col: 11, offset: 8440},
+										pos: position{line: 301, col: 11, offset: 9095},
 										exprs: []any{
 											&notExpr{
-												pos: position{line: 278, col: 11, offset: 8440},
+												pos: position{line: 301, col: 11, offset: 9095},
 												expr: &ruleRefExpr{
-													pos:  position{line: 278, col: 14, offset: 8443},
+													pos:  position{line: 301, col: 14, offset: 9098},
 													name: "EOL",
 												},
 											},
 											&ruleRefExpr{
-												pos:  position{line: 278, col: 20, offset: 8449},
+												pos:  position{line: 301, col: 20, offset: 9104},
 												name: "SourceChar",
 											},
 										},
 									},
 								},
 								&choiceExpr{
-									pos: position{line: 278, col: 36, offset: 8465},
+									pos: position{line: 301, col: 36, offset: 9120},
 									alternatives: []any{
 										&ruleRefExpr{
-											pos:  position{line: 278, col: 36, offset: 8465},
+											pos:  position{line: 301, col: 36, offset: 9120},
 											name: "EOL",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 278, col: 42, offset: 8471},
+											pos:  position{line: 301, col: 42, offset: 9126},
 											name: "EOF",
 										},
 									},
@@ -2031,22 +2131,22 @@ var g = &grammar{
 		},
 		{
 			name: "ClassCharRange",
-			pos:  position{line: 282, col: 1, offset: 8581},
+			pos:  position{line: 305, col: 1, offset: 9236},
 			expr: &seqExpr{
-				pos: position{line: 282, col: 18, offset: 8600},
+				pos: position{line: 305, col: 18, offset: 9255},
 				exprs: []any{
 					&ruleRefExpr{
-						pos:  position{line: 282, col: 18, offset: 8600},
+						pos:  position{line: 305, col: 18, offset: 9255},
 						name: "ClassChar",
 					},
 					&litMatcher{
-						pos:        position{line: 282, col: 28, offset: 8610},
+						pos:        position{line: 305, col: 28, offset: 9265},
 						val:        "-",
 						ignoreCase: false,
 						want:       "\"-\"",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 282, col: 32, offset: 8614},
+						pos:  position{line: 305, col: 32, offset: 9269},
 						name: "ClassChar",
 					},
 				},
@@ -2054,54 +2154,54 @@ var g = &grammar{
 		},
 		{
 			name: "ClassChar",
-			pos:  position{line: 283, col: 1, offset: 8624},
+			pos:  position{line: 306, col: 1, offset: 9279},
 			expr: &choiceExpr{
-				pos: position{line: 283, col: 13, offset: 8638},
+				pos: position{line: 306, col: 13, offset: 9293},
 				alternatives: []any{
 					&seqExpr{
-						pos: position{line: 283, col: 13, offset: 8638},
+						pos: position{line: 306, col: 13, offset: 9293},
 						exprs: []any{
 							&notExpr{
-								pos: position{line: 283, col: 13, offset: 8638},
+								pos: position{line: 306, col: 13, offset: 9293},
 								expr: &choiceExpr{
-									pos: position{line: 283, col: 16, offset: 8641},
+									pos: position{line: 306, col: 16, offset: 9296},
 									alternatives: []any{
 										&litMatcher{
-											pos:        position{line: 283, col: 16, offset: 8641},
+											pos:        position{line: 306, col: 16, offset: 9296},
 											val:        "]",
 											ignoreCase: false,
 											want:       "\"]\"",
 										},
 										&litMatcher{
-											pos:        position{line: 283, col: 22, offset: 8647},
+											pos:        position{line: 306, col: 22, offset: 9302},
 											val:        "\\",
 											ignoreCase: false,
 											want:       "\"\\\\\"",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 283, col: 29, offset: 8654},
+											pos:  position{line: 306, col: 29, offset: 9309},
 											name: "EOL",
 										},
 									},
 								},
 							},
 							&ruleRefExpr{
-								pos:  position{line: 283, col: 35, offset: 8660},
+								pos:  position{line: 306, col: 35, offset: 9315},
 								name: "SourceChar",
 							},
 						},
 					},
 					&seqExpr{
-						pos: position{line: 283, col: 48, offset: 8673},
+						pos: position{line: 306, col: 48, offset: 9328},
 						exprs: []any{
 							&litMatcher{
-								pos:        position{line: 283, col: 48, offset: 8673},
+								pos:        position{line: 306, col: 48, offset: 9328},
 								val:        "\\",
 								ignoreCase: false,
 								want:       "\"\\\\\"",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 283, col: 53, offset: 8678},
+								pos:  position{line: 306, col: 53, offset: 9333},
 								name: "CharClassEscape",
 							},
 						},
@@ -2111,53 +2211,53 @@ var g = &grammar{
 		},
 		{
 			name: "CharClassEscape",
-			pos:  position{line: 284, col: 1, offset: 8694},
+			pos:  position{line: 307, col: 1, offset: 9349},
 			expr: &choiceExpr{
-				pos: position{line: 284, col: 19, offset: 8714},
+				pos: position{line: 307, col: 19, offset: 9369},
 				alternatives: []any{
 					&choiceExpr{
-						pos: position{line: 284, col: 21, offset: 8716},
+						pos: position{line: 307, col: 21, offset: 9371},
 						alternatives: []any{
 							&litMatcher{
-								pos:        position{line: 284, col: 21, offset: 8716},
+								pos:        position{line: 307, col: 21, offset: 9371},
 								val:        "]",
 								ignoreCase: false,
 								want:       "\"]\"",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 284, col: 27, offset: 8722},
+								pos:  position{line: 307, col: 27, offset: 9377},
 								name: "CommonEscapeSequence",
 							},
 						},
 					},
 					&actionExpr{
-						pos: position{line: 285, col: 7, offset: 8751},
+						pos: position{line: 308, col: 7, offset: 9406},
 						run: (*parser).callonCharClassEscape5,
 						expr: &seqExpr{
-							pos: position{line: 285, col: 7, offset: 8751},
+							pos: position{line: 308, col: 7, offset: 9406},
 							exprs: []any{
 								&notExpr{
-									pos: position{line: 285, col: 7, offset: 8751},
+									pos: position{line: 308, col: 7, offset: 9406},
 									expr: &litMatcher{
-										pos:        position{line: 285, col: 8, offset: 8752},
+										pos:        position{line: 308, col: 8, offset: 9407},
 										val:        "p",
 										ignoreCase: false,
 										want:       "\"p\"",
 									},
 								},
 								&choiceExpr{
-									pos: position{line: 285, col: 14, offset: 8758},
+									pos: position{line: 308, col: 14, offset: 9413},
 									alternatives: []any{
 										&ruleRefExpr{
-											pos:  position{line: 285, col: 14, offset: 8758},
+											pos:  position{line: 308, col: 14, offset: 9413},
 											name: "SourceChar",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 285, col: 27, offset: 8771},
+											pos:  position{line: 308, col: 27, offset: 9426},
 											name: "EOL",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 285, col: 33, offset: 8777},
+											pos:  position{line: 308, col: 33, offset: 9432},
 											name: "EOF",
 										},
 									},
@@ -2170,51 +2270,51 @@ var g = &grammar{
 		},
 		{
 			name: "UnicodeClassEscape",
-			pos:  position{line: 289, col: 1, offset: 8843},
+			pos:  position{line: 312, col: 1, offset: 9498},
 			expr: &seqExpr{
-				pos: position{line: 289, col: 22, offset: 8866},
+				pos: position{line: 312, col: 22, offset: 9521},
 				exprs: []any{
 					&litMatcher{
-						pos:        position{line: 289, col: 22, offset: 8866},
+						pos:        position{line: 312, col: 22, offset: 9521},
 						val:        "p",
 						ignoreCase: false,
 						want:       "\"p\"",
 					},
 					&choiceExpr{
-						pos: position{line: 290, col: 7, offset: 8878},
+						pos: position{line: 313, col: 7, offset: 9533},
 						alternatives: []any{
 							&ruleRefExpr{
-								pos:  position{line: 290, col: 7, offset: 8878},
+								pos:  position{line: 313, col: 7, offset: 9533},
 								name: "SingleCharUnicodeClass",
 							},
 							&actionExpr{
-								pos: position{line: 291, col: 7, offset: 8907},
+								pos: position{line: 314, col: 7, offset: 9562},
 								run: (*parser).callonUnicodeClassEscape5,
 								expr: &seqExpr{
-									pos: position{line: 291, col: 7, offset: 8907},
+									pos: position{line: 314, col: 7, offset: 9562},
 									exprs: []any{
 										&notExpr{
-											pos: position{line: 291, col: 7, offset: 8907},
+											pos: position{line: 314, col: 7, offset: 9562},
 											expr: &litMatcher{
-												pos:        position{line: 291, col: 8, offset: 8908},
+												pos:        position{line: 314, col: 8, offset: 9563},
 												val:        "{",
 												ignoreCase: false,
 												want:       "\"{\"",
 											},
 										},
 										&choiceExpr{
-											pos: position{line: 291, col: 14, offset: 8914},
+											pos: position{line: 314, col: 14, offset: 9569},
 											alternatives: []any{
 												&ruleRefExpr{
-													pos:  position{line: 291, col: 14, offset: 8914},
+													pos:  position{line: 314, col: 14, offset: 9569},
 													name: "SourceChar",
 												},
 												&ruleRefExpr{
-													pos:  position{line: 291, col: 27, offset: 8927},
+													pos:  position{line: 314, col: 27, offset: 9582},
 													name: "EOL",
 												},
 												&ruleRefExpr{
-													pos:  position{line: 291, col: 33, offset: 8933},
+													pos:  position{line: 314, col: 33, offset: 9588},
 													name: "EOF",
 												},
 											},
@@ -2223,27 +2323,27 @@ var g = &grammar{
 								},
 							},
 							&actionExpr{
-								pos: position{line: 292, col: 7, offset: 9004},
+								pos: position{line: 315, col: 7, offset: 9659},
 								run: (*parser).callonUnicodeClassEscape13,
 								expr: &seqExpr{
-									pos: position{line: 292, col: 7, offset: 9004},
+									pos: position{line: 315, col: 7, offset: 9659},
 									exprs: []any{
 										&litMatcher{
-											pos:        position{line: 292, col: 7, offset: 9004},
+											pos:        position{line: 315, col: 7, offset: 9659},
 											val:        "{",
 											ignoreCase: false,
 											want:       "\"{\"",
 										},
 										&labeledExpr{
-											pos:   position{line: 292, col: 11, offset: 9008},
+											pos:   position{line: 315, col: 11, offset: 9663},
 											label: "ident",
 											expr: &ruleRefExpr{
-												pos:  position{line: 292, col: 17, offset: 9014},
+												pos:  position{line: 315, col: 17, offset: 9669},
 												name: "IdentifierName",
 											},
 										},
 										&litMatcher{
-											pos:        position{line: 292, col: 32, offset: 9029},
+											pos:        position{line: 315, col: 32, offset: 9684},
 											val:        "}",
 											ignoreCase: false,
 											want:       "\"}\"",
@@ -2252,36 +2352,36 @@ var g = &grammar{
 								},
 							},
 							&actionExpr{
-								pos: position{line: 298, col: 7, offset: 9206},
+								pos: position{line: 321, col: 7, offset: 9861},
 								run: (*parser).callonUnicodeClassEscape19,
 								expr: &seqExpr{
-									pos: position{line: 298, col: 7, offset: 9206},
+									pos: position{line: 321, col: 7, offset: 9861},
 									exprs: []any{
 										&litMatcher{
-											pos:        position{line: 298, col: 7, offset: 9206},
+											pos:        position{line: 321, col: 7, offset: 9861},
 											val:        "{",
 											ignoreCase: false,
 											want:       "\"{\"",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 298, col: 11, offset: 9210},
+											pos:  position{line: 321, col: 11, offset: 9865},
 											name: "IdentifierName",
 										},
 										&choiceExpr{
-											pos: position{line: 298, col: 28, offset: 9227},
+											pos: position{line: 321, col: 28, offset: 9882},
 											alternatives: []any{
 												&litMatcher{
-													pos:        position{line: 298, col: 28, offset: 9227},
+													pos:        position{line: 321, col: 28, offset: 9882},
 													val:        "]",
 													ignoreCase: false,
 													want:       "\"]\"",
 												},
 												&ruleRefExpr{
-													pos:  position{line: 298, col: 34, offset: 9233},
+													pos:  position{line: 321, col: 34, offset: 9888},
 													name: "EOL",
 												},
 												&ruleRefExpr{
-													pos:  position{line: 298, col: 40, offset: 9239},
+													pos:  position{line: 321, col: 40, offset: 9894},
 													name: "EOF",
 												},
 											},
@@ -2296,9 +2396,9 @@ var g = &grammar{
 		},
 		{
 			name: "SingleCharUnicodeClass",
-			pos:  position{line: 302, col: 1, offset: 9322},
+			pos:  position{line: 325, col: 1, offset: 9977},
 			expr: &charClassMatcher{
-				pos:        position{line: 302, col: 26, offset: 9349},
+				pos:        position{line: 325, col: 26, offset: 10004},
 				val:        "[LMNCPZS]",
 				chars:      []rune{'L', 'M', 'N', 'C', 'P', 'Z', 'S'},
 				ignoreCase: false,
@@ -2307,12 +2407,12 @@ var g = &grammar{
 		},
 		{
 			name: "AnyMatcher",
-			pos:  position{line: 304, col: 1, offset: 9360},
+			pos:  position{line: 327, col: 1, offset: 10015},
 			expr: &actionExpr{
-				pos: position{line: 304, col: 14, offset: 9375},
+				pos: position{line: 327, col: 14, offset: 10030},
 				run: (*parser).callonAnyMatcher1,
 				expr: &litMatcher{
-					pos:        position{line: 304, col: 14, offset: 9375},
+					pos:        position{line: 327, col: 14, offset: 10030},
 					val:        ".",
 					ignoreCase: false,
 					want:       "\".\"",
@@ -2321,38 +2421,38 @@ var g = &grammar{
 		},
 		{
 			name: "ThrowExpr",
-			pos:  position{line: 309, col: 1, offset: 9450},
+			pos:  position{line: 332, col: 1, offset: 10105},
 			expr: &choiceExpr{
-				pos: position{line: 309, col: 13, offset: 9464},
+				pos: position{line: 332, col: 13, offset: 10119},
 				alternatives: []any{
 					&actionExpr{
-						pos: position{line: 309, col: 13, offset: 9464},
+						pos: position{line: 332, col: 13, offset: 10119},
 						run: (*parser).callonThrowExpr2,
 						expr: &seqExpr{
-							pos: position{line: 309, col: 13, offset: 9464},
+							pos: position{line: 332, col: 13, offset: 10119},
 							exprs: []any{
 								&litMatcher{
-									pos:        position{line: 309, col: 13, offset: 9464},
+									pos:        position{line: 332, col: 13, offset: 10119},
 									val:        "%",
 									ignoreCase: false,
 									want:       "\"%\"",
 								},
 								&litMatcher{
-									pos:        position{line: 309, col: 17, offset: 9468},
+									pos:        position{line: 332, col: 17, offset: 10123},
 									val:        "{",
 									ignoreCase: false,
 									want:       "\"{\"",
 								},
 								&labeledExpr{
-									pos:   position{line: 309, col: 21, offset: 9472},
+									pos:   position{line: 332, col: 21, offset: 10127},
 									label: "label",
 									expr: &ruleRefExpr{
-										pos:  position{line: 309, col: 27, offset: 9478},
+										pos:  position{line: 332, col: 27, offset: 10133},
 										name: "IdentifierName",
 									},
 								},
 								&litMatcher{
-									pos:        position{line: 309, col: 42, offset: 9493},
+									pos:        position{line: 332, col: 42, offset: 10148},
 									val:        "}",
 									ignoreCase: false,
 									want:       "\"}\"",
@@ -2361,29 +2461,29 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 313, col: 5, offset: 9601},
+						pos: position{line: 336, col: 5, offset: 10256},
 						run: (*parser).callonThrowExpr9,
 						expr: &seqExpr{
-							pos: position{line: 313, col: 5, offset: 9601},
+							pos: position{line: 336, col: 5, offset: 10256},
 							exprs: []any{
 								&litMatcher{
-									pos:        position{line: 313, col: 5, offset: 9601},
+									pos:        position{line: 336, col: 5, offset: 10256},
 									val:        "%",
 									ignoreCase: false,
 									want:       "\"%\"",
 								},
 								&litMatcher{
-									pos:        position{line: 313, col: 9, offset: 9605},
+									pos:        position{line: 336, col: 9, offset: 10260},
 									val:        "{",
 									ignoreCase: false,
 									want:       "\"{\"",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 313, col: 13, offset: 9609},
+									pos:  position{line: 336, col: 13, offset: 10264},
 									name: "IdentifierName",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 313, col: 28, offset: 9624},
+									pos:  position{line: 336, col: 28, offset: 10279},
 									name: "EOF",
 								},
 							},
@@ -2394,28 +2494,28 @@ var g = &grammar{
 		},
 		{
 			name: "CodeBlock",
-			pos:  position{line: 317, col: 1, offset: 9695},
+			pos:  position{line: 340, col: 1, offset: 10350},
 			expr: &choiceExpr{
-				pos: position{line: 317, col: 13, offset: 9709},
+				pos: position{line: 340, col: 13, offset: 10364},
 				alternatives: []any{
 					&actionExpr{
-						pos: position{line: 317, col: 13, offset: 9709},
+						pos: position{line: 340, col: 13, offset: 10364},
 						run: (*parser).callonCodeBlock2,
 						expr: &seqExpr{
-							pos: position{line: 317, col: 13, offset: 9709},
+							pos: position{line: 340, col: 13, offset: 10364},
 							exprs: []any{
 								&litMatcher{
-									pos:        position{line: 317, col: 13, offset: 9709},
+									pos:        position{line: 340, col: 13, offset: 10364},
 									val:        "{",
 									ignoreCase: false,
 									want:       "\"{\"",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 317, col: 17, offset: 9713},
+									pos:  position{line: 340, col: 17, offset: 10368},
 									name: "Code",
 								},
 								&litMatcher{
-									pos:        position{line: 317, col: 22, offset: 9718},
+									pos:        position{line: 340, col: 22, offset: 10373},
 									val:        "}",
 									ignoreCase: false,
 									want:       "\"}\"",
@@ -2424,23 +2524,23 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 321, col: 5, offset: 9817},
+						pos: position{line: 344, col: 5, offset: 10472},
 						run: (*parser).callonCodeBlock7,
 						expr: &seqExpr{
-							pos: position{line: 321, col: 5, offset: 9817},
+							pos: position{line: 344, col: 5, offset: 10472},
 							exprs: []any{
 								&litMatcher{
-									pos:        position{line: 321, col: 5, offset: 9817},
+									pos:        position{line: 344, col: 5, offset: 10472},
 									val:        "{",
 									ignoreCase: false,
 									want:       "\"{\"",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 321, col: 9, offset: 9821},
+									pos:  position{line: 344, col: 9, offset: 10476},
 									name: "Code",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 321, col: 14, offset: 9826},
+									pos:  position{line: 344, col: 14, offset: 10481},
 									name: "EOF",
 								},
 							},
@@ -2451,32 +2551,32 @@ var g = &grammar{
 		},
 		{
 			name: "Code",
-			pos:  position{line: 325, col: 1, offset: 9891},
+			pos:  position{line: 348, col: 1, offset: 10546},
 			expr: &zeroOrMoreExpr{
-				pos: position{line: 325, col: 8, offset: 9900},
+				pos: position{line: 348, col: 8, offset: 10555},
 				expr: &choiceExpr{
-					pos: position{line: 325, col: 10, offset: 9902},
+					pos: position{line: 348, col: 10, offset: 10557},
 					alternatives: []any{
 						&oneOrMoreExpr{
-							pos: position{line: 325, col: 10, offset: 9902},
+							pos: position{line: 348, col: 10, offset: 10557},
 							expr: &choiceExpr{
-								pos: position{line: 325, col: 12, offset: 9904},
+								pos: position{line: 348, col: 12, offset: 10559},
 								alternatives: []any{
 									&ruleRefExpr{
-										pos:  position{line: 325, col: 12, offset: 9904},
+										pos:  position{line: 348, col: 12, offset: 10559},
 										name: "Comment",
 									},
 									&ruleRefExpr{
-										pos:  position{line: 325, col: 22, offset: 9914},
+										pos:  position{line: 348, col: 22, offset: 10569},
 										name: "CodeStringLiteral",
 									},
 									&seqExpr{
-										pos: position{line: 325, col: 42, offset: 9934},
+										pos: position{line: 348, col: 42, offset: 10589},
 										exprs: []any{
 											&notExpr{
-												pos: position{line: 325, col: 42, offset: 9934},
+												pos: position{line: 348, col: 42, offset: 10589},
 												expr: &charClassMatcher{
-													pos:        position{line: 325, col: 43, offset: 9935},
+													pos:        position{line: 348, col: 43, offset: 10590},
 													val:        "[{}]",
 													chars:      []rune{'{', '}'},
 													ignoreCase: false,
@@ -2484,7 +2584,7 @@ var g = &grammar{
 												},
 											},
 											&ruleRefExpr{
-												pos:  position{line: 325, col: 48, offset: 9940},
+												pos:  position{line: 348, col: 48, offset: 10595},
 												name: "SourceChar",
 											},
 										},
@@ -2493,20 +2593,20 @@ var g = &grammar{
 							},
 						},
 						&seqExpr{
-							pos: position{line: 325, col: 64, offset: 9956},
+							pos: position{line: 348, col: 64, offset: 10611},
 							exprs: []any{
 								&litMatcher{
-									pos:        position{line: 325, col: 64, offset: 9956},
+									pos:        position{line: 348, col: 64, offset: 10611},
 									val:        "{",
 									ignoreCase: false,
 									want:       "\"{\"",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 325, col: 68, offset: 9960},
+									pos:  position{line: 348, col: 68, offset: 10615},
 									name: "Code",
 								},
 								&litMatcher{
-									pos:        position{line: 325, col: 73, offset: 9965},
+									pos:        position{line: 348, col: 73, offset: 10620},
 									val:        "}",
 									ignoreCase: false,
 									want:       "\"}\"",
@@ -2519,38 +2619,38 @@ var g = &grammar{
 		},
 		{
 			name: "CodeStringLiteral",
-			pos:  position{line: 327, col: 1, offset: 9973},
+			pos:  position{line: 350, col: 1, offset: 10628},
 			expr: &choiceExpr{
-				pos: position{line: 327, col: 21, offset: 9995},
+				pos: position{line: 350, col: 21, offset: 10650},
 				alternatives: []any{
 					&seqExpr{
-						pos: position{line: 327, col: 21, offset: 9995},
+						pos: position{line: 350, col: 21, offset: 10650},
 						exprs: []any{
 							&litMatcher{
-								pos:        position{line: 327, col: 21, offset: 9995},
+								pos:        position{line: 350, col: 21, offset: 10650},
 								val:        "\"",
 								ignoreCase: false,
 								want:       "\"\\\"\"",
 							},
 							&zeroOrMoreExpr{
-								pos: position{line: 327, col: 25, offset: 9999},
+								pos: position{line: 350, col: 25, offset: 10654},
 								expr: &choiceExpr{
-									pos: position{line: 327, col: 26, offset: 10000},
+									pos: position{line: 350, col: 26, offset: 10655},
 									alternatives: []any{
 										&litMatcher{
-											pos:        position{line: 327, col: 26, offset: 10000},
+											pos:        position{line: 350, col: 26, offset: 10655},
 											val:        "\\\"",
 											ignoreCase: false,
 											want:       "\"\\\\\\\"\"",
 										},
 										&litMatcher{
-											pos:        position{line: 327, col: 33, offset: 10007},
+											pos:        position{line: 350, col: 33, offset: 10662},
 											val:        "\\\\",
 											ignoreCase: false,
 											want:       "\"\\\\\\\\\"",
 										},
 										&charClassMatcher{
-											pos:        position{line: 327, col: 40, offset: 10014},
+											pos:        position{line: 350, col: 40, offset: 10669},
 											val:        "[^\"\\r\\n]",
 											chars:      []rune{'"', '\r', '\n'},
 											ignoreCase: false,
@@ -2560,7 +2660,7 @@ var g = &grammar{
 								},
 							},
 							&litMatcher{
-								pos:        position{line: 327, col: 51, offset: 10025},
+								pos:        position{line: 350, col: 51, offset: 10680},
 								val:        "\"",
 								ignoreCase: false,
 								want:       "\"\\\"\"",
@@ -2568,18 +2668,18 @@ var g = &grammar{
 						},
 					},
 					&seqExpr{
-						pos: position{line: 328, col: 21, offset: 10051},
+						pos: position{line: 351, col: 21, offset: 10706},
 						exprs: []any{
 							&litMatcher{
-								pos:        position{line: 328, col: 21, offset: 10051},
+								pos:        position{line: 351, col: 21, offset: 10706},
 								val:        "`",
 								ignoreCase: false,
 								want:       "\"`\"",
 							},
 							&zeroOrMoreExpr{
-								pos: position{line: 328, col: 25, offset: 10055},
+								pos: position{line: 351, col: 25, offset: 10710},
 								expr: &charClassMatcher{
-									pos:        position{line: 328, col: 25, offset: 10055},
+									pos:        position{line: 351, col: 25, offset: 10710},
 									val:        "[^`]",
 									chars:      []rune{'`'},
 									ignoreCase: false,
@@ -2587,7 +2687,7 @@ var g = &grammar{
 								},
 							},
 							&litMatcher{
-								pos:        position{line: 328, col: 31, offset: 10061},
+								pos:        position{line: 351, col: 31, offset: 10716},
 								val:        "`",
 								ignoreCase: false,
 								want:       "\"`\"",
@@ -2595,33 +2695,33 @@ var g = &grammar{
 						},
 					},
 					&seqExpr{
-						pos: position{line: 329, col: 21, offset: 10087},
+						pos: position{line: 352, col: 21, offset: 10742},
 						exprs: []any{
 							&litMatcher{
-								pos:        position{line: 329, col: 21, offset: 10087},
+								pos:        position{line: 352, col: 21, offset: 10742},
 								val:        "'",
 								ignoreCase: false,
 								want:       "\"'\"",
 							},
 							&choiceExpr{
-								pos: position{line: 329, col: 27, offset: 10093},
+								pos: position{line: 352, col: 27, offset: 10748},
 								alternatives: []any{
 									&litMatcher{
-										pos:        position{line: 329, col: 27, offset: 10093},
+										pos:        position{line: 352, col: 27, offset: 10748},
 										val:        "\\'",
 										ignoreCase: false,
 										want:       "\"\\\\'\"",
 									},
 									&litMatcher{
-										pos:        position{line: 329, col: 34, offset: 10100},
+										pos:        position{line: 352, col: 34, offset: 10755},
 										val:        "\\\\",
 										ignoreCase: false,
 										want:       "\"\\\\\\\\\"",
 									},
 									&oneOrMoreExpr{
-										pos: position{line: 329, col: 41, offset: 10107},
+										pos: position{line: 352, col: 41, offset: 10762},
 										expr: &charClassMatcher{
-											pos:        position{line: 329, col: 41, offset: 10107},
+											pos:        position{line: 352, col: 41, offset: 10762},
 											val:        "[^']",
 											chars:      []rune{'\''},
 											ignoreCase: false,
@@ -2631,7 +2731,7 @@ var g = &grammar{
 								},
 							},
 							&litMatcher{
-								pos:        position{line: 329, col: 48, offset: 10114},
+								pos:        position{line: 352, col: 48, offset: 10769},
 								val:        "'",
 								ignoreCase: false,
 								want:       "\"'\"",
@@ -2643,23 +2743,23 @@ var g = &grammar{
 		},
 		{
 			name: "__",
-			pos:  position{line: 331, col: 1, offset: 10120},
+			pos:  position{line: 354, col: 1, offset: 10775},
 			expr: &zeroOrMoreExpr{
-				pos: position{line: 331, col: 6, offset: 10127},
+				pos: position{line: 354, col: 6, offset: 10782},
 				expr: &choiceExpr{
-					pos: position{line: 331, col: 8, offset: 10129},
+					pos: position{line: 354, col: 8, offset: 10784},
 					alternatives: []any{
 						&ruleRefExpr{
-							pos:  position{line: 331, col: 8, offset: 10129},
+							pos:  position{line: 354, col: 8, offset: 10784},
 							name: "Whitespace",
 						},
 						&ruleRefExpr{
-							pos:  position{line: 331, col: 21, offset: 10142},
+							pos:  position{line: 354, col: 21, offset: 10797},
 							name: "EOL",
 						},
 						&ruleRefExpr{
-							pos:  position{line: 331, col: 27, offset: 10148},
-							name: "Comment",
+							pos:  position{line: 354, col: 27, offset: 10803},
+							name: "GrammarComment",
 						},
 					},
 				},
@@ -2667,19 +2767,31 @@ var g = &grammar{
 		},
 		{
 			name: "_",
-			pos:  position{line: 332, col: 1, offset: 10159},
+			pos:  position{line: 355, col: 1, offset: 10821},
 			expr: &zeroOrMoreExpr{
-				pos: position{line: 332, col: 5, offset: 10165},
+				pos: position{line: 355, col: 5, offset: 10827},
 				expr: &choiceExpr{
-					pos: position{line: 332, col: 7, offset: 10167},
+					pos: position{line: 355, col: 7, offset: 10829},
 					alternatives: []any{
 						&ruleRefExpr{
-							pos:  position{line: 332, col: 7, offset: 10167},
+							pos:  position{line: 355, col: 7, offset: 10829},
 							name: "Whitespace",
 						},
-						&ruleRefExpr{
-							pos:  position{line: 332, col: 20, offset: 10180},
-							name: "MultiLineCommentNoLineTerminator",
+						&seqExpr{
+							pos: position{line: 355, col: 20, offset: 10842},
+							exprs: []any{
+								&andExpr{
+									pos: position{line: 355, col: 20, offset: 10842},
+									expr: &ruleRefExpr{
+										pos:  position{line: 355, col: 21, offset: 10843},
+										name: "MultiLineCommentNoLineTerminator",
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 355, col: 54, offset: 10876},
+									name: "GrammarComment",
+								},
+							},
 						},
 					},
 				},
@@ -2687,9 +2799,9 @@ var g = &grammar{
 		},
 		{
 			name: "Whitespace",
-			pos:  position{line: 334, col: 1, offset: 10217},
+			pos:  position{line: 357, col: 1, offset: 10895},
 			expr: &charClassMatcher{
-				pos:        position{line: 334, col: 14, offset: 10232},
+				pos:        position{line: 357, col: 14, offset: 10910},
 				val:        "[ \\t\\r]",
 				chars:      []rune{' ', '\t', '\r'},
 				ignoreCase: false,
@@ -2698,9 +2810,9 @@ var g = &grammar{
 		},
 		{
 			name: "EOL",
-			pos:  position{line: 335, col: 1, offset: 10240},
+			pos:  position{line: 358, col: 1, offset: 10918},
 			expr: &litMatcher{
-				pos:        position{line: 335, col: 7, offset: 10248},
+				pos:        position{line: 358, col: 7, offset: 10926},
 				val:        "\n",
 				ignoreCase: false,
 				want:       "\"\\n\"",
@@ -2708,19 +2820,19 @@ var g = &grammar{
 		},
 		{
 			name: "EOS",
-			pos:  position{line: 336, col: 1, offset: 10253},
+			pos:  position{line: 359, col: 1, offset: 10931},
 			expr: &choiceExpr{
-				pos: position{line: 336, col: 7, offset: 10261},
+				pos: position{line: 359, col: 7, offset: 10939},
 				alternatives: []any{
 					&seqExpr{
-						pos: position{line: 336, col: 7, offset: 10261},
+						pos: position{line: 359, col: 7, offset: 10939},
 						exprs: []any{
 							&ruleRefExpr{
-								pos:  position{line: 336, col: 7, offset: 10261},
+								pos:  position{line: 359, col: 7, offset: 10939},
 								name: "__",
 							},
 							&litMatcher{
-								pos:        position{line: 336, col: 10, offset: 10264},
+								pos:        position{line: 359, col: 10, offset: 10942},
 								val:        ";",
 								ignoreCase: false,
 								want:       "\";\"",
@@ -2728,34 +2840,46 @@ var g = &grammar{
 						},
 					},
 					&seqExpr{
-						pos: position{line: 336, col: 16, offset: 10270},
+						pos: position{line: 359, col: 16, offset: 10948},
 						exprs: []any{
 							&ruleRefExpr{
-								pos:  position{line: 336, col: 16, offset: 10270},
+								pos:  position{line: 359, col: 16, offset: 10948},
 								name: "_",
 							},
 							&zeroOrOneExpr{
-								pos: position{line: 336, col: 18, offset: 10272},
-								expr: &ruleRefExpr{
-									pos:  position{line: 336, col: 18, offset: 10272},
-									name: "SingleLineComment",
+								pos: position{line: 359, col: 18, offset: 10950},
+								expr: &seqExpr{
+									pos: position{line: 359, col: 20, offset: 10952},
+									exprs: []any{
+										&andExpr{
+											pos: position{line: 359, col: 20, offset: 10952},
+											expr: &ruleRefExpr{
+												pos:  position{line: 359, col: 21, offset: 10953},
+												name: "SingleLineComment",
+											},
+										},
+										&ruleRefExpr{
+											pos:  position{line: 359, col: 39, offset: 10971},
+											name: "GrammarComment",
+										},
+									},
 								},
 							},
 							&ruleRefExpr{
-								pos:  position{line: 336, col: 37, offset: 10291},
+								pos:  position{line: 359, col: 57, offset: 10989},
 								name: "EOL",
 							},
 						},
 					},
 					&seqExpr{
-						pos: position{line: 336, col: 43, offset: 10297},
+						pos: position{line: 359, col: 63, offset: 10995},
 						exprs: []any{
 							&ruleRefExpr{
-								pos:  position{line: 336, col: 43, offset: 10297},
+								pos:  position{line: 359, col: 63, offset: 10995},
 								name: "__",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 336, col: 46, offset: 10300},
+								pos:  position{line: 359, col: 66, offset: 10998},
 								name: "EOF",
 							},
 						},
@@ -2765,11 +2889,11 @@ var g = &grammar{
 		},
 		{
 			name: "EOF",
-			pos:  position{line: 338, col: 1, offset: 10305},
+			pos:  position{line: 361, col: 1, offset: 11003},
 			expr: &notExpr{
-				pos: position{line: 338, col: 7, offset: 10313},
+				pos: position{line: 361, col: 7, offset: 11011},
 				expr: &anyMatcher{
-					line: 338, col: 8, offset: 10314,
+					line: 361, col: 8, offset: 11012,
 				},
 			},
 		},
@@ -2791,6 +2915,8 @@ func (c *current) onGrammar1(initializer, rules any) (any, error) {
 	for i, duo := range rulesSlice {
 		g.Rules[i] = duo.([]any)[0].(*ast.Rule)
 	}
+	g.Comments = c.comments()
+	setRuleDocs(g)
 
 	return g, nil
 }
@@ -3006,14 +3132,14 @@ func (p *parser) callonSuffixedOp1() (any, error) {
 	return p.cur.onSuffixedOp1()
 }
 
-func (c *current) onPrimaryExpr7(expr any) (any, error) {
+func (c *current) onPrimaryExpr9(expr any) (any, error) {
 	return expr, nil
 }
 
-func (p *parser) callonPrimaryExpr7() (any, error) {
+func (p *parser) callonPrimaryExpr9() (any, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onPrimaryExpr7(stack["expr"])
+	return p.cur.onPrimaryExpr9(stack["expr"])
 }
 
 func (c *current) onRuleRefExpr1(name any) (any, error) {
@@ -3028,6 +3154,37 @@ func (p *parser) callonRuleRefExpr1() (any, error) {
 	return p.cur.onRuleRefExpr1(stack["name"])
 }
 
+func (c *current) onBackRefExpr1(label any) (any, error) {
+	ref := ast.NewBackRefExpr(c.astPos())
+	ref.Label = label.(*ast.Identifier)
+	return ref, nil
+}
+
+func (p *parser) callonBackRefExpr1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onBackRefExpr1(stack["label"])
+}
+
+func (c *current) onIndentExpr1(op any) (any, error) {
+	var kind ast.IndentKind
+	switch string(op.([]byte)) {
+	case "INDENT":
+		kind = ast.IndentKindIndent
+	case "DEDENT":
+		kind = ast.IndentKindDedent
+	default:
+		kind = ast.IndentKindSamedent
+	}
+	return ast.NewIndentExpr(c.astPos(), kind), nil
+}
+
+func (p *parser) callonIndentExpr1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onIndentExpr1(stack["op"])
+}
+
 func (c *current) onSemanticPredExpr1(op, code any) (any, error) {
 	switch op.(string) {
 	case "#":
@@ -3065,6 +3222,17 @@ func (p *parser) callonSemanticPredOp1() (any, error) {
 	return p.cur.onSemanticPredOp1()
 }
 
+func (c *current) onGrammarComment1() (any, error) {
+	c.addComment()
+	return nil, nil
+}
+
+func (p *parser) callonGrammarComment1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onGrammarComment1()
+}
+
 func (c *current) onIdentifier1(ident any) (any, error) {
 	astIdent := ast.NewIdentifier(c.astPos(), string(c.text))
 	if reservedWords[astIdent.Val] {
@@ -3421,9 +3589,23 @@ func Statistics(stats *Stats, choiceNoMatch string) Option {
 // The default is false.
 func Debug(b bool) Option {
 	return func(p *parser) Option {
-		old := p.debug
-		p.debug = b
-		return Debug(old)
+		old := p.tracer
+		if b {
+			p.tracer = &debugTracer{p: p}
+		} else if _, ok := old.(*debugTracer); ok {
+			p.tracer = nil
+		}
+		return setTracer(old)
+	}
+}
+
+// setTracer creates an Option to set the tracer that receives the events
+// of the parse.
+func setTracer(t tracer) Option {
+	return func(p *parser) Option {
+		old := p.tracer
+		p.tracer = t
+		return setTracer(old)
 	}
 }
 
@@ -3431,6 +3613,9 @@ func Debug(b bool) Option {
 // the parser will cache all results so each expression is evaluated only
 // once. This guarantees linear parsing time even for pathological cases,
 // at the expense of more memory and slower times for typical cases.
+// The results of the rules with the @memoize annotation in the grammar
+// are cached even if b is false, without the cost of caching every
+// expression.
 //
 // The default is false.
 func Memoize(b bool) Option {
@@ -3441,6 +3626,23 @@ func Memoize(b bool) Option {
 	}
 }
 
+// MemoWindow creates an Option to bound the memory of the memoization
+// table: the results are kept only for the n offsets behind the furthest
+// offset where a result was cached, as the parser rarely backtracks
+// further. The results evicted from the window are evaluated again if
+// needed, the memory is O(n) instead of O(len(input)). The rules with
+// the @commit annotation in the grammar also discard the results before
+// the end of their match. If n is 0, all the results are kept.
+//
+// The default is 0.
+func MemoWindow(n int) Option {
+	return func(p *parser) Option {
+		old := p.memoWindow
+		p.memoWindow = n
+		return MemoWindow(old)
+	}
+}
+
 // AllowInvalidUTF8 creates an Option to allow invalid UTF-8 bytes.
 // Every invalid UTF-8 byte is treated as a utf8.RuneError (U+FFFD)
 // by character class matchers and is matched by the any matcher.
@@ -3699,6 +3901,11 @@ func (e *errList) dedupe() {
 	*e = cleaned
 }
 
+// Unwrap returns the errors of the list.
+func (e errList) Unwrap() []error {
+	return e
+}
+
 func (e errList) Error() string {
 	switch len(e) {
 	case 0:
@@ -3790,7 +3997,8 @@ type Stats struct {
 	// ChoiceAltCnt is used to count for each ordered choice expression,
 	// which alternative is used how may times.
 	// These numbers allow to optimize the order of the ordered choice expression
-	// to increase the performance of the parser
+	// to increase the performance of the parser, saved as JSON for the
+	// -choice-stats flag of pigeon.
 	//
 	// The outer key of ChoiceAltCnt is composed of the name of the rule as well
 	// as the line and the column of the ordered choice.
@@ -3811,14 +4019,17 @@ type parser struct {
 	data []byte
 	errs *errList
 
-	depth   int
 	recover bool
-	debug   bool
+	tracer  tracer
 
 	memoize bool
-	// memoization table for the packrat algorithm:
-	// map[offset in source] map[expression or rule] {value, match}
-	memo map[int]map[any]resultTuple
+	// memoization table for the packrat algorithm, the results of the
+	// expressions and the rules by offset in source, in a ring of
+	// memoWindow slots if set
+	memo       []memoSlot
+	memoWindow int
+	// the results before this offset are discarded by the commit points
+	memoFloor int
 
 	// rules table, maps the rule identifier to the rule node
 	rules map[string]*rule
@@ -3903,29 +4114,73 @@ func (p *parser) popRecovery() {
 	p.recoveryStack = p.recoveryStack[:len(p.recoveryStack)-1]
 }
 
-func (p *parser) print(prefix, s string) string {
-	if !p.debug {
-		return s
-	}
+func (p *parser) in(s string) string {
+	p.tracer.enterExpr(s, p.pt.position)
+	return s
+}
 
-	fmt.Printf("%s %d:%d:%d: %s [%#U]\n",
-		prefix, p.pt.line, p.pt.col, p.pt.offset, s, p.pt.rn)
+func (p *parser) out(s string) string {
+	p.tracer.exitExpr(s, p.pt.position)
 	return s
 }
 
-func (p *parser) printIndent(mark string, s string) string {
-	return p.print(strings.Repeat(" ", p.depth)+mark, s)
+// tracer receives the events of a parse, with the positions of the
+// parser.
+type tracer interface {
+	enterRule(rule string, pos position)
+	exitRule(rule string, matched bool, start, end position)
+	enterExpr(step string, pos position)
+	exitExpr(step string, pos position)
+	backtrack(from, to position)
+	memo(rule string, pos position, hit bool)
+	error(err error)
 }
 
-func (p *parser) in(s string) string {
-	res := p.printIndent(">", s)
-	p.depth++
-	return res
+// debugTracer is the tracer of the Debug option, it prints the events
+// to stdout, indented by the depth of the steps.
+type debugTracer struct {
+	p     *parser
+	depth int
 }
 
-func (p *parser) out(s string) string {
-	p.depth--
-	return p.printIndent("<", s)
+func (t *debugTracer) print(mark string, pos position, s string) {
+	fmt.Printf("%s%s %d:%d:%d: %s [%#U]\n",
+		strings.Repeat(" ", t.depth), mark, pos.line, pos.col, pos.offset, s, t.p.pt.rn)
+}
+
+func (t *debugTracer) enterRule(rule string, pos position) {
+	t.enterExpr("parseRule "+rule, pos)
+}
+
+func (t *debugTracer) exitRule(rule string, matched bool, start, end position) {
+	if matched {
+		t.print("MATCH", end, string(t.p.slice(start.offset, end.offset)))
+	}
+	t.exitExpr("parseRule "+rule, end)
+}
+
+func (t *debugTracer) enterExpr(step string, pos position) {
+	t.print(">", pos, step)
+	t.depth++
+}
+
+func (t *debugTracer) exitExpr(step string, pos position) {
+	t.depth--
+	t.print("<", pos, step)
+}
+
+func (t *debugTracer) backtrack(from, to position) {
+	t.print("BACKTRACK", to, fmt.Sprintf("from %d:%d:%d", from.line, from.col, from.offset))
+}
+
+func (t *debugTracer) memo(rule string, pos position, hit bool) {
+	if hit {
+		t.print("MEMO", pos, rule)
+	}
+}
+
+func (t *debugTracer) error(err error) {
+	t.print("ERROR", t.p.pt.position, err.Error())
 }
 
 func (p *parser) addErr(err error) {
@@ -3954,6 +4209,9 @@ func (p *parser) addErrAt(err error, pos position, expected []string) {
 	}
 	pe := &parserError{Inner: err, pos: pos, prefix: buf.String(), expected: expected}
 	p.errs.add(pe)
+	if p.tracer != nil {
+		p.tracer.error(pe)
+	}
 }
 
 func (p *parser) failAt(fail bool, pos position, want string) {
@@ -3978,7 +4236,7 @@ func (p *parser) failAt(fail bool, pos position, want string) {
 // read advances the parser to the next rune.
 func (p *parser) read() {
 	p.pt.offset += p.pt.w
-	rn, n := utf8.DecodeRune(p.data[p.pt.offset:])
+	rn, n, valid := p.decode(p.data[p.pt.offset:])
 	p.pt.rn = rn
 	p.pt.w = n
 	p.pt.col++
@@ -3987,21 +4245,27 @@ func (p *parser) read() {
 		p.pt.col = 0
 	}
 
-	if rn == utf8.RuneError && n == 1 { // see utf8.DecodeRune
+	if !valid {
 		if !p.allowInvalidUTF8 {
 			p.addErr(errInvalidEncoding)
 		}
 	}
 }
 
+// decode decodes the first character of b in the encoding of the input.
+func (p *parser) decode(b []byte) (rune, int, bool) {
+	rn, n := utf8.DecodeRune(b)
+	return rn, n, rn != utf8.RuneError || n != 1 // see utf8.DecodeRune
+}
+
 // restore parser position to the savepoint pt.
 func (p *parser) restore(pt savepoint) {
-	if p.debug {
-		defer p.out(p.in("restore"))
-	}
 	if pt.offset == p.pt.offset {
 		return
 	}
+	if p.tracer != nil && pt.offset < p.pt.offset {
+		p.tracer.backtrack(p.pt.position, pt.position)
+	}
 	p.pt = pt
 }
 
@@ -4029,7 +4293,7 @@ func (sd storeDict) Discard() {
 
 // clone and return parser current state.
 func (p *parser) cloneState() storeDict {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("cloneState"))
 	}
 
@@ -4047,7 +4311,7 @@ func (p *parser) cloneState() storeDict {
 // restore parser current state to the state storeDict.
 // every restoreState should applied only one time for every cloned state
 func (p *parser) restoreState(state storeDict) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("restoreState"))
 	}
 	p.cur.state.Discard()
@@ -4056,31 +4320,73 @@ func (p *parser) restoreState(state storeDict) {
 
 // get the slice of bytes from the savepoint start to the current position.
 func (p *parser) sliceFrom(start savepoint) []byte {
-	return p.data[start.position.offset:p.pt.position.offset]
+	return p.slice(start.position.offset, p.pt.position.offset)
+}
+
+// get the slice of bytes between the offsets start and end.
+func (p *parser) slice(start, end int) []byte {
+	return p.data[start:end]
+}
+
+// memoEntry is the result of an expression or a rule in the memoization
+// table. The few results at an offset are searched linearly.
+type memoEntry struct {
+	node any
+	res  resultTuple
+}
+
+// memoSlot holds the results at an offset of the memoization table, the
+// slots are reused for the offsets that are memoWindow apart.
+type memoSlot struct {
+	offset  int
+	entries []memoEntry
 }
 
 func (p *parser) getMemoized(node any) (resultTuple, bool) {
-	if len(p.memo) == 0 {
+	if len(p.memo) == 0 || p.pt.offset < p.memoFloor {
 		return resultTuple{}, false
 	}
-	m := p.memo[p.pt.offset]
-	if len(m) == 0 {
+	slot := &p.memo[p.pt.offset%len(p.memo)]
+	if slot.offset != p.pt.offset {
 		return resultTuple{}, false
 	}
-	res, ok := m[node]
-	return res, ok
+	for _, e := range slot.entries {
+		if e.node == node {
+			return e.res, true
+		}
+	}
+	return resultTuple{}, false
 }
 
 func (p *parser) setMemoized(pt savepoint, node any, tuple resultTuple) {
 	if p.memo == nil {
-		p.memo = make(map[int]map[any]resultTuple)
+		n := len(p.data) + 1
+		if p.memoWindow > 0 && p.memoWindow < n {
+			n = p.memoWindow
+		}
+		p.memo = make([]memoSlot, n)
 	}
-	m := p.memo[pt.offset]
-	if m == nil {
-		m = make(map[any]resultTuple)
-		p.memo[pt.offset] = m
+	if pt.offset < p.memoFloor {
+		return
+	}
+	slot := &p.memo[pt.offset%len(p.memo)]
+	if slot.offset != pt.offset {
+		if slot.offset > pt.offset {
+			// out of the window
+			return
+		}
+		for i := range slot.entries {
+			slot.entries[i] = memoEntry{}
+		}
+		slot.offset, slot.entries = pt.offset, slot.entries[:0]
 	}
-	m[node] = tuple
+	for i := range slot.entries {
+		if slot.entries[i].node == node {
+			slot.entries[i].res = tuple
+			return
+		}
+	}
+	slot.entries = append(slot.entries, memoEntry{node: node, res: tuple})
 }
 
 func (p *parser) buildRulesTable(g *grammar) {
@@ -4105,7 +4411,7 @@ func (p *parser) parse(g *grammar) (val any, err error) {
 		// and return the panic as an error.
 		defer func() {
 			if e := recover(); e != nil {
-				if p.debug {
+				if p.tracer != nil {
 					defer p.out(p.in("panic handler"))
 				}
 				val = nil
@@ -4168,8 +4474,17 @@ func listJoin(list []string, sep string, lastSep string) string {
 	}
 }
 
+// memoizeRule returns true if the results of the rule are memoized, with
+// the Memoize option or with the @memoize annotation of the rule.
+func (p *parser) memoizeRule(rule *rule) bool {
+	return p.memoize
+}
+
 func (p *parser) parseRuleMemoize(rule *rule) (any, bool) {
 	res, ok := p.getMemoized(rule)
+	if p.tracer != nil {
+		p.tracer.memo(rule.name, p.pt.position, ok)
+	}
 	if ok {
 		p.restore(res.end)
 		return res.v, res.b
@@ -4183,8 +4498,8 @@ func (p *parser) parseRuleMemoize(rule *rule) (any, bool) {
 }
 
 func (p *parser) parseRuleWrap(rule *rule) (any, bool) {
-	if p.debug {
-		defer p.out(p.in("parseRule " + rule.name))
+	if p.tracer != nil {
+		p.tracer.enterRule(rule.name, p.pt.position)
 	}
 	var (
 		val       any
@@ -4192,14 +4507,14 @@ func (p *parser) parseRuleWrap(rule *rule) (any, bool) {
 		startMark = p.pt
 	)
 
-	if p.memoize {
+	if p.memoizeRule(rule) {
 		val, ok = p.parseRuleMemoize(rule)
 	} else {
 		val, ok = p.parseRule(rule)
 	}
 
-	if ok && p.debug {
-		p.printIndent("MATCH", string(p.sliceFrom(startMark)))
+	if p.tracer != nil {
+		p.tracer.exitRule(rule.name, ok, startMark.position, p.pt.position)
 	}
 	return val, ok
 }
@@ -4234,14 +4549,12 @@ func (p *parser) parseExprWrap(expr any) (any, bool) {
 }
 
 // nolint: gocyclo
-func (p *parser) parseExpr(expr any) (any, bool) {
+func (p *parser) parseExpr(expr any) (val any, ok bool) {
 	p.ExprCnt++
 	if p.ExprCnt > p.maxExprCnt {
 		panic(errMaxExprCnt)
 	}
 
-	var val any
-	var ok bool
 	switch expr := expr.(type) {
 	case *actionExpr:
 		val, ok = p.parseActionExpr(expr)
@@ -4286,7 +4599,7 @@ func (p *parser) parseExpr(expr any) (any, bool) {
 }
 
 func (p *parser) parseActionExpr(act *actionExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseActionExpr"))
 	}
 
@@ -4304,14 +4617,11 @@ func (p *parser) parseActionExpr(act *actionExpr) (any, bool) {
 
 		val = actVal
 	}
-	if ok && p.debug {
-		p.printIndent("MATCH", string(p.sliceFrom(start)))
-	}
 	return val, ok
 }
 
 func (p *parser) parseAndCodeExpr(and *andCodeExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseAndCodeExpr"))
 	}
 
@@ -4327,23 +4637,21 @@ func (p *parser) parseAndCodeExpr(and *andCodeExpr) (any, bool) {
 }
 
 func (p *parser) parseAndExpr(and *andExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseAndExpr"))
 	}
 
 	pt := p.pt
-	state := p.cloneState()
 	p.pushV()
 	_, ok := p.parseExprWrap(and.expr)
 	p.popV()
-	p.restoreState(state)
 	p.restore(pt)
 
 	return nil, ok
 }
 
 func (p *parser) parseAnyMatcher(any *anyMatcher) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseAnyMatcher"))
 	}
 
@@ -4360,7 +4668,7 @@ func (p *parser) parseAnyMatcher(any *anyMatcher) (any, bool) {
 
 // nolint: gocyclo
 func (p *parser) parseCharClassMatcher(chr *charClassMatcher) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseCharClassMatcher"))
 	}
 
@@ -4441,7 +4749,7 @@ func (p *parser) incChoiceAltCnt(ch *choiceExpr, altI int) {
 }
 
 func (p *parser) parseChoiceExpr(ch *choiceExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseChoiceExpr"))
 	}
 
@@ -4449,8 +4757,6 @@ func (p *parser) parseChoiceExpr(ch *choiceExpr) (any, bool) {
 		// dummy assignment to prevent compile error if optimized
 		_ = altI
 
-		state := p.cloneState()
-
 		p.pushV()
 		val, ok := p.parseExprWrap(alt)
 		p.popV()
@@ -4458,14 +4764,13 @@ func (p *parser) parseChoiceExpr(ch *choiceExpr) (any, bool) {
 			p.incChoiceAltCnt(ch, altI)
 			return val, ok
 		}
-		p.restoreState(state)
 	}
 	p.incChoiceAltCnt(ch, choiceNoMatch)
 	return nil, false
 }
 
 func (p *parser) parseLabeledExpr(lab *labeledExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseLabeledExpr"))
 	}
 
@@ -4480,7 +4785,7 @@ func (p *parser) parseLabeledExpr(lab *labeledExpr) (any, bool) {
 }
 
 func (p *parser) parseLitMatcher(lit *litMatcher) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseLitMatcher"))
 	}
 
@@ -4502,7 +4807,7 @@ func (p *parser) parseLitMatcher(lit *litMatcher) (any, bool) {
 }
 
 func (p *parser) parseNotCodeExpr(not *notCodeExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseNotCodeExpr"))
 	}
 
@@ -4518,25 +4823,23 @@ func (p *parser) parseNotCodeExpr(not *notCodeExpr) (any, bool) {
 }
 
 func (p *parser) parseNotExpr(not *notExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseNotExpr"))
 	}
 
 	pt := p.pt
-	state := p.cloneState()
 	p.pushV()
 	p.maxFailInvertExpected = !p.maxFailInvertExpected
 	_, ok := p.parseExprWrap(not.expr)
 	p.maxFailInvertExpected = !p.maxFailInvertExpected
 	p.popV()
-	p.restoreState(state)
 	p.restore(pt)
 
 	return nil, !ok
 }
 
 func (p *parser) parseOneOrMoreExpr(expr *oneOrMoreExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseOneOrMoreExpr"))
 	}
 
@@ -4558,7 +4861,7 @@ func (p *parser) parseOneOrMoreExpr(expr *oneOrMoreExpr) (any, bool) {
 }
 
 func (p *parser) parseRecoveryExpr(recover *recoveryExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseRecoveryExpr (" + strings.Join(recover.failureLabel, ",") + ")"))
 	}
 
@@ -4570,7 +4873,7 @@ func (p *parser) parseRecoveryExpr(recover *recoveryExpr) (any, bool) {
 }
 
 func (p *parser) parseRuleRefExpr(ref *ruleRefExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseRuleRefExpr " + ref.name))
 	}
 
@@ -4587,18 +4890,16 @@ func (p *parser) parseRuleRefExpr(ref *ruleRefExpr) (any, bool) {
 }
 
 func (p *parser) parseSeqExpr(seq *seqExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseSeqExpr"))
 	}
 
 	vals := make([]any, 0, len(seq.exprs))
 
 	pt := p.pt
-	state := p.cloneState()
 	for _, expr := range seq.exprs {
 		val, ok := p.parseExprWrap(expr)
 		if !ok {
-			p.restoreState(state)
 			p.restore(pt)
 			return nil, false
 		}
@@ -4608,7 +4909,7 @@ func (p *parser) parseSeqExpr(seq *seqExpr) (any, bool) {
 }
 
 func (p *parser) parseStateCodeExpr(state *stateCodeExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseStateCodeExpr"))
 	}
 
@@ -4620,7 +4921,7 @@ func (p *parser) parseStateCodeExpr(state *stateCodeExpr) (any, bool) {
 }
 
 func (p *parser) parseThrowExpr(expr *throwExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseThrowExpr"))
 	}
 
@@ -4636,7 +4937,7 @@ func (p *parser) parseThrowExpr(expr *throwExpr) (any, bool) {
 }
 
 func (p *parser) parseZeroOrMoreExpr(expr *zeroOrMoreExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseZeroOrMoreExpr"))
 	}
 
@@ -4654,7 +4955,7 @@ func (p *parser) parseZeroOrMoreExpr(expr *zeroOrMoreExpr) (any, bool) {
 }
 
 func (p *parser) parseZeroOrOneExpr(expr *zeroOrOneExpr) (any, bool) {
-	if p.debug {
+	if p.tracer != nil {
 		defer p.out(p.in("parseZeroOrOneExpr"))
 	}
 
@@ -1,4 +1,4 @@
-package main
+package parser
 
 var reservedWords = map[string]bool{
 	// Go keywords http://golang.org/ref/spec#Keywords
@@ -1,7 +1,7 @@
 // This file is generated by the misc/cmd/unicode-classes tool.
 // Do not edit.
 
-package main
+package parser
 
 var unicodeClasses = map[string]bool{
 	"ASCII_Hex_Digit":                    true,
@@ -0,0 +1,81 @@
+// Package parse parses pigeon grammars into their AST.
+//
+// ParseGrammar reads a grammar and, unless the Validate option disables it,
+// validates it as the pigeon command does before generating its parser.
+package parse
+
+import (
+	"io"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/builder"
+	"github.com/mna/pigeon/parse/internal/parser"
+)
+
+// Option is a function that can set an option on ParseGrammar.
+type Option func(*options)
+
+type options struct {
+	debug    bool
+	memoize  bool
+	recover  bool
+	validate bool
+}
+
+// Debug creates an Option to print the trace of the grammar parser to
+// os.Stdout. The default is false.
+func Debug(b bool) Option {
+	return func(o *options) {
+		o.debug = b
+	}
+}
+
+// Memoize creates an Option to memoize the results of the grammar parser.
+// The default is false.
+func Memoize(b bool) Option {
+	return func(o *options) {
+		o.memoize = b
+	}
+}
+
+// Recover creates an Option to recover from the panics of the grammar
+// parser and return them as errors. The default is true.
+func Recover(b bool) Option {
+	return func(o *options) {
+		o.recover = b
+	}
+}
+
+// Validate creates an Option to report the errors that prevent building
+// the parser of the grammar, as builder.CheckGrammar does. The tools that
+// only read or rewrite a grammar disable it. The default is true.
+func Validate(b bool) Option {
+	return func(o *options) {
+		o.validate = b
+	}
+}
+
+// ParseGrammar parses the grammar read from r, using filename as
+// information in the error messages, and returns its AST. Besides the
+// syntax errors and the errors reported by the grammar parser, such as the
+// reserved words used as rule names and the invalid Unicode classes, it
+// returns the errors that prevent building the parser of the grammar.
+func ParseGrammar(filename string, r io.Reader, opts ...Option) (*ast.Grammar, error) {
+	o := options{recover: true, validate: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	g, err := parser.ParseReader(filename, r,
+		parser.Debug(o.debug), parser.Memoize(o.memoize), parser.Recover(o.recover))
+	if err != nil {
+		return nil, err
+	}
+	grammar := g.(*ast.Grammar)
+	if o.validate {
+		if err := builder.CheckGrammar(grammar); err != nil {
+			return nil, err
+		}
+	}
+	return grammar, nil
+}
@@ -0,0 +1,37 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseGrammar(t *testing.T) {
+	cases := []struct {
+		src, err string
+		opts     []Option
+	}{
+		{src: "a = b\nb = 'b'"},
+		{src: "a = a 'x' / 'y'"},
+		{src: "a = x:'x' \\x"},
+		{src: "a = ", err: `g.peg:1:5 (4): no match found, expected: "!", "#", "%", "&", "'", "(", ".", "/*", "//", "[", "\"", "\\", "\n", "` + "`" + `", [ \t\r] or [\pL_]`},
+		{src: "a = func:'x'", err: "g.peg:1:5 (4): rule Identifier: identifier is a reserved word"},
+		{src: "a = x:'x' \\y", err: "incorrect grammar: rule a: 1:11 (10): back-reference to undefined label y"},
+		{src: "a = ( x:'x' / 'y' ) \\x", err: "incorrect grammar: rule a: 1:21 (20): back-reference to undefined label x"},
+		{src: "a = x:'x' \\y", opts: []Option{Validate(false)}},
+		{src: "a = func:'x'", opts: []Option{Validate(false)}, err: "g.peg:1:5 (4): rule Identifier: identifier is a reserved word"},
+	}
+	for _, c := range cases {
+		g, err := ParseGrammar("g.peg", strings.NewReader(c.src), c.opts...)
+		var got string
+		if err != nil {
+			got = err.Error()
+		}
+		if got != c.err {
+			t.Errorf("%q: want error %q, got %q", c.src, c.err, got)
+			continue
+		}
+		if err == nil && len(g.Rules) == 0 {
+			t.Errorf("%q: want rules, got none", c.src)
+		}
+	}
+}
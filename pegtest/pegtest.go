@@ -0,0 +1,175 @@
+// Package pegtest runs example inputs against the rules of a grammar.
+//
+// The examples are read from the doc comments of the rules, in lines that
+// start with @accept or @reject followed by the input as a Go string
+// literal:
+//
+//	// Number is an integer.
+//	//
+//	// @accept "42"
+//	// @accept "-1" => ["-" "1"]
+//	// @reject "4x"
+//	Number ← '-'? [0-9]+
+//
+// or from a YAML file that maps the names of the rules to their examples:
+//
+//	Number:
+//	  accept:
+//	    - "42"
+//	    - input: "-1"
+//	      want: '["-" "1"]'
+//	  reject:
+//	    - "4x"
+//
+// The optional snapshot after => or in want is the expected value returned
+// by the parser, as formatted by Snapshot. Run generates the parser of the
+// grammar in a temporary Go module and runs the examples with the go test
+// command, the rule of each example being used as entrypoint.
+package pegtest
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mna/pigeon/ast"
+)
+
+// Case is an example input of a rule.
+type Case struct {
+	// Pos is the position of the example, in the grammar or in the YAML
+	// file.
+	Pos ast.Pos
+
+	Rule  string
+	Input string
+
+	// Reject is set if the rule must not match Input.
+	Reject bool
+
+	// Want is the snapshot of the expected value, if not empty.
+	Want string
+}
+
+// String returns the textual representation of the case.
+func (c *Case) String() string {
+	verb := "accept"
+	if c.Reject {
+		verb = "reject"
+	}
+	return fmt.Sprintf("%s:%d: %s %s %q", c.Pos.Filename, c.Pos.Line, c.Rule, verb, c.Input)
+}
+
+// Result is the result of running a case.
+type Result struct {
+	*Case
+
+	// Got is the snapshot of the value returned by the parser, if it
+	// succeeds, and Err the error returned by the parser otherwise.
+	Got string
+	Err string
+}
+
+// Passed returns true if the parser behaved as expected by the case.
+func (r *Result) Passed() bool {
+	if r.Reject {
+		return r.Err != ""
+	}
+	return r.Err == "" && (r.Want == "" || r.Want == r.Got)
+}
+
+// annotation matches the examples in the doc comments.
+var annotation = regexp.MustCompile(`^\s*\*?\s*@(accept|reject)\s+(.*)$`)
+
+// FromComments returns the examples in the doc comments of the rules of
+// g, read from the grammar file filename.
+func FromComments(filename string, g *ast.Grammar) ([]*Case, error) {
+	var cases []*Case
+	for _, r := range g.Rules {
+		for _, c := range r.Doc {
+			text := strings.TrimPrefix(c.Val, "//")
+			if strings.HasPrefix(c.Val, "/*") {
+				text = strings.TrimSuffix(c.Val[2:], "*/")
+			}
+
+			for i, l := range strings.Split(text, "\n") {
+				m := annotation.FindStringSubmatch(l)
+				if m == nil {
+					continue
+				}
+				p := c.Pos()
+				p.Line += i
+				if p.Filename == "" {
+					p.Filename = filename
+				}
+				tc, err := parseAnnotation(p, r.Name.Val, m[1] == "reject", strings.TrimSpace(m[2]))
+				if err != nil {
+					return nil, err
+				}
+				cases = append(cases, tc)
+			}
+		}
+	}
+	return cases, nil
+}
+
+// parseAnnotation returns the case of the example in s, the input as a Go
+// string literal followed by an optional snapshot after =>.
+func parseAnnotation(p ast.Pos, rule string, reject bool, s string) (*Case, error) {
+	lit, err := strconv.QuotedPrefix(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid example input, want a Go string literal: %s", p, s)
+	}
+	input, _ := strconv.Unquote(lit)
+	tc := &Case{Pos: p, Rule: rule, Input: input, Reject: reject}
+
+	rest := strings.TrimSpace(s[len(lit):])
+	if rest == "" {
+		return tc, nil
+	}
+	want, ok := strings.CutPrefix(rest, "=>")
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("%s: unexpected %q after the example input", p, rest)
+	case reject:
+		return nil, fmt.Errorf("%s: a rejected example cannot have a snapshot", p)
+	}
+	tc.Want = strings.TrimSpace(want)
+	return tc, nil
+}
+
+// Snapshot returns the snapshot of the value v returned by a parser: the
+// strings and byte slices are quoted, the slices of values are written in
+// brackets and separated by spaces, and the other values are formatted
+// with the %v verb of the fmt package.
+//
+// It is the format of the snapshots of the examples, and the generated test
+// formats the values in the same way.
+func Snapshot(v any) string {
+	var buf strings.Builder
+	writeSnapshot(&buf, v)
+	return buf.String()
+}
+
+func writeSnapshot(buf *strings.Builder, v any) {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("nil")
+	case string:
+		buf.WriteString(strconv.Quote(v))
+	case []byte:
+		buf.WriteString(strconv.Quote(string(v)))
+	case []any:
+		buf.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			writeSnapshot(buf, e)
+		}
+		buf.WriteByte(']')
+	default:
+		fmt.Fprintf(buf, "%v", v)
+	}
+}
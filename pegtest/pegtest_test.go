@@ -0,0 +1,178 @@
+package pegtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/bootstrap"
+)
+
+func TestFromComments(t *testing.T) {
+	src := `{ package x }
+// A is the start rule.
+//
+// @accept "a"
+// @accept "ab" => ["a" "b"]
+// @reject "b"
+A ← 'a' 'b'?
+
+/* B has a
+ * @accept ` + "`\\b`" + `
+ */
+B ← "\\b"
+`
+	g, err := bootstrap.NewParser().Parse("", strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases, err := FromComments("test.peg", g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`test.peg:4: A accept "a" `,
+		`test.peg:5: A accept "ab" ["a" "b"]`,
+		`test.peg:6: A reject "b" `,
+		`test.peg:10: B accept "\\b" `,
+	}
+	if len(cases) != len(want) {
+		t.Fatalf("want %d cases, got %d", len(want), len(cases))
+	}
+	for i, c := range cases {
+		if got := c.String() + " " + c.Want; got != want[i] {
+			t.Errorf("%d: want %s, got %s", i, want[i], got)
+		}
+	}
+}
+
+func TestFromCommentsErrors(t *testing.T) {
+	cases := map[string]string{
+		`// @accept a`:          `invalid example input, want a Go string literal: a`,
+		`// @accept "a" b`:      `unexpected "b" after the example input`,
+		`// @reject "a" => "a"`: `a rejected example cannot have a snapshot`,
+	}
+	for doc, want := range cases {
+		g, err := bootstrap.NewParser().Parse("", strings.NewReader(doc+"\nA ← 'a'\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = FromComments("test.peg", g)
+		if err == nil || !strings.HasSuffix(err.Error(), want) {
+			t.Errorf("%s: want error %q, got %v", doc, want, err)
+		}
+	}
+}
+
+func TestReadYAML(t *testing.T) {
+	src := `---
+# examples
+A:
+  accept:
+    - a
+    - "a\tb"   # comment
+    - 'it''s'
+    -
+      input: |
+        a
+        b
+
+      want: '["a" "b"]'
+    - input: |-
+        c
+  reject:
+  - b
+"B":
+  accept: x
+`
+	cases, err := ReadYAML("test.yaml", strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`test.yaml:5: A accept "a" `,
+		`test.yaml:6: A accept "a\tb" `,
+		`test.yaml:7: A accept "it's" `,
+		`test.yaml:9: A accept "a\nb\n" ["a" "b"]`,
+		`test.yaml:14: A accept "c" `,
+		`test.yaml:17: A reject "b" `,
+		`test.yaml:19: B accept "x" `,
+	}
+	if len(cases) != len(want) {
+		t.Fatalf("want %d cases, got %d: %v", len(want), len(cases), cases)
+	}
+	for i, c := range cases {
+		if got := c.String() + " " + c.Want; got != want[i] {
+			t.Errorf("%d: want %s, got %s", i, want[i], got)
+		}
+	}
+}
+
+func TestReadYAMLErrors(t *testing.T) {
+	cases := []struct {
+		src string
+		err string
+	}{
+		{"- a\n", "test.yaml:1: expected a mapping of rule names, got a sequence"},
+		{"A: x\n", "test.yaml:1: expected a mapping with accept and reject, got a scalar"},
+		{"A:\n  match: x\n", `test.yaml:2: unexpected key "match", want accept or reject`},
+		{"A:\n  accept:\n    - want: x\n", "test.yaml:3: missing input of the example"},
+		{"A:\n  reject:\n    - input: x\n      want: y\n", "test.yaml:4: a rejected example cannot have a snapshot"},
+		{"A:\n  accept: [a, b]\n", "test.yaml:2: unsupported YAML syntax [a, b]"},
+		{"A:\n  accept: \"a\n", `test.yaml:2: invalid double-quoted scalar "a`},
+		{"A:\n  accept: 'a\n", "test.yaml:2: single-quoted scalar not terminated"},
+		{"A:\n  accept: >\n    a\n", `test.yaml:2: unsupported block scalar ">"`},
+		{"A:\n  accept: a\n    b: c\n", "test.yaml:3: unexpected indentation"},
+		{"A:\n  x\n", "test.yaml:2: expected a mapping key"},
+		{"A: 1\nA: 2\n", `test.yaml:2: duplicate key "A"`},
+		{"  A: 1\n", "test.yaml:1: unexpected indentation"},
+	}
+	for _, tc := range cases {
+		_, err := ReadYAML("test.yaml", strings.NewReader(tc.src))
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("%q: want error %q, got %v", tc.src, tc.err, err)
+		}
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	cases := []struct {
+		v    any
+		want string
+	}{
+		{nil, "nil"},
+		{"a", `"a"`},
+		{[]byte("a\n"), `"a\n"`},
+		{[]any{[]byte("a"), nil, []any{1, "b"}}, `["a" nil [1 "b"]]`},
+		{3.5, "3.5"},
+		{fmt.Errorf("x"), "x"},
+	}
+	for _, tc := range cases {
+		if got := Snapshot(tc.v); got != tc.want {
+			t.Errorf("%v: want %s, got %s", tc.v, tc.want, got)
+		}
+	}
+}
+
+func TestResultPassed(t *testing.T) {
+	p := ast.Pos{Filename: "test.peg", Line: 1}
+	cases := []struct {
+		r    Result
+		want bool
+	}{
+		{Result{Case: &Case{Pos: p}, Got: "nil"}, true},
+		{Result{Case: &Case{Pos: p}, Err: "no match"}, false},
+		{Result{Case: &Case{Pos: p, Want: `"a"`}, Got: `"a"`}, true},
+		{Result{Case: &Case{Pos: p, Want: `"a"`}, Got: `"b"`}, false},
+		{Result{Case: &Case{Pos: p, Reject: true}, Err: "no match"}, true},
+		{Result{Case: &Case{Pos: p, Reject: true}, Got: "nil"}, false},
+	}
+	for i, tc := range cases {
+		if got := tc.r.Passed(); got != tc.want {
+			t.Errorf("%d: want %t, got %t", i, tc.want, got)
+		}
+	}
+}
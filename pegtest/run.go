@@ -0,0 +1,204 @@
+package pegtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"golang.org/x/tools/imports"
+
+	"github.com/mna/pigeon/ast"
+	"github.com/mna/pigeon/builder"
+)
+
+// The files written by Run.
+const (
+	parserFile   = "pigeon_parser.go"
+	testFile     = "pigeon_examples_test.go"
+	casesFile    = "pigeon_examples.json"
+	resultsFile  = "pigeon_results.json"
+	goModContent = "module pigeontest\n\ngo 1.22\n"
+)
+
+// Run generates the parser of g with the builder options opts and runs the
+// cases against it. An accepted example must match the whole input.
+//
+// The parser and its test are written to dir, that must be in a Go module
+// where the imports of the code blocks of g are available. If dir is
+// empty, they are written to a temporary Go module, removed after the
+// run, where only the standard library is available.
+func Run(g *ast.Grammar, cases []*Case, dir string, opts ...builder.Option) ([]*Result, error) {
+	rules := make(map[string]bool, len(g.Rules))
+	for _, r := range g.Rules {
+		rules[r.Name.Val] = true
+	}
+	for _, c := range cases {
+		if !rules[c.Rule] {
+			return nil, fmt.Errorf("%s:%d: unknown rule %s", c.Pos.Filename, c.Pos.Line, c.Rule)
+		}
+	}
+
+	src, pkg, err := generate(g, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "pigeon-test-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tmp)
+		if err := os.WriteFile(filepath.Join(tmp, "go.mod"), []byte(goModContent), 0o644); err != nil {
+			return nil, err
+		}
+		dir = tmp
+	} else if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	type jsonCase struct{ Rule, Input string }
+	jcases := make([]jsonCase, len(cases))
+	for i, c := range cases {
+		jcases[i] = jsonCase{c.Rule, c.Input}
+	}
+	b, err := json.Marshal(jcases)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{
+		parserFile: src,
+		testFile:   []byte(fmt.Sprintf(testTemplate, pkg)),
+		casesFile:  b,
+	}
+	for name, b := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0o644); err != nil {
+			return nil, err
+		}
+	}
+	os.Remove(filepath.Join(dir, resultsFile))
+
+	cmd := exec.Command("go", "test", "-count=1", "-run", "^TestPigeonExamples$", ".")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("go test: %v\n%s", err, out)
+	}
+
+	b, err = os.ReadFile(filepath.Join(dir, resultsFile))
+	if err != nil {
+		return nil, err
+	}
+	var jresults []struct{ Got, Err string }
+	if err := json.Unmarshal(b, &jresults); err != nil {
+		return nil, err
+	}
+	if len(jresults) != len(cases) {
+		return nil, fmt.Errorf("got %d results for %d examples", len(jresults), len(cases))
+	}
+	results := make([]*Result, len(cases))
+	for i, c := range cases {
+		results[i] = &Result{Case: c, Got: jresults[i].Got, Err: jresults[i].Err}
+	}
+	return results, nil
+}
+
+// generate returns the formatted source of the parser of g and its
+// package name.
+func generate(g *ast.Grammar, opts []builder.Option) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if err := builder.BuildParser(&buf, g, opts...); err != nil {
+		return nil, "", err
+	}
+
+	// Defaults from golang.org/x/tools/cmd/goimports
+	options := &imports.Options{
+		TabWidth:  8,
+		TabIndent: true,
+		Comments:  true,
+		Fragment:  true,
+	}
+	src, err := imports.Process(parserFile, buf.Bytes(), options)
+	if err != nil {
+		return nil, "", fmt.Errorf("format error: %v", err)
+	}
+
+	f, err := parser.ParseFile(token.NewFileSet(), parserFile, src, parser.PackageClauseOnly)
+	if err != nil {
+		return nil, "", fmt.Errorf("the grammar has no valid package clause: %v", err)
+	}
+	return src, f.Name.Name, nil
+}
+
+// testTemplate is the test that runs the examples in the package of the
+// generated parser, and writes the snapshots of the values or the errors.
+const testTemplate = `// Code generated by pigeon test; DO NOT EDIT.
+
+package %s
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestPigeonExamples(t *testing.T) {
+	b, err := os.ReadFile("` + casesFile + `")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases []struct{ Rule, Input string }
+	if err := json.Unmarshal(b, &cases); err != nil {
+		t.Fatal(err)
+	}
+
+	results := make([]struct{ Got, Err string }, len(cases))
+	for i, c := range cases {
+		p := newParser("input", []byte(c.Input), Entrypoint(c.Rule))
+		v, err := p.parse(g)
+		switch {
+		case err != nil:
+			results[i].Err = err.Error()
+		case p.pt.offset < len(c.Input):
+			results[i].Err = fmt.Sprintf("input:%%d:%%d (%%d): unexpected input after the match", p.pt.line, p.pt.col, p.pt.offset)
+		default:
+			results[i].Got = pigeonSnapshot(v)
+		}
+	}
+
+	if b, err = json.Marshal(results); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("` + resultsFile + `", b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func pigeonSnapshot(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(v)
+	case []byte:
+		return strconv.Quote(string(v))
+	case []any:
+		s := "["
+		for i, e := range v {
+			if i > 0 {
+				s += " "
+			}
+			s += pigeonSnapshot(e)
+		}
+		return s + "]"
+	default:
+		return fmt.Sprintf("%%v", v)
+	}
+}
+`
@@ -0,0 +1,398 @@
+package pegtest
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mna/pigeon/ast"
+)
+
+// ReadYAML returns the examples of the YAML file read from r, that maps
+// the names of the rules to a mapping with the accept and reject keys.
+// Their value is a sequence of examples, each one the input as a string or
+// a mapping with the input key and, for the accepted examples, the
+// optional want key with the snapshot of the expected value.
+//
+// It supports the block style of YAML, with plain, quoted and literal (|)
+// scalars, but not the flow style, anchors or tags.
+func ReadYAML(filename string, r io.Reader) ([]*Case, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &yamlParser{filename: filename, lines: strings.Split(strings.ReplaceAll(string(b), "\r\n", "\n"), "\n")}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return p.cases(root)
+}
+
+type yamlKind int
+
+const (
+	yamlScalar yamlKind = iota
+	yamlMapping
+	yamlSequence
+)
+
+func (k yamlKind) String() string {
+	return [...]string{"scalar", "mapping", "sequence"}[k]
+}
+
+// yamlNode is a node of a YAML document.
+type yamlNode struct {
+	kind yamlKind
+	line int
+	val  string
+
+	// keys and vals are the keys and values of a mapping, vals the items of
+	// a sequence.
+	keys []string
+	vals []*yamlNode
+}
+
+// yamlParser parses the block style of YAML. The syntax errors panic with
+// a yamlError, recovered by parse.
+type yamlParser struct {
+	filename string
+	lines    []string
+	i        int
+}
+
+type yamlError struct {
+	err error
+}
+
+func (p *yamlParser) errorf(line int, format string, args ...any) {
+	panic(yamlError{fmt.Errorf("%s:%d: %s", p.filename, line, fmt.Sprintf(format, args...))})
+}
+
+func (p *yamlParser) parse() (n *yamlNode, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			ye, ok := e.(yamlError)
+			if !ok {
+				panic(e)
+			}
+			n, err = nil, ye.err
+		}
+	}()
+
+	if !p.skipBlank() {
+		return &yamlNode{kind: yamlMapping, line: 1}, nil
+	}
+	if indent(p.lines[p.i]) != 0 {
+		p.errorf(p.i+1, "unexpected indentation")
+	}
+	n = p.block(0)
+	if p.skipBlank() {
+		p.errorf(p.i+1, "unexpected indentation")
+	}
+	return n, nil
+}
+
+// skipBlank skips the blank and comment lines, and returns false at the
+// end of the document.
+func (p *yamlParser) skipBlank() bool {
+	for ; p.i < len(p.lines); p.i++ {
+		l := strings.TrimSpace(p.lines[p.i])
+		if l == "---" && p.i == 0 {
+			continue
+		}
+		if l != "" && !strings.HasPrefix(l, "#") {
+			return true
+		}
+	}
+	return false
+}
+
+// block parses the mapping or the sequence that starts at the current
+// line, indented by n spaces.
+func (p *yamlParser) block(n int) *yamlNode {
+	l := p.lines[p.i][n:]
+	if l == "-" || strings.HasPrefix(l, "- ") {
+		return p.sequence(n)
+	}
+	return p.mapping(n)
+}
+
+func (p *yamlParser) sequence(n int) *yamlNode {
+	seq := &yamlNode{kind: yamlSequence, line: p.i + 1}
+	for p.skipBlank() && indent(p.lines[p.i]) == n && isItem(p.lines[p.i][n:]) {
+		line := p.i + 1
+		rest := strings.TrimLeft(p.lines[p.i][n+1:], " ")
+		if rest == "" || strings.HasPrefix(rest, "#") {
+			// the item is on the following lines
+			p.i++
+			if !p.skipBlank() || indent(p.lines[p.i]) <= n {
+				seq.vals = append(seq.vals, &yamlNode{kind: yamlScalar, line: line})
+				continue
+			}
+			seq.vals = append(seq.vals, p.block(indent(p.lines[p.i])))
+			continue
+		}
+
+		// the item starts on the same line, parse it as if the dash was a
+		// space
+		col := len(p.lines[p.i]) - len(rest)
+		if isMappingEntry(rest) {
+			p.lines[p.i] = strings.Repeat(" ", col) + rest
+			seq.vals = append(seq.vals, p.mapping(col))
+			continue
+		}
+		seq.vals = append(seq.vals, p.value(n, rest))
+	}
+	return seq
+}
+
+func (p *yamlParser) mapping(n int) *yamlNode {
+	m := &yamlNode{kind: yamlMapping, line: p.i + 1}
+	for p.skipBlank() && indent(p.lines[p.i]) == n && !isItem(p.lines[p.i][n:]) {
+		l := p.lines[p.i][n:]
+		key, rest, ok := cutKey(l)
+		if !ok {
+			p.errorf(p.i+1, "expected a mapping key")
+		}
+		if key[0] == '"' || key[0] == '\'' {
+			key = p.scalar(p.i+1, key)
+		}
+		for _, k := range m.keys {
+			if k == key {
+				p.errorf(p.i+1, "duplicate key %q", key)
+			}
+		}
+		m.keys = append(m.keys, key)
+
+		rest = strings.TrimSpace(rest)
+		if rest != "" && !strings.HasPrefix(rest, "#") {
+			m.vals = append(m.vals, p.value(n, rest))
+			continue
+		}
+
+		// the value is on the following lines, a sequence may have the same
+		// indentation as the key
+		line := p.i + 1
+		p.i++
+		if p.skipBlank() {
+			if ind := indent(p.lines[p.i]); ind > n || (ind == n && isItem(p.lines[p.i][n:])) {
+				m.vals = append(m.vals, p.block(ind))
+				continue
+			}
+		}
+		m.vals = append(m.vals, &yamlNode{kind: yamlScalar, line: line})
+	}
+	if p.skipBlank() && indent(p.lines[p.i]) > n {
+		p.errorf(p.i+1, "unexpected indentation")
+	}
+	return m
+}
+
+// value parses the scalar rest at the end of the current line, indented
+// by n spaces, and the following lines of a literal scalar.
+func (p *yamlParser) value(n int, rest string) *yamlNode {
+	node := &yamlNode{kind: yamlScalar, line: p.i + 1}
+	p.i++
+	switch {
+	case rest == "|" || rest == "|-" || rest == "|+":
+		node.val = p.literal(n, rest[1:])
+	case strings.HasPrefix(rest, "|") || strings.HasPrefix(rest, ">"):
+		p.errorf(node.line, "unsupported block scalar %q", rest)
+	default:
+		node.val = p.scalar(node.line, rest)
+	}
+	return node
+}
+
+// literal reads the lines of a literal scalar, more indented than n, with
+// the chomping indicator chomp.
+func (p *yamlParser) literal(n int, chomp string) string {
+	var lines []string
+	ind := -1
+	for ; p.i < len(p.lines); p.i++ {
+		l := p.lines[p.i]
+		if strings.TrimSpace(l) == "" {
+			lines = append(lines, "")
+			continue
+		}
+		if ind < 0 {
+			ind = indent(l)
+		}
+		if indent(l) < ind || ind <= n {
+			break
+		}
+		lines = append(lines, l[ind:])
+	}
+
+	// the trailing blank lines are not part of the block
+	text := strings.Join(lines, "\n")
+	trimmed := strings.TrimRight(text, "\n")
+	if blank := len(text) - len(trimmed); blank > 0 {
+		p.i -= blank
+	}
+	switch chomp {
+	case "-":
+		return trimmed
+	case "+":
+		return text + "\n"
+	}
+	if trimmed == "" {
+		return ""
+	}
+	return trimmed + "\n"
+}
+
+// scalar returns the value of the quoted or plain scalar s, on the given
+// line.
+func (p *yamlParser) scalar(line int, s string) string {
+	switch s[0] {
+	case '"':
+		lit, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			p.errorf(line, "invalid double-quoted scalar %s", s)
+		}
+		p.checkEnd(line, s[len(lit):])
+		v, _ := strconv.Unquote(lit)
+		return v
+	case '\'':
+		var buf strings.Builder
+		for i := 1; i < len(s); i++ {
+			if s[i] != '\'' {
+				buf.WriteByte(s[i])
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '\'' {
+				buf.WriteByte('\'')
+				i++
+				continue
+			}
+			p.checkEnd(line, s[i+1:])
+			return buf.String()
+		}
+		p.errorf(line, "single-quoted scalar not terminated")
+	case '[', '{', '&', '*', '!':
+		p.errorf(line, "unsupported YAML syntax %s", s)
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+func (p *yamlParser) checkEnd(line int, rest string) {
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		p.errorf(line, "unexpected %q after scalar", rest)
+	}
+}
+
+// cases returns the examples of the document root.
+func (p *yamlParser) cases(root *yamlNode) ([]*Case, error) {
+	errorf := func(n *yamlNode, format string, args ...any) error {
+		return fmt.Errorf("%s:%d: %s", p.filename, n.line, fmt.Sprintf(format, args...))
+	}
+	if root.kind != yamlMapping {
+		return nil, errorf(root, "expected a mapping of rule names, got a %s", root.kind)
+	}
+
+	var cases []*Case
+	for i, rule := range root.keys {
+		rn := root.vals[i]
+		if rn.kind != yamlMapping {
+			return nil, errorf(rn, "expected a mapping with accept and reject, got a %s", rn.kind)
+		}
+		for j, key := range rn.keys {
+			if key != "accept" && key != "reject" {
+				return nil, errorf(rn.vals[j], "unexpected key %q, want accept or reject", key)
+			}
+			items := rn.vals[j]
+			switch items.kind {
+			case yamlScalar:
+				items = &yamlNode{kind: yamlSequence, line: items.line, vals: []*yamlNode{items}}
+			case yamlMapping:
+				return nil, errorf(items, "expected a sequence of examples, got a %s", items.kind)
+			}
+
+			for _, item := range items.vals {
+				tc := &Case{
+					Pos:    ast.Pos{Filename: p.filename, Line: item.line, Col: 1},
+					Rule:   rule,
+					Reject: key == "reject",
+				}
+				switch item.kind {
+				case yamlScalar:
+					tc.Input = item.val
+				case yamlMapping:
+					hasInput := false
+					for k, ik := range item.keys {
+						iv := item.vals[k]
+						if iv.kind != yamlScalar {
+							return nil, errorf(iv, "expected a scalar for %s, got a %s", ik, iv.kind)
+						}
+						switch ik {
+						case "input":
+							tc.Input, hasInput = iv.val, true
+						case "want":
+							if tc.Reject {
+								return nil, errorf(iv, "a rejected example cannot have a snapshot")
+							}
+							tc.Want = strings.TrimSpace(iv.val)
+						default:
+							return nil, errorf(iv, "unexpected key %q, want input or want", ik)
+						}
+					}
+					if !hasInput {
+						return nil, errorf(item, "missing input of the example")
+					}
+				default:
+					return nil, errorf(item, "expected an example, got a %s", item.kind)
+				}
+				cases = append(cases, tc)
+			}
+		}
+	}
+	return cases, nil
+}
+
+// indent returns the number of leading spaces of l.
+func indent(l string) int {
+	return len(l) - len(strings.TrimLeft(l, " "))
+}
+
+// isItem returns true if l, without indentation, is a sequence item.
+func isItem(l string) bool {
+	return l == "-" || strings.HasPrefix(l, "- ")
+}
+
+// isMappingEntry returns true if l starts with a mapping key.
+func isMappingEntry(l string) bool {
+	_, _, ok := cutKey(l)
+	return ok
+}
+
+// cutKey returns the key of the mapping entry l and the rest of the line
+// after the colon.
+func cutKey(l string) (key, rest string, ok bool) {
+	if l == "" {
+		return "", "", false
+	}
+	if l[0] == '"' || l[0] == '\'' {
+		end := strings.IndexByte(l[1:], l[0])
+		if end < 0 {
+			return "", "", false
+		}
+		key, rest = l[:end+2], l[end+2:]
+		if rest, ok = strings.CutPrefix(rest, ":"); !ok || (rest != "" && rest[0] != ' ') {
+			return "", "", false
+		}
+		return key, rest, true
+	}
+	if i := strings.Index(l, ": "); i > 0 {
+		return l[:i], l[i+2:], true
+	}
+	if strings.HasSuffix(l, ":") && len(l) > 1 {
+		return l[:len(l)-1], "", true
+	}
+	return "", "", false
+}
@@ -0,0 +1,97 @@
+package profile
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mna/pigeon/ast"
+)
+
+// snippetLen is the maximum number of characters of the source of an
+// expression quoted in the annotations.
+const snippetLen = 30
+
+// Annotate writes src, the source of the grammar g, to w with the
+// statistics of p as comments. The statistics of each rule and of its
+// expressions are inserted before the line where the rule starts, the
+// expressions being identified by their position and the start of their
+// source. The rules and expressions that were not evaluated are not
+// annotated.
+func Annotate(w io.Writer, src []byte, g *ast.Grammar, p *Profile) error {
+	notes := make(map[int][]string)
+	for _, r := range g.Rules {
+		var lines []string
+		if e := p.Rules[r.Name.Val]; e != nil {
+			lines = append(lines, "// "+e.String())
+		}
+
+		var exprs []ast.Expression
+		ast.Inspect(r.Expr, func(expr ast.Expression) bool {
+			if p.Exprs[ExprKey(r.Name.Val, expr)] != nil {
+				exprs = append(exprs, expr)
+			}
+			return true
+		})
+		// the expressions at the same position are from the outermost one
+		slices.SortStableFunc(exprs, func(a, b ast.Expression) int {
+			return cmp.Compare(a.Pos().Off, b.Pos().Off)
+		})
+		for _, expr := range exprs {
+			pos := expr.Pos()
+			lines = append(lines, fmt.Sprintf("//   %d:%d %s %s: %s", pos.Line, pos.Col,
+				exprKind(expr), snippet(src, pos.Off), p.Exprs[ExprKey(r.Name.Val, expr)]))
+		}
+
+		if len(lines) > 0 {
+			line := r.Pos().Line
+			notes[line] = append(notes[line], lines...)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	for i, line := range bytes.SplitAfter(src, []byte("\n")) {
+		for _, note := range notes[i+1] {
+			bw.WriteString(note + "\n")
+		}
+		bw.Write(line)
+	}
+	return bw.Flush()
+}
+
+// ExprKey returns the key in the Exprs of a profile of the expression
+// expr of the rule named rule, e.g. "Sum 3:7 choiceExpr".
+func ExprKey(rule string, expr ast.Expression) string {
+	pos := expr.Pos()
+	return fmt.Sprintf("%s %d:%d %s", rule, pos.Line, pos.Col, exprKind(expr))
+}
+
+// exprKind returns the name of the type of expr in the generated
+// parsers, e.g. "choiceExpr" for an *ast.ChoiceExpr.
+func exprKind(expr ast.Expression) string {
+	name := reflect.TypeOf(expr).Elem().Name()
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// snippet returns the start of the source at offset off, up to the end
+// of the line.
+func snippet(src []byte, off int) string {
+	if off < 0 || off > len(src) {
+		return ""
+	}
+	s := string(src[off:])
+	if i := strings.IndexAny(s, "\r\n"); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+	if utf8.RuneCountInString(s) > snippetLen {
+		s = string([]rune(s)[:snippetLen]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,156 @@
+package profile
+
+import (
+	"compress/gzip"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/mna/pigeon/ast"
+)
+
+// WritePprof writes the stacks of rules of p to w as a gzipped profile
+// in the format of pprof, with the number of evaluations and the time of
+// each stack as sample values. The rules are the functions of the
+// profile, located at their line in the grammar g, read from filename.
+// If g is nil, the rules have no line.
+func (p *Profile) WritePprof(w io.Writer, filename string, g *ast.Grammar) error {
+	lines := make(map[string]int)
+	if g != nil {
+		for _, r := range g.Rules {
+			lines[r.Name.Val] = r.Pos().Line
+		}
+	}
+
+	var (
+		b       protobuf
+		strs    = map[string]int{"": 0}
+		strList = []string{""}
+		funcs   = make(map[string]uint64)
+	)
+	str := func(s string) int64 {
+		i, ok := strs[s]
+		if !ok {
+			i = len(strList)
+			strs[s] = i
+			strList = append(strList, s)
+		}
+		return int64(i)
+	}
+	valueType := func(field int, typ, unit string) {
+		b.message(field, func(b *protobuf) {
+			b.int64(1, str(typ))
+			b.int64(2, str(unit))
+		})
+	}
+
+	valueType(1, "calls", "count")
+	valueType(1, "time", "nanoseconds")
+
+	stacks := make([]string, 0, len(p.Stacks))
+	for stack := range p.Stacks {
+		stacks = append(stacks, stack)
+	}
+	slices.Sort(stacks)
+	for _, stack := range stacks {
+		rules := strings.Split(stack, ";")
+		locs := make([]uint64, len(rules))
+		for i, rule := range rules {
+			id, ok := funcs[rule]
+			if !ok {
+				id = uint64(len(funcs) + 1)
+				funcs[rule] = id
+			}
+			// the locations are from the innermost rule
+			locs[len(rules)-1-i] = id
+		}
+		s := p.Stacks[stack]
+		b.message(2, func(b *protobuf) {
+			b.packed(1, locs)
+			b.packed(2, []uint64{uint64(s.Calls), uint64(s.Time)})
+		})
+	}
+
+	rules := make([]string, len(funcs))
+	for rule, id := range funcs {
+		rules[id-1] = rule
+	}
+	for i, rule := range rules {
+		id := uint64(i + 1)
+		b.message(4, func(b *protobuf) {
+			b.uint64(1, id)
+			b.message(4, func(b *protobuf) {
+				b.uint64(1, id)
+				b.int64(2, int64(lines[rule]))
+			})
+		})
+		b.message(5, func(b *protobuf) {
+			b.uint64(1, id)
+			b.int64(2, str(rule))
+			b.int64(3, str(rule))
+			b.int64(4, str(filename))
+			b.int64(5, int64(lines[rule]))
+		})
+	}
+	valueType(11, "time", "nanoseconds")
+	b.int64(14, str("time"))
+	for _, s := range strList {
+		b.string(6, s)
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b.buf); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// protobuf encodes the fields of a protocol buffers message.
+type protobuf struct {
+	buf []byte
+}
+
+func (b *protobuf) varint(x uint64) {
+	for x >= 0x80 {
+		b.buf = append(b.buf, byte(x)|0x80)
+		x >>= 7
+	}
+	b.buf = append(b.buf, byte(x))
+}
+
+func (b *protobuf) key(field, wireType int) {
+	b.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (b *protobuf) uint64(field int, x uint64) {
+	b.key(field, 0)
+	b.varint(x)
+}
+
+func (b *protobuf) int64(field int, x int64) {
+	b.uint64(field, uint64(x))
+}
+
+func (b *protobuf) bytes(field int, p []byte) {
+	b.key(field, 2)
+	b.varint(uint64(len(p)))
+	b.buf = append(b.buf, p...)
+}
+
+func (b *protobuf) string(field int, s string) {
+	b.bytes(field, []byte(s))
+}
+
+func (b *protobuf) packed(field int, xs []uint64) {
+	var m protobuf
+	for _, x := range xs {
+		m.varint(x)
+	}
+	b.bytes(field, m.buf)
+}
+
+func (b *protobuf) message(field int, fn func(*protobuf)) {
+	var m protobuf
+	fn(&m)
+	b.bytes(field, m.buf)
+}
@@ -0,0 +1,138 @@
+// Package profile reports the profiles of the parses of a grammar: the
+// evaluations, matches and failures of its rules and expressions, the
+// bytes that they consumed and backtracked, their hits in the
+// memoization table and the time spent.
+//
+// A Profile is gathered by the Profile option of the interp package, or
+// by the Profiling option of a generated parser, which stores it in a
+// type with the same JSON encoding. It is reported as a text table, as
+// a pprof profile of the stacks of rules or as comments added to the
+// source of the grammar.
+package profile
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+// Profile stores the statistics of the rules and the expressions of a
+// grammar.
+type Profile struct {
+	// Rules maps the name of the rules to their statistics.
+	Rules map[string]*Entry
+	// Exprs maps the expressions to their statistics. The key is
+	// composed of the name of the rule, the line and the column of the
+	// expression and its type, see ExprKey.
+	Exprs map[string]*Entry
+	// Stacks maps the stacks of rules, the names of the rules from the
+	// outermost one separated by ";", to the evaluations of the innermost
+	// rule and the time spent in it, excluding the rules that it invoked.
+	Stacks map[string]*Stack
+}
+
+// Entry stores the statistics of a rule or an expression.
+type Entry struct {
+	// Calls is the number of evaluations, Matches and Failures their
+	// outcomes, and Revisits the evaluations at an offset where the same
+	// rule or expression was already evaluated in the parse. The results
+	// found in the memoization table are counted in MemoHits instead.
+	Calls    int64
+	Matches  int64
+	Failures int64
+	Revisits int64
+	MemoHits int64
+	// Bytes is the number of bytes consumed by the matches, Backtracked
+	// the number of bytes given back when backtracking.
+	Bytes       int64
+	Backtracked int64
+	// Time is the cumulative time of the evaluations, the recursive ones
+	// being counted once.
+	Time time.Duration
+}
+
+// Stack stores the statistics of a stack of rules.
+type Stack struct {
+	Calls int64
+	Time  time.Duration
+}
+
+// Read decodes the JSON encoding of a profile from r, as written by a
+// generated parser.
+func Read(r io.Reader) (*Profile, error) {
+	var p Profile
+	if err := json.NewDecoder(r).Decode(&p); err != nil {
+		return nil, fmt.Errorf("invalid profile: %w", err)
+	}
+	return &p, nil
+}
+
+// String returns the statistics of e on a single line.
+func (e *Entry) String() string {
+	return fmt.Sprintf("%d calls, %d matches, %d failures, %d revisits, %d memo hits, %d bytes, %d backtracked, %v",
+		e.Calls, e.Matches, e.Failures, e.Revisits, e.MemoHits, e.Bytes, e.Backtracked, e.Time)
+}
+
+// WriteText writes the statistics of the rules, then those of the
+// expressions, as tables sorted by decreasing time.
+func (p *Profile) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	writeTable(tw, "rule", p.Rules)
+	fmt.Fprintln(tw)
+	writeTable(tw, "expression", p.Exprs)
+	return tw.Flush()
+}
+
+func writeTable(w io.Writer, title string, entries map[string]*Entry) {
+	fmt.Fprintf(w, "time\tcalls\tmatches\tfailures\trevisits\tmemo hits\tbytes\tbacktracked\t%s\n", title)
+	for _, key := range sortedKeys(entries) {
+		e := entries[key]
+		fmt.Fprintf(w, "%v\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%s\n",
+			e.Time, e.Calls, e.Matches, e.Failures, e.Revisits, e.MemoHits, e.Bytes, e.Backtracked, key)
+	}
+}
+
+// DefaultMemoRatio is the minimum ratio of the evaluations of a rule that
+// are revisits for MemoRules to memoize it.
+const DefaultMemoRatio = 0.25
+
+// MemoRules returns the names of the rules of p that are worth memoizing,
+// sorted by name: the rules that are evaluated again at the same offsets,
+// with a ratio of revisits to evaluations of at least ratio. The results
+// found in the memoization table count as revisits, for the profiles
+// gathered with memoization.
+func (p *Profile) MemoRules(ratio float64) []string {
+	var names []string
+	for name, e := range p.Rules {
+		revisits, calls := e.Revisits+e.MemoHits, e.Calls+e.MemoHits
+		if revisits > 0 && float64(revisits) >= ratio*float64(calls) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// sortedKeys returns the keys of entries by decreasing time, then by
+// decreasing calls and by key.
+func sortedKeys(entries map[string]*Entry) []string {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		ea, eb := entries[a], entries[b]
+		if c := cmp.Compare(eb.Time, ea.Time); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(eb.Calls, ea.Calls); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+	return keys
+}
@@ -0,0 +1,149 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mna/pigeon/parse"
+)
+
+const grammar = `Sum ← Num '+' Sum / Num
+
+Num ← [0-9]+
+`
+
+func testProfile() *Profile {
+	return &Profile{
+		Rules: map[string]*Entry{
+			"Sum": {Calls: 2, Matches: 2, Bytes: 4, Backtracked: 1, Time: 3 * time.Microsecond},
+			"Num": {Calls: 3, Matches: 3, Revisits: 1, Bytes: 3, Time: 2 * time.Microsecond},
+		},
+		Exprs: map[string]*Entry{
+			"Sum 1:7 choiceExpr":    {Calls: 2, Matches: 2, Bytes: 4, Time: 3 * time.Microsecond},
+			"Sum 1:11 litMatcher":   {Calls: 2, Matches: 1, Failures: 1, Bytes: 1, Time: time.Microsecond},
+			"Num 3:7 oneOrMoreExpr": {Calls: 3, Matches: 3, Revisits: 1, Bytes: 3, Time: 2 * time.Microsecond},
+		},
+		Stacks: map[string]*Stack{
+			"Sum":         {Calls: 1, Time: time.Microsecond},
+			"Sum;Num":     {Calls: 1, Time: time.Microsecond},
+			"Sum;Sum;Num": {Calls: 2, Time: time.Microsecond},
+		},
+	}
+}
+
+func TestRead(t *testing.T) {
+	p, err := Read(strings.NewReader(`{"Rules": {"Sum": {"Calls": 2, "Time": 1000}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := p.Rules["Sum"]; e == nil || e.Calls != 2 || e.Time != time.Microsecond {
+		t.Errorf("want 2 calls in 1µs, got %+v", e)
+	}
+	if _, err := Read(strings.NewReader(`[]`)); err == nil {
+		t.Errorf("want an error")
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testProfile().WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `time  calls  matches  failures  revisits  memo hits  bytes  backtracked  rule
+3µs   2      2        0         0         0          4      1            Sum
+2µs   3      3        0         1         0          3      0            Num
+
+time  calls  matches  failures  revisits  memo hits  bytes  backtracked  expression
+3µs   2      2        0         0         0          4      0            Sum 1:7 choiceExpr
+2µs   3      3        0         1         0          3      0            Num 3:7 oneOrMoreExpr
+1µs   2      1        1         0         0          1      0            Sum 1:11 litMatcher
+`
+	if got := buf.String(); got != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	g, err := parse.ParseGrammar("sum.peg", strings.NewReader(grammar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Annotate(&buf, []byte(grammar), g, testProfile()); err != nil {
+		t.Fatal(err)
+	}
+	want := `// 2 calls, 2 matches, 0 failures, 0 revisits, 0 memo hits, 4 bytes, 1 backtracked, 3µs
+//   1:7 choiceExpr Num '+' Sum / Num: 2 calls, 2 matches, 0 failures, 0 revisits, 0 memo hits, 4 bytes, 0 backtracked, 3µs
+//   1:11 litMatcher '+' Sum / Num: 2 calls, 1 matches, 1 failures, 0 revisits, 0 memo hits, 1 bytes, 0 backtracked, 1µs
+Sum ← Num '+' Sum / Num
+
+// 3 calls, 3 matches, 0 failures, 1 revisits, 0 memo hits, 3 bytes, 0 backtracked, 2µs
+//   3:7 oneOrMoreExpr [0-9]+: 3 calls, 3 matches, 0 failures, 1 revisits, 0 memo hits, 3 bytes, 0 backtracked, 2µs
+Num ← [0-9]+
+`
+	if got := buf.String(); got != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestMemoRules(t *testing.T) {
+	p := testProfile()
+	p.Rules["Sum"].MemoHits = 1
+	p.Rules["Expr"] = &Entry{Calls: 10, Revisits: 2}
+	cases := map[float64]string{
+		0.1: "Expr,Num,Sum",
+		0.3: "Num,Sum",
+		0.5: "",
+	}
+	for ratio, want := range cases {
+		if got := strings.Join(p.MemoRules(ratio), ","); got != want {
+			t.Errorf("%v: want %s, got %s", ratio, want, got)
+		}
+	}
+}
+
+func TestWritePprof(t *testing.T) {
+	g, err := parse.ParseGrammar("sum.peg", strings.NewReader(grammar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := testProfile().WritePprof(&buf, "sum.peg", g); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// decode the fields of the profile, that are short enough to have
+	// a single byte of length, to get the string table
+	var strs []string
+	for len(b) > 0 {
+		field, typ := b[0]>>3, b[0]&7
+		if typ == 0 && b[1] < 0x80 {
+			b = b[2:]
+			continue
+		}
+		if typ != 2 || b[1] >= 0x80 {
+			t.Fatalf("unexpected field %d of type %d", field, typ)
+		}
+		n := int(b[1])
+		if field == 6 {
+			strs = append(strs, string(b[2:2+n]))
+		}
+		b = b[2+n:]
+	}
+	want := []string{"", "calls", "count", "time", "nanoseconds", "Sum", "sum.peg", "Num"}
+	if strings.Join(strs, ",") != strings.Join(want, ",") {
+		t.Errorf("want the strings %q, got %q", want, strs)
+	}
+}
@@ -0,0 +1,34 @@
+package backref
+
+import "testing"
+
+var cases = map[string]string{
+	`r"abc"`:          "",
+	`r#"a"b"#`:        "",
+	`r##"a"#b"##`:     "",
+	`r#"a"##`:         `1:7 (6): no match found, expected: EOF`,
+	`r##"a"#`:         `1:8 (7): no match found, expected: "\"" or .`,
+	"<<EOT\nabc\nEOT": "",
+	"<<EOT\nEO\nEOT":  "",
+	"<<EOT\nabc\nEOF": `3:4 (13): no match found, expected: "\n" or .`,
+	"<a></a>":         "",
+	"<a><b>x</b></a>": "",
+	"<a><a></a>y</a>": "",
+	"<a><b></a></b>":  `1:9 (8): no match found, expected: "b"`,
+	"<a></b>":         `1:6 (5): no match found, expected: "a"`,
+}
+
+func TestBackRef(t *testing.T) {
+	for _, memoize := range []bool{false, true} {
+		for tc, exp := range cases {
+			_, err := Parse("", []byte(tc), Memoize(memoize))
+			var got string
+			if err != nil {
+				got = err.Error()
+			}
+			if got != exp {
+				t.Errorf("%q: want %v, got %v", tc, exp, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package bytemode
+
+import (
+	"reflect"
+	"testing"
+)
+
+var cases = map[string]struct {
+	want []int
+	err  string
+}{
+	"\x89BIN":                         {want: []int{}},
+	"\x89bIn\x01ab\x00":               {want: []int{2}},
+	"\x89BIN\x02\xff\xfe\x00\x03\x00": {want: []int{2, 0}},
+	"\x89BIN\x01\x1f\x00\x1f\x01\x00": {want: []int{4}},
+	"\x89BIN\x01\xc3\xa9\x00":         {want: []int{2}},
+	"\x89BIN\x01\xc3\x00\x02\xa9\x00": {want: []int{1, 1}},
+	"\x89BIM":                         {err: `1:1 (0): no match found, expected: "\x89BIN"i`},
+	"\x89BIN\x01a":                    {err: `1:7 (6): no match found, expected: "\x00", "\x1f" or [\x20-\xff]`},
+	"\x89BIN\x01a\x00\x00":            {err: `1:8 (7): no match found, expected: [\x01-\x1f] or EOF`},
+}
+
+func TestByteMode(t *testing.T) {
+	for tc, exp := range cases {
+		got, err := Parse("", []byte(tc))
+		var gotErr string
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if gotErr != exp.err {
+			t.Errorf("%q: want error %q, got %q", tc, exp.err, gotErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, exp.want) {
+			t.Errorf("%q: want %v, got %v", tc, exp.want, got)
+		}
+	}
+}
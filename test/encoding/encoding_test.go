@@ -0,0 +1,53 @@
+package encoding
+
+import (
+	"reflect"
+	"testing"
+)
+
+var cases = []struct {
+	in   string
+	dec  Decoder
+	want []word
+	err  string
+}{
+	{in: "héllo wörld", want: []word{{"héllo", 0}, {"wörld", 7}}},
+	{in: "héllo wörld", dec: DecodeUTF8, want: []word{{"héllo", 0}, {"wörld", 7}}},
+	{in: "h\xe9llo w\xf6rld", dec: DecodeLatin1, want: []word{{"héllo", 0}, {"wörld", 6}}},
+	{
+		in:   "h\x00\xe9\x00l\x00l\x00o\x00 \x00w\x00\xf6\x00r\x00l\x00d\x00",
+		dec:  DecodeUTF16LE,
+		want: []word{{"héllo", 0}, {"wörld", 12}},
+	},
+	{
+		in:   "\x00h\x00\xe9\x00l\x00l\x00o\x00 \x00w\x00\xf6\x00r\x00l\x00d",
+		dec:  DecodeUTF16BE,
+		want: []word{{"héllo", 0}, {"wörld", 12}},
+	},
+	{
+		// U+1D49C MATHEMATICAL SCRIPT CAPITAL A, as a surrogate pair
+		in:   "\x35\xd8\x9c\xdc \x00a\x00",
+		dec:  DecodeUTF16LE,
+		want: []word{{"\U0001D49C", 0}, {"a", 6}},
+	},
+	{in: "h\xe9llo", err: "1:2 (1): rule Word: invalid encoding"},
+	{in: "a\x00\x00\xdc", dec: DecodeUTF16LE, err: "1:2 (2): rule Word: invalid encoding"},
+	{in: "a\x00b", dec: DecodeUTF16LE, err: "1:2 (2): rule Word: invalid encoding"},
+}
+
+func TestEncoding(t *testing.T) {
+	for _, tc := range cases {
+		got, err := Parse("", []byte(tc.in), InputDecoder(tc.dec))
+		var gotErr string
+		if err != nil {
+			gotErr = err.Error()
+		}
+		if gotErr != tc.err {
+			t.Errorf("%q: want error %q, got %q", tc.in, tc.err, gotErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q: want %v, got %v", tc.in, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Code generated by pigeon; DO NOT EDIT.
+
+package fuzz
+
+import (
+	"errors"
+	"testing"
+)
+
+// FuzzParse checks that the parser does not panic, that it completes
+// the parse within 1000000 expressions and that the positions of its errors
+// are in the input. A parse that reaches the limit of expressions fails
+// the test, as it likely does not terminate.
+func FuzzParse(f *testing.F) {
+	f.Add([]byte("[1, -2, [3]]"))
+	f.Add([]byte("[1,"))
+	f.Add([]byte("[]  "))
+	f.Add([]byte("\t[\n-59\t]"))
+	f.Add([]byte("\t\t[]\t"))
+	f.Add([]byte(" \n\t[-147\t \r,  -737]\t\r"))
+	f.Add([]byte("\r\r\r[\n\t\t43\t\t,\t\n\n6\t \t]\n"))
+	f.Add([]byte("\t[]\t\n\n"))
+	f.Add([]byte("\t[\r ]\t"))
+	f.Add([]byte("[] "))
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		defer func() {
+			if e := recover(); e != nil {
+				if e != errMaxExprCnt {
+					panic(e)
+				}
+				t.Errorf("parse not completed within %d expressions", 1000000)
+			}
+		}()
+		_, err := Parse("fuzz", b, Recover(false), MaxExpressions(1000000))
+		var errs errList
+		if !errors.As(err, &errs) {
+			return
+		}
+		for _, err := range errs {
+			var pe *parserError
+			if !errors.As(err, &pe) {
+				continue
+			}
+			if pe.pos.line < 1 || pe.pos.col < 0 || pe.pos.offset < 0 || pe.pos.offset > len(b) {
+				t.Errorf("error position %s out of the input of %d bytes: %v", pe.pos, len(b), err)
+			}
+		}
+	})
+}
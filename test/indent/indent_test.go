@@ -0,0 +1,47 @@
+package indent
+
+import "testing"
+
+var cases = map[string]struct {
+	tabWidth int
+	want     string
+	err      string
+}{
+	"a":                        {want: "a"},
+	"a\nb\n":                   {want: "a;b"},
+	"a\n  b\n  c\n    d\ne":    {want: "a(b;c(d));e"},
+	"a\n  b\n    c\nd":         {want: "a(b(c));d"},
+	"a\n  b\n    c":            {want: "a(b(c))"},
+	"a\n\n  b\n   \n  c\n\n":   {want: "a(b;c)"},
+	"\n\na\n  b":               {want: "a(b)"},
+	"a\n\tb\n\tc":              {want: "a(b;c)"},
+	"a\n\tb\n\t\tc\nd":         {want: "a(b(c));d"},
+	"a\n\tb\n        c":        {err: "3:1 (5): rule Block: inconsistent use of tabs and spaces in indentation\n3:1 (5): rule Lines: inconsistent use of tabs and spaces in indentation"},
+	"a\n\tb\n    c":            {tabWidth: 4, err: "3:1 (5): rule Block: inconsistent use of tabs and spaces in indentation\n3:1 (5): rule Lines: inconsistent use of tabs and spaces in indentation"},
+	"a\n    b\n  c":            {err: "3:1 (8): no match found, expected: %DEDENT, %INDENT or %SAMEDENT"},
+	"  a":                      {err: "1:1 (0): no match found, expected: %SAMEDENT"},
+	"a\nb\n  c\n    d\n  e\nf": {want: "a;b(c(d);e);f"},
+}
+
+func TestIndent(t *testing.T) {
+	for _, memoize := range []bool{false, true} {
+		for tc, exp := range cases {
+			opts := []Option{Memoize(memoize)}
+			if exp.tabWidth > 0 {
+				opts = append(opts, TabWidth(exp.tabWidth))
+			}
+			got, err := Parse("", []byte(tc), opts...)
+			var gotErr string
+			if err != nil {
+				gotErr = err.Error()
+			}
+			if gotErr != exp.err {
+				t.Errorf("%q: want error %q, got %q", tc, exp.err, gotErr)
+				continue
+			}
+			if err == nil && got != exp.want {
+				t.Errorf("%q: want %q, got %q", tc, exp.want, got)
+			}
+		}
+	}
+}
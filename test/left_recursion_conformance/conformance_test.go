@@ -0,0 +1,170 @@
+package conformance_test
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mna/pigeon/interp"
+	"github.com/mna/pigeon/parse"
+
+	optimized "github.com/mna/pigeon/test/left_recursion_conformance/optimized"
+	optimizedgrammar "github.com/mna/pigeon/test/left_recursion_conformance/optimized_grammar"
+	standard "github.com/mna/pigeon/test/left_recursion_conformance/standard"
+)
+
+// parser parses the input from the entrypoint, the values of the
+// interpreter are not compared as it does not run the actions.
+type parser struct {
+	name   string
+	values bool
+	parse  func(entrypoint string, b []byte) (any, error)
+}
+
+func parsers(t *testing.T) []parser {
+	t.Helper()
+	src, err := os.ReadFile("conformance.peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := parse.ParseGrammar("conformance.peg", bytes.NewReader(src), parse.Validate(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ip, err := interp.New(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []parser{
+		{"standard", true, func(e string, b []byte) (any, error) {
+			return standard.Parse("", b, standard.Entrypoint(e))
+		}},
+		{"memoized", true, func(e string, b []byte) (any, error) {
+			return standard.Parse("", b, standard.Entrypoint(e), standard.Memoize(true))
+		}},
+		{"memo window", true, func(e string, b []byte) (any, error) {
+			return standard.Parse("", b, standard.Entrypoint(e), standard.Memoize(true), standard.MemoWindow(1))
+		}},
+		{"optimized", true, func(e string, b []byte) (any, error) {
+			return optimized.Parse("", b, optimized.Entrypoint(e))
+		}},
+		{"optimized grammar", true, func(e string, b []byte) (any, error) {
+			return optimizedgrammar.Parse("", b, optimizedgrammar.Entrypoint(e))
+		}},
+		{"interp", false, func(e string, b []byte) (any, error) {
+			return ip.Parse("", b, interp.Entrypoint(e))
+		}},
+	}
+}
+
+func TestConformance(t *testing.T) {
+	cases := []struct {
+		entrypoint, in, want string
+	}{
+		{"Direct", "1", "1"},
+		{"Direct", "1+2+3", "((1+2)+3)"},
+		{"Direct", "1+", ""},
+		{"Indirect", "a", "a"},
+		{"Indirect", "abbb", "(((ab)b)b)"},
+		{"Indirect", "ba", ""},
+		{"Mutual", "+", "+"},
+		{"Mutual", "-*+", "((-*)+)"},
+		{"Mutual", "+-+*-+", "(((((+-)+)*)-)+)"},
+		{"Mutual", "*-*+", "(((*-)*)+)"},
+		{"Mutual", "-+", "(-+)"},
+		{"Mutual", "--+", ""},
+		{"Mutual", "+-", ""},
+		{"Nested", "1+2*3-4/5*6", "((1+(2*3))-((4/5)*6))"},
+		{"Nested", "1*2*3", "((1*2)*3)"},
+		{"Nested", "1*+2", ""},
+		{"Interlocking", "x", "x"},
+		{"Interlocking", "x.x(n)(n).x", "((((x field) call) call) field)"},
+		{"Interlocking", "x(n).x.x", "(((x call) field) field)"},
+		{"Interlocking", "x.x.", ""},
+		{"Hidden", "h", "h"},
+		{"Hidden", "h!!", "((h!)!)"},
+		{"Hidden", "!h", ""},
+	}
+	for _, p := range parsers(t) {
+		for _, c := range cases {
+			v, err := p.parse(c.entrypoint, []byte(c.in))
+			if c.want == "" {
+				if err == nil {
+					t.Errorf("%s: %s %q: want an error, got %v", p.name, c.entrypoint, c.in, v)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s: %s %q: %v", p.name, c.entrypoint, c.in, err)
+				continue
+			}
+			if p.values && v != c.want {
+				t.Errorf("%s: %s %q: want %s, got %v", p.name, c.entrypoint, c.in, c.want, v)
+			}
+		}
+	}
+}
+
+// TestMutualLanguage checks that the parsers agree on the inputs of the
+// mutually left-recursive rules with several leaders, and that they match
+// their language: the strings of '+', '-' and '*' without two equal
+// consecutive characters that end with '+', except the strings of
+// excluded.
+func TestMutualLanguage(t *testing.T) {
+	// As the choices are ordered, a rule stops growing when its first
+	// alternative that matches does not extend its previous match, and the
+	// longer match of a later alternative is never tried. The comments list
+	// the rule, its match and the first alternative that returns it, and the
+	// match of the later alternative that prevents the input from being
+	// matched.
+	excluded := map[string]bool{
+		"-+*+":   true, // Foo "-+" by Bar '+', not "-+*+" by Baz '+'
+		"+-+*+":  true, // Foo "+-+" by Bar '+', not "+-+*+" by Baz '+'
+		"+*-*+":  true, // Baz "+*" by Foo '*', not "+*-*" by Bar '*'
+		"*-+-+":  true, // Bar "*-" by Baz '-', not "*-+-" by Foo '-'
+		"*-+*+":  true, // Foo "*-+" by Bar '+', not "*-+*+" by Baz '+'
+		"+*-+*+": true, // Foo "+*-+" by Bar '+', not "+*-+*+" by Baz '+'
+		"+*-*-+": true, // Baz "+*" by Foo '*', not "+*-*" by Bar '*'
+		"-+-+*+": true, // Foo "-+-+" by Bar '+', not "-+-+*+" by Baz '+'
+		"-+*+-+": true, // Foo "-+" by Bar '+', not "-+*+" by Baz '+'
+		"-+*+*+": true, // Foo "-+" by Bar '+', not "-+*+" by Baz '+'
+		"-*-+*+": true, // Foo "-*-+" by Bar '+', not "-*-+*+" by Baz '+'
+		"*+-+*+": true, // Foo "*+-+" by Bar '+', not "*+-+*+" by Baz '+'
+		"*-+-*+": true, // Bar "*-" by Baz '-', not "*-+-" by Foo '-'
+	}
+
+	var inputs []string
+	level := []string{""}
+	for range 6 {
+		var next []string
+		for _, in := range level {
+			for _, ch := range "+-*" {
+				next = append(next, in+string(ch))
+			}
+		}
+		inputs = append(inputs, next...)
+		level = next
+	}
+
+	ps := parsers(t)
+	for _, in := range inputs {
+		inLanguage := strings.HasSuffix(in, "+")
+		for i := 1; i < len(in); i++ {
+			if in[i] == in[i-1] {
+				inLanguage = false
+			}
+		}
+		if excluded[in] && !inLanguage {
+			t.Errorf("%q: excluded but out of the language", in)
+		}
+		want := inLanguage && !excluded[in]
+
+		for _, p := range ps {
+			if _, err := p.parse("Mutual", []byte(in)); (err == nil) != want {
+				t.Errorf("%s: %q: want match %t, got error %v", p.name, in, want, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,2673 @@
+// Code generated by pigeon; DO NOT EDIT.
+
+package conformance
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+var g = &grammar{
+	rules: []*rule{
+		{
+			name: "Direct",
+			pos:  position{line: 8, col: 1, offset: 157},
+			expr: &actionExpr{
+				pos: position{line: 8, col: 10, offset: 166},
+				run: (*parser).callonDirect1,
+				expr: &seqExpr{
+					pos: position{line: 8, col: 10, offset: 166},
+					exprs: []any{
+						&labeledExpr{
+							pos:   position{line: 8, col: 10, offset: 166},
+							label: "v",
+							expr: &ruleRefExpr{
+								pos:  position{line: 8, col: 12, offset: 168},
+								name: "Sum",
+							},
+						},
+						&notExpr{
+							pos: position{line: 8, col: 16, offset: 172},
+							expr: &anyMatcher{
+								line: 8, col: 17, offset: 173,
+							},
+						},
+					},
+				},
+			},
+			leader:        false,
+			leftRecursive: false,
+		},
+		{
+			name: "Indirect",
+			pos:  position{line: 12, col: 1, offset: 198},
+			expr: &actionExpr{
+				pos: position{line: 12, col: 12, offset: 209},
+				run: (*parser).callonIndirect1,
+				expr: &seqExpr{
+					pos: position{line: 12, col: 12, offset: 209},
+					exprs: []any{
+						&labeledExpr{
+							pos:   position{line: 12, col: 12, offset: 209},
+							label: "v",
+							expr: &ruleRefExpr{
+								pos:  position{line: 12, col: 14, offset: 211},
+								name: "A",
+							},
+						},
+						&notExpr{
+							pos: position{line: 12, col: 16, offset: 213},
+							expr: &anyMatcher{
+								line: 12, col: 17, offset: 214,
+							},
+						},
+					},
+				},
+			},
+			leader:        false,
+			leftRecursive: false,
+		},
+		{
+			name: "Mutual",
+			pos:  position{line: 16, col: 1, offset: 239},
+			expr: &actionExpr{
+				pos: position{line: 16, col: 10, offset: 248},
+				run: (*parser).callonMutual1,
+				expr: &seqExpr{
+					pos: position{line: 16, col: 10, offset: 248},
+					exprs: []any{
+						&labeledExpr{
+							pos:   position{line: 16, col: 10, offset: 248},
+							label: "v",
+							expr: &ruleRefExpr{
+								pos:  position{line: 16, col: 12, offset: 250},
+								name: "Foo",
+							},
+						},
+						&notExpr{
+							pos: position{line: 16, col: 16, offset: 254},
+							expr: &anyMatcher{
+								line: 16, col: 17, offset: 255,
+							},
+						},
+					},
+				},
+			},
+			leader:        false,
+			leftRecursive: false,
+		},
+		{
+			name: "Nested",
+			pos:  position{line: 20, col: 1, offset: 280},
+			expr: &actionExpr{
+				pos: position{line: 20, col: 10, offset: 289},
+				run: (*parser).callonNested1,
+				expr: &seqExpr{
+					pos: position{line: 20, col: 10, offset: 289},
+					exprs: []any{
+						&labeledExpr{
+							pos:   position{line: 20, col: 10, offset: 289},
+							label: "v",
+							expr: &ruleRefExpr{
+								pos:  position{line: 20, col: 12, offset: 291},
+								name: "Expr",
+							},
+						},
+						&notExpr{
+							pos: position{line: 20, col: 17, offset: 296},
+							expr: &anyMatcher{
+								line: 20, col: 18, offset: 297,
+							},
+						},
+					},
+				},
+			},
+			leader:        false,
+			leftRecursive: false,
+		},
+		{
+			name: "Interlocking",
+			pos:  position{line: 24, col: 1, offset: 322},
+			expr: &actionExpr{
+				pos: position{line: 24, col: 16, offset: 337},
+				run: (*parser).callonInterlocking1,
+				expr: &seqExpr{
+					pos: position{line: 24, col: 16, offset: 337},
+					exprs: []any{
+						&labeledExpr{
+							pos:   position{line: 24, col: 16, offset: 337},
+							label: "v",
+							expr: &ruleRefExpr{
+								pos:  position{line: 24, col: 18, offset: 339},
+								name: "Primary",
+							},
+						},
+						&notExpr{
+							pos: position{line: 24, col: 26, offset: 347},
+							expr: &anyMatcher{
+								line: 24, col: 27, offset: 348,
+							},
+						},
+					},
+				},
+			},
+			leader:        false,
+			leftRecursive: false,
+		},
+		{
+			name: "Hidden",
+			pos:  position{line: 28, col: 1, offset: 373},
+			expr: &actionExpr{
+				pos: position{line: 28, col: 10, offset: 382},
+				run: (*parser).callonHidden1,
+				expr: &seqExpr{
+					pos: position{line: 28, col: 10, offset: 382},
+					exprs: []any{
+						&labeledExpr{
+							pos:   position{line: 28, col: 10, offset: 382},
+							label: "v",
+							expr: &ruleRefExpr{
+								pos:  position{line: 28, col: 12, offset: 384},
+								name: "H",
+							},
+						},
+						&notExpr{
+							pos: position{line: 28, col: 14, offset: 386},
+							expr: &anyMatcher{
+								line: 28, col: 15, offset: 387,
+							},
+						},
+					},
+				},
+			},
+			leader:        false,
+			leftRecursive: false,
+		},
+		// Sum is directly left-recursive.
+		{
+			name: "Sum",
+			pos:  position{line: 33, col: 1, offset: 447},
+			expr: &choiceExpr{
+				pos: position{line: 33, col: 7, offset: 453},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 33, col: 7, offset: 453},
+						run: (*parser).callonSum2,
+						expr: &seqExpr{
+							pos: position{line: 33, col: 7, offset: 453},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 33, col: 7, offset: 453},
+									label: "a",
+									expr: &ruleRefExpr{
+										pos:  position{line: 33, col: 9, offset: 455},
+										name: "Sum",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 33, col: 13, offset: 459},
+									val:        "+",
+									ignoreCase: false,
+									want:       "\"+\"",
+								},
+								&labeledExpr{
+									pos:   position{line: 33, col: 17, offset: 463},
+									label: "b",
+									expr: &ruleRefExpr{
+										pos:  position{line: 33, col: 19, offset: 465},
+										name: "Num",
+									},
+								},
+							},
+						},
+					},
+					&ruleRefExpr{
+						pos:  position{line: 35, col: 5, offset: 533},
+						name: "Num",
+					},
+				},
+			},
+			leader:        true,
+			leftRecursive: true,
+		},
+		{
+			name: "Num",
+			pos:  position{line: 37, col: 1, offset: 538},
+			expr: &actionExpr{
+				pos: position{line: 37, col: 7, offset: 544},
+				run: (*parser).callonNum1,
+				expr: &charClassMatcher{
+					pos:        position{line: 37, col: 7, offset: 544},
+					val:        "[0-9]",
+					ranges:     []rune{'0', '9'},
+					ignoreCase: false,
+					inverted:   false,
+				},
+			},
+			leader:        false,
+			leftRecursive: false,
+		},
+		// A and B are indirectly left-recursive, A is in the only cycle.
+		{
+			name: "A",
+			pos:  position{line: 42, col: 1, offset: 652},
+			expr: &choiceExpr{
+				pos: position{line: 42, col: 5, offset: 656},
+				alternatives: []any{
+					&ruleRefExpr{
+						pos:  position{line: 42, col: 5, offset: 656},
+						name: "B",
+					},
+					&actionExpr{
+						pos: position{line: 42, col: 9, offset: 660},
+						run: (*parser).callonA3,
+						expr: &litMatcher{
+							pos:        position{line: 42, col: 9, offset: 660},
+							val:        "a",
+							ignoreCase: false,
+							want:       "\"a\"",
+						},
+					},
+				},
+			},
+			leader:        true,
+			leftRecursive: true,
+		},
+		{
+			name: "B",
+			pos:  position{line: 46, col: 1, offset: 689},
+			expr: &actionExpr{
+				pos: position{line: 46, col: 5, offset: 693},
+				run: (*parser).callonB1,
+				expr: &seqExpr{
+					pos: position{line: 46, col: 5, offset: 693},
+					exprs: []any{
+						&labeledExpr{
+							pos:   position{line: 46, col: 5, offset: 693},
+							label: "a",
+							expr: &ruleRefExpr{
+								pos:  position{line: 46, col: 7, offset: 695},
+								name: "A",
+							},
+						},
+						&litMatcher{
+							pos:        position{line: 46, col: 9, offset: 697},
+							val:        "b",
+							ignoreCase: false,
+							want:       "\"b\"",
+						},
+					},
+				},
+			},
+			leader:        false,
+			leftRecursive: true,
+		},
+		// Foo, Bar and Baz are mutually left-recursive, no rule is in all the
+		// cycles so that two of them are leaders.
+		{
+			name: "Foo",
+			pos:  position{line: 52, col: 1, offset: 860},
+			expr: &choiceExpr{
+				pos: position{line: 52, col: 7, offset: 866},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 52, col: 7, offset: 866},
+						run: (*parser).callonFoo2,
+						expr: &seqExpr{
+							pos: position{line: 52, col: 7, offset: 866},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 52, col: 7, offset: 866},
+									label: "a",
+									expr: &ruleRefExpr{
+										pos:  position{line: 52, col: 9, offset: 868},
+										name: "Bar",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 52, col: 13, offset: 872},
+									val:        "+",
+									ignoreCase: false,
+									want:       "\"+\"",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 54, col: 5, offset: 922},
+						run: (*parser).callonFoo7,
+						expr: &seqExpr{
+							pos: position{line: 54, col: 5, offset: 922},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 54, col: 5, offset: 922},
+									label: "a",
+									expr: &ruleRefExpr{
+										pos:  position{line: 54, col: 7, offset: 924},
+										name: "Baz",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 54, col: 11, offset: 928},
+									val:        "+",
+									ignoreCase: false,
+									want:       "\"+\"",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 56, col: 5, offset: 978},
+						run: (*parser).callonFoo12,
+						expr: &litMatcher{
+							pos:        position{line: 56, col: 5, offset: 978},
+							val:        "+",
+							ignoreCase: false,
+							want:       "\"+\"",
+						},
+					},
+				},
+			},
+			leader:        false,
+			leftRecursive: true,
+		},
+		{
+			name: "Bar",
+			pos:  position{line: 60, col: 1, offset: 1007},
+			expr: &choiceExpr{
+				pos: position{line: 60, col: 7, offset: 1013},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 60, col: 7, offset: 1013},
+						run: (*parser).callonBar2,
+						expr: &seqExpr{
+							pos: position{line: 60, col: 7, offset: 1013},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 60, col: 7, offset: 1013},
+									label: "a",
+									expr: &ruleRefExpr{
+										pos:  position{line: 60, col: 9, offset: 1015},
+										name: "Baz",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 60, col: 13, offset: 1019},
+									val:        "-",
+									ignoreCase: false,
+									want:       "\"-\"",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 62, col: 5, offset: 1069},
+						run: (*parser).callonBar7,
+						expr: &seqExpr{
+							pos: position{line: 62, col: 5, offset: 1069},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 62, col: 5, offset: 1069},
+									label: "a",
+									expr: &ruleRefExpr{
+										pos:  position{line: 62, col: 7, offset: 1071},
+										name: "Foo",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 62, col: 11, offset: 1075},
+									val:        "-",
+									ignoreCase: false,
+									want:       "\"-\"",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 64, col: 5, offset: 1125},
+						run: (*parser).callonBar12,
+						expr: &litMatcher{
+							pos:        position{line: 64, col: 5, offset: 1125},
+							val:        "-",
+							ignoreCase: false,
+							want:       "\"-\"",
+						},
+					},
+				},
+			},
+			leader:        true,
+			leftRecursive: true,
+		},
+		{
+			name: "Baz",
+			pos:  position{line: 68, col: 1, offset: 1154},
+			expr: &choiceExpr{
+				pos: position{line: 68, col: 7, offset: 1160},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 68, col: 7, offset: 1160},
+						run: (*parser).callonBaz2,
+						expr: &seqExpr{
+							pos: position{line: 68, col: 7, offset: 1160},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 68, col: 7, offset: 1160},
+									label: "a",
+									expr: &ruleRefExpr{
+										pos:  position{line: 68, col: 9, offset: 1162},
+										name: "Foo",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 68, col: 13, offset: 1166},
+									val:        "*",
+									ignoreCase: false,
+									want:       "\"*\"",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 70, col: 5, offset: 1216},
+						run: (*parser).callonBaz7,
+						expr: &seqExpr{
+							pos: position{line: 70, col: 5, offset: 1216},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 70, col: 5, offset: 1216},
+									label: "a",
+									expr: &ruleRefExpr{
+										pos:  position{line: 70, col: 7, offset: 1218},
+										name: "Bar",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 70, col: 11, offset: 1222},
+									val:        "*",
+									ignoreCase: false,
+									want:       "\"*\"",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 72, col: 5, offset: 1272},
+						run: (*parser).callonBaz12,
+						expr: &litMatcher{
+							pos:        position{line: 72, col: 5, offset: 1272},
+							val:        "*",
+							ignoreCase: false,
+							want:       "\"*\"",
+						},
+					},
+				},
+			},
+			leader:        true,
+			leftRecursive: true,
+		},
+		// Expr and Term are left-recursive leaders at the same offset, Term in
+		// Expr.
+		{
+			name: "Expr",
+			pos:  position{line: 78, col: 1, offset: 1382},
+			expr: &choiceExpr{
+				pos: position{line: 78, col: 8, offset: 1389},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 78, col: 8, offset: 1389},
+						run: (*parser).callonExpr2,
+						expr: &seqExpr{
+							pos: position{line: 78, col: 8, offset: 1389},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 78, col: 8, offset: 1389},
+									label: "a",
+									expr: &ruleRefExpr{
+										pos:  position{line: 78, col: 10, offset: 1391},
+										name: "Expr",
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 78, col: 15, offset: 1396},
+									label: "op",
+									expr: &charClassMatcher{
+										pos:        position{line: 78, col: 18, offset: 1399},
+										val:        "[+-]",
+										chars:      []rune{'+', '-'},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 78, col: 23, offset: 1404},
+									label: "b",
+									expr: &ruleRefExpr{
+										pos:  position{line: 78, col: 25, offset: 1406},
+										name: "Term",
+									},
+								},
+							},
+						},
+					},
+					&ruleRefExpr{
+						pos:  position{line: 80, col: 5, offset: 1491},
+						name: "Term",
+					},
+				},
+			},
+			leader:        true,
+			leftRecursive: true,
+		},
+		{
+			name: "Term",
+			pos:  position{line: 82, col: 1, offset: 1497},
+			expr: &choiceExpr{
+				pos: position{line: 82, col: 8, offset: 1504},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 82, col: 8, offset: 1504},
+						run: (*parser).callonTerm2,
+						expr: &seqExpr{
+							pos: position{line: 82, col: 8, offset: 1504},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 82, col: 8, offset: 1504},
+									label: "a",
+									expr: &ruleRefExpr{
+										pos:  position{line: 82, col: 10, offset: 1506},
+										name: "Term",
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 82, col: 15, offset: 1511},
+									label: "op",
+									expr: &charClassMatcher{
+										pos:        position{line: 82, col: 18, offset: 1514},
+										val:        "[*/]",
+										chars:      []rune{'*', '/'},
+										ignoreCase: false,
+										inverted:   false,
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 82, col: 23, offset: 1519},
+									label: "b",
+									expr: &ruleRefExpr{
+										pos:  position{line: 82, col: 25, offset: 1521},
+										name: "Num",
+									},
+								},
+							},
+						},
+					},
+					&ruleRefExpr{
+						pos:  position{line: 84, col: 5, offset: 1605},
+						name: "Num",
+					},
+				},
+			},
+			leader:        true,
+			leftRecursive: true,
+		},
+		// Primary and Field are interlocking left-recursive rules, the calls and
+		// the field accesses of the primary expressions of Java.
+		{
+			name: "Primary",
+			pos:  position{line: 88, col: 1, offset: 1742},
+			expr: &choiceExpr{
+				pos: position{line: 88, col: 11, offset: 1752},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 88, col: 11, offset: 1752},
+						run: (*parser).callonPrimary2,
+						expr: &seqExpr{
+							pos: position{line: 88, col: 11, offset: 1752},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 88, col: 11, offset: 1752},
+									label: "a",
+									expr: &ruleRefExpr{
+										pos:  position{line: 88, col: 13, offset: 1754},
+										name: "Primary",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 88, col: 21, offset: 1762},
+									val:        "(n)",
+									ignoreCase: false,
+									want:       "\"(n)\"",
+								},
+							},
+						},
+					},
+					&ruleRefExpr{
+						pos:  position{line: 90, col: 5, offset: 1818},
+						name: "Field",
+					},
+				},
+			},
+			leader:        true,
+			leftRecursive: true,
+		},
+		{
+			name: "Field",
+			pos:  position{line: 92, col: 1, offset: 1825},
+			expr: &choiceExpr{
+				pos: position{line: 92, col: 9, offset: 1833},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 92, col: 9, offset: 1833},
+						run: (*parser).callonField2,
+						expr: &seqExpr{
+							pos: position{line: 92, col: 9, offset: 1833},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 92, col: 9, offset: 1833},
+									label: "a",
+									expr: &ruleRefExpr{
+										pos:  position{line: 92, col: 11, offset: 1835},
+										name: "Primary",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 92, col: 19, offset: 1843},
+									val:        ".x",
+									ignoreCase: false,
+									want:       "\".x\"",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 94, col: 5, offset: 1899},
+						run: (*parser).callonField7,
+						expr: &litMatcher{
+							pos:        position{line: 94, col: 5, offset: 1899},
+							val:        "x",
+							ignoreCase: false,
+							want:       "\"x\"",
+						},
+					},
+				},
+			},
+			leader:        false,
+			leftRecursive: true,
+		},
+		// H is left-recursive after the optional sign, that can match nothing.
+		{
+			name: "H",
+			pos:  position{line: 99, col: 1, offset: 2000},
+			expr: &choiceExpr{
+				pos: position{line: 99, col: 5, offset: 2004},
+				alternatives: []any{
+					&actionExpr{
+						pos: position{line: 99, col: 5, offset: 2004},
+						run: (*parser).callonH2,
+						expr: &seqExpr{
+							pos: position{line: 99, col: 5, offset: 2004},
+							exprs: []any{
+								&labeledExpr{
+									pos:   position{line: 99, col: 5, offset: 2004},
+									label: "s",
+									expr: &ruleRefExpr{
+										pos:  position{line: 99, col: 7, offset: 2006},
+										name: "Sign",
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 99, col: 12, offset: 2011},
+									label: "a",
+									expr: &ruleRefExpr{
+										pos:  position{line: 99, col: 14, offset: 2013},
+										name: "H",
+									},
+								},
+								&litMatcher{
+									pos:        position{line: 99, col: 16, offset: 2015},
+									val:        "!",
+									ignoreCase: false,
+									want:       "\"!\"",
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 101, col: 5, offset: 2078},
+						run: (*parser).callonH9,
+						expr: &litMatcher{
+							pos:        position{line: 101, col: 5, offset: 2078},
+							val:        "h",
+							ignoreCase: false,
+							want:       "\"h\"",
+						},
+					},
+				},
+			},
+			leader:        true,
+			leftRecursive: true,
+		},
+		{
+			name: "Sign",
+			pos:  position{line: 105, col: 1, offset: 2107},
+			expr: &actionExpr{
+				pos: position{line: 105, col: 8, offset: 2114},
+				run: (*parser).callonSign1,
+				expr: &zeroOrOneExpr{
+					pos: position{line: 105, col: 8, offset: 2114},
+					expr: &litMatcher{
+						pos:        position{line: 105, col: 8, offset: 2114},
+						val:        "-",
+						ignoreCase: false,
+						want:       "\"-\"",
+					},
+				},
+			},
+			leader:        false,
+			leftRecursive: false,
+		},
+	},
+}
+
+func (c *current) onDirect1(v any) (any, error) {
+	return v, nil
+}
+
+func (p *parser) callonDirect1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onDirect1(stack["v"])
+}
+
+func (c *current) onIndirect1(v any) (any, error) {
+	return v, nil
+}
+
+func (p *parser) callonIndirect1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onIndirect1(stack["v"])
+}
+
+func (c *current) onMutual1(v any) (any, error) {
+	return v, nil
+}
+
+func (p *parser) callonMutual1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onMutual1(stack["v"])
+}
+
+func (c *current) onNested1(v any) (any, error) {
+	return v, nil
+}
+
+func (p *parser) callonNested1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onNested1(stack["v"])
+}
+
+func (c *current) onInterlocking1(v any) (any, error) {
+	return v, nil
+}
+
+func (p *parser) callonInterlocking1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onInterlocking1(stack["v"])
+}
+
+func (c *current) onHidden1(v any) (any, error) {
+	return v, nil
+}
+
+func (p *parser) callonHidden1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onHidden1(stack["v"])
+}
+
+// onSum2 is a code block of rule Sum:
+//
+// Sum is directly left-recursive.
+func (c *current) onSum2(a, b any) (any, error) {
+	return "(" + a.(string) + "+" + b.(string) + ")", nil
+}
+
+func (p *parser) callonSum2() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onSum2(stack["a"], stack["b"])
+}
+
+func (c *current) onNum1() (any, error) {
+	return string(c.text), nil
+}
+
+func (p *parser) callonNum1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onNum1()
+}
+
+// onA3 is a code block of rule A:
+//
+// A and B are indirectly left-recursive, A is in the only cycle.
+func (c *current) onA3() (any, error) {
+	return "a", nil
+}
+
+func (p *parser) callonA3() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onA3()
+}
+
+func (c *current) onB1(a any) (any, error) {
+	return "(" + a.(string) + "b)", nil
+}
+
+func (p *parser) callonB1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onB1(stack["a"])
+}
+
+// onFoo2 is a code block of rule Foo:
+//
+// Foo, Bar and Baz are mutually left-recursive, no rule is in all the
+// cycles so that two of them are leaders.
+func (c *current) onFoo2(a any) (any, error) {
+	return "(" + a.(string) + "+)", nil
+}
+
+func (p *parser) callonFoo2() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onFoo2(stack["a"])
+}
+
+// onFoo7 is a code block of rule Foo:
+//
+// Foo, Bar and Baz are mutually left-recursive, no rule is in all the
+// cycles so that two of them are leaders.
+func (c *current) onFoo7(a any) (any, error) {
+	return "(" + a.(string) + "+)", nil
+}
+
+func (p *parser) callonFoo7() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onFoo7(stack["a"])
+}
+
+// onFoo12 is a code block of rule Foo:
+//
+// Foo, Bar and Baz are mutually left-recursive, no rule is in all the
+// cycles so that two of them are leaders.
+func (c *current) onFoo12() (any, error) {
+	return "+", nil
+}
+
+func (p *parser) callonFoo12() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onFoo12()
+}
+
+func (c *current) onBar2(a any) (any, error) {
+	return "(" + a.(string) + "-)", nil
+}
+
+func (p *parser) callonBar2() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onBar2(stack["a"])
+}
+
+func (c *current) onBar7(a any) (any, error) {
+	return "(" + a.(string) + "-)", nil
+}
+
+func (p *parser) callonBar7() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onBar7(stack["a"])
+}
+
+func (c *current) onBar12() (any, error) {
+	return "-", nil
+}
+
+func (p *parser) callonBar12() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onBar12()
+}
+
+func (c *current) onBaz2(a any) (any, error) {
+	return "(" + a.(string) + "*)", nil
+}
+
+func (p *parser) callonBaz2() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onBaz2(stack["a"])
+}
+
+func (c *current) onBaz7(a any) (any, error) {
+	return "(" + a.(string) + "*)", nil
+}
+
+func (p *parser) callonBaz7() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onBaz7(stack["a"])
+}
+
+func (c *current) onBaz12() (any, error) {
+	return "*", nil
+}
+
+func (p *parser) callonBaz12() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onBaz12()
+}
+
+// onExpr2 is a code block of rule Expr:
+//
+// Expr and Term are left-recursive leaders at the same offset, Term in
+// Expr.
+func (c *current) onExpr2(a, op, b any) (any, error) {
+	return "(" + a.(string) + string(op.([]byte)) + b.(string) + ")", nil
+}
+
+func (p *parser) callonExpr2() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onExpr2(stack["a"], stack["op"], stack["b"])
+}
+
+func (c *current) onTerm2(a, op, b any) (any, error) {
+	return "(" + a.(string) + string(op.([]byte)) + b.(string) + ")", nil
+}
+
+func (p *parser) callonTerm2() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onTerm2(stack["a"], stack["op"], stack["b"])
+}
+
+// onPrimary2 is a code block of rule Primary:
+//
+// Primary and Field are interlocking left-recursive rules, the calls and
+// the field accesses of the primary expressions of Java.
+func (c *current) onPrimary2(a any) (any, error) {
+	return "(" + a.(string) + " call)", nil
+}
+
+func (p *parser) callonPrimary2() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onPrimary2(stack["a"])
+}
+
+func (c *current) onField2(a any) (any, error) {
+	return "(" + a.(string) + " field)", nil
+}
+
+func (p *parser) callonField2() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onField2(stack["a"])
+}
+
+func (c *current) onField7() (any, error) {
+	return "x", nil
+}
+
+func (p *parser) callonField7() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onField7()
+}
+
+// onH2 is a code block of rule H:
+//
+// H is left-recursive after the optional sign, that can match nothing.
+func (c *current) onH2(s, a any) (any, error) {
+	return "(" + s.(string) + a.(string) + "!)", nil
+}
+
+func (p *parser) callonH2() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onH2(stack["s"], stack["a"])
+}
+
+// onH9 is a code block of rule H:
+//
+// H is left-recursive after the optional sign, that can match nothing.
+func (c *current) onH9() (any, error) {
+	return "h", nil
+}
+
+func (p *parser) callonH9() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onH9()
+}
+
+func (c *current) onSign1() (any, error) {
+	return string(c.text), nil
+}
+
+func (p *parser) callonSign1() (any, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onSign1()
+}
+
+var (
+	// errNoRule is returned when the grammar to parse has no rule.
+	errNoRule = errors.New("grammar has no rule")
+
+	// errInvalidEntrypoint is returned when the specified entrypoint rule
+	// does not exit.
+	errInvalidEntrypoint = errors.New("invalid entrypoint")
+
+	// errInvalidEncoding is returned when the source is not properly
+	// utf8-encoded.
+	errInvalidEncoding = errors.New("invalid encoding")
+
+	// errMaxExprCnt is used to signal that the maximum number of
+	// expressions have been parsed.
+	errMaxExprCnt = errors.New("max number of expressions parsed")
+)
+
+// Option is a function that can set an option on the parser. It returns
+// the previous setting as an Option.
+type Option func(*parser) Option
+
+// MaxExpressions creates an Option to stop parsing after the provided
+// number of expressions have been parsed, if the value is 0 then the parser will
+// parse for as many steps as needed (possibly an infinite number).
+//
+// The default for maxExprCnt is 0.
+func MaxExpressions(maxExprCnt uint64) Option {
+	return func(p *parser) Option {
+		oldMaxExprCnt := p.maxExprCnt
+		p.maxExprCnt = maxExprCnt
+		return MaxExpressions(oldMaxExprCnt)
+	}
+}
+
+// Entrypoint creates an Option to set the rule name to use as entrypoint.
+// The rule name must have been specified in the -alternate-entrypoints
+// if generating the parser with the -optimize-grammar flag, otherwise
+// it may have been optimized out. Passing an empty string sets the
+// entrypoint to the first rule in the grammar.
+//
+// The default is to start parsing at the first rule in the grammar.
+func Entrypoint(ruleName string) Option {
+	return func(p *parser) Option {
+		oldEntrypoint := p.entrypoint
+		p.entrypoint = ruleName
+		if ruleName == "" {
+			p.entrypoint = g.rules[0].name
+		}
+		return Entrypoint(oldEntrypoint)
+	}
+}
+
+// Statistics adds a user provided Stats struct to the parser to allow
+// the user to process the results after the parsing has finished.
+// Also the key for the "no match" counter is set.
+//
+// Example usage:
+//
+//	input := "input"
+//	stats := Stats{}
+//	_, err := Parse("input-file", []byte(input), Statistics(&stats, "no match"))
+//	if err != nil {
+//	    log.Panicln(err)
+//	}
+//	b, err := json.MarshalIndent(stats.ChoiceAltCnt, "", "  ")
+//	if err != nil {
+//	    log.Panicln(err)
+//	}
+//	fmt.Println(string(b))
+func Statistics(stats *Stats, choiceNoMatch string) Option {
+	return func(p *parser) Option {
+		oldStats := p.Stats
+		p.Stats = stats
+		oldChoiceNoMatch := p.choiceNoMatch
+		p.choiceNoMatch = choiceNoMatch
+		if p.Stats.ChoiceAltCnt == nil {
+			p.Stats.ChoiceAltCnt = make(map[string]map[string]int)
+		}
+		return Statistics(oldStats, oldChoiceNoMatch)
+	}
+}
+
+// Debug creates an Option to set the debug flag to b. When set to true,
+// debugging information is printed to stdout while parsing.
+//
+// The default is false.
+func Debug(b bool) Option {
+	return func(p *parser) Option {
+		old := p.tracer
+		if b {
+			p.tracer = &debugTracer{p: p}
+		} else if _, ok := old.(*debugTracer); ok {
+			p.tracer = nil
+		}
+		return setTracer(old)
+	}
+}
+
+// setTracer creates an Option to set the tracer that receives the events
+// of the parse.
+func setTracer(t tracer) Option {
+	return func(p *parser) Option {
+		old := p.tracer
+		p.tracer = t
+		return setTracer(old)
+	}
+}
+
+// Memoize creates an Option to set the memoize flag to b. When set to true,
+// the parser will cache all results so each expression is evaluated only
+// once. This guarantees linear parsing time even for pathological cases,
+// at the expense of more memory and slower times for typical cases.
+// The results of the rules with the @memoize annotation in the grammar
+// are cached even if b is false, without the cost of caching every
+// expression.
+//
+// The default is false.
+func Memoize(b bool) Option {
+	return func(p *parser) Option {
+		old := p.memoize
+		p.memoize = b
+		return Memoize(old)
+	}
+}
+
+// MemoWindow creates an Option to bound the memory of the memoization
+// table: the results are kept only for the n offsets behind the furthest
+// offset where a result was cached, as the parser rarely backtracks
+// further. The results evicted from the window are evaluated again if
+// needed, the memory is O(n) instead of O(len(input)). The rules with
+// the @commit annotation in the grammar also discard the results before
+// the end of their match. If n is 0, all the results are kept.
+//
+// The default is 0.
+func MemoWindow(n int) Option {
+	return func(p *parser) Option {
+		old := p.memoWindow
+		p.memoWindow = n
+		return MemoWindow(old)
+	}
+}
+
+// AllowInvalidUTF8 creates an Option to allow invalid UTF-8 bytes.
+// Every invalid UTF-8 byte is treated as a utf8.RuneError (U+FFFD)
+// by character class matchers and is matched by the any matcher.
+// The returned matched value, c.text and c.offset are NOT affected.
+//
+// The default is false.
+func AllowInvalidUTF8(b bool) Option {
+	return func(p *parser) Option {
+		old := p.allowInvalidUTF8
+		p.allowInvalidUTF8 = b
+		return AllowInvalidUTF8(old)
+	}
+}
+
+// Recover creates an Option to set the recover flag to b. When set to
+// true, this causes the parser to recover from panics and convert it
+// to an error. Setting it to false can be useful while debugging to
+// access the full stack trace.
+//
+// The default is true.
+func Recover(b bool) Option {
+	return func(p *parser) Option {
+		old := p.recover
+		p.recover = b
+		return Recover(old)
+	}
+}
+
+// GlobalStore creates an Option to set a key to a certain value in
+// the globalStore.
+func GlobalStore(key string, value any) Option {
+	return func(p *parser) Option {
+		old := p.cur.globalStore[key]
+		p.cur.globalStore[key] = value
+		return GlobalStore(key, old)
+	}
+}
+
+// InitState creates an Option to set a key to a certain value in
+// the global "state" store.
+func InitState(key string, value any) Option {
+	return func(p *parser) Option {
+		old := p.cur.state[key]
+		p.cur.state[key] = value
+		return InitState(key, old)
+	}
+}
+
+// ParseFile parses the file identified by filename.
+func ParseFile(filename string, opts ...Option) (i any, err error) { // nolint: deadcode
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil {
+			err = closeErr
+		}
+	}()
+	return ParseReader(filename, f, opts...)
+}
+
+// ParseReader parses the data from r using filename as information in the
+// error messages.
+func ParseReader(filename string, r io.Reader, opts ...Option) (any, error) { // nolint: deadcode
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(filename, b, opts...)
+}
+
+// Parse parses the data from b using filename as information in the
+// error messages.
+func Parse(filename string, b []byte, opts ...Option) (any, error) {
+	return newParser(filename, b, opts...).parse(g)
+}
+
+// position records a position in the text.
+type position struct {
+	line, col, offset int
+}
+
+func (p position) String() string {
+	return strconv.Itoa(p.line) + ":" + strconv.Itoa(p.col) + " [" + strconv.Itoa(p.offset) + "]"
+}
+
+// savepoint stores all state required to go back to this point in the
+// parser.
+type savepoint struct {
+	position
+	rn rune
+	w  int
+}
+
+type current struct {
+	pos  position // start position of the match
+	text []byte   // raw text of the match
+
+	// state is a store for arbitrary key,value pairs that the user wants to be
+	// tied to the backtracking of the parser.
+	// This is always rolled back if a parsing rule fails.
+	state storeDict
+
+	// globalStore is a general store for the user to store arbitrary key-value
+	// pairs that they need to manage and that they do not want tied to the
+	// backtracking of the parser. This is only modified by the user and never
+	// rolled back by the parser. It is always up to the user to keep this in a
+	// consistent state.
+	globalStore storeDict
+}
+
+type storeDict map[string]any
+
+// the AST types...
+
+// nolint: structcheck
+type grammar struct {
+	pos   position
+	rules []*rule
+}
+
+// nolint: structcheck
+type rule struct {
+	pos         position
+	name        string
+	displayName string
+	expr        any
+
+	leader        bool
+	leftRecursive bool
+}
+
+// nolint: structcheck
+type choiceExpr struct {
+	pos          position
+	alternatives []any
+}
+
+// nolint: structcheck
+type actionExpr struct {
+	pos  position
+	expr any
+	run  func(*parser) (any, error)
+}
+
+// nolint: structcheck
+type recoveryExpr struct {
+	pos          position
+	expr         any
+	recoverExpr  any
+	failureLabel []string
+}
+
+// nolint: structcheck
+type seqExpr struct {
+	pos   position
+	exprs []any
+}
+
+// nolint: structcheck
+type throwExpr struct {
+	pos   position
+	label string
+}
+
+// nolint: structcheck
+type labeledExpr struct {
+	pos   position
+	label string
+	expr  any
+}
+
+// nolint: structcheck
+type expr struct {
+	pos  position
+	expr any
+}
+
+type (
+	andExpr        expr // nolint: structcheck
+	notExpr        expr // nolint: structcheck
+	zeroOrOneExpr  expr // nolint: structcheck
+	zeroOrMoreExpr expr // nolint: structcheck
+	oneOrMoreExpr  expr // nolint: structcheck
+)
+
+// nolint: structcheck
+type ruleRefExpr struct {
+	pos  position
+	name string
+}
+
+// nolint: structcheck
+type stateCodeExpr struct {
+	pos position
+	run func(*parser) error
+}
+
+// nolint: structcheck
+type andCodeExpr struct {
+	pos position
+	run func(*parser) (bool, error)
+}
+
+// nolint: structcheck
+type notCodeExpr struct {
+	pos position
+	run func(*parser) (bool, error)
+}
+
+// nolint: structcheck
+type litMatcher struct {
+	pos        position
+	val        string
+	ignoreCase bool
+	want       string
+}
+
+// nolint: structcheck
+type charClassMatcher struct {
+	pos             position
+	val             string
+	basicLatinChars [128]bool
+	chars           []rune
+	ranges          []rune
+	classes         []*unicode.RangeTable
+	ignoreCase      bool
+	inverted        bool
+}
+
+type anyMatcher position // nolint: structcheck
+
+// errList cumulates the errors found by the parser.
+type errList []error
+
+func (e *errList) add(err error) {
+	*e = append(*e, err)
+}
+
+func (e errList) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	e.dedupe()
+	return e
+}
+
+func (e *errList) dedupe() {
+	var cleaned []error
+	set := make(map[string]bool)
+	for _, err := range *e {
+		if msg := err.Error(); !set[msg] {
+			set[msg] = true
+			cleaned = append(cleaned, err)
+		}
+	}
+	*e = cleaned
+}
+
+// Unwrap returns the errors of the list.
+func (e errList) Unwrap() []error {
+	return e
+}
+
+func (e errList) Error() string {
+	switch len(e) {
+	case 0:
+		return ""
+	case 1:
+		return e[0].Error()
+	default:
+		var buf bytes.Buffer
+
+		for i, err := range e {
+			if i > 0 {
+				buf.WriteRune('\n')
+			}
+			buf.WriteString(err.Error())
+		}
+		return buf.String()
+	}
+}
+
+// parserError wraps an error with a prefix indicating the rule in which
+// the error occurred. The original error is stored in the Inner field.
+type parserError struct {
+	Inner    error
+	pos      position
+	prefix   string
+	expected []string
+}
+
+// Error returns the error message.
+func (p *parserError) Error() string {
+	return p.prefix + ": " + p.Inner.Error()
+}
+
+// newParser creates a parser with the specified input source and options.
+func newParser(filename string, b []byte, opts ...Option) *parser {
+	stats := Stats{
+		ChoiceAltCnt: make(map[string]map[string]int),
+	}
+
+	p := &parser{
+		filename: filename,
+		errs:     new(errList),
+		data:     b,
+		pt:       savepoint{position: position{line: 1}},
+		recover:  true,
+		cur: current{
+			state:       make(storeDict),
+			globalStore: make(storeDict),
+		},
+		maxFailPos:      position{col: 1, line: 1},
+		maxFailExpected: make([]string, 0, 20),
+		Stats:           &stats,
+		// start rule is rule [0] unless an alternate entrypoint is specified
+		entrypoint: g.rules[0].name,
+	}
+	p.setOptions(opts)
+
+	if p.maxExprCnt == 0 {
+		p.maxExprCnt = math.MaxUint64
+	}
+
+	return p
+}
+
+// setOptions applies the options to the parser.
+func (p *parser) setOptions(opts []Option) {
+	for _, opt := range opts {
+		opt(p)
+	}
+}
+
+// nolint: structcheck,deadcode
+type resultTuple struct {
+	v   any
+	b   bool
+	end savepoint
+}
+
+// nolint: varcheck
+const choiceNoMatch = -1
+
+// Stats stores some statistics, gathered during parsing
+type Stats struct {
+	// ExprCnt counts the number of expressions processed during parsing
+	// This value is compared to the maximum number of expressions allowed
+	// (set by the MaxExpressions option).
+	ExprCnt uint64
+
+	// ChoiceAltCnt is used to count for each ordered choice expression,
+	// which alternative is used how may times.
+	// These numbers allow to optimize the order of the ordered choice expression
+	// to increase the performance of the parser, saved as JSON for the
+	// -choice-stats flag of pigeon.
+	//
+	// The outer key of ChoiceAltCnt is composed of the name of the rule as well
+	// as the line and the column of the ordered choice.
+	// The inner key of ChoiceAltCnt is the number (one-based) of the matching alternative.
+	// For each alternative the number of matches are counted. If an ordered choice does not
+	// match, a special counter is incremented. The name of this counter is set with
+	// the parser option Statistics.
+	// For an alternative to be included in ChoiceAltCnt, it has to match at least once.
+	ChoiceAltCnt map[string]map[string]int
+}
+
+type ruleWithExpsStack struct {
+	rule   *rule
+	estack []any
+}
+
+// nolint: structcheck,maligned
+type parser struct {
+	filename string
+	pt       savepoint
+	cur      current
+
+	data []byte
+	errs *errList
+
+	recover bool
+	tracer  tracer
+
+	memoize bool
+	// memoization table for the packrat algorithm, the results of the
+	// expressions and the rules by offset in source, in a ring of
+	// memoWindow slots if set
+	memo       []memoSlot
+	memoWindow int
+	// the results before this offset are discarded by the commit points
+	memoFloor int
+	// results of the left-recursive leaders by offset in source, out of
+	// the memoization table so that they are never discarded
+	leaders map[leaderKey]resultTuple
+	// left-recursive leaders being grown, innermost last
+	growing []leaderGrowth
+
+	// rules table, maps the rule identifier to the rule node
+	rules map[string]*rule
+	// variables stack, map of label to value
+	vstack []map[string]any
+	// rule stack, allows identification of the current rule in errors
+	rstack []*rule
+
+	// parse fail
+	maxFailPos            position
+	maxFailExpected       []string
+	maxFailInvertExpected bool
+
+	// max number of expressions to be parsed
+	maxExprCnt uint64
+	// entrypoint for the parser
+	entrypoint string
+
+	allowInvalidUTF8 bool
+
+	*Stats
+
+	choiceNoMatch string
+	// recovery expression stack, keeps track of the currently available recovery expression, these are traversed in reverse
+	recoveryStack []map[string]any
+}
+
+// push a variable set on the vstack.
+func (p *parser) pushV() {
+	if cap(p.vstack) == len(p.vstack) {
+		// create new empty slot in the stack
+		p.vstack = append(p.vstack, nil)
+	} else {
+		// slice to 1 more
+		p.vstack = p.vstack[:len(p.vstack)+1]
+	}
+
+	// get the last args set
+	m := p.vstack[len(p.vstack)-1]
+	if m != nil && len(m) == 0 {
+		// empty map, all good
+		return
+	}
+
+	m = make(map[string]any)
+	p.vstack[len(p.vstack)-1] = m
+}
+
+// pop a variable set from the vstack.
+func (p *parser) popV() {
+	// if the map is not empty, clear it
+	m := p.vstack[len(p.vstack)-1]
+	if len(m) > 0 {
+		// GC that map
+		p.vstack[len(p.vstack)-1] = nil
+	}
+	p.vstack = p.vstack[:len(p.vstack)-1]
+}
+
+// push a recovery expression with its labels to the recoveryStack
+func (p *parser) pushRecovery(labels []string, expr any) {
+	if cap(p.recoveryStack) == len(p.recoveryStack) {
+		// create new empty slot in the stack
+		p.recoveryStack = append(p.recoveryStack, nil)
+	} else {
+		// slice to 1 more
+		p.recoveryStack = p.recoveryStack[:len(p.recoveryStack)+1]
+	}
+
+	m := make(map[string]any, len(labels))
+	for _, fl := range labels {
+		m[fl] = expr
+	}
+	p.recoveryStack[len(p.recoveryStack)-1] = m
+}
+
+// pop a recovery expression from the recoveryStack
+func (p *parser) popRecovery() {
+	// GC that map
+	p.recoveryStack[len(p.recoveryStack)-1] = nil
+
+	p.recoveryStack = p.recoveryStack[:len(p.recoveryStack)-1]
+}
+
+func (p *parser) in(s string) string {
+	p.tracer.enterExpr(s, p.pt.position)
+	return s
+}
+
+func (p *parser) out(s string) string {
+	p.tracer.exitExpr(s, p.pt.position)
+	return s
+}
+
+// tracer receives the events of a parse, with the positions of the
+// parser.
+type tracer interface {
+	enterRule(rule string, pos position)
+	exitRule(rule string, matched bool, start, end position)
+	enterExpr(step string, pos position)
+	exitExpr(step string, pos position)
+	backtrack(from, to position)
+	memo(rule string, pos position, hit bool)
+	error(err error)
+}
+
+// debugTracer is the tracer of the Debug option, it prints the events
+// to stdout, indented by the depth of the steps.
+type debugTracer struct {
+	p     *parser
+	depth int
+}
+
+func (t *debugTracer) print(mark string, pos position, s string) {
+	fmt.Printf("%s%s %d:%d:%d: %s [%#U]\n",
+		strings.Repeat(" ", t.depth), mark, pos.line, pos.col, pos.offset, s, t.p.pt.rn)
+}
+
+func (t *debugTracer) enterRule(rule string, pos position) {
+	t.enterExpr("parseRule "+rule, pos)
+}
+
+func (t *debugTracer) exitRule(rule string, matched bool, start, end position) {
+	if matched {
+		t.print("MATCH", end, string(t.p.slice(start.offset, end.offset)))
+	}
+	t.exitExpr("parseRule "+rule, end)
+}
+
+func (t *debugTracer) enterExpr(step string, pos position) {
+	t.print(">", pos, step)
+	t.depth++
+}
+
+func (t *debugTracer) exitExpr(step string, pos position) {
+	t.depth--
+	t.print("<", pos, step)
+}
+
+func (t *debugTracer) backtrack(from, to position) {
+	t.print("BACKTRACK", to, fmt.Sprintf("from %d:%d:%d", from.line, from.col, from.offset))
+}
+
+func (t *debugTracer) memo(rule string, pos position, hit bool) {
+	if hit {
+		t.print("MEMO", pos, rule)
+	}
+}
+
+func (t *debugTracer) error(err error) {
+	t.print("ERROR", t.p.pt.position, err.Error())
+}
+
+func (p *parser) addErr(err error) {
+	p.addErrAt(err, p.pt.position, []string{})
+}
+
+func (p *parser) addErrAt(err error, pos position, expected []string) {
+	var buf bytes.Buffer
+	if p.filename != "" {
+		buf.WriteString(p.filename)
+	}
+	if buf.Len() > 0 {
+		buf.WriteString(":")
+	}
+	buf.WriteString(fmt.Sprintf("%d:%d (%d)", pos.line, pos.col, pos.offset))
+	if len(p.rstack) > 0 {
+		if buf.Len() > 0 {
+			buf.WriteString(": ")
+		}
+		rule := p.rstack[len(p.rstack)-1]
+		if rule.displayName != "" {
+			buf.WriteString("rule " + rule.displayName)
+		} else {
+			buf.WriteString("rule " + rule.name)
+		}
+	}
+	pe := &parserError{Inner: err, pos: pos, prefix: buf.String(), expected: expected}
+	p.errs.add(pe)
+	if p.tracer != nil {
+		p.tracer.error(pe)
+	}
+}
+
+func (p *parser) failAt(fail bool, pos position, want string) {
+	// process fail if parsing fails and not inverted or parsing succeeds and invert is set
+	if fail == p.maxFailInvertExpected {
+		if pos.offset < p.maxFailPos.offset {
+			return
+		}
+
+		if pos.offset > p.maxFailPos.offset {
+			p.maxFailPos = pos
+			p.maxFailExpected = p.maxFailExpected[:0]
+		}
+
+		if p.maxFailInvertExpected {
+			want = "!" + want
+		}
+		p.maxFailExpected = append(p.maxFailExpected, want)
+	}
+}
+
+// read advances the parser to the next rune.
+func (p *parser) read() {
+	p.pt.offset += p.pt.w
+	rn, n, valid := p.decode(p.data[p.pt.offset:])
+	p.pt.rn = rn
+	p.pt.w = n
+	p.pt.col++
+	if rn == '\n' {
+		p.pt.line++
+		p.pt.col = 0
+	}
+
+	if !valid {
+		if !p.allowInvalidUTF8 {
+			p.addErr(errInvalidEncoding)
+		}
+	}
+}
+
+// decode decodes the first character of b in the encoding of the input.
+func (p *parser) decode(b []byte) (rune, int, bool) {
+	rn, n := utf8.DecodeRune(b)
+	return rn, n, rn != utf8.RuneError || n != 1 // see utf8.DecodeRune
+}
+
+// restore parser position to the savepoint pt.
+func (p *parser) restore(pt savepoint) {
+	if pt.offset == p.pt.offset {
+		return
+	}
+	if p.tracer != nil && pt.offset < p.pt.offset {
+		p.tracer.backtrack(p.pt.position, pt.position)
+	}
+	p.pt = pt
+}
+
+// Cloner is implemented by any value that has a Clone method, which returns a
+// copy of the value. This is mainly used for types which are not passed by
+// value (e.g map, slice, chan) or structs that contain such types.
+//
+// This is used in conjunction with the global state feature to create proper
+// copies of the state to allow the parser to properly restore the state in
+// the case of backtracking.
+type Cloner interface {
+	Clone() any
+}
+
+var statePool = &sync.Pool{
+	New: func() any { return make(storeDict) },
+}
+
+func (sd storeDict) Discard() {
+	for k := range sd {
+		delete(sd, k)
+	}
+	statePool.Put(sd)
+}
+
+// clone and return parser current state.
+func (p *parser) cloneState() storeDict {
+	if p.tracer != nil {
+		defer p.out(p.in("cloneState"))
+	}
+
+	state := statePool.Get().(storeDict)
+	for k, v := range p.cur.state {
+		if c, ok := v.(Cloner); ok {
+			state[k] = c.Clone()
+		} else {
+			state[k] = v
+		}
+	}
+	return state
+}
+
+// restore parser current state to the state storeDict.
+// every restoreState should applied only one time for every cloned state
+func (p *parser) restoreState(state storeDict) {
+	if p.tracer != nil {
+		defer p.out(p.in("restoreState"))
+	}
+	p.cur.state.Discard()
+	p.cur.state = state
+}
+
+// get the slice of bytes from the savepoint start to the current position.
+func (p *parser) sliceFrom(start savepoint) []byte {
+	return p.slice(start.position.offset, p.pt.position.offset)
+}
+
+// get the slice of bytes between the offsets start and end.
+func (p *parser) slice(start, end int) []byte {
+	return p.data[start:end]
+}
+
+// memoEntry is the result of an expression or a rule in the memoization
+// table. The few results at an offset are searched linearly.
+type memoEntry struct {
+	node any
+	res  resultTuple
+}
+
+// memoSlot holds the results at an offset of the memoization table, the
+// slots are reused for the offsets that are memoWindow apart.
+type memoSlot struct {
+	offset  int
+	entries []memoEntry
+}
+
+func (p *parser) getMemoized(node any) (resultTuple, bool) {
+	if len(p.memo) == 0 || p.pt.offset < p.memoFloor {
+		return resultTuple{}, false
+	}
+	slot := &p.memo[p.pt.offset%len(p.memo)]
+	if slot.offset != p.pt.offset {
+		return resultTuple{}, false
+	}
+	for _, e := range slot.entries {
+		if e.node == node {
+			return e.res, true
+		}
+	}
+	return resultTuple{}, false
+}
+
+func (p *parser) setMemoized(pt savepoint, node any, tuple resultTuple) {
+	if p.memo == nil {
+		n := len(p.data) + 1
+		if p.memoWindow > 0 && p.memoWindow < n {
+			n = p.memoWindow
+		}
+		p.memo = make([]memoSlot, n)
+	}
+	if pt.offset < p.memoFloor {
+		return
+	}
+	slot := &p.memo[pt.offset%len(p.memo)]
+	if slot.offset != pt.offset {
+		if slot.offset > pt.offset {
+			// out of the window
+			return
+		}
+		for i := range slot.entries {
+			slot.entries[i] = memoEntry{}
+		}
+		slot.offset, slot.entries = pt.offset, slot.entries[:0]
+	}
+	for i := range slot.entries {
+		if slot.entries[i].node == node {
+			slot.entries[i].res = tuple
+			return
+		}
+	}
+	slot.entries = append(slot.entries, memoEntry{node: node, res: tuple})
+}
+
+// leaderKey is the key of the result of a left-recursive leader.
+type leaderKey struct {
+	offset int
+	node   any
+}
+
+// leaderGrowth is a left-recursive leader being grown, involved is set
+// if its result depends on the seed of an enclosing leader.
+type leaderGrowth struct {
+	key      leaderKey
+	involved bool
+}
+
+func (p *parser) newLeaderKey(rule *rule) leaderKey {
+	var node any = rule
+	return leaderKey{offset: p.pt.offset, node: node}
+}
+
+func (p *parser) getLeader(key leaderKey) (resultTuple, bool) {
+	res, ok := p.leaders[key]
+	if !ok {
+		return res, false
+	}
+	for i := len(p.growing) - 1; i >= 0; i-- {
+		if p.growing[i].key == key {
+			// the seed of a leader being grown, the results of the leaders
+			// grown since depend on it
+			for j := i + 1; j < len(p.growing); j++ {
+				p.growing[j].involved = true
+			}
+			break
+		}
+	}
+	return res, true
+}
+
+func (p *parser) setLeader(key leaderKey, tuple resultTuple) {
+	if p.leaders == nil {
+		p.leaders = make(map[leaderKey]resultTuple)
+	}
+	p.leaders[key] = tuple
+}
+
+func (p *parser) buildRulesTable(g *grammar) {
+	p.rules = make(map[string]*rule, len(g.rules))
+	for _, r := range g.rules {
+		p.rules[r.name] = r
+	}
+}
+
+// nolint: gocyclo
+func (p *parser) parse(g *grammar) (val any, err error) {
+	if len(g.rules) == 0 {
+		p.addErr(errNoRule)
+		return nil, p.errs.err()
+	}
+
+	// TODO : not super critical but this could be generated
+	p.buildRulesTable(g)
+
+	if p.recover {
+		// panic can be used in action code to stop parsing immediately
+		// and return the panic as an error.
+		defer func() {
+			if e := recover(); e != nil {
+				if p.tracer != nil {
+					defer p.out(p.in("panic handler"))
+				}
+				val = nil
+				switch e := e.(type) {
+				case error:
+					p.addErr(e)
+				default:
+					p.addErr(fmt.Errorf("%v", e))
+				}
+				err = p.errs.err()
+			}
+		}()
+	}
+
+	startRule, ok := p.rules[p.entrypoint]
+	if !ok {
+		p.addErr(errInvalidEntrypoint)
+		return nil, p.errs.err()
+	}
+
+	p.read() // advance to first rune
+	val, ok = p.parseRuleWrap(startRule)
+	if !ok {
+		if len(*p.errs) == 0 {
+			// If parsing fails, but no errors have been recorded, the expected values
+			// for the farthest parser position are returned as error.
+			maxFailExpectedMap := make(map[string]struct{}, len(p.maxFailExpected))
+			for _, v := range p.maxFailExpected {
+				maxFailExpectedMap[v] = struct{}{}
+			}
+			expected := make([]string, 0, len(maxFailExpectedMap))
+			eof := false
+			if _, ok := maxFailExpectedMap["!."]; ok {
+				delete(maxFailExpectedMap, "!.")
+				eof = true
+			}
+			for k := range maxFailExpectedMap {
+				expected = append(expected, k)
+			}
+			sort.Strings(expected)
+			if eof {
+				expected = append(expected, "EOF")
+			}
+			p.addErrAt(errors.New("no match found, expected: "+listJoin(expected, ", ", "or")), p.maxFailPos, expected)
+		}
+
+		return nil, p.errs.err()
+	}
+	return val, p.errs.err()
+}
+
+func listJoin(list []string, sep string, lastSep string) string {
+	switch len(list) {
+	case 0:
+		return ""
+	case 1:
+		return list[0]
+	default:
+		return strings.Join(list[:len(list)-1], sep) + " " + lastSep + " " + list[len(list)-1]
+	}
+}
+
+func (p *parser) parseRuleRecursiveLeader(rule *rule) (any, bool) {
+	key := p.newLeaderKey(rule)
+	result, ok := p.getLeader(key)
+	if p.tracer != nil {
+		p.tracer.memo(rule.name, p.pt.position, ok)
+	}
+	if ok {
+		p.restore(result.end)
+		return result.v, result.b
+	}
+
+	if p.tracer != nil {
+		defer p.out(p.in("recursive " + rule.name))
+	}
+
+	var (
+		depth      = 0
+		startMark  = p.pt
+		lastResult = resultTuple{nil, false, startMark}
+		lastErrors = *p.errs
+	)
+
+	p.growing = append(p.growing, leaderGrowth{key: key})
+	for {
+		p.setLeader(key, lastResult)
+		val, ok := p.parseRule(rule)
+		endMark := p.pt
+		if (!ok) || (endMark.offset <= lastResult.end.offset && depth != 0) {
+			*p.errs = lastErrors
+			break
+		}
+		lastResult = resultTuple{val, ok, endMark}
+		lastErrors = *p.errs
+		p.restore(startMark)
+		depth++
+	}
+
+	involved := p.growing[len(p.growing)-1].involved
+	p.growing = p.growing[:len(p.growing)-1]
+
+	p.restore(lastResult.end)
+	if involved {
+		// the result depends on the seed of an enclosing leader, it is
+		// evaluated again with the next seed
+		delete(p.leaders, key)
+	} else {
+		p.setLeader(key, lastResult)
+	}
+	return lastResult.v, lastResult.b
+}
+
+func (p *parser) parseRuleRecursiveNoLeader(rule *rule) (any, bool) {
+	return p.parseRule(rule)
+}
+
+// memoizeRule returns true if the results of the rule are memoized, with
+// the Memoize option or with the @memoize annotation of the rule.
+func (p *parser) memoizeRule(rule *rule) bool {
+	return p.memoize
+}
+
+func (p *parser) parseRuleMemoize(rule *rule) (any, bool) {
+	res, ok := p.getMemoized(rule)
+	if p.tracer != nil {
+		p.tracer.memo(rule.name, p.pt.position, ok)
+	}
+	if ok {
+		p.restore(res.end)
+		return res.v, res.b
+	}
+
+	startMark := p.pt
+	val, ok := p.parseRule(rule)
+	p.setMemoized(startMark, rule, resultTuple{val, ok, p.pt})
+
+	return val, ok
+}
+
+func (p *parser) parseRuleWrap(rule *rule) (any, bool) {
+	if p.tracer != nil {
+		p.tracer.enterRule(rule.name, p.pt.position)
+	}
+	var (
+		val       any
+		ok        bool
+		startMark = p.pt
+	)
+
+	switch {
+	case rule.leader:
+		val, ok = p.parseRuleRecursiveLeader(rule)
+	case rule.leftRecursive:
+		val, ok = p.parseRuleRecursiveNoLeader(rule)
+	case p.memoizeRule(rule):
+		val, ok = p.parseRuleMemoize(rule)
+	default:
+		val, ok = p.parseRule(rule)
+	}
+
+	if p.tracer != nil {
+		p.tracer.exitRule(rule.name, ok, startMark.position, p.pt.position)
+	}
+	return val, ok
+}
+
+func (p *parser) parseRule(rule *rule) (any, bool) {
+	p.rstack = append(p.rstack, rule)
+	p.pushV()
+	val, ok := p.parseExprWrap(rule.expr)
+	p.popV()
+	p.rstack = p.rstack[:len(p.rstack)-1]
+	return val, ok
+}
+
+// memoizeExprs returns true if the results of the expressions of the rule
+// may be memoized. This is not the case for left-recursive rules and for
+// rules with back-references, as their results depend on the context.
+func (r *rule) memoizeExprs() bool {
+	if r.leftRecursive {
+		return false
+	}
+	return true
+}
+
+func (p *parser) parseExprWrap(expr any) (any, bool) {
+	var pt savepoint
+
+	memoize := p.memoize && p.rstack[len(p.rstack)-1].memoizeExprs()
+	if memoize {
+		res, ok := p.getMemoized(expr)
+		if ok {
+			p.restore(res.end)
+			return res.v, res.b
+		}
+		pt = p.pt
+	}
+
+	val, ok := p.parseExpr(expr)
+
+	if memoize {
+		p.setMemoized(pt, expr, resultTuple{val, ok, p.pt})
+	}
+	return val, ok
+}
+
+// nolint: gocyclo
+func (p *parser) parseExpr(expr any) (val any, ok bool) {
+	p.ExprCnt++
+	if p.ExprCnt > p.maxExprCnt {
+		panic(errMaxExprCnt)
+	}
+
+	switch expr := expr.(type) {
+	case *actionExpr:
+		val, ok = p.parseActionExpr(expr)
+	case *andCodeExpr:
+		val, ok = p.parseAndCodeExpr(expr)
+	case *andExpr:
+		val, ok = p.parseAndExpr(expr)
+	case *anyMatcher:
+		val, ok = p.parseAnyMatcher(expr)
+	case *charClassMatcher:
+		val, ok = p.parseCharClassMatcher(expr)
+	case *choiceExpr:
+		val, ok = p.parseChoiceExpr(expr)
+	case *labeledExpr:
+		val, ok = p.parseLabeledExpr(expr)
+	case *litMatcher:
+		val, ok = p.parseLitMatcher(expr)
+	case *notCodeExpr:
+		val, ok = p.parseNotCodeExpr(expr)
+	case *notExpr:
+		val, ok = p.parseNotExpr(expr)
+	case *oneOrMoreExpr:
+		val, ok = p.parseOneOrMoreExpr(expr)
+	case *recoveryExpr:
+		val, ok = p.parseRecoveryExpr(expr)
+	case *ruleRefExpr:
+		val, ok = p.parseRuleRefExpr(expr)
+	case *seqExpr:
+		val, ok = p.parseSeqExpr(expr)
+	case *stateCodeExpr:
+		val, ok = p.parseStateCodeExpr(expr)
+	case *throwExpr:
+		val, ok = p.parseThrowExpr(expr)
+	case *zeroOrMoreExpr:
+		val, ok = p.parseZeroOrMoreExpr(expr)
+	case *zeroOrOneExpr:
+		val, ok = p.parseZeroOrOneExpr(expr)
+	default:
+		panic(fmt.Sprintf("unknown expression type %T", expr))
+	}
+	return val, ok
+}
+
+func (p *parser) parseActionExpr(act *actionExpr) (any, bool) {
+	if p.tracer != nil {
+		defer p.out(p.in("parseActionExpr"))
+	}
+
+	start := p.pt
+	val, ok := p.parseExprWrap(act.expr)
+	if ok {
+		p.cur.pos = start.position
+		p.cur.text = p.sliceFrom(start)
+		state := p.cloneState()
+		actVal, err := act.run(p)
+		if err != nil {
+			p.addErrAt(err, start.position, []string{})
+		}
+		p.restoreState(state)
+
+		val = actVal
+	}
+	return val, ok
+}
+
+func (p *parser) parseAndCodeExpr(and *andCodeExpr) (any, bool) {
+	if p.tracer != nil {
+		defer p.out(p.in("parseAndCodeExpr"))
+	}
+
+	state := p.cloneState()
+
+	ok, err := and.run(p)
+	if err != nil {
+		p.addErr(err)
+	}
+	p.restoreState(state)
+
+	return nil, ok
+}
+
+func (p *parser) parseAndExpr(and *andExpr) (any, bool) {
+	if p.tracer != nil {
+		defer p.out(p.in("parseAndExpr"))
+	}
+
+	pt := p.pt
+	p.pushV()
+	_, ok := p.parseExprWrap(and.expr)
+	p.popV()
+	p.restore(pt)
+
+	return nil, ok
+}
+
+func (p *parser) parseAnyMatcher(any *anyMatcher) (any, bool) {
+	if p.tracer != nil {
+		defer p.out(p.in("parseAnyMatcher"))
+	}
+
+	if p.pt.rn == utf8.RuneError && p.pt.w == 0 {
+		// EOF - see utf8.DecodeRune
+		p.failAt(false, p.pt.position, ".")
+		return nil, false
+	}
+	start := p.pt
+	p.read()
+	p.failAt(true, start.position, ".")
+	return p.sliceFrom(start), true
+}
+
+// nolint: gocyclo
+func (p *parser) parseCharClassMatcher(chr *charClassMatcher) (any, bool) {
+	if p.tracer != nil {
+		defer p.out(p.in("parseCharClassMatcher"))
+	}
+
+	cur := p.pt.rn
+	start := p.pt
+
+	// can't match EOF
+	if cur == utf8.RuneError && p.pt.w == 0 { // see utf8.DecodeRune
+		p.failAt(false, start.position, chr.val)
+		return nil, false
+	}
+
+	if chr.ignoreCase {
+		cur = unicode.ToLower(cur)
+	}
+
+	// try to match in the list of available chars
+	for _, rn := range chr.chars {
+		if rn == cur {
+			if chr.inverted {
+				p.failAt(false, start.position, chr.val)
+				return nil, false
+			}
+			p.read()
+			p.failAt(true, start.position, chr.val)
+			return p.sliceFrom(start), true
+		}
+	}
+
+	// try to match in the list of ranges
+	for i := 0; i < len(chr.ranges); i += 2 {
+		if cur >= chr.ranges[i] && cur <= chr.ranges[i+1] {
+			if chr.inverted {
+				p.failAt(false, start.position, chr.val)
+				return nil, false
+			}
+			p.read()
+			p.failAt(true, start.position, chr.val)
+			return p.sliceFrom(start), true
+		}
+	}
+
+	// try to match in the list of Unicode classes
+	for _, cl := range chr.classes {
+		if unicode.Is(cl, cur) {
+			if chr.inverted {
+				p.failAt(false, start.position, chr.val)
+				return nil, false
+			}
+			p.read()
+			p.failAt(true, start.position, chr.val)
+			return p.sliceFrom(start), true
+		}
+	}
+
+	if chr.inverted {
+		p.read()
+		p.failAt(true, start.position, chr.val)
+		return p.sliceFrom(start), true
+	}
+	p.failAt(false, start.position, chr.val)
+	return nil, false
+}
+
+func (p *parser) incChoiceAltCnt(ch *choiceExpr, altI int) {
+	choiceIdent := fmt.Sprintf("%s %d:%d", p.rstack[len(p.rstack)-1].name, ch.pos.line, ch.pos.col)
+	m := p.ChoiceAltCnt[choiceIdent]
+	if m == nil {
+		m = make(map[string]int)
+		p.ChoiceAltCnt[choiceIdent] = m
+	}
+	// We increment altI by 1, so the keys do not start at 0
+	alt := strconv.Itoa(altI + 1)
+	if altI == choiceNoMatch {
+		alt = p.choiceNoMatch
+	}
+	m[alt]++
+}
+
+func (p *parser) parseChoiceExpr(ch *choiceExpr) (any, bool) {
+	if p.tracer != nil {
+		defer p.out(p.in("parseChoiceExpr"))
+	}
+
+	for altI, alt := range ch.alternatives {
+		// dummy assignment to prevent compile error if optimized
+		_ = altI
+
+		p.pushV()
+		val, ok := p.parseExprWrap(alt)
+		p.popV()
+		if ok {
+			p.incChoiceAltCnt(ch, altI)
+			return val, ok
+		}
+	}
+	p.incChoiceAltCnt(ch, choiceNoMatch)
+	return nil, false
+}
+
+func (p *parser) parseLabeledExpr(lab *labeledExpr) (any, bool) {
+	if p.tracer != nil {
+		defer p.out(p.in("parseLabeledExpr"))
+	}
+
+	p.pushV()
+	val, ok := p.parseExprWrap(lab.expr)
+	p.popV()
+	if ok && lab.label != "" {
+		m := p.vstack[len(p.vstack)-1]
+		m[lab.label] = val
+	}
+	return val, ok
+}
+
+func (p *parser) parseLitMatcher(lit *litMatcher) (any, bool) {
+	if p.tracer != nil {
+		defer p.out(p.in("parseLitMatcher"))
+	}
+
+	start := p.pt
+	for _, want := range lit.val {
+		cur := p.pt.rn
+		if lit.ignoreCase {
+			cur = unicode.ToLower(cur)
+		}
+		if cur != want {
+			p.failAt(false, start.position, lit.want)
+			p.restore(start)
+			return nil, false
+		}
+		p.read()
+	}
+	p.failAt(true, start.position, lit.want)
+	return p.sliceFrom(start), true
+}
+
+func (p *parser) parseNotCodeExpr(not *notCodeExpr) (any, bool) {
+	if p.tracer != nil {
+		defer p.out(p.in("parseNotCodeExpr"))
+	}
+
+	state := p.cloneState()
+
+	ok, err := not.run(p)
+	if err != nil {
+		p.addErr(err)
+	}
+	p.restoreState(state)
+
+	return nil, !ok
+}
+
+func (p *parser) parseNotExpr(not *notExpr) (any, bool) {
+	if p.tracer != nil {
+		defer p.out(p.in("parseNotExpr"))
+	}
+
+	pt := p.pt
+	p.pushV()
+	p.maxFailInvertExpected = !p.maxFailInvertExpected
+	_, ok := p.parseExprWrap(not.expr)
+	p.maxFailInvertExpected = !p.maxFailInvertExpected
+	p.popV()
+	p.restore(pt)
+
+	return nil, !ok
+}
+
+func (p *parser) parseOneOrMoreExpr(expr *oneOrMoreExpr) (any, bool) {
+	if p.tracer != nil {
+		defer p.out(p.in("parseOneOrMoreExpr"))
+	}
+
+	var vals []any
+
+	for {
+		p.pushV()
+		val, ok := p.parseExprWrap(expr.expr)
+		p.popV()
+		if !ok {
+			if len(vals) == 0 {
+				// did not match once, no match
+				return nil, false
+			}
+			return vals, true
+		}
+		vals = append(vals, val)
+	}
+}
+
+func (p *parser) parseRecoveryExpr(recover *recoveryExpr) (any, bool) {
+	if p.tracer != nil {
+		defer p.out(p.in("parseRecoveryExpr (" + strings.Join(recover.failureLabel, ",") + ")"))
+	}
+
+	p.pushRecovery(recover.failureLabel, recover.recoverExpr)
+	val, ok := p.parseExprWrap(recover.expr)
+	p.popRecovery()
+
+	return val, ok
+}
+
+func (p *parser) parseRuleRefExpr(ref *ruleRefExpr) (any, bool) {
+	if p.tracer != nil {
+		defer p.out(p.in("parseRuleRefExpr " + ref.name))
+	}
+
+	if ref.name == "" {
+		panic(fmt.Sprintf("%s: invalid rule: missing name", ref.pos))
+	}
+
+	rule := p.rules[ref.name]
+	if rule == nil {
+		p.addErr(fmt.Errorf("undefined rule: %s", ref.name))
+		return nil, false
+	}
+	return p.parseRuleWrap(rule)
+}
+
+func (p *parser) parseSeqExpr(seq *seqExpr) (any, bool) {
+	if p.tracer != nil {
+		defer p.out(p.in("parseSeqExpr"))
+	}
+
+	vals := make([]any, 0, len(seq.exprs))
+
+	pt := p.pt
+	for _, expr := range seq.exprs {
+		val, ok := p.parseExprWrap(expr)
+		if !ok {
+			p.restore(pt)
+			return nil, false
+		}
+		vals = append(vals, val)
+	}
+	return vals, true
+}
+
+func (p *parser) parseStateCodeExpr(state *stateCodeExpr) (any, bool) {
+	if p.tracer != nil {
+		defer p.out(p.in("parseStateCodeExpr"))
+	}
+
+	err := state.run(p)
+	if err != nil {
+		p.addErr(err)
+	}
+	return nil, true
+}
+
+func (p *parser) parseThrowExpr(expr *throwExpr) (any, bool) {
+	if p.tracer != nil {
+		defer p.out(p.in("parseThrowExpr"))
+	}
+
+	for i := len(p.recoveryStack) - 1; i >= 0; i-- {
+		if recoverExpr, ok := p.recoveryStack[i][expr.label]; ok {
+			if val, ok := p.parseExprWrap(recoverExpr); ok {
+				return val, ok
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func (p *parser) parseZeroOrMoreExpr(expr *zeroOrMoreExpr) (any, bool) {
+	if p.tracer != nil {
+		defer p.out(p.in("parseZeroOrMoreExpr"))
+	}
+
+	var vals []any
+
+	for {
+		p.pushV()
+		val, ok := p.parseExprWrap(expr.expr)
+		p.popV()
+		if !ok {
+			return vals, true
+		}
+		vals = append(vals, val)
+	}
+}
+
+func (p *parser) parseZeroOrOneExpr(expr *zeroOrOneExpr) (any, bool) {
+	if p.tracer != nil {
+		defer p.out(p.in("parseZeroOrOneExpr"))
+	}
+
+	p.pushV()
+	val, _ := p.parseExprWrap(expr.expr)
+	p.popV()
+	// whether it matched or not, consider it a match
+	return val, true
+}
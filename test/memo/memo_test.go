@@ -0,0 +1,35 @@
+package memo
+
+import (
+	"reflect"
+	"testing"
+
+	optimized "github.com/mna/pigeon/test/memo/optimized"
+)
+
+func TestMemoizeRule(t *testing.T) {
+	var prof Profile
+	want, err := Parse("", []byte("(1-2)+(3)"), Profiling(&prof))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := prof.Rules["Term"]; e.MemoHits == 0 || e.Revisits != 0 {
+		t.Errorf("want memo hits and no revisit of Term, got %+v", e)
+	}
+	if e := prof.Rules["Expr"]; e.MemoHits != 0 {
+		t.Errorf("want no memo hit of Expr, got %+v", e)
+	}
+	for key, e := range prof.Exprs {
+		if e.MemoHits != 0 {
+			t.Errorf("%s: want no memo hit of the expressions, got %+v", key, e)
+		}
+	}
+
+	got, err := optimized.Parse("", []byte("(1-2)+(3)"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v from the optimized parser, got %v", want, got)
+	}
+}
@@ -0,0 +1,37 @@
+package memowindow
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestMemoWindow(t *testing.T) {
+	input := bytes.Repeat([]byte("1+2;(3-4)-5;6;"), 20)
+	want, err := Parse("", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, window := range []int{0, 1, 4, 100} {
+		p := newParser("", input, Memoize(true), MemoWindow(window))
+		got, err := p.parse(g)
+		if err != nil {
+			t.Fatalf("window %d: %v", window, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("window %d: want %v, got %v", window, want, got)
+		}
+		if window > 0 && len(p.memo) > window {
+			t.Errorf("window %d: want at most %d slots, got %d", window, window, len(p.memo))
+		}
+		if p.memoFloor != len(input) {
+			t.Errorf("window %d: want the results discarded up to %d, got %d", window, len(input), p.memoFloor)
+		}
+		for i, slot := range p.memo {
+			if slot.offset < p.memoFloor && slot.entries != nil {
+				t.Errorf("window %d: slot %d: want the results of offset %d discarded", window, i, slot.offset)
+			}
+		}
+	}
+}
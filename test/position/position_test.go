@@ -0,0 +1,151 @@
+package position
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"unicode/utf16"
+)
+
+const input = "ab\n\tx😀é\r\nz"
+
+func TestPosition(t *testing.T) {
+	cases := []struct {
+		offset    int
+		unit      ColumnUnit
+		line, col int
+	}{
+		{0, ColumnRunes, 1, 1},
+		{2, ColumnRunes, 1, 3}, // the newline ends the first line
+		{3, ColumnBytes, 2, 1},
+		{4, ColumnBytes, 2, 2},
+		{4, ColumnRunes, 2, 2},
+		{4, ColumnUTF16, 2, 2},
+		{4, ColumnTabs, 2, 9},
+		{5, ColumnBytes, 2, 3},
+		{5, ColumnRunes, 2, 3},
+		{5, ColumnUTF16, 2, 3},
+		{5, ColumnTabs, 2, 10},
+		{7, ColumnRunes, 2, 3}, // inside the emoji
+		{9, ColumnBytes, 2, 7},
+		{9, ColumnRunes, 2, 4},
+		{9, ColumnUTF16, 2, 5},
+		{9, ColumnTabs, 2, 11},
+		{11, ColumnUTF16, 2, 6},
+		{13, ColumnRunes, 3, 1},
+		{14, ColumnRunes, 3, 2},
+		{100, ColumnRunes, 3, 2},
+		{-1, ColumnRunes, 1, 1},
+	}
+
+	pc := NewPositionConverter([]byte(input))
+	for _, tc := range cases {
+		line, col := pc.Position(tc.offset, tc.unit)
+		if line != tc.line || col != tc.col {
+			t.Errorf("Position(%d, %d): want %d:%d, got %d:%d", tc.offset, tc.unit, tc.line, tc.col, line, col)
+		}
+		if tc.offset < 0 || tc.offset > len(input) || tc.offset == 7 {
+			continue
+		}
+		if got := pc.Offset(tc.line, tc.col, tc.unit); got != tc.offset {
+			t.Errorf("Offset(%d, %d, %d): want %d, got %d", tc.line, tc.col, tc.unit, tc.offset, got)
+		}
+	}
+}
+
+func TestOffset(t *testing.T) {
+	cases := []struct {
+		line, col int
+		unit      ColumnUnit
+		offset    int
+	}{
+		{0, 1, ColumnRunes, 0},
+		{1, 10, ColumnRunes, 2},
+		{2, 5, ColumnTabs, 3},  // inside the tab
+		{2, 4, ColumnUTF16, 5}, // inside the emoji
+		{2, 10, ColumnRunes, 12},
+		{4, 1, ColumnRunes, len(input)},
+	}
+
+	pc := NewPositionConverter([]byte(input))
+	for _, tc := range cases {
+		if got := pc.Offset(tc.line, tc.col, tc.unit); got != tc.offset {
+			t.Errorf("Offset(%d, %d, %d): want %d, got %d", tc.line, tc.col, tc.unit, tc.offset, got)
+		}
+	}
+}
+
+func TestTabWidth(t *testing.T) {
+	pc := NewPositionConverter([]byte("\ta\t\tb"), TabWidth(4))
+	if _, col := pc.Position(4, ColumnTabs); col != 13 {
+		t.Errorf("want column 13, got %d", col)
+	}
+}
+
+func TestUTF16(t *testing.T) {
+	// U+010A is 0A 01 in UTF-16LE, its low byte is a newline
+	units := utf16.Encode([]rune("aĊb\nc"))
+	encodings := []struct {
+		name  string
+		order binary.AppendByteOrder
+		dec   Decoder
+	}{
+		{"LE", binary.LittleEndian, DecodeUTF16LE},
+		{"BE", binary.BigEndian, DecodeUTF16BE},
+	}
+	for _, enc := range encodings {
+		var src []byte
+		for _, u := range units {
+			src = enc.order.AppendUint16(src, u)
+		}
+		pc := NewPositionConverter(src, InputDecoder(enc.dec))
+
+		for _, tc := range []struct {
+			offset    int
+			unit      ColumnUnit
+			line, col int
+		}{
+			{4, ColumnRunes, 1, 3},
+			{4, ColumnBytes, 1, 5},
+			{6, ColumnRunes, 1, 4},
+			{8, ColumnRunes, 2, 1},
+			{9, ColumnRunes, 2, 1}, // inside the c
+			{10, ColumnRunes, 2, 2},
+		} {
+			line, col := pc.Position(tc.offset, tc.unit)
+			if line != tc.line || col != tc.col {
+				t.Errorf("%s: Position(%d, %d): want %d:%d, got %d:%d", enc.name, tc.offset, tc.unit, tc.line, tc.col, line, col)
+			}
+		}
+		if got := pc.Offset(2, 1, ColumnRunes); got != 8 {
+			t.Errorf("%s: Offset(2, 1): want 8, got %d", enc.name, got)
+		}
+		if got := pc.Offset(1, 10, ColumnRunes); got != 6 {
+			t.Errorf("%s: Offset(1, 10): want 6, got %d", enc.name, got)
+		}
+	}
+}
+
+func TestErrorPosition(t *testing.T) {
+	src := []byte("a\n\t😀!")
+	_, err := Parse("", src)
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+	var errs interface{ Unwrap() []error }
+	if !errors.As(err, &errs) || len(errs.Unwrap()) != 1 {
+		t.Fatalf("want a list of 1 error, got %v", err)
+	}
+
+	pc := NewPositionConverter(src)
+	for unit, want := range map[ColumnUnit]int{ColumnBytes: 6, ColumnRunes: 3, ColumnUTF16: 4, ColumnTabs: 10} {
+		line, col, ok := pc.ErrorPosition(err, unit)
+		if !ok || line != 2 || col != want {
+			t.Errorf("unit %d: want 2:%d, got %d:%d (%t)", unit, want, line, col, ok)
+		}
+	}
+
+	if _, _, ok := pc.ErrorPosition(errors.New("no position"), ColumnRunes); ok {
+		t.Error("want no position for a foreign error")
+	}
+}
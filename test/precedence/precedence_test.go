@@ -0,0 +1,51 @@
+package precedence
+
+import (
+	"strings"
+	"testing"
+
+	optimized "github.com/mna/pigeon/test/precedence/optimized"
+)
+
+func TestPrecedence(t *testing.T) {
+	cases := []struct {
+		in, want, err string
+	}{
+		{in: "1", want: "1"},
+		{in: "1 + 2 * 3", want: "(1 + (2 * 3))"},
+		{in: "1 - 2 - 3", want: "((1 - 2) - 3)"},
+		{in: "2 ** 3 ** 2", want: "(2 ** (3 ** 2))"},
+		{in: "-2 ** 2", want: "(-(2 ** 2))"},
+		{in: "-2 * 3", want: "((-2) * 3)"},
+		{in: "--1", want: "(-(-1))"},
+		{in: "3! + 1", want: "((3!) + 1)"},
+		{in: "-3!", want: "(-(3!))"},
+		{in: "!1 == 2 < 3", err: `1:9 (8): no match found`},
+		{in: "(1 + 2) * 3 == 9", want: "(((1 + 2) * 3) == 9)"},
+		{in: "1 < 2 == 3", err: `1:7 (6): no match found`},
+		{in: "1 +", err: `1:4 (3): no match found`},
+		{in: "4 / 0 + 1", err: `1:1 (0): rule Expr: division by zero`},
+	}
+	parsers := map[string]func(b []byte) (any, error){
+		"standard":  func(b []byte) (any, error) { return Parse("", b) },
+		"optimized": func(b []byte) (any, error) { return optimized.Parse("", b) },
+	}
+	for name, parse := range parsers {
+		for _, c := range cases {
+			got, err := parse([]byte(c.in))
+			if c.err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), c.err) {
+					t.Errorf("%s: %q: want error %q, got %v", name, c.in, c.err, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s: %q: %v", name, c.in, err)
+				continue
+			}
+			if got != c.want {
+				t.Errorf("%s: %q: want %s, got %v", name, c.in, c.want, got)
+			}
+		}
+	}
+}
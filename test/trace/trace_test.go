@@ -0,0 +1,152 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// recorder is a Tracer that records the rule, backtracking, memoization
+// and error events.
+type recorder struct {
+	events []string
+	steps  int
+}
+
+func (r *recorder) EnterRule(rule string, pos Position) {
+	r.events = append(r.events, fmt.Sprintf("> %s %d", rule, pos.Offset))
+}
+
+func (r *recorder) ExitRule(rule string, matched bool, span Span) {
+	r.events = append(r.events, fmt.Sprintf("< %s %t %d-%d", rule, matched, span.Start.Offset, span.End.Offset))
+}
+
+func (r *recorder) EnterExpr(step string, pos Position) {
+	r.steps++
+}
+
+func (r *recorder) ExitExpr(step string, pos Position) {
+	r.steps--
+}
+
+func (r *recorder) Backtrack(from, to Position) {
+	r.events = append(r.events, fmt.Sprintf("backtrack %d-%d", from.Offset, to.Offset))
+}
+
+func (r *recorder) Memo(rule string, pos Position, hit bool) {
+	r.events = append(r.events, fmt.Sprintf("memo %s %d %t", rule, pos.Offset, hit))
+}
+
+func (r *recorder) Error(err error) {
+	r.events = append(r.events, "error "+err.Error())
+}
+
+func TestTrace(t *testing.T) {
+	cases := []struct {
+		input   string
+		memoize bool
+		want    []string
+	}{
+		{"1+2", false, []string{
+			"> Sum 0",
+			"> Num 0",
+			"< Num true 0-1",
+			"> Sum 2",
+			"> Num 2",
+			"< Num true 2-3",
+			"backtrack 3-2",
+			"> Num 2",
+			"< Num true 2-3",
+			"< Sum true 2-3",
+			"< Sum true 0-3",
+		}},
+		{"1+2", true, []string{
+			"> Sum 0",
+			"memo Sum 0 false",
+			"> Num 0",
+			"memo Num 0 false",
+			"< Num true 0-1",
+			"> Sum 2",
+			"memo Sum 2 false",
+			"> Num 2",
+			"memo Num 2 false",
+			"< Num true 2-3",
+			"backtrack 3-2",
+			"> Num 2",
+			"memo Num 2 true",
+			"< Num true 2-3",
+			"< Sum true 2-3",
+			"< Sum true 0-3",
+		}},
+		{"+", false, []string{
+			"> Sum 0",
+			"> Num 0",
+			"< Num false 0-0",
+			"> Num 0",
+			"< Num false 0-0",
+			"< Sum false 0-0",
+			`error 1:1 (0): no match found, expected: [0-9]`,
+		}},
+	}
+
+	for _, tc := range cases {
+		var r recorder
+		_, err := Parse("", []byte(tc.input), Trace(&r), Memoize(tc.memoize))
+		if (err != nil) != strings.HasPrefix(tc.input, "+") {
+			t.Errorf("%q: unexpected error: %v", tc.input, err)
+		}
+		if got, want := strings.Join(r.events, "\n"), strings.Join(tc.want, "\n"); got != want {
+			t.Errorf("%q, memoize %t: want\n%s\ngot\n%s", tc.input, tc.memoize, want, got)
+		}
+		if r.steps != 0 {
+			t.Errorf("%q: %d steps entered but not exited", tc.input, r.steps)
+		}
+	}
+}
+
+func TestTraceDebug(t *testing.T) {
+	var r recorder
+	// Debug(false) keeps a Tracer set before, Debug(true) replaces it.
+	if _, err := Parse("", []byte("1"), Trace(&r), Debug(false)); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.events) == 0 {
+		t.Errorf("want events with Debug(false)")
+	}
+
+	p := newParser("", []byte("1"), Trace(&r), Debug(true))
+	if _, ok := p.tracer.(*debugTracer); !ok {
+		t.Errorf("want the debug tracer, got %T", p.tracer)
+	}
+	p.setOptions([]Option{Debug(false)})
+	if p.tracer != nil {
+		t.Errorf("want no tracer, got %T", p.tracer)
+	}
+}
+
+func TestProfiling(t *testing.T) {
+	var prof Profile
+	for _, in := range []string{"1+2", "3"} {
+		if _, err := Parse("", []byte(in), Profiling(&prof)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rules := map[string]ProfileEntry{
+		"Sum": {Calls: 3, Matches: 3, Bytes: 5, Backtracked: 2},
+		"Num": {Calls: 5, Matches: 5, Revisits: 2, Bytes: 5},
+	}
+	for name, want := range rules {
+		got := *prof.Rules[name]
+		got.Time = 0
+		if got != want {
+			t.Errorf("rule %s: want %+v, got %+v", name, want, got)
+		}
+	}
+	if e := prof.Exprs["Sum 5:11 litMatcher"]; e == nil || e.Calls != 3 || e.Failures != 2 {
+		t.Errorf("want 3 calls and 2 failures of the literal, got %+v", e)
+	}
+	if s := prof.Stacks["Sum;Sum;Num"]; s == nil || s.Calls != 2 {
+		t.Errorf("want 2 calls of the stack Sum;Sum;Num, got %+v", s)
+	}
+}
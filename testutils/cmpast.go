@@ -1,4 +1,4 @@
-package main
+package testutils
 
 import (
 	"strconv"
@@ -7,7 +7,10 @@ import (
 	"github.com/mna/pigeon/ast"
 )
 
-func compareGrammars(t *testing.T, src string, exp, got *ast.Grammar) bool {
+// CompareGrammars reports the first difference between the grammars exp
+// and got, parsed from src, as an error of t. It returns true if they are
+// equivalent, ignoring the positions.
+func CompareGrammars(t *testing.T, src string, exp, got *ast.Grammar) bool {
 	if (exp.Init != nil) != (got.Init != nil) {
 		t.Errorf("%q: want Init? %t, got %t", src, exp.Init != nil, got.Init != nil)
 		return false
@@ -49,6 +52,10 @@ func compareRule(t *testing.T, prefix string, exp, got *ast.Rule) bool {
 			return false
 		}
 	}
+	if exp.DocText() != got.DocText() {
+		t.Errorf("%q: want Doc %q, got %q", prefix, exp.DocText(), got.DocText())
+		return false
+	}
 	return compareExpr(t, prefix, 0, exp.Expr, got.Expr)
 }
 
@@ -110,6 +117,17 @@ func compareExpr(t *testing.T, prefix string, ix int, exp, got ast.Expression) b
 			t.Errorf("%q: want value %q, got %q", ixPrefix, exp.Val, got.Val)
 		}
 
+	case *ast.BackRefExpr:
+		got, ok := got.(*ast.BackRefExpr)
+		if !ok {
+			t.Errorf("%q: want expression type %T, got %T", ixPrefix, exp, got)
+			return false
+		}
+		if exp.Label.Val != got.Label.Val {
+			t.Errorf("%q: want label %q, got %q", ixPrefix, exp.Label.Val, got.Label.Val)
+			return false
+		}
+
 	case *ast.CharClassMatcher:
 		got, ok := got.(*ast.CharClassMatcher)
 		if !ok {
@@ -179,6 +197,17 @@ func compareExpr(t *testing.T, prefix string, ix int, exp, got ast.Expression) b
 			}
 		}
 
+	case *ast.IndentExpr:
+		got, ok := got.(*ast.IndentExpr)
+		if !ok {
+			t.Errorf("%q: want expression type %T, got %T", ixPrefix, exp, got)
+			return false
+		}
+		if exp.Kind != got.Kind {
+			t.Errorf("%q: want kind %s, got %s", ixPrefix, exp.Kind, got.Kind)
+			return false
+		}
+
 	case *ast.LabeledExpr:
 		got, ok := got.(*ast.LabeledExpr)
 		if !ok {
@@ -246,6 +275,28 @@ func compareExpr(t *testing.T, prefix string, ix int, exp, got ast.Expression) b
 		}
 		return compareExpr(t, prefix, ix+1, exp.Expr, got.Expr)
 
+	case *ast.RecoveryExpr:
+		got, ok := got.(*ast.RecoveryExpr)
+		if !ok {
+			t.Errorf("%q: want expression type %T, got %T", ixPrefix, exp, got)
+			return false
+		}
+		ne, ng := len(exp.Labels), len(got.Labels)
+		if ne != ng {
+			t.Errorf("%q: want %d Labels, got %d", ixPrefix, ne, ng)
+			return false
+		}
+		for i, l := range exp.Labels {
+			if l != got.Labels[i] {
+				t.Errorf("%q: want Labels[%d] %q, got %q", ixPrefix, i, l, got.Labels[i])
+				return false
+			}
+		}
+		if !compareExpr(t, prefix, ix+1, exp.Expr, got.Expr) {
+			return false
+		}
+		return compareExpr(t, prefix, ix+1, exp.RecoverExpr, got.RecoverExpr)
+
 	case *ast.RuleRefExpr:
 		got, ok := got.(*ast.RuleRefExpr)
 		if !ok {
@@ -281,6 +332,34 @@ func compareExpr(t *testing.T, prefix string, ix int, exp, got ast.Expression) b
 			}
 		}
 
+	case *ast.StateCodeExpr:
+		got, ok := got.(*ast.StateCodeExpr)
+		if !ok {
+			t.Errorf("%q: want expression type %T, got %T", ixPrefix, exp, got)
+			return false
+		}
+		if (exp.Code != nil) != (got.Code != nil) {
+			t.Errorf("%q: want Code?: %t, got %t", ixPrefix, exp.Code != nil, got.Code != nil)
+			return false
+		}
+		if exp.Code != nil {
+			if exp.Code.Val != got.Code.Val {
+				t.Errorf("%q: want code %q, got %q", ixPrefix, exp.Code.Val, got.Code.Val)
+				return false
+			}
+		}
+
+	case *ast.ThrowExpr:
+		got, ok := got.(*ast.ThrowExpr)
+		if !ok {
+			t.Errorf("%q: want expression type %T, got %T", ixPrefix, exp, got)
+			return false
+		}
+		if exp.Label != got.Label {
+			t.Errorf("%q: want label %q, got %q", ixPrefix, exp.Label, got.Label)
+			return false
+		}
+
 	case *ast.ZeroOrMoreExpr:
 		got, ok := got.(*ast.ZeroOrMoreExpr)
 		if !ok {